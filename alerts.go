@@ -0,0 +1,590 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"stock-analysis/internal/indicators"
+	"stock-analysis/internal/providers"
+)
+
+const (
+	// alertCheckInterval is the default of how often the background
+	// evaluator runs
+	alertCheckInterval = time.Minute
+	// alertLookbackDays covers the MACD warm-up of the rule evaluator
+	alertLookbackDays = 180
+	// maxAlertTriggers caps the stored trigger history
+	maxAlertTriggers = 1000
+)
+
+// AlertRule is a condition watched on a symbol
+type AlertRule struct {
+	ID           string  `json:"id"`
+	Symbol       string  `json:"symbol"`
+	Type         string  `json:"type"` // "priceCrossAbove", "priceCrossBelow", "volumeRate5D", "macdGoldenCross", "macdDeathCross", "expression" or "drawdown"
+	Threshold    float64 `json:"threshold,omitempty"`
+	Expression   string  `json:"expression,omitempty"` // formula for "expression" rules
+	EntryDate    string  `json:"entryDate,omitempty"`  // "drawdown" rules track the peak close from this date
+	Enabled      bool    `json:"enabled"`
+	Muted        bool    `json:"muted,omitempty"`        // record triggers without notifying
+	SnoozedUntil string  `json:"snoozedUntil,omitempty"` // RFC 3339; not evaluated before then
+	CreatedAt    string  `json:"createdAt"`
+	LastFired    string  `json:"lastFired,omitempty"` // bar date of the last trigger
+}
+
+// snoozed reports whether the rule is snoozed at now
+func (r *AlertRule) snoozed(now time.Time) bool {
+	if r.SnoozedUntil == "" {
+		return false
+	}
+	until, err := time.Parse(time.RFC3339, r.SnoozedUntil)
+	return err == nil && now.Before(until)
+}
+
+// AlertMarketValues are the market values on the bar that fired a rule
+type AlertMarketValues struct {
+	Open         float64 `json:"open"`
+	High         float64 `json:"high"`
+	Low          float64 `json:"low"`
+	Close        float64 `json:"close"`
+	ChangePct    float64 `json:"changePct"`
+	Volume       float64 `json:"volume"`
+	VolumeRate5D float64 `json:"volumeRate5d"`
+}
+
+// AlertTrigger records a rule firing
+type AlertTrigger struct {
+	ID          string  `json:"id"`
+	RuleID      string  `json:"ruleId"`
+	Symbol      string  `json:"symbol"`
+	Type        string  `json:"type"`
+	Date        string  `json:"date"` // bar date
+	Value       float64 `json:"value"`
+	Message     string  `json:"message"`
+	TriggeredAt string  `json:"triggeredAt"`
+
+	Rule         AlertRule         `json:"rule"` // the rule as it was when it fired
+	Market       AlertMarketValues `json:"market"`
+	Acknowledged string            `json:"acknowledgedAt,omitempty"`
+}
+
+// validate checks the rule type and its threshold
+func (r *AlertRule) validate() error {
+	r.Symbol = normalizeSymbol(r.Symbol)
+	if r.Symbol == "" {
+		return codeErrorf(codeParse, "symbol is required")
+	}
+	switch r.Type {
+	case "priceCrossAbove", "priceCrossBelow":
+		if r.Threshold <= 0 {
+			return codeErrorf(codeParse, "%s rule requires a positive price", r.Type)
+		}
+	case "volumeRate5D", "macdGoldenCross", "macdDeathCross":
+	case "drawdown":
+		if r.Threshold <= 0 || r.Threshold >= 100 {
+			return codeErrorf(codeParse, "drawdown rule requires a percent between 0 and 100")
+		}
+	case "expression":
+		if _, err := compileFormula(r.Expression); err != nil {
+			return codeErrorf(codeParse, "invalid alert expression: %v", err)
+		}
+	default:
+		return codeErrorf(codeParse, "unknown alert type: %s", r.Type)
+	}
+	return nil
+}
+
+// evaluateAlertRule checks rule against the latest bar of a chronological
+// series and describes the trigger if it fires
+func evaluateAlertRule(rule AlertRule, bars []Bar) (AlertTrigger, bool) {
+	n := len(bars)
+	if n < 2 {
+		return AlertTrigger{}, false
+	}
+	last, prev := bars[n-1], bars[n-2]
+	volumes := make([]float64, n)
+	for i, bar := range bars {
+		volumes[i] = bar.Volume
+	}
+	volumeRate := indicators.FiveDayRate(volumes)[n-1]
+	trigger := AlertTrigger{
+		RuleID: rule.ID,
+		Symbol: rule.Symbol,
+		Type:   rule.Type,
+		Date:   last.Date,
+		Rule:   rule,
+		Market: AlertMarketValues{
+			Open:         last.Open,
+			High:         last.High,
+			Low:          last.Low,
+			Close:        last.Close,
+			ChangePct:    last.ChangePct,
+			Volume:       last.Volume,
+			VolumeRate5D: volumeRate,
+		},
+	}
+
+	switch rule.Type {
+	case "priceCrossAbove":
+		if prev.Close < rule.Threshold && last.Close >= rule.Threshold {
+			trigger.Value = last.Close
+			trigger.Message = tr("alert.priceAbove", rule.Symbol, rule.Threshold, last.Close)
+			return trigger, true
+		}
+	case "priceCrossBelow":
+		if prev.Close > rule.Threshold && last.Close <= rule.Threshold {
+			trigger.Value = last.Close
+			trigger.Message = tr("alert.priceBelow", rule.Symbol, rule.Threshold, last.Close)
+			return trigger, true
+		}
+	case "volumeRate5D":
+		if n > 5 && volumeRate > rule.Threshold {
+			trigger.Value = volumeRate
+			trigger.Message = tr("alert.volumeRate", rule.Symbol, volumeRate, rule.Threshold)
+			return trigger, true
+		}
+	case "macdGoldenCross", "macdDeathCross":
+		set := symbolIndicators(rule.Symbol, bars)
+		dif, dea := set.DIF, set.DEA
+		before, after := dif[n-2]-dea[n-2], dif[n-1]-dea[n-1]
+		golden := before <= 0 && after > 0
+		death := before >= 0 && after < 0
+		if (rule.Type == "macdGoldenCross" && golden) || (rule.Type == "macdDeathCross" && death) {
+			trigger.Value = dif[n-1]
+			key := "alert.macdGolden"
+			if death {
+				key = "alert.macdDeath"
+			}
+			trigger.Message = tr(key, rule.Symbol, dif[n-1], dea[n-1])
+			return trigger, true
+		}
+	case "drawdown":
+		// Fire when the drop from the peak close since entry reaches the
+		// threshold, not on every bar it stays there
+		peak, prevPeak := 0.0, 0.0
+		for i, b := range bars {
+			if b.Date < rule.EntryDate {
+				continue
+			}
+			peak = math.Max(peak, b.Close)
+			if i < n-1 {
+				prevPeak = peak
+			}
+		}
+		if peak <= 0 {
+			return AlertTrigger{}, false
+		}
+		drawdown := (peak - last.Close) / peak * 100
+		prevDrawdown := 0.0
+		if prevPeak > 0 {
+			prevDrawdown = (prevPeak - prev.Close) / prevPeak * 100
+		}
+		if drawdown >= rule.Threshold && prevDrawdown < rule.Threshold {
+			trigger.Value = drawdown
+			trigger.Message = tr("alert.drawdown", rule.Symbol, drawdown, peak, last.Close)
+			return trigger, true
+		}
+	case "expression":
+		// Fire when the condition becomes true, not on every bar it holds
+		formula, err := compileFormula(rule.Expression)
+		if err != nil {
+			return AlertTrigger{}, false
+		}
+		ctx := newFormulaContext(bars)
+		ctx.symbol = rule.Symbol
+		values, err := formula.EvalContext(ctx)
+		if err != nil {
+			return AlertTrigger{}, false
+		}
+		if truthy(values[n-1]) && !truthy(values[n-2]) {
+			trigger.Value = last.Close
+			trigger.Message = tr("alert.expression", rule.Symbol, rule.Expression, last.Close)
+			return trigger, true
+		}
+	}
+	return AlertTrigger{}, false
+}
+
+// alertStore persists alert rules and their trigger history
+type alertStore struct {
+	mu          sync.Mutex
+	rulesPath   string
+	historyPath string
+	loaded      bool
+	rules       []*AlertRule
+	triggers    []AlertTrigger
+}
+
+func newAlertStore(dataDir string) *alertStore {
+	return &alertStore{
+		rulesPath:   filepath.Join(dataDir, "alert_rules.json"),
+		historyPath: filepath.Join(dataDir, "alert_triggers.json"),
+	}
+}
+
+// unload forgets the rules and their history so the next use reads the file again
+func (s *alertStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules, s.triggers, s.loaded = nil, nil, false
+}
+
+// load reads rules and history from disk on first use. Callers hold s.mu.
+func (s *alertStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.rulesPath, &s.rules); err != nil {
+		return err
+	}
+	if err := loadJSON(s.historyPath, &s.triggers); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// find returns the rule with id. Callers hold s.mu.
+func (s *alertStore) find(id string) (*AlertRule, error) {
+	for _, r := range s.rules {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, codeErrorf(codeNotFound, "alert rule not found: %s", id)
+}
+
+// activeRules returns copies of the enabled rules that are not snoozed
+func (s *alertStore) activeRules(now time.Time) ([]AlertRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	var rules []AlertRule
+	for _, r := range s.rules {
+		if r.Enabled && !r.snoozed(now) {
+			rules = append(rules, *r)
+		}
+	}
+	return rules, nil
+}
+
+// record stores triggers, dropping any whose rule already fired on that
+// bar or was removed meanwhile, and returns the ones kept
+func (s *alertStore) record(triggers []AlertTrigger) ([]AlertTrigger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	var kept []AlertTrigger
+	now := shanghaiNow().Format(time.RFC3339)
+	for _, t := range triggers {
+		rule, err := s.find(t.RuleID)
+		if err != nil || rule.LastFired == t.Date {
+			continue
+		}
+		rule.LastFired = t.Date
+		t.Rule = *rule
+		t.ID = newID()
+		t.TriggeredAt = now
+		kept = append(kept, t)
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	s.triggers = append(s.triggers, kept...)
+	if len(s.triggers) > maxAlertTriggers {
+		s.triggers = s.triggers[len(s.triggers)-maxAlertTriggers:]
+	}
+	if err := saveJSON(s.rulesPath, s.rules); err != nil {
+		return nil, err
+	}
+	return kept, saveJSON(s.historyPath, s.triggers)
+}
+
+// evaluateAlerts checks every active rule against fresh bars, records
+// the ones that fire and notifies the user unless the rule is muted. Each
+// rule fires at most once per bar.
+func (a *App) evaluateAlerts() ([]AlertTrigger, error) {
+	now := shanghaiNow()
+	rules, err := a.alerts.activeRules(now)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make(map[string][]Bar)
+	var triggers []AlertTrigger
+	var held map[string]monitoredPosition
+	for _, rule := range rules {
+		start := now.AddDate(0, 0, -alertLookbackDays)
+		if rule.Type == "drawdown" {
+			// Drawdown rules watch a position only while it is held
+			if held == nil {
+				if held, err = a.heldPositions(); err != nil {
+					return nil, err
+				}
+			}
+			if _, ok := held[providers.SohuCode(rule.Symbol)]; !ok {
+				continue
+			}
+			if entry, err := time.ParseInLocation("2006-01-02", rule.EntryDate, now.Location()); err == nil && entry.Before(start) {
+				start = entry
+			}
+		}
+		series, ok := bars[rule.Symbol]
+		if !ok || rule.Type == "drawdown" && len(series) > 0 && series[0].Date > start.Format("2006-01-02") {
+			series, err = fetchDailyBars(rule.Symbol, start, now)
+			if err != nil {
+				continue
+			}
+			bars[rule.Symbol] = series
+		}
+		if n := len(series); n == 0 || series[n-1].Date == rule.LastFired {
+			continue
+		}
+		if trigger, ok := evaluateAlertRule(rule, series); ok {
+			triggers = append(triggers, trigger)
+		}
+	}
+	metrics.observeAlerts(len(rules), len(triggers))
+	kept, err := a.alerts.record(triggers)
+	if err != nil {
+		return nil, err
+	}
+	var loud []AlertTrigger
+	for _, t := range kept {
+		if !t.Rule.Muted {
+			loud = append(loud, t)
+		}
+	}
+	a.notifyAlerts(loud)
+	a.dispatchAlerts(loud)
+	return kept, nil
+}
+
+// runAlertLoop evaluates alerts at the interval set in the settings until
+// ctx ends
+func (a *App) runAlertLoop(ctx context.Context) {
+	interval := currentSettings().alertInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var evaluated time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Bars only change while the market trades; evaluate once more
+			// after each close so the closing bar is seen
+			now := shanghaiNow()
+			if phaseOpen(marketPhase(now)) || evaluated.Before(lastClose(now)) {
+				a.evaluateAlerts()
+				evaluated = now
+			}
+			// Pick up a changed interval without a restart
+			if next := currentSettings().alertInterval(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// ListAlertRules returns every alert rule
+func (a *App) ListAlertRules() (string, error) {
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	if s.rules == nil {
+		return "[]", nil
+	}
+	return toJSON(s.rules)
+}
+
+// AddAlertRule creates an enabled rule and returns it with its ID
+func (a *App) AddAlertRule(ruleJSON string) (string, error) {
+	var rule AlertRule
+	if err := json.Unmarshal([]byte(ruleJSON), &rule); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse alert rule: %v", err)
+	}
+	if err := rule.validate(); err != nil {
+		return "", err
+	}
+	rule.ID = newID()
+	rule.Enabled = true
+	rule.CreatedAt = shanghaiNow().Format(time.RFC3339)
+	rule.LastFired = ""
+	if rule.Type == "drawdown" && rule.EntryDate == "" {
+		held, err := a.heldPositions()
+		if err != nil {
+			return "", err
+		}
+		pos, ok := held[providers.SohuCode(rule.Symbol)]
+		if !ok {
+			return "", codeErrorf(codeNotFound, "no position in %s", rule.Symbol)
+		}
+		rule.EntryDate = positionEntryDate(pos)
+	}
+
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	s.rules = append(s.rules, &rule)
+	if err := saveJSON(s.rulesPath, s.rules); err != nil {
+		return "", err
+	}
+	return toJSON(rule)
+}
+
+// SetAlertRuleEnabled turns a rule on or off
+func (a *App) SetAlertRuleEnabled(id string, enabled bool) error {
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	rule, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	rule.Enabled = enabled
+	return saveJSON(s.rulesPath, s.rules)
+}
+
+// DeleteAlertRule removes a rule. Its past triggers are kept.
+func (a *App) DeleteAlertRule(id string) error {
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i, r := range s.rules {
+		if r.ID == id {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			return saveJSON(s.rulesPath, s.rules)
+		}
+	}
+	return codeErrorf(codeNotFound, "alert rule not found: %s", id)
+}
+
+// CheckAlerts evaluates every active rule now and returns the new triggers
+func (a *App) CheckAlerts() (string, error) {
+	triggers, err := a.evaluateAlerts()
+	if err != nil {
+		return "", err
+	}
+	if triggers == nil {
+		triggers = []AlertTrigger{}
+	}
+	return toJSON(triggers)
+}
+
+// GetAlertTriggers returns the trigger history, newest first. With
+// pendingOnly set only unacknowledged triggers are returned.
+func (a *App) GetAlertTriggers(pendingOnly bool) (string, error) {
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	out := []AlertTrigger{}
+	for i := len(s.triggers) - 1; i >= 0; i-- {
+		if !pendingOnly || s.triggers[i].Acknowledged == "" {
+			out = append(out, s.triggers[i])
+		}
+	}
+	return toJSON(out)
+}
+
+// AcknowledgeAlert marks a trigger as seen. An empty id acknowledges
+// every pending trigger.
+func (a *App) AcknowledgeAlert(id string) error {
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	now := shanghaiNow().Format(time.RFC3339)
+	found := false
+	for i := range s.triggers {
+		t := &s.triggers[i]
+		if id != "" && t.ID != id {
+			continue
+		}
+		found = true
+		if t.Acknowledged == "" {
+			t.Acknowledged = now
+		}
+	}
+	if id != "" && !found {
+		return codeErrorf(codeNotFound, "alert trigger not found: %s", id)
+	}
+	return saveJSON(s.historyPath, s.triggers)
+}
+
+// MuteAlertRule keeps recording a rule's triggers without notifications
+// or webhook and email delivery
+func (a *App) MuteAlertRule(id string, muted bool) error {
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	rule, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	rule.Muted = muted
+	return saveJSON(s.rulesPath, s.rules)
+}
+
+// SnoozeAlertRule stops evaluating a rule for the given number of minutes.
+// Zero minutes ends a snooze.
+func (a *App) SnoozeAlertRule(id string, minutes int) error {
+	if minutes < 0 {
+		return codeErrorf(codeParse, "snooze duration cannot be negative")
+	}
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	rule, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	rule.SnoozedUntil = ""
+	if minutes > 0 {
+		rule.SnoozedUntil = shanghaiNow().Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)
+	}
+	return saveJSON(s.rulesPath, s.rules)
+}
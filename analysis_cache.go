@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"stock-analysis/internal/providers"
+)
+
+// Indicator tables are cached by symbol, date range and a hash of the
+// indicator spec, so switching back to a recently viewed symbol skips
+// loading and recomputing. Every table remembers the data version of its
+// symbol; storing new bars bumps the version and retires the table.
+
+// IndicatorColumnSpec is one column of an indicator table: a name and the
+// formula that computes it, e.g. {"name": "MA20", "formula": "MA(CLOSE, 20)"}
+type IndicatorColumnSpec struct {
+	Name    string `json:"name"`
+	Formula string `json:"formula"`
+}
+
+// defaultIndicatorSpec is the table shown when the frontend passes no spec
+var defaultIndicatorSpec = []IndicatorColumnSpec{
+	{"MA5", "MA(CLOSE, 5)"},
+	{"MA10", "MA(CLOSE, 10)"},
+	{"MA20", "MA(CLOSE, 20)"},
+	{"DIF", "DIF"},
+	{"DEA", "DEA"},
+	{"MACD", "MACD"},
+	{"VOLUME_RATE_5D", "VOLUME_RATE_5D"},
+}
+
+// IndicatorColumn is a computed column of an indicator table
+type IndicatorColumn struct {
+	Name    string     `json:"name"`
+	Formula string     `json:"formula"`
+	Values  []*float64 `json:"values"` // null where the value is NaN or infinite
+}
+
+// IndicatorTable holds indicator series of a symbol side by side, one
+// value per date
+type IndicatorTable struct {
+	Symbol   string            `json:"symbol"`
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	SpecHash string            `json:"specHash"`
+	Dates    []string          `json:"dates"`
+	Columns  []IndicatorColumn `json:"columns"`
+}
+
+// dataVersions counts the writes of bars per symbol so cached results
+// computed over older bars can be told apart
+type dataVersions struct {
+	mu       sync.Mutex
+	versions map[string]uint64
+}
+
+// barVersions is bumped by historyStore.save
+var barVersions = &dataVersions{versions: make(map[string]uint64)}
+
+// bump marks the bars of symbol as changed
+func (v *dataVersions) bump(symbol string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.versions[providers.SohuCode(symbol)]++
+}
+
+// get returns the current version of the bars of symbol
+func (v *dataVersions) get(symbol string) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.versions[providers.SohuCode(symbol)]
+}
+
+// tableEntry is a cached indicator table and the data version it was
+// computed from
+type tableEntry struct {
+	version uint64
+	table   string
+}
+
+// analysisCache holds recently computed indicator tables
+var analysisCache = newTTLCache()
+
+// parseIndicatorSpec decodes and validates a spec, defaulting to
+// defaultIndicatorSpec when specJSON is empty
+func parseIndicatorSpec(specJSON string) ([]IndicatorColumnSpec, []*Formula, error) {
+	spec := defaultIndicatorSpec
+	if strings.TrimSpace(specJSON) != "" {
+		spec = nil
+		if err := json.Unmarshal([]byte(specJSON), &spec); err != nil {
+			return nil, nil, codeErrorf(codeParse, "invalid indicator spec: %v", err)
+		}
+		if len(spec) == 0 {
+			return nil, nil, codeErrorf(codeParse, "indicator spec has no columns")
+		}
+	}
+	formulas := make([]*Formula, len(spec))
+	for i, col := range spec {
+		if col.Name == "" {
+			return nil, nil, codeErrorf(codeParse, "indicator column %d has no name", i+1)
+		}
+		f, err := compileFormula(col.Formula)
+		if err != nil {
+			return nil, nil, codeErrorf(codeParse, "invalid formula for %s: %v", col.Name, err)
+		}
+		formulas[i] = f
+	}
+	return spec, formulas, nil
+}
+
+// specHash identifies a spec by its columns, in order
+func specHash(spec []IndicatorColumnSpec) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// nullable converts values for JSON, which has no NaN, with nil in place
+// of non-finite values
+func nullable(values []float64) []*float64 {
+	out := make([]*float64, len(values))
+	for i := range values {
+		if !math.IsNaN(values[i]) && !math.IsInf(values[i], 0) {
+			out[i] = &values[i]
+		}
+	}
+	return out
+}
+
+// indicatorTable evaluates spec over the bars of symbol
+func indicatorTable(symbol string, bars []Bar, spec []IndicatorColumnSpec, formulas []*Formula) (IndicatorTable, error) {
+	table := IndicatorTable{Symbol: symbol, SpecHash: specHash(spec), Dates: make([]string, len(bars)), Columns: []IndicatorColumn{}}
+	for i, b := range bars {
+		table.Dates[i] = b.Date
+	}
+	if len(bars) > 0 {
+		table.From, table.To = bars[0].Date, bars[len(bars)-1].Date
+	}
+	ctx := newFormulaContext(bars)
+	ctx.symbol = symbol
+	for i, f := range formulas {
+		values, err := f.EvalContext(ctx)
+		if err != nil {
+			return IndicatorTable{}, codeErrorf(codeParse, "%s: %v", spec[i].Name, err)
+		}
+		table.Columns = append(table.Columns, IndicatorColumn{Name: spec[i].Name, Formula: spec[i].Formula, Values: nullable(values)})
+	}
+	return table, nil
+}
+
+// GetIndicatorTable returns the indicator table of symbol over the last
+// days. specJSON lists the columns as IndicatorColumnSpec; empty uses the
+// standard MA, MACD and volume rate columns. days of 0 uses the
+// configured lookback and lookbackMax (-1) all available history. Tables
+// are cached until new bars of symbol are stored.
+func (a *App) GetIndicatorTable(symbol string, days int, specJSON string) (string, error) {
+	spec, formulas, err := parseIndicatorSpec(specJSON)
+	if err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	now := shanghaiNow()
+	start := lookbackStart(symbol, days, now)
+	key := fmt.Sprintf("%s|%s|%s|%s", providers.SohuCode(symbol), start.Format("2006-01-02"), now.Format("2006-01-02"), specHash(spec))
+	version := barVersions.get(symbol)
+	if v, ok := analysisCache.get(key); ok && v.(tableEntry).version == version {
+		return v.(tableEntry).table, nil
+	}
+
+	bars, err := fetchDailyBars(symbol, start, now)
+	if err != nil {
+		return "", err
+	}
+	table, err := indicatorTable(symbol, bars, spec, formulas)
+	if err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	result, err := toJSON(table)
+	if err != nil {
+		return "", err
+	}
+	// Fetching may have stored new bars; the table covers them, so it is
+	// valid from the version after the fetch
+	analysisCache.set(key, tableEntry{version: barVersions.get(symbol), table: result}, indicatorTTL)
+	return result, nil
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeyProviders are the data providers that take a token
+var apiKeyProviders = map[string]string{
+	"tushare":      "Tushare Pro",
+	"alphavantage": "Alpha Vantage",
+	"sentiment":    "Sentiment API",
+}
+
+// storedAPIKey is an encrypted provider token as persisted
+type storedAPIKey struct {
+	Sealed     string `json:"sealed"` // base64 of nonce || AES-GCM ciphertext
+	AddedAt    string `json:"addedAt"`
+	TestedAt   string `json:"testedAt,omitempty"`
+	TestResult string `json:"testResult,omitempty"` // "ok" or the error
+}
+
+// APIKeyInfo describes a stored token without revealing it
+type APIKeyInfo struct {
+	Provider   string `json:"provider"`
+	Name       string `json:"name"`
+	Masked     string `json:"masked"`
+	AddedAt    string `json:"addedAt"`
+	TestedAt   string `json:"testedAt,omitempty"`
+	TestResult string `json:"testResult,omitempty"`
+}
+
+// apiKeyStore keeps provider tokens encrypted with AES-256-GCM. The key
+// lives in a separate owner-only file that backups leave out, so tokens
+// restored on another machine have to be entered again.
+type apiKeyStore struct {
+	mu      sync.Mutex
+	path    string
+	keyPath string
+	loaded  bool
+	keys    map[string]storedAPIKey
+}
+
+func newAPIKeyStore(dataDir string) *apiKeyStore {
+	return &apiKeyStore{
+		path:    filepath.Join(dataDir, "api_keys.json"),
+		keyPath: filepath.Join(dataDir, "secret.key"),
+	}
+}
+
+// unload forgets the keys so the next use reads the file again
+func (s *apiKeyStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys, s.loaded = nil, false
+}
+
+// load reads the tokens from disk on first use. Callers hold s.mu.
+func (s *apiKeyStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.keys = make(map[string]storedAPIKey)
+	if err := loadJSON(s.path, &s.keys); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// cipher returns the AES-GCM cipher, creating the local key on first use
+func (s *apiKeyStore) cipher() (cipher.AEAD, error) {
+	key, err := os.ReadFile(s.keyPath)
+	if os.IsNotExist(err) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(s.keyPath), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(s.keyPath, key, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret key file is damaged")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a token. Callers hold s.mu.
+func (s *apiKeyStore) seal(token string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(token), nil)), nil
+}
+
+// open decrypts a sealed token. Callers hold s.mu.
+func (s *apiKeyStore) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("stored key is damaged")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("stored key cannot be decrypted, enter it again")
+	}
+	return string(plain), nil
+}
+
+// sealSecret encrypts a credential kept by another store with the same
+// local key as the API keys
+func (s *apiKeyStore) sealSecret(secret string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seal(secret)
+}
+
+// openSecret decrypts a credential sealed by sealSecret
+func (s *apiKeyStore) openSecret(sealed string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open(sealed)
+}
+
+// token returns the decrypted token of provider
+func (s *apiKeyStore) token(provider string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	stored, ok := s.keys[provider]
+	if !ok {
+		return "", codeErrorf(codeNotFound, "no API key for %s", provider)
+	}
+	return s.open(stored.Sealed)
+}
+
+// maskToken shows only the last four characters of a token
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", 8) + token[len(token)-4:]
+}
+
+// testAPIKey makes a cheap authenticated request to provider
+func testAPIKey(provider, token string) error {
+	// Straight to the connection pool: fixtures and validators would write
+	// the token in the URL to disk
+	client := &http.Client{Transport: providerTransport, Timeout: 15 * time.Second}
+	switch provider {
+	case "tushare":
+		body, _ := json.Marshal(map[string]interface{}{
+			"api_name": "trade_cal",
+			"token":    token,
+			"params":   map[string]string{"exchange": "SSE", "start_date": "20240102", "end_date": "20240102"},
+		})
+		resp, err := client.Post("http://api.tushare.pro", "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		providerUsage.record("tushare", resp.StatusCode, 0)
+		var result struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return codeErrorf(codeParse, "failed to parse JSON: %v", err)
+		}
+		if result.Code != 0 {
+			return fmt.Errorf("tushare: %s", result.Msg)
+		}
+		return nil
+	case "alphavantage":
+		resp, err := client.Get("https://www.alphavantage.co/query?function=GLOBAL_QUOTE&symbol=IBM&apikey=" + url.QueryEscape(token))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		providerUsage.record("alphavantage", resp.StatusCode, len(data))
+		var result map[string]interface{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return codeErrorf(codeParse, "failed to parse JSON: %v", err)
+		}
+		if _, ok := result["Global Quote"]; ok {
+			return nil
+		}
+		for _, field := range []string{"Error Message", "Information", "Note"} {
+			if msg, ok := result[field].(string); ok {
+				return fmt.Errorf("alphavantage: %s", msg)
+			}
+		}
+		return fmt.Errorf("alphavantage: unexpected response")
+	case "sentiment":
+		settings := currentSettings().Sentiment
+		if settings.Scorer != sentimentAPI {
+			return fmt.Errorf("the sentiment scorer is not set to an API")
+		}
+		_, err := callSentimentAPI(settings.URL, token, []string{"test"})
+		return err
+	}
+	return codeErrorf(codeParse, "unknown provider: %s", provider)
+}
+
+// ListAPIKeys returns the stored provider tokens, masked
+func (a *App) ListAPIKeys() (string, error) {
+	s := a.apiKeys
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	out := []APIKeyInfo{}
+	for provider, stored := range s.keys {
+		info := APIKeyInfo{
+			Provider:   provider,
+			Name:       apiKeyProviders[provider],
+			AddedAt:    stored.AddedAt,
+			TestedAt:   stored.TestedAt,
+			TestResult: stored.TestResult,
+		}
+		if token, err := s.open(stored.Sealed); err == nil {
+			info.Masked = maskToken(token)
+		} else {
+			info.TestResult = err.Error()
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return toJSON(out)
+}
+
+// SetAPIKey encrypts and stores the token of provider ("tushare" or
+// "alphavantage"), replacing an earlier one
+func (a *App) SetAPIKey(provider string, token string) error {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	token = strings.TrimSpace(token)
+	if _, ok := apiKeyProviders[provider]; !ok {
+		return codeErrorf(codeParse, "unknown provider: %s", provider)
+	}
+	if token == "" {
+		return codeErrorf(codeParse, "API key is required")
+	}
+
+	s := a.apiKeys
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	sealed, err := s.seal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt API key: %v", err)
+	}
+	s.keys[provider] = storedAPIKey{Sealed: sealed, AddedAt: shanghaiNow().Format(time.RFC3339)}
+	err = saveJSON(s.path, s.keys)
+	recordAudit(auditSettings, "set API key", provider, "", err)
+	return err
+}
+
+// RemoveAPIKey deletes the token of provider
+func (a *App) RemoveAPIKey(provider string) error {
+	s := a.apiKeys
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.keys[provider]; !ok {
+		return codeErrorf(codeNotFound, "no API key for %s", provider)
+	}
+	delete(s.keys, provider)
+	err := saveJSON(s.path, s.keys)
+	recordAudit(auditSettings, "remove API key", provider, "", err)
+	return err
+}
+
+// TestAPIKey checks the stored token of provider against the provider and
+// records the outcome
+func (a *App) TestAPIKey(provider string) error {
+	token, err := a.apiKeys.token(provider)
+	if err != nil {
+		return err
+	}
+	testErr := testAPIKey(provider, token)
+
+	s := a.apiKeys
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.keys[provider]; ok {
+		stored.TestedAt = shanghaiNow().Format(time.RFC3339)
+		stored.TestResult = "ok"
+		if testErr != nil {
+			stored.TestResult = testErr.Error()
+		}
+		s.keys[provider] = stored
+		if err := saveJSON(s.path, s.keys); err != nil {
+			return err
+		}
+	}
+	return testErr
+}
@@ -5,25 +5,149 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"strconv"
+	"sync"
 	"time"
+
+	"stock-analysis/internal/providers"
 )
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx        context.Context
+	profile    string // id of the profile in use
+	dataDir    string
+	paper      *paperStore
+	exitRules  *exitRuleStore
+	strategies *strategyStore
+	portfolio  *portfolioStore
+	fx         *fxStore
+	watchlists *watchlistStore
+	notes      *noteStore
+	symbols    *symbolStore
+	metadata   *metadataStore
+	alerts     *alertStore
+	cloud      *cloudStore
+	webhooks   *webhookStore
+	smtp       *smtpStore
+	summaries  *summaryStore
+	syncs      *syncStore
+	snapshots  *snapshotStore
+	settings   *settingsStore
+	apiKeys    *apiKeyStore
+	session    *sessionStore
+	recent     *recentStore
+	jobs       *jobRegistry
+	push       *pushHub
+	rpc        *grpcHost
+	updates    *updater
+
+	stateMu      sync.Mutex         // guards migrationErr and stopLoops
+	migrationErr error              // outcome of the startup data migration
+	stopLoops    context.CancelFunc // stops the background loops; nil while they are stopped
+	loops        sync.WaitGroup
+}
+
+// NewApp creates a new App application struct using the profile named
+// by profile, or the startup profile if it is empty
+func NewApp(profile string) *App {
+	p, err := profiles.resolve(profile)
+	a := &App{profile: p.ID, dataDir: profiles.dir(p.ID), jobs: newJobRegistry(), push: newPushHub(), rpc: &grpcHost{}, updates: &updater{}}
+	initLogging(a.dataDir)
+	if err != nil {
+		logger.Warn("failed to select profile", "profile", profile, "error", err)
+	}
+	logger.Info("using profile", "profile", p.ID, "name", p.Name)
+	a.openStores()
+	return a
+}
+
+// openStores points every store at the files in the data directory. The
+// stores load lazily, so this is cheap. NewApp calls it once; a restore
+// keeps the stores and reloads them with reloadStores.
+func (a *App) openStores() {
+	dataDir := a.dataDir
+	localHistory = newHistoryStore(dataDir)
+	a.settings = newSettingsStore(dataDir)
+	appSettings = a.settings
+	a.paper = newPaperStore(dataDir)
+	a.exitRules = newExitRuleStore(dataDir)
+	a.strategies = newStrategyStore(dataDir)
+	a.portfolio = newPortfolioStore(dataDir)
+	a.fx = newFXStore(dataDir)
+	a.watchlists = newWatchlistStore(dataDir)
+	a.notes = newNoteStore(dataDir)
+	a.symbols = newSymbolStore(dataDir)
+	a.metadata = newMetadataStore(dataDir)
+	a.alerts = newAlertStore(dataDir)
+	a.cloud = newCloudStore(dataDir)
+	a.webhooks = newWebhookStore(dataDir)
+	a.smtp = newSMTPStore(dataDir)
+	a.summaries = newSummaryStore(dataDir)
+	a.syncs = newSyncStore(dataDir)
+	a.snapshots = newSnapshotStore(dataDir)
+	a.apiKeys = newAPIKeyStore(dataDir)
+	a.session = newSessionStore(dataDir)
+	a.recent = newRecentStore(dataDir)
+	providerUsage = newUsageStore(dataDir)
+	crashes = newCrashStore(dataDir)
+	holidays = newHolidayStore(dataDir)
+	validators.open(dataDir)
+	vault.open(dataDir)
 }
 
-// NewApp creates a new App application struct
-func NewApp() *App {
-	return &App{}
+// reloadStores makes the stores read their files again, as after a
+// restore. The stores stay the same objects, so goroutines holding one
+// and the callbacks set on them carry on.
+func (a *App) reloadStores() {
+	stores := []interface{ unload() }{
+		a.settings, a.paper, a.exitRules, a.portfolio, a.fx, a.watchlists,
+		a.notes, a.symbols, a.metadata, a.alerts, a.webhooks, a.smtp,
+		a.summaries, a.syncs, a.snapshots, a.apiKeys, a.session, a.recent,
+		a.cloud, providerUsage, holidays,
+	}
+	for _, s := range stores {
+		s.unload()
+	}
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	// Migrate only once the single-instance lock is held. The stores load
+	// lazily, so none has read a file yet. Encrypted data waits for Unlock.
+	if !vault.locked() {
+		a.migrate()
+	}
+	a.markStarted()
+	a.watchNetworkStatus()
+	a.watchCooldowns()
+	a.watchJobs()
+	a.watchCrashes()
+	a.resumeLoops()
+}
+
+// startLoops starts the pollers and schedulers under a context that
+// stopLoops cancels
+func (a *App) startLoops() {
+	loopCtx, stop := context.WithCancel(a.ctx)
+	a.stateMu.Lock()
+	a.stopLoops = stop
+	a.stateMu.Unlock()
+	a.goLoop(loopCtx, a.runAlertLoop)
+	a.goLoop(loopCtx, a.runSyncLoop)
+	a.goLoop(loopCtx, a.runDailySummaryLoop)
+	a.goLoop(loopCtx, a.runSettingsWatcher)
+	a.goLoop(loopCtx, a.runTrayLoop)
+	a.goLoop(loopCtx, a.runPushServer)
+	a.goLoop(loopCtx, a.runGRPCServer)
+	a.goLoop(loopCtx, a.runUsageFlusher)
+	a.goLoop(loopCtx, a.runUpdateLoop)
+	a.goLoop(loopCtx, a.runArchiveLoop)
+	a.goLoop(loopCtx, a.runAutoLockLoop)
+	a.goLoop(loopCtx, a.runCloudSyncLoop)
+	a.goLoop(loopCtx, a.runHolidayLoop)
 }
 
 // Greet returns a greeting for the given name
@@ -33,32 +157,44 @@ func (a *App) Greet(name string) string {
 
 // GetStockData returns stock data
 func (a *App) GetStockData() (string, error) {
+	return a.GetStockDataWindow(0)
+}
+
+// GetStockDataWindow returns stock data for the last days calendar days.
+// 0 uses the configured lookback and lookbackMax (-1) all available history.
+func (a *App) GetStockDataWindow(days int) (string, error) {
 	// Get current date in China Standard Time (Shanghai)
-	loc, err := time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		loc = time.Local // fallback to local time
-	}
-	now := time.Now().In(loc)
+	now := shanghaiNow()
 
-	// Calculate 180 days ago
-	startDate := now.AddDate(0, 0, -180)
+	// The overview index and window come from the settings
+	settings := currentSettings()
+	symbol := settings.DefaultSymbols[0]
+	startDate := lookbackStart(symbol, days, now)
 
 	// Format dates
 	startDateStr := startDate.Format("20060102")
 	endDateStr := now.Format("20060102")
 
 	// Build URL
-	url := fmt.Sprintf("https://q.stock.sohu.com/hisHq?code=zs_000001&start=%s&end=%s&stat=1&order=D&period=d",
-		startDateStr, endDateStr)
+	url := fmt.Sprintf("https://q.stock.sohu.com/hisHq?code=%s&start=%s&end=%s&stat=1&order=D&period=d",
+		providers.SohuCode(symbol), startDateStr, endDateStr)
 
-	// Debug: Print the dates being used
-	fmt.Printf("Requesting data from %s to %s\n", startDateStr, endDateStr)
-	fmt.Printf("Current time: %v, Start date: %v\n", now, startDate)
+	logger.Debug("requesting index data", "symbol", symbol, "start", startDateStr, "end", endDateStr)
+
+	// Serve the stored index history when offline
+	if !connectivity.allow() {
+		return offlineStockData(symbol, startDate, now, errOffline)
+	}
+	if err := cooldowns.check(providerSohu); err != nil {
+		return offlineStockData(symbol, startDate, now, err)
+	}
 
 	// Make HTTP request
-	resp, err := http.Get(url)
+	started := time.Now()
+	resp, err := providerClient.Get(url)
+	connectivity.report(err)
 	if err != nil {
-		return "", err
+		return offlineStockData(symbol, startDate, now, err)
 	}
 	defer resp.Body.Close()
 
@@ -67,6 +203,15 @@ func (a *App) GetStockData() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	logProviderResponse(resp, body, started)
+	if err := checkProviderResponse(providerSohu, resp, body); err != nil {
+		return offlineStockData(symbol, startDate, now, err)
+	}
+
+	// Keep the bars so the index can be served offline
+	if bars, err := providers.ParseSohuBars(body); err == nil {
+		localHistory.save(symbol, bars)
+	}
 
 	return string(body), nil
 }
@@ -86,11 +231,11 @@ func (a *App) CalculateFiveDayRate(data string) (string, error) {
 	var stockData []map[string]interface{}
 	err := json.Unmarshal([]byte(data), &stockData)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %v", err)
+		return "", codeErrorf(codeParse, "failed to parse JSON: %v", err)
 	}
 
 	if len(stockData) == 0 {
-		return "", fmt.Errorf("no stock data available")
+		return "", codeErrorf(codeNoData, "no stock data available")
 	}
 
 	// Get hq data - handle different types
@@ -99,11 +244,11 @@ func (a *App) CalculateFiveDayRate(data string) (string, error) {
 	case []interface{}:
 		hqData = v
 	default:
-		return "", fmt.Errorf("unexpected hq data type: %T", v)
+		return "", codeErrorf(codeParse, "unexpected hq data type: %T", v)
 	}
 
 	if len(hqData) == 0 {
-		return "", fmt.Errorf("no hq data available")
+		return "", codeErrorf(codeNoData, "no hq data available")
 	}
 
 	// Create a slice to hold the extracted data with proper structure
@@ -165,23 +310,15 @@ func (a *App) CalculateFiveDayRate(data string) (string, error) {
 
 		if i >= 5 {
 			prev := dailyData[i-5]
-
-			// Debug: Print calculation details
-			fmt.Printf("计算第%d天的变动率: 当前日期=%s, 5天前日期=%s\n", i, current.Date, prev.Date)
-			fmt.Printf("计算第%d天的变动率: 当前成交量=%f, 5天前成交量=%f\n", i, current.Volume, prev.Volume)
-			fmt.Printf("计算第%d天的变动率: 当前成交额=%f, 5天前成交额=%f\n", i, current.Turnover, prev.Turnover)
-
 			if prev.Volume != 0 {
 				volumeRate = (current.Volume - prev.Volume) / prev.Volume * 100
-				fmt.Printf("成交量变动率: (%f - %f) / %f * 100 = %f%%\n", current.Volume, prev.Volume, prev.Volume, volumeRate)
 			}
 			if prev.Turnover != 0 {
 				turnoverRate = (current.Turnover - prev.Turnover) / prev.Turnover * 100
-				fmt.Printf("成交额变动率: (%f - %f) / %f * 100 = %f%%\n", current.Turnover, prev.Turnover, prev.Turnover, turnoverRate)
 			}
-		} else {
-			// Debug: Print for first 5 days
-			fmt.Printf("第%d天: 数据不足5天，变动率为0\n", i)
+			logger.Debug("five-day rate", "date", current.Date, "baseDate", prev.Date,
+				"volume", current.Volume, "baseVolume", prev.Volume, "volumeRate", volumeRate,
+				"turnover", current.Turnover, "baseTurnover", prev.Turnover, "turnoverRate", turnoverRate)
 		}
 
 		stockItem := StockData{
@@ -205,10 +342,16 @@ func (a *App) CalculateFiveDayRate(data string) (string, error) {
 
 // GetStockAnalysis returns complete stock analysis
 func (a *App) GetStockAnalysis() (string, error) {
+	settings := currentSettings()
+	return cachedJSON("stockAnalysis:"+settings.DefaultSymbols[0], settings.analysisTTL(), a.stockAnalysis)
+}
+
+// stockAnalysis computes the analysis GetStockAnalysis caches
+func (a *App) stockAnalysis() (string, error) {
 	// Get stock data
 	stockData, err := a.GetStockData()
 	if err != nil {
-		return "", fmt.Errorf("failed to get stock data: %v", err)
+		return "", fmt.Errorf("failed to get stock data: %w", err)
 	}
 
 	// Calculate 5-day rates
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// archiveDelay is when the first archive pass over the history store runs
+// after startup; later passes run every archiveInterval
+const (
+	archiveDelay    = 10 * time.Minute
+	archiveInterval = 24 * time.Hour
+)
+
+// runArchiveLoop archives cold history shortly after startup and then
+// every archiveInterval
+func (a *App) runArchiveLoop(ctx context.Context) {
+	timer := time.NewTimer(archiveDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			result, err := localHistory.Archive(ctx, shanghaiNow())
+			if err != nil && ctx.Err() == nil {
+				logger.Warn("history archive failed", "error", err)
+			} else if result.Bars > 0 {
+				logger.Info("archived cold history", "blocks", result.Blocks, "bars", result.Bars,
+					"bytesBefore", result.BytesBefore, "bytesAfter", result.BytesAfter)
+			}
+			timer.Reset(archiveInterval)
+		}
+	}
+}
+
+// ArchiveHistory compresses the stored bars of past years now instead of
+// waiting for the daily pass
+func (a *App) ArchiveHistory() (string, error) {
+	result, err := localHistory.Archive(context.Background(), shanghaiNow())
+	if err != nil {
+		return "", err
+	}
+	return toJSON(result)
+}
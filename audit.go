@@ -0,0 +1,181 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// auditSchema creates the audit log. Triggers refuse updates and deletes,
+// so entries can only be added. Entries name what was changed but never
+// amounts, prices or tokens: the history database is not encrypted.
+const auditSchema = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	at       TEXT NOT NULL,
+	category TEXT NOT NULL,
+	action   TEXT NOT NULL,
+	target   TEXT NOT NULL,
+	detail   TEXT NOT NULL,
+	error    TEXT NOT NULL -- empty if the operation succeeded
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_category ON audit_log (category, id);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'the audit log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'the audit log is append-only');
+END;
+`
+
+// Audit categories
+const (
+	auditImport    = "import"
+	auditExport    = "export"
+	auditPortfolio = "portfolio"
+	auditSettings  = "settings"
+)
+
+const (
+	// defaultAuditPage is how many entries GetAuditLog returns by default
+	defaultAuditPage = 100
+	// maxAuditPage is the most entries GetAuditLog returns at once
+	maxAuditPage = 1000
+)
+
+// AuditEntry is one recorded data operation
+type AuditEntry struct {
+	ID       int64  `json:"id"`
+	At       string `json:"at"`
+	Category string `json:"category"`
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// AuditPage is a page of the audit log, newest first
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextBefore int64        `json:"nextBefore,omitempty"` // pass as before for the next page; 0 at the end
+}
+
+// recordAudit appends an entry for an operation that ended with err.
+// Failing to record is logged, never returned: the operation happened.
+func recordAudit(category, action, target, detail string, err error) {
+	entry := AuditEntry{At: shanghaiNow().Format(time.RFC3339), Category: category, Action: action, Target: target, Detail: detail}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if localHistory == nil {
+		return
+	}
+	db, openErr := localHistory.Open()
+	if openErr == nil {
+		_, openErr = db.Exec(`INSERT INTO audit_log (at, category, action, target, detail, error) VALUES (?, ?, ?, ?, ?, ?)`,
+			entry.At, entry.Category, entry.Action, entry.Target, entry.Detail, entry.Error)
+	}
+	if openErr != nil {
+		logger.Warn("failed to record audit entry", "category", category, "action", action, "error", openErr)
+	}
+}
+
+// changedKeys lists the top-level JSON fields that differ between before
+// and after, for recording which settings changed without their values
+func changedKeys(before, after interface{}) string {
+	var a, b map[string]json.RawMessage
+	data, _ := json.Marshal(before)
+	json.Unmarshal(data, &a)
+	data, _ = json.Marshal(after)
+	json.Unmarshal(data, &b)
+	var keys []string
+	for k, v := range b {
+		if string(a[k]) != string(v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// auditEntries reads a page of the log. category "" reads every category.
+func auditEntries(db *sql.DB, category string, limit int, before int64) (AuditPage, error) {
+	query := `SELECT id, at, category, action, target, detail, error FROM audit_log WHERE id < ?`
+	args := []interface{}{before}
+	if before <= 0 {
+		args[0] = int64(1) << 62
+	}
+	if category != "" {
+		query += ` AND category = ?`
+		args = append(args, category)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return AuditPage{}, err
+	}
+	defer rows.Close()
+	page := AuditPage{Entries: []AuditEntry{}}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.At, &e.Category, &e.Action, &e.Target, &e.Detail, &e.Error); err != nil {
+			return AuditPage{}, err
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return AuditPage{}, err
+	}
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		page.NextBefore = page.Entries[limit-1].ID
+	}
+	return page, nil
+}
+
+// GetAuditLog returns the recorded imports, exports, portfolio edits and
+// settings changes, newest first. category is "import", "export",
+// "portfolio", "settings" or "" for all; limit 0 returns 100 entries.
+// before pages back from an earlier page's nextBefore.
+func (a *App) GetAuditLog(category string, limit int, before int64) (string, error) {
+	switch category {
+	case "", auditImport, auditExport, auditPortfolio, auditSettings:
+	default:
+		return "", codeErrorf(codeParse, "unknown audit category: %s", category)
+	}
+	if limit <= 0 {
+		limit = defaultAuditPage
+	}
+	limit = min(limit, maxAuditPage)
+	db, err := localHistory.Open()
+	if err != nil {
+		return "", err
+	}
+	page, err := auditEntries(db, category, limit, before)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(page)
+}
+
+// activePortfolio returns the ID of the portfolio the portfolio calls
+// apply to, as the target of their audit entries
+func (a *App) activePortfolio() string {
+	if p, err := a.portfolio.snapshot(); err == nil {
+		return p.ID
+	}
+	return ""
+}
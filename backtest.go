@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"stock-analysis/internal/indicators"
+)
+
+// BacktestConfig configures a single-symbol long-only backtest
+type BacktestConfig struct {
+	InitialCash float64      `json:"initialCash"`
+	Entry       EntrySignal  `json:"entry"`
+	Sizing      SizingParams `json:"sizing"`
+	Exits       []ExitRule   `json:"exits"`
+}
+
+// EntrySignal selects the built-in entry condition. "maCross" enters when
+// the Fast SMA of the close crosses above the Slow SMA.
+type EntrySignal struct {
+	Type string `json:"type"`
+	Fast int    `json:"fast"`
+	Slow int    `json:"slow"`
+}
+
+// BacktestTrade is a completed round trip
+type BacktestTrade struct {
+	EntryDate  string  `json:"entryDate"`
+	EntryPrice float64 `json:"entryPrice"`
+	ExitDate   string  `json:"exitDate"`
+	ExitPrice  float64 `json:"exitPrice"`
+	Shares     int     `json:"shares"`
+	PnL        float64 `json:"pnl"`
+	ReturnPct  float64 `json:"returnPct"`
+	ExitReason string  `json:"exitReason"`
+}
+
+// EquityPoint is the account value at the close of a bar
+type EquityPoint struct {
+	Date   string  `json:"date"`
+	Equity float64 `json:"equity"`
+}
+
+// BacktestResult summarizes a backtest run
+type BacktestResult struct {
+	Trades         []BacktestTrade `json:"trades"`
+	Equity         []EquityPoint   `json:"equity"`
+	FinalEquity    float64         `json:"finalEquity"`
+	TotalReturnPct float64         `json:"totalReturnPct"`
+	MaxDrawdownPct float64         `json:"maxDrawdownPct"`
+	WinRate        float64         `json:"winRate"`
+}
+
+// entryFunc reports whether to enter at the close of bar i
+type entryFunc func(i int) bool
+
+// maCrossEntry builds the entry function for a moving average crossover
+func maCrossEntry(bars []Bar, fast, slow int) entryFunc {
+	if fast <= 0 {
+		fast = 5
+	}
+	if slow <= 0 {
+		slow = 20
+	}
+	closeSeries := closes(bars)
+	fastMA := indicators.SMA(closeSeries, fast)
+	slowMA := indicators.SMA(closeSeries, slow)
+	return func(i int) bool {
+		if i == 0 || math.IsNaN(slowMA[i-1]) || math.IsNaN(fastMA[i-1]) {
+			return false
+		}
+		return fastMA[i-1] <= slowMA[i-1] && fastMA[i] > slowMA[i]
+	}
+}
+
+// runBacktest simulates entering at the close when entry fires and
+// exiting when one of the exit rules triggers or, if given, exit fires at
+// the close. Positions still open on the last bar are closed at its close.
+// Entries are sized in quantities symbol can be bought in. progress, if
+// not nil, is told about every simulated bar.
+func runBacktest(symbol string, bars []Bar, cfg BacktestConfig, entry, exit entryFunc, progress *progressReporter) (BacktestResult, error) {
+	if cfg.InitialCash <= 0 {
+		cfg.InitialCash = paperDefaultCash
+	}
+	if err := validateExitRules(cfg.Exits); err != nil {
+		return BacktestResult{}, err
+	}
+
+	cash := cfg.InitialCash
+	sizingATR := ATR(bars, cfg.Sizing.withDefaults().ATRPeriod)
+	exitATRs := exitATRSeries(cfg.Exits, bars)
+
+	result := BacktestResult{Trades: []BacktestTrade{}, Equity: []EquityPoint{}}
+	var open *BacktestTrade
+	var state exitState
+
+	closeTrade := func(date string, price float64, reason string) {
+		proceeds := price * float64(open.Shares)
+		cash += proceeds - paperFees("sell", proceeds)
+		open.ExitDate = date
+		open.ExitPrice = price
+		open.ExitReason = reason
+		open.PnL = (price-open.EntryPrice)*float64(open.Shares) - paperFees("buy", open.EntryPrice*float64(open.Shares)) - paperFees("sell", proceeds)
+		open.ReturnPct = (price - open.EntryPrice) / open.EntryPrice * 100
+		result.Trades = append(result.Trades, *open)
+		open = nil
+	}
+
+	for i, bar := range bars {
+		progress.step(i+1, "simulate", "")
+		if open != nil {
+			state.BarsHeld++
+			if signal, hit := checkExits(cfg.Exits, state, bar, atrsAt(exitATRs, i-1)); hit {
+				closeTrade(bar.Date, signal.Price, signal.Rule.Type)
+			} else if exit != nil && exit(i) {
+				closeTrade(bar.Date, bar.Close, "exitSignal")
+			} else {
+				state.HighestClose = math.Max(state.HighestClose, bar.Close)
+			}
+		}
+
+		if open == nil && entry(i) {
+			sized, err := cfg.Sizing.size(symbol, cash, bar.Close, sizingATR[i])
+			if err == nil && sized.Shares > 0 {
+				cost := bar.Close * float64(sized.Shares)
+				cash -= cost + paperFees("buy", cost)
+				open = &BacktestTrade{EntryDate: bar.Date, EntryPrice: bar.Close, Shares: sized.Shares}
+				state = exitState{EntryPrice: bar.Close, HighestClose: bar.Close}
+			}
+		}
+
+		equity := cash
+		if open != nil {
+			equity += bar.Close * float64(open.Shares)
+		}
+		result.Equity = append(result.Equity, EquityPoint{Date: bar.Date, Equity: equity})
+	}
+
+	if open != nil && len(bars) > 0 {
+		last := bars[len(bars)-1]
+		closeTrade(last.Date, last.Close, "endOfData")
+		result.Equity[len(result.Equity)-1].Equity = cash
+	}
+
+	result.FinalEquity = cash
+	result.TotalReturnPct = (cash - cfg.InitialCash) / cfg.InitialCash * 100
+	result.MaxDrawdownPct = maxDrawdownPct(result.Equity)
+	wins := 0
+	for _, t := range result.Trades {
+		if t.PnL > 0 {
+			wins++
+		}
+	}
+	if len(result.Trades) > 0 {
+		result.WinRate = float64(wins) / float64(len(result.Trades)) * 100
+	}
+	return result, nil
+}
+
+// maxDrawdownPct returns the largest peak-to-trough decline of an equity curve
+func maxDrawdownPct(curve []EquityPoint) float64 {
+	peak, maxDD := 0.0, 0.0
+	for _, p := range curve {
+		peak = math.Max(peak, p.Equity)
+		if peak > 0 {
+			maxDD = math.Max(maxDD, (peak-p.Equity)/peak*100)
+		}
+	}
+	return maxDD
+}
+
+// RunBacktest backtests configJSON (a BacktestConfig) on the last days
+// calendar days of symbol, or on all available history if days is
+// lookbackMax (-1)
+func (a *App) RunBacktest(symbol string, days int, configJSON string) (string, error) {
+	label, fn, err := a.backtestJob(symbol, days, configJSON)
+	if err != nil {
+		return "", err
+	}
+	a.recent.touch(symbol)
+	return a.jobs.run("backtest", label, fn)
+}
+
+// backtestJob validates the parameters of RunBacktest and returns the job
+// that runs it
+func (a *App) backtestJob(symbol string, days int, configJSON string) (string, jobFunc, error) {
+	var cfg BacktestConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return "", nil, codeErrorf(codeParse, "failed to parse backtest config: %v", err)
+	}
+	switch cfg.Entry.Type {
+	case "", "maCross":
+	default:
+		return "", nil, codeErrorf(codeParse, "unknown entry signal: %s", cfg.Entry.Type)
+	}
+	if days == 0 || days < lookbackMax {
+		days = 365
+	}
+
+	return symbol, func(ctx context.Context, job Job) (string, error) {
+		progress := a.newProgress(eventBacktestProgress, job, 0)
+		progress.step(0, "fetch", symbol)
+		now := shanghaiNow()
+		bars, err := fetchDailyBarsContext(ctx, symbol, lookbackStart(symbol, days, now), now)
+		if err != nil {
+			return "", fmt.Errorf("failed to get stock data: %w", canceled(ctx, err))
+		}
+		progress.current.Total = len(bars)
+
+		entry := maCrossEntry(bars, cfg.Entry.Fast, cfg.Entry.Slow)
+		result, err := runBacktest(symbol, bars, cfg, entry, nil, progress)
+		if err != nil {
+			return "", err
+		}
+		return toJSON(result)
+	}, nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	backupApp          = "stock-analysis"
+	backupManifestName = "manifest.json"
+	historyDBName      = "history.db"
+)
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	App       string   `json:"app"`
+	CreatedAt string   `json:"createdAt"`
+	Files     []string `json:"files"`
+}
+
+// BackupResult is returned by the backup and restore endpoints
+type BackupResult struct {
+	Path     string         `json:"path"`
+	Manifest BackupManifest `json:"manifest"`
+	// SafetyCopy is the backup of the replaced data taken before a restore
+	SafetyCopy string `json:"safetyCopy,omitempty"`
+}
+
+// backupDirs are the subdirectories of the data directory whose JSON
+// files a backup carries, such as the saved strategies
+var backupDirs = []string{"strategies"}
+
+// stateFiles lists the JSON state files in the data directory and its
+// backupDirs, as archive names: slash-separated and relative to the data
+// directory
+func (a *App) stateFiles() ([]string, error) {
+	var names []string
+	for _, dir := range append([]string{""}, backupDirs...) {
+		paths, err := filepath.Glob(filepath.Join(a.dataDir, dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			names = append(names, path.Join(dir, filepath.Base(p)))
+		}
+	}
+	return names, nil
+}
+
+// writeBackup archives the settings, portfolios, watchlists, strategies
+// and the history database into a single zip file at path
+func (a *App) writeBackup(path string) (BackupManifest, error) {
+	manifest := BackupManifest{App: backupApp, CreatedAt: shanghaiNow().Format(time.RFC3339)}
+	files, err := a.stateFiles()
+	if err != nil {
+		return manifest, err
+	}
+
+	// VACUUM INTO takes a consistent copy of the live WAL database
+	staging, err := os.MkdirTemp("", "stock-analysis-backup")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(staging)
+	sources := make(map[string]string, len(files)+1)
+	for _, name := range files {
+		sources[name] = filepath.Join(a.dataDir, filepath.FromSlash(name))
+	}
+	if _, err := os.Stat(filepath.Join(a.dataDir, historyDBName)); err == nil {
+		db, err := localHistory.Open()
+		if err != nil {
+			return manifest, err
+		}
+		snapshot := filepath.Join(staging, historyDBName)
+		if _, err := db.Exec(`VACUUM INTO ?`, snapshot); err != nil {
+			return manifest, fmt.Errorf("failed to snapshot history database: %v", err)
+		}
+		files = append(files, historyDBName)
+		sources[historyDBName] = snapshot
+	}
+	manifest.Files = files
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return manifest, err
+	}
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return manifest, err
+	}
+	zw := zip.NewWriter(out)
+	err = func() error {
+		w, err := zw.Create(backupManifestName)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(w).Encode(manifest); err != nil {
+			return err
+		}
+		for _, name := range files {
+			if err := addZipFile(zw, name, sources[name]); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}()
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return manifest, fmt.Errorf("failed to write backup: %v", err)
+	}
+	return manifest, os.Rename(tmp, path)
+}
+
+// addZipFile copies the file at src into the archive as name
+func addZipFile(zw *zip.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// validBackupName reports whether name is a file a backup may restore:
+// a JSON state file or the history database, or a JSON file directly in
+// one of backupDirs. Anything else, such as a path leaving the data
+// directory, is refused.
+func validBackupName(name string) bool {
+	if strings.Contains(name, `\`) {
+		return false
+	}
+	dir, base := path.Split(name)
+	if base == "" || base == "." || base == ".." {
+		return false
+	}
+	if dir != "" {
+		return containsString(backupDirs, strings.TrimSuffix(dir, "/")) && strings.HasSuffix(base, ".json")
+	}
+	return base == historyDBName || (strings.HasSuffix(base, ".json") && base != backupManifestName)
+}
+
+// restoreBackup replaces the data directory with the contents of the
+// archive at path. The current data is backed up first, and nothing is
+// replaced unless the whole archive reads back cleanly.
+func (a *App) restoreBackup(path string) (BackupResult, error) {
+	result := BackupResult{Path: path}
+	// Jobs read and write the stores the restore replaces
+	if n := a.jobs.unfinished(); n > 0 {
+		return result, codeErrorf(codeBusy, "cannot restore while %d jobs are running", n)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+	mf, ok := entries[backupManifestName]
+	if !ok {
+		return result, codeErrorf(codeParse, "not a backup archive: missing %s", backupManifestName)
+	}
+	if err := readZipJSON(mf, &result.Manifest); err != nil {
+		return result, err
+	}
+	if result.Manifest.App != backupApp {
+		return result, codeErrorf(codeParse, "not a backup archive: app is %q", result.Manifest.App)
+	}
+	for _, name := range result.Manifest.Files {
+		if !validBackupName(name) || entries[name] == nil {
+			return result, codeErrorf(codeParse, "backup archive is damaged: bad entry %q", name)
+		}
+	}
+
+	// Extract into a staging directory next to the data so the final
+	// renames stay on one filesystem
+	staging, err := os.MkdirTemp(filepath.Dir(a.dataDir), "restore")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(staging)
+	for _, dir := range backupDirs {
+		if err := os.MkdirAll(filepath.Join(staging, dir), 0o755); err != nil {
+			return result, err
+		}
+	}
+	for _, name := range result.Manifest.Files {
+		if err := extractZipFile(entries[name], filepath.Join(staging, filepath.FromSlash(name))); err != nil {
+			return result, fmt.Errorf("failed to read %s from backup: %v", name, err)
+		}
+	}
+
+	result.SafetyCopy = filepath.Join(a.dataDir, "backups", "pre-restore-"+shanghaiNow().Format("20060102-150405")+".zip")
+	if _, err := a.writeBackup(result.SafetyCopy); err != nil {
+		return result, fmt.Errorf("failed to back up current data: %v", err)
+	}
+
+	// Stop the loops so none writes a store over the restored files, and
+	// start them again once the stores have reloaded
+	if a.cancelLoops() {
+		a.loops.Wait()
+		defer a.resumeLoops()
+	}
+	if err := localHistory.Close(); err != nil {
+		return result, err
+	}
+	current, err := a.stateFiles()
+	if err != nil {
+		return result, err
+	}
+	for _, name := range current {
+		os.Remove(filepath.Join(a.dataDir, filepath.FromSlash(name)))
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(filepath.Join(a.dataDir, historyDBName+suffix))
+	}
+	for _, dir := range backupDirs {
+		if err := os.MkdirAll(filepath.Join(a.dataDir, dir), 0o755); err != nil {
+			return result, err
+		}
+	}
+	for _, name := range result.Manifest.Files {
+		local := filepath.FromSlash(name)
+		if err := os.Rename(filepath.Join(staging, local), filepath.Join(a.dataDir, local)); err != nil {
+			return result, fmt.Errorf("failed to restore %s, the previous data is in %s: %v", name, result.SafetyCopy, err)
+		}
+	}
+
+	// The restored files may be encrypted under another passcode; they
+	// stay locked until it is entered. An older backup may need the
+	// current migrations.
+	vault.unload()
+	if !vault.locked() {
+		a.migrate()
+	}
+	a.reloadStores()
+	a.ClearCache()
+	a.emitLockStatus()
+	return result, nil
+}
+
+// readZipJSON decodes a JSON archive entry into v
+func readZipJSON(f *zip.File, v interface{}) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return codeErrorf(codeParse, "failed to parse %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// extractZipFile writes an archive entry to dst
+func extractZipFile(f *zip.File, dst string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// BackupData asks for a destination and writes a backup archive of all
+// local data there. It returns "" if the dialog is cancelled.
+func (a *App) BackupData() (string, error) {
+	path, err := wailsruntime.SaveFileDialog(a.ctx, wailsruntime.SaveDialogOptions{
+		Title:           "Back up data",
+		DefaultFilename: "stock-analysis-" + shanghaiNow().Format("20060102") + ".zip",
+		Filters:         []wailsruntime.FileFilter{{DisplayName: "Backup (*.zip)", Pattern: "*.zip"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	manifest, err := a.writeBackup(path)
+	recordAudit(auditExport, "backup", path, fmt.Sprintf("%d files", len(manifest.Files)), err)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(BackupResult{Path: path, Manifest: manifest})
+}
+
+// RestoreData asks for a backup archive and replaces all local data with
+// its contents. It returns "" if the dialog is cancelled.
+func (a *App) RestoreData() (string, error) {
+	path, err := wailsruntime.OpenFileDialog(a.ctx, wailsruntime.OpenDialogOptions{
+		Title:   "Restore data",
+		Filters: []wailsruntime.FileFilter{{DisplayName: "Backup (*.zip)", Pattern: "*.zip"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	// Recorded after the restore, in the restored history database
+	result, err := a.restoreBackup(path)
+	recordAudit(auditImport, "backup", path, fmt.Sprintf("%d files, created %s", len(result.Manifest.Files), result.Manifest.CreatedAt), err)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(result)
+}
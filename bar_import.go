@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BarMapping tells the bar importer which column holds each field. Values
+// are header names; the built-in formats provide their own.
+type BarMapping struct {
+	Date         string `json:"date"`
+	Open         string `json:"open"`
+	High         string `json:"high"`
+	Low          string `json:"low"`
+	Close        string `json:"close"`
+	Volume       string `json:"volume"`
+	Turnover     string `json:"turnover,omitempty"`
+	VolumeUnit   string `json:"volumeUnit,omitempty"`   // "shares" (default) or "lots"
+	TurnoverUnit string `json:"turnoverUnit,omitempty"` // "yuan" (default) or "10k"
+	DateFormat   string `json:"dateFormat,omitempty"`   // Go layout, tried before the defaults
+}
+
+// barFormats are the daily bar layouts of common exports. 通达信's
+// 数据导出 writes a title line before the header and a source line after
+// the data, both of which are skipped.
+var barFormats = map[string]BarMapping{
+	"tdx": {
+		Date:     "日期",
+		Open:     "开盘",
+		High:     "最高",
+		Low:      "最低",
+		Close:    "收盘",
+		Volume:   "成交量",
+		Turnover: "成交额",
+	},
+	"generic": {
+		Date:     "date",
+		Open:     "open",
+		High:     "high",
+		Low:      "low",
+		Close:    "close",
+		Volume:   "volume",
+		Turnover: "turnover",
+	},
+}
+
+// BarImportRow is the outcome of importing one CSV line
+type BarImportRow struct {
+	Line    int    `json:"line"`
+	Bar     Bar    `json:"bar"`
+	Status  string `json:"status"` // "new", "updated" or "error"
+	Message string `json:"message,omitempty"`
+}
+
+// BarImportResult summarizes a bar import run
+type BarImportResult struct {
+	DryRun   bool           `json:"dryRun"`
+	Symbol   string         `json:"symbol"`
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Rows     []BarImportRow `json:"rows"`
+	Imported int            `json:"imported"`
+	Updated  int            `json:"updated"`
+	Errors   int            `json:"errors"`
+}
+
+// tdxTitleCode matches the code at the start of a 通达信 export title line
+var tdxTitleCode = regexp.MustCompile(`^\s*(\d{6})\s`)
+
+// validateBar checks that a bar is internally consistent
+func validateBar(b Bar) error {
+	switch {
+	case b.Open <= 0 || b.High <= 0 || b.Low <= 0 || b.Close <= 0:
+		return codeErrorf(codeParse, "prices must be positive")
+	case b.High < math.Max(b.Open, b.Close) || b.Low > math.Min(b.Open, b.Close):
+		return codeErrorf(codeParse, "high/low do not bracket open and close")
+	case b.Volume < 0 || b.Turnover < 0:
+		return codeErrorf(codeParse, "volume and turnover cannot be negative")
+	}
+	return nil
+}
+
+// splitTitleLine separates the title line 通达信 writes before the header
+// ("600519 贵州茅台 日线 前复权") and returns the code it names
+func splitTitleLine(text string) (code, rest string) {
+	i := strings.IndexByte(text, '\n')
+	if i < 0 || strings.ContainsAny(text[:i], ",\t") {
+		return "", text
+	}
+	if m := tdxTitleCode.FindStringSubmatch(text[:i]); m != nil {
+		code = m[1]
+	}
+	return code, text[i+1:]
+}
+
+// parseBars maps CSV records to bars using mapping
+func parseBars(records [][]string, mapping BarMapping) ([]BarImportRow, error) {
+	if len(records) == 0 {
+		return nil, codeErrorf(codeParse, "CSV is empty")
+	}
+	cols := make(map[string]int)
+	for i, name := range records[0] {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{mapping.Date, mapping.Open, mapping.High, mapping.Low, mapping.Close, mapping.Volume} {
+		if _, ok := cols[required]; !ok {
+			return nil, codeErrorf(codeParse, "missing column: %s", required)
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+	volumeScale, turnoverScale := 0.01, 1e-4 // to lots and 万元 as stored
+	if mapping.VolumeUnit == "lots" {
+		volumeScale = 1
+	}
+	if mapping.TurnoverUnit == "10k" {
+		turnoverScale = 1
+	}
+
+	rows := []BarImportRow{}
+	seen := make(map[string]int)
+	for n, rec := range records[1:] {
+		row := BarImportRow{Line: n + 2}
+		dateText := field(rec, mapping.Date)
+		if dateText == "" {
+			continue
+		}
+		date, err := parseImportDate(dateText, mapping.DateFormat)
+		if err != nil {
+			// 通达信 ends the file with a "数据来源:通达信" line
+			if len(rec) == 1 {
+				continue
+			}
+			row.Status, row.Message = "error", err.Error()
+			rows = append(rows, row)
+			continue
+		}
+
+		b := &row.Bar
+		b.Date = date
+		numbers := []struct {
+			dst *float64
+			col string
+		}{{&b.Open, mapping.Open}, {&b.High, mapping.High}, {&b.Low, mapping.Low}, {&b.Close, mapping.Close}, {&b.Volume, mapping.Volume}, {&b.Turnover, mapping.Turnover}}
+		for _, num := range numbers {
+			if err != nil || num.col == "" {
+				continue
+			}
+			*num.dst, err = parseImportNumber(field(rec, num.col))
+		}
+		b.Volume *= volumeScale
+		b.Turnover *= turnoverScale
+		if err == nil {
+			err = validateBar(*b)
+		}
+		if line, dup := seen[date]; dup && err == nil {
+			err = codeErrorf(codeParse, "duplicate date, first seen on line %d", line)
+		}
+		if err != nil {
+			row.Status, row.Message = "error", err.Error()
+		} else {
+			seen[date] = row.Line
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// fillChanges sets the change fields of bars, sorted oldest first, from
+// the previous close
+func fillChanges(bars []Bar) {
+	for i := 1; i < len(bars); i++ {
+		prev := bars[i-1].Close
+		bars[i].Change = bars[i].Close - prev
+		bars[i].ChangePct = bars[i].Change / prev * 100
+	}
+}
+
+// ImportBarsCSV imports daily bars for symbol into the local history
+// store, e.g. for symbols the online providers do not cover. format is
+// "tdx", "generic" or "custom"; for "custom" mappingJSON holds a
+// BarMapping. An empty symbol is read from a 通达信 title line. With
+// dryRun set nothing is saved. Imported bars replace stored bars of the
+// same date.
+func (a *App) ImportBarsCSV(symbol string, content string, format string, mappingJSON string, dryRun bool) (_ string, err error) {
+	var result BarImportResult
+	defer func() {
+		if !dryRun {
+			recordAudit(auditImport, "bars", symbol, fmt.Sprintf("%s: %d new, %d updated, %d errors", format, result.Imported, result.Updated, result.Errors), err)
+		}
+	}()
+	mapping, ok := barFormats[format]
+	if format == "custom" {
+		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+			return "", codeErrorf(codeParse, "failed to parse CSV mapping: %v", err)
+		}
+	} else if !ok {
+		return "", codeErrorf(codeParse, "unknown import format: %s", format)
+	}
+
+	decoded := decodeCSVText([]byte(content))
+	title, text := splitTitleLine(decoded)
+	records, err := readCSVRecords(text)
+	if err != nil {
+		return "", err
+	}
+	rows, err := parseBars(records, mapping)
+	if err != nil {
+		return "", err
+	}
+	if len(text) < len(decoded) {
+		// Count the stripped title line in the reported line numbers
+		for i := range rows {
+			rows[i].Line++
+		}
+	}
+	if symbol = normalizeSymbol(symbol); symbol == "" {
+		symbol = title
+	}
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+
+	result = BarImportResult{DryRun: dryRun, Symbol: symbol, Rows: rows}
+	var bars []Bar
+	for _, row := range rows {
+		if row.Status != "error" {
+			bars = append(bars, row.Bar)
+		}
+	}
+	if len(bars) == 0 {
+		result.Errors = len(rows)
+		return toJSON(result)
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Date < bars[j].Date })
+	result.From, result.To = bars[0].Date, bars[len(bars)-1].Date
+
+	// Seed the first change from the stored bar before the import, and
+	// mark dates the store already holds
+	start, _ := time.Parse("2006-01-02", result.From)
+	end, _ := time.Parse("2006-01-02", result.To)
+	stored, err := localHistory.load(symbol, start.AddDate(0, 0, -30), end)
+	if err != nil {
+		return "", err
+	}
+	existing := make(map[string]bool, len(stored))
+	var before []Bar
+	for _, b := range stored {
+		existing[b.Date] = true
+		if b.Date < result.From {
+			before = append(before[:0], b)
+		}
+	}
+	series := append(before, bars...)
+	fillChanges(series)
+	bars = series[len(before):]
+	changes := make(map[string]Bar, len(bars))
+	for _, b := range bars {
+		changes[b.Date] = b
+	}
+
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		if row.Status == "error" {
+			result.Errors++
+			continue
+		}
+		row.Bar = changes[row.Bar.Date]
+		if existing[row.Bar.Date] {
+			row.Status = "updated"
+			result.Updated++
+		} else {
+			row.Status = "new"
+			result.Imported++
+		}
+	}
+	if !dryRun {
+		if err := localHistory.save(symbol, bars); err != nil {
+			return "", err
+		}
+	}
+	return toJSON(result)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// An analysis bundle carries everything needed to see a symbol's analysis
+// the way its author saw it: the bars it was computed from, the indicator
+// columns, the author's note and, optionally, a saved snapshot. Importing
+// recomputes the indicators from the bundled bars rather than fetching
+// fresh ones, and checks the result against the hash the author's app
+// recorded, so both users look at the same numbers.
+
+const (
+	bundleFormat  = "stock-analysis/analysis-bundle"
+	bundleVersion = 1
+	// maxBundleSize bounds what ImportAnalysisBundle accepts
+	maxBundleSize = 32 << 20
+)
+
+// AnalysisBundle is the shareable file
+type AnalysisBundle struct {
+	Format     string                `json:"format"`
+	Version    int                   `json:"version"`
+	AppVersion string                `json:"appVersion"`
+	ExportedAt string                `json:"exportedAt"`
+	Symbol     string                `json:"symbol"`
+	Name       string                `json:"name,omitempty"` // security name, for display
+	Bars       []Bar                 `json:"bars"`
+	Spec       []IndicatorColumnSpec `json:"spec"`
+	Note       *SymbolNote           `json:"note,omitempty"`
+	Snapshot   *AnalysisSnapshot     `json:"snapshot,omitempty"`
+	DataHash   string                `json:"dataHash"`  // of the bars and spec
+	TableHash  string                `json:"tableHash"` // of the indicator columns computed from them
+}
+
+// BundleImportResult is an imported bundle reproduced locally
+type BundleImportResult struct {
+	Symbol     string            `json:"symbol"`
+	Name       string            `json:"name,omitempty"`
+	ExportedAt string            `json:"exportedAt"`
+	AppVersion string            `json:"appVersion"`
+	Note       *SymbolNote       `json:"note,omitempty"`
+	Table      IndicatorTable    `json:"table"`
+	Stats      SymbolStats       `json:"stats"`
+	Snapshot   *AnalysisSnapshot `json:"snapshot,omitempty"` // as saved locally, if the bundle had one
+	Reproduced bool              `json:"reproduced"`         // the columns match the author's exactly
+}
+
+// bundleHash hashes values as JSON. Go writes floats in their shortest
+// exact form, so bars survive the round trip through the file unchanged.
+func bundleHash(values ...interface{}) string {
+	h := sha256.New()
+	for _, v := range values {
+		data, _ := json.Marshal(v)
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ExportAnalysisBundle returns a bundle of the last days of symbol with
+// the indicator columns of specJSON (empty for the standard columns), the
+// symbol's note and, if snapshotID is not empty, that saved snapshot.
+// days of 0 uses the configured lookback and lookbackMax (-1) all
+// available history.
+func (a *App) ExportAnalysisBundle(symbol string, days int, specJSON string, snapshotID string) (_ string, err error) {
+	symbol = normalizeSymbol(symbol)
+	defer func() {
+		recordAudit(auditExport, "analysis bundle", symbol, snapshotID, err)
+	}()
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+	spec, formulas, err := parseIndicatorSpec(specJSON)
+	if err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	bundle := AnalysisBundle{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		AppVersion: appVersion,
+		ExportedAt: shanghaiNow().Format(time.RFC3339),
+		Symbol:     symbol,
+		Spec:       spec,
+	}
+	if snapshotID != "" {
+		snap, err := a.snapshots.get(snapshotID)
+		if err != nil {
+			return "", err
+		}
+		if snap.Symbol != symbol {
+			return "", codeErrorf(codeParse, "snapshot %s is of %s, not %s", snapshotID, snap.Symbol, symbol)
+		}
+		bundle.Snapshot = &snap
+		if days == 0 {
+			days = snap.Days
+		}
+	}
+	if days == 0 {
+		days = currentSettings().LookbackDays
+	}
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	if bundle.Snapshot != nil {
+		// End the data where the snapshot ended, so it can be recomputed
+		for len(bars) > 0 && bars[len(bars)-1].Date > bundle.Snapshot.Stats.To {
+			bars = bars[:len(bars)-1]
+		}
+	}
+	if len(bars) == 0 {
+		return "", codeErrorf(codeNoData, "no data for %s", symbol)
+	}
+	bundle.Bars = bars
+
+	table, err := indicatorTable(symbol, bars, spec, formulas)
+	if err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	bundle.DataHash = bundleHash(bars, spec)
+	bundle.TableHash = bundleHash(table.Columns)
+	if meta, err := a.metadata.get(symbol, false); err == nil {
+		bundle.Name = meta.Name
+	}
+	if note, ok := a.notes.lookup([]string{symbol})[symbol]; ok {
+		bundle.Note = &note
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ImportAnalysisBundle recomputes a bundle's indicators from its bars and
+// reports whether they match the author's. A bundled snapshot is saved
+// among the local snapshots, with the author's note; the local note of
+// the symbol is left as it is.
+func (a *App) ImportAnalysisBundle(content string) (_ string, err error) {
+	var bundle AnalysisBundle
+	defer func() {
+		recordAudit(auditImport, "analysis bundle", bundle.Symbol, fmt.Sprintf("%d bars", len(bundle.Bars)), err)
+	}()
+	if len(content) > maxBundleSize {
+		return "", codeErrorf(codeParse, "bundle is larger than %d bytes", maxBundleSize)
+	}
+	if err := json.Unmarshal([]byte(content), &bundle); err != nil {
+		return "", codeErrorf(codeParse, "not an analysis bundle: %v", err)
+	}
+	if bundle.Format != bundleFormat {
+		return "", codeErrorf(codeParse, "not an analysis bundle")
+	}
+	if bundle.Version > bundleVersion {
+		return "", codeErrorf(codeParse, "the bundle needs a newer version of the app (format %d)", bundle.Version)
+	}
+	bundle.Symbol = normalizeSymbol(bundle.Symbol)
+	if bundle.Symbol == "" || len(bundle.Bars) == 0 {
+		return "", codeErrorf(codeParse, "the bundle has no symbol or no data")
+	}
+	if bundleHash(bundle.Bars, bundle.Spec) != bundle.DataHash {
+		return "", codeErrorf(codeParse, "the bundle is damaged or was edited")
+	}
+	specJSON, _ := json.Marshal(bundle.Spec)
+	spec, formulas, err := parseIndicatorSpec(string(specJSON))
+	if err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	table, err := indicatorTable(bundle.Symbol, bundle.Bars, spec, formulas)
+	if err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	result := BundleImportResult{
+		Symbol:     bundle.Symbol,
+		Name:       bundle.Name,
+		ExportedAt: bundle.ExportedAt,
+		AppVersion: bundle.AppVersion,
+		Note:       bundle.Note,
+		Table:      table,
+		Stats:      symbolStats(bundle.Bars),
+		Reproduced: bundleHash(table.Columns) == bundle.TableHash,
+	}
+	if !result.Reproduced {
+		logger.Warn("imported bundle computes differently", "symbol", bundle.Symbol, "author version", bundle.AppVersion)
+	}
+
+	if bundle.Snapshot != nil {
+		snap := *bundle.Snapshot
+		snap.ID = newID()
+		snap.Symbol = bundle.Symbol
+		snap.Name = strings.TrimSpace(snap.Name)
+		if snap.Name == "" {
+			snap.Name = bundle.Symbol + " " + snap.Stats.To
+		}
+		if bundle.Note != nil && strings.TrimSpace(bundle.Note.Note) != "" && strings.TrimSpace(snap.Note) == "" {
+			snap.Note = bundle.Note.Note
+		}
+
+		s := a.snapshots
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.load(); err != nil {
+			return "", err
+		}
+		s.snapshots = append(s.snapshots, snap)
+		if err := saveJSON(s.path, s.snapshots); err != nil {
+			return "", err
+		}
+		result.Snapshot = &snap
+	}
+	return toJSON(result)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Default time-to-live of cached results. Quotes change during the
+// session, so they are kept briefly; analysis over daily bars can live
+// longer. The settings override both.
+const (
+	quoteCacheTTL    = 15 * time.Second
+	analysisCacheTTL = time.Minute
+)
+
+// cacheEntry is a cached value and its expiry
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// ttlCache is a concurrency-safe in-memory cache whose entries expire
+type ttlCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newTTLCache() *ttlCache {
+	return &ttlCache{entries: make(map[string]cacheEntry)}
+}
+
+// get returns the value stored under key if it has not expired
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	metrics.observeCache(ok)
+	if !ok {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// set stores value under key for ttl
+func (c *ttlCache) set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
+
+// len returns the number of entries, including expired ones not yet
+// dropped
+func (c *ttlCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// clear drops every entry and returns how many there were
+func (c *ttlCache) clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	c.entries = make(map[string]cacheEntry)
+	return n
+}
+
+// resultCache holds recent quotes and analysis results so repeated
+// frontend calls do not hit the providers again
+var resultCache = newTTLCache()
+
+// cachedJSON returns the cached result for key, or calls fn and caches
+// its result for ttl. Errors are not cached.
+func cachedJSON(key string, ttl time.Duration, fn func() (string, error)) (string, error) {
+	if v, ok := resultCache.get(key); ok {
+		return v.(string), nil
+	}
+	result, err := fn()
+	if err != nil {
+		return "", err
+	}
+	resultCache.set(key, result, ttl)
+	return result, nil
+}
+
+// ClearCache drops every cached quote, analysis result, indicator set,
+// indicator table and validated provider response and returns the number
+// of entries removed
+func (a *App) ClearCache() int {
+	return resultCache.clear() + indicatorCache.clear() + analysisCache.clear() + validators.clear()
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The exchanges close on the public holidays the State Council announces
+// each autumn for the next year, and never trade on weekends, including
+// the weekends worked to make up for a holiday. The schedule of each year
+// is fetched once it is published and cached in holidays.json; it is
+// checked again monthly in case of late changes. Years fetched before ship
+// with the app, so a first run offline still knows them, and a year
+// nobody has published yet falls back to weekdays, with a warning in the
+// log.
+
+const (
+	// holidayCalendarURL serves the State Council schedule of a year as
+	// {"days": [{"date": "2025-01-01", "isOffDay": true}, ...]}
+	holidayCalendarURL = "https://cdn.jsdelivr.net/gh/NateScarlet/holiday-cn@master/%d.json"
+	// holidayRefreshDays is how old a fetched schedule may get before it
+	// is checked again
+	holidayRefreshDays = 30
+	// holidayCheckInterval is how often the loop looks for stale years
+	holidayCheckInterval = 24 * time.Hour
+)
+
+// bundledHolidays are the weekdays the exchanges closed in years known at
+// build time. They include closures outside the public schedule, such as
+// Spring Festival's eve in 2024.
+var bundledHolidays = map[int][]string{
+	2024: {
+		"2024-01-01",
+		"2024-02-09", "2024-02-12", "2024-02-13", "2024-02-14", "2024-02-15", "2024-02-16",
+		"2024-04-04", "2024-04-05",
+		"2024-05-01", "2024-05-02", "2024-05-03",
+		"2024-06-10",
+		"2024-09-16", "2024-09-17",
+		"2024-10-01", "2024-10-02", "2024-10-03", "2024-10-04", "2024-10-07",
+	},
+	2025: {
+		"2025-01-01",
+		"2025-01-28", "2025-01-29", "2025-01-30", "2025-01-31", "2025-02-03", "2025-02-04",
+		"2025-04-04",
+		"2025-05-01", "2025-05-02", "2025-05-05",
+		"2025-06-02",
+		"2025-10-01", "2025-10-02", "2025-10-03", "2025-10-06", "2025-10-07", "2025-10-08",
+	},
+	2026: {
+		"2026-01-01", "2026-01-02",
+		"2026-02-16", "2026-02-17", "2026-02-18", "2026-02-19", "2026-02-20", "2026-02-23",
+		"2026-04-06",
+		"2026-05-01", "2026-05-04", "2026-05-05",
+		"2026-06-19",
+		"2026-09-25",
+		"2026-10-01", "2026-10-02", "2026-10-05", "2026-10-06", "2026-10-07",
+	},
+}
+
+// Sources of a holiday calendar
+const (
+	holidaySourceFetched  = "fetched"
+	holidaySourceBundled  = "bundled"
+	holidaySourceWeekdays = "weekdays" // not published yet
+)
+
+// HolidayCalendar is the schedule of one year
+type HolidayCalendar struct {
+	Year      int      `json:"year"`
+	Closed    []string `json:"closed"` // weekdays the exchanges are closed
+	Source    string   `json:"source"` // "fetched", "bundled" or "weekdays"
+	FetchedAt string   `json:"fetchedAt,omitempty"`
+	Checked   string   `json:"checked,omitempty"` // last attempt to fetch, successful or not
+}
+
+// holidayStore holds the fetched schedules, keyed by year
+type holidayStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	years  map[int]*HolidayCalendar
+	closed map[string]bool // every closed date of every known year
+}
+
+// holidays answers isTradingDay. openStores points it at the data
+// directory; until then only the bundled years are known.
+var holidays *holidayStore
+
+// holidayClient goes straight to the connection pool so recorded
+// fixtures never capture the schedule
+var holidayClient = &http.Client{Transport: providerTransport, Timeout: 15 * time.Second}
+
+func newHolidayStore(dataDir string) *holidayStore {
+	return &holidayStore{path: filepath.Join(dataDir, "holidays.json")}
+}
+
+// unload forgets the schedules so the next use reads the file again
+func (s *holidayStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.years, s.closed, s.loaded = nil, nil, false
+}
+
+// load reads the schedules from disk on first use. Callers hold s.mu.
+func (s *holidayStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.years = make(map[int]*HolidayCalendar)
+	if err := loadJSON(s.path, &s.years); err != nil {
+		return err
+	}
+	s.index()
+	s.loaded = true
+	return nil
+}
+
+// index rebuilds the set of closed dates from the bundled and fetched
+// years. Both count for a year known to both, since the public schedule
+// leaves out closures such as Spring Festival's eve. Callers hold s.mu.
+func (s *holidayStore) index() {
+	s.closed = make(map[string]bool)
+	for _, dates := range bundledHolidays {
+		for _, d := range dates {
+			s.closed[d] = true
+		}
+	}
+	for _, c := range s.years {
+		for _, d := range c.Closed {
+			s.closed[d] = true
+		}
+	}
+}
+
+// isClosed reports whether the exchanges are closed on date, a weekday
+func (s *holidayStore) isClosed(date string) bool {
+	year := yearOfDate(date)
+	if s == nil {
+		if _, ok := bundledHolidays[year]; !ok {
+			warnWeekdaysOnly(year)
+		}
+		return containsString(bundledHolidays[year], date)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		logger.Warn("failed to load holidays", "error", err)
+		return containsString(bundledHolidays[year], date)
+	}
+	if !s.known(year) {
+		warnWeekdaysOnly(year)
+	}
+	return s.closed[date]
+}
+
+// known reports whether the closures of year are bundled or fetched.
+// Callers hold s.mu.
+func (s *holidayStore) known(year int) bool {
+	if _, ok := bundledHolidays[year]; ok {
+		return true
+	}
+	c := s.years[year]
+	return c != nil && c.Source == holidaySourceFetched
+}
+
+// weekdaysOnlyWarned are the years warnWeekdaysOnly has logged
+var weekdaysOnlyWarned sync.Map
+
+// warnWeekdaysOnly logs, once per year, that the closures of year are
+// unknown and every weekday counts as a trading day. Years before the
+// bundled ones were never covered and are not reported.
+func warnWeekdaysOnly(year int) {
+	for bundled := range bundledHolidays {
+		if year < bundled {
+			return
+		}
+	}
+	if _, seen := weekdaysOnlyWarned.LoadOrStore(year, true); !seen {
+		logger.Warn("no holiday schedule for the year, counting every weekday as a trading day", "year", year)
+	}
+}
+
+// yearOfDate returns the year of a "2006-01-02" date
+func yearOfDate(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, _ := strconv.Atoi(date[:4])
+	return year
+}
+
+// calendar returns the schedule of year from the cache, the bundled
+// dates or, failing both, weekdays only
+func (s *holidayStore) calendar(year int) (HolidayCalendar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return HolidayCalendar{}, err
+	}
+	out := HolidayCalendar{Year: year, Source: holidaySourceWeekdays}
+	if c := s.years[year]; c != nil {
+		out = *c
+	}
+	if bundled, ok := bundledHolidays[year]; ok {
+		if out.Source == holidaySourceWeekdays {
+			out.Source = holidaySourceBundled
+		}
+		for _, d := range bundled {
+			if !containsString(out.Closed, d) {
+				out.Closed = append(out.Closed, d)
+			}
+		}
+	}
+	if out.Source == holidaySourceWeekdays {
+		warnWeekdaysOnly(year)
+	}
+	out.Closed = append([]string(nil), out.Closed...)
+	sort.Strings(out.Closed)
+	return out, nil
+}
+
+// stale reports whether year should be fetched at now: it was never
+// fetched or its schedule is more than holidayRefreshDays old. A year not
+// published yet is tried once a day.
+func (s *holidayStore) stale(year int, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return false
+	}
+	c := s.years[year]
+	if c == nil {
+		return true
+	}
+	last := c.FetchedAt
+	if c.Source != holidaySourceFetched {
+		last = c.Checked
+	}
+	t, err := time.Parse(time.RFC3339, last)
+	if err != nil {
+		return true
+	}
+	limit := holidayRefreshDays * 24 * time.Hour
+	if c.Source != holidaySourceFetched {
+		limit = holidayCheckInterval
+	}
+	return now.Sub(t) >= limit
+}
+
+// set stores the schedule of a year and writes the file
+func (s *holidayStore) set(c HolidayCalendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if prev := s.years[c.Year]; prev != nil && c.Source != holidaySourceFetched && prev.Source == holidaySourceFetched {
+		// Keep the schedule fetched before; only note the attempt
+		prev.Checked = c.Checked
+	} else {
+		s.years[c.Year] = &c
+	}
+	s.index()
+	return saveJSON(s.path, s.years)
+}
+
+// fetchHolidays downloads the schedule of year. It returns a calendar
+// with source "weekdays" if the schedule is not published yet.
+func fetchHolidays(ctx context.Context, year int) (HolidayCalendar, error) {
+	now := shanghaiNow().Format(time.RFC3339)
+	c := HolidayCalendar{Year: year, Source: holidaySourceWeekdays, Checked: now}
+	if !connectivity.allow() {
+		return c, errOffline
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(holidayCalendarURL, year), nil)
+	if err != nil {
+		return c, err
+	}
+	resp, err := holidayClient.Do(req)
+	if err != nil {
+		return c, codeErrorf(codeNetwork, "failed to fetch holidays of %d: %v", year, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return c, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return c, codeErrorf(codeProvider, "failed to fetch holidays of %d: %s", year, resp.Status)
+	}
+	var body struct {
+		Days []struct {
+			Date     string `json:"date"`
+			IsOffDay bool   `json:"isOffDay"`
+		} `json:"days"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return c, codeErrorf(codeParse, "failed to parse holidays of %d: %v", year, err)
+	}
+	if len(body.Days) == 0 {
+		return c, nil
+	}
+	closed := []string{}
+	for _, d := range body.Days {
+		day, err := time.Parse("2006-01-02", d.Date)
+		if err != nil || day.Year() != year {
+			continue
+		}
+		// Make-up workdays fall on weekends, when the exchanges are closed
+		// anyway
+		if d.IsOffDay && day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			closed = append(closed, d.Date)
+		}
+	}
+	sort.Strings(closed)
+	c.Closed, c.Source, c.FetchedAt = closed, holidaySourceFetched, now
+	return c, nil
+}
+
+// refreshHolidays fetches the schedule of year and caches it
+func refreshHolidays(ctx context.Context, year int) (HolidayCalendar, error) {
+	c, err := fetchHolidays(ctx, year)
+	if err != nil {
+		// Record the attempt so the loop waits a day before the next
+		if serr := holidays.set(c); serr != nil {
+			logger.Warn("failed to save holidays", "error", serr)
+		}
+		return c, err
+	}
+	if err := holidays.set(c); err != nil {
+		return c, err
+	}
+	if c.Source == holidaySourceFetched {
+		logger.Info("holidays updated", "year", year, "closed", len(c.Closed))
+	}
+	return c, nil
+}
+
+// holidayYears are the years the calendar should know at now: this one,
+// and the next from October, when its schedule is usually announced
+func holidayYears(now time.Time) []int {
+	years := []int{now.Year()}
+	if now.Month() >= time.October {
+		years = append(years, now.Year()+1)
+	}
+	return years
+}
+
+// runHolidayLoop keeps the schedules of this year and the next fresh
+func (a *App) runHolidayLoop(ctx context.Context) {
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			now := shanghaiNow()
+			for _, year := range holidayYears(now) {
+				if !holidays.stale(year, now) {
+					continue
+				}
+				if _, err := refreshHolidays(ctx, year); err != nil {
+					logger.Warn("holiday update failed", "year", year, "error", err)
+				}
+			}
+			timer.Reset(holidayCheckInterval)
+		}
+	}
+}
+
+// tradingDaysBefore returns the day n trading days before the day of t
+func tradingDaysBefore(t time.Time, n int) time.Time {
+	day := t
+	for n > 0 {
+		day = day.AddDate(0, 0, -1)
+		if isTradingDay(day) {
+			n--
+		}
+	}
+	return day
+}
+
+// tradingDaysBetween counts the trading days after from up to and
+// including to
+func tradingDaysBetween(from, to time.Time) int {
+	n := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if isTradingDay(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// GetHolidayCalendar returns the schedule of year, 0 for this year
+func (a *App) GetHolidayCalendar(year int) (string, error) {
+	if year == 0 {
+		year = shanghaiNow().Year()
+	}
+	c, err := holidays.calendar(year)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(c)
+}
+
+// RefreshHolidayCalendar fetches the schedule of year now, 0 for this
+// year, and returns it
+func (a *App) RefreshHolidayCalendar(year int) (string, error) {
+	if year == 0 {
+		year = shanghaiNow().Year()
+	}
+	if year < 2000 || year > shanghaiNow().Year()+1 {
+		return "", codeErrorf(codeNoData, "no holiday schedule for %d", year)
+	}
+	if _, err := refreshHolidays(context.Background(), year); err != nil {
+		return "", err
+	}
+	return a.GetHolidayCalendar(year)
+}
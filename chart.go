@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// Candlestick charts are drawn in Go so reports, webhook messages and
+// exports can include one without the webview. The layout is drawn once
+// onto a chartCanvas, which writes SVG or paints a bitmap for PNG. The
+// bitmap has no font files to draw with; its labels use a small built-in
+// font of digits and Latin capitals, and leave other characters out.
+
+const (
+	defaultChartWidth  = 900
+	defaultChartHeight = 540
+	maxChartSize       = 4000
+
+	// Rising bars are red and falling bars green, as on Chinese exchanges
+	chartUpColor   = "#e0393e"
+	chartDownColor = "#1a9850"
+	chartGridColor = "#e6e6e6"
+	chartTextColor = "#666666"
+)
+
+// chartOverlayColors are the colors of the price overlays, by name
+var chartOverlayColors = map[string]string{
+	"MA5":   "#ff7f0e",
+	"MA10":  "#9467bd",
+	"MA20":  "#1f77b4",
+	"EMA12": "#8c564b",
+	"EMA26": "#17becf",
+}
+
+// ChartOptions selects what a chart shows and how it is encoded
+type ChartOptions struct {
+	Days       int      `json:"days"`       // 0 for the configured lookback, -1 for all history
+	Format     string   `json:"format"`     // "svg" or "png"
+	Width      int      `json:"width"`      // pixels; 0 for 900
+	Height     int      `json:"height"`     // pixels; 0 for 540
+	Overlays   []string `json:"overlays"`   // of MA5, MA10, MA20, EMA12 and EMA26; null for MA5 and MA20
+	HideVolume bool     `json:"hideVolume"` // leave out the volume pane
+	Indicator  string   `json:"indicator"`  // lower pane: "macd", "atr" or "none"; "" for macd
+}
+
+// normalize fills in defaults and checks the options
+func (o *ChartOptions) normalize() error {
+	switch o.Format {
+	case "":
+		o.Format = "svg"
+	case "svg", "png":
+	default:
+		return codeErrorf(codeParse, "unknown chart format: %s", o.Format)
+	}
+	if o.Width == 0 {
+		o.Width = defaultChartWidth
+	}
+	if o.Height == 0 {
+		o.Height = defaultChartHeight
+	}
+	if o.Width < 200 || o.Height < 150 || o.Width > maxChartSize || o.Height > maxChartSize {
+		return codeErrorf(codeParse, "chart size must be between 200×150 and %d×%d", maxChartSize, maxChartSize)
+	}
+	if o.Overlays == nil {
+		o.Overlays = []string{"MA5", "MA20"}
+	}
+	for _, name := range o.Overlays {
+		if _, ok := chartOverlayColors[name]; !ok {
+			return codeErrorf(codeParse, "unknown chart overlay: %s", name)
+		}
+	}
+	switch o.Indicator {
+	case "":
+		o.Indicator = "macd"
+	case "macd", "atr", "none":
+	default:
+		return codeErrorf(codeParse, "unknown chart indicator: %s", o.Indicator)
+	}
+	return nil
+}
+
+// chartCanvas is what a chart is drawn on. Coordinates are pixels from
+// the top left.
+type chartCanvas interface {
+	rect(x, y, w, h float64, fill string)
+	line(x1, y1, x2, y2 float64, stroke string)
+	polyline(points [][2]float64, stroke string)
+	// text draws s with its baseline at y, starting, centered or ending
+	// at x for anchor "start", "middle" or "end"
+	text(x, y float64, s, fill, anchor string)
+}
+
+// chartPane maps values onto a horizontal band of the chart
+type chartPane struct {
+	top, height float64
+	min, max    float64
+}
+
+// newChartPane fits the finite values of series into the band
+func newChartPane(top, height float64, series ...[]float64) chartPane {
+	p := chartPane{top: top, height: height, min: math.Inf(1), max: math.Inf(-1)}
+	for _, values := range series {
+		for _, v := range values {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				p.min, p.max = math.Min(p.min, v), math.Max(p.max, v)
+			}
+		}
+	}
+	if math.IsInf(p.min, 0) {
+		p.min, p.max = 0, 1
+	}
+	if p.max <= p.min {
+		p.max = p.min + 1
+	}
+	return p
+}
+
+// y returns the vertical position of v
+func (p chartPane) y(v float64) float64 {
+	return p.top + (p.max-v)/(p.max-p.min)*p.height
+}
+
+// drawCandleChart lays out the chart of bars on c: candles with the
+// overlays, then volume, then the lower indicator
+func drawCandleChart(c chartCanvas, title string, bars []Bar, set *indicatorSet, opts ChartOptions) {
+	const (
+		left, right = 8.0, 60.0
+		top, bottom = 24.0, 22.0
+		gap         = 8.0
+	)
+	width, height := float64(opts.Width), float64(opts.Height)
+	c.rect(0, 0, width, height, "#ffffff")
+	plotW := width - left - right
+
+	// Panes share the height 3:1:1, leaving out the ones not shown
+	weights := []float64{3}
+	if !opts.HideVolume {
+		weights = append(weights, 1)
+	}
+	if opts.Indicator != "none" {
+		weights = append(weights, 1)
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	unit := (height - top - bottom - gap*float64(len(weights)-1)) / total
+	y := top
+	band := func(w float64) (float64, float64) {
+		t := y
+		y += w*unit + gap
+		return t, w * unit
+	}
+
+	n := len(bars)
+	step := plotW / math.Max(float64(n), 1)
+	x := func(i int) float64 { return left + (float64(i)+0.5)*step }
+	body := math.Max(1, step*0.7)
+	rising := func(b Bar) bool { return b.Close >= b.Open }
+
+	highs, lows := highsLows(bars)
+	overlays := make([][]float64, len(opts.Overlays))
+	for i, name := range opts.Overlays {
+		overlays[i] = set.series(name)
+	}
+	t, h := band(3)
+	price := newChartPane(t, h, append([][]float64{highs, lows}, overlays...)...)
+	drawChartAxis(c, price, left, plotW, priceLabel)
+	for i, b := range bars {
+		color := chartDownColor
+		if rising(b) {
+			color = chartUpColor
+		}
+		c.line(x(i), price.y(b.High), x(i), price.y(b.Low), color)
+		yOpen, yClose := price.y(b.Open), price.y(b.Close)
+		c.rect(x(i)-body/2, math.Min(yOpen, yClose), body, math.Max(1, math.Abs(yOpen-yClose)), color)
+	}
+	for i, values := range overlays {
+		drawChartLine(c, values, x, price, chartOverlayColors[opts.Overlays[i]])
+	}
+
+	c.text(left, 16, title, "#222222", "start")
+	legendX := left + float64(len([]rune(title))+2)*7
+	for _, name := range opts.Overlays {
+		c.text(legendX, 16, name, chartOverlayColors[name], "start")
+		legendX += float64(len(name)+2) * 7
+	}
+
+	if !opts.HideVolume {
+		volumes := make([]float64, n)
+		for i, b := range bars {
+			volumes[i] = b.Volume
+		}
+		t, h := band(1)
+		pane := newChartPane(t, h, volumes, []float64{0})
+		drawChartAxis(c, pane, left, plotW, volumeLabel)
+		for i, b := range bars {
+			color := chartDownColor
+			if rising(b) {
+				color = chartUpColor
+			}
+			c.rect(x(i)-body/2, pane.y(b.Volume), body, pane.y(0)-pane.y(b.Volume), color)
+		}
+		c.text(left+2, pane.top+11, "VOL", chartTextColor, "start")
+	}
+
+	switch opts.Indicator {
+	case "macd":
+		t, h := band(1)
+		pane := newChartPane(t, h, set.DIF, set.DEA, set.Hist, []float64{0})
+		drawChartAxis(c, pane, left, plotW, priceLabel)
+		zero := pane.y(0)
+		for i, v := range set.Hist {
+			if math.IsNaN(v) {
+				continue
+			}
+			color := chartDownColor
+			if v >= 0 {
+				color = chartUpColor
+			}
+			c.rect(x(i)-body/4, math.Min(zero, pane.y(v)), math.Max(1, body/2), math.Abs(pane.y(v)-zero), color)
+		}
+		drawChartLine(c, set.DIF, x, pane, "#1f77b4")
+		drawChartLine(c, set.DEA, x, pane, "#ff7f0e")
+		c.text(left+2, pane.top+11, "MACD(12,26,9)", chartTextColor, "start")
+	case "atr":
+		t, h := band(1)
+		pane := newChartPane(t, h, set.ATR14)
+		drawChartAxis(c, pane, left, plotW, priceLabel)
+		drawChartLine(c, set.ATR14, x, pane, "#9467bd")
+		c.text(left+2, pane.top+11, "ATR14", chartTextColor, "start")
+	}
+
+	if n > 0 {
+		c.text(left, height-6, bars[0].Date, chartTextColor, "start")
+		if n > 2 {
+			c.text(left+plotW/2, height-6, bars[n/2].Date, chartTextColor, "middle")
+		}
+		c.text(left+plotW, height-6, bars[n-1].Date, chartTextColor, "end")
+	}
+}
+
+// drawChartAxis frames a pane with gridlines at its top, middle and
+// bottom, labelled on the right inside the pane so neighbours don't clash
+func drawChartAxis(c chartCanvas, p chartPane, left, w float64, label func(float64) string) {
+	for i, v := range []float64{p.max, (p.min + p.max) / 2, p.min} {
+		y := p.y(v)
+		c.line(left, y, left+w, y, chartGridColor)
+		c.text(left+w+4, y+[]float64{11, 4, -2}[i], label(v), chartTextColor, "start")
+	}
+}
+
+// priceLabel formats a price axis value
+func priceLabel(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+// volumeLabel formats a volume axis value with a K, M or B suffix
+func volumeLabel(v float64) string {
+	switch a := math.Abs(v); {
+	case a >= 1e9:
+		return fmt.Sprintf("%.1fB", v/1e9)
+	case a >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case a >= 1e3:
+		return fmt.Sprintf("%.0fK", v/1e3)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+// drawChartLine draws values as a line broken where they are NaN
+func drawChartLine(c chartCanvas, values []float64, x func(int) float64, p chartPane, stroke string) {
+	var points [][2]float64
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			if len(points) > 1 {
+				c.polyline(points, stroke)
+			}
+			points = nil
+			continue
+		}
+		points = append(points, [2]float64{x(i), p.y(v)})
+	}
+	if len(points) > 1 {
+		c.polyline(points, stroke)
+	}
+}
+
+// series returns the overlay named name
+func (s *indicatorSet) series(name string) []float64 {
+	switch name {
+	case "MA5":
+		return s.MA5
+	case "MA10":
+		return s.MA10
+	case "MA20":
+		return s.MA20
+	case "EMA12":
+		return s.EMA12
+	case "EMA26":
+		return s.EMA26
+	}
+	return nil
+}
+
+// svgCanvas writes the chart as SVG elements
+type svgCanvas struct {
+	b strings.Builder
+}
+
+func newSVGCanvas(width, height int) *svgCanvas {
+	c := &svgCanvas{}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`, width, height, width, height)
+	return c
+}
+
+func (c *svgCanvas) rect(x, y, w, h float64, fill string) {
+	fmt.Fprintf(&c.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x, y, w, h, fill)
+}
+
+func (c *svgCanvas) line(x1, y1, x2, y2 float64, stroke string) {
+	fmt.Fprintf(&c.b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, x1, y1, x2, y2, stroke)
+}
+
+func (c *svgCanvas) polyline(points [][2]float64, stroke string) {
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", p[0], p[1])
+	}
+	fmt.Fprintf(&c.b, `<polyline fill="none" stroke="%s" stroke-width="1.2" points="%s"/>`, stroke, strings.Join(coords, " "))
+}
+
+func (c *svgCanvas) text(x, y float64, s, fill, anchor string) {
+	fmt.Fprintf(&c.b, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="%s">%s</text>`, x, y, fill, anchor, template.HTMLEscapeString(s))
+}
+
+func (c *svgCanvas) String() string {
+	return c.b.String() + "</svg>"
+}
+
+// rasterCanvas paints the chart into a bitmap
+type rasterCanvas struct {
+	img *image.RGBA
+}
+
+func newRasterCanvas(width, height int) *rasterCanvas {
+	return &rasterCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+}
+
+// rgba converts #rrggbb to a color
+func rgba(hex string) color.RGBA {
+	r, g, b := hexColor(hex)
+	return color.RGBA{uint8(r*255 + 0.5), uint8(g*255 + 0.5), uint8(b*255 + 0.5), 255}
+}
+
+func (c *rasterCanvas) rect(x, y, w, h float64, fill string) {
+	col := rgba(fill)
+	r := image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h))).Intersect(c.img.Rect)
+	if r.Dx() == 0 && w > 0 {
+		r.Max.X = r.Min.X + 1
+	}
+	if r.Dy() == 0 && h > 0 {
+		r.Max.Y = r.Min.Y + 1
+	}
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			c.img.SetRGBA(px, py, col)
+		}
+	}
+}
+
+// line draws a one-pixel line by stepping along its longer axis
+func (c *rasterCanvas) line(x1, y1, x2, y2 float64, stroke string) {
+	col := rgba(stroke)
+	steps := math.Max(math.Abs(x2-x1), math.Abs(y2-y1))
+	if steps < 1 {
+		steps = 1
+	}
+	for i := 0.0; i <= steps; i++ {
+		t := i / steps
+		c.img.SetRGBA(int(math.Round(x1+(x2-x1)*t)), int(math.Round(y1+(y2-y1)*t)), col)
+	}
+}
+
+func (c *rasterCanvas) polyline(points [][2]float64, stroke string) {
+	for i := 1; i < len(points); i++ {
+		c.line(points[i-1][0], points[i-1][1], points[i][0], points[i][1], stroke)
+	}
+}
+
+// rasterGlyphs is a 3×5 pixel font; each glyph is five rows of three
+// bits, the top row first
+var rasterGlyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {7, 1, 7, 4, 7}, '3': {7, 1, 3, 1, 7},
+	'4': {5, 5, 7, 1, 1}, '5': {7, 4, 7, 1, 7}, '6': {7, 4, 7, 5, 7}, '7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7}, '9': {7, 5, 7, 1, 7},
+	'A': {2, 5, 7, 5, 5}, 'B': {6, 5, 6, 5, 6}, 'C': {3, 4, 4, 4, 3}, 'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7}, 'F': {7, 4, 6, 4, 4}, 'G': {3, 4, 5, 5, 3}, 'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7}, 'J': {1, 1, 1, 5, 2}, 'K': {5, 5, 6, 5, 5}, 'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5}, 'N': {6, 5, 5, 5, 5}, 'O': {2, 5, 5, 5, 2}, 'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3}, 'R': {6, 5, 6, 5, 5}, 'S': {3, 4, 2, 1, 6}, 'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7}, 'V': {5, 5, 5, 5, 2}, 'W': {5, 5, 7, 7, 5}, 'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2}, 'Z': {7, 1, 2, 4, 7},
+	'.': {0, 0, 0, 0, 2}, ',': {0, 0, 0, 2, 4}, '-': {0, 0, 7, 0, 0}, '+': {0, 2, 7, 2, 0},
+	':': {0, 2, 0, 2, 0}, '/': {1, 1, 2, 4, 4}, '%': {5, 1, 2, 4, 5}, '_': {0, 0, 0, 0, 7},
+	'(': {1, 2, 2, 2, 1}, ')': {4, 2, 2, 2, 4}, ' ': {},
+}
+
+// rasterScale is the pixel size of one font dot
+const rasterScale = 2
+
+func (c *rasterCanvas) text(x, y float64, s, fill, anchor string) {
+	col := rgba(fill)
+	var glyphs [][5]uint8
+	for _, r := range strings.ToUpper(s) {
+		if g, ok := rasterGlyphs[r]; ok {
+			glyphs = append(glyphs, g)
+		}
+	}
+	advance := float64(4 * rasterScale)
+	width := float64(len(glyphs))*advance - rasterScale
+	switch anchor {
+	case "middle":
+		x -= width / 2
+	case "end":
+		x -= width
+	}
+	top := int(math.Round(y)) - 5*rasterScale
+	for i, g := range glyphs {
+		left := int(math.Round(x + float64(i)*advance))
+		for row, bits := range g {
+			for col3 := 0; col3 < 3; col3++ {
+				if bits&(4>>col3) == 0 {
+					continue
+				}
+				for dy := 0; dy < rasterScale; dy++ {
+					for dx := 0; dx < rasterScale; dx++ {
+						p := image.Pt(left+col3*rasterScale+dx, top+row*rasterScale+dy)
+						if p.In(c.img.Rect) {
+							c.img.SetRGBA(p.X, p.Y, col)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// renderCandleChart draws the chart of bars as SVG text or PNG bytes
+func renderCandleChart(title string, bars []Bar, opts ChartOptions) ([]byte, error) {
+	set := (*indicatorSet)(nil).extend(bars)
+	if opts.Format == "png" {
+		c := newRasterCanvas(opts.Width, opts.Height)
+		drawCandleChart(c, title, bars, set, opts)
+		var b bytes.Buffer
+		if err := png.Encode(&b, c.img); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+	c := newSVGCanvas(opts.Width, opts.Height)
+	drawCandleChart(c, title, bars, set, opts)
+	return []byte(c.String()), nil
+}
+
+// symbolChart fetches the bars of symbol and draws its chart with
+// normalized opts
+func (a *App) symbolChart(symbol string, opts ChartOptions) ([]byte, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, opts.Days, now), now)
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) == 0 {
+		return nil, codeErrorf(codeNoData, "no data for %s", symbol)
+	}
+	title := symbol
+	if meta, err := a.metadata.get(symbol, false); err == nil && meta.Name != "" {
+		title += " " + meta.Name
+	}
+	return renderCandleChart(title, bars, opts)
+}
+
+// RenderChart draws the candlestick chart of symbol with volume and an
+// indicator pane. optionsJSON is a ChartOptions object, empty for the
+// defaults. SVG is returned as text and PNG base64-encoded.
+func (a *App) RenderChart(symbol string, optionsJSON string) (string, error) {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+	var opts ChartOptions
+	if strings.TrimSpace(optionsJSON) != "" {
+		if err := json.Unmarshal([]byte(optionsJSON), &opts); err != nil {
+			return "", codeErrorf(codeParse, "invalid chart options: %v", err)
+		}
+	}
+	if err := opts.normalize(); err != nil {
+		return "", codeErrorf(codeParse, "%v", err)
+	}
+	data, err := a.symbolChart(symbol, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.Format == "png" {
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+	return string(data), nil
+}
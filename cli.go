@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/indicators"
+)
+
+// cliOptions are the command line flags of headless mode
+type cliOptions struct {
+	Symbol  string
+	Days    int
+	Out     string
+	Format  string
+	AsOf    string
+	Profile string
+}
+
+// cliRequested reports whether args ask for headless mode rather than
+// the GUI. Deep links and unknown platform arguments start the GUI.
+func cliRequested(args []string) bool {
+	for _, arg := range args {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if strings.HasPrefix(arg, "-") && (name == "analyze" || name == "h" || name == "help") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCLI reads the headless mode flags
+func parseCLI(args []string, stderr io.Writer) (cliOptions, error) {
+	var opts cliOptions
+	fs := flag.NewFlagSet("stock-analysis", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.Symbol, "analyze", "", "symbol to analyze, e.g. 600519")
+	fs.IntVar(&opts.Days, "days", 0, "calendar days of history; 0 uses the configured lookback, -1 all stored history")
+	fs.StringVar(&opts.Out, "out", "", "output file; empty or - writes to stdout")
+	fs.StringVar(&opts.Format, "format", "", "csv, json, html, pdf or xlsx; defaults to the extension of -out, else csv")
+	fs.StringVar(&opts.AsOf, "as-of", "", "analyze as of the close of this date (YYYY-MM-DD) instead of today")
+	fs.StringVar(&opts.Profile, "profile", "", "profile id or name; defaults to the startup profile")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: stock-analysis --analyze SYMBOL [--days N] [--out FILE] [--format FORMAT] [--as-of DATE] [--profile NAME]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	if opts.Symbol = normalizeSymbol(opts.Symbol); opts.Symbol == "" {
+		return opts, fmt.Errorf("--analyze is required")
+	}
+	if opts.Format == "" {
+		opts.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.Out)), ".")
+		if opts.Format == "" {
+			opts.Format = "csv"
+		}
+	}
+	switch opts.Format {
+	case "csv", "json", "html", "pdf", "xlsx":
+	default:
+		return opts, fmt.Errorf("unknown format: %s", opts.Format)
+	}
+	if opts.AsOf != "" {
+		if _, err := time.Parse("2006-01-02", opts.AsOf); err != nil {
+			return opts, fmt.Errorf("invalid --as-of date: %s", opts.AsOf)
+		}
+	}
+	return opts, nil
+}
+
+// runCLI runs the analysis pipeline without the GUI and returns the exit
+// code: 0 on success, 1 if the analysis failed and 2 for bad flags. It
+// skips migrations and the background loops, so it is safe to run from
+// cron while the GUI is open.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseCLI(args, stderr)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 2
+	}
+
+	if opts.AsOf != "" {
+		// After the close, so the day's bar counts as complete
+		loc := shanghaiNow().Location()
+		day, _ := time.ParseInLocation("2006-01-02", opts.AsOf, loc)
+		setClock(fixedClock(day.Add(16 * time.Hour)))
+	}
+	a := NewApp(opts.Profile)
+	defer localHistory.Close()
+	defer providerUsage.flush()
+	data, sessions, err := a.analyzeCLI(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		logger.Error("headless analysis failed", "symbol", opts.Symbol, "error", err)
+		return 1
+	}
+	if opts.Out == "" || opts.Out == "-" {
+		_, err = stdout.Write(data)
+	} else {
+		err = os.WriteFile(opts.Out, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+	logger.Info("headless analysis done", "symbol", opts.Symbol, "format", opts.Format, "sessions", sessions)
+	if opts.Out != "" && opts.Out != "-" {
+		fmt.Fprintf(stderr, "Wrote %s (%s, %d sessions)\n", opts.Out, opts.Symbol, sessions)
+	}
+	return 0
+}
+
+// analyzeCLI fetches the bars of opts.Symbol and renders them in
+// opts.Format. It returns the output and the number of sessions.
+func (a *App) analyzeCLI(opts cliOptions) ([]byte, int, error) {
+	days := opts.Days
+	if opts.Format == "xlsx" && (days == 0 || days < lookbackMax) {
+		days = 365
+	}
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(opts.Symbol, lookbackStart(opts.Symbol, days, now), now)
+	if err != nil {
+		return nil, 0, err
+	}
+	a.recent.touch(opts.Symbol)
+	report := a.buildReport(opts.Symbol, bars)
+
+	var data []byte
+	switch opts.Format {
+	case "csv":
+		data, err = barsCSV(bars)
+	case "json":
+		data, err = json.MarshalIndent(report, "", "  ")
+	case "html":
+		var page string
+		page, err = report.html()
+		data = []byte(page)
+	case "pdf":
+		data = report.pdf()
+	case "xlsx":
+		data, err = buildXLSX(symbolWorkbook(opts.Symbol, report.Name, bars))
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, len(bars), nil
+}
+
+// barsCSV writes bars with their indicator series as CSV. Indicators are
+// empty until they have enough history.
+func barsCSV(bars []Bar) ([]byte, error) {
+	prices := closes(bars)
+	volumes := make([]float64, len(bars))
+	for i, b := range bars {
+		volumes[i] = b.Volume
+	}
+	ma5, ma10, ma20 := indicators.SMA(prices, 5), indicators.SMA(prices, 10), indicators.SMA(prices, 20)
+	dif, dea, hist := indicators.MACD(prices, 12, 26, 9)
+	atr := ATR(bars, 14)
+
+	num := func(v float64) string {
+		if math.IsNaN(v) {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"date", "open", "high", "low", "close", "volume", "changePct",
+		"ma5", "ma10", "ma20", "dif", "dea", "macd", "atr14"})
+	for i, bar := range bars {
+		w.Write([]string{bar.Date, num(bar.Open), num(bar.High), num(bar.Low), num(bar.Close),
+			num(bar.Volume), num(bar.ChangePct), num(ma5[i]), num(ma10[i]), num(ma20[i]),
+			num(dif[i]), num(dea[i]), num(hist[i]), num(atr[i])})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %v", err)
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Tables go on the clipboard as tab-separated values, which Excel, WPS
+// and most spreadsheets split into cells on paste. Numbers are written in
+// full precision without grouping so they paste as numbers, not text.
+
+// ClipboardTable is a table the frontend asks to copy
+type ClipboardTable struct {
+	Headers []string        `json:"headers"`
+	Rows    [][]interface{} `json:"rows"` // strings, numbers, booleans or null
+}
+
+// tsvField quotes a field that holds a tab, newline or quote the way
+// spreadsheets read it
+func tsvField(s string) string {
+	if !strings.ContainsAny(s, "\t\r\n\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// tsvNumber formats a number for pasting; NaN and infinities paste as
+// empty cells
+func tsvNumber(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatTSV writes rows as tab-separated lines
+func formatTSV(rows [][]string) string {
+	var b strings.Builder
+	for _, row := range rows {
+		for i, field := range row {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			b.WriteString(tsvField(field))
+		}
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// copyText places text on the system clipboard
+func (a *App) copyText(text string) error {
+	if a.ctx == nil {
+		return fmt.Errorf("the clipboard is not available")
+	}
+	if err := wailsruntime.ClipboardSetText(a.ctx, text); err != nil {
+		return fmt.Errorf("failed to copy to the clipboard: %v", err)
+	}
+	return nil
+}
+
+// CopyTable places a table from the frontend on the clipboard as TSV.
+// tableJSON is a ClipboardTable.
+func (a *App) CopyTable(tableJSON string) error {
+	var table ClipboardTable
+	if err := json.Unmarshal([]byte(tableJSON), &table); err != nil {
+		return codeErrorf(codeParse, "failed to parse table: %v", err)
+	}
+	rows := make([][]string, 0, len(table.Rows)+1)
+	if len(table.Headers) > 0 {
+		rows = append(rows, table.Headers)
+	}
+	for _, row := range table.Rows {
+		fields := make([]string, len(row))
+		for i, v := range row {
+			switch v := v.(type) {
+			case nil:
+			case float64:
+				fields[i] = tsvNumber(v)
+			case string:
+				fields[i] = v
+			default:
+				fields[i] = fmt.Sprint(v)
+			}
+		}
+		rows = append(rows, fields)
+	}
+	return a.copyText(formatTSV(rows))
+}
+
+// CopyBars places the daily bars of the last days of symbol on the
+// clipboard as TSV, with the same columns as the Excel export. days of 0
+// uses the configured lookback and lookbackMax (-1) all available history.
+func (a *App) CopyBars(symbol string, days int) error {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{{tr("report.date"), tr("xlsx.open"), tr("report.close"), tr("xlsx.change"), tr("xlsx.changePct"),
+		tr("report.low"), tr("report.high"), tr("xlsx.volume"), tr("xlsx.turnover"), tr("xlsx.turnoverRate")}}
+	for _, b := range bars {
+		rows = append(rows, []string{b.Date, tsvNumber(b.Open), tsvNumber(b.Close), tsvNumber(b.Change), tsvNumber(b.ChangePct),
+			tsvNumber(b.Low), tsvNumber(b.High), tsvNumber(b.Volume), tsvNumber(b.Turnover), tsvNumber(b.TurnoverRate)})
+	}
+	return a.copyText(formatTSV(rows))
+}
+
+// CopyIndicatorTable places an indicator table on the clipboard as TSV,
+// one row per date. The arguments are those of GetIndicatorTable.
+func (a *App) CopyIndicatorTable(symbol string, days int, specJSON string) error {
+	result, err := a.GetIndicatorTable(symbol, days, specJSON)
+	if err != nil {
+		return err
+	}
+	var table IndicatorTable
+	if err := json.Unmarshal([]byte(result), &table); err != nil {
+		return err
+	}
+	header := []string{tr("report.date")}
+	for _, col := range table.Columns {
+		header = append(header, col.Name)
+	}
+	rows := [][]string{header}
+	for i, date := range table.Dates {
+		row := []string{date}
+		for _, col := range table.Columns {
+			field := ""
+			if v := col.Values[i]; v != nil {
+				field = tsvNumber(*v)
+			}
+			row = append(row, field)
+		}
+		rows = append(rows, row)
+	}
+	return a.copyText(formatTSV(rows))
+}
+
+// quoteSummary is a one-line summary of a quote, such as
+// "cn_600519 贵州茅台 1700.00 +1.23% (2024-05-08)"
+func quoteSummary(q Quote, name string) string {
+	line := strings.TrimSpace(q.Symbol + " " + name)
+	return fmt.Sprintf("%s %.2f %+.2f%% (%s)", line, q.Price, q.ChangePct, q.Date)
+}
+
+// CopyQuoteSummary places a one-line summary of the latest quote of each
+// symbol on the clipboard and returns the copied text. Symbols whose
+// quote cannot be fetched are left out.
+func (a *App) CopyQuoteSummary(symbols []string) (string, error) {
+	var lines []string
+	for _, symbol := range symbols {
+		symbol = normalizeSymbol(symbol)
+		if symbol == "" {
+			continue
+		}
+		q, err := fetchQuote(symbol)
+		if err != nil {
+			logger.Warn("quote left out of the summary", "symbol", symbol, "error", err)
+			continue
+		}
+		name := ""
+		if meta, err := a.metadata.get(symbol, false); err == nil {
+			name = meta.Name
+		}
+		lines = append(lines, quoteSummary(q, name))
+	}
+	if len(lines) == 0 {
+		return "", codeErrorf(codeNoData, "no quotes to copy")
+	}
+	text := strings.Join(lines, "\n") + "\n" + shanghaiNow().Format("2006-01-02 15:04:05")
+	if err := a.copyText(text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time. Date ranges, trading session checks and the
+// schedulers read it through shanghaiNow, so tests can freeze or advance
+// time by installing their own Clock. Timeouts, cache expiry and rate
+// measurements keep using the wall clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// fixedClock is frozen at one instant
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// clockBox wraps the installed Clock, since atomic.Value needs one
+// concrete type
+type clockBox struct{ Clock }
+
+// appClock holds the Clock in use
+var appClock atomic.Value
+
+func init() {
+	appClock.Store(clockBox{systemClock{}})
+}
+
+// setClock installs c and returns a function restoring the previous clock
+func setClock(c Clock) (restore func()) {
+	prev := appClock.Swap(clockBox{c})
+	return func() { appClock.Store(prev) }
+}
+
+// clockNow returns the time of the installed clock
+func clockNow() time.Time {
+	return appClock.Load().(clockBox).Now()
+}
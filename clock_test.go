@@ -0,0 +1,161 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// advancingClock starts at an instant and moves only when advanced, so a
+// test can walk the schedulers through a day
+type advancingClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *advancingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// advance moves the clock forward by d
+func (c *advancingClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// shanghaiTime returns the time in Shanghai at "2006-01-02 15:04"
+func shanghaiTime(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, shanghaiNow().Location())
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestSetClock(t *testing.T) {
+	clock := &advancingClock{now: shanghaiTime("2024-09-13 09:30")}
+	restore := setClock(clock)
+	if got := shanghaiNow(); !got.Equal(clock.now) {
+		t.Fatalf("shanghaiNow = %v, want %v", got, clock.now)
+	}
+	clock.advance(90 * time.Minute)
+	if got := shanghaiNow().Format("15:04"); got != "11:00" {
+		t.Errorf("after advancing, shanghaiNow is %s, want 11:00", got)
+	}
+
+	inner := setClock(fixedClock(shanghaiTime("2020-01-02 10:00")))
+	if got := shanghaiNow().Year(); got != 2020 {
+		t.Errorf("fixed clock year = %d, want 2020", got)
+	}
+	inner()
+	if got := shanghaiNow().Format("15:04"); got != "11:00" {
+		t.Errorf("restoring the fixed clock gave %s, want the advancing clock's 11:00", got)
+	}
+	restore()
+	if d := time.Since(shanghaiNow()); d < 0 || d > time.Minute {
+		t.Errorf("restored clock is %v off the wall clock", d)
+	}
+}
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2024-09-13", true},  // Friday
+		{"2024-09-14", false}, // Saturday make-up workday; the exchanges stay shut
+		{"2024-09-15", false}, // Sunday
+		{"2024-09-16", false}, // Mid-Autumn Festival
+		{"2024-09-17", false},
+		{"2024-09-18", true},
+		{"2024-09-30", true}, // eve of National Day
+		{"2024-10-01", false},
+		{"2024-10-07", false},
+		{"2024-10-08", true},
+		{"2024-10-12", false}, // Saturday make-up workday
+		{"2026-02-16", false}, // Spring Festival
+		{"2026-02-24", true},
+	}
+	newTestApp(t)
+	for _, tt := range tests {
+		if got := isTradingDay(shanghaiDay(tt.date)); got != tt.want {
+			t.Errorf("isTradingDay(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteThrough(t *testing.T) {
+	tests := []struct {
+		now, want string
+	}{
+		{"2024-09-13 00:00", "2024-09-12"},
+		{"2024-09-13 15:00", "2024-09-12"}, // the close, but bars are not final yet
+		{"2024-09-13 15:29", "2024-09-12"},
+		{"2024-09-13 15:30", "2024-09-13"},
+		{"2024-09-13 23:59", "2024-09-13"},
+	}
+	for _, tt := range tests {
+		if got := completeThrough(shanghaiTime(tt.now)).Format("2006-01-02"); got != tt.want {
+			t.Errorf("completeThrough(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+// TestSchedulersOverAWeek walks the clock from a Friday close across the
+// weekend and the Mid-Autumn holiday, checking when the sync and the
+// daily summary fall due
+func TestSchedulersOverAWeek(t *testing.T) {
+	a := newTestApp(t)
+	clock := &advancingClock{now: shanghaiTime("2024-09-13 15:09")}
+	defer setClock(clock)()
+
+	due := func(wantSync, wantSummary bool) {
+		t.Helper()
+		now := shanghaiNow()
+		if got := a.syncDue(now); got != wantSync {
+			t.Errorf("%s: syncDue = %v, want %v", now.Format("Mon 2006-01-02 15:04"), got, wantSync)
+		}
+		if got := a.summaryDue(now); got != wantSummary {
+			t.Errorf("%s: summaryDue = %v, want %v", now.Format("Mon 2006-01-02 15:04"), got, wantSummary)
+		}
+	}
+	finish := func() {
+		t.Helper()
+		date := shanghaiNow().Format("2006-01-02")
+		if _, err := a.syncs.update(func(s *SyncStatus) { s.Date, s.Running = date, false }); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.summaries.save(DailySummary{Date: date}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	due(false, false) // Friday before the sync time
+	clock.advance(time.Minute)
+	due(true, false) // 15:10
+	if _, err := a.syncs.update(func(s *SyncStatus) { s.Running = true }); err != nil {
+		t.Fatal(err)
+	}
+	due(false, false) // not while a sync runs
+	clock.advance(20 * time.Minute)
+	due(false, true) // 15:30, the sync still running
+	finish()
+	due(false, false) // both done for the day
+
+	// Saturday, Sunday and the two holiday days
+	for day := 0; day < 4; day++ {
+		clock.advance(24 * time.Hour)
+		due(false, false)
+	}
+
+	clock.advance(24*time.Hour - 30*time.Minute) // Wednesday 15:00
+	due(false, false)
+	clock.advance(30 * time.Minute)
+	due(true, true) // Wednesday 15:30
+	finish()
+	clock.advance(8 * time.Hour)
+	due(false, false) // late the same evening
+}
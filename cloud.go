@@ -0,0 +1,721 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Cloud sync keeps watchlists, alert rules and settings the same on
+// several machines through a WebDAV folder or S3-compatible bucket the
+// user provides. Each item is one JSON document of keyed records: the
+// lists and rules by ID, the settings by field. A sync merges the local
+// and remote records three ways against the records of the last sync, so
+// edits on both machines combine; a record changed differently on both
+// is a conflict, settled by the conflict policy.
+
+const (
+	// cloudCheckInterval is how often the loop checks whether a sync is due
+	cloudCheckInterval = time.Minute
+	// cloudAttempts is how often a sync retries an item whose remote
+	// document changed while it was merged
+	cloudAttempts = 3
+
+	eventCloudSync = "cloud:sync"
+)
+
+var errCloudSyncRunning error = &codedError{codeBusy, errors.New("a cloud sync is already running")}
+
+// cloudItems are the items that can be synced, in sync order
+var cloudItems = []string{"watchlists", "alertRules", "settings"}
+
+// cloudSettingsFields are the settings that follow the user between
+// machines; the proxy, tray, push server, fixtures and crash reporting
+// belong to the machine
+var cloudSettingsFields = []string{"defaultSymbols", "lookbackDays", "refresh", "providers", "quotas", "updateChannel", "locale"}
+
+// CloudSyncConfig is where and what to sync
+type CloudSyncConfig struct {
+	Enabled         bool     `json:"enabled"`
+	Provider        string   `json:"provider"` // "webdav" or "s3"
+	Endpoint        string   `json:"endpoint"` // WebDAV folder URL, or the S3 endpoint such as https://s3.amazonaws.com
+	Bucket          string   `json:"bucket,omitempty"`
+	Region          string   `json:"region,omitempty"` // S3 only; empty for us-east-1
+	Prefix          string   `json:"prefix"`           // folder or key prefix of the documents
+	Username        string   `json:"username"`         // WebDAV user, or the S3 access key ID
+	Secret          string   `json:"secret,omitempty"` // WebDAV password or S3 secret key; write-only
+	HasSecret       bool     `json:"hasSecret"`
+	Items           []string `json:"items"`           // of cloudItems
+	Conflict        string   `json:"conflict"`        // "newer", "local" or "remote"
+	IntervalMinutes int      `json:"intervalMinutes"` // 0 syncs only on request
+}
+
+// CloudItemResult is the outcome of syncing one item
+type CloudItemResult struct {
+	Item      string   `json:"item"`
+	Pulled    int      `json:"pulled"` // records changed locally
+	Pushed    bool     `json:"pushed"` // the remote document was written
+	Conflicts []string `json:"conflicts,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// CloudSyncResult is the outcome of a sync run
+type CloudSyncResult struct {
+	Trigger    string            `json:"trigger"` // "schedule" or "manual"
+	StartedAt  string            `json:"startedAt"`
+	FinishedAt string            `json:"finishedAt"`
+	Items      []CloudItemResult `json:"items"`
+}
+
+// cloudRecord is one keyed record of a sync document
+type cloudRecord struct {
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data"`
+}
+
+// cloudDocument is what is stored remotely for an item
+type cloudDocument struct {
+	Device    string        `json:"device"`
+	UpdatedAt string        `json:"updatedAt"`
+	Records   []cloudRecord `json:"records"`
+}
+
+// cloudState is cloud_sync.json: the configuration with the secret sealed,
+// and the records of the last sync of every item as the merge base
+type cloudState struct {
+	Config     CloudSyncConfig          `json:"config"`
+	Sealed     string                   `json:"sealedSecret,omitempty"`
+	Device     string                   `json:"device"`
+	Base       map[string][]cloudRecord `json:"base"`
+	BaseAt     map[string]string        `json:"baseAt"` // when each base was synced
+	LastResult *CloudSyncResult         `json:"lastResult,omitempty"`
+}
+
+// cloudStore guards cloud_sync.json
+type cloudStore struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	state   cloudState
+	running bool
+}
+
+func newCloudStore(dataDir string) *cloudStore {
+	return &cloudStore{path: filepath.Join(dataDir, "cloud_sync.json")}
+}
+
+// unload forgets the state so the next use reads the file again
+func (s *cloudStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state, s.loaded = cloudState{}, false
+}
+
+// load reads the state from disk on first use. Callers hold s.mu.
+func (s *cloudStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	state := cloudState{Config: CloudSyncConfig{Provider: "webdav", Prefix: "stock-analysis", Items: cloudItems, Conflict: "newer"}}
+	if err := loadJSON(s.path, &state); err != nil {
+		return err
+	}
+	if state.Device == "" {
+		state.Device = newID()
+	}
+	if state.Base == nil {
+		state.Base = make(map[string][]cloudRecord)
+	}
+	if state.BaseAt == nil {
+		state.BaseAt = make(map[string]string)
+	}
+	s.state, s.loaded = state, true
+	return nil
+}
+
+// update applies fn to the state and saves the result
+func (s *cloudStore) update(fn func(state *cloudState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := fn(&s.state); err != nil {
+		return err
+	}
+	return saveJSON(s.path, s.state)
+}
+
+// snapshot returns a copy of the state
+func (s *cloudStore) snapshot() (cloudState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return cloudState{}, err
+	}
+	out := s.state
+	out.Base = make(map[string][]cloudRecord, len(s.state.Base))
+	for k, v := range s.state.Base {
+		out.Base[k] = v
+	}
+	return out, nil
+}
+
+// begin marks a run as started, failing if one is running
+func (s *cloudStore) begin() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return errCloudSyncRunning
+	}
+	s.running = true
+	return nil
+}
+
+// end marks the run as finished
+func (s *cloudStore) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+}
+
+// validate checks a configuration before it is saved
+func (c *CloudSyncConfig) validate() error {
+	switch c.Provider {
+	case "webdav", "s3":
+	default:
+		return codeErrorf(codeParse, "unknown cloud provider: %s", c.Provider)
+	}
+	switch c.Conflict {
+	case "":
+		c.Conflict = "newer"
+	case "newer", "local", "remote":
+	default:
+		return codeErrorf(codeParse, "unknown conflict policy: %s", c.Conflict)
+	}
+	for _, item := range c.Items {
+		if !containsString(cloudItems, item) {
+			return codeErrorf(codeParse, "unknown sync item: %s", item)
+		}
+	}
+	if c.IntervalMinutes < 0 {
+		return codeErrorf(codeParse, "interval must not be negative")
+	}
+	if _, err := newCloudRemote(*c, ""); err != nil && (c.Enabled || c.Endpoint != "") {
+		return err
+	}
+	return nil
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRecords reports whether two record lists hold the same records in
+// the same order
+func sameRecords(a, b []cloudRecord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || !sameJSON(a[i].Data, b[i].Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameJSON compares two JSON values ignoring formatting
+func sameJSON(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return string(a) == string(b)
+	}
+	ax, _ := json.Marshal(x)
+	by, _ := json.Marshal(y)
+	return string(ax) == string(by)
+}
+
+// mergeRecords merges local and remote against base, the records both
+// had after the last sync. A record changed on one side only takes that
+// change, deletions included. A record changed differently on both sides
+// is a conflict: an edit beats a deletion, otherwise the side preferred
+// wins. Local order is kept, with records new on the remote appended.
+func mergeRecords(base, local, remote []cloudRecord, preferLocal bool) (merged []cloudRecord, conflicts []string) {
+	index := func(records []cloudRecord) map[string]json.RawMessage {
+		m := make(map[string]json.RawMessage, len(records))
+		for _, r := range records {
+			m[r.Key] = r.Data
+		}
+		return m
+	}
+	b, l, r := index(base), index(local), index(remote)
+	equal := func(x, y json.RawMessage) bool {
+		if x == nil || y == nil {
+			return x == nil && y == nil
+		}
+		return sameJSON(x, y)
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, records := range [][]cloudRecord{local, remote, base} {
+		for _, rec := range records {
+			if !seen[rec.Key] {
+				seen[rec.Key] = true
+				keys = append(keys, rec.Key)
+			}
+		}
+	}
+	for _, key := range keys {
+		lv, rv, bv := l[key], r[key], b[key]
+		var pick json.RawMessage
+		switch {
+		case equal(lv, rv):
+			pick = lv
+		case equal(lv, bv):
+			pick = rv
+		case equal(rv, bv):
+			pick = lv
+		default:
+			conflicts = append(conflicts, key)
+			switch {
+			case lv == nil:
+				pick = rv
+			case rv == nil:
+				pick = lv
+			case preferLocal:
+				pick = lv
+			default:
+				pick = rv
+			}
+		}
+		if pick != nil {
+			merged = append(merged, cloudRecord{Key: key, Data: pick})
+		}
+	}
+	return merged, conflicts
+}
+
+// cloudLocal reads the records of item and when they last changed
+func (a *App) cloudLocal(item string) ([]cloudRecord, time.Time, error) {
+	var records []cloudRecord
+	var path string
+	add := func(key string, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		records = append(records, cloudRecord{Key: key, Data: data})
+		return nil
+	}
+	switch item {
+	case "watchlists":
+		s := a.watchlists
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.load(); err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, w := range s.lists {
+			if err := add(w.ID, w); err != nil {
+				return nil, time.Time{}, err
+			}
+		}
+		path = s.path
+	case "alertRules":
+		s := a.alerts
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.load(); err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, r := range s.rules {
+			// When a rule last fired is the state of this machine
+			rule := *r
+			rule.LastFired = ""
+			if err := add(rule.ID, rule); err != nil {
+				return nil, time.Time{}, err
+			}
+		}
+		path = s.rulesPath
+	case "settings":
+		settings, err := a.settings.get()
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		data, _ := json.Marshal(settings)
+		var fields map[string]json.RawMessage
+		json.Unmarshal(data, &fields)
+		for _, name := range cloudSettingsFields {
+			records = append(records, cloudRecord{Key: name, Data: fields[name]})
+		}
+		path = a.settings.path
+	}
+	var modified time.Time
+	if info, err := os.Stat(path); err == nil {
+		modified = info.ModTime()
+	}
+	return records, modified, nil
+}
+
+// cloudApply replaces the local records of item with records
+func (a *App) cloudApply(item string, records []cloudRecord) error {
+	switch item {
+	case "watchlists":
+		lists := make([]*Watchlist, 0, len(records))
+		for _, rec := range records {
+			var w Watchlist
+			if err := json.Unmarshal(rec.Data, &w); err != nil {
+				return codeErrorf(codeParse, "invalid remote watchlist %s: %v", rec.Key, err)
+			}
+			lists = append(lists, &w)
+		}
+		s := a.watchlists
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := saveJSON(s.path, lists); err != nil {
+			return err
+		}
+		s.lists, s.loaded = lists, true
+		return nil
+	case "alertRules":
+		s := a.alerts
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.load(); err != nil {
+			return err
+		}
+		fired := make(map[string]string, len(s.rules))
+		for _, r := range s.rules {
+			fired[r.ID] = r.LastFired
+		}
+		rules := make([]*AlertRule, 0, len(records))
+		for _, rec := range records {
+			var r AlertRule
+			if err := json.Unmarshal(rec.Data, &r); err != nil {
+				return codeErrorf(codeParse, "invalid remote alert rule %s: %v", rec.Key, err)
+			}
+			if err := r.validate(); err != nil {
+				return codeErrorf(codeParse, "invalid remote alert rule %s: %v", rec.Key, err)
+			}
+			r.LastFired = fired[r.ID]
+			rules = append(rules, &r)
+		}
+		if err := saveJSON(s.rulesPath, rules); err != nil {
+			return err
+		}
+		s.rules = rules
+		return nil
+	case "settings":
+		s := a.settings
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.load(); err != nil {
+			return err
+		}
+		data, _ := json.Marshal(s.settings)
+		var fields map[string]json.RawMessage
+		json.Unmarshal(data, &fields)
+		for _, rec := range records {
+			if containsString(cloudSettingsFields, rec.Key) {
+				fields[rec.Key] = rec.Data
+			}
+		}
+		data, _ = json.Marshal(fields)
+		var updated Settings
+		if err := json.Unmarshal(data, &updated); err != nil {
+			return codeErrorf(codeParse, "invalid remote settings: %v", err)
+		}
+		if err := updated.normalize(); err != nil {
+			return codeErrorf(codeParse, "invalid remote settings: %v", err)
+		}
+		if err := s.save(updated); err != nil {
+			return err
+		}
+		a.emitSettingsChanged(updated)
+		return nil
+	}
+	return nil
+}
+
+// syncItem merges item with its remote document. It retries when the
+// remote document changes between reading and writing it.
+func (a *App) syncItem(ctx context.Context, remote cloudRemote, state cloudState, item string) (CloudItemResult, []cloudRecord, error) {
+	result := CloudItemResult{Item: item}
+	name := item + ".json"
+	for attempt := 1; ; attempt++ {
+		local, modified, err := a.cloudLocal(item)
+		if err != nil {
+			return result, nil, err
+		}
+		data, etag, err := remote.get(ctx, name)
+		if err != nil {
+			return result, nil, err
+		}
+		var doc cloudDocument
+		if data != nil {
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return result, nil, codeErrorf(codeParse, "remote %s is not a sync document: %v", name, err)
+			}
+		}
+
+		preferLocal := state.Config.Conflict == "local"
+		if state.Config.Conflict == "newer" {
+			remoteAt, _ := time.Parse(time.RFC3339, doc.UpdatedAt)
+			preferLocal = !modified.Before(remoteAt)
+		}
+		base := state.Base[item]
+		if data == nil {
+			// Nothing remote, or it was removed: this machine's records
+			// start the document again
+			base = nil
+		}
+		merged, conflicts := mergeRecords(base, local, doc.Records, preferLocal)
+		result.Conflicts = conflicts
+
+		if data == nil || !sameRecords(merged, doc.Records) {
+			next := cloudDocument{Device: state.Device, UpdatedAt: shanghaiNow().Format(time.RFC3339), Records: merged}
+			if next.Records == nil {
+				next.Records = []cloudRecord{}
+			}
+			body, err := json.MarshalIndent(next, "", "  ")
+			if err != nil {
+				return result, nil, err
+			}
+			if err := remote.put(ctx, name, body, etag); err == errRemoteChanged && attempt < cloudAttempts {
+				continue
+			} else if err != nil {
+				return result, nil, err
+			}
+			result.Pushed = true
+		}
+		if !sameRecords(merged, local) {
+			if err := a.cloudApply(item, merged); err != nil {
+				return result, nil, err
+			}
+			result.Pulled = countChanged(local, merged)
+		}
+		return result, merged, nil
+	}
+}
+
+// countChanged counts the records added, changed or removed from before
+// to after
+func countChanged(before, after []cloudRecord) int {
+	old := make(map[string]json.RawMessage, len(before))
+	for _, r := range before {
+		old[r.Key] = r.Data
+	}
+	n := 0
+	for _, r := range after {
+		if prev, ok := old[r.Key]; !ok || !sameJSON(prev, r.Data) {
+			n++
+		}
+		delete(old, r.Key)
+	}
+	return n + len(old)
+}
+
+// runCloudSync syncs every configured item
+func (a *App) runCloudSync(ctx context.Context, trigger string) (CloudSyncResult, error) {
+	if err := a.cloud.begin(); err != nil {
+		return CloudSyncResult{}, err
+	}
+	defer a.cloud.end()
+
+	state, err := a.cloud.snapshot()
+	if err != nil {
+		return CloudSyncResult{}, err
+	}
+	config := state.Config
+	if config.Endpoint == "" {
+		return CloudSyncResult{}, fmt.Errorf("cloud sync is not set up")
+	}
+	if !connectivity.allow() {
+		return CloudSyncResult{}, errOffline
+	}
+	secret := ""
+	if state.Sealed != "" {
+		if secret, err = a.apiKeys.openSecret(state.Sealed); err != nil {
+			return CloudSyncResult{}, err
+		}
+	}
+	remote, err := newCloudRemote(config, secret)
+	if err != nil {
+		return CloudSyncResult{}, err
+	}
+
+	result := CloudSyncResult{Trigger: trigger, StartedAt: shanghaiNow().Format(time.RFC3339), Items: []CloudItemResult{}}
+	bases := make(map[string][]cloudRecord)
+	for _, item := range cloudItems {
+		if !containsString(config.Items, item) {
+			continue
+		}
+		itemResult, merged, err := a.syncItem(ctx, remote, state, item)
+		if err != nil {
+			itemResult.Error = err.Error()
+			logger.Warn("cloud sync failed", "item", item, "error", err)
+		} else {
+			bases[item] = merged
+		}
+		result.Items = append(result.Items, itemResult)
+	}
+	result.FinishedAt = shanghaiNow().Format(time.RFC3339)
+
+	err = a.cloud.update(func(s *cloudState) error {
+		for item, merged := range bases {
+			s.Base[item] = merged
+			s.BaseAt[item] = result.FinishedAt
+		}
+		s.LastResult = &result
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	logger.Info("cloud sync done", "trigger", trigger, "items", len(result.Items))
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventCloudSync, result)
+	}
+	return result, nil
+}
+
+// cloudSyncDue reports whether a scheduled sync should run at now
+func (a *App) cloudSyncDue(now time.Time) bool {
+	state, err := a.cloud.snapshot()
+	if err != nil || !state.Config.Enabled || state.Config.IntervalMinutes <= 0 {
+		return false
+	}
+	if state.LastResult == nil {
+		return true
+	}
+	last, err := time.Parse(time.RFC3339, state.LastResult.StartedAt)
+	return err != nil || now.Sub(last) >= time.Duration(state.Config.IntervalMinutes)*time.Minute
+}
+
+// runCloudSyncLoop syncs on the configured interval until ctx ends
+func (a *App) runCloudSyncLoop(ctx context.Context) {
+	ticker := time.NewTicker(cloudCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !a.cloudSyncDue(shanghaiNow()) {
+				continue
+			}
+			if _, err := a.runCloudSync(ctx, "schedule"); err != nil && err != errCloudSyncRunning {
+				logger.Warn("scheduled cloud sync failed", "error", err)
+			}
+		}
+	}
+}
+
+// GetCloudSyncConfig returns the cloud sync configuration. The secret is
+// never returned; hasSecret tells whether one is stored.
+func (a *App) GetCloudSyncConfig() (string, error) {
+	state, err := a.cloud.snapshot()
+	if err != nil {
+		return "", err
+	}
+	config := state.Config
+	config.Secret, config.HasSecret = "", state.Sealed != ""
+	return toJSON(config)
+}
+
+// SaveCloudSyncConfig stores the cloud sync configuration. An empty
+// secret keeps the stored one. Changing the endpoint, bucket or prefix
+// starts over: the next sync merges with whatever is there as if for the
+// first time.
+func (a *App) SaveCloudSyncConfig(configJSON string) (string, error) {
+	var config CloudSyncConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse cloud sync settings: %v", err)
+	}
+	config.Endpoint = strings.TrimSpace(config.Endpoint)
+	config.Prefix = strings.Trim(strings.TrimSpace(config.Prefix), "/")
+	if config.Items == nil {
+		config.Items = cloudItems
+	}
+	sort.SliceStable(config.Items, func(i, j int) bool {
+		return indexOf(cloudItems, config.Items[i]) < indexOf(cloudItems, config.Items[j])
+	})
+	if err := config.validate(); err != nil {
+		return "", err
+	}
+	secret := config.Secret
+	config.Secret = ""
+	err := a.cloud.update(func(s *cloudState) error {
+		if secret != "" {
+			sealed, err := a.apiKeys.sealSecret(secret)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt the secret: %v", err)
+			}
+			s.Sealed = sealed
+		}
+		old := s.Config
+		if old.Provider != config.Provider || old.Endpoint != config.Endpoint || old.Bucket != config.Bucket || old.Prefix != config.Prefix {
+			s.Base, s.BaseAt = make(map[string][]cloudRecord), make(map[string]string)
+		}
+		config.HasSecret = s.Sealed != ""
+		s.Config = config
+		return nil
+	})
+	recordAudit(auditSettings, "update", "cloud sync", config.Provider+" "+config.Endpoint, err)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(config)
+}
+
+// indexOf returns the position of s in list, or len(list)
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return len(list)
+}
+
+// CloudSyncNow syncs the configured items with the remote and returns
+// what changed
+func (a *App) CloudSyncNow() (string, error) {
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	result, err := a.runCloudSync(ctx, "manual")
+	if err != nil {
+		return "", err
+	}
+	return toJSON(result)
+}
+
+// GetCloudSyncStatus returns the outcome of the latest sync, or null
+func (a *App) GetCloudSyncStatus() (string, error) {
+	state, err := a.cloud.snapshot()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(state.LastResult)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxCloudDocument bounds what is read back from the remote
+const maxCloudDocument = 8 << 20
+
+// errRemoteChanged means the remote document changed since it was read
+var errRemoteChanged = errors.New("the remote document changed meanwhile")
+
+// cloudRemote stores sync documents by name on a user-provided endpoint
+type cloudRemote interface {
+	// get returns a document and its ETag; data is nil if it does not
+	// exist yet
+	get(ctx context.Context, name string) (data []byte, etag string, err error)
+	// put writes a document if it still has etag, or does not exist yet
+	// when etag is empty. It returns errRemoteChanged otherwise.
+	put(ctx context.Context, name string, data []byte, etag string) error
+}
+
+// cloudClient goes straight to the connection pool: fixtures and
+// validators would keep credentials and personal data on disk
+var cloudClient = &http.Client{Transport: providerTransport, Timeout: 30 * time.Second}
+
+// newCloudRemote returns the remote for config with its secret in the
+// clear
+func newCloudRemote(config CloudSyncConfig, secret string) (cloudRemote, error) {
+	base, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || (base.Scheme != "https" && base.Scheme != "http") || base.Host == "" {
+		return nil, codeErrorf(codeParse, "invalid endpoint: %s", config.Endpoint)
+	}
+	prefix := strings.Trim(config.Prefix, "/")
+	switch config.Provider {
+	case "webdav":
+		return &webdavRemote{base: base, prefix: prefix, user: config.Username, password: secret}, nil
+	case "s3":
+		if config.Bucket == "" {
+			return nil, codeErrorf(codeParse, "an S3 bucket is required")
+		}
+		region := config.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		return &s3Remote{base: base, bucket: config.Bucket, prefix: prefix, region: region, accessKey: config.Username, secretKey: secret}, nil
+	default:
+		return nil, codeErrorf(codeParse, "unknown cloud provider: %s", config.Provider)
+	}
+}
+
+// doCloud sends req and classifies the status. A 404 returns a nil
+// response and no error.
+func doCloud(req *http.Request) (*http.Response, error) {
+	resp, err := cloudClient.Do(req)
+	if err != nil {
+		return nil, codeErrorf(codeNetwork, "%v", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		resp.Body.Close()
+		return nil, errRemoteChanged
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, codeErrorf(codeProvider, "%s %s: access denied, check the credentials", req.Method, req.URL.Redacted())
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, codeErrorf(codeProvider, "%s %s: %s %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// readCloud reads the body of a document response
+func readCloud(resp *http.Response) ([]byte, string, error) {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCloudDocument+1))
+	if err != nil {
+		return nil, "", codeErrorf(codeNetwork, "%v", err)
+	}
+	if len(data) > maxCloudDocument {
+		return nil, "", fmt.Errorf("remote document is larger than %d bytes", maxCloudDocument)
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// webdavRemote keeps documents as files in a WebDAV folder
+type webdavRemote struct {
+	base     *url.URL
+	prefix   string
+	user     string
+	password string
+}
+
+func (r *webdavRemote) url(name string) string {
+	u := *r.base
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + strings.Trim(r.prefix+"/"+name, "/")
+	return u.String()
+}
+
+func (r *webdavRemote) request(ctx context.Context, method, target string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if r.user != "" || r.password != "" {
+		req.SetBasicAuth(r.user, r.password)
+	}
+	return req, nil
+}
+
+func (r *webdavRemote) get(ctx context.Context, name string) ([]byte, string, error) {
+	req, err := r.request(ctx, http.MethodGet, r.url(name), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := doCloud(req)
+	if err != nil || resp == nil {
+		return nil, "", err
+	}
+	return readCloud(resp)
+}
+
+func (r *webdavRemote) put(ctx context.Context, name string, data []byte, etag string) error {
+	for attempt := 0; ; attempt++ {
+		req, err := r.request(ctx, http.MethodPut, r.url(name), data)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		} else {
+			req.Header.Set("If-None-Match", "*")
+		}
+		resp, err := cloudClient.Do(req)
+		if err != nil {
+			return codeErrorf(codeNetwork, "%v", err)
+		}
+		resp.Body.Close()
+		// A missing folder answers 409 Conflict; create it once
+		if resp.StatusCode == http.StatusConflict && attempt == 0 && r.prefix != "" {
+			if err := r.mkcol(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		switch {
+		case resp.StatusCode == http.StatusPreconditionFailed:
+			return errRemoteChanged
+		case resp.StatusCode >= 300:
+			return codeErrorf(codeProvider, "PUT %s: %s", req.URL.Redacted(), resp.Status)
+		}
+		return nil
+	}
+}
+
+// mkcol creates the folders of the prefix
+func (r *webdavRemote) mkcol(ctx context.Context) error {
+	u := *r.base
+	path := strings.TrimRight(u.Path, "/")
+	for _, part := range strings.Split(r.prefix, "/") {
+		path += "/" + part
+		u.Path = path + "/"
+		req, err := r.request(ctx, "MKCOL", u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := cloudClient.Do(req)
+		if err != nil {
+			return codeErrorf(codeNetwork, "%v", err)
+		}
+		resp.Body.Close()
+		// 405 Method Not Allowed: the folder already exists
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+			return codeErrorf(codeProvider, "MKCOL %s: %s", u.Redacted(), resp.Status)
+		}
+	}
+	return nil
+}
+
+// s3Remote keeps documents as objects in an S3-compatible bucket,
+// addressed path-style so MinIO and other compatible stores work too
+type s3Remote struct {
+	base      *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+func (r *s3Remote) url(name string) string {
+	u := *r.base
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + r.bucket + "/" + strings.Trim(r.prefix+"/"+name, "/")
+	return u.String()
+}
+
+func (r *s3Remote) request(ctx context.Context, method, name string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.url(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (r *s3Remote) get(ctx context.Context, name string) ([]byte, string, error) {
+	req, err := r.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	signS3(req, nil, r.accessKey, r.secretKey, r.region, time.Now())
+	resp, err := doCloud(req)
+	if err != nil || resp == nil {
+		return nil, "", err
+	}
+	return readCloud(resp)
+}
+
+func (r *s3Remote) put(ctx context.Context, name string, data []byte, etag string) error {
+	req, err := r.request(ctx, http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	} else {
+		req.Header.Set("If-None-Match", "*")
+	}
+	signS3(req, data, r.accessKey, r.secretKey, r.region, time.Now())
+	resp, err := doCloud(req)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return codeErrorf(codeProvider, "PUT %s: bucket not found", req.URL.Redacted())
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// signS3 signs req with AWS Signature Version 4
+func signS3(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	stamp := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	for _, h := range []string{"If-Match", "If-None-Match", "Content-Type"} {
+		if req.Header.Get(h) != "" {
+			names = append(names, strings.ToLower(h))
+		}
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, headers.String(), signed, payload}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+)
+
+// BarColumns holds bars field by field instead of row by row: one array
+// per field, each as long as Dates. It is a fraction of the size of the
+// row-wise JSON and parses into typed arrays directly.
+type BarColumns struct {
+	Symbol       string      `json:"symbol"`
+	Count        int         `json:"count"`
+	Encoding     string      `json:"encoding"` // "json" or "binary"
+	Dates        []string    `json:"dates"`
+	Open         interface{} `json:"open"`
+	Close        interface{} `json:"close"`
+	Change       interface{} `json:"change"`
+	ChangePct    interface{} `json:"changePct"`
+	Low          interface{} `json:"low"`
+	High         interface{} `json:"high"`
+	Volume       interface{} `json:"volume"`
+	Turnover     interface{} `json:"turnover"`
+	TurnoverRate interface{} `json:"turnoverRate"`
+	LastSession  string      `json:"lastSession"` // market phase of the last bar; not "closed" while it is forming
+}
+
+// packFloats encodes values as base64 of little-endian float64s, ready
+// for a Float64Array on the frontend
+func packFloats(values []float64) string {
+	buf := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(buf[8*i:], math.Float64bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// barColumns splits bars into columns. The "json" encoding keeps number
+// arrays, the smaller of the two for prices with few decimals; "binary"
+// packs each with packFloats so the frontend skips parsing numbers.
+func barColumns(symbol string, bars []Bar, encoding string) (BarColumns, error) {
+	if encoding == "" {
+		encoding = "json"
+	}
+	if encoding != "json" && encoding != "binary" {
+		return BarColumns{}, codeErrorf(codeParse, "unknown column encoding: %s", encoding)
+	}
+	n := len(bars)
+	dates := make([]string, n)
+	fields := make([][]float64, 9)
+	for f := range fields {
+		fields[f] = make([]float64, n)
+	}
+	for i, b := range bars {
+		dates[i] = b.Date
+		for f, v := range []float64{b.Open, b.Close, b.Change, b.ChangePct, b.Low, b.High, b.Volume, b.Turnover, b.TurnoverRate} {
+			fields[f][i] = v
+		}
+	}
+	column := func(values []float64) interface{} {
+		if encoding == "binary" {
+			return packFloats(values)
+		}
+		return values
+	}
+	return BarColumns{
+		Symbol:       symbol,
+		Count:        n,
+		Encoding:     encoding,
+		Dates:        dates,
+		Open:         column(fields[0]),
+		Close:        column(fields[1]),
+		Change:       column(fields[2]),
+		ChangePct:    column(fields[3]),
+		Low:          column(fields[4]),
+		High:         column(fields[5]),
+		Volume:       column(fields[6]),
+		Turnover:     column(fields[7]),
+		TurnoverRate: column(fields[8]),
+	}, nil
+}
+
+// GetBarsColumnar returns the daily bars of symbol over the last days as
+// columns: "json" number arrays or "binary" base64 float64 arrays. days
+// of 0 uses the configured lookback and lookbackMax (-1) all available
+// history.
+func (a *App) GetBarsColumnar(symbol string, days int, encoding string) (string, error) {
+	bars, err := windowBars(symbol, days)
+	if err != nil {
+		return "", err
+	}
+	columns, err := barColumns(symbol, bars, encoding)
+	if err != nil {
+		return "", err
+	}
+	columns.LastSession = phaseClosed
+	if n := len(bars); n > 0 {
+		columns.LastSession = barSession(bars[n-1].Date, shanghaiNow())
+	}
+	return toJSON(columns)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Commands are the actions the frontend binds to hotkeys. The backend
+// owns the list, the hotkeys and what each command does, so every view
+// handles a key the same way and scripts can run the same commands. A
+// command changes backend state, then emits eventCommand so the views
+// follow.
+
+const eventCommand = "command:executed"
+
+// Command is a command with its current hotkey
+type Command struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Hotkey        string `json:"hotkey"` // "" if unbound
+	DefaultHotkey string `json:"defaultHotkey"`
+	Args          string `json:"args,omitempty"` // what the arguments object may hold
+}
+
+// CommandResult is what ExecuteCommand returns and eventCommand carries
+type CommandResult struct {
+	ID     string          `json:"id"`
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result interface{}     `json:"result,omitempty"`
+}
+
+// commandArgs are the arguments any command may take
+type commandArgs struct {
+	WindowID string `json:"windowId"` // symbol window to act on instead of the main view
+	Name     string `json:"name"`     // indicator of toggleIndicator
+	Strategy string `json:"strategy"` // strategy openScreener opens with
+}
+
+// commandSpec registers a command
+type commandSpec struct {
+	id     string
+	hotkey string
+	args   string
+	run    func(a *App, args commandArgs) (interface{}, error)
+}
+
+// commands are the registered commands in menu order
+var commands = []commandSpec{
+	{id: "refresh", hotkey: "F5", run: (*App).commandRefresh},
+	{id: "nextSymbol", hotkey: "PageDown", args: "windowId", run: func(a *App, args commandArgs) (interface{}, error) {
+		return a.commandStepSymbol(args, 1)
+	}},
+	{id: "previousSymbol", hotkey: "PageUp", args: "windowId", run: func(a *App, args commandArgs) (interface{}, error) {
+		return a.commandStepSymbol(args, -1)
+	}},
+	{id: "toggleIndicator", hotkey: "Ctrl+I", args: "name, windowId", run: (*App).commandToggleIndicator},
+	{id: "openScreener", hotkey: "Ctrl+Shift+S", args: "strategy", run: func(a *App, args commandArgs) (interface{}, error) {
+		return map[string]string{"view": "screener", "strategy": args.Strategy}, nil
+	}},
+	{id: "lock", hotkey: "Ctrl+L", run: func(a *App, args commandArgs) (interface{}, error) {
+		return nil, a.Lock()
+	}},
+}
+
+// findCommand returns the command with id
+func findCommand(id string) (commandSpec, bool) {
+	for _, c := range commands {
+		if c.id == id {
+			return c, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+// hotkeyModifiers are the modifiers in the order hotkeys are written
+var hotkeyModifiers = []string{"Ctrl", "Alt", "Shift", "Meta"}
+
+// normalizeHotkey writes a hotkey such as "shift+ctrl+k" as "Ctrl+Shift+K"
+func normalizeHotkey(hotkey string) (string, error) {
+	var mods []string
+	key := ""
+	for _, part := range strings.Split(hotkey, "+") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return "", codeErrorf(codeParse, "invalid hotkey: %s", hotkey)
+		}
+		switch strings.ToLower(part) {
+		case "ctrl", "control", "cmdorctrl":
+			part = "Ctrl"
+		case "alt", "option":
+			part = "Alt"
+		case "shift":
+			part = "Shift"
+		case "meta", "cmd", "command", "super":
+			part = "Meta"
+		default:
+			if key != "" {
+				return "", codeErrorf(codeParse, "hotkey has two keys: %s", hotkey)
+			}
+			// Keys are written as KeyboardEvent.key names them: "K", "F5"
+			key = strings.ToUpper(part[:1]) + part[1:]
+			continue
+		}
+		mods = append(mods, part)
+	}
+	if key == "" {
+		return "", codeErrorf(codeParse, "hotkey has no key: %s", hotkey)
+	}
+	sort.Slice(mods, func(i, j int) bool { return indexOf(hotkeyModifiers, mods[i]) < indexOf(hotkeyModifiers, mods[j]) })
+	for i := 1; i < len(mods); i++ {
+		if mods[i] == mods[i-1] {
+			return "", codeErrorf(codeParse, "invalid hotkey: %s", hotkey)
+		}
+	}
+	return strings.Join(append(mods, key), "+"), nil
+}
+
+// commandHotkeys returns the hotkey of every command, with the user's
+// bindings over the defaults
+func commandHotkeys(session SessionState) map[string]string {
+	out := make(map[string]string, len(commands))
+	for _, c := range commands {
+		out[c.id] = c.hotkey
+		if key, ok := session.Hotkeys[c.id]; ok {
+			out[c.id] = key
+		}
+	}
+	return out
+}
+
+// ListCommands returns the commands with their hotkeys
+func (a *App) ListCommands() (string, error) {
+	session, err := a.session.get()
+	if err != nil {
+		return "", err
+	}
+	hotkeys := commandHotkeys(session)
+	out := make([]Command, len(commands))
+	for i, c := range commands {
+		out[i] = Command{ID: c.id, Title: tr("command." + c.id), Hotkey: hotkeys[c.id], DefaultHotkey: c.hotkey, Args: c.args}
+	}
+	return toJSON(out)
+}
+
+// SetCommandHotkey binds hotkey to a command. An empty hotkey unbinds it
+// and "default" restores its default. A hotkey can run one command only.
+func (a *App) SetCommandHotkey(id string, hotkey string) (string, error) {
+	c, ok := findCommand(id)
+	if !ok {
+		return "", codeErrorf(codeNotFound, "command not found: %s", id)
+	}
+	hotkey = strings.TrimSpace(hotkey)
+	if hotkey == "default" {
+		hotkey = c.hotkey
+	} else if hotkey != "" {
+		var err error
+		if hotkey, err = normalizeHotkey(hotkey); err != nil {
+			return "", codeErrorf(codeParse, "%v", err)
+		}
+	}
+	_, err := a.session.update(func(s *SessionState) error {
+		if hotkey != "" {
+			for other, key := range commandHotkeys(*s) {
+				if key == hotkey && other != id {
+					return codeErrorf(codeParse, "%s already runs %s", hotkey, tr("command."+other))
+				}
+			}
+		}
+		if hotkey == c.hotkey {
+			delete(s.Hotkeys, id)
+		} else {
+			s.Hotkeys[id] = hotkey
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return a.ListCommands()
+}
+
+// ExecuteCommand runs a command. argsJSON is an object with the
+// arguments the command takes, or empty.
+func (a *App) ExecuteCommand(id string, argsJSON string) (string, error) {
+	c, ok := findCommand(id)
+	if !ok {
+		return "", codeErrorf(codeNotFound, "command not found: %s", id)
+	}
+	var args commandArgs
+	result := CommandResult{ID: id}
+	if strings.TrimSpace(argsJSON) != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return "", codeErrorf(codeParse, "invalid command arguments: %v", err)
+		}
+		result.Args = json.RawMessage(argsJSON)
+	}
+	out, err := c.run(a, args)
+	if err != nil {
+		return "", err
+	}
+	result.Result = out
+	logger.Debug("command executed", "command", id)
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventCommand, result)
+	}
+	return toJSON(result)
+}
+
+// commandRefresh drops the cached results so the views fetch again
+func (a *App) commandRefresh(args commandArgs) (interface{}, error) {
+	return map[string]int{"cleared": a.ClearCache()}, nil
+}
+
+// commandStepSymbol moves the main view or a symbol window by step
+// through the active watchlist, or the recent symbols without one
+func (a *App) commandStepSymbol(args commandArgs, step int) (interface{}, error) {
+	session, err := a.session.get()
+	if err != nil {
+		return nil, err
+	}
+	symbols := session.Symbols
+	if session.ActiveWatchlist != "" {
+		if w, err := a.watchlists.get(session.ActiveWatchlist); err == nil && len(w.Symbols) > 0 {
+			symbols = w.Symbols
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, codeErrorf(codeNoData, "no symbols to step through")
+	}
+
+	current := session.ActiveSymbol
+	if args.WindowID != "" {
+		i := findWindow(session.Windows, args.WindowID)
+		if i < 0 {
+			return nil, codeErrorf(codeNotFound, "window not found: %s", args.WindowID)
+		}
+		current = session.Windows[i].Symbol
+	}
+	next := symbols[0]
+	if i := indexOf(symbols, current); i < len(symbols) {
+		next = symbols[((i+step)%len(symbols)+len(symbols))%len(symbols)]
+	}
+
+	session, err = a.session.update(func(s *SessionState) error {
+		if args.WindowID == "" {
+			s.ActiveSymbol = next
+			return nil
+		}
+		i := findWindow(s.Windows, args.WindowID)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "window not found: %s", args.WindowID)
+		}
+		s.Windows[i].Symbol = next
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if args.WindowID != "" {
+		a.emitWindows(session.Windows)
+	}
+	return map[string]string{"symbol": next}, nil
+}
+
+// commandToggleIndicator shows or hides an indicator in the main view or
+// a symbol window. The layout key "indicator.NAME" is "off" while it is
+// hidden; indicators are shown by default.
+func (a *App) commandToggleIndicator(args commandArgs) (interface{}, error) {
+	name := strings.TrimSpace(args.Name)
+	if name == "" {
+		return nil, codeErrorf(codeParse, "indicator name is required")
+	}
+	key := "indicator." + name
+	var shown bool
+	toggle := func(layout map[string]string) {
+		shown = layout[key] == "off"
+		layout[key] = map[bool]string{true: "on", false: "off"}[shown]
+	}
+	session, err := a.session.update(func(s *SessionState) error {
+		if args.WindowID == "" {
+			toggle(s.Layout)
+			return nil
+		}
+		i := findWindow(s.Windows, args.WindowID)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "window not found: %s", args.WindowID)
+		}
+		toggle(s.Windows[i].Layout)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if args.WindowID != "" {
+		a.emitWindows(session.Windows)
+	}
+	return map[string]interface{}{"name": name, "shown": shown}, nil
+}
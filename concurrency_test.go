@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// These tests exercise the shared app state from many goroutines. They
+// pass without the race detector too; run them with go test -race.
+
+// newTestApp returns an app over a temporary data directory. Provider
+// requests are replayed from a copy of testdata/fixtures, which the
+// settings keep in effect across reloads.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	a := &App{dataDir: t.TempDir(), jobs: newJobRegistry(), push: newPushHub(), rpc: &grpcHost{}}
+	a.openStores()
+	t.Cleanup(func() {
+		fixtures.set(fixturesOff, "")
+		localHistory.Close()
+	})
+	if err := os.CopyFS(filepath.Join(a.dataDir, "fixtures"), os.DirFS("testdata/fixtures")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.UpdateSettings(`{"fixtures":"replay"}`); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// hammer calls fn from n goroutines until stop is closed and returns the
+// first error any call returned
+func hammer(n int, stop <-chan struct{}, fn func(worker, i int) error) func() error {
+	var wg sync.WaitGroup
+	var first atomic.Value
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := fn(w, i); err != nil {
+					first.CompareAndSwap(nil, err)
+				}
+			}
+		}(w)
+	}
+	return func() error {
+		wg.Wait()
+		err, _ := first.Load().(error)
+		return err
+	}
+}
+
+// readState calls bound methods backed by the stores
+func readState(a *App) error {
+	for _, get := range []func() (string, error){
+		a.ListWatchlists, a.GetSettings, a.ListStrategies, a.GetPortfolio,
+		a.ListAlertRules, a.GetFXRates, a.GetSession, a.ListSymbolTags,
+	} {
+		if _, err := get(); err != nil {
+			return err
+		}
+	}
+	_, err := a.GetSymbolMetadata("600519")
+	return err
+}
+
+func TestReloadStoresWhileReading(t *testing.T) {
+	a := newTestApp(t)
+	if _, err := a.CreateWatchlist("core"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int64
+	stop := make(chan struct{})
+	wait := hammer(4, stop, func(w, i int) error {
+		calls.Add(1)
+		switch w {
+		case 0:
+			return a.SetSymbolNote("600519", fmt.Sprintf("note %d", i), `["t"]`)
+		case 1:
+			// Downloads every time, so reloads land mid-download
+			_, err := a.RefreshSymbolMetadata("600519")
+			return err
+		}
+		return readState(a)
+	})
+	for reloads := 0; reloads < 50 || calls.Load() < 500; reloads++ {
+		a.reloadStores()
+		runtime.Gosched()
+	}
+	close(stop)
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := a.ListWatchlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lists []Watchlist
+	if err := json.Unmarshal([]byte(out), &lists); err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || lists[0].Name != "core" {
+		t.Errorf("watchlists after reloads = %+v, want the one created", lists)
+	}
+}
+
+// startFakeLoops runs loop as the background loops of a, the way
+// startLoops does, without the loops that need the Wails runtime
+func startFakeLoops(a *App, loop func(ctx context.Context)) {
+	ctx, stop := context.WithCancel(context.Background())
+	a.stateMu.Lock()
+	a.stopLoops = stop
+	a.stateMu.Unlock()
+	a.goLoop(ctx, loop)
+}
+
+func TestRestoreWhileLoopsRun(t *testing.T) {
+	a := newTestApp(t)
+	if _, err := a.CreateWatchlist("restored"); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(t.TempDir(), "backup.zip")
+	if _, err := a.writeBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CreateWatchlist("dropped"); err != nil {
+		t.Fatal(err)
+	}
+
+	var writes atomic.Int64
+	startFakeLoops(a, func(ctx context.Context) {
+		for i := 0; ctx.Err() == nil; i++ {
+			a.SetSymbolNote("000001", fmt.Sprintf("loop %d", i), "[]")
+			writes.Add(1)
+		}
+	})
+	stop := make(chan struct{})
+	wait := hammer(3, stop, func(w, i int) error {
+		err := readState(a)
+		if err != nil && errorCode(err) == codeParse {
+			return err // a reader saw a half-written file
+		}
+		return nil
+	})
+	for writes.Load() == 0 {
+		runtime.Gosched()
+	}
+
+	if _, err := a.restoreBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	// Without a Wails context the loops are not resumed, so the restored
+	// files are left as they are
+	if a.loopsRunning() {
+		t.Error("loops still marked running after the restore")
+	}
+	a.loops.Wait()
+
+	out, err := a.ListWatchlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"restored"`) || strings.Contains(out, `"dropped"`) {
+		t.Errorf("watchlists after restore = %s, want only the backed up one", out)
+	}
+}
+
+func TestCancelLoopsConcurrently(t *testing.T) {
+	a := newTestApp(t)
+	for round := 0; round < 20; round++ {
+		startFakeLoops(a, func(ctx context.Context) { <-ctx.Done() })
+		if !a.loopsRunning() {
+			t.Fatal("loops not running after start")
+		}
+
+		var canceled atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if a.cancelLoops() {
+					canceled.Add(1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				a.loopsRunning()
+			}()
+		}
+		wg.Wait()
+		a.loops.Wait()
+
+		if n := canceled.Load(); n != 1 {
+			t.Fatalf("round %d: %d callers canceled the loops, want exactly 1", round, n)
+		}
+		if a.loopsRunning() {
+			t.Fatalf("round %d: loops still running after cancel", round)
+		}
+	}
+}
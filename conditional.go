@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Providers that send an ETag or Last-Modified header get conditional
+// requests: the response is kept on disk with its validators, the next
+// request for the same URL carries If-None-Match and If-Modified-Since, and
+// a 304 Not Modified is answered from disk. Repeated refreshes then cost a
+// round trip instead of a download.
+
+// maxValidatedBody is the largest response body kept for revalidation
+const maxValidatedBody = 4 << 20
+
+// ValidatedResponse is a provider response kept with its validators
+type ValidatedResponse struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	ContentType  string `json:"contentType"`
+	Body         string `json:"body"`
+	FetchedAt    string `json:"fetchedAt"`
+}
+
+// validatingTransport adds conditional headers to GET requests it has a
+// validated response for and serves 304s from that response
+type validatingTransport struct {
+	mu  sync.Mutex
+	dir string // empty until openStores sets it; nothing is kept then
+}
+
+// validators sits between fixtures and the network
+var validators = &validatingTransport{}
+
+// open keeps responses in the responses directory under dataDir
+func (t *validatingTransport) open(dataDir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dir = filepath.Join(dataDir, "responses")
+}
+
+func (t *validatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	dir := t.dir
+	t.mu.Unlock()
+	// Requests with their own validators are the caller's business
+	if dir == "" || req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return providerTransport.RoundTrip(req)
+	}
+	path := fixturePath(dir, req)
+
+	var cached ValidatedResponse
+	if err := loadJSON(path, &cached); err != nil {
+		logger.Debug("failed to read validated response", "url", req.URL.String(), "error", err)
+	}
+	if cached.URL != "" {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := providerTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached.URL != "" {
+		resp.Body.Close()
+		logger.Debug("response not modified", "url", cached.URL)
+		header := resp.Header.Clone()
+		header.Set("Content-Type", cached.ContentType)
+		header.Set("Content-Length", strconv.Itoa(len(cached.Body)))
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(cached.Body))),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	etag, modified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && modified == "") || resp.ContentLength > maxValidatedBody {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxValidatedBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxValidatedBody {
+		// Too large to keep; hand back what was read followed by the rest
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	kept := ValidatedResponse{
+		URL:          req.URL.String(),
+		ETag:         etag,
+		LastModified: modified,
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         string(body),
+		FetchedAt:    shanghaiNow().Format(time.RFC3339),
+	}
+	if err := saveJSON(path, kept); err != nil {
+		logger.Warn("failed to keep validated response", "url", kept.URL, "error", err)
+	}
+	return resp, nil
+}
+
+// count returns how many responses are kept
+func (t *validatingTransport) count() int {
+	t.mu.Lock()
+	dir := t.dir
+	t.mu.Unlock()
+	if dir == "" {
+		return 0
+	}
+	n := 0
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".json" {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// clear deletes every kept response and returns how many there were
+func (t *validatingTransport) clear() int {
+	n := t.count()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dir == "" {
+		return 0
+	}
+	if err := os.RemoveAll(t.dir); err != nil {
+		logger.Warn("failed to clear validated responses", "error", err)
+	}
+	return n
+}
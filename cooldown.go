@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// cooldownBase is the first cool-down after a throttling response;
+	// each further strike doubles it up to cooldownMax
+	cooldownBase = 30 * time.Second
+	cooldownMax  = 30 * time.Minute
+
+	eventProviderCooldown = "provider:cooldown"
+)
+
+// Provider names of the hosts the app downloads from
+const (
+	providerSohu      = "sohu"
+	providerEastmoney = "eastmoney"
+)
+
+// ProviderCooldown is the throttling state of a provider
+type ProviderCooldown struct {
+	Provider string `json:"provider"`
+	Active   bool   `json:"active"`
+	Until    string `json:"until,omitempty"`
+	Strikes  int    `json:"strikes"` // throttling responses since the last success
+	Reason   string `json:"reason,omitempty"`
+}
+
+type cooldownState struct {
+	until   time.Time
+	strikes int
+	reason  string
+}
+
+// cooldownTracker keeps throttled providers from being hammered: after a
+// throttling response no request goes to the provider until its cool-down
+// ends, and every strike in a row doubles the next cool-down
+type cooldownTracker struct {
+	mu        sync.Mutex
+	providers map[string]*cooldownState
+	onChange  func(ProviderCooldown)
+}
+
+// cooldowns is shared by every provider request
+var cooldowns = &cooldownTracker{providers: make(map[string]*cooldownState)}
+
+// statusLocked returns the state of provider. Callers hold c.mu.
+func (c *cooldownTracker) statusLocked(provider string) ProviderCooldown {
+	status := ProviderCooldown{Provider: provider}
+	if s := c.providers[provider]; s != nil {
+		status.Strikes, status.Reason = s.strikes, s.reason
+		if time.Now().Before(s.until) {
+			status.Active = true
+			status.Until = s.until.In(shanghaiNow().Location()).Format(time.RFC3339)
+		}
+	}
+	return status
+}
+
+// check returns a rate-limited error while provider cools down
+func (c *cooldownTracker) check(provider string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s := c.providers[provider]; s != nil && time.Now().Before(s.until) {
+		return codeErrorf(codeRateLimited, "%s is throttling requests, retrying after %s",
+			provider, s.until.In(shanghaiNow().Location()).Format("15:04:05"))
+	}
+	return nil
+}
+
+// strike starts a cool-down of provider. retryAfter, if set, is the wait
+// the provider asked for and is used when longer than the backoff.
+func (c *cooldownTracker) strike(provider, reason string, retryAfter time.Duration) {
+	c.mu.Lock()
+	s := c.providers[provider]
+	if s == nil {
+		s = &cooldownState{}
+		c.providers[provider] = s
+	}
+	s.strikes++
+	wait := cooldownBase << (s.strikes - 1)
+	if wait > cooldownMax || wait <= 0 {
+		wait = cooldownMax
+	}
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	s.until, s.reason = time.Now().Add(wait), reason
+	status, notify := c.statusLocked(provider), c.onChange
+	c.mu.Unlock()
+
+	logger.Warn("provider throttled, cooling down", "provider", provider, "reason", reason, "wait", wait)
+	if notify != nil {
+		notify(status)
+	}
+}
+
+// succeed clears the strikes of provider after a good response
+func (c *cooldownTracker) succeed(provider string) {
+	c.mu.Lock()
+	s := c.providers[provider]
+	if s == nil || s.strikes == 0 {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.providers, provider)
+	status, notify := c.statusLocked(provider), c.onChange
+	c.mu.Unlock()
+
+	logger.Info("provider recovered", "provider", provider)
+	if notify != nil {
+		notify(status)
+	}
+}
+
+// reset ends the cool-down of provider
+func (c *cooldownTracker) reset(provider string) {
+	c.mu.Lock()
+	_, ok := c.providers[provider]
+	delete(c.providers, provider)
+	status, notify := c.statusLocked(provider), c.onChange
+	c.mu.Unlock()
+
+	if ok && notify != nil {
+		notify(status)
+	}
+}
+
+// watchCooldowns emits an event whenever a provider enters or leaves a
+// cool-down
+func (a *App) watchCooldowns() {
+	cooldowns.mu.Lock()
+	defer cooldowns.mu.Unlock()
+	cooldowns.onChange = func(status ProviderCooldown) {
+		wailsruntime.EventsEmit(a.ctx, eventProviderCooldown, status)
+	}
+}
+
+// GetProviderCooldowns returns the throttling state of every provider
+func (a *App) GetProviderCooldowns() (string, error) {
+	cooldowns.mu.Lock()
+	defer cooldowns.mu.Unlock()
+	names := []string{providerSohu, providerEastmoney}
+	for name := range cooldowns.providers {
+		if name != providerSohu && name != providerEastmoney {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := make([]ProviderCooldown, 0, len(names))
+	for _, name := range names {
+		out = append(out, cooldowns.statusLocked(name))
+	}
+	return toJSON(out)
+}
+
+// ResetProviderCooldown lets requests go to provider again right away
+func (a *App) ResetProviderCooldown(provider string) {
+	cooldowns.reset(provider)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	wailslogger "github.com/wailsapp/wails/v2/pkg/logger"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// crashKeep is how many crash reports are kept
+	crashKeep = 20
+	// crashLogLines is how many recent log entries a report carries
+	crashLogLines = 50
+	// loopRestartDelay is how long a background loop that panicked waits
+	// before it runs again
+	loopRestartDelay = 5 * time.Second
+
+	eventAppCrash = "app:crash"
+)
+
+// CrashReport records a recovered panic. Reports stay on disk until the
+// user deletes them; one is only sent when the user asks for it.
+type CrashReport struct {
+	ID        string     `json:"id"`
+	Time      string     `json:"time"`
+	Where     string     `json:"where"` // the bound method or background task that panicked
+	Panic     string     `json:"panic"`
+	Stack     string     `json:"stack"`
+	GoVersion string     `json:"goVersion"`
+	Platform  string     `json:"platform"`
+	Logs      []LogEntry `json:"logs"`
+	SentAt    string     `json:"sentAt,omitempty"`
+}
+
+// crashStore keeps crash reports as one file each under crashes/
+type crashStore struct {
+	mu       sync.Mutex
+	dir      string
+	onReport func(CrashReport) // set once the window exists
+}
+
+// crashes is where recovered panics are reported. NewApp points it at
+// the app data directory.
+var crashes *crashStore
+
+func newCrashStore(dataDir string) *crashStore {
+	return &crashStore{dir: filepath.Join(dataDir, "crashes")}
+}
+
+func (s *crashStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// save writes report and drops the oldest reports beyond crashKeep
+func (s *crashStore) save(report CrashReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := saveJSON(s.path(report.ID), report); err != nil {
+		return err
+	}
+	reports, err := s.listLocked()
+	if err != nil {
+		return err
+	}
+	for _, r := range reports[min(len(reports), crashKeep):] {
+		os.Remove(s.path(r.ID))
+	}
+	return nil
+}
+
+// listLocked reads every report, newest first. Callers hold s.mu.
+func (s *crashStore) listLocked() ([]CrashReport, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []CrashReport{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []CrashReport{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		var report CrashReport
+		if err := loadJSON(filepath.Join(s.dir, e.Name()), &report); err != nil || report.ID == "" {
+			continue
+		}
+		out = append(out, report)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time > out[j].Time })
+	return out, nil
+}
+
+// get reads the report with id
+func (s *crashStore) get(id string) (CrashReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var report CrashReport
+	if err := loadJSON(s.path(filepath.Base(id)), &report); err != nil {
+		return report, err
+	}
+	if report.ID == "" {
+		return report, codeErrorf(codeNotFound, "crash report not found: %s", id)
+	}
+	return report, nil
+}
+
+// reportPanic logs a recovered panic with its stack and persists a crash
+// report
+func reportPanic(where string, value interface{}, stack []byte) {
+	logger.Error("recovered from panic", "where", where, "panic", fmt.Sprint(value), "stack", string(stack))
+	if crashes == nil {
+		return
+	}
+	logs := recentLogs.snapshot()
+	if len(logs) > crashLogLines {
+		logs = logs[len(logs)-crashLogLines:]
+	}
+	report := CrashReport{
+		ID:        newID(),
+		Time:      shanghaiNow().Format(time.RFC3339),
+		Where:     where,
+		Panic:     fmt.Sprint(value),
+		Stack:     string(stack),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Logs:      logs,
+	}
+	if err := crashes.save(report); err != nil {
+		logger.Warn("failed to save crash report", "error", err)
+		return
+	}
+	crashes.mu.Lock()
+	notify := crashes.onReport
+	crashes.mu.Unlock()
+	if notify != nil {
+		notify(report)
+	}
+}
+
+// recoverPanic reports a panic of the calling goroutine instead of
+// letting it kill the app. Defer it directly: defer recoverPanic("...").
+func recoverPanic(where string) {
+	if r := recover(); r != nil {
+		reportPanic(where, r, debug.Stack())
+	}
+}
+
+// callSafely runs fn and turns a panic into an error
+func callSafely(where string, fn func() (string, error)) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic(where, r, debug.Stack())
+			err = fmt.Errorf("internal error in %s: %v", where, r)
+		}
+	}()
+	return fn()
+}
+
+// funcName returns a short name of fn for crash reports
+func funcName(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// runLoop runs loop until ctx is done. A loop that panics is reported
+// and started again after loopRestartDelay.
+func runLoop(ctx context.Context, where string, loop func(ctx context.Context)) {
+	for {
+		panicked := true
+		func() {
+			defer recoverPanic(where)
+			loop(ctx)
+			panicked = false
+		}()
+		if !panicked {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(loopRestartDelay):
+		}
+	}
+}
+
+// watchCrashes emits an event whenever a crash report is saved so the
+// frontend can offer to send it
+func (a *App) watchCrashes() {
+	crashes.mu.Lock()
+	defer crashes.mu.Unlock()
+	crashes.onReport = func(report CrashReport) {
+		wailsruntime.EventsEmit(a.ctx, eventAppCrash, report)
+	}
+}
+
+// wailsLog forwards the Wails runtime log to the app log. Wails recovers
+// panics of bound methods and logs them as errors from a deferred call,
+// so the stack of the panic is still there to report.
+type wailsLog struct{}
+
+// boundCallName finds the method name in a Wails call message
+var boundCallName = regexp.MustCompile(`"name":"([^"]+)"`)
+
+func (wailsLog) Print(message string)   { logger.Info(message, "source", "wails") }
+func (wailsLog) Trace(message string)   { logger.Debug(message, "source", "wails") }
+func (wailsLog) Debug(message string)   { logger.Debug(message, "source", "wails") }
+func (wailsLog) Info(message string)    { logger.Info(message, "source", "wails") }
+func (wailsLog) Warning(message string) { logger.Warn(message, "source", "wails") }
+func (wailsLog) Fatal(message string)   { logger.Error(message, "source", "wails") }
+
+func (wailsLog) Error(message string) {
+	stack := debug.Stack()
+	if !strings.HasPrefix(message, "process message error") || !bytes.Contains(stack, []byte("\npanic(")) {
+		logger.Error(message, "source", "wails")
+		return
+	}
+	where := "bound method"
+	if m := boundCallName.FindStringSubmatch(message); m != nil {
+		where = m[1]
+	}
+	panicked := message
+	if i := strings.LastIndex(message, " -> "); i >= 0 {
+		panicked = message[i+len(" -> "):]
+	}
+	reportPanic(where, panicked, stack)
+}
+
+var _ wailslogger.Logger = wailsLog{}
+
+// ListCrashReports returns the saved crash reports, newest first
+func (a *App) ListCrashReports() (string, error) {
+	crashes.mu.Lock()
+	defer crashes.mu.Unlock()
+	reports, err := crashes.listLocked()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(reports)
+}
+
+// DeleteCrashReport removes a crash report
+func (a *App) DeleteCrashReport(id string) error {
+	if _, err := crashes.get(id); err != nil {
+		return err
+	}
+	crashes.mu.Lock()
+	defer crashes.mu.Unlock()
+	return os.Remove(crashes.path(filepath.Base(id)))
+}
+
+// crashClient posts reports through the connection pool with a deadline
+var crashClient = &http.Client{Transport: providerTransport, Timeout: 30 * time.Second}
+
+// SendCrashReport posts a crash report to the crash report URL of the
+// settings. Nothing is sent unless the user calls this for a report.
+func (a *App) SendCrashReport(id string) (string, error) {
+	endpoint := currentSettings().CrashReportURL
+	if endpoint == "" {
+		return "", fmt.Errorf("no crash report URL is configured")
+	}
+	report, err := crashes.get(id)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	resp, err := crashClient.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("crash report was rejected: %s", resp.Status)
+	}
+	report.SentAt = shanghaiNow().Format(time.RFC3339)
+	crashes.mu.Lock()
+	err = saveJSON(crashes.path(report.ID), report)
+	crashes.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	logger.Info("crash report sent", "id", report.ID)
+	return toJSON(report)
+}
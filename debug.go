@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// payloadCaptureLimit is how many provider responses debug mode keeps
+	payloadCaptureLimit = 20
+	// payloadMaxBytes is how much of each response body is kept
+	payloadMaxBytes = 64 << 10
+)
+
+// debugMode turns on debug logging and provider payload capture. It is
+// off at every start.
+var debugMode atomic.Bool
+
+// ProviderPayload is a provider response captured in debug mode
+type ProviderPayload struct {
+	Time        string `json:"time"`
+	URL         string `json:"url"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Bytes       int    `json:"bytes"`
+	TookMs      int64  `json:"tookMs"`
+	Body        string `json:"body"`
+	Truncated   bool   `json:"truncated"`
+}
+
+// DebugReport is the bug report file written by ExportDebugReport
+type DebugReport struct {
+	CreatedAt string            `json:"createdAt"`
+	Settings  Settings          `json:"settings"`
+	Network   NetworkStatus     `json:"network"`
+	Logs      []LogEntry        `json:"logs"`
+	Payloads  []ProviderPayload `json:"payloads"`
+}
+
+// payloadLog keeps the latest captured provider responses
+var payloadLog struct {
+	mu       sync.Mutex
+	payloads []ProviderPayload
+}
+
+// logProviderResponse logs a provider response at debug level and, in
+// debug mode, keeps its body for bug reports
+func logProviderResponse(resp *http.Response, body []byte, started time.Time) {
+	recordProviderResponse(resp, body, len(body), started)
+}
+
+// recordProviderResponse is logProviderResponse for a body of size bytes
+// of which only the start, body, was kept
+func recordProviderResponse(resp *http.Response, body []byte, size int, started time.Time) {
+	took := time.Since(started)
+	provider := providerForURL(resp.Request.URL)
+	providerUsage.record(provider, resp.StatusCode, size)
+	metrics.observeProvider(provider, resp.StatusCode, took)
+	logger.Debug("provider response", "url", resp.Request.URL.String(), "status", resp.StatusCode,
+		"bytes", size, "took", took)
+	if !debugMode.Load() {
+		return
+	}
+	p := ProviderPayload{
+		Time:        shanghaiNow().Format(time.RFC3339),
+		URL:         resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Bytes:       size,
+		TookMs:      took.Milliseconds(),
+		Truncated:   len(body) < size,
+	}
+	if len(body) > payloadMaxBytes {
+		body, p.Truncated = body[:payloadMaxBytes], true
+	}
+	p.Body = string(body)
+
+	payloadLog.mu.Lock()
+	defer payloadLog.mu.Unlock()
+	payloadLog.payloads = append(payloadLog.payloads, p)
+	if n := len(payloadLog.payloads); n > payloadCaptureLimit {
+		payloadLog.payloads = append([]ProviderPayload(nil), payloadLog.payloads[n-payloadCaptureLimit:]...)
+	}
+}
+
+// capturedPayloads returns the captured responses, newest first
+func capturedPayloads() []ProviderPayload {
+	payloadLog.mu.Lock()
+	defer payloadLog.mu.Unlock()
+	out := make([]ProviderPayload, 0, len(payloadLog.payloads))
+	for i := len(payloadLog.payloads) - 1; i >= 0; i-- {
+		out = append(out, payloadLog.payloads[i])
+	}
+	return out
+}
+
+// SetDebugMode switches debug logging and provider payload capture on or
+// off until the app exits
+func (a *App) SetDebugMode(enabled bool) {
+	debugMode.Store(enabled)
+	if enabled {
+		logLevel.Set(slog.LevelDebug)
+	} else {
+		logLevel.Set(slog.LevelInfo)
+		payloadLog.mu.Lock()
+		payloadLog.payloads = nil
+		payloadLog.mu.Unlock()
+	}
+	logger.Info("debug mode changed", "enabled", enabled)
+}
+
+// GetDebugMode reports whether debug mode is on
+func (a *App) GetDebugMode() bool {
+	return debugMode.Load()
+}
+
+// GetCapturedPayloads returns the provider responses captured in debug
+// mode, newest first
+func (a *App) GetCapturedPayloads() (string, error) {
+	return toJSON(capturedPayloads())
+}
+
+// ExportDebugReport asks for a destination and writes the settings, the
+// recent logs and the captured payloads there for a bug report. Proxy
+// credentials and the push token are left out. It returns "" if the dialog is cancelled.
+func (a *App) ExportDebugReport() (string, error) {
+	path, err := wailsruntime.SaveFileDialog(a.ctx, wailsruntime.SaveDialogOptions{
+		Title:           "Export debug report",
+		DefaultFilename: "stock-analysis-debug-" + shanghaiNow().Format("20060102-150405") + ".json",
+		Filters:         []wailsruntime.FileFilter{{DisplayName: "JSON (*.json)", Pattern: "*.json"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	report := DebugReport{
+		CreatedAt: shanghaiNow().Format(time.RFC3339),
+		Settings:  currentSettings(),
+		Network:   connectivity.status(),
+		Logs:      recentLogs.snapshot(),
+		Payloads:  capturedPayloads(),
+	}
+	if u, err := url.Parse(report.Settings.Proxy); err == nil && u.User != nil {
+		u.User = url.User("redacted")
+		report.Settings.Proxy = u.String()
+	}
+	if report.Settings.Push.Token != "" {
+		report.Settings.Push.Token = "redacted"
+	}
+	if report.Settings.GRPC.Token != "" {
+		report.Settings.GRPC.Token = "redacted"
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, data, 0o644)
+	recordAudit(auditExport, "debug report", path, "", err)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
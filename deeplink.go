@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// deepLinkScheme is the URL scheme registered for the app in wails.json,
+// e.g. stockanalysis://symbol/600519
+const deepLinkScheme = "stockanalysis"
+
+// DeepLink is a parsed stockanalysis:// URL
+type DeepLink struct {
+	Action string `json:"action"` // "symbol"
+	Symbol string `json:"symbol,omitempty"`
+	URL    string `json:"url"`
+}
+
+// parseDeepLink parses a stockanalysis:// URL
+func parseDeepLink(raw string) (DeepLink, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != deepLinkScheme {
+		return DeepLink{}, codeErrorf(codeParse, "not a %s link: %s", deepLinkScheme, raw)
+	}
+	link := DeepLink{Action: u.Host, URL: raw}
+	switch link.Action {
+	case "symbol":
+		link.Symbol = normalizeSymbol(strings.Trim(u.Path, "/"))
+		if link.Symbol == "" {
+			return DeepLink{}, codeErrorf(codeParse, "deep link has no symbol: %s", raw)
+		}
+	default:
+		return DeepLink{}, codeErrorf(codeParse, "unknown deep link action: %s", link.Action)
+	}
+	return link, nil
+}
+
+// deepLinkArg returns the first command line argument that is a deep link
+func deepLinkArg(args []string) string {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, deepLinkScheme+"://") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// deepLinks holds a link that arrived before the frontend could receive it
+var deepLinks struct {
+	mu      sync.Mutex
+	ready   bool
+	pending string
+}
+
+// openDeepLink navigates the frontend to the target of a deep link, or
+// keeps it until the frontend has loaded
+func (a *App) openDeepLink(raw string) {
+	deepLinks.mu.Lock()
+	if !deepLinks.ready {
+		deepLinks.pending = raw
+		deepLinks.mu.Unlock()
+		return
+	}
+	deepLinks.mu.Unlock()
+
+	link, err := parseDeepLink(raw)
+	if err != nil {
+		logger.Warn("ignoring deep link", "error", err)
+		return
+	}
+	logger.Info("opening deep link", "url", raw)
+	a.recent.touch(link.Symbol)
+	a.ShowWindow()
+	wailsruntime.EventsEmit(a.ctx, eventOpenSymbol, link.Symbol)
+}
+
+// domReady restores the window layout of the last session and opens a
+// deep link the app was launched with once the frontend can handle it
+func (a *App) domReady(ctx context.Context) {
+	a.restoreWindow()
+
+	deepLinks.mu.Lock()
+	deepLinks.ready = true
+	pending := deepLinks.pending
+	deepLinks.pending = ""
+	deepLinks.mu.Unlock()
+
+	if pending != "" {
+		a.openDeepLink(pending)
+	}
+}
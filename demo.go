@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// demoProvider is the synthetic provider. Selecting it serves generated
+// bars instead of downloading, without touching the local history, so the
+// app can be demoed and developed offline.
+const demoProvider = "demo"
+
+// demoEpoch is the first session of every demo series. Walks always start
+// here, so a symbol's bars do not depend on the requested range.
+var demoEpoch = time.Date(2015, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// demoDailyBars returns the demo bars of symbol between start and end,
+// oldest first. Every symbol gets its own seeded random walk over the
+// weekdays since demoEpoch. The bars are rendered as a Sohu hisHq payload
+// and parsed back, so the demo exercises the same parsing as downloads.
+func demoDailyBars(symbol string, start, end time.Time) ([]Bar, error) {
+	code := providers.SohuCode(normalizeSymbol(symbol))
+	h := fnv.New64a()
+	h.Write([]byte(code))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	// Indices move less and trade more than single stocks
+	index := strings.HasPrefix(code, "zs_")
+	price, vol, limit := math.Round((5+rng.Float64()*95)*100)/100, 0.02, 0.1
+	volume := 2e4 + rng.Float64()*2e5
+	if index {
+		price, vol, limit, volume = math.Round(2000+rng.Float64()*2000), 0.011, 0.08, 2e8
+	}
+	drift := (rng.Float64() - 0.45) * 0.001
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	var rows [][]string
+	for day := demoEpoch; day.Format("2006-01-02") <= to; day = day.AddDate(0, 0, 1) {
+		if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		round := func(v float64) float64 { return math.Round(v*100) / 100 }
+		ret := math.Max(-limit, math.Min(limit, drift+rng.NormFloat64()*vol))
+		open := round(price * (1 + rng.NormFloat64()*vol/4))
+		close := round(price * (1 + ret))
+		high := round(math.Max(open, close) * (1 + math.Abs(rng.NormFloat64())*vol/3))
+		low := round(math.Min(open, close) * (1 - math.Abs(rng.NormFloat64())*vol/3))
+		lots := math.Round(volume * math.Exp(rng.NormFloat64()*0.3) * (1 + math.Abs(ret)*10))
+		prev := price
+		price = close
+		if date := day.Format("2006-01-02"); date >= from {
+			num := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+			rate := "-"
+			if !index {
+				rate = num(lots/1e5) + "%"
+			}
+			rows = append(rows, []string{date, num(open), num(close), num(close - prev),
+				num((close/prev-1)*100) + "%", num(low), num(high), strconv.FormatFloat(lots, 'f', 0, 64),
+				num(lots * 100 * (open + close) / 2 / 1e4), rate})
+		}
+	}
+	if len(rows) == 0 {
+		return nil, providers.ErrNoBars
+	}
+	// hisHq lists the newest row first
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	body, err := json.Marshal([]struct {
+		Status int        `json:"status"`
+		Hq     [][]string `json:"hq"`
+		Code   string     `json:"code"`
+	}{{Status: 0, Hq: rows, Code: code}})
+	if err != nil {
+		return nil, err
+	}
+	return providers.ParseSohuBars(body)
+}
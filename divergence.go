@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+
+	"stock-analysis/internal/indicators"
+)
+
+// A new high that trades on less volume than the days before lacks
+// buyers to carry it and often marks a top; a new low on shrinking volume
+// shows sellers drying up. Both are flagged where the close reaches its
+// divergenceWindow-day extreme while the 5-day volume rate, the same
+// series the reports and watchlists show, is below -divergenceVolumeDrop.
+// Consecutive flagged days form one event.
+
+const (
+	// divergenceWindow is the number of sessions a close must top or
+	// undercut to count as a new high or low
+	divergenceWindow = 20
+	// divergenceVolumeDrop is how far the 5-day volume rate must fall, in
+	// percent, for volume to count as contracting
+	divergenceVolumeDrop = 20
+)
+
+// Kinds of volume-price divergence
+const (
+	divergenceBearish = "bearish" // new high on contracting volume
+	divergenceBullish = "bullish" // new low on contracting volume
+)
+
+// DivergenceEvent is a run of days where price and volume diverge
+type DivergenceEvent struct {
+	Kind       string  `json:"kind"` // "bearish" or "bullish"
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Days       int     `json:"days"`
+	Close      float64 `json:"close"`      // on To
+	VolumeRate float64 `json:"volumeRate"` // lowest 5-day volume rate of the run, %
+}
+
+// VolumePriceDivergence is the divergence of a symbol day by day, one
+// entry per date: 1 bearish, -1 bullish, 0 neither
+type VolumePriceDivergence struct {
+	Symbol string            `json:"symbol"`
+	Dates  []string          `json:"dates"`
+	Flags  []int             `json:"flags"`
+	Events []DivergenceEvent `json:"events"`
+}
+
+// divergenceFlags marks each bar 1 for a new high and -1 for a new low on
+// contracting volume, given the 5-day volume rate of the bars
+func divergenceFlags(bars []Bar, volumeRate []float64) []int {
+	prices := closes(bars)
+	highs := indicators.Ref(indicators.RollingExtreme(prices, divergenceWindow, math.Max), 1)
+	lows := indicators.Ref(indicators.RollingExtreme(prices, divergenceWindow, math.Min), 1)
+	flags := make([]int, len(bars))
+	for i := range bars {
+		if math.IsNaN(highs[i]) || volumeRate[i] > -divergenceVolumeDrop {
+			continue
+		}
+		switch {
+		case prices[i] > highs[i]:
+			flags[i] = 1
+		case prices[i] < lows[i]:
+			flags[i] = -1
+		}
+	}
+	return flags
+}
+
+// divergenceEvents merges runs of equal flags into events
+func divergenceEvents(bars []Bar, flags []int, volumeRate []float64) []DivergenceEvent {
+	events := []DivergenceEvent{}
+	for i := 0; i < len(bars); i++ {
+		if flags[i] == 0 {
+			continue
+		}
+		e := DivergenceEvent{Kind: divergenceBearish, From: bars[i].Date, VolumeRate: volumeRate[i]}
+		if flags[i] < 0 {
+			e.Kind = divergenceBullish
+		}
+		j := i
+		for j+1 < len(bars) && flags[j+1] == flags[i] {
+			j++
+			e.VolumeRate = math.Min(e.VolumeRate, volumeRate[j])
+		}
+		e.To, e.Days, e.Close = bars[j].Date, j-i+1, bars[j].Close
+		events = append(events, e)
+		i = j
+	}
+	return events
+}
+
+// volumePriceDivergence finds the divergences of symbol over bars
+func volumePriceDivergence(symbol string, bars []Bar) VolumePriceDivergence {
+	set := symbolIndicators(symbol, bars)
+	flags := divergenceFlags(bars, set.VolumeRate5D)
+	d := VolumePriceDivergence{Symbol: symbol, Dates: make([]string, len(bars)), Flags: flags}
+	for i, b := range bars {
+		d.Dates[i] = b.Date
+	}
+	d.Events = divergenceEvents(bars, flags, set.VolumeRate5D)
+	return d
+}
+
+// divergenceSignals returns the divergence events as report signals,
+// dated on the day each began
+func divergenceSignals(events []DivergenceEvent) []ReportSignal {
+	out := make([]ReportSignal, len(events))
+	for i, e := range events {
+		out[i] = ReportSignal{Date: e.From, Type: e.Kind + "Divergence", Message: tr("signal.divergence."+e.Kind, e.Days, e.VolumeRate)}
+	}
+	return out
+}
+
+// GetVolumePriceDivergence returns the days symbol made new highs or
+// lows on contracting volume over the last days, as warning events. days
+// of 0 uses the configured lookback and lookbackMax (-1) all available
+// history.
+func (a *App) GetVolumePriceDivergence(symbol string, days int) (string, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(volumePriceDivergence(symbol, bars))
+}
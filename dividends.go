@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DividendEvent is a cash dividend and/or bonus share distribution
+// announced by a listed company (分红送转)
+type DividendEvent struct {
+	Symbol        string  `json:"symbol"`
+	RecordDate    string  `json:"recordDate"`    // 股权登记日
+	ExDate        string  `json:"exDate"`        // 除权除息日
+	CashPerShare  float64 `json:"cashPerShare"`  // pre-tax
+	BonusPerShare float64 `json:"bonusPerShare"` // 送股 + 转增 shares per share held
+	Plan          string  `json:"plan"`
+}
+
+// fetchDividendEvents downloads the distribution history of symbol from
+// the Eastmoney corporate action data center
+func fetchDividendEvents(symbol string) ([]DividendEvent, error) {
+	code := strings.TrimPrefix(symbol, "cn_")
+	params := url.Values{}
+	params.Set("reportName", "RPT_SHAREBONUS_DET")
+	params.Set("columns", "ALL")
+	params.Set("pageSize", "100")
+	params.Set("sortColumns", "EX_DIVIDEND_DATE")
+	params.Set("sortTypes", "-1")
+	params.Set("filter", fmt.Sprintf(`(SECURITY_CODE="%s")`, code))
+
+	if err := cooldowns.check(providerEastmoney); err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	resp, err := providerClient.Get("https://datacenter-web.eastmoney.com/api/data/v1/get?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logProviderResponse(resp, body, started)
+	if err := checkProviderResponse(providerEastmoney, resp, body); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Success bool `json:"success"`
+		Result  *struct {
+			Data []struct {
+				PretaxBonusRMB  float64 `json:"PRETAX_BONUS_RMB"`
+				BonusITRatio    float64 `json:"BONUS_IT_RATIO"`
+				ExDividendDate  string  `json:"EX_DIVIDEND_DATE"`
+				EquityRecordDay string  `json:"EQUITY_RECORD_DATE"`
+				ImplPlanProfile string  `json:"IMPL_PLAN_PROFILE"`
+			} `json:"data"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, codeErrorf(codeParse, "failed to parse JSON: %v", err)
+	}
+	if payload.Result == nil {
+		// No distributions on record
+		return nil, nil
+	}
+
+	var events []DividendEvent
+	for _, row := range payload.Result.Data {
+		// Plans that were never implemented have no ex-date
+		if row.ExDividendDate == "" {
+			continue
+		}
+		events = append(events, DividendEvent{
+			Symbol:        symbol,
+			RecordDate:    dateOnly(row.EquityRecordDay),
+			ExDate:        dateOnly(row.ExDividendDate),
+			CashPerShare:  row.PretaxBonusRMB / 10, // quoted per 10 shares
+			BonusPerShare: row.BonusITRatio / 10,
+			Plan:          row.ImplPlanProfile,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ExDate < events[j].ExDate })
+	return events, nil
+}
+
+// dateOnly trims the time part of "2006-01-02 15:04:05" timestamps
+func dateOnly(s string) string {
+	if len(s) >= 10 {
+		return s[:10]
+	}
+	return s
+}
+
+// sharesHeldBefore returns the shares of symbol held at the end of the
+// day before date
+func (p *Portfolio) sharesHeldBefore(symbol, date string) float64 {
+	shares := 0.0
+	for _, tx := range p.sortedTransactions() {
+		if tx.Symbol != symbol || tx.Date >= date {
+			continue
+		}
+		switch tx.Type {
+		case "buy", "bonus":
+			shares += tx.Shares
+		case "sell":
+			shares -= tx.Shares
+		}
+	}
+	return shares
+}
+
+// dividendTransactions turns corporate actions into ledger entries for
+// the shares held on each ex-date. Events already in the ledger are
+// skipped.
+func (p *Portfolio) dividendTransactions(events []DividendEvent, today string) []Transaction {
+	existing := make(map[string]bool)
+	for _, tx := range p.Transactions {
+		if tx.Type == "dividend" || tx.Type == "bonus" {
+			existing[tx.Symbol+"|"+tx.Type+"|"+tx.Date] = true
+		}
+	}
+
+	var txs []Transaction
+	for _, ev := range events {
+		if ev.ExDate > today {
+			continue
+		}
+		held := p.sharesHeldBefore(ev.Symbol, ev.ExDate)
+		if held <= 0 {
+			continue
+		}
+		if ev.CashPerShare > 0 && !existing[ev.Symbol+"|dividend|"+ev.ExDate] {
+			txs = append(txs, Transaction{
+				ID:     newID(),
+				Date:   ev.ExDate,
+				Symbol: ev.Symbol,
+				Type:   "dividend",
+				Shares: held,
+				Price:  ev.CashPerShare,
+				Note:   ev.Plan,
+			})
+		}
+		if ev.BonusPerShare > 0 && !existing[ev.Symbol+"|bonus|"+ev.ExDate] {
+			txs = append(txs, Transaction{
+				ID:     newID(),
+				Date:   ev.ExDate,
+				Symbol: ev.Symbol,
+				Type:   "bonus",
+				Shares: held * ev.BonusPerShare,
+				Note:   ev.Plan,
+			})
+		}
+	}
+	return txs
+}
+
+// SyncDividends fetches corporate actions for every symbol that has been
+// held in the portfolio and records the dividends and bonus shares the
+// holdings were entitled to. It returns the transactions that were added.
+func (a *App) SyncDividends() (string, error) {
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool)
+	var events []DividendEvent
+	for _, tx := range p.Transactions {
+		if seen[tx.Symbol] {
+			continue
+		}
+		seen[tx.Symbol] = true
+		symbolEvents, err := fetchDividendEvents(tx.Symbol)
+		if err != nil {
+			return "", fmt.Errorf("failed to get dividends for %s: %v", tx.Symbol, err)
+		}
+		events = append(events, symbolEvents...)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ExDate < events[j].ExDate })
+
+	var added []Transaction
+	today := shanghaiNow().Format("2006-01-02")
+	err = a.portfolio.update(func(p *Portfolio) error {
+		// Apply events one at a time so bonus shares count towards later
+		// distributions
+		for _, ev := range events {
+			txs := p.dividendTransactions([]DividendEvent{ev}, today)
+			p.Transactions = append(p.Transactions, txs...)
+			added = append(added, txs...)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if added == nil {
+		added = []Transaction{}
+	}
+	return toJSON(added)
+}
+
+// DividendIncome is the dividend income received in one year
+type DividendIncome struct {
+	Year     int                `json:"year"`
+	Total    float64            `json:"total"`
+	BySymbol map[string]float64 `json:"bySymbol"`
+	Events   []Transaction      `json:"events"`
+}
+
+// GetDividendSummary returns net dividend income per year, newest first
+func (a *App) GetDividendSummary() (string, error) {
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	byYear := make(map[int]*DividendIncome)
+	for _, tx := range p.sortedTransactions() {
+		if tx.Type != "dividend" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", tx.Date)
+		if err != nil {
+			continue
+		}
+		income := byYear[date.Year()]
+		if income == nil {
+			income = &DividendIncome{Year: date.Year(), BySymbol: make(map[string]float64)}
+			byYear[date.Year()] = income
+		}
+		amount := tx.Shares*tx.Price - tx.Fees
+		income.Total += amount
+		income.BySymbol[tx.Symbol] += amount
+		income.Events = append(income.Events, tx)
+	}
+
+	summary := []DividendIncome{}
+	for _, income := range byYear {
+		summary = append(summary, *income)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Year > summary[j].Year })
+	return toJSON(summary)
+}
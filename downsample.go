@@ -0,0 +1,46 @@
+package main
+
+import "stock-analysis/internal/analysis"
+
+const (
+	// defaultChartPoints is about the width of a chart in pixels
+	defaultChartPoints = 1000
+	// minChartPoints is the fewest points LTTB can keep
+	minChartPoints = 3
+)
+
+// DownsampledBars is a series thinned out for a chart
+type DownsampledBars struct {
+	Symbol string `json:"symbol"`
+	Total  int    `json:"total"` // bars before downsampling
+	Bars   []Bar  `json:"bars"`
+}
+
+// downsampleBars keeps points bars of bars chosen by LTTB on the close
+func downsampleBars(bars []Bar, points int) []Bar {
+	idx := analysis.LTTB(closes(bars), points)
+	out := make([]Bar, len(idx))
+	for i, j := range idx {
+		out[i] = bars[j]
+	}
+	return out
+}
+
+// GetDownsampledBars returns the daily bars of symbol over the last days
+// thinned out to about points bars (a chart's width; 0 for 1000) with the
+// largest-triangle-three-buckets algorithm, so long histories render
+// without sending every bar. days of 0 uses the configured lookback and
+// lookbackMax (-1) all available history.
+func (a *App) GetDownsampledBars(symbol string, days int, points int) (string, error) {
+	if points == 0 {
+		points = defaultChartPoints
+	}
+	if points < minChartPoints {
+		return "", codeErrorf(codeParse, "points must be at least %d", minChartPoints)
+	}
+	bars, err := windowBars(symbol, days)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(DownsampledBars{Symbol: symbol, Total: len(bars), Bars: downsampleBars(bars, points)})
+}
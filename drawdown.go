@@ -0,0 +1,90 @@
+package main
+
+import (
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// Drawdown alerts are trailing-stop reminders for positions held outside
+// a broker: a "drawdown" alert rule follows the highest close of its
+// symbol since the position was opened and fires once the latest price
+// falls the rule's threshold percent below it. The rules live with the
+// other alert rules and go quiet while the symbol is not held.
+
+// heldPositions returns the positions of the paper account and the
+// portfolio keyed by providers.SohuCode
+func (a *App) heldPositions() (map[string]monitoredPosition, error) {
+	positions, err := a.monitoredPositions()
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[string]monitoredPosition, len(positions))
+	for sym, pos := range positions {
+		if pos.Shares > 0 {
+			held[providers.SohuCode(sym)] = pos
+		}
+	}
+	return held, nil
+}
+
+// positionEntryDate returns the day the peak of a position is tracked
+// from: its first purchase, or today for paper positions, which do not
+// record one
+func positionEntryDate(pos monitoredPosition) string {
+	if pos.EntryDate != "" {
+		return pos.EntryDate
+	}
+	return shanghaiNow().Format("2006-01-02")
+}
+
+// WatchPositionDrawdowns adds a drawdown alert at percent below the peak
+// to every held position that has none yet, and returns the rules added
+func (a *App) WatchPositionDrawdowns(percent float64) (string, error) {
+	if percent <= 0 || percent >= 100 {
+		return "", codeErrorf(codeParse, "drawdown percent must be between 0 and 100")
+	}
+	held, err := a.monitoredPositions()
+	if err != nil {
+		return "", err
+	}
+
+	s := a.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	watched := make(map[string]bool)
+	for _, r := range s.rules {
+		if r.Type == "drawdown" {
+			watched[providers.SohuCode(r.Symbol)] = true
+		}
+	}
+	added := []AlertRule{}
+	now := shanghaiNow().Format(time.RFC3339)
+	for sym, pos := range held {
+		if pos.Shares <= 0 || watched[providers.SohuCode(sym)] {
+			continue
+		}
+		watched[providers.SohuCode(sym)] = true
+		rule := AlertRule{
+			ID:        newID(),
+			Symbol:    normalizeSymbol(sym),
+			Type:      "drawdown",
+			Threshold: percent,
+			EntryDate: positionEntryDate(pos),
+			Enabled:   true,
+			CreatedAt: now,
+		}
+		s.rules = append(s.rules, &rule)
+		added = append(added, rule)
+	}
+	if len(added) > 0 {
+		if err := saveJSON(s.rulesPath, s.rules); err != nil {
+			return "", err
+		}
+	}
+	return toJSON(added)
+}
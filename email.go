@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPSettings configures email delivery of alerts and reports
+type SMTPSettings struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Security string   `json:"security"` // "starttls", "tls" or "none"
+	Username string   `json:"username"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Events   []string `json:"events"` // empty means every event
+	Enabled  bool     `json:"enabled"`
+}
+
+// validate fills in the default port and checks the required fields
+func (s *SMTPSettings) validate() error {
+	if s.Host == "" {
+		return codeErrorf(codeParse, "SMTP host is required")
+	}
+	switch s.Security {
+	case "", "starttls":
+		s.Security = "starttls"
+		if s.Port == 0 {
+			s.Port = 587
+		}
+	case "tls":
+		if s.Port == 0 {
+			s.Port = 465
+		}
+	case "none":
+		if s.Port == 0 {
+			s.Port = 25
+		}
+	default:
+		return codeErrorf(codeParse, "unknown SMTP security: %s", s.Security)
+	}
+	if s.From == "" || len(s.To) == 0 {
+		return codeErrorf(codeParse, "sender and at least one recipient are required")
+	}
+	return nil
+}
+
+// wants reports whether email should be sent for event
+func (s *SMTPSettings) wants(event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// buildEmail renders a plain-text UTF-8 message
+func buildEmail(from string, to []string, subject, body string, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
+
+// sendEmail delivers a message with the given settings
+func sendEmail(settings SMTPSettings, subject, body string) error {
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	tlsConfig := &tls.Config{ServerName: settings.Host}
+
+	var client *smtp.Client
+	if settings.Security == "tls" {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 15 * time.Second}, "tcp", addr, tlsConfig)
+		if err != nil {
+			return err
+		}
+		client, err = smtp.NewClient(conn, settings.Host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		conn, err := net.DialTimeout("tcp", addr, 15*time.Second)
+		if err != nil {
+			return err
+		}
+		client, err = smtp.NewClient(conn, settings.Host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		if settings.Security == "starttls" {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	defer client.Close()
+
+	if settings.Username != "" {
+		auth := smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+	if err := client.Mail(settings.From); err != nil {
+		return err
+	}
+	for _, rcpt := range settings.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildEmail(settings.From, settings.To, subject, body, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// smtpStore persists the SMTP settings
+type smtpStore struct {
+	mu       sync.Mutex
+	path     string
+	loaded   bool
+	settings SMTPSettings
+}
+
+func newSMTPStore(dataDir string) *smtpStore {
+	return &smtpStore{path: filepath.Join(dataDir, "smtp.json")}
+}
+
+// unload forgets the SMTP settings so the next use reads the file again
+func (s *smtpStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings, s.loaded = SMTPSettings{}, false
+}
+
+// get returns a copy of the settings
+func (s *smtpStore) get() (SMTPSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		if err := loadJSON(s.path, &s.settings); err != nil {
+			return SMTPSettings{}, err
+		}
+		s.loaded = true
+	}
+	settings := s.settings
+	settings.To = append([]string(nil), s.settings.To...)
+	return settings, nil
+}
+
+// emailMessage sends msg by email in the background if SMTP is enabled
+// and subscribed to its event
+func (a *App) emailMessage(msg outboundMessage) {
+	settings, err := a.smtp.get()
+	if err != nil || !settings.Enabled || !settings.wants(msg.Event) {
+		return
+	}
+	go func() {
+		defer recoverPanic("email")
+		sendEmail(settings, msg.Title, msg.Text)
+	}()
+}
+
+// GetSMTPSettings returns the SMTP settings without the password
+func (a *App) GetSMTPSettings() (string, error) {
+	settings, err := a.smtp.get()
+	if err != nil {
+		return "", err
+	}
+	settings.Password = ""
+	return toJSON(settings)
+}
+
+// SaveSMTPSettings stores the SMTP settings. An empty password keeps the
+// saved one.
+func (a *App) SaveSMTPSettings(settingsJSON string) error {
+	var settings SMTPSettings
+	if err := json.Unmarshal([]byte(settingsJSON), &settings); err != nil {
+		return codeErrorf(codeParse, "failed to parse SMTP settings: %v", err)
+	}
+	if err := settings.validate(); err != nil {
+		return err
+	}
+
+	current, err := a.smtp.get()
+	if err != nil {
+		return err
+	}
+	if settings.Password == "" {
+		settings.Password = current.Password
+	}
+
+	s := a.smtp
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = settings
+	err = saveJSON(s.path, s.settings)
+	recordAudit(auditSettings, "update", "smtp", changedKeys(current, settings), err)
+	return err
+}
+
+// SendTestEmail sends a test message with the saved settings and returns
+// any delivery error
+func (a *App) SendTestEmail() error {
+	settings, err := a.smtp.get()
+	if err != nil {
+		return err
+	}
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	return sendEmail(settings, "stock-analysis test email",
+		"This is a test message sent at "+shanghaiNow().Format("2006-01-02 15:04:05")+".")
+}
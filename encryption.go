@@ -0,0 +1,694 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/crypto/scrypt"
+
+	"stock-analysis/internal/store"
+)
+
+// The files holding personal data (portfolios, the paper account,
+// watchlists, notes, alerts and the like) can be encrypted under a
+// passcode. They are sealed with a random data key that vault.json keeps
+// wrapped by a key derived from the passcode, so changing the passcode
+// rewrites one file. The data key is only in memory while the app is
+// unlocked: locking forgets it, stops the background loops and unloads
+// the stores. The history database holds public market data and an audit
+// log that names no amounts, and is not encrypted.
+
+// sealedMagic starts every encrypted file
+var sealedMagic = []byte("stock-analysis sealed 1\n")
+
+// sealedFiles are the files in the data directory encrypted while
+// encryption is on
+var sealedFiles = map[string]bool{
+	"portfolios.json":         true,
+	"portfolio.json":          true,
+	"paper_account.json":      true,
+	"watchlists.json":         true,
+	"symbol_notes.json":       true,
+	"alert_rules.json":        true,
+	"alert_triggers.json":     true,
+	"exit_rules.json":         true,
+	"analysis_snapshots.json": true,
+	"daily_summaries.json":    true,
+	"session.json":            true,
+	"recent_symbols.json":     true,
+	"smtp.json":               true,
+	"webhooks.json":           true,
+	"cloud_sync.json":         true,
+}
+
+const (
+	// minPasscode is the shortest passcode accepted, a four-digit PIN
+	minPasscode = 4
+	// defaultAutoLock is the idle time before locking, in minutes
+	defaultAutoLock = 15
+	// maxAutoLock is the longest idle time that can be set, in minutes
+	maxAutoLock = 24 * 60
+	// freeUnlockAttempts is how many wrong passcodes are allowed before
+	// each further attempt has to wait
+	freeUnlockAttempts = 5
+	// maxUnlockDelay caps the wait between attempts
+	maxUnlockDelay = 5 * time.Minute
+	// autoLockCheckInterval is how often the idle time is checked
+	autoLockCheckInterval = 30 * time.Second
+)
+
+const eventLockStatus = "app:lockStatus"
+
+var errLocked error = &codedError{codeLocked, errors.New("the data is locked, unlock it with the passcode")}
+
+// vaultConfig is vault.json, present while encryption is on
+type vaultConfig struct {
+	Salt            string `json:"salt"`
+	WrappedKey      string `json:"wrappedKey"` // base64 of nonce || AES-GCM sealed data key
+	AutoLockMinutes int    `json:"autoLockMinutes"`
+	EnabledAt       string `json:"enabledAt"`
+}
+
+// LockStatus reports whether the data is encrypted and locked
+type LockStatus struct {
+	Enabled         bool   `json:"enabled"`
+	Locked          bool   `json:"locked"`
+	AutoLockMinutes int    `json:"autoLockMinutes"`      // 0 never locks automatically
+	RetryAfter      string `json:"retryAfter,omitempty"` // set after repeated wrong passcodes
+}
+
+// dataVault holds the data key while the app is unlocked
+type dataVault struct {
+	mu         sync.Mutex
+	dir        string
+	loaded     bool
+	config     *vaultConfig // nil while encryption is off
+	key        []byte       // nil while locked
+	lastActive time.Time
+	failures   int
+	retryAt    time.Time
+
+	// switching serializes locking and unlocking the app, which stop and
+	// start the loops
+	switching sync.Mutex
+}
+
+// vault seals the files of the data directory openStores points it at
+var vault = &dataVault{}
+
+// open points the vault at vault.json in dataDir, locked
+func (v *dataVault) open(dataDir string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.dir = dataDir
+	v.forget()
+}
+
+// unload forgets the configuration and the data key so the next use reads
+// vault.json again, locked, as after a restore
+func (v *dataVault) unload() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.forget()
+}
+
+// forget drops the configuration and data key. Callers hold v.mu.
+func (v *dataVault) forget() {
+	clear(v.key)
+	v.config, v.key, v.loaded = nil, nil, false
+}
+
+func (v *dataVault) path() string {
+	return filepath.Join(v.dir, "vault.json")
+}
+
+// load reads vault.json on first use. Callers hold v.mu.
+func (v *dataVault) load() error {
+	if v.loaded || v.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(v.path())
+	if os.IsNotExist(err) {
+		v.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var config vaultConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return codeErrorf(codeParse, "failed to parse vault.json: %v", err)
+	}
+	v.config, v.loaded = &config, true
+	return nil
+}
+
+// save writes the configuration. Callers hold v.mu.
+func (v *dataVault) save() error {
+	data, err := json.MarshalIndent(v.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.WriteFile(v.path(), data)
+}
+
+// covers reports whether path is a file the vault encrypts
+func (v *dataVault) covers(path string) bool {
+	return v.dir != "" && filepath.Dir(path) == v.dir && sealedFiles[filepath.Base(path)]
+}
+
+// newGCM returns AES-GCM under key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// gcmSeal encrypts plain under key with a random nonce in front
+func gcmSeal(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// gcmOpen reverses gcmSeal
+func gcmOpen(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is damaged")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// passcodeKey derives the key that wraps the data key
+func passcodeKey(passcode string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passcode), salt, 1<<15, 8, 1, 32)
+}
+
+// wrap stores key in config under passcode with a new salt
+func (config *vaultConfig) wrap(passcode string, key []byte) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	kek, err := passcodeKey(passcode, salt)
+	if err != nil {
+		return err
+	}
+	wrapped, err := gcmSeal(kek, key)
+	if err != nil {
+		return err
+	}
+	config.Salt = base64.StdEncoding.EncodeToString(salt)
+	config.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	return nil
+}
+
+// unwrap returns the data key if passcode is right
+func (config *vaultConfig) unwrap(passcode string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(config.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("vault.json is damaged")
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(config.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("vault.json is damaged")
+	}
+	kek, err := passcodeKey(passcode, salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := gcmOpen(kek, wrapped)
+	if err != nil {
+		return nil, codeErrorf(codeLocked, "wrong passcode")
+	}
+	return key, nil
+}
+
+// checkPasscode rejects passcodes too short to be one
+func checkPasscode(passcode string) error {
+	if len([]rune(passcode)) < minPasscode {
+		return codeErrorf(codeParse, "the passcode needs at least %d characters", minPasscode)
+	}
+	return nil
+}
+
+// Seal encrypts data bound for path if path is covered and encryption is
+// on. It fails rather than write personal data in the clear while locked.
+func (v *dataVault) Seal(path string, data []byte) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.covers(path) {
+		return data, nil
+	}
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	if v.config == nil {
+		return data, nil
+	}
+	if v.key == nil {
+		return nil, errLocked
+	}
+	sealed, err := gcmSeal(v.key, data)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, sealedMagic...), sealed...), nil
+}
+
+// Unseal decrypts data read from a file if it is encrypted
+func (v *dataVault) Unseal(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedMagic) {
+		return data, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return nil, errLocked
+	}
+	plain, err := gcmOpen(v.key, data[len(sealedMagic):])
+	if err != nil {
+		return nil, fmt.Errorf("encrypted file cannot be decrypted: %v", err)
+	}
+	return plain, nil
+}
+
+// rewrite encrypts every covered file with key, or decrypts them with a
+// nil key. Files already in the wanted form are left alone. Callers hold
+// v.mu.
+func (v *dataVault) rewrite(current, key []byte) error {
+	for name := range sealedFiles {
+		path := filepath.Join(v.dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		sealed := bytes.HasPrefix(data, sealedMagic)
+		if sealed == (key != nil) {
+			continue
+		}
+		if sealed {
+			if data, err = gcmOpen(current, data[len(sealedMagic):]); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %v", name, err)
+			}
+		} else {
+			if data, err = gcmSeal(key, data); err != nil {
+				return err
+			}
+			data = append(append([]byte{}, sealedMagic...), data...)
+		}
+		if err := store.WriteFile(path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// status reports the vault. Callers hold v.mu.
+func (v *dataVault) status() LockStatus {
+	if v.config == nil {
+		return LockStatus{}
+	}
+	status := LockStatus{Enabled: true, Locked: v.key == nil, AutoLockMinutes: v.config.AutoLockMinutes}
+	if v.retryAt.After(time.Now()) {
+		status.RetryAfter = v.retryAt.In(shanghaiNow().Location()).Format(time.RFC3339)
+	}
+	return status
+}
+
+// current returns the status, reading vault.json if needed
+func (v *dataVault) current() (LockStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return LockStatus{}, err
+	}
+	return v.status(), nil
+}
+
+// locked reports whether encryption is on and the data key is not known.
+// An unreadable vault.json counts as locked.
+func (v *dataVault) locked() bool {
+	status, err := v.current()
+	return err != nil || status.Locked
+}
+
+// touch records user activity, postponing the auto-lock
+func (v *dataVault) touch() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastActive = time.Now()
+}
+
+// idle reports whether the app is unlocked and has been idle for longer
+// than the auto-lock time
+func (v *dataVault) idle(now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.config == nil || v.key == nil || v.config.AutoLockMinutes <= 0 {
+		return false
+	}
+	return now.Sub(v.lastActive) >= time.Duration(v.config.AutoLockMinutes)*time.Minute
+}
+
+// unlock learns the data key from passcode. Wrong passcodes beyond
+// freeUnlockAttempts make the next attempt wait, doubling each time.
+func (v *dataVault) unlock(passcode string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	if v.config == nil || v.key != nil {
+		return nil
+	}
+	if wait := time.Until(v.retryAt); wait > 0 {
+		return codeErrorf(codeRateLimited, "too many wrong passcodes, try again in %s", wait.Round(time.Second))
+	}
+	key, err := v.config.unwrap(passcode)
+	if err != nil {
+		v.failures++
+		if extra := v.failures - freeUnlockAttempts; extra >= 0 {
+			v.retryAt = time.Now().Add(min(maxUnlockDelay, time.Second<<min(extra, 16)))
+		}
+		return err
+	}
+	v.key, v.failures, v.retryAt, v.lastActive = key, 0, time.Time{}, time.Now()
+	return nil
+}
+
+// lock forgets the data key and reports whether it was known
+func (v *dataVault) lock() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return false
+	}
+	clear(v.key)
+	v.key = nil
+	return true
+}
+
+// enable turns encryption on under passcode and encrypts the covered
+// files. The app stays unlocked.
+func (v *dataVault) enable(passcode string, autoLockMinutes int) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	if v.config != nil {
+		return fmt.Errorf("encryption is already on")
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	config := &vaultConfig{AutoLockMinutes: autoLockMinutes, EnabledAt: shanghaiNow().Format(time.RFC3339)}
+	if err := config.wrap(passcode, key); err != nil {
+		return err
+	}
+	// vault.json goes first: a half-encrypted directory can be read with
+	// it, never without
+	v.config = config
+	if err := v.save(); err != nil {
+		v.config = nil
+		return err
+	}
+	v.key, v.lastActive = key, time.Now()
+	return v.rewrite(nil, key)
+}
+
+// disable decrypts the covered files and turns encryption off
+func (v *dataVault) disable(passcode string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	if v.config == nil {
+		return nil
+	}
+	key, err := v.config.unwrap(passcode)
+	if err != nil {
+		return err
+	}
+	if err := v.rewrite(key, nil); err != nil {
+		return err
+	}
+	if err := os.Remove(v.path()); err != nil {
+		return err
+	}
+	v.config, v.key = nil, nil
+	return nil
+}
+
+// changePasscode wraps the data key under a new passcode
+func (v *dataVault) changePasscode(old, passcode string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	if v.config == nil {
+		return fmt.Errorf("encryption is off")
+	}
+	key, err := v.config.unwrap(old)
+	if err != nil {
+		return err
+	}
+	config := *v.config
+	if err := config.wrap(passcode, key); err != nil {
+		return err
+	}
+	previous := v.config
+	v.config = &config
+	if err := v.save(); err != nil {
+		v.config = previous
+		return err
+	}
+	return nil
+}
+
+// setAutoLock changes the idle time before locking
+func (v *dataVault) setAutoLock(minutes int) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	if v.config == nil {
+		return fmt.Errorf("encryption is off")
+	}
+	previous := v.config.AutoLockMinutes
+	v.config.AutoLockMinutes = minutes
+	if err := v.save(); err != nil {
+		v.config.AutoLockMinutes = previous
+		return err
+	}
+	return nil
+}
+
+// checkAutoLock validates an auto-lock time in minutes
+func checkAutoLock(minutes int) error {
+	if minutes < 0 || minutes > maxAutoLock {
+		return codeErrorf(codeParse, "auto-lock must be between 0 and %d minutes", maxAutoLock)
+	}
+	return nil
+}
+
+// emitLockStatus tells the frontend the data was locked or unlocked
+func (a *App) emitLockStatus() {
+	if a.ctx == nil {
+		return
+	}
+	if status, err := vault.current(); err == nil {
+		wailsruntime.EventsEmit(a.ctx, eventLockStatus, status)
+	}
+}
+
+// lockData stops the loops, forgets the data key and unloads the stores
+// so no personal data stays in memory
+func (a *App) lockData(reason string) {
+	vault.switching.Lock()
+	defer vault.switching.Unlock()
+	if vault.locked() {
+		return
+	}
+	if a.cancelLoops() {
+		a.loops.Wait()
+	}
+	vault.lock()
+	a.reloadStores()
+	a.ClearCache()
+	logger.Info("data locked", "reason", reason)
+	a.emitLockStatus()
+}
+
+// resumeLoops starts the loops again unless the data is locked
+func (a *App) resumeLoops() {
+	if a.ctx != nil && !vault.locked() && !a.loopsRunning() {
+		a.startLoops()
+	}
+}
+
+// runAutoLockLoop locks the data once the user has been idle for the
+// auto-lock time
+func (a *App) runAutoLockLoop(ctx context.Context) {
+	ticker := time.NewTicker(autoLockCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if vault.idle(now) {
+				// Locking waits for the loops, this one included
+				go a.lockData("idle")
+				return
+			}
+		}
+	}
+}
+
+// GetLockStatus reports whether the data is encrypted and locked
+func (a *App) GetLockStatus() (string, error) {
+	status, err := vault.current()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(status)
+}
+
+// EnableEncryption encrypts the files holding personal data under
+// passcode. autoLockMinutes is the idle time before the app locks itself;
+// 0 uses 15 minutes and -1 never locks automatically.
+func (a *App) EnableEncryption(passcode string, autoLockMinutes int) (string, error) {
+	if err := checkPasscode(passcode); err != nil {
+		return "", err
+	}
+	switch autoLockMinutes {
+	case 0:
+		autoLockMinutes = defaultAutoLock
+	case -1:
+		autoLockMinutes = 0
+	}
+	if err := checkAutoLock(autoLockMinutes); err != nil {
+		return "", err
+	}
+	err := vault.enable(passcode, autoLockMinutes)
+	recordAudit(auditSettings, "enable encryption", "vault", "", err)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("encryption enabled", "autoLockMinutes", autoLockMinutes)
+	return a.GetLockStatus()
+}
+
+// DisableEncryption decrypts the files holding personal data and stops
+// asking for the passcode
+func (a *App) DisableEncryption(passcode string) (string, error) {
+	vault.switching.Lock()
+	defer vault.switching.Unlock()
+	err := vault.disable(passcode)
+	recordAudit(auditSettings, "disable encryption", "vault", "", err)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("encryption disabled")
+	a.migrate()
+	a.resumeLoops()
+	a.emitLockStatus()
+	return a.GetLockStatus()
+}
+
+// ChangePasscode replaces the passcode. The data stays encrypted with the
+// same key, so nothing but vault.json is rewritten.
+func (a *App) ChangePasscode(oldPasscode string, newPasscode string) error {
+	if err := checkPasscode(newPasscode); err != nil {
+		return err
+	}
+	err := vault.changePasscode(oldPasscode, newPasscode)
+	recordAudit(auditSettings, "change passcode", "vault", "", err)
+	if err != nil {
+		return err
+	}
+	logger.Info("passcode changed")
+	return nil
+}
+
+// SetAutoLock sets the idle time in minutes before the app locks itself;
+// 0 never locks automatically
+func (a *App) SetAutoLock(minutes int) (string, error) {
+	if err := checkAutoLock(minutes); err != nil {
+		return "", err
+	}
+	err := vault.setAutoLock(minutes)
+	recordAudit(auditSettings, "set auto-lock", "vault", fmt.Sprintf("%d minutes", minutes), err)
+	if err != nil {
+		return "", err
+	}
+	return a.GetLockStatus()
+}
+
+// Unlock decrypts the data with passcode and starts the background loops
+func (a *App) Unlock(passcode string) (string, error) {
+	vault.switching.Lock()
+	defer vault.switching.Unlock()
+	if err := vault.unlock(passcode); err != nil {
+		logger.Warn("unlock failed", "error", err)
+		return "", err
+	}
+	logger.Info("data unlocked")
+	// Migrations that touch encrypted files wait for the first unlock
+	a.migrate()
+	a.resumeLoops()
+	a.emitLockStatus()
+	return a.GetLockStatus()
+}
+
+// Lock forgets the passcode until the next Unlock
+func (a *App) Lock() error {
+	status, err := vault.current()
+	if err != nil {
+		return err
+	}
+	if !status.Enabled {
+		return fmt.Errorf("encryption is off")
+	}
+	a.lockData("user")
+	return nil
+}
+
+// RecordActivity postpones the auto-lock. The frontend calls it on user
+// input, at most every few seconds.
+func (a *App) RecordActivity() {
+	vault.touch()
+}
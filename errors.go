@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"stock-analysis/internal/providers"
+)
+
+// ErrorCode classifies an error so the frontend can decide what to show
+// and whether to retry
+type ErrorCode string
+
+const (
+	codeNetwork     ErrorCode = "network"     // the provider could not be reached
+	codeProvider    ErrorCode = "provider"    // the provider answered with an error
+	codeParse       ErrorCode = "parse"       // a response or parameter could not be read
+	codeRateLimited ErrorCode = "rateLimited" // the provider is throttling requests
+	codeNoData      ErrorCode = "noData"      // nothing to return for the request
+	codeOffline     ErrorCode = "offline"     // offline mode is on
+	codeCanceled    ErrorCode = "canceled"    // the user canceled the job
+	codeBusy        ErrorCode = "busy"        // the same operation is already running
+	codeNotFound    ErrorCode = "notFound"    // a file or record does not exist
+	codeLocked      ErrorCode = "locked"      // the data is encrypted and locked
+	codeInternal    ErrorCode = "internal"    // anything else
+)
+
+// errorMessage returns the user-facing message of code in the locale of
+// the settings
+func errorMessage(code ErrorCode) string {
+	return tr("error." + string(code))
+}
+
+// retryableCodes are the codes worth retrying later without changes
+var retryableCodes = map[ErrorCode]bool{
+	codeNetwork:     true,
+	codeProvider:    true,
+	codeRateLimited: true,
+	codeBusy:        true,
+}
+
+// AppError is what a bound method's error looks like to the frontend
+type AppError struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"` // user-facing, by code
+	Detail    string    `json:"detail"`  // the underlying error text
+	Retryable bool      `json:"retryable"`
+
+	Response *ProviderResponseError `json:"response,omitempty"` // set for unusable provider responses
+}
+
+// codedError attaches an ErrorCode to an error
+type codedError struct {
+	code ErrorCode
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// codeErrorf formats an error like fmt.Errorf and tags it with code
+func codeErrorf(code ErrorCode, format string, args ...interface{}) error {
+	return &codedError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// errorCode classifies err, looking through wrapped errors
+func errorCode(err error) ErrorCode {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, errJobCanceled), errors.Is(err, context.Canceled):
+		return codeCanceled
+	case errors.Is(err, os.ErrNotExist):
+		return codeNotFound
+	case errors.Is(err, providers.ErrNoBars):
+		return codeNoData
+	case errors.Is(err, providers.ErrMalformed):
+		return codeParse
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return codeParse
+	case isNetworkError(err):
+		return codeNetwork
+	}
+	return codeInternal
+}
+
+// formatError turns an error returned by a bound method into an AppError
+// for the frontend
+func formatError(err error) any {
+	code := errorCode(err)
+	logger.Warn("request failed", "code", code, "error", err.Error())
+	out := AppError{
+		Code:      code,
+		Message:   errorMessage(code),
+		Detail:    err.Error(),
+		Retryable: retryableCodes[code],
+	}
+	var respErr *ProviderResponseError
+	if errors.As(err, &respErr) {
+		out.Response = respErr
+	}
+	return out
+}
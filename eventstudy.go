@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/analysis"
+	"stock-analysis/internal/providers"
+)
+
+// An event study measures how a stock typically reacts to a kind of
+// event. Each event day is day 0 of a window of sessions around it. The
+// returns expected in the window come from a market model fitted over an
+// estimation period just before the window: r = alpha + beta * r_market.
+// The abnormal return of a day is its return less the expected one, and
+// the abnormal volume its volume against the estimation period's average.
+// Averaging across events removes the noise specific to any one of them.
+
+const (
+	eventStudyBefore     = 5   // default sessions before day 0
+	eventStudyAfter      = 10  // default sessions after day 0
+	eventStudyEstimation = 120 // default sessions of the estimation period
+	// eventStudyMinEstimation is the fewest estimation sessions a market
+	// model is fitted on
+	eventStudyMinEstimation = 30
+)
+
+// EventStudyRequest describes an event study. Dates are the events;
+// with Dividends set the ex-dividend dates of the symbol are added.
+// Events on non-trading days count from the next session.
+type EventStudyRequest struct {
+	Symbol     string   `json:"symbol"`
+	Dates      []string `json:"dates"`
+	Dividends  bool     `json:"dividends,omitempty"`
+	Benchmark  string   `json:"benchmark,omitempty"`  // default zs_000001
+	Before     int      `json:"before,omitempty"`     // sessions before day 0, default 5
+	After      int      `json:"after,omitempty"`      // sessions after day 0, default 10
+	Estimation int      `json:"estimation,omitempty"` // sessions of the estimation period, default 120
+}
+
+// EventWindow is the reaction to one event
+type EventWindow struct {
+	Date    string  `json:"date"`              // as given
+	Day0    string  `json:"day0"`              // the session it counts from
+	Alpha   float64 `json:"alpha"`             // daily, %
+	Beta    float64 `json:"beta"`              // against the benchmark
+	CAR     float64 `json:"car"`               // cumulative abnormal return over the window, %
+	Skipped string  `json:"skipped,omitempty"` // why the event was left out, if it was
+}
+
+// EventStudy is the average reaction over the events. The per-offset
+// series run from -Before to After.
+type EventStudy struct {
+	Symbol         string        `json:"symbol"`
+	Benchmark      string        `json:"benchmark"`
+	Offsets        []int         `json:"offsets"`
+	AAR            []float64     `json:"aar"`            // average abnormal return, %
+	CAAR           []float64     `json:"caar"`           // cumulative from -Before, %
+	AbnormalVolume []float64     `json:"abnormalVolume"` // average volume against the estimation period, %
+	Events         []EventWindow `json:"events"`
+	Used           int           `json:"used"`
+	TStat          float64       `json:"tStat"`    // of the mean CAR across events
+	Positive       float64       `json:"positive"` // share of events with a positive CAR, %
+}
+
+// normalize fills in the defaults and checks the window
+func (r *EventStudyRequest) normalize() error {
+	r.Symbol = normalizeSymbol(r.Symbol)
+	if r.Symbol == "" {
+		return codeErrorf(codeParse, "symbol is required")
+	}
+	if r.Benchmark == "" {
+		r.Benchmark = defaultBenchmark
+	}
+	if r.Before == 0 {
+		r.Before = eventStudyBefore
+	}
+	if r.After == 0 {
+		r.After = eventStudyAfter
+	}
+	if r.Estimation == 0 {
+		r.Estimation = eventStudyEstimation
+	}
+	if r.Before < 0 || r.After < 0 || r.Before+r.After > 120 {
+		return codeErrorf(codeParse, "event window must span 0 to 120 sessions")
+	}
+	if r.Estimation < eventStudyMinEstimation {
+		return codeErrorf(codeParse, "estimation period must be at least %d sessions", eventStudyMinEstimation)
+	}
+	return nil
+}
+
+// eventStudy computes the study over the aligned closes and volumes of
+// the symbol and the benchmark
+func eventStudy(req EventStudyRequest, dates []string, prices, bench, volumes []float64) EventStudy {
+	span := req.Before + req.After + 1
+	study := EventStudy{
+		Symbol:         req.Symbol,
+		Benchmark:      req.Benchmark,
+		Offsets:        make([]int, span),
+		AAR:            make([]float64, span),
+		CAAR:           make([]float64, span),
+		AbnormalVolume: make([]float64, span),
+		Events:         []EventWindow{},
+	}
+	for k := range study.Offsets {
+		study.Offsets[k] = k - req.Before
+	}
+
+	// returns[i] is the return into dates[i]
+	returns := append([]float64{0}, analysis.SimpleReturns(prices)...)
+	benchReturns := append([]float64{0}, analysis.SimpleReturns(bench)...)
+	var cars []float64
+	for _, date := range req.Dates {
+		e := EventWindow{Date: date}
+		day0 := sort.SearchStrings(dates, date)
+		start, end := day0-req.Before, day0+req.After
+		switch {
+		case day0 == len(dates):
+			e.Skipped = "after the last session"
+		case end >= len(dates):
+			e.Day0, e.Skipped = dates[day0], "window not complete yet"
+		case start-req.Estimation < 1:
+			e.Day0, e.Skipped = dates[day0], "not enough history before the event"
+		}
+		if e.Skipped != "" {
+			study.Events = append(study.Events, e)
+			continue
+		}
+		e.Day0 = dates[day0]
+
+		est := returns[start-req.Estimation : start]
+		estBench := benchReturns[start-req.Estimation : start]
+		beta := 0.0
+		if v := analysis.Variance(estBench); v > 0 {
+			beta = analysis.Covariance(est, estBench) / v
+		}
+		alpha := analysis.Mean(est) - beta*analysis.Mean(estBench)
+		avgVolume := analysis.Mean(volumes[start-req.Estimation : start])
+		e.Alpha, e.Beta = alpha*100, beta
+
+		for k := 0; k < span; k++ {
+			i := start + k
+			ar := (returns[i] - alpha - beta*benchReturns[i]) * 100
+			e.CAR += ar
+			study.AAR[k] += ar
+			if avgVolume > 0 {
+				study.AbnormalVolume[k] += (volumes[i]/avgVolume - 1) * 100
+			}
+		}
+		cars = append(cars, e.CAR)
+		study.Events = append(study.Events, e)
+	}
+
+	study.Used = len(cars)
+	if study.Used == 0 {
+		return study
+	}
+	n := float64(study.Used)
+	sum := 0.0
+	for k := range study.AAR {
+		study.AAR[k] /= n
+		study.AbnormalVolume[k] /= n
+		sum += study.AAR[k]
+		study.CAAR[k] = sum
+	}
+	if sd := analysis.StdDev(cars); sd > 0 {
+		study.TStat = analysis.Mean(cars) / (sd / math.Sqrt(n))
+	}
+	positive := 0
+	for _, car := range cars {
+		if car > 0 {
+			positive++
+		}
+	}
+	study.Positive = float64(positive) / n * 100
+	return study
+}
+
+// RunEventStudy computes the average abnormal return and volume of a
+// symbol around event dates. requestJSON is an EventStudyRequest.
+func (a *App) RunEventStudy(requestJSON string) (string, error) {
+	var req EventStudyRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse event study: %v", err)
+	}
+	if err := req.normalize(); err != nil {
+		return "", err
+	}
+	if req.Dividends {
+		events, err := fetchDividendEvents(providers.SohuCode(req.Symbol))
+		if err != nil {
+			return "", fmt.Errorf("failed to get dividend dates: %v", err)
+		}
+		for _, e := range events {
+			req.Dates = append(req.Dates, e.ExDate)
+		}
+	}
+	now := shanghaiNow()
+	var dates []string
+	for _, d := range req.Dates {
+		d = strings.TrimSpace(d)
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return "", codeErrorf(codeParse, "invalid event date: %s", d)
+		}
+		if !containsString(dates, d) {
+			dates = append(dates, d)
+		}
+	}
+	if len(dates) == 0 {
+		return "", codeErrorf(codeNoData, "no event dates")
+	}
+	sort.Strings(dates)
+	req.Dates = dates
+
+	first, _ := time.ParseInLocation("2006-01-02", req.Dates[0], now.Location())
+	start := tradingDaysBefore(first, req.Estimation+req.Before+5)
+	bars, err := fetchDailyBars(req.Symbol, start, now)
+	if err != nil {
+		return "", err
+	}
+	benchBars, err := fetchDailyBars(req.Benchmark, start, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to get benchmark data: %w", err)
+	}
+	sessions, aligned := alignCloses(map[string][]Bar{"symbol": bars, "benchmark": benchBars})
+	volumeOn := make(map[string]float64, len(bars))
+	for _, b := range bars {
+		volumeOn[b.Date] = b.Volume
+	}
+	volumes := make([]float64, len(sessions))
+	for i, d := range sessions {
+		volumes[i] = volumeOn[d]
+	}
+	return toJSON(eventStudy(req, sessions, aligned["symbol"], aligned["benchmark"], volumes))
+}
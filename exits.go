@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExitRule is a single stop-loss / take-profit condition.
+//
+// Supported types:
+//   - "stopLoss":    exit when price falls Percent below entry
+//   - "takeProfit":  exit when price rises Percent above entry
+//   - "atrTrailing": exit when price falls ATRMultiple ATRs below the highest close since entry
+//   - "timeStop":    exit after MaxBars bars in the position
+type ExitRule struct {
+	Type        string  `json:"type"`
+	Percent     float64 `json:"percent,omitempty"`
+	ATRMultiple float64 `json:"atrMultiple,omitempty"`
+	ATRPeriod   int     `json:"atrPeriod,omitempty"`
+	MaxBars     int     `json:"maxBars,omitempty"`
+}
+
+// exitState tracks what the rules need to know about an open position
+type exitState struct {
+	EntryPrice   float64 `json:"entryPrice"`
+	HighestClose float64 `json:"highestClose"`
+	BarsHeld     int     `json:"barsHeld"`
+}
+
+// ExitSignal describes a rule that fired
+type ExitSignal struct {
+	Rule  ExitRule `json:"rule"`
+	Price float64  `json:"price"`
+	Level float64  `json:"level"`
+	Date  string   `json:"date"`
+}
+
+// atrPeriod returns the ATR period used by the rule
+func (r ExitRule) atrPeriod() int {
+	if r.ATRPeriod > 0 {
+		return r.ATRPeriod
+	}
+	return 14
+}
+
+// checkExits evaluates rules against bar and returns the first one that
+// fires. atrs maps each ATR period to its value as of the previous bar.
+// Price gaps through a level are filled at the open.
+func checkExits(rules []ExitRule, state exitState, bar Bar, atrs map[int]float64) (ExitSignal, bool) {
+	for _, rule := range rules {
+		switch rule.Type {
+		case "stopLoss":
+			level := state.EntryPrice * (1 - rule.Percent/100)
+			if rule.Percent > 0 && bar.Low <= level {
+				return ExitSignal{Rule: rule, Level: level, Price: math.Min(bar.Open, level), Date: bar.Date}, true
+			}
+		case "takeProfit":
+			level := state.EntryPrice * (1 + rule.Percent/100)
+			if rule.Percent > 0 && bar.High >= level {
+				return ExitSignal{Rule: rule, Level: level, Price: math.Max(bar.Open, level), Date: bar.Date}, true
+			}
+		case "atrTrailing":
+			atr := atrs[rule.atrPeriod()]
+			if rule.ATRMultiple <= 0 || atr <= 0 || math.IsNaN(atr) {
+				continue
+			}
+			level := state.HighestClose - rule.ATRMultiple*atr
+			if bar.Low <= level {
+				return ExitSignal{Rule: rule, Level: level, Price: math.Min(bar.Open, level), Date: bar.Date}, true
+			}
+		case "timeStop":
+			if rule.MaxBars > 0 && state.BarsHeld >= rule.MaxBars {
+				return ExitSignal{Rule: rule, Level: bar.Close, Price: bar.Close, Date: bar.Date}, true
+			}
+		}
+	}
+	return ExitSignal{}, false
+}
+
+// validateExitRules rejects unknown rule types and missing parameters
+func validateExitRules(rules []ExitRule) error {
+	for _, rule := range rules {
+		switch rule.Type {
+		case "stopLoss", "takeProfit":
+			if rule.Percent <= 0 {
+				return codeErrorf(codeParse, "%s rule requires a positive percent", rule.Type)
+			}
+		case "atrTrailing":
+			if rule.ATRMultiple <= 0 {
+				return codeErrorf(codeParse, "atrTrailing rule requires a positive atrMultiple")
+			}
+		case "timeStop":
+			if rule.MaxBars <= 0 {
+				return codeErrorf(codeParse, "timeStop rule requires a positive maxBars")
+			}
+		default:
+			return codeErrorf(codeParse, "unknown exit rule type: %s", rule.Type)
+		}
+	}
+	return nil
+}
+
+// maxATRPeriod returns the longest ATR period any rule needs
+func maxATRPeriod(rules []ExitRule) int {
+	period := 0
+	for _, rule := range rules {
+		if rule.Type == "atrTrailing" && rule.atrPeriod() > period {
+			period = rule.atrPeriod()
+		}
+	}
+	return period
+}
+
+// exitATRSeries computes the ATR series for every period the rules use
+func exitATRSeries(rules []ExitRule, bars []Bar) map[int][]float64 {
+	series := make(map[int][]float64)
+	for _, rule := range rules {
+		if rule.Type != "atrTrailing" {
+			continue
+		}
+		if _, ok := series[rule.atrPeriod()]; !ok {
+			series[rule.atrPeriod()] = ATR(bars, rule.atrPeriod())
+		}
+	}
+	return series
+}
+
+// atrsAt picks the value at index i out of each ATR series
+func atrsAt(series map[int][]float64, i int) map[int]float64 {
+	atrs := make(map[int]float64, len(series))
+	for period, values := range series {
+		if i >= 0 && i < len(values) {
+			atrs[period] = values[i]
+		}
+	}
+	return atrs
+}
+
+// PositionExitRules are live monitoring rules attached to a held symbol
+type PositionExitRules struct {
+	Symbol    string     `json:"symbol"`
+	Rules     []ExitRule `json:"rules"`
+	EntryDate string     `json:"entryDate"`
+	State     exitState  `json:"state"`
+}
+
+// PositionExitAlert is a live exit rule that fired for a held symbol
+type PositionExitAlert struct {
+	Symbol string     `json:"symbol"`
+	Shares float64    `json:"shares"`
+	Signal ExitSignal `json:"signal"`
+}
+
+// exitRuleStore persists live exit rules keyed by symbol
+type exitRuleStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	rules  map[string]*PositionExitRules
+}
+
+func newExitRuleStore(dataDir string) *exitRuleStore {
+	return &exitRuleStore{path: filepath.Join(dataDir, "exit_rules.json")}
+}
+
+// unload forgets the rules so the next use reads the file again
+func (s *exitRuleStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules, s.loaded = nil, false
+}
+
+// load reads the rules from disk on first use. Callers hold s.mu.
+func (s *exitRuleStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.rules = make(map[string]*PositionExitRules)
+	if err := loadJSON(s.path, &s.rules); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// SetPositionExitRules attaches live exit rules to a held symbol. Passing
+// an empty list removes the rules.
+func (a *App) SetPositionExitRules(symbol string, rulesJSON string) error {
+	var rules []ExitRule
+	if rulesJSON != "" {
+		if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+			return codeErrorf(codeParse, "failed to parse exit rules: %v", err)
+		}
+	}
+	if err := validateExitRules(rules); err != nil {
+		return err
+	}
+
+	s := a.exitRules
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		delete(s.rules, symbol)
+	} else if existing, ok := s.rules[symbol]; ok {
+		existing.Rules = rules
+	} else {
+		s.rules[symbol] = &PositionExitRules{
+			Symbol:    symbol,
+			Rules:     rules,
+			EntryDate: shanghaiNow().Format("2006-01-02"),
+		}
+	}
+
+	return saveJSON(s.path, s.rules)
+}
+
+// GetPositionExitRules returns all live exit rules
+func (a *App) GetPositionExitRules() (string, error) {
+	s := a.exitRules
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	list := make([]*PositionExitRules, 0, len(s.rules))
+	for _, r := range s.rules {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return toJSON(list)
+}
+
+// monitoredPosition is a held position that live exit rules watch
+type monitoredPosition struct {
+	Shares    float64
+	AvgCost   float64
+	EntryDate string
+}
+
+// monitoredPositions merges the paper account positions with the
+// portfolio holdings. A symbol held in both is combined at average cost.
+func (a *App) monitoredPositions() (map[string]monitoredPosition, error) {
+	positions := make(map[string]monitoredPosition)
+	add := func(symbol string, shares, avgCost float64, entryDate string) {
+		pos := positions[symbol]
+		total := pos.Shares + shares
+		pos.AvgCost = (pos.AvgCost*pos.Shares + avgCost*shares) / total
+		pos.Shares = total
+		if pos.EntryDate == "" || (entryDate != "" && entryDate < pos.EntryDate) {
+			pos.EntryDate = entryDate
+		}
+		positions[symbol] = pos
+	}
+
+	a.paper.mu.Lock()
+	if err := a.paper.load(); err != nil {
+		a.paper.mu.Unlock()
+		return nil, err
+	}
+	for sym, pos := range a.paper.account.Positions {
+		add(sym, float64(pos.Shares), pos.AvgCost, "")
+	}
+	a.paper.mu.Unlock()
+
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := p.holdings()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		if h.Shares > 0 {
+			add(h.Symbol, h.Shares, h.AvgCost, h.FirstBought)
+		}
+	}
+	return positions, nil
+}
+
+// CheckPositionExits evaluates live exit rules against the positions held
+// in the paper account and the portfolio, returning the rules that fired
+// on the latest bar
+func (a *App) CheckPositionExits() (string, error) {
+	positions, err := a.monitoredPositions()
+	if err != nil {
+		return "", err
+	}
+
+	s := a.exitRules
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	alerts := []PositionExitAlert{}
+	now := shanghaiNow()
+	for sym, cfg := range s.rules {
+		pos, held := positions[sym]
+		if !held {
+			continue
+		}
+		entryDate := cfg.EntryDate
+		if pos.EntryDate != "" && pos.EntryDate < entryDate {
+			entryDate = pos.EntryDate
+		}
+		entry, err := time.Parse("2006-01-02", entryDate)
+		if err != nil {
+			entry = now
+		}
+		// Include enough bars before entry to warm up the ATR
+		start := entry.AddDate(0, 0, -(maxATRPeriod(cfg.Rules)*2 + 10))
+		bars, err := fetchDailyBars(sym, start, now)
+		if err != nil || len(bars) == 0 {
+			continue
+		}
+
+		state := exitState{EntryPrice: pos.AvgCost}
+		for _, bar := range bars {
+			if bar.Date >= entryDate {
+				state.BarsHeld++
+				state.HighestClose = math.Max(state.HighestClose, bar.Close)
+			}
+		}
+		if state.HighestClose == 0 {
+			state.HighestClose = pos.AvgCost
+		}
+		cfg.State = state
+
+		last := len(bars) - 1
+		atrs := atrsAt(exitATRSeries(cfg.Rules, bars), last-1)
+		if signal, hit := checkExits(cfg.Rules, state, bars[last], atrs); hit {
+			alerts = append(alerts, PositionExitAlert{Symbol: sym, Shares: pos.Shares, Signal: signal})
+		}
+	}
+
+	if err := saveJSON(s.path, s.rules); err != nil {
+		return "", err
+	}
+
+	return toJSON(alerts)
+}
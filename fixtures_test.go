@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// replayFixtures serves provider requests from testdata/fixtures for the
+// rest of the test, without the local history
+func replayFixtures(t *testing.T) {
+	t.Helper()
+	history := localHistory
+	localHistory = nil
+	fixtures.set(fixturesReplay, "testdata/fixtures")
+	t.Cleanup(func() {
+		fixtures.set(fixturesOff, "")
+		localHistory = history
+	})
+}
+
+func shanghaiDay(date string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02", date, shanghaiNow().Location())
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestReplaySohuBars(t *testing.T) {
+	replayFixtures(t)
+
+	bars, err := downloadDailyBars(context.Background(), "600519", shanghaiDay("2024-09-02"), shanghaiDay("2024-09-13"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 10 {
+		t.Fatalf("got %d bars, want 10", len(bars))
+	}
+	if bars[0].Date != "2024-09-02" || bars[9].Date != "2024-09-13" {
+		t.Errorf("bars run %s to %s, want oldest first", bars[0].Date, bars[9].Date)
+	}
+	want := Bar{Date: "2024-09-13", Open: 1318, Close: 1313.9, Change: -3.45, ChangePct: -0.26,
+		Low: 1306, High: 1322, Volume: 29605, Turnover: 389587, TurnoverRate: 0.24}
+	if bars[9] != want {
+		t.Errorf("last bar = %+v, want %+v", bars[9], want)
+	}
+	for i := 1; i < len(bars); i++ {
+		if prev := bars[i].Close - bars[i].Change; prev-bars[i-1].Close > 0.005 || bars[i-1].Close-prev > 0.005 {
+			t.Errorf("%s: close less change is %.2f, previous close %.2f", bars[i].Date, prev, bars[i-1].Close)
+		}
+	}
+}
+
+func TestReplaySohuIndex(t *testing.T) {
+	replayFixtures(t)
+
+	bars, err := downloadDailyBars(context.Background(), "zs_000001", shanghaiDay("2024-09-02"), shanghaiDay("2024-09-13"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 10 {
+		t.Fatalf("got %d bars, want 10", len(bars))
+	}
+	// Indices have no turnover rate; hisHq sends "-"
+	for _, b := range bars {
+		if b.TurnoverRate != 0 {
+			t.Errorf("%s: turnover rate %v, want 0", b.Date, b.TurnoverRate)
+		}
+	}
+	if bars[0].Close != 2811.04 {
+		t.Errorf("first close = %v, want 2811.04", bars[0].Close)
+	}
+}
+
+func TestReplaySohuNoData(t *testing.T) {
+	replayFixtures(t)
+
+	_, err := downloadDailyBars(context.Background(), "600519", shanghaiDay("2024-10-01"), shanghaiDay("2024-10-07"))
+	if !errors.Is(err, providers.ErrNoBars) {
+		t.Fatalf("got %v, want ErrNoBars", err)
+	}
+	if code := errorCode(err); code != codeNoData {
+		t.Errorf("error code = %s, want %s", code, codeNoData)
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	replayFixtures(t)
+
+	_, err := downloadDailyBars(context.Background(), "600519", shanghaiDay("2023-01-03"), shanghaiDay("2023-01-06"))
+	if code := errorCode(err); code != codeNoData {
+		t.Fatalf("got %v (%s), want a noData error", err, code)
+	}
+}
+
+func TestReplayQuote(t *testing.T) {
+	replayFixtures(t)
+	defer setClock(fixedClock(shanghaiDay("2024-09-13").Add(16 * time.Hour)))()
+	resultCache.clear()
+	defer resultCache.clear()
+
+	quote, err := fetchQuote("600519")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := providers.Quote{Symbol: "600519", Date: "2024-09-13", Price: 1313.9, PrevClose: 1317.35,
+		Change: -3.45, ChangePct: -0.26, Volume: 29605, Turnover: 389587}
+	if quote.Date != want.Date || quote.Price != want.Price || quote.Change != want.Change ||
+		quote.ChangePct != want.ChangePct || quote.Volume != want.Volume || quote.Turnover != want.Turnover {
+		t.Errorf("quote = %+v, want %+v", quote.Quote, want)
+	}
+	if d := quote.PrevClose - want.PrevClose; d > 1e-9 || d < -1e-9 {
+		t.Errorf("prev close = %v, want %v", quote.PrevClose, want.PrevClose)
+	}
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"stock-analysis/internal/indicators"
+)
+
+// The formula engine evaluates 通达信-style expressions such as
+//
+//	TURNOVER_RATE_5D > 80 AND CLOSE > MA(CLOSE, 20)
+//
+// over a bar series. Every expression evaluates to a series with one value
+// per bar; conditions yield 1 for true and 0 for false.
+
+// formulaNode is a parsed expression
+type formulaNode interface {
+	eval(ctx *formulaContext) ([]float64, error)
+}
+
+type numberNode float64
+
+type identNode string
+
+type unaryNode struct {
+	op      string
+	operand formulaNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right formulaNode
+}
+
+type callNode struct {
+	name string
+	args []formulaNode
+}
+
+// formulaContext provides the data series an expression can reference
+type formulaContext struct {
+	bars   []Bar
+	symbol string          // optional, for the board's price limit
+	meta   *SymbolMetadata // optional, for share-count based variables
+	series map[string][]float64
+}
+
+func newFormulaContext(bars []Bar) *formulaContext {
+	return &formulaContext{bars: bars, series: make(map[string][]float64)}
+}
+
+// variable resolves a built-in series by name, computing it on first use
+func (ctx *formulaContext) variable(name string) ([]float64, error) {
+	if s, ok := ctx.series[name]; ok {
+		return s, nil
+	}
+	n := len(ctx.bars)
+	pick := func(f func(Bar) float64) []float64 {
+		out := make([]float64, n)
+		for i, bar := range ctx.bars {
+			out[i] = f(bar)
+		}
+		return out
+	}
+
+	var s []float64
+	switch name {
+	case "OPEN", "O":
+		s = pick(func(b Bar) float64 { return b.Open })
+	case "HIGH", "H":
+		s = pick(func(b Bar) float64 { return b.High })
+	case "LOW", "L":
+		s = pick(func(b Bar) float64 { return b.Low })
+	case "CLOSE", "C":
+		s = pick(func(b Bar) float64 { return b.Close })
+	case "VOLUME", "VOL", "V":
+		s = pick(func(b Bar) float64 { return b.Volume })
+	case "TURNOVER", "AMOUNT":
+		s = pick(func(b Bar) float64 { return b.Turnover })
+	case "CHANGE_PCT":
+		s = pick(func(b Bar) float64 { return b.ChangePct })
+	case "TURNOVER_RATIO":
+		s = pick(func(b Bar) float64 {
+			// Fall back to volume (in 手) over free float when the feed
+			// leaves the rate empty
+			if b.TurnoverRate == 0 && ctx.meta != nil && ctx.meta.FloatShares > 0 {
+				return b.Volume * 100 / ctx.meta.FloatShares * 100
+			}
+			return b.TurnoverRate
+		})
+	case "CAPITAL":
+		// 流通股本 in 手, as in 通达信
+		s = pick(func(Bar) float64 {
+			if ctx.meta == nil {
+				return math.NaN()
+			}
+			return ctx.meta.FloatShares / 100
+		})
+	case "VOLUME_RATE_5D":
+		v, _ := ctx.variable("VOLUME")
+		s = indicators.FiveDayRate(v)
+	case "TURNOVER_RATE_5D":
+		v, _ := ctx.variable("TURNOVER")
+		s = indicators.FiveDayRate(v)
+	case "AMPLITUDE":
+		o, _ := ctx.variable("OPEN")
+		h, _ := ctx.variable("HIGH")
+		l, _ := ctx.variable("LOW")
+		c, _ := ctx.variable("CLOSE")
+		s = indicators.Amplitude(h, l, o, c)
+	case "AMPLITUDE_MA20":
+		amp, _ := ctx.variable("AMPLITUDE")
+		s = indicators.SMA(amp, rangeAvgPeriod)
+	case "RANGE_EXPANSION":
+		amp, _ := ctx.variable("AMPLITUDE")
+		s = indicators.RangeExpansion(amp, rangeAvgPeriod, rangeExpansionFactor)
+	case "BOARDS":
+		s = limitUpSeries(ctx)
+	case "LIMIT_UP":
+		boards, _ := ctx.variable("BOARDS")
+		s = mapSeries(boards, func(n float64) float64 { return boolValue(n > 0) })
+	case "ML_SCORE":
+		scores, err := modelScores(ctx.symbol, ctx.bars)
+		if err != nil {
+			return nil, err
+		}
+		s = scores
+	case "DIF", "DEA", "MACD":
+		c, _ := ctx.variable("CLOSE")
+		dif, dea, hist := indicators.MACD(c, 12, 26, 9)
+		ctx.series["DIF"], ctx.series["DEA"], ctx.series["MACD"] = dif, dea, hist
+		return ctx.series[name], nil
+	default:
+		return nil, fmt.Errorf("unknown variable: %s", name)
+	}
+	ctx.series[name] = s
+	return s, nil
+}
+
+func (n numberNode) eval(ctx *formulaContext) ([]float64, error) {
+	out := make([]float64, len(ctx.bars))
+	for i := range out {
+		out[i] = float64(n)
+	}
+	return out, nil
+}
+
+func (n identNode) eval(ctx *formulaContext) ([]float64, error) {
+	return ctx.variable(string(n))
+}
+
+func (n unaryNode) eval(ctx *formulaContext) ([]float64, error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(v))
+	for i, x := range v {
+		switch n.op {
+		case "-":
+			out[i] = -x
+		case "NOT":
+			out[i] = boolValue(!truthy(x))
+		}
+	}
+	return out, nil
+}
+
+func (n binaryNode) eval(ctx *formulaContext) ([]float64, error) {
+	l, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(l))
+	for i := range l {
+		a, b := l[i], r[i]
+		switch n.op {
+		case "+":
+			out[i] = a + b
+		case "-":
+			out[i] = a - b
+		case "*":
+			out[i] = a * b
+		case "/":
+			if b == 0 {
+				out[i] = math.NaN()
+			} else {
+				out[i] = a / b
+			}
+		case ">":
+			out[i] = boolValue(a > b)
+		case "<":
+			out[i] = boolValue(a < b)
+		case ">=":
+			out[i] = boolValue(a >= b)
+		case "<=":
+			out[i] = boolValue(a <= b)
+		case "==":
+			out[i] = boolValue(a == b)
+		case "!=":
+			out[i] = boolValue(a != b)
+		case "AND":
+			out[i] = boolValue(truthy(a) && truthy(b))
+		case "OR":
+			out[i] = boolValue(truthy(a) || truthy(b))
+		}
+	}
+	return out, nil
+}
+
+func (n callNode) eval(ctx *formulaContext) ([]float64, error) {
+	// ATR takes a period only, everything else takes series arguments
+	if n.name == "ATR" {
+		period, err := n.intArg(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		return ATR(ctx.bars, period), nil
+	}
+
+	args := make([][]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch n.name {
+	case "MA", "SMA", "EMA", "REF", "HHV", "LLV", "STD", "SUM":
+		period, err := n.intArg(1, 2)
+		if err != nil {
+			return nil, err
+		}
+		switch n.name {
+		case "MA", "SMA":
+			return indicators.SMA(args[0], period), nil
+		case "EMA":
+			return indicators.EMA(args[0], period), nil
+		case "REF":
+			return indicators.Ref(args[0], period), nil
+		case "HHV":
+			return indicators.RollingExtreme(args[0], period, math.Max), nil
+		case "LLV":
+			return indicators.RollingExtreme(args[0], period, math.Min), nil
+		case "STD":
+			return indicators.StdDev(args[0], period), nil
+		case "SUM":
+			return indicators.RollingSum(args[0], period), nil
+		}
+	case "CROSS":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("CROSS expects 2 arguments")
+		}
+		out := make([]float64, len(args[0]))
+		for i := 1; i < len(out); i++ {
+			out[i] = boolValue(args[0][i-1] <= args[1][i-1] && args[0][i] > args[1][i])
+		}
+		return out, nil
+	case "ABS":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("ABS expects 1 argument")
+		}
+		return mapSeries(args[0], math.Abs), nil
+	case "MAX", "MIN":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s expects 2 arguments", n.name)
+		}
+		f := math.Max
+		if n.name == "MIN" {
+			f = math.Min
+		}
+		out := make([]float64, len(args[0]))
+		for i := range out {
+			out[i] = f(args[0][i], args[1][i])
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown function: %s", n.name)
+}
+
+// intArg reads argument i as a constant integer period
+func (n callNode) intArg(i, want int) (int, error) {
+	if len(n.args) != want {
+		return 0, fmt.Errorf("%s expects %d arguments", n.name, want)
+	}
+	num, ok := n.args[i].(numberNode)
+	if !ok || num < 0 {
+		return 0, fmt.Errorf("%s period must be a non-negative number", n.name)
+	}
+	return int(num), nil
+}
+
+func truthy(x float64) bool {
+	return x != 0 && !math.IsNaN(x)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func mapSeries(values []float64, f func(float64) float64) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// Formula is a compiled expression ready to be evaluated against bars
+type Formula struct {
+	source string
+	root   formulaNode
+}
+
+// compileFormula parses an expression
+func compileFormula(source string) (*Formula, error) {
+	tokens, err := lexFormula(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &formulaParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in formula", p.tokens[p.pos])
+	}
+	return &Formula{source: source, root: root}, nil
+}
+
+// Eval evaluates the formula over bars
+func (f *Formula) Eval(bars []Bar) ([]float64, error) {
+	return f.root.eval(newFormulaContext(bars))
+}
+
+// EvalContext evaluates the formula reusing a context's cached series
+func (f *Formula) EvalContext(ctx *formulaContext) ([]float64, error) {
+	return f.root.eval(ctx)
+}
+
+// lexFormula splits an expression into tokens
+func lexFormula(source string) ([]string, error) {
+	var tokens []string
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, strings.ToUpper(string(runes[i:j])))
+			i = j
+		case strings.ContainsRune("<>=!&|", r):
+			j := i + 1
+			if j < len(runes) && strings.ContainsRune("=&|", runes[j]) {
+				j++
+			}
+			op := string(runes[i:j])
+			switch op {
+			case "&&":
+				op = "AND"
+			case "||":
+				op = "OR"
+			case "=":
+				op = "=="
+			case "!":
+				op = "NOT"
+			case "<>":
+				op = "!="
+			}
+			tokens = append(tokens, op)
+			i = j
+		case strings.ContainsRune("+-*/(),", r):
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q in formula", r)
+		}
+	}
+	return tokens, nil
+}
+
+// formulaParser is a recursive-descent parser over lexed tokens
+type formulaParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *formulaParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *formulaParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *formulaParser) parseOr() (formulaNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "OR" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseAnd() (formulaNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "AND" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseNot() (formulaNode, error) {
+	if p.peek() == "NOT" {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "NOT", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *formulaParser) parseComparison() (formulaNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op {
+	case ">", "<", ">=", "<=", "==", "!=":
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseAdditive() (formulaNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseMultiplicative() (formulaNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseUnary() (formulaNode, error) {
+	if p.peek() == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// Fold negative literals so they can be used as periods
+		if num, ok := operand.(numberNode); ok {
+			return -num, nil
+		}
+		return unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *formulaParser) parsePrimary() (formulaNode, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of formula")
+	case tok == "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ) in formula")
+		}
+		return node, nil
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		val, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in formula", tok)
+		}
+		return numberNode(val), nil
+	case unicode.IsLetter(rune(tok[0])) || tok[0] == '_':
+		if p.peek() != "(" {
+			return identNode(tok), nil
+		}
+		p.next()
+		call := callNode{name: tok}
+		for p.peek() != ")" {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek() == "," {
+				p.next()
+			} else if p.peek() != ")" {
+				return nil, fmt.Errorf("expected , or ) in call to %s", tok)
+			}
+		}
+		p.next()
+		return call, nil
+	}
+	return nil, fmt.Errorf("unexpected %q in formula", tok)
+}
@@ -90,7 +90,9 @@ const StockAnalysis: React.FC = () => {
       console.log('最新日期:', sortedData[sortedData.length - 1]?.date);
     } catch (err: any) {
       console.error('分析过程中出错:', err);
-      setError(`分析失败: ${err.message || err}`);
+      // Backend errors arrive as { code, message, detail, retryable }
+      const detail = err?.detail ? `（${err.detail}）` : '';
+      setError(`分析失败: ${err?.message || err}${detail}`);
     } finally {
       setLoading(false);
     }
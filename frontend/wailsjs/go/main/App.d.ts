@@ -1,10 +1,392 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AcknowledgeAlert(arg1:string):Promise<void>;
+
+export function AddAlertRule(arg1:string):Promise<string>;
+
+export function AddPortfolioTransaction(arg1:string):Promise<string>;
+
+export function AddWatchlistSymbol(arg1:string,arg2:string):Promise<void>;
+
+export function AnalyzePair(arg1:string):Promise<string>;
+
+export function ArchiveHistory():Promise<string>;
+
+export function BacktestPair(arg1:string):Promise<string>;
+
+export function BackupData():Promise<string>;
+
 export function CalculateFiveDayRate(arg1:string):Promise<string>;
 
+export function CalculatePositionSize(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function CancelJob(arg1:string):Promise<void>;
+
+export function CancelPaperOrder(arg1:string):Promise<void>;
+
+export function ChangePasscode(arg1:string,arg2:string):Promise<void>;
+
+export function CheckAlerts():Promise<string>;
+
+export function CheckForUpdate():Promise<string>;
+
+export function CheckPositionExits():Promise<string>;
+
+export function ClearCache():Promise<number>;
+
+export function ClearFixtures():Promise<void>;
+
+export function ClearRecentSymbols():Promise<void>;
+
+export function CloseSymbolWindow(arg1:string):Promise<void>;
+
+export function CloudSyncNow():Promise<string>;
+
+export function CompareAnalysisSnapshot(arg1:string):Promise<string>;
+
+export function CompareProviders(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<string>;
+
+export function CopyBars(arg1:string,arg2:number):Promise<void>;
+
+export function CopyIndicatorTable(arg1:string,arg2:number,arg3:string):Promise<void>;
+
+export function CopyQuoteSummary(arg1:Array<string>):Promise<string>;
+
+export function CopyTable(arg1:string):Promise<void>;
+
+export function CountFixtures():Promise<number>;
+
+export function CreatePortfolio(arg1:string,arg2:string):Promise<string>;
+
+export function CreateProfile(arg1:string):Promise<string>;
+
+export function CreateWatchlist(arg1:string):Promise<string>;
+
+export function DeleteAlertRule(arg1:string):Promise<void>;
+
+export function DeleteAnalysisSnapshot(arg1:string):Promise<void>;
+
+export function DeleteCrashReport(arg1:string):Promise<void>;
+
+export function DeletePortfolio(arg1:string):Promise<void>;
+
+export function DeletePortfolioTransaction(arg1:string):Promise<void>;
+
+export function DeleteProfile(arg1:string):Promise<void>;
+
+export function DeleteStrategy(arg1:string):Promise<void>;
+
+export function DeleteWatchlist(arg1:string):Promise<void>;
+
+export function DeleteWebhook(arg1:string):Promise<void>;
+
+export function DisableEncryption(arg1:string):Promise<string>;
+
+export function EnableEncryption(arg1:string,arg2:number):Promise<string>;
+
+export function ExecuteCommand(arg1:string,arg2:string):Promise<string>;
+
+export function ExportAnalysisBundle(arg1:string,arg2:number,arg3:string,arg4:string):Promise<string>;
+
+export function ExportDebugReport():Promise<string>;
+
+export function ExportStrategy(arg1:string,arg2:string):Promise<string>;
+
+export function ExportSymbolXLSX(arg1:string,arg2:number):Promise<string>;
+
+export function ExportWatchlist(arg1:string,arg2:string):Promise<string>;
+
+export function FocusSymbolWindow(arg1:string):Promise<void>;
+
+export function GenerateDailySummary():Promise<string>;
+
+export function GenerateReport(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function GetAggregatedValuation(arg1:string):Promise<string>;
+
+export function GetAlertTriggers(arg1:boolean):Promise<string>;
+
+export function GetAnalysisSnapshot(arg1:string):Promise<string>;
+
+export function GetAppStatus():Promise<string>;
+
+export function GetAuditLog(arg1:string,arg2:number,arg3:number):Promise<string>;
+
+export function GetBarsColumnar(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function GetBarsPage(arg1:string,arg2:number,arg3:number,arg4:number):Promise<string>;
+
+export function GetCapturedPayloads():Promise<string>;
+
+export function GetCloudSyncConfig():Promise<string>;
+
+export function GetCloudSyncStatus():Promise<string>;
+
+export function GetDailySummaries():Promise<string>;
+
+export function GetDataQuality(arg1:string,arg2:boolean):Promise<string>;
+
+export function GetDebugMode():Promise<boolean>;
+
+export function GetDividendSummary():Promise<string>;
+
+export function GetDownsampledBars(arg1:string,arg2:number,arg3:number):Promise<string>;
+
+export function GetFXRates():Promise<string>;
+
+export function GetGRPCStatus():Promise<string>;
+
+export function GetHistoryCoverage():Promise<string>;
+
+export function GetHolidayCalendar(arg1:number):Promise<string>;
+
+export function GetIndicatorTable(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function GetIntraday(arg1:string):Promise<string>;
+
+export function GetJob(arg1:string):Promise<string>;
+
+export function GetJobResult(arg1:string):Promise<string>;
+
+export function GetLimitUpStreaks(arg1:string,arg2:number):Promise<string>;
+
+export function GetLocales():Promise<Array<string>>;
+
+export function GetLockStatus():Promise<string>;
+
+export function GetMarketSession():Promise<string>;
+
+export function GetModelScores(arg1:string,arg2:number):Promise<string>;
+
+export function GetNetworkStatus():Promise<string>;
+
+export function GetNewsSentiment(arg1:string):Promise<string>;
+
+export function GetPaperAccount():Promise<string>;
+
+export function GetPortfolio():Promise<string>;
+
+export function GetPortfolioPerformance(arg1:string):Promise<string>;
+
+export function GetPortfolioRiskReport(arg1:string,arg2:number):Promise<string>;
+
+export function GetPortfolioValuation():Promise<string>;
+
+export function GetPositionExitRules():Promise<string>;
+
+export function GetProjection(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function GetProviderCooldowns():Promise<string>;
+
+export function GetProviderUsage():Promise<string>;
+
+export function GetPushStatus():Promise<string>;
+
+export function GetQuote(arg1:string):Promise<string>;
+
+export function GetRangeStats(arg1:string,arg2:number):Promise<string>;
+
+export function GetRebalancePlan(arg1:string,arg2:number,arg3:number):Promise<string>;
+
+export function GetRecentLogs(arg1:string,arg2:number):Promise<string>;
+
+export function GetRecentSymbols(arg1:number,arg2:boolean):Promise<string>;
+
+export function GetSMTPSettings():Promise<string>;
+
+export function GetSchemaStatus():Promise<string>;
+
+export function GetSession():Promise<string>;
+
+export function GetSettings():Promise<string>;
+
 export function GetStockAnalysis():Promise<string>;
 
 export function GetStockData():Promise<string>;
 
+export function GetStockDataWindow(arg1:number):Promise<string>;
+
+export function GetSymbolMetadata(arg1:string):Promise<string>;
+
+export function GetSymbolNote(arg1:string):Promise<string>;
+
+export function GetSyncStatus():Promise<string>;
+
+export function GetTrayState():Promise<string>;
+
+export function GetUpdateStatus():Promise<string>;
+
+export function GetVolatilityCone(arg1:string,arg2:number):Promise<string>;
+
+export function GetVolumePriceDivergence(arg1:string,arg2:number):Promise<string>;
+
+export function GetWatchlistQuotes(arg1:string):Promise<string>;
+
 export function Greet(arg1:string):Promise<string>;
+
+export function HideWindow():Promise<void>;
+
+export function ImportAnalysisBundle(arg1:string):Promise<string>;
+
+export function ImportBarsCSV(arg1:string,arg2:string,arg3:string,arg4:string,arg5:boolean):Promise<string>;
+
+export function ImportPortfolioCSV(arg1:string,arg2:string,arg3:string,arg4:boolean):Promise<string>;
+
+export function ImportStrategy(arg1:string):Promise<string>;
+
+export function ImportWatchlist(arg1:string,arg2:string):Promise<string>;
+
+export function InstallUpdate():Promise<void>;
+
+export function IsMarketOpen():Promise<boolean>;
+
+export function ListAPIKeys():Promise<string>;
+
+export function ListAlertRules():Promise<string>;
+
+export function ListAnalysisSnapshots(arg1:string):Promise<string>;
+
+export function ListCommands():Promise<string>;
+
+export function ListCrashReports():Promise<string>;
+
+export function ListDataQuality():Promise<string>;
+
+export function ListJobs():Promise<string>;
+
+export function ListPortfolios():Promise<string>;
+
+export function ListProfiles():Promise<string>;
+
+export function ListStrategies():Promise<string>;
+
+export function ListSymbolTags():Promise<string>;
+
+export function ListSymbolWindows():Promise<string>;
+
+export function ListWatchlists():Promise<string>;
+
+export function ListWebhooks():Promise<string>;
+
+export function Lock():Promise<void>;
+
+export function MuteAlertRule(arg1:string,arg2:boolean):Promise<void>;
+
+export function OpenSymbolWindow(arg1:string,arg2:string):Promise<string>;
+
+export function PlacePaperOrder(arg1:string,arg2:string,arg3:number,arg4:number):Promise<string>;
+
+export function PushStrategyScreen(arg1:string):Promise<string>;
+
+export function QuitApp():Promise<void>;
+
+export function RecordActivity():Promise<void>;
+
+export function RecordRecentSymbol(arg1:string):Promise<void>;
+
+export function RefreshHolidayCalendar(arg1:number):Promise<string>;
+
+export function RefreshSymbolList():Promise<number>;
+
+export function RefreshSymbolMetadata(arg1:string):Promise<string>;
+
+export function RemoveAPIKey(arg1:string):Promise<void>;
+
+export function RemoveWatchlistSymbol(arg1:string,arg2:string):Promise<void>;
+
+export function RenamePortfolio(arg1:string):Promise<void>;
+
+export function RenameProfile(arg1:string,arg2:string):Promise<void>;
+
+export function RenameWatchlist(arg1:string,arg2:string):Promise<void>;
+
+export function RenderChart(arg1:string,arg2:string):Promise<string>;
+
+export function ReorderWatchlist(arg1:string,arg2:string):Promise<void>;
+
+export function ResetPaperAccount(arg1:number):Promise<void>;
+
+export function ResetProviderCooldown(arg1:string):Promise<void>;
+
+export function ResetSettings():Promise<string>;
+
+export function RestoreData():Promise<string>;
+
+export function RunBacktest(arg1:string,arg2:number,arg3:string):Promise<string>;
+
+export function RunEventStudy(arg1:string):Promise<string>;
+
+export function RunStrategyBacktest(arg1:string,arg2:string):Promise<string>;
+
+export function RunStrategyScreen(arg1:string):Promise<string>;
+
+export function SaveAnalysisSnapshot(arg1:string,arg2:number,arg3:string,arg4:string):Promise<string>;
+
+export function SaveCloudSyncConfig(arg1:string):Promise<string>;
+
+export function SaveSMTPSettings(arg1:string):Promise<void>;
+
+export function SaveSession(arg1:string):Promise<string>;
+
+export function SaveWebhook(arg1:string):Promise<string>;
+
+export function SearchSymbols(arg1:string,arg2:number):Promise<string>;
+
+export function SelectPortfolio(arg1:string):Promise<void>;
+
+export function SendCrashReport(arg1:string):Promise<string>;
+
+export function SendTestEmail():Promise<void>;
+
+export function SetAPIKey(arg1:string,arg2:string):Promise<void>;
+
+export function SetAlertRuleEnabled(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetAutoLock(arg1:number):Promise<string>;
+
+export function SetCommandHotkey(arg1:string,arg2:string):Promise<string>;
+
+export function SetDebugMode(arg1:boolean):Promise<void>;
+
+export function SetFXRate(arg1:string,arg2:number):Promise<void>;
+
+export function SetOfflineMode(arg1:boolean):Promise<void>;
+
+export function SetPortfolioCurrency(arg1:string):Promise<void>;
+
+export function SetPositionExitRules(arg1:string,arg2:string):Promise<void>;
+
+export function SetStartupProfile(arg1:string):Promise<string>;
+
+export function SetSymbolNote(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function ShowWindow():Promise<void>;
+
+export function SnoozeAlertRule(arg1:string,arg2:number):Promise<void>;
+
+export function StreamBars(arg1:string,arg2:number,arg3:number,arg4:string):Promise<string>;
+
+export function SubmitJob(arg1:string,arg2:string):Promise<string>;
+
+export function SyncDividends():Promise<string>;
+
+export function SyncNow():Promise<string>;
+
+export function TestAPIKey(arg1:string):Promise<void>;
+
+export function TestWebhook(arg1:string):Promise<void>;
+
+export function ToggleWindow():Promise<void>;
+
+export function Unlock(arg1:string):Promise<string>;
+
+export function UpdateAnalysisSnapshotNote(arg1:string,arg2:string):Promise<void>;
+
+export function UpdatePortfolioTransaction(arg1:string):Promise<void>;
+
+export function UpdateSettings(arg1:string):Promise<string>;
+
+export function UpdateSymbolWindow(arg1:string):Promise<string>;
+
+export function WatchPositionDrawdowns(arg1:number):Promise<string>;
@@ -2,10 +2,406 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AcknowledgeAlert(arg1) {
+  return window['go']['main']['App']['AcknowledgeAlert'](arg1);
+}
+
+export function AddAlertRule(arg1) {
+  return window['go']['main']['App']['AddAlertRule'](arg1);
+}
+
+export function AddPortfolioTransaction(arg1) {
+  return window['go']['main']['App']['AddPortfolioTransaction'](arg1);
+}
+
+export function AddWatchlistSymbol(arg1, arg2) {
+  return window['go']['main']['App']['AddWatchlistSymbol'](arg1, arg2);
+}
+
+export function AnalyzePair(arg1) {
+  return window['go']['main']['App']['AnalyzePair'](arg1);
+}
+
+export function ArchiveHistory() {
+  return window['go']['main']['App']['ArchiveHistory']();
+}
+
+export function BacktestPair(arg1) {
+  return window['go']['main']['App']['BacktestPair'](arg1);
+}
+
+export function BackupData() {
+  return window['go']['main']['App']['BackupData']();
+}
+
 export function CalculateFiveDayRate(arg1) {
   return window['go']['main']['App']['CalculateFiveDayRate'](arg1);
 }
 
+export function CalculatePositionSize(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CalculatePositionSize'](arg1, arg2, arg3);
+}
+
+export function CancelJob(arg1) {
+  return window['go']['main']['App']['CancelJob'](arg1);
+}
+
+export function CancelPaperOrder(arg1) {
+  return window['go']['main']['App']['CancelPaperOrder'](arg1);
+}
+
+export function ChangePasscode(arg1, arg2) {
+  return window['go']['main']['App']['ChangePasscode'](arg1, arg2);
+}
+
+export function CheckAlerts() {
+  return window['go']['main']['App']['CheckAlerts']();
+}
+
+export function CheckForUpdate() {
+  return window['go']['main']['App']['CheckForUpdate']();
+}
+
+export function CheckPositionExits() {
+  return window['go']['main']['App']['CheckPositionExits']();
+}
+
+export function ClearCache() {
+  return window['go']['main']['App']['ClearCache']();
+}
+
+export function ClearFixtures() {
+  return window['go']['main']['App']['ClearFixtures']();
+}
+
+export function ClearRecentSymbols() {
+  return window['go']['main']['App']['ClearRecentSymbols']();
+}
+
+export function CloseSymbolWindow(arg1) {
+  return window['go']['main']['App']['CloseSymbolWindow'](arg1);
+}
+
+export function CloudSyncNow() {
+  return window['go']['main']['App']['CloudSyncNow']();
+}
+
+export function CompareAnalysisSnapshot(arg1) {
+  return window['go']['main']['App']['CompareAnalysisSnapshot'](arg1);
+}
+
+export function CompareProviders(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CompareProviders'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function CopyBars(arg1, arg2) {
+  return window['go']['main']['App']['CopyBars'](arg1, arg2);
+}
+
+export function CopyIndicatorTable(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CopyIndicatorTable'](arg1, arg2, arg3);
+}
+
+export function CopyQuoteSummary(arg1) {
+  return window['go']['main']['App']['CopyQuoteSummary'](arg1);
+}
+
+export function CopyTable(arg1) {
+  return window['go']['main']['App']['CopyTable'](arg1);
+}
+
+export function CountFixtures() {
+  return window['go']['main']['App']['CountFixtures']();
+}
+
+export function CreatePortfolio(arg1, arg2) {
+  return window['go']['main']['App']['CreatePortfolio'](arg1, arg2);
+}
+
+export function CreateProfile(arg1) {
+  return window['go']['main']['App']['CreateProfile'](arg1);
+}
+
+export function CreateWatchlist(arg1) {
+  return window['go']['main']['App']['CreateWatchlist'](arg1);
+}
+
+export function DeleteAlertRule(arg1) {
+  return window['go']['main']['App']['DeleteAlertRule'](arg1);
+}
+
+export function DeleteAnalysisSnapshot(arg1) {
+  return window['go']['main']['App']['DeleteAnalysisSnapshot'](arg1);
+}
+
+export function DeleteCrashReport(arg1) {
+  return window['go']['main']['App']['DeleteCrashReport'](arg1);
+}
+
+export function DeletePortfolio(arg1) {
+  return window['go']['main']['App']['DeletePortfolio'](arg1);
+}
+
+export function DeletePortfolioTransaction(arg1) {
+  return window['go']['main']['App']['DeletePortfolioTransaction'](arg1);
+}
+
+export function DeleteProfile(arg1) {
+  return window['go']['main']['App']['DeleteProfile'](arg1);
+}
+
+export function DeleteStrategy(arg1) {
+  return window['go']['main']['App']['DeleteStrategy'](arg1);
+}
+
+export function DeleteWatchlist(arg1) {
+  return window['go']['main']['App']['DeleteWatchlist'](arg1);
+}
+
+export function DeleteWebhook(arg1) {
+  return window['go']['main']['App']['DeleteWebhook'](arg1);
+}
+
+export function DisableEncryption(arg1) {
+  return window['go']['main']['App']['DisableEncryption'](arg1);
+}
+
+export function EnableEncryption(arg1, arg2) {
+  return window['go']['main']['App']['EnableEncryption'](arg1, arg2);
+}
+
+export function ExecuteCommand(arg1, arg2) {
+  return window['go']['main']['App']['ExecuteCommand'](arg1, arg2);
+}
+
+export function ExportAnalysisBundle(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ExportAnalysisBundle'](arg1, arg2, arg3, arg4);
+}
+
+export function ExportDebugReport() {
+  return window['go']['main']['App']['ExportDebugReport']();
+}
+
+export function ExportStrategy(arg1, arg2) {
+  return window['go']['main']['App']['ExportStrategy'](arg1, arg2);
+}
+
+export function ExportSymbolXLSX(arg1, arg2) {
+  return window['go']['main']['App']['ExportSymbolXLSX'](arg1, arg2);
+}
+
+export function ExportWatchlist(arg1, arg2) {
+  return window['go']['main']['App']['ExportWatchlist'](arg1, arg2);
+}
+
+export function FocusSymbolWindow(arg1) {
+  return window['go']['main']['App']['FocusSymbolWindow'](arg1);
+}
+
+export function GenerateDailySummary() {
+  return window['go']['main']['App']['GenerateDailySummary']();
+}
+
+export function GenerateReport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GenerateReport'](arg1, arg2, arg3);
+}
+
+export function GetAggregatedValuation(arg1) {
+  return window['go']['main']['App']['GetAggregatedValuation'](arg1);
+}
+
+export function GetAlertTriggers(arg1) {
+  return window['go']['main']['App']['GetAlertTriggers'](arg1);
+}
+
+export function GetAnalysisSnapshot(arg1) {
+  return window['go']['main']['App']['GetAnalysisSnapshot'](arg1);
+}
+
+export function GetAppStatus() {
+  return window['go']['main']['App']['GetAppStatus']();
+}
+
+export function GetAuditLog(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetAuditLog'](arg1, arg2, arg3);
+}
+
+export function GetBarsColumnar(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetBarsColumnar'](arg1, arg2, arg3);
+}
+
+export function GetBarsPage(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetBarsPage'](arg1, arg2, arg3, arg4);
+}
+
+export function GetCapturedPayloads() {
+  return window['go']['main']['App']['GetCapturedPayloads']();
+}
+
+export function GetCloudSyncConfig() {
+  return window['go']['main']['App']['GetCloudSyncConfig']();
+}
+
+export function GetCloudSyncStatus() {
+  return window['go']['main']['App']['GetCloudSyncStatus']();
+}
+
+export function GetDailySummaries() {
+  return window['go']['main']['App']['GetDailySummaries']();
+}
+
+export function GetDataQuality(arg1, arg2) {
+  return window['go']['main']['App']['GetDataQuality'](arg1, arg2);
+}
+
+export function GetDebugMode() {
+  return window['go']['main']['App']['GetDebugMode']();
+}
+
+export function GetDividendSummary() {
+  return window['go']['main']['App']['GetDividendSummary']();
+}
+
+export function GetDownsampledBars(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetDownsampledBars'](arg1, arg2, arg3);
+}
+
+export function GetFXRates() {
+  return window['go']['main']['App']['GetFXRates']();
+}
+
+export function GetGRPCStatus() {
+  return window['go']['main']['App']['GetGRPCStatus']();
+}
+
+export function GetHistoryCoverage() {
+  return window['go']['main']['App']['GetHistoryCoverage']();
+}
+
+export function GetHolidayCalendar(arg1) {
+  return window['go']['main']['App']['GetHolidayCalendar'](arg1);
+}
+
+export function GetIndicatorTable(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetIndicatorTable'](arg1, arg2, arg3);
+}
+
+export function GetIntraday(arg1) {
+  return window['go']['main']['App']['GetIntraday'](arg1);
+}
+
+export function GetJob(arg1) {
+  return window['go']['main']['App']['GetJob'](arg1);
+}
+
+export function GetJobResult(arg1) {
+  return window['go']['main']['App']['GetJobResult'](arg1);
+}
+
+export function GetLimitUpStreaks(arg1, arg2) {
+  return window['go']['main']['App']['GetLimitUpStreaks'](arg1, arg2);
+}
+
+export function GetLocales() {
+  return window['go']['main']['App']['GetLocales']();
+}
+
+export function GetLockStatus() {
+  return window['go']['main']['App']['GetLockStatus']();
+}
+
+export function GetMarketSession() {
+  return window['go']['main']['App']['GetMarketSession']();
+}
+
+export function GetModelScores(arg1, arg2) {
+  return window['go']['main']['App']['GetModelScores'](arg1, arg2);
+}
+
+export function GetNetworkStatus() {
+  return window['go']['main']['App']['GetNetworkStatus']();
+}
+
+export function GetNewsSentiment(arg1) {
+  return window['go']['main']['App']['GetNewsSentiment'](arg1);
+}
+
+export function GetPaperAccount() {
+  return window['go']['main']['App']['GetPaperAccount']();
+}
+
+export function GetPortfolio() {
+  return window['go']['main']['App']['GetPortfolio']();
+}
+
+export function GetPortfolioPerformance(arg1) {
+  return window['go']['main']['App']['GetPortfolioPerformance'](arg1);
+}
+
+export function GetPortfolioRiskReport(arg1, arg2) {
+  return window['go']['main']['App']['GetPortfolioRiskReport'](arg1, arg2);
+}
+
+export function GetPortfolioValuation() {
+  return window['go']['main']['App']['GetPortfolioValuation']();
+}
+
+export function GetPositionExitRules() {
+  return window['go']['main']['App']['GetPositionExitRules']();
+}
+
+export function GetProjection(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetProjection'](arg1, arg2, arg3);
+}
+
+export function GetProviderCooldowns() {
+  return window['go']['main']['App']['GetProviderCooldowns']();
+}
+
+export function GetProviderUsage() {
+  return window['go']['main']['App']['GetProviderUsage']();
+}
+
+export function GetPushStatus() {
+  return window['go']['main']['App']['GetPushStatus']();
+}
+
+export function GetQuote(arg1) {
+  return window['go']['main']['App']['GetQuote'](arg1);
+}
+
+export function GetRangeStats(arg1, arg2) {
+  return window['go']['main']['App']['GetRangeStats'](arg1, arg2);
+}
+
+export function GetRebalancePlan(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetRebalancePlan'](arg1, arg2, arg3);
+}
+
+export function GetRecentLogs(arg1, arg2) {
+  return window['go']['main']['App']['GetRecentLogs'](arg1, arg2);
+}
+
+export function GetRecentSymbols(arg1, arg2) {
+  return window['go']['main']['App']['GetRecentSymbols'](arg1, arg2);
+}
+
+export function GetSMTPSettings() {
+  return window['go']['main']['App']['GetSMTPSettings']();
+}
+
+export function GetSchemaStatus() {
+  return window['go']['main']['App']['GetSchemaStatus']();
+}
+
+export function GetSession() {
+  return window['go']['main']['App']['GetSession']();
+}
+
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
+}
+
 export function GetStockAnalysis() {
   return window['go']['main']['App']['GetStockAnalysis']();
 }
@@ -14,6 +410,374 @@ export function GetStockData() {
   return window['go']['main']['App']['GetStockData']();
 }
 
+export function GetStockDataWindow(arg1) {
+  return window['go']['main']['App']['GetStockDataWindow'](arg1);
+}
+
+export function GetSymbolMetadata(arg1) {
+  return window['go']['main']['App']['GetSymbolMetadata'](arg1);
+}
+
+export function GetSymbolNote(arg1) {
+  return window['go']['main']['App']['GetSymbolNote'](arg1);
+}
+
+export function GetSyncStatus() {
+  return window['go']['main']['App']['GetSyncStatus']();
+}
+
+export function GetTrayState() {
+  return window['go']['main']['App']['GetTrayState']();
+}
+
+export function GetUpdateStatus() {
+  return window['go']['main']['App']['GetUpdateStatus']();
+}
+
+export function GetVolatilityCone(arg1, arg2) {
+  return window['go']['main']['App']['GetVolatilityCone'](arg1, arg2);
+}
+
+export function GetVolumePriceDivergence(arg1, arg2) {
+  return window['go']['main']['App']['GetVolumePriceDivergence'](arg1, arg2);
+}
+
+export function GetWatchlistQuotes(arg1) {
+  return window['go']['main']['App']['GetWatchlistQuotes'](arg1);
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
+
+export function HideWindow() {
+  return window['go']['main']['App']['HideWindow']();
+}
+
+export function ImportAnalysisBundle(arg1) {
+  return window['go']['main']['App']['ImportAnalysisBundle'](arg1);
+}
+
+export function ImportBarsCSV(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ImportBarsCSV'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ImportPortfolioCSV(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ImportPortfolioCSV'](arg1, arg2, arg3, arg4);
+}
+
+export function ImportStrategy(arg1) {
+  return window['go']['main']['App']['ImportStrategy'](arg1);
+}
+
+export function ImportWatchlist(arg1, arg2) {
+  return window['go']['main']['App']['ImportWatchlist'](arg1, arg2);
+}
+
+export function InstallUpdate() {
+  return window['go']['main']['App']['InstallUpdate']();
+}
+
+export function IsMarketOpen() {
+  return window['go']['main']['App']['IsMarketOpen']();
+}
+
+export function ListAPIKeys() {
+  return window['go']['main']['App']['ListAPIKeys']();
+}
+
+export function ListAlertRules() {
+  return window['go']['main']['App']['ListAlertRules']();
+}
+
+export function ListAnalysisSnapshots(arg1) {
+  return window['go']['main']['App']['ListAnalysisSnapshots'](arg1);
+}
+
+export function ListCommands() {
+  return window['go']['main']['App']['ListCommands']();
+}
+
+export function ListCrashReports() {
+  return window['go']['main']['App']['ListCrashReports']();
+}
+
+export function ListDataQuality() {
+  return window['go']['main']['App']['ListDataQuality']();
+}
+
+export function ListJobs() {
+  return window['go']['main']['App']['ListJobs']();
+}
+
+export function ListPortfolios() {
+  return window['go']['main']['App']['ListPortfolios']();
+}
+
+export function ListProfiles() {
+  return window['go']['main']['App']['ListProfiles']();
+}
+
+export function ListStrategies() {
+  return window['go']['main']['App']['ListStrategies']();
+}
+
+export function ListSymbolTags() {
+  return window['go']['main']['App']['ListSymbolTags']();
+}
+
+export function ListSymbolWindows() {
+  return window['go']['main']['App']['ListSymbolWindows']();
+}
+
+export function ListWatchlists() {
+  return window['go']['main']['App']['ListWatchlists']();
+}
+
+export function ListWebhooks() {
+  return window['go']['main']['App']['ListWebhooks']();
+}
+
+export function Lock() {
+  return window['go']['main']['App']['Lock']();
+}
+
+export function MuteAlertRule(arg1, arg2) {
+  return window['go']['main']['App']['MuteAlertRule'](arg1, arg2);
+}
+
+export function OpenSymbolWindow(arg1, arg2) {
+  return window['go']['main']['App']['OpenSymbolWindow'](arg1, arg2);
+}
+
+export function PlacePaperOrder(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['PlacePaperOrder'](arg1, arg2, arg3, arg4);
+}
+
+export function PushStrategyScreen(arg1) {
+  return window['go']['main']['App']['PushStrategyScreen'](arg1);
+}
+
+export function QuitApp() {
+  return window['go']['main']['App']['QuitApp']();
+}
+
+export function RecordActivity() {
+  return window['go']['main']['App']['RecordActivity']();
+}
+
+export function RecordRecentSymbol(arg1) {
+  return window['go']['main']['App']['RecordRecentSymbol'](arg1);
+}
+
+export function RefreshHolidayCalendar(arg1) {
+  return window['go']['main']['App']['RefreshHolidayCalendar'](arg1);
+}
+
+export function RefreshSymbolList() {
+  return window['go']['main']['App']['RefreshSymbolList']();
+}
+
+export function RefreshSymbolMetadata(arg1) {
+  return window['go']['main']['App']['RefreshSymbolMetadata'](arg1);
+}
+
+export function RemoveAPIKey(arg1) {
+  return window['go']['main']['App']['RemoveAPIKey'](arg1);
+}
+
+export function RemoveWatchlistSymbol(arg1, arg2) {
+  return window['go']['main']['App']['RemoveWatchlistSymbol'](arg1, arg2);
+}
+
+export function RenamePortfolio(arg1) {
+  return window['go']['main']['App']['RenamePortfolio'](arg1);
+}
+
+export function RenameProfile(arg1, arg2) {
+  return window['go']['main']['App']['RenameProfile'](arg1, arg2);
+}
+
+export function RenameWatchlist(arg1, arg2) {
+  return window['go']['main']['App']['RenameWatchlist'](arg1, arg2);
+}
+
+export function RenderChart(arg1, arg2) {
+  return window['go']['main']['App']['RenderChart'](arg1, arg2);
+}
+
+export function ReorderWatchlist(arg1, arg2) {
+  return window['go']['main']['App']['ReorderWatchlist'](arg1, arg2);
+}
+
+export function ResetPaperAccount(arg1) {
+  return window['go']['main']['App']['ResetPaperAccount'](arg1);
+}
+
+export function ResetProviderCooldown(arg1) {
+  return window['go']['main']['App']['ResetProviderCooldown'](arg1);
+}
+
+export function ResetSettings() {
+  return window['go']['main']['App']['ResetSettings']();
+}
+
+export function RestoreData() {
+  return window['go']['main']['App']['RestoreData']();
+}
+
+export function RunBacktest(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RunBacktest'](arg1, arg2, arg3);
+}
+
+export function RunEventStudy(arg1) {
+  return window['go']['main']['App']['RunEventStudy'](arg1);
+}
+
+export function RunStrategyBacktest(arg1, arg2) {
+  return window['go']['main']['App']['RunStrategyBacktest'](arg1, arg2);
+}
+
+export function RunStrategyScreen(arg1) {
+  return window['go']['main']['App']['RunStrategyScreen'](arg1);
+}
+
+export function SaveAnalysisSnapshot(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SaveAnalysisSnapshot'](arg1, arg2, arg3, arg4);
+}
+
+export function SaveCloudSyncConfig(arg1) {
+  return window['go']['main']['App']['SaveCloudSyncConfig'](arg1);
+}
+
+export function SaveSMTPSettings(arg1) {
+  return window['go']['main']['App']['SaveSMTPSettings'](arg1);
+}
+
+export function SaveSession(arg1) {
+  return window['go']['main']['App']['SaveSession'](arg1);
+}
+
+export function SaveWebhook(arg1) {
+  return window['go']['main']['App']['SaveWebhook'](arg1);
+}
+
+export function SearchSymbols(arg1, arg2) {
+  return window['go']['main']['App']['SearchSymbols'](arg1, arg2);
+}
+
+export function SelectPortfolio(arg1) {
+  return window['go']['main']['App']['SelectPortfolio'](arg1);
+}
+
+export function SendCrashReport(arg1) {
+  return window['go']['main']['App']['SendCrashReport'](arg1);
+}
+
+export function SendTestEmail() {
+  return window['go']['main']['App']['SendTestEmail']();
+}
+
+export function SetAPIKey(arg1, arg2) {
+  return window['go']['main']['App']['SetAPIKey'](arg1, arg2);
+}
+
+export function SetAlertRuleEnabled(arg1, arg2) {
+  return window['go']['main']['App']['SetAlertRuleEnabled'](arg1, arg2);
+}
+
+export function SetAutoLock(arg1) {
+  return window['go']['main']['App']['SetAutoLock'](arg1);
+}
+
+export function SetCommandHotkey(arg1, arg2) {
+  return window['go']['main']['App']['SetCommandHotkey'](arg1, arg2);
+}
+
+export function SetDebugMode(arg1) {
+  return window['go']['main']['App']['SetDebugMode'](arg1);
+}
+
+export function SetFXRate(arg1, arg2) {
+  return window['go']['main']['App']['SetFXRate'](arg1, arg2);
+}
+
+export function SetOfflineMode(arg1) {
+  return window['go']['main']['App']['SetOfflineMode'](arg1);
+}
+
+export function SetPortfolioCurrency(arg1) {
+  return window['go']['main']['App']['SetPortfolioCurrency'](arg1);
+}
+
+export function SetPositionExitRules(arg1, arg2) {
+  return window['go']['main']['App']['SetPositionExitRules'](arg1, arg2);
+}
+
+export function SetStartupProfile(arg1) {
+  return window['go']['main']['App']['SetStartupProfile'](arg1);
+}
+
+export function SetSymbolNote(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetSymbolNote'](arg1, arg2, arg3);
+}
+
+export function ShowWindow() {
+  return window['go']['main']['App']['ShowWindow']();
+}
+
+export function SnoozeAlertRule(arg1, arg2) {
+  return window['go']['main']['App']['SnoozeAlertRule'](arg1, arg2);
+}
+
+export function StreamBars(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['StreamBars'](arg1, arg2, arg3, arg4);
+}
+
+export function SubmitJob(arg1, arg2) {
+  return window['go']['main']['App']['SubmitJob'](arg1, arg2);
+}
+
+export function SyncDividends() {
+  return window['go']['main']['App']['SyncDividends']();
+}
+
+export function SyncNow() {
+  return window['go']['main']['App']['SyncNow']();
+}
+
+export function TestAPIKey(arg1) {
+  return window['go']['main']['App']['TestAPIKey'](arg1);
+}
+
+export function TestWebhook(arg1) {
+  return window['go']['main']['App']['TestWebhook'](arg1);
+}
+
+export function ToggleWindow() {
+  return window['go']['main']['App']['ToggleWindow']();
+}
+
+export function Unlock(arg1) {
+  return window['go']['main']['App']['Unlock'](arg1);
+}
+
+export function UpdateAnalysisSnapshotNote(arg1, arg2) {
+  return window['go']['main']['App']['UpdateAnalysisSnapshotNote'](arg1, arg2);
+}
+
+export function UpdatePortfolioTransaction(arg1) {
+  return window['go']['main']['App']['UpdatePortfolioTransaction'](arg1);
+}
+
+export function UpdateSettings(arg1) {
+  return window['go']['main']['App']['UpdateSettings'](arg1);
+}
+
+export function UpdateSymbolWindow(arg1) {
+  return window['go']['main']['App']['UpdateSymbolWindow'](arg1);
+}
+
+export function WatchPositionDrawdowns(arg1) {
+  return window['go']['main']['App']['WatchPositionDrawdowns'](arg1);
+}
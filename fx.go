@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// baseCurrency is the currency FX rates are quoted against
+const baseCurrency = "CNY"
+
+// FXRate is the CNY price of one unit of a foreign currency
+type FXRate struct {
+	Currency  string  `json:"currency"`
+	Rate      float64 `json:"rate"`
+	UpdatedAt string  `json:"updatedAt"`
+}
+
+// fxStore keeps the user-maintained exchange rates
+type fxStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	rates  map[string]FXRate
+}
+
+func newFXStore(dataDir string) *fxStore {
+	return &fxStore{path: filepath.Join(dataDir, "fx_rates.json")}
+}
+
+// unload forgets the rates so the next use reads the file again
+func (s *fxStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates, s.loaded = nil, false
+}
+
+// load reads the rates from disk on first use. Callers hold s.mu.
+func (s *fxStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.rates = make(map[string]FXRate)
+	if err := loadJSON(s.path, &s.rates); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// normalizeCurrency upper-cases a currency code, defaulting to CNY
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return baseCurrency
+	}
+	return currency
+}
+
+// convert returns amount in currency from expressed in currency to,
+// going through CNY
+func (s *fxStore) convert(amount float64, from, to string) (float64, error) {
+	from, to = normalizeCurrency(from), normalizeCurrency(to)
+	if from == to {
+		return amount, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return 0, err
+	}
+
+	rate := func(currency string) (float64, error) {
+		if currency == baseCurrency {
+			return 1, nil
+		}
+		r, ok := s.rates[currency]
+		if !ok || r.Rate <= 0 {
+			return 0, codeErrorf(codeNoData, "no exchange rate for %s", currency)
+		}
+		return r.Rate, nil
+	}
+	fromRate, err := rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * fromRate / toRate, nil
+}
+
+// GetFXRates returns the configured exchange rates against CNY
+func (a *App) GetFXRates() (string, error) {
+	a.fx.mu.Lock()
+	defer a.fx.mu.Unlock()
+
+	if err := a.fx.load(); err != nil {
+		return "", err
+	}
+	rates := []FXRate{}
+	for _, r := range a.fx.rates {
+		rates = append(rates, r)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Currency < rates[j].Currency })
+	return toJSON(rates)
+}
+
+// SetFXRate sets how many CNY one unit of currency is worth. A rate of
+// zero removes the currency.
+func (a *App) SetFXRate(currency string, rate float64) error {
+	currency = normalizeCurrency(currency)
+	if currency == baseCurrency {
+		return codeErrorf(codeParse, "%s is the base currency", baseCurrency)
+	}
+	if rate < 0 {
+		return codeErrorf(codeParse, "exchange rate cannot be negative")
+	}
+
+	a.fx.mu.Lock()
+	defer a.fx.mu.Unlock()
+
+	if err := a.fx.load(); err != nil {
+		return err
+	}
+	if rate == 0 {
+		delete(a.fx.rates, currency)
+	} else {
+		a.fx.rates[currency] = FXRate{
+			Currency:  currency,
+			Rate:      rate,
+			UpdatedAt: shanghaiNow().Format(time.RFC3339),
+		}
+	}
+	return saveJSON(a.fx.path, a.fx.rates)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"stock-analysis/internal/indicators"
+	pb "stock-analysis/proto/stockanalysis/v1"
+)
+
+// The gRPC server exposes the data, indicator and screener services of
+// proto/stockanalysis/v1 to other programs on the same machine. Like the
+// push server it only listens on the loopback interface and every call
+// must carry the token of the settings as "authorization: Bearer <token>"
+// metadata. The services call the same code as the bound methods, so
+// offline mode, quotas and the local history apply to them too.
+
+// defaultGRPCPort is where the gRPC server listens unless configured
+const defaultGRPCPort = 8766
+
+// GRPCSettings configure the gRPC server. It only listens on the loopback
+// interface.
+type GRPCSettings struct {
+	Enabled bool   `json:"enabled"`
+	Port    int    `json:"port"`
+	Token   string `json:"token"` // clients pass it as a bearer token; generated when empty
+}
+
+// GRPCStatus describes the gRPC server
+type GRPCStatus struct {
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+	Address string `json:"address,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// grpcHost runs the gRPC server
+type grpcHost struct {
+	mu     sync.Mutex
+	server *grpc.Server
+	addr   string
+	err    error // of the last start
+}
+
+// grpcCodes map error codes to gRPC status codes
+var grpcCodes = map[ErrorCode]codes.Code{
+	codeParse:       codes.InvalidArgument,
+	codeNotFound:    codes.NotFound,
+	codeNoData:      codes.NotFound,
+	codeNetwork:     codes.Unavailable,
+	codeProvider:    codes.Unavailable,
+	codeOffline:     codes.Unavailable,
+	codeRateLimited: codes.ResourceExhausted,
+	codeCanceled:    codes.Canceled,
+	codeBusy:        codes.Aborted,
+	codeLocked:      codes.FailedPrecondition,
+}
+
+// grpcError turns an error of the app into a gRPC status
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, ok := grpcCodes[errorCode(err)]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcAuth rejects calls without the token and maps the errors of the
+// services to gRPC statuses
+func grpcAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var got string
+		if values := md.Get("authorization"); len(values) > 0 {
+			got = strings.TrimPrefix(values[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		resp, err := handler(ctx, req)
+		if err != nil {
+			logger.Warn("grpc call failed", "method", info.FullMethod, "error", err)
+			return nil, grpcError(err)
+		}
+		return resp, nil
+	}
+}
+
+// start listens on the loopback port of settings. Callers hold h.mu.
+func (h *grpcHost) start(a *App, settings GRPCSettings) {
+	addr := fmt.Sprintf("127.0.0.1:%d", settings.Port)
+	ln, err := net.Listen("tcp", addr)
+	h.err = err
+	if err != nil {
+		logger.Warn("failed to start grpc server", "addr", addr, "error", err)
+		return
+	}
+	h.server = grpc.NewServer(grpc.UnaryInterceptor(grpcAuth(settings.Token)))
+	pb.RegisterDataServiceServer(h.server, dataService{app: a})
+	pb.RegisterIndicatorServiceServer(h.server, indicatorService{})
+	pb.RegisterScreenerServiceServer(h.server, screenerService{app: a})
+	h.addr = addr
+	go h.server.Serve(ln)
+	logger.Info("grpc server started", "addr", addr)
+}
+
+// stop closes the server and its connections. Callers hold h.mu.
+func (h *grpcHost) stop() {
+	if h.server == nil {
+		return
+	}
+	h.server.Stop()
+	h.server, h.addr = nil, ""
+	logger.Info("grpc server stopped")
+}
+
+// apply starts, restarts or stops the server to match settings
+func (h *grpcHost) apply(a *App, settings GRPCSettings, running *GRPCSettings) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if settings == *running && (h.server != nil || !settings.Enabled || h.err != nil) {
+		return
+	}
+	h.stop()
+	h.err = nil
+	if settings.Enabled {
+		h.start(a, settings)
+	}
+	*running = settings
+}
+
+// runGRPCServer keeps the gRPC server in line with the settings
+func (a *App) runGRPCServer(ctx context.Context) {
+	var running GRPCSettings
+	check := time.NewTicker(settingsPollInterval)
+	defer check.Stop()
+	for {
+		a.rpc.apply(a, currentSettings().GRPC, &running)
+		select {
+		case <-ctx.Done():
+			a.rpc.mu.Lock()
+			a.rpc.stop()
+			a.rpc.mu.Unlock()
+			return
+		case <-check.C:
+		}
+	}
+}
+
+// GetGRPCStatus returns whether the gRPC server runs and its address
+func (a *App) GetGRPCStatus() (string, error) {
+	settings := currentSettings().GRPC
+	h := a.rpc
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := GRPCStatus{Enabled: settings.Enabled, Running: h.server != nil, Address: h.addr}
+	if h.err != nil {
+		out.Error = h.err.Error()
+	}
+	return toJSON(out)
+}
+
+// grpcBars fetches the bars of symbol over days like the bound methods do
+func grpcBars(ctx context.Context, symbol string, days int32) ([]Bar, error) {
+	if symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol is required")
+	}
+	now := shanghaiNow()
+	return fetchDailyBarsContext(ctx, symbol, lookbackStart(symbol, int(days), now), now)
+}
+
+// dataService serves bars and quotes
+type dataService struct {
+	pb.UnimplementedDataServiceServer
+	app *App
+}
+
+func (s dataService) GetBars(ctx context.Context, req *pb.GetBarsRequest) (*pb.GetBarsResponse, error) {
+	bars, err := grpcBars(ctx, req.GetSymbol(), req.GetDays())
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetBarsResponse{Symbol: req.GetSymbol(), Bars: make([]*pb.Bar, len(bars))}
+	for i, b := range bars {
+		resp.Bars[i] = &pb.Bar{Date: b.Date, Open: b.Open, Close: b.Close, Change: b.Change, ChangePct: b.ChangePct,
+			Low: b.Low, High: b.High, Volume: b.Volume, Turnover: b.Turnover, TurnoverRate: b.TurnoverRate}
+	}
+	return resp, nil
+}
+
+func (s dataService) GetQuote(ctx context.Context, req *pb.GetQuoteRequest) (*pb.Quote, error) {
+	if req.GetSymbol() == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol is required")
+	}
+	q, err := s.app.quote(req.GetSymbol())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Quote{Symbol: q.Symbol, Date: q.Date, Price: q.Price, PrevClose: q.PrevClose, Change: q.Change,
+		ChangePct: q.ChangePct, Volume: q.Volume, Turnover: q.Turnover, StaleAsOf: q.StaleAsOf}, nil
+}
+
+// indicatorService computes indicators over the bars of a symbol
+type indicatorService struct {
+	pb.UnimplementedIndicatorServiceServer
+}
+
+// orDefault returns v, or def when v is not set
+func orDefault(v int32, def int) int {
+	if v > 0 {
+		return int(v)
+	}
+	return def
+}
+
+func (indicatorService) ComputeIndicators(ctx context.Context, req *pb.ComputeIndicatorsRequest) (*pb.ComputeIndicatorsResponse, error) {
+	bars, err := grpcBars(ctx, req.GetSymbol(), req.GetDays())
+	if err != nil {
+		return nil, err
+	}
+	prices := closes(bars)
+	highs, lows := highsLows(bars)
+	resp := &pb.ComputeIndicatorsResponse{Symbol: req.GetSymbol(), Dates: make([]string, len(bars))}
+	for i, b := range bars {
+		resp.Dates[i] = b.Date
+	}
+	add := func(name string, values []float64) {
+		resp.Series = append(resp.Series, &pb.Series{Name: name, Values: values})
+	}
+	for _, spec := range req.GetIndicators() {
+		switch spec.GetKind() {
+		case pb.IndicatorSpec_KIND_SMA:
+			n := orDefault(spec.GetPeriod(), 20)
+			add(fmt.Sprintf("SMA%d", n), indicators.SMA(prices, n))
+		case pb.IndicatorSpec_KIND_EMA:
+			n := orDefault(spec.GetPeriod(), 20)
+			add(fmt.Sprintf("EMA%d", n), indicators.EMA(prices, n))
+		case pb.IndicatorSpec_KIND_STD:
+			n := orDefault(spec.GetPeriod(), 20)
+			add(fmt.Sprintf("STD%d", n), indicators.StdDev(prices, n))
+		case pb.IndicatorSpec_KIND_ATR:
+			n := orDefault(spec.GetPeriod(), 14)
+			add(fmt.Sprintf("ATR%d", n), indicators.ATR(highs, lows, prices, n))
+		case pb.IndicatorSpec_KIND_MACD:
+			dif, dea, hist := indicators.MACD(prices, orDefault(spec.GetFast(), 12), orDefault(spec.GetSlow(), 26), orDefault(spec.GetSignal(), 9))
+			add("DIF", dif)
+			add("DEA", dea)
+			add("MACD", hist)
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unknown indicator kind: %v", spec.GetKind())
+		}
+	}
+	return resp, nil
+}
+
+// screenerService runs saved or ad hoc screens as jobs
+type screenerService struct {
+	pb.UnimplementedScreenerServiceServer
+	app *App
+}
+
+func (s screenerService) Screen(ctx context.Context, req *pb.ScreenRequest) (*pb.ScreenResponse, error) {
+	var (
+		label string
+		fn    jobFunc
+		err   error
+	)
+	switch screen := req.GetScreen().(type) {
+	case *pb.ScreenRequest_StrategyId:
+		label, fn, err = s.app.screenJob(screen.StrategyId)
+	case *pb.ScreenRequest_AdHoc:
+		def := StrategyDefinition{Name: "grpc", Entry: screen.AdHoc.GetEntry(), Universe: screen.AdHoc.GetUniverse(),
+			LookbackDays: int(screen.AdHoc.GetLookbackDays())}
+		if _, err := compileFormula(def.Entry); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		label, fn, err = s.app.definitionScreenJob(def)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "a strategy id or an ad hoc screen is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.app.jobs.run("screen", label, fn)
+	if err != nil {
+		return nil, err
+	}
+	var matches []ScreenMatch
+	if err := json.Unmarshal([]byte(out), &matches); err != nil {
+		return nil, err
+	}
+	resp := &pb.ScreenResponse{Matches: make([]*pb.ScreenMatch, len(matches))}
+	for i, m := range matches {
+		resp.Matches[i] = &pb.ScreenMatch{Symbol: m.Symbol, Name: m.Name, Industry: m.Industry, Date: m.Date, Close: m.Close}
+	}
+	return resp, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"stock-analysis/internal/providers"
+	"stock-analysis/internal/store"
+)
+
+// historyStore is the local bar history of the app. store.History keeps
+// the bars by Sohu code; this adds the symbol keys, the data versions of
+// cached results and the sync against the provider.
+type historyStore struct {
+	*store.History
+}
+
+// localHistory is the bar store fetchDailyBars reads through. NewApp
+// points it at the app data directory.
+var localHistory *historyStore
+
+func newHistoryStore(dataDir string) *historyStore {
+	return &historyStore{store.NewHistory(filepath.Join(dataDir, "history.db"), migrateHistory, logger)}
+}
+
+// save upserts bars for symbol
+func (s *historyStore) save(symbol string, bars []Bar) error {
+	if s == nil || len(bars) == 0 {
+		return nil
+	}
+	if err := s.Save(providers.SohuCode(symbol), bars); err != nil {
+		return err
+	}
+	barVersions.bump(symbol)
+	return nil
+}
+
+// load returns the stored bars of symbol between start and end, oldest
+// first, archived ones included
+func (s *historyStore) load(symbol string, start, end time.Time) ([]Bar, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return s.Load(providers.SohuCode(symbol), start.Format("2006-01-02"), end.Format("2006-01-02"))
+}
+
+// firstDate returns the date of the oldest stored bar of symbol. ok is
+// false if none is stored.
+func (s *historyStore) firstDate(symbol string) (first string, ok bool, err error) {
+	if s == nil {
+		return "", false, nil
+	}
+	return s.FirstDate(providers.SohuCode(symbol))
+}
+
+// completeThrough returns the last date whose session is final as of now:
+// today once the close has settled, otherwise yesterday
+func completeThrough(now time.Time) time.Time {
+	if now.Hour()*60+now.Minute() < summaryHour*60+summaryMinute {
+		return now.AddDate(0, 0, -1)
+	}
+	return now
+}
+
+// syncBars downloads the parts of [start, end] the store does not hold
+// yet and records the new complete range
+func (s *historyStore) syncBars(ctx context.Context, symbol string, start, end time.Time) error {
+	const layout = "2006-01-02"
+	from, to := start.Format(layout), end.Format(layout)
+	first, through, synced, err := s.SyncRange(providers.SohuCode(symbol))
+	if err != nil {
+		return err
+	}
+
+	// Missing head and tail of the requested range. A symbol that was
+	// never synced is one tail segment covering the whole range.
+	type segment struct{ start, end time.Time }
+	var missing []segment
+	if !synced {
+		missing = append(missing, segment{start, end})
+		first, through = from, from
+	} else {
+		if from < first {
+			firstDate, _ := time.ParseInLocation(layout, first, start.Location())
+			missing = append(missing, segment{start, firstDate.AddDate(0, 0, -1)})
+			first = from
+		}
+		if to > through {
+			throughDate, _ := time.ParseInLocation(layout, through, end.Location())
+			missing = append(missing, segment{throughDate.AddDate(0, 0, 1), end})
+		}
+	}
+
+	for _, seg := range missing {
+		bars, err := downloadDailyBars(ctx, symbol, seg.start, seg.end)
+		if err != nil && err != providers.ErrNoBars {
+			return err
+		}
+		if err := s.save(symbol, bars); err != nil {
+			return err
+		}
+	}
+	if !synced {
+		// The first download decides whether the symbol exists at all
+		if stored, err := s.load(symbol, start, end); err == nil && len(stored) == 0 {
+			return providers.ErrNoBars
+		}
+	}
+
+	// Today's bar is only final after the close, so it is fetched again
+	// on the next sync
+	if final := completeThrough(shanghaiNow()).Format(layout); to > final {
+		to = final
+	}
+	if to > through {
+		through = to
+	}
+	return s.SetSyncRange(providers.SohuCode(symbol), first, through)
+}
+
+// fetchDailyBars returns daily bars for symbol between start and end,
+// oldest first. Only the days missing from the local store are downloaded,
+// and the stored history is served when the provider cannot be reached.
+func fetchDailyBars(symbol string, start, end time.Time) ([]Bar, error) {
+	return fetchDailyBarsContext(context.Background(), symbol, start, end)
+}
+
+// fetchDailyBarsContext is fetchDailyBars with downloads that stop when
+// ctx ends
+func fetchDailyBarsContext(ctx context.Context, symbol string, start, end time.Time) ([]Bar, error) {
+	if currentSettings().demoMode() {
+		return demoDailyBars(symbol, start, end)
+	}
+	if localHistory == nil {
+		return downloadDailyBars(ctx, symbol, start, end)
+	}
+	syncErr := localHistory.syncBars(ctx, symbol, start, end)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	bars, err := localHistory.load(symbol, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) == 0 {
+		if syncErr != nil {
+			return nil, syncErr
+		}
+		return nil, providers.ErrNoBars
+	}
+	return bars, nil
+}
+
+// GetHistoryCoverage lists the symbols in the local history store with
+// their stored date ranges
+func (a *App) GetHistoryCoverage() (string, error) {
+	coverage, err := localHistory.Coverage()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(coverage)
+}
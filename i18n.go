@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Locales of the backend message catalogs. The locale setting selects one
+// so errors, reports and notifications match the UI language.
+const (
+	localeZH      = "zh-CN"
+	localeEN      = "en"
+	defaultLocale = localeZH
+)
+
+// catalogs hold the backend-produced strings by locale and message key.
+// Values are fmt formats; every locale has the same keys.
+var catalogs = map[string]map[string]string{
+	localeZH: {
+		"error.network":     "网络连接失败，请检查网络后重试",
+		"error.provider":    "数据源返回错误，请稍后重试",
+		"error.parse":       "数据格式错误，无法解析",
+		"error.rateLimited": "请求过于频繁，请稍后再试",
+		"error.noData":      "所选区间没有数据",
+		"error.offline":     "当前处于离线模式",
+		"error.canceled":    "任务已取消",
+		"error.busy":        "任务正在进行中，请稍候",
+		"error.notFound":    "未找到相应的数据",
+		"error.locked":      "数据已锁定，请输入密码解锁",
+		"error.internal":    "操作失败",
+
+		"report.title":       "%s %s 分析报告",
+		"report.generated":   "生成于 %s",
+		"report.summary":     "概况",
+		"report.sessions":    "交易日",
+		"report.return":      "区间涨幅",
+		"report.firstClose":  "期初收盘",
+		"report.lastClose":   "期末收盘",
+		"report.high":        "最高",
+		"report.low":         "最低",
+		"report.avgVolume":   "平均成交量（手）",
+		"report.volatility":  "年化波动率",
+		"report.maxDrawdown": "最大回撤",
+		"report.chart":       "走势",
+		"report.indicators":  "指标",
+		"report.signals":     "信号",
+		"report.date":        "日期",
+		"report.type":        "类型",
+		"report.message":     "说明",
+		"report.noSignals":   "本区间没有信号。",
+		"report.close":       "收盘",
+		"report.volume5d":    "5日量比 %",
+
+		"xlsx.open":         "开盘",
+		"xlsx.change":       "涨跌额",
+		"xlsx.changePct":    "涨跌幅",
+		"xlsx.volume":       "成交量（手）",
+		"xlsx.turnover":     "成交额（万元）",
+		"xlsx.turnoverRate": "换手率",
+		"xlsx.item":         "项目",
+		"xlsx.value":        "数值",
+		"xlsx.symbol":       "代码",
+		"xlsx.name":         "名称",
+		"xlsx.from":         "起始日",
+		"xlsx.to":           "截止日",
+		"xlsx.exportedAt":   "导出时间",
+		"xlsx.chartTitle":   "%s 收盘价",
+
+		"signal.crossAbove":         "%s 上穿",
+		"signal.crossBelow":         "%s 下穿",
+		"signal.divergence.bearish": "缩量创新高 %d 天，5 日量比 %.1f%%",
+		"signal.divergence.bullish": "缩量创新低 %d 天，5 日量比 %.1f%%",
+
+		"alert.priceAbove": "%s 上穿 %.2f，收于 %.2f",
+		"alert.priceBelow": "%s 下穿 %.2f，收于 %.2f",
+		"alert.volumeRate": "%s 5日量比 %.1f%% 超过 %.1f%%",
+		"alert.macdGolden": "%s MACD 金叉（DIF %.3f，DEA %.3f）",
+		"alert.macdDeath":  "%s MACD 死叉（DIF %.3f，DEA %.3f）",
+		"alert.drawdown":   "%s 自高点回撤 %.1f%%（最高收盘 %.2f，现价 %.2f）",
+		"alert.expression": "%s 满足 %s，价格 %.2f",
+
+		"summary.title":   "每日复盘 %s",
+		"summary.counts":  "%d 只股票，%d 个信号",
+		"summary.gainers": "涨幅居前",
+		"summary.losers":  "跌幅居前",
+		"summary.signals": "信号",
+
+		"message.alert":     "提醒：%s",
+		"message.screen":    "选股：%s",
+		"message.matches":   "%d 只符合条件",
+		"message.test":      "测试消息，发送于 %s",
+		"risk.unclassified": "未分类",
+		"projection.label":  "统计外推：基于历史走势的数学延伸，不是对未来价格的判断",
+
+		"command.refresh":         "刷新",
+		"command.nextSymbol":      "下一只股票",
+		"command.previousSymbol":  "上一只股票",
+		"command.toggleIndicator": "显示/隐藏指标",
+		"command.openScreener":    "打开选股",
+		"command.lock":            "锁定数据",
+	},
+	localeEN: {
+		"error.network":     "Network connection failed. Check the network and try again.",
+		"error.provider":    "The data provider returned an error. Try again later.",
+		"error.parse":       "The data could not be read.",
+		"error.rateLimited": "Too many requests. Try again later.",
+		"error.noData":      "No data in the selected period.",
+		"error.offline":     "Offline mode is on.",
+		"error.canceled":    "The task was canceled.",
+		"error.busy":        "The task is already running. Please wait.",
+		"error.notFound":    "The data was not found.",
+		"error.locked":      "The data is locked. Enter the passcode to unlock it.",
+		"error.internal":    "The operation failed.",
+
+		"report.title":       "%s %s report",
+		"report.generated":   "generated %s",
+		"report.summary":     "Summary",
+		"report.sessions":    "Sessions",
+		"report.return":      "Return",
+		"report.firstClose":  "First close",
+		"report.lastClose":   "Last close",
+		"report.high":        "High",
+		"report.low":         "Low",
+		"report.avgVolume":   "Average volume (lots)",
+		"report.volatility":  "Annualized volatility",
+		"report.maxDrawdown": "Max drawdown",
+		"report.chart":       "Chart",
+		"report.indicators":  "Indicators",
+		"report.signals":     "Signals",
+		"report.date":        "Date",
+		"report.type":        "Type",
+		"report.message":     "Message",
+		"report.noSignals":   "No signals in this period.",
+		"report.close":       "Close",
+		"report.volume5d":    "Volume 5D %",
+
+		"xlsx.open":         "Open",
+		"xlsx.change":       "Change",
+		"xlsx.changePct":    "Change %",
+		"xlsx.volume":       "Volume (lots)",
+		"xlsx.turnover":     "Turnover (10k)",
+		"xlsx.turnoverRate": "Turnover rate",
+		"xlsx.item":         "Item",
+		"xlsx.value":        "Value",
+		"xlsx.symbol":       "Symbol",
+		"xlsx.name":         "Name",
+		"xlsx.from":         "From",
+		"xlsx.to":           "To",
+		"xlsx.exportedAt":   "Exported at",
+		"xlsx.chartTitle":   "%s close",
+
+		"signal.crossAbove":         "%s crossed above",
+		"signal.crossBelow":         "%s crossed below",
+		"signal.divergence.bearish": "new high on contracting volume for %d days, 5-day volume rate %.1f%%",
+		"signal.divergence.bullish": "new low on contracting volume for %d days, 5-day volume rate %.1f%%",
+
+		"alert.priceAbove": "%s crossed above %.2f, closing at %.2f",
+		"alert.priceBelow": "%s crossed below %.2f, closing at %.2f",
+		"alert.volumeRate": "%s 5-day volume rate %.1f%% above %.1f%%",
+		"alert.macdGolden": "%s MACD golden cross (DIF %.3f, DEA %.3f)",
+		"alert.macdDeath":  "%s MACD death cross (DIF %.3f, DEA %.3f)",
+		"alert.drawdown":   "%s down %.1f%% from its peak close of %.2f, now %.2f",
+		"alert.expression": "%s matched %s at %.2f",
+
+		"summary.title":   "Daily summary %s",
+		"summary.counts":  "%d symbols, %d signals",
+		"summary.gainers": "Top gainers",
+		"summary.losers":  "Top losers",
+		"summary.signals": "Signals",
+
+		"message.alert":     "Alert: %s",
+		"message.screen":    "Screen: %s",
+		"message.matches":   "%d matches",
+		"message.test":      "Test message sent at %s",
+		"risk.unclassified": "Unclassified",
+		"projection.label":  "Statistical extrapolation of past prices, not a prediction of where they will go",
+
+		"command.refresh":         "Refresh",
+		"command.nextSymbol":      "Next symbol",
+		"command.previousSymbol":  "Previous symbol",
+		"command.toggleIndicator": "Show or hide indicator",
+		"command.openScreener":    "Open screener",
+		"command.lock":            "Lock data",
+	},
+}
+
+// translate formats the message key of locale with args, falling back to
+// the default locale and then to the key itself
+func translate(locale, key string, args ...interface{}) string {
+	format, ok := catalogs[locale][key]
+	if !ok {
+		if format, ok = catalogs[defaultLocale][key]; !ok {
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// tr formats the message key in the locale of the settings
+func tr(key string, args ...interface{}) string {
+	return translate(currentSettings().Locale, key, args...)
+}
+
+// GetLocales returns the locales the backend has messages for
+func (a *App) GetLocales() []string {
+	out := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		out = append(out, locale)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// CSVMapping tells the importer which column holds each transaction field.
+// Values are header names; the built-in broker formats provide their own.
+type CSVMapping struct {
+	Date       string   `json:"date"`
+	Symbol     string   `json:"symbol"`
+	Type       string   `json:"type"`
+	Shares     string   `json:"shares"`
+	Price      string   `json:"price"`
+	Fees       []string `json:"fees"` // summed, e.g. commission + stamp duty + transfer fee
+	Note       string   `json:"note,omitempty"`
+	BuyValues  []string `json:"buyValues,omitempty"`  // Type column values meaning buy
+	SellValues []string `json:"sellValues,omitempty"` // Type column values meaning sell
+	DateFormat string   `json:"dateFormat,omitempty"` // Go layout, tried before the defaults
+}
+
+// brokerFormats are the export layouts of common A-share broker clients.
+// 同花顺 and 通达信 based clients share the 交割单 column names below.
+var brokerFormats = map[string]CSVMapping{
+	"ths": {
+		Date:       "成交日期",
+		Symbol:     "证券代码",
+		Type:       "操作",
+		Shares:     "成交数量",
+		Price:      "成交均价",
+		Fees:       []string{"手续费", "印花税", "过户费", "其他费"},
+		Note:       "证券名称",
+		BuyValues:  []string{"证券买入", "买入"},
+		SellValues: []string{"证券卖出", "卖出"},
+	},
+	"tdx": {
+		Date:       "成交日期",
+		Symbol:     "证券代码",
+		Type:       "业务名称",
+		Shares:     "成交数量",
+		Price:      "成交价格",
+		Fees:       []string{"佣金", "印花税", "过户费", "其他费用"},
+		Note:       "证券名称",
+		BuyValues:  []string{"证券买入", "买入", "担保品买入"},
+		SellValues: []string{"证券卖出", "卖出", "担保品卖出"},
+	},
+	"generic": {
+		Date:       "date",
+		Symbol:     "symbol",
+		Type:       "type",
+		Shares:     "shares",
+		Price:      "price",
+		Fees:       []string{"fees"},
+		Note:       "note",
+		BuyValues:  []string{"buy", "b"},
+		SellValues: []string{"sell", "s"},
+	},
+}
+
+// ImportRow is the outcome of importing one CSV line
+type ImportRow struct {
+	Line        int         `json:"line"`
+	Transaction Transaction `json:"transaction"`
+	Status      string      `json:"status"` // "new", "duplicate", "skipped" or "error"
+	Message     string      `json:"message,omitempty"`
+}
+
+// ImportResult summarizes an import run
+type ImportResult struct {
+	DryRun     bool        `json:"dryRun"`
+	Rows       []ImportRow `json:"rows"`
+	Imported   int         `json:"imported"`
+	Duplicates int         `json:"duplicates"`
+	Skipped    int         `json:"skipped"`
+	Errors     int         `json:"errors"`
+}
+
+// decodeCSVText converts GBK exports (the default for Chinese broker
+// clients) to UTF-8 and strips a UTF-8 BOM
+func decodeCSVText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// readCSVRecords reads comma- or tab-separated text. Broker clients often
+// export tab-separated "xls" files, so the delimiter is sniffed from the
+// header line.
+func readCSVRecords(text string) ([][]string, error) {
+	header := text
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		header = text[:i]
+	}
+	r := csv.NewReader(strings.NewReader(text))
+	if strings.Count(header, "\t") > strings.Count(header, ",") {
+		r.Comma = '\t'
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var records [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, codeErrorf(codeParse, "failed to read CSV: %v", err)
+		}
+		for i := range rec {
+			// 通达信 wraps codes as ="600519" to keep leading zeros in Excel
+			rec[i] = strings.Trim(strings.TrimSpace(rec[i]), "=\"")
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// parseImportDate accepts the date layouts seen in broker exports
+func parseImportDate(value, layout string) (string, error) {
+	layouts := []string{"2006-01-02", "20060102", "2006/01/02", "2006/1/2", "2006-1-2"}
+	if layout != "" {
+		layouts = append([]string{layout}, layouts...)
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, value); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", codeErrorf(codeParse, "unrecognized date: %s", value)
+}
+
+// parseImportNumber parses numbers that may carry thousands separators
+func parseImportNumber(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	if value == "" || value == "-" || value == "--" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// parseTransactions maps CSV records to transactions using mapping
+func parseTransactions(records [][]string, mapping CSVMapping) ([]ImportRow, error) {
+	if len(records) == 0 {
+		return nil, codeErrorf(codeParse, "CSV is empty")
+	}
+	cols := make(map[string]int)
+	for i, name := range records[0] {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{mapping.Date, mapping.Symbol, mapping.Type, mapping.Shares, mapping.Price} {
+		if _, ok := cols[required]; !ok {
+			return nil, codeErrorf(codeParse, "missing column: %s", required)
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+	matches := func(value string, candidates []string) bool {
+		for _, c := range candidates {
+			if strings.EqualFold(value, c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var rows []ImportRow
+	for n, rec := range records[1:] {
+		row := ImportRow{Line: n + 2}
+		if len(rec) == 0 || (len(rec) == 1 && rec[0] == "") {
+			continue
+		}
+
+		kind := field(rec, mapping.Type)
+		switch {
+		case matches(kind, mapping.BuyValues):
+			row.Transaction.Type = "buy"
+		case matches(kind, mapping.SellValues):
+			row.Transaction.Type = "sell"
+		default:
+			// Bank transfers, interest and the like are not trades
+			row.Status = "skipped"
+			row.Message = fmt.Sprintf("not a trade: %s", kind)
+			rows = append(rows, row)
+			continue
+		}
+
+		var err error
+		tx := &row.Transaction
+		tx.Symbol = field(rec, mapping.Symbol)
+		tx.Note = field(rec, mapping.Note)
+		if tx.Date, err = parseImportDate(field(rec, mapping.Date), mapping.DateFormat); err == nil {
+			if tx.Shares, err = parseImportNumber(field(rec, mapping.Shares)); err == nil {
+				tx.Price, err = parseImportNumber(field(rec, mapping.Price))
+			}
+		}
+		for _, feeCol := range mapping.Fees {
+			if err != nil {
+				break
+			}
+			var fee float64
+			fee, err = parseImportNumber(field(rec, feeCol))
+			tx.Fees += fee
+		}
+		// Some brokers report sell quantities as negative numbers
+		tx.Shares = math.Abs(tx.Shares)
+		if err == nil {
+			err = tx.validate()
+		}
+		if err != nil {
+			row.Status = "error"
+			row.Message = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// transactionKey identifies a trade for duplicate detection
+func transactionKey(tx Transaction) string {
+	return fmt.Sprintf("%s|%s|%s|%.4f|%.4f", tx.Date, tx.Symbol, tx.Type, tx.Shares, tx.Price)
+}
+
+// ImportPortfolioCSV imports broker transactions into the portfolio.
+// format is "ths", "tdx", "generic" or "custom"; for "custom" mappingJSON
+// holds a CSVMapping. With dryRun set nothing is saved and the result is
+// a preview of what would be imported. Rows matching an existing
+// transaction (same date, symbol, side, shares and price) are skipped.
+func (a *App) ImportPortfolioCSV(content string, format string, mappingJSON string, dryRun bool) (_ string, err error) {
+	var result ImportResult
+	defer func() {
+		if !dryRun {
+			recordAudit(auditImport, "portfolio transactions", a.activePortfolio(), fmt.Sprintf("%s: %d new, %d duplicates, %d errors", format, result.Imported, result.Duplicates, result.Errors), err)
+		}
+	}()
+	mapping, ok := brokerFormats[format]
+	if format == "custom" {
+		if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+			return "", codeErrorf(codeParse, "failed to parse CSV mapping: %v", err)
+		}
+		if len(mapping.BuyValues) == 0 {
+			mapping.BuyValues = brokerFormats["generic"].BuyValues
+		}
+		if len(mapping.SellValues) == 0 {
+			mapping.SellValues = brokerFormats["generic"].SellValues
+		}
+	} else if !ok {
+		return "", codeErrorf(codeParse, "unknown import format: %s", format)
+	}
+
+	records, err := readCSVRecords(decodeCSVText([]byte(content)))
+	if err != nil {
+		return "", err
+	}
+	rows, err := parseTransactions(records, mapping)
+	if err != nil {
+		return "", err
+	}
+
+	result = ImportResult{DryRun: dryRun, Rows: rows}
+	err = a.portfolio.update(func(p *Portfolio) error {
+		existing := make(map[string]bool, len(p.Transactions))
+		for _, tx := range p.Transactions {
+			existing[transactionKey(tx)] = true
+		}
+		for i := range result.Rows {
+			row := &result.Rows[i]
+			switch row.Status {
+			case "skipped":
+				result.Skipped++
+				continue
+			case "error":
+				result.Errors++
+				continue
+			}
+			key := transactionKey(row.Transaction)
+			if existing[key] {
+				row.Status = "duplicate"
+				result.Duplicates++
+				continue
+			}
+			existing[key] = true
+			row.Status = "new"
+			tx := row.Transaction
+			tx.ID = newID()
+			p.Transactions = append(p.Transactions, tx)
+			if !dryRun {
+				row.Transaction.ID = tx.ID
+			}
+			result.Imported++
+		}
+		if dryRun {
+			// Validate the combined ledger, then discard the changes
+			if _, err := p.holdings(); err != nil {
+				return err
+			}
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return "", err
+	}
+
+	return toJSON(result)
+}
+
+// errDryRun aborts a portfolio update after validation without saving
+var errDryRun = fmt.Errorf("dry run")
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"time"
+
+	"stock-analysis/internal/indicators"
+)
+
+// closes extracts the close prices of bars
+func closes(bars []Bar) []float64 {
+	out := make([]float64, len(bars))
+	for i, bar := range bars {
+		out[i] = bar.Close
+	}
+	return out
+}
+
+// highsLows extracts the high and low prices of bars
+func highsLows(bars []Bar) (highs, lows []float64) {
+	highs, lows = make([]float64, len(bars)), make([]float64, len(bars))
+	for i, bar := range bars {
+		highs[i], lows[i] = bar.High, bar.Low
+	}
+	return highs, lows
+}
+
+// ATR returns Wilder's average true range of bars over period
+func ATR(bars []Bar, period int) []float64 {
+	highs, lows := highsLows(bars)
+	return indicators.ATR(highs, lows, closes(bars), period)
+}
+
+// indicatorTTL is how long the indicator series of a symbol are kept to
+// be extended when its bars refresh
+const indicatorTTL = 30 * time.Minute
+
+// indicatorCache holds the latest indicatorSet of each symbol
+var indicatorCache = newTTLCache()
+
+// indicatorSet is the standard indicator series over a run of bars, as
+// shown in reports and exports. Every series is as long as bars.
+type indicatorSet struct {
+	bars         []Bar
+	MA5          []float64
+	MA10         []float64
+	MA20         []float64
+	EMA12        []float64
+	EMA26        []float64
+	DIF          []float64
+	DEA          []float64
+	Hist         []float64
+	ATR14        []float64
+	VolumeRate5D []float64
+}
+
+// sharedPrefix returns how many leading bars a and b have in common
+func sharedPrefix(a, b []Bar) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// extend computes the set over bars, recomputing only the bars after
+// those it shares with prev. prev may be nil.
+func (prev *indicatorSet) extend(bars []Bar) *indicatorSet {
+	if prev == nil {
+		prev = &indicatorSet{}
+	}
+	keep := sharedPrefix(prev.bars, bars)
+	prices := closes(bars)
+	highs, lows := highsLows(bars)
+	volumes := make([]float64, len(bars))
+	for i, b := range bars {
+		volumes[i] = b.Volume
+	}
+
+	s := &indicatorSet{
+		bars:         append([]Bar(nil), bars...), // the caller may reuse bars
+		MA5:          indicators.ExtendSMA(prices, prev.MA5, keep, 5),
+		MA10:         indicators.ExtendSMA(prices, prev.MA10, keep, 10),
+		MA20:         indicators.ExtendSMA(prices, prev.MA20, keep, 20),
+		EMA12:        indicators.ExtendEMA(prices, prev.EMA12, keep, 12),
+		EMA26:        indicators.ExtendEMA(prices, prev.EMA26, keep, 26),
+		ATR14:        indicators.ExtendATR(highs, lows, prices, prev.ATR14, keep, 14),
+		VolumeRate5D: indicators.ExtendFiveDayRate(volumes, prev.VolumeRate5D, keep),
+	}
+	// MACD(12, 26, 9) from the two EMAs, as indicators.MACD computes it
+	s.DIF = make([]float64, len(bars))
+	copy(s.DIF, prev.DIF[:min(keep, len(prev.DIF))])
+	for i := min(keep, len(prev.DIF)); i < len(bars); i++ {
+		s.DIF[i] = s.EMA12[i] - s.EMA26[i]
+	}
+	s.DEA = indicators.ExtendEMA(s.DIF, prev.DEA, keep, 9)
+	s.Hist = make([]float64, len(bars))
+	copy(s.Hist, prev.Hist[:min(keep, len(prev.Hist))])
+	for i := min(keep, len(prev.Hist)); i < len(bars); i++ {
+		s.Hist[i] = 2 * (s.DIF[i] - s.DEA[i])
+	}
+	return s
+}
+
+// symbolIndicators returns the indicator set of symbol over bars. When
+// the cached set of symbol starts with the same bars, as after a refresh
+// that appends bars or revises the latest, only the changed tail is
+// recomputed, which keeps live refreshes of long histories fast.
+func symbolIndicators(symbol string, bars []Bar) *indicatorSet {
+	var prev *indicatorSet
+	if v, ok := indicatorCache.get(symbol); ok {
+		prev = v.(*indicatorSet)
+	}
+	s := prev.extend(bars)
+	indicatorCache.set(symbol, s, indicatorTTL)
+	return s
+}
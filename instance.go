@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/options"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// singleInstanceID identifies the app to the single-instance lock. A
+// second launch hands its arguments to the running instance and exits, so
+// only one process ever writes the data directory.
+const singleInstanceID = "com.novooo.stock-analysis"
+
+const eventSecondInstance = "app:secondInstance"
+
+// onSecondInstanceLaunch brings the window forward when the app is
+// launched again and passes the new launch's arguments to the frontend
+func (a *App) onSecondInstanceLaunch(data options.SecondInstanceData) {
+	logger.Info("second instance launched", "args", data.Args)
+	if a.ctx == nil {
+		return
+	}
+	if link := deepLinkArg(data.Args); link != "" {
+		a.openDeepLink(link)
+		return
+	}
+	a.ShowWindow()
+	wailsruntime.EventsEmit(a.ctx, eventSecondInstance, data.Args)
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"Mean", Mean(values), 5},
+		{"Mean of none", Mean(nil), 0},
+		{"Variance", Variance(values), 32.0 / 7},
+		{"StdDev", StdDev(values), math.Sqrt(32.0 / 7)},
+		{"Covariance of one", Covariance([]float64{1}, []float64{1}), 0},
+		{"Covariance", Covariance([]float64{1, 2, 3}, []float64{2, 4, 6}), 2},
+		{"Percentile 0", Percentile(values, 0), 2},
+		{"Percentile 50", Percentile(values, 50), 4.5},
+		{"Percentile 100", Percentile(values, 100), 9},
+		{"Percentile 10", Percentile(values, 10), 2 + 2*0.7},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	returns := SimpleReturns([]float64{10, 11, 0, 5})
+	if len(returns) != 3 || math.Abs(returns[0]-0.1) > 1e-12 || returns[2] != 0 {
+		t.Errorf("SimpleReturns = %v, want [0.1 -1 0]", returns)
+	}
+}
+
+func TestRegress(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n := 500
+	y, x1, x2 := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range y {
+		x1[i], x2[i] = rng.NormFloat64(), rng.NormFloat64()
+		y[i] = 1.5 + 2*x1[i] - 0.5*x2[i] + 0.01*rng.NormFloat64()
+	}
+	coef, stderr, ok := Regress(y, [][]float64{x1, x2})
+	if !ok {
+		t.Fatal("regression failed")
+	}
+	for i, want := range []float64{1.5, 2, -0.5} {
+		if math.Abs(coef[i]-want) > 0.01 {
+			t.Errorf("coefficient %d = %v, want %v", i, coef[i], want)
+		}
+		if stderr[i] <= 0 || stderr[i] > 0.01 {
+			t.Errorf("standard error %d = %v", i, stderr[i])
+		}
+	}
+
+	if _, _, ok := Regress(y, [][]float64{x1, x1}); ok {
+		t.Error("collinear regressors were fitted")
+	}
+	if _, _, ok := Regress(y[:2], [][]float64{x1[:2]}); ok {
+		t.Error("two observations fitted a line with a standard error")
+	}
+}
+
+func TestADF(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	n := 500
+	meanReverting, randomWalk := make([]float64, n), make([]float64, n)
+	for i := 1; i < n; i++ {
+		meanReverting[i] = 0.5*meanReverting[i-1] + rng.NormFloat64()
+		randomWalk[i] = randomWalk[i-1] + rng.NormFloat64()
+	}
+	stationary, ok := ADF(meanReverting, 1)
+	if !ok || stationary > -5 {
+		t.Errorf("ADF of a mean-reverting series = %v, want well below the 1%% critical value", stationary)
+	}
+	walk, ok := ADF(randomWalk, 1)
+	if !ok || walk < -3 {
+		t.Errorf("ADF of a random walk = %v, want above the 5%% critical value", walk)
+	}
+	if _, ok := ADF(randomWalk[:5], 1); ok {
+		t.Error("ADF fitted 5 values")
+	}
+}
+
+func TestLTTB(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[i] = math.Sin(float64(i) / 5)
+	}
+	values[50] = 10 // a spike must survive
+	values[70] = math.NaN()
+	kept := LTTB(values, 20)
+	if len(kept) != 20 || kept[0] != 0 || kept[19] != 99 {
+		t.Fatalf("kept %d points from %v to %v, want 20 from the first to the last", len(kept), kept[0], kept[len(kept)-1])
+	}
+	spike := false
+	for i, k := range kept {
+		if i > 0 && k <= kept[i-1] {
+			t.Errorf("indices out of order: %v", kept)
+		}
+		if math.IsNaN(values[k]) {
+			t.Errorf("kept the NaN at %d", k)
+		}
+		spike = spike || k == 50
+	}
+	if !spike {
+		t.Errorf("the spike at 50 was dropped: %v", kept)
+	}
+	if got := LTTB(values[:10], 20); len(got) != 10 {
+		t.Errorf("a short series kept %d of 10 points", len(got))
+	}
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import "math"
+
+// LTTB picks threshold points of values with the largest-triangle-three-
+// buckets algorithm, keeping the shape of the line at a fraction of its
+// points. Points are evenly spaced; NaN values are never picked. It
+// returns the indices of the kept points in order, or every index if
+// values has no more than threshold points.
+func LTTB(values []float64, threshold int) []int {
+	n := len(values)
+	if threshold >= n || threshold < 3 {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	}
+
+	out := make([]int, 0, threshold)
+	out = append(out, 0)
+	// The first and last points are kept; the rest are split into
+	// threshold-2 buckets
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+	for b := 0; b < threshold-2; b++ {
+		start := int(float64(b)*every) + 1
+		end := int(float64(b+1)*every) + 1
+
+		// Average of the next bucket, the third corner of the triangle
+		nextStart, nextEnd := end, min(int(float64(b+2)*every)+1, n)
+		if b == threshold-3 {
+			nextStart, nextEnd = n-1, n
+		}
+		avgX, avgY, count := 0.0, 0.0, 0
+		for i := nextStart; i < nextEnd; i++ {
+			if !math.IsNaN(values[i]) {
+				avgX += float64(i)
+				avgY += values[i]
+				count++
+			}
+		}
+		if count > 0 {
+			avgX /= float64(count)
+			avgY /= float64(count)
+		} else {
+			avgX, avgY = float64(nextStart), values[a]
+		}
+
+		best, bestArea := -1, -1.0
+		ax, ay := float64(a), values[a]
+		for i := start; i < end; i++ {
+			if math.IsNaN(values[i]) {
+				continue
+			}
+			area := math.Abs((ax-avgX)*(values[i]-ay) - (ax-float64(i))*(avgY-ay))
+			if area > bestArea {
+				best, bestArea = i, area
+			}
+		}
+		if best >= 0 {
+			out = append(out, best)
+			a = best
+		}
+	}
+	return append(out, n-1)
+}
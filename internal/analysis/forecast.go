@@ -0,0 +1,140 @@
+package analysis
+
+import "math"
+
+// Forecast is a forecast of a series: the expected value and its standard
+// error for each step ahead, from 1
+type Forecast struct {
+	Mean   []float64
+	StdErr []float64
+}
+
+// HoltParams are the smoothing parameters of a damped Holt model
+type HoltParams struct {
+	Alpha float64 // level
+	Beta  float64 // trend
+	Phi   float64 // trend damping
+}
+
+// holtSSE runs a damped Holt model over values and returns its sum of
+// squared one-step errors with the final level and trend
+func holtSSE(values []float64, p HoltParams) (sse, level, trend float64) {
+	level, trend = values[0], values[1]-values[0]
+	for _, v := range values[1:] {
+		fit := level + p.Phi*trend
+		e := v - fit
+		sse += e * e
+		prev := level
+		level = fit + p.Alpha*e
+		trend = p.Phi*trend + p.Beta*(level-prev-p.Phi*trend)
+	}
+	return sse, level, trend
+}
+
+// Holt forecasts values horizon steps ahead with Holt's linear exponential
+// smoothing with a damped trend, the non-seasonal form of Holt-Winters.
+// The parameters are chosen by grid search for the smallest one-step
+// error, and the standard errors are those of the equivalent state space
+// model. ok is false with fewer than 10 values.
+func Holt(values []float64, horizon int) (f Forecast, params HoltParams, ok bool) {
+	n := len(values)
+	if n < 10 || horizon < 1 {
+		return f, params, false
+	}
+	best := math.Inf(1)
+	for alpha := 0.05; alpha < 1; alpha += 0.05 {
+		for beta := 0.0; beta <= alpha+1e-9; beta += 0.05 {
+			for _, phi := range []float64{0.8, 0.9, 0.95, 0.98, 1} {
+				p := HoltParams{Alpha: alpha, Beta: beta, Phi: phi}
+				if sse, _, _ := holtSSE(values, p); sse < best {
+					best, params = sse, p
+				}
+			}
+		}
+	}
+	_, level, trend := holtSSE(values, params)
+	sigma2 := best / float64(n-3)
+
+	f.Mean, f.StdErr = make([]float64, horizon), make([]float64, horizon)
+	damp, variance := 0.0, 1.0
+	for h := 1; h <= horizon; h++ {
+		damp += math.Pow(params.Phi, float64(h))
+		f.Mean[h-1] = level + damp*trend
+		f.StdErr[h-1] = math.Sqrt(sigma2 * variance)
+		// The error of the next step adds c_h^2 with c_h = alpha + alpha*beta*(phi+...+phi^h)
+		c := params.Alpha * (1 + params.Beta*damp)
+		variance += c * c
+	}
+	return f, params, true
+}
+
+// ARIMA forecasts values horizon steps ahead with an ARIMA(p,1,0) model
+// with drift: an autoregression of order p on the first differences,
+// fitted by least squares. It returns the forecast with the AR
+// coefficients, the drift first; ok is false when the fit fails.
+func ARIMA(values []float64, p, horizon int) (f Forecast, coef []float64, ok bool) {
+	n := len(values)
+	if p < 0 || horizon < 1 || n < p+12 {
+		return f, nil, false
+	}
+	diff := make([]float64, n-1)
+	for t := 1; t < n; t++ {
+		diff[t-1] = values[t] - values[t-1]
+	}
+	var y []float64
+	xs := make([][]float64, p)
+	for t := p; t < len(diff); t++ {
+		y = append(y, diff[t])
+		for i := 1; i <= p; i++ {
+			xs[i-1] = append(xs[i-1], diff[t-i])
+		}
+	}
+	var sigma2 float64
+	if p == 0 {
+		coef = []float64{Mean(y)}
+		sigma2 = Variance(y)
+	} else {
+		coef, _, ok = Regress(y, xs)
+		if !ok {
+			return f, nil, false
+		}
+		rss := 0.0
+		for t := range y {
+			fit := coef[0]
+			for i := 1; i <= p; i++ {
+				fit += coef[i] * xs[i-1][t]
+			}
+			rss += (y[t] - fit) * (y[t] - fit)
+		}
+		sigma2 = rss / float64(len(y)-p-1)
+	}
+
+	// Forecast the differences recursively and sum them onto the last value
+	history := append([]float64(nil), diff...)
+	// psi are the MA weights of the differences; their running sums weight
+	// the errors of the level
+	psi := make([]float64, horizon)
+	f.Mean, f.StdErr = make([]float64, horizon), make([]float64, horizon)
+	level, cum, variance := values[n-1], 0.0, 0.0
+	for h := 0; h < horizon; h++ {
+		d := coef[0]
+		for i := 1; i <= p; i++ {
+			d += coef[i] * history[len(history)-i]
+		}
+		history = append(history, d)
+		level += d
+		f.Mean[h] = level
+
+		psi[h] = 1
+		if h > 0 {
+			psi[h] = 0
+			for i := 1; i <= p && i <= h; i++ {
+				psi[h] += coef[i] * psi[h-i]
+			}
+		}
+		cum += psi[h]
+		variance += cum * cum
+		f.StdErr[h] = math.Sqrt(sigma2 * variance)
+	}
+	return f, coef, true
+}
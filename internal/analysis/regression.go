@@ -0,0 +1,134 @@
+package analysis
+
+import "math"
+
+// Regress fits y = b0 + b1*x1 + ... + bk*xk by ordinary least squares.
+// xs holds one series per regressor, each as long as y. It returns the
+// coefficients, intercept first, and their standard errors; ok is false
+// when the regressors are collinear or there are too few observations.
+func Regress(y []float64, xs [][]float64) (coef, stderr []float64, ok bool) {
+	n, k := len(y), len(xs)+1
+	if n <= k {
+		return nil, nil, false
+	}
+	row := func(i int) []float64 {
+		r := make([]float64, k)
+		r[0] = 1
+		for j, x := range xs {
+			r[j+1] = x[i]
+		}
+		return r
+	}
+
+	// Normal equations X'X b = X'y
+	xtx := make([][]float64, k)
+	for j := range xtx {
+		xtx[j] = make([]float64, k)
+	}
+	xty := make([]float64, k)
+	for i := 0; i < n; i++ {
+		r := row(i)
+		for a := 0; a < k; a++ {
+			xty[a] += r[a] * y[i]
+			for b := 0; b < k; b++ {
+				xtx[a][b] += r[a] * r[b]
+			}
+		}
+	}
+	inv, ok := invert(xtx)
+	if !ok {
+		return nil, nil, false
+	}
+	coef = make([]float64, k)
+	for a := 0; a < k; a++ {
+		for b := 0; b < k; b++ {
+			coef[a] += inv[a][b] * xty[b]
+		}
+	}
+
+	rss := 0.0
+	for i := 0; i < n; i++ {
+		fit := 0.0
+		for a, v := range row(i) {
+			fit += coef[a] * v
+		}
+		rss += (y[i] - fit) * (y[i] - fit)
+	}
+	sigma2 := rss / float64(n-k)
+	stderr = make([]float64, k)
+	for a := range stderr {
+		stderr[a] = math.Sqrt(sigma2 * inv[a][a])
+	}
+	return coef, stderr, true
+}
+
+// invert returns the inverse of a square matrix by Gauss-Jordan
+// elimination with partial pivoting
+func invert(m [][]float64) ([][]float64, bool) {
+	n := len(m)
+	a := make([][]float64, n)
+	for i := range m {
+		a[i] = make([]float64, 2*n)
+		copy(a[i], m[i])
+		a[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		p := a[col][col]
+		for c := range a[col] {
+			a[col][c] /= p
+		}
+		for r := 0; r < n; r++ {
+			if r == col || a[r][col] == 0 {
+				continue
+			}
+			f := a[r][col]
+			for c := range a[r] {
+				a[r][c] -= f * a[col][c]
+			}
+		}
+	}
+	out := make([][]float64, n)
+	for i := range a {
+		out[i] = a[i][n:]
+	}
+	return out, true
+}
+
+// ADF returns the augmented Dickey-Fuller statistic of values with a
+// constant and lags lagged differences: the t-statistic of gamma in
+// dy_t = a + gamma*y_t-1 + sum(phi_i*dy_t-i). The more negative, the
+// stronger the evidence that values revert to a mean.
+func ADF(values []float64, lags int) (float64, bool) {
+	n := len(values)
+	if lags < 0 || n < lags+10 {
+		return 0, false
+	}
+	diff := make([]float64, n)
+	for t := 1; t < n; t++ {
+		diff[t] = values[t] - values[t-1]
+	}
+	var y []float64
+	xs := make([][]float64, lags+1)
+	for t := lags + 1; t < n; t++ {
+		y = append(y, diff[t])
+		xs[0] = append(xs[0], values[t-1])
+		for i := 1; i <= lags; i++ {
+			xs[i] = append(xs[i], diff[t-i])
+		}
+	}
+	coef, stderr, ok := Regress(y, xs)
+	if !ok || stderr[1] == 0 {
+		return 0, false
+	}
+	return coef[1] / stderr[1], true
+}
@@ -0,0 +1,78 @@
+// Package analysis holds the statistics the reports, risk and backtests
+// are built on
+package analysis
+
+import (
+	"math"
+	"sort"
+)
+
+// TradingDaysPerYear is used to annualize daily statistics
+const TradingDaysPerYear = 252
+
+// Mean returns the arithmetic mean of values
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Covariance returns the sample covariance of two equal-length series
+func Covariance(a, b []float64) float64 {
+	n := len(a)
+	if n < 2 || len(b) != n {
+		return 0
+	}
+	ma, mb := Mean(a), Mean(b)
+	sum := 0.0
+	for i := range a {
+		sum += (a[i] - ma) * (b[i] - mb)
+	}
+	return sum / float64(n-1)
+}
+
+// Variance returns the sample variance of values
+func Variance(values []float64) float64 {
+	return Covariance(values, values)
+}
+
+// StdDev returns the sample standard deviation of values
+func StdDev(values []float64) float64 {
+	return math.Sqrt(Variance(values))
+}
+
+// Percentile returns the p-th percentile (0-100) of values using linear
+// interpolation between closest ranks
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// SimpleReturns returns the period-over-period returns of a price series
+func SimpleReturns(prices []float64) []float64 {
+	if len(prices) < 2 {
+		return nil
+	}
+	out := make([]float64, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		if prices[i-1] != 0 {
+			out[i-1] = prices[i]/prices[i-1] - 1
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package indicators
+
+// The Extend functions continue a series after values changed only at the
+// end, as when new bars arrive or the latest bar is revised. prev is the
+// result of the matching series function over an earlier version of
+// values that agrees with values on its first keep entries; entries from
+// keep on are recomputed and the rest copied, so the result equals the
+// full function over values up to rounding at a fraction of the cost.
+
+// validPrefix clamps keep to the entries of prev still usable for a
+// series over n values
+func validPrefix(prev []float64, keep, n int) int {
+	return max(0, min(keep, len(prev), n))
+}
+
+// ExtendSMA continues SMA over values from entry keep of prev
+func ExtendSMA(values, prev []float64, keep, period int) []float64 {
+	out := NaNSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	keep = validPrefix(prev, keep, len(values))
+	copy(out, prev[:keep])
+	// Running sum of the window ending just before keep, as SMA would
+	// have it at that point
+	sum := 0.0
+	for i := max(0, keep-period); i < keep; i++ {
+		sum += values[i]
+	}
+	for i := keep; i < len(values); i++ {
+		sum += values[i]
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// ExtendEMA continues EMA over values from entry keep of prev
+func ExtendEMA(values, prev []float64, keep, period int) []float64 {
+	keep = validPrefix(prev, keep, len(values))
+	if keep == 0 || period <= 0 {
+		return EMA(values, period)
+	}
+	out := NaNSeries(len(values))
+	copy(out, prev[:keep])
+	k := 2.0 / float64(period+1)
+	for i := keep; i < len(values); i++ {
+		out[i] = values[i]*k + out[i-1]*(1-k)
+	}
+	return out
+}
+
+// ExtendATR continues ATR over high, low and close from entry keep of
+// prev
+func ExtendATR(high, low, close, prev []float64, keep, period int) []float64 {
+	keep = validPrefix(prev, keep, len(close))
+	if keep < period || period <= 0 {
+		return ATR(high, low, close, period)
+	}
+	out := NaNSeries(len(close))
+	copy(out, prev[:keep])
+	// The true range of bar i only looks back at bar i-1
+	from := keep - 1
+	tr := TrueRange(high[from:], low[from:], close[from:])
+	for i := keep; i < len(close); i++ {
+		out[i] = (out[i-1]*float64(period-1) + tr[i-from]) / float64(period)
+	}
+	return out
+}
+
+// ExtendFiveDayRate continues FiveDayRate over values from entry keep of
+// prev
+func ExtendFiveDayRate(values, prev []float64, keep int) []float64 {
+	keep = validPrefix(prev, keep, len(values))
+	out := make([]float64, len(values))
+	copy(out, prev[:keep])
+	for i := max(keep, 5); i < len(values); i++ {
+		if p := values[i-5]; p != 0 {
+			out[i] = (values[i] - p) / p * 100
+		}
+	}
+	return out
+}
@@ -0,0 +1,232 @@
+// Package indicators computes technical indicator series. The series
+// functions take plain float64 series, oldest first, and return a series
+// of the same length; entries without enough history are NaN.
+package indicators
+
+import "math"
+
+// SMA returns the simple moving average of values over period. Entries
+// before the first full window are NaN.
+func SMA(values []float64, period int) []float64 {
+	out := NaNSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// EMA returns the exponential moving average of values over period,
+// seeded with the first value
+func EMA(values []float64, period int) []float64 {
+	out := NaNSeries(len(values))
+	if period <= 0 || len(values) == 0 {
+		return out
+	}
+	k := 2.0 / float64(period+1)
+	out[0] = values[0]
+	for i := 1; i < len(values); i++ {
+		out[i] = values[i]*k + out[i-1]*(1-k)
+	}
+	return out
+}
+
+// TrueRange returns the true range of each bar given its high, low and
+// close
+func TrueRange(high, low, close []float64) []float64 {
+	out := make([]float64, len(close))
+	for i := range close {
+		tr := high[i] - low[i]
+		if i > 0 {
+			prevClose := close[i-1]
+			tr = math.Max(tr, math.Abs(high[i]-prevClose))
+			tr = math.Max(tr, math.Abs(low[i]-prevClose))
+		}
+		out[i] = tr
+	}
+	return out
+}
+
+// ATR returns Wilder's average true range over period
+func ATR(high, low, close []float64, period int) []float64 {
+	tr := TrueRange(high, low, close)
+	out := NaNSeries(len(close))
+	if period <= 0 || len(close) < period {
+		return out
+	}
+	sum := 0.0
+	for i := 0; i < period; i++ {
+		sum += tr[i]
+	}
+	out[period-1] = sum / float64(period)
+	for i := period; i < len(close); i++ {
+		out[i] = (out[i-1]*float64(period-1) + tr[i]) / float64(period)
+	}
+	return out
+}
+
+// Ref shifts values back by n bars, so Ref(x, 1)[i] is x[i-1]
+func Ref(values []float64, n int) []float64 {
+	out := NaNSeries(len(values))
+	for i := n; i < len(values); i++ {
+		out[i] = values[i-n]
+	}
+	return out
+}
+
+// StdDev returns the rolling population standard deviation over period
+func StdDev(values []float64, period int) []float64 {
+	out := NaNSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	mean := SMA(values, period)
+	for i := period - 1; i < len(values); i++ {
+		sum := 0.0
+		for j := i - period + 1; j <= i; j++ {
+			d := values[j] - mean[i]
+			sum += d * d
+		}
+		out[i] = math.Sqrt(sum / float64(period))
+	}
+	return out
+}
+
+// MACD returns the DIF, DEA and MACD histogram series. The histogram is
+// scaled by 2 as is customary in Chinese charting software.
+func MACD(values []float64, fast, slow, signal int) (dif, dea, hist []float64) {
+	fastEMA := EMA(values, fast)
+	slowEMA := EMA(values, slow)
+	dif = make([]float64, len(values))
+	for i := range values {
+		dif[i] = fastEMA[i] - slowEMA[i]
+	}
+	dea = EMA(dif, signal)
+	hist = make([]float64, len(values))
+	for i := range values {
+		hist[i] = 2 * (dif[i] - dea[i])
+	}
+	return dif, dea, hist
+}
+
+// FiveDayRate returns the percentage change of each value against the
+// value five bars earlier, 0 where there is not enough history
+func FiveDayRate(values []float64) []float64 {
+	out := make([]float64, len(values))
+	for i := 5; i < len(values); i++ {
+		if prev := values[i-5]; prev != 0 {
+			out[i] = (values[i] - prev) / prev * 100
+		}
+	}
+	return out
+}
+
+// Amplitude returns the range of each bar against the previous close, in
+// percent, as A-share quotes show 振幅. The first bar, without a previous
+// close, is measured against its open.
+func Amplitude(high, low, open, close []float64) []float64 {
+	out := NaNSeries(len(close))
+	for i := range close {
+		base := open[i]
+		if i > 0 {
+			base = close[i-1]
+		}
+		if base != 0 {
+			out[i] = (high[i] - low[i]) / base * 100
+		}
+	}
+	return out
+}
+
+// RangeExpansion returns 1 where amplitude exceeds factor times its
+// average over the period bars before, 0 elsewhere and NaN without enough
+// history
+func RangeExpansion(amplitude []float64, period int, factor float64) []float64 {
+	out := NaNSeries(len(amplitude))
+	if period <= 0 {
+		return out
+	}
+	sum, count := 0.0, 0
+	for i, v := range amplitude {
+		// sum and count cover the valid entries of the period bars before i
+		if count == period && !math.IsNaN(v) {
+			out[i] = 0
+			if v > factor*sum/float64(period) {
+				out[i] = 1
+			}
+		}
+		if !math.IsNaN(v) {
+			sum += v
+			count++
+		}
+		if i >= period && !math.IsNaN(amplitude[i-period]) {
+			sum -= amplitude[i-period]
+			count--
+		}
+	}
+	return out
+}
+
+// RollingSum returns the sum of values over period
+func RollingSum(values []float64, period int) []float64 {
+	out := NaNSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum
+		}
+	}
+	return out
+}
+
+// RollingExtreme returns the highest or lowest value over period, as
+// picked by pick (math.Max or math.Min)
+func RollingExtreme(values []float64, period int, pick func(a, b float64) float64) []float64 {
+	out := NaNSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	for i := period - 1; i < len(values); i++ {
+		ext := values[i-period+1]
+		for j := i - period + 2; j <= i; j++ {
+			ext = pick(ext, values[j])
+		}
+		out[i] = ext
+	}
+	return out
+}
+
+// LastValid returns the last non-NaN value of a series, or 0
+func LastValid(series []float64) float64 {
+	for i := len(series) - 1; i >= 0; i-- {
+		if !math.IsNaN(series[i]) {
+			return series[i]
+		}
+	}
+	return 0
+}
+
+// NaNSeries returns a series of n NaNs
+func NaNSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// sameSeries reports whether got and want agree to within tol, NaN
+// matching NaN
+func sameSeries(t *testing.T, name string, got, want []float64, tol float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: length %d, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > tol {
+			t.Fatalf("%s: [%d] = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func nan() float64 { return math.NaN() }
+
+func TestSeries(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6}
+	high := []float64{11, 12, 13, 12, 15}
+	low := []float64{9, 10, 11, 10, 12}
+	close := []float64{10, 11, 12, 11, 14}
+	tests := []struct {
+		name      string
+		got, want []float64
+	}{
+		{"SMA 3", SMA(values, 3), []float64{nan(), nan(), 2, 3, 4, 5}},
+		{"SMA 1", SMA(values, 1), values},
+		{"SMA 0", SMA(values, 0), []float64{nan(), nan(), nan(), nan(), nan(), nan()}},
+		{"SMA longer than values", SMA(values[:2], 3), []float64{nan(), nan()}},
+		// k = 2/(3+1) = 0.5, seeded with the first value
+		{"EMA 3", EMA(values, 3), []float64{1, 1.5, 2.25, 3.125, 4.0625, 5.03125}},
+		{"EMA empty", EMA(nil, 3), []float64{}},
+		{"TrueRange", TrueRange(high, low, close), []float64{2, 2, 2, 2, 4}},
+		// Wilder: seed with the mean of the first period, then (prev*2+tr)/3
+		{"ATR 3", ATR(high, low, close, 3), []float64{nan(), nan(), 2, 2, 8.0 / 3}},
+		{"ATR longer than bars", ATR(high[:2], low[:2], close[:2], 3), []float64{nan(), nan()}},
+		{"FiveDayRate", FiveDayRate(values), []float64{0, 0, 0, 0, 0, 500}},
+		{"StdDev 2", StdDev([]float64{1, 3, 3, 7}, 2), []float64{nan(), 1, 0, 2}},
+		{"Ref 2", Ref(values, 2), []float64{nan(), nan(), 1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		sameSeries(t, tt.name, tt.got, tt.want, 1e-12)
+	}
+}
+
+// walk returns a deterministic price walk of n values
+func walk(n int, seed float64) []float64 {
+	out := make([]float64, n)
+	price := 100.0
+	for i := range out {
+		price *= 1 + 0.02*math.Sin(float64(i)*0.7+seed)
+		out[i] = price
+	}
+	return out
+}
+
+func TestExtendMatchesFull(t *testing.T) {
+	const n, period = 60, 14
+	values := walk(n, 0)
+	high, low := make([]float64, n), make([]float64, n)
+	for i, v := range values {
+		high[i], low[i] = v*1.01, v*0.99
+	}
+	// prev is computed over an older version of the series that agrees
+	// on the first keep values and differs after them, and may be shorter
+	older := func(series []float64, keep, length int) []float64 {
+		out := append([]float64(nil), series[:min(keep, length)]...)
+		for i := len(out); i < length; i++ {
+			out = append(out, series[i%len(series)]*1.07)
+		}
+		return out
+	}
+	for _, keep := range []int{0, 1, period - 1, period, period + 1, 30, n - 1, n, n + 5} {
+		for _, length := range []int{keep, n} {
+			length = min(length, n)
+			oldValues := older(values, keep, length)
+			oldHigh, oldLow := older(high, keep, length), older(low, keep, length)
+
+			sameSeries(t, "ExtendSMA", ExtendSMA(values, SMA(oldValues, period), keep, period), SMA(values, period), 1e-9)
+			sameSeries(t, "ExtendEMA", ExtendEMA(values, EMA(oldValues, period), keep, period), EMA(values, period), 1e-9)
+			sameSeries(t, "ExtendATR", ExtendATR(high, low, values, ATR(oldHigh, oldLow, oldValues, period), keep, period),
+				ATR(high, low, values, period), 1e-9)
+			sameSeries(t, "ExtendFiveDayRate", ExtendFiveDayRate(values, FiveDayRate(oldValues), keep), FiveDayRate(values), 1e-9)
+		}
+	}
+}
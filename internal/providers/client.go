@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// Timeout bounds a whole provider request, body included
+const Timeout = 30 * time.Second
+
+// NewTransport returns the connection pool of provider requests. Syncs
+// and screens fan out to the same few hosts, so it keeps more idle
+// connections per host than the default of two and reuses them over
+// HTTP/2 where the provider offers it.
+func NewTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = RouteProxy
+	t.ForceAttemptHTTP2 = true
+	t.MaxIdleConns = 64
+	t.MaxIdleConnsPerHost = 16
+	t.MaxConnsPerHost = 32
+	t.IdleConnTimeout = 90 * time.Second
+	t.TLSHandshakeTimeout = 10 * time.Second
+	t.ResponseHeaderTimeout = 20 * time.Second
+	return t
+}
+
+// proxyURL is the configured proxy, nil for the one named by the
+// environment
+var proxyURL atomic.Pointer[url.URL]
+
+// SetProxy routes later requests through u, or through the proxy of the
+// environment when u is nil
+func SetProxy(u *url.URL) {
+	proxyURL.Store(u)
+}
+
+// RouteProxy picks the proxy of a request. Transports ask it for every
+// request, so SetProxy can switch proxies while requests are in flight.
+func RouteProxy(req *http.Request) (*url.URL, error) {
+	if u := proxyURL.Load(); u != nil {
+		return u, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
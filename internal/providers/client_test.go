@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// TestSetProxyDuringRequests switches the proxy while requests are in
+// flight; run it with go test -race
+func TestSetProxyDuringRequests(t *testing.T) {
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "direct")
+	}))
+	defer direct.Close()
+	// A forward proxy receives the absolute URL and answers for it
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "proxy")
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetProxy(nil)
+
+	transport := NewTransport()
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport, Timeout: Timeout}
+
+	var wg sync.WaitGroup
+	seen := make(chan string, 400)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				resp, err := client.Get(direct.URL)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				seen <- string(body)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				SetProxy(proxyURL)
+			} else {
+				SetProxy(nil)
+			}
+		}
+	}()
+	wg.Wait()
+	close(seen)
+
+	for body := range seen {
+		if body != "direct" && body != "proxy" {
+			t.Fatalf("unexpected response %q", body)
+		}
+	}
+
+	// Once settled, the last proxy set is used
+	SetProxy(proxyURL)
+	resp, err := client.Get(direct.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "proxy" {
+		t.Errorf("got %q through the proxy, want the proxy's answer", body)
+	}
+}
@@ -0,0 +1,31 @@
+package providers
+
+// Quote is the latest known price for a symbol
+type Quote struct {
+	Symbol    string  `json:"symbol"`
+	Date      string  `json:"date"`
+	Price     float64 `json:"price"`
+	PrevClose float64 `json:"prevClose"`
+	Change    float64 `json:"change"`
+	ChangePct float64 `json:"changePct"`
+	Volume    float64 `json:"volume"`
+	Turnover  float64 `json:"turnover"`
+}
+
+// QuoteFromBars builds a quote from the last bar of a chronological series
+func QuoteFromBars(symbol string, bars []Bar) Quote {
+	if len(bars) == 0 {
+		return Quote{Symbol: symbol}
+	}
+	last := bars[len(bars)-1]
+	return Quote{
+		Symbol:    symbol,
+		Date:      last.Date,
+		Price:     last.Close,
+		PrevClose: last.Close - last.Change,
+		Change:    last.Change,
+		ChangePct: last.ChangePct,
+		Volume:    last.Volume,
+		Turnover:  last.Turnover,
+	}
+}
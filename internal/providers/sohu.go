@@ -0,0 +1,167 @@
+// Package providers parses the responses of the market data providers
+// and holds the HTTP transport their requests share. It knows nothing of
+// the app: offline mode, cooldowns and response checks wrap it in main.
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bar is a single daily bar as returned by the Sohu hisHq API
+type Bar struct {
+	Date         string  `json:"date"`
+	Open         float64 `json:"open"`
+	Close        float64 `json:"close"`
+	Change       float64 `json:"change"`
+	ChangePct    float64 `json:"changePct"`
+	Low          float64 `json:"low"`
+	High         float64 `json:"high"`
+	Volume       float64 `json:"volume"`
+	Turnover     float64 `json:"turnover"`
+	TurnoverRate float64 `json:"turnoverRate"`
+}
+
+var (
+	// ErrNoBars is returned when the provider has no bars in the range,
+	// e.g. a range of non-trading days
+	ErrNoBars = errors.New("no hq data available")
+	// ErrMalformed wraps every error reading a response that is not the
+	// expected JSON
+	ErrMalformed = errors.New("failed to parse JSON")
+)
+
+// SohuCode converts a symbol such as "600519" into the Sohu code "cn_600519".
+// Codes that already carry a prefix (cn_, zs_) are passed through unchanged.
+func SohuCode(symbol string) string {
+	symbol = strings.TrimSpace(symbol)
+	if strings.HasPrefix(symbol, "cn_") || strings.HasPrefix(symbol, "zs_") {
+		return symbol
+	}
+	return "cn_" + symbol
+}
+
+// SohuDailyURL returns the hisHq URL of the daily bars of symbol between
+// start and end
+func SohuDailyURL(symbol string, start, end time.Time) string {
+	return fmt.Sprintf("https://q.stock.sohu.com/hisHq?code=%s&start=%s&end=%s&stat=1&order=D&period=d",
+		SohuCode(symbol), start.Format("20060102"), end.Format("20060102"))
+}
+
+// ParseSohuBars parses a hisHq response body into bars, oldest first
+func ParseSohuBars(body []byte) ([]Bar, error) {
+	return DecodeSohuBars(json.NewDecoder(bytes.NewReader(body)))
+}
+
+// DecodeSohuBars reads a hisHq response, an array whose first object
+// holds the rows under "hq", one row at a time and returns the bars
+// oldest first
+func DecodeSohuBars(dec *json.Decoder) ([]Bar, error) {
+	parseErr := func(err error) error {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, parseErr(err)
+	}
+	if !dec.More() {
+		return nil, ErrNoBars
+	}
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, parseErr(err)
+	}
+	var bars []Bar
+	rows := 0
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, parseErr(err)
+		}
+		if key != "hq" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, parseErr(err)
+			}
+			continue
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, parseErr(err)
+		}
+		if tok == nil {
+			continue
+		}
+		if tok != json.Delim('[') {
+			return nil, parseErr(fmt.Errorf("hq is %v, not an array", tok))
+		}
+		for dec.More() {
+			var row []string
+			if err := dec.Decode(&row); err != nil {
+				return nil, parseErr(err)
+			}
+			rows++
+			if len(row) >= 9 {
+				bars = append(bars, sohuBar(row))
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, parseErr(err)
+		}
+	}
+	if rows == 0 {
+		return nil, ErrNoBars
+	}
+	// The API returns rows newest first
+	for i, j := 0, len(bars)-1; i < j; i, j = i+1, j-1 {
+		bars[i], bars[j] = bars[j], bars[i]
+	}
+	return bars, nil
+}
+
+// expectDelim reads the next token of dec and fails unless it is delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, found %v", delim, tok)
+	}
+	return nil
+}
+
+// sohuBar converts a hisHq row of at least nine fields
+func sohuBar(row []string) Bar {
+	bar := Bar{
+		Date:      row[0],
+		Open:      parseSohuNumber(row[1]),
+		Close:     parseSohuNumber(row[2]),
+		Change:    parseSohuNumber(row[3]),
+		ChangePct: parseSohuNumber(row[4]),
+		Low:       parseSohuNumber(row[5]),
+		High:      parseSohuNumber(row[6]),
+		Volume:    parseSohuNumber(row[7]),
+		Turnover:  parseSohuNumber(row[8]),
+	}
+	if len(row) > 9 {
+		bar.TurnoverRate = parseSohuNumber(row[9])
+	}
+	return bar
+}
+
+// parseSohuNumber parses a numeric hisHq field, tolerating "-" and "%" suffixes
+func parseSohuNumber(s string) float64 {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	if s == "" || s == "-" {
+		return 0
+	}
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return val
+}
@@ -0,0 +1,339 @@
+package store
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// Bars of past years rarely change, so they are moved out of the bars
+// table into one compressed block per symbol and year. Load merges the
+// blocks back in and Save unpacks a block before writing into its year,
+// so callers never see the difference.
+
+// ArchiveSchema creates the table of compressed yearly blocks
+const ArchiveSchema = `
+CREATE TABLE IF NOT EXISTS bar_archive (
+	symbol TEXT NOT NULL,
+	year   INTEGER NOT NULL,
+	first  TEXT NOT NULL,
+	last   TEXT NOT NULL,
+	count  INTEGER NOT NULL,
+	data   BLOB NOT NULL, -- encodeBarBlock
+	PRIMARY KEY (symbol, year)
+) WITHOUT ROWID;
+`
+
+// HotYears is how many years, the current one included, stay in the bars
+// table
+const HotYears = 2
+
+// ArchiveResult describes an archive pass
+type ArchiveResult struct {
+	Blocks      int    `json:"blocks"` // symbol-years written
+	Bars        int    `json:"bars"`   // bars moved out of the bars table
+	BytesBefore int64  `json:"bytesBefore"`
+	BytesAfter  int64  `json:"bytesAfter"`
+	ArchivedAt  string `json:"archivedAt"`
+}
+
+// encodeBarBlock packs the bars of one year column by column, the day as
+// MMDD and every number as a float64, and deflates the result. Columns
+// of similar numbers compress far better than rows.
+func encodeBarBlock(bars []providers.Bar) ([]byte, error) {
+	var raw bytes.Buffer
+	binary.Write(&raw, binary.LittleEndian, uint32(len(bars)))
+	for _, b := range bars {
+		if len(b.Date) != 10 {
+			return nil, fmt.Errorf("bad bar date %q", b.Date)
+		}
+		mmdd, err := strconv.Atoi(b.Date[5:7] + b.Date[8:10])
+		if err != nil {
+			return nil, fmt.Errorf("bad bar date %q", b.Date)
+		}
+		binary.Write(&raw, binary.LittleEndian, uint16(mmdd))
+	}
+	fields := []func(providers.Bar) float64{
+		func(b providers.Bar) float64 { return b.Open },
+		func(b providers.Bar) float64 { return b.Close },
+		func(b providers.Bar) float64 { return b.Change },
+		func(b providers.Bar) float64 { return b.ChangePct },
+		func(b providers.Bar) float64 { return b.Low },
+		func(b providers.Bar) float64 { return b.High },
+		func(b providers.Bar) float64 { return b.Volume },
+		func(b providers.Bar) float64 { return b.Turnover },
+		func(b providers.Bar) float64 { return b.TurnoverRate },
+	}
+	for _, field := range fields {
+		for _, b := range bars {
+			binary.Write(&raw, binary.LittleEndian, math.Float64bits(field(b)))
+		}
+	}
+
+	var out bytes.Buffer
+	w, err := flate.NewWriter(&out, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// decodeBarBlock unpacks a block of year written by encodeBarBlock
+func decodeBarBlock(year int, data []byte) ([]providers.Bar, error) {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate bar block: %v", err)
+	}
+	if len(raw) < 4 {
+		return nil, fmt.Errorf("bar block is truncated")
+	}
+	n := int(binary.LittleEndian.Uint32(raw))
+	if len(raw) != 4+n*2+n*9*8 {
+		return nil, fmt.Errorf("bar block is truncated")
+	}
+	bars := make([]providers.Bar, n)
+	pos := 4
+	for i := range bars {
+		mmdd := int(binary.LittleEndian.Uint16(raw[pos:]))
+		bars[i].Date = fmt.Sprintf("%04d-%02d-%02d", year, mmdd/100, mmdd%100)
+		pos += 2
+	}
+	fields := []func(*providers.Bar) *float64{
+		func(b *providers.Bar) *float64 { return &b.Open },
+		func(b *providers.Bar) *float64 { return &b.Close },
+		func(b *providers.Bar) *float64 { return &b.Change },
+		func(b *providers.Bar) *float64 { return &b.ChangePct },
+		func(b *providers.Bar) *float64 { return &b.Low },
+		func(b *providers.Bar) *float64 { return &b.High },
+		func(b *providers.Bar) *float64 { return &b.Volume },
+		func(b *providers.Bar) *float64 { return &b.Turnover },
+		func(b *providers.Bar) *float64 { return &b.TurnoverRate },
+	}
+	for _, field := range fields {
+		for i := range bars {
+			*field(&bars[i]) = math.Float64frombits(binary.LittleEndian.Uint64(raw[pos:]))
+			pos += 8
+		}
+	}
+	return bars, nil
+}
+
+// queryer is what archive reads need of a *sql.DB or *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// archivedBars returns the archived bars of symbol (a Sohu code) between
+// the dates from and to, oldest first
+func archivedBars(q queryer, code, from, to string) ([]providers.Bar, error) {
+	rows, err := q.Query(`SELECT year, data FROM bar_archive
+		WHERE symbol = ? AND last >= ? AND first <= ? ORDER BY year`, code, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []providers.Bar
+	for rows.Next() {
+		var year int
+		var data []byte
+		if err := rows.Scan(&year, &data); err != nil {
+			return nil, err
+		}
+		block, err := decodeBarBlock(year, data)
+		if err != nil {
+			return nil, fmt.Errorf("archived bars of %s in %d: %v", code, year, err)
+		}
+		for _, b := range block {
+			if b.Date >= from && b.Date <= to {
+				out = append(out, b)
+			}
+		}
+	}
+	return out, rows.Err()
+}
+
+// mergeBars combines archived and hot bars by date, oldest first. A hot
+// bar replaces an archived one of the same date.
+func mergeBars(archived, hot []providers.Bar) []providers.Bar {
+	if len(archived) == 0 {
+		return hot
+	}
+	byDate := make(map[string]providers.Bar, len(archived)+len(hot))
+	for _, b := range archived {
+		byDate[b.Date] = b
+	}
+	for _, b := range hot {
+		byDate[b.Date] = b
+	}
+	out := make([]providers.Bar, 0, len(byDate))
+	for _, b := range byDate {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// unarchiveYears moves the archived blocks of code for the years of bars
+// back into the bars table, so bars can be upserted over them
+func unarchiveYears(tx *sql.Tx, code string, bars []providers.Bar) error {
+	years := make(map[int]bool)
+	for _, b := range bars {
+		if year, err := strconv.Atoi(b.Date[:min(4, len(b.Date))]); err == nil {
+			years[year] = true
+		}
+	}
+	for year := range years {
+		var data []byte
+		err := tx.QueryRow(`SELECT data FROM bar_archive WHERE symbol = ? AND year = ?`, code, year).Scan(&data)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		block, err := decodeBarBlock(year, data)
+		if err != nil {
+			return err
+		}
+		for _, b := range block {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO bars
+				(symbol, date, open, close, change, change_pct, low, high, volume, turnover, turnover_rate)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				code, b.Date, b.Open, b.Close, b.Change, b.ChangePct, b.Low, b.High, b.Volume, b.Turnover, b.TurnoverRate); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM bar_archive WHERE symbol = ? AND year = ?`, code, year); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveYear packs the bars of code in year into a block, merged with
+// any block already archived, and removes them from the bars table. It
+// returns how many bars moved.
+func archiveYear(tx *sql.Tx, code string, year int) (int, error) {
+	from, to := fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year)
+	rows, err := tx.Query(`SELECT date, open, close, change, change_pct, low, high, volume, turnover, turnover_rate
+		FROM bars WHERE symbol = ? AND date >= ? AND date <= ? ORDER BY date`, code, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var hot []providers.Bar
+	for rows.Next() {
+		var b providers.Bar
+		if err := rows.Scan(&b.Date, &b.Open, &b.Close, &b.Change, &b.ChangePct, &b.Low, &b.High, &b.Volume, &b.Turnover, &b.TurnoverRate); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		hot = append(hot, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(hot) == 0 {
+		return 0, err
+	}
+	archived, err := archivedBars(tx, code, from, to)
+	if err != nil {
+		return 0, err
+	}
+	bars := mergeBars(archived, hot)
+	data, err := encodeBarBlock(bars)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO bar_archive (symbol, year, first, last, count, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		code, year, bars[0].Date, bars[len(bars)-1].Date, len(bars), data); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM bars WHERE symbol = ? AND date >= ? AND date <= ?`, code, from, to); err != nil {
+		return 0, err
+	}
+	return len(hot), nil
+}
+
+// Archive moves the bars of every year before the hot years into
+// compressed blocks and compacts the database file if anything moved
+func (h *History) Archive(ctx context.Context, now time.Time) (ArchiveResult, error) {
+	db, err := h.Open()
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	// Sizes are compared with the write-ahead log folded in
+	checkpoint := func() {
+		if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+			h.log.Warn("failed to checkpoint history database", "error", err)
+		}
+	}
+	checkpoint()
+	result := ArchiveResult{BytesBefore: h.Size()}
+	cutoff := fmt.Sprintf("%04d-01-01", now.Year()-HotYears+1)
+	rows, err := db.Query(`SELECT DISTINCT symbol, CAST(substr(date, 1, 4) AS INTEGER) FROM bars WHERE date < ?`, cutoff)
+	if err != nil {
+		return result, err
+	}
+	type symbolYear struct {
+		code string
+		year int
+	}
+	var cold []symbolYear
+	for rows.Next() {
+		var sy symbolYear
+		if err := rows.Scan(&sy.code, &sy.year); err != nil {
+			rows.Close()
+			return result, err
+		}
+		cold = append(cold, sy)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, sy := range cold {
+		if ctx.Err() != nil {
+			break
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return result, err
+		}
+		moved, err := archiveYear(tx, sy.code, sy.year)
+		if err != nil {
+			tx.Rollback()
+			return result, fmt.Errorf("failed to archive %s %d: %v", sy.code, sy.year, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return result, err
+		}
+		result.Blocks++
+		result.Bars += moved
+	}
+	if result.Bars > 0 {
+		// Deleted rows only free pages; VACUUM gives them back to the disk
+		if _, err := db.Exec(`VACUUM`); err != nil {
+			h.log.Warn("failed to compact history database", "error", err)
+		}
+		checkpoint()
+	}
+	result.BytesAfter = h.Size()
+	result.ArchivedAt = now.Format(time.RFC3339)
+	return result, ctx.Err()
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// testBars returns n daily bars from date on, skipping no days
+func testBars(date string, n int, base float64) []providers.Bar {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	bars := make([]providers.Bar, n)
+	for i := range bars {
+		price := base + float64(i)*0.37
+		bars[i] = providers.Bar{
+			Date: day.AddDate(0, 0, i).Format("2006-01-02"), Open: price - 0.1, Close: price,
+			Change: 0.37, ChangePct: 0.37 / price * 100, Low: price - 0.5, High: price + 0.5,
+			Volume: 1e5 + float64(i), Turnover: price * 1e5, TurnoverRate: 0.12,
+		}
+	}
+	return bars
+}
+
+func TestBarBlockRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		bars []providers.Bar
+	}{
+		{"empty", []providers.Bar{}},
+		{"one", testBars("2021-03-01", 1, 10)},
+		{"year", testBars("2021-01-01", 365, 1800.5)},
+		{"odd values", []providers.Bar{{Date: "2021-12-31", Open: -1, Close: 1e-9, Volume: 3e15, TurnoverRate: 0}}},
+	}
+	for _, tt := range tests {
+		data, err := encodeBarBlock(tt.bars)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := decodeBarBlock(2021, data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.bars) {
+			t.Errorf("%s: round trip gave %+v, want %+v", tt.name, got, tt.bars)
+		}
+	}
+}
+
+func TestBarBlockErrors(t *testing.T) {
+	if _, err := encodeBarBlock([]providers.Bar{{Date: "2021-1-1"}}); err == nil {
+		t.Error("encoded a bar with a malformed date")
+	}
+	data, err := encodeBarBlock(testBars("2021-01-04", 5, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range [][]byte{nil, data[:len(data)/2], []byte("not deflate")} {
+		if _, err := decodeBarBlock(2021, bad); err == nil {
+			t.Errorf("decoded a damaged block of %d bytes", len(bad))
+		}
+	}
+}
+
+func TestMergeBars(t *testing.T) {
+	bar := func(date string, close float64) providers.Bar { return providers.Bar{Date: date, Close: close} }
+	tests := []struct {
+		name          string
+		archived, hot []providers.Bar
+		want          []providers.Bar
+	}{
+		{"nothing archived", nil, []providers.Bar{bar("2021-01-05", 1)}, []providers.Bar{bar("2021-01-05", 1)}},
+		{"nothing hot", []providers.Bar{bar("2021-01-05", 1)}, nil, []providers.Bar{bar("2021-01-05", 1)}},
+		{
+			"interleaved",
+			[]providers.Bar{bar("2021-01-04", 1), bar("2021-01-06", 3)},
+			[]providers.Bar{bar("2021-01-05", 2), bar("2021-01-07", 4)},
+			[]providers.Bar{bar("2021-01-04", 1), bar("2021-01-05", 2), bar("2021-01-06", 3), bar("2021-01-07", 4)},
+		},
+		{
+			"hot replaces archived",
+			[]providers.Bar{bar("2021-01-04", 1), bar("2021-01-05", 2)},
+			[]providers.Bar{bar("2021-01-05", 9)},
+			[]providers.Bar{bar("2021-01-04", 1), bar("2021-01-05", 9)},
+		},
+	}
+	for _, tt := range tests {
+		if got := mergeBars(tt.archived, tt.hot); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newTestHistory returns a history store over a temporary database
+func newTestHistory(t *testing.T) *History {
+	t.Helper()
+	migrate := func(db *sql.DB) error {
+		_, err := db.Exec(HistorySchema + ArchiveSchema)
+		return err
+	}
+	h := NewHistory(filepath.Join(t.TempDir(), "history.db"), migrate, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func TestHistoryAcrossArchivedYear(t *testing.T) {
+	h := newTestHistory(t)
+	old, recent := testBars("2022-12-20", 20, 10), testBars("2025-06-02", 5, 20)
+	if err := h.Save("cn_600519", append(append([]providers.Bar(nil), old...), recent...)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := h.Archive(context.Background(), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2022 and the 2023 part of the old bars are cold; 2025 is hot
+	if result.Blocks != 2 || result.Bars != len(old) {
+		t.Errorf("archived %d blocks of %d bars, want 2 of %d", result.Blocks, result.Bars, len(old))
+	}
+
+	got, err := h.Load("cn_600519", "2022-01-01", "2025-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]providers.Bar(nil), old...), recent...)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("loaded %d bars across the archive, want %d unchanged", len(got), len(want))
+	}
+	if got, err := h.Load("cn_600519", "2022-12-30", "2023-01-02"); err != nil || len(got) != 4 {
+		t.Errorf("a range inside the archive loaded %d bars (%v), want 4", len(got), err)
+	}
+	if first, ok, err := h.FirstDate("cn_600519"); err != nil || !ok || first != "2022-12-20" {
+		t.Errorf("first date = %s %v %v, want 2022-12-20 from the archive", first, ok, err)
+	}
+
+	// Revising an archived bar unpacks its year and keeps the rest
+	revised := old[3]
+	revised.Close = 99
+	if err := h.Save("cn_600519", []providers.Bar{revised}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = h.Load("cn_600519", "2022-01-01", "2025-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want[3] = revised
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("after revising %s, loaded %d bars that differ from the %d expected", revised.Date, len(got), len(want))
+	}
+
+	coverage, err := h.Coverage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coverage) != 1 || coverage[0].Bars != len(want) || coverage[0].First != "2022-12-20" || coverage[0].Last != "2025-06-06" {
+		t.Errorf("coverage = %+v", coverage)
+	}
+}
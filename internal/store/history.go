@@ -0,0 +1,246 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"stock-analysis/internal/providers"
+)
+
+// HistorySchema creates the daily bar table. Bars are keyed by the Sohu
+// code so "600519" and "cn_600519" share rows. It is the first history
+// migration; later schema changes are migrations of their own.
+const HistorySchema = `
+CREATE TABLE IF NOT EXISTS bars (
+	symbol        TEXT NOT NULL,
+	date          TEXT NOT NULL,
+	open          REAL NOT NULL,
+	close         REAL NOT NULL,
+	change        REAL NOT NULL,
+	change_pct    REAL NOT NULL,
+	low           REAL NOT NULL,
+	high          REAL NOT NULL,
+	volume        REAL NOT NULL,
+	turnover      REAL NOT NULL,
+	turnover_rate REAL NOT NULL,
+	PRIMARY KEY (symbol, date)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS sync_state (
+	symbol  TEXT PRIMARY KEY,
+	first   TEXT NOT NULL, -- earliest date requested from the provider
+	through TEXT NOT NULL  -- every session up to this date is stored
+);
+`
+
+// History persists downloaded daily bars in SQLite so history survives
+// restarts and accumulates beyond a single download window. Bars are
+// keyed by the Sohu code of their symbol, see providers.SohuCode.
+type History struct {
+	mu      sync.Mutex
+	path    string
+	db      *sql.DB
+	migrate func(*sql.DB) error
+	log     *slog.Logger
+}
+
+// NewHistory returns the store of the database at path. migrate brings
+// the database up to date when it is first opened.
+func NewHistory(path string, migrate func(*sql.DB) error, log *slog.Logger) *History {
+	return &History{path: path, migrate: migrate, log: log}
+}
+
+// Open connects to the database on first use
+func (h *History) Open() (*sql.DB, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.db != nil {
+		return h.db, nil
+	}
+	db, err := sql.Open("sqlite3", h.path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if err := h.migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate history database: %v", err)
+	}
+	h.db = db
+	return db, nil
+}
+
+// Size returns the bytes of the database file and its write-ahead log
+func (h *History) Size() int64 {
+	var n int64
+	for _, suffix := range []string{"", "-wal"} {
+		if info, err := os.Stat(h.path + suffix); err == nil {
+			n += info.Size()
+		}
+	}
+	return n
+}
+
+// Close releases the database connection. The next use opens it again.
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.db == nil {
+		return nil
+	}
+	err := h.db.Close()
+	h.db = nil
+	return err
+}
+
+// Save upserts bars under code
+func (h *History) Save(code string, bars []providers.Bar) error {
+	db, err := h.Open()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO bars
+		(symbol, date, open, close, change, change_pct, low, high, volume, turnover, turnover_rate)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if err := unarchiveYears(tx, code, bars); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, b := range bars {
+		if _, err := stmt.Exec(code, b.Date, b.Open, b.Close, b.Change, b.ChangePct, b.Low, b.High, b.Volume, b.Turnover, b.TurnoverRate); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Load returns the stored bars of code between the dates from and to,
+// oldest first, archived ones included
+func (h *History) Load(code, from, to string) ([]providers.Bar, error) {
+	db, err := h.Open()
+	if err != nil {
+		return nil, err
+	}
+	// One transaction reads both tables as of the same moment, so an
+	// archive pass cannot move bars between the two reads
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`SELECT date, open, close, change, change_pct, low, high, volume, turnover, turnover_rate
+		FROM bars WHERE symbol = ? AND date >= ? AND date <= ? ORDER BY date`, code, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bars []providers.Bar
+	for rows.Next() {
+		var b providers.Bar
+		if err := rows.Scan(&b.Date, &b.Open, &b.Close, &b.Change, &b.ChangePct, &b.Low, &b.High, &b.Volume, &b.Turnover, &b.TurnoverRate); err != nil {
+			return nil, err
+		}
+		bars = append(bars, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	archived, err := archivedBars(tx, code, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return mergeBars(archived, bars), nil
+}
+
+// FirstDate returns the date of the oldest stored bar of code. ok is
+// false if none is stored.
+func (h *History) FirstDate(code string) (first string, ok bool, err error) {
+	db, err := h.Open()
+	if err != nil {
+		return "", false, err
+	}
+	var date sql.NullString
+	if err := db.QueryRow(`SELECT MIN(first) FROM (
+		SELECT MIN(date) AS first FROM bars WHERE symbol = ?1
+		UNION ALL SELECT MIN(first) FROM bar_archive WHERE symbol = ?1)`, code).Scan(&date); err != nil {
+		return "", false, err
+	}
+	return date.String, date.Valid, nil
+}
+
+// SyncRange returns the dates the store holds complete history of code
+// for. ok is false if the code was never synced.
+func (h *History) SyncRange(code string) (first, through string, ok bool, err error) {
+	db, err := h.Open()
+	if err != nil {
+		return "", "", false, err
+	}
+	err = db.QueryRow(`SELECT first, through FROM sync_state WHERE symbol = ?`, code).Scan(&first, &through)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	return first, through, err == nil, err
+}
+
+// SetSyncRange records the dates the store holds complete history of
+// code for
+func (h *History) SetSyncRange(code, first, through string) error {
+	db, err := h.Open()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT OR REPLACE INTO sync_state (symbol, first, through) VALUES (?, ?, ?)`, code, first, through)
+	return err
+}
+
+// Coverage describes the stored bars of one symbol
+type Coverage struct {
+	Symbol string `json:"symbol"`
+	First  string `json:"first"`
+	Last   string `json:"last"`
+	Bars   int    `json:"bars"`
+}
+
+// Coverage returns the stored date range of every symbol
+func (h *History) Coverage() ([]Coverage, error) {
+	db, err := h.Open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT symbol, MIN(first), MAX(last), SUM(count) FROM (
+		SELECT symbol, MIN(date) AS first, MAX(date) AS last, COUNT(*) AS count FROM bars GROUP BY symbol
+		UNION ALL SELECT symbol, first, last, count FROM bar_archive)
+		GROUP BY symbol ORDER BY symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Coverage{}
+	for rows.Next() {
+		var c Coverage
+		if err := rows.Scan(&c.Symbol, &c.First, &c.Last, &c.Bars); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,62 @@
+// Package store persists the app's local state: JSON files written
+// atomically and the SQLite history of daily bars with its compressed
+// archive of past years
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Codec transforms file contents on their way to and from the disk, e.g.
+// to encrypt them
+type Codec interface {
+	Seal(path string, data []byte) ([]byte, error)
+	Unseal(data []byte) ([]byte, error)
+}
+
+// LoadJSON reads the JSON file at path into v through codec. A missing
+// file is not an error and leaves v untouched.
+func LoadJSON(path string, v interface{}, codec Codec) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if data, err = codec.Unseal(data); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// SaveJSON writes v to path through codec, going through a temp file so
+// a crash never leaves a half-written file behind
+func SaveJSON(path string, v interface{}, codec Codec) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if data, err = codec.Seal(path, data); err != nil {
+		return err
+	}
+	return WriteFile(path, data)
+}
+
+// WriteFile writes data to path through a temp file
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
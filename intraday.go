@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The intraday view plots each minute's last price against two running
+// averages. The average-price line (分时均线) that A-share charting apps
+// draw is the day's turnover over its volume so far, the price the
+// average share changed hands at. VWAP weighs each minute's typical
+// price, (high + low + close) / 3, by its volume instead, as it is
+// defined for bars. The two track closely and differ most on minutes
+// with a wide range.
+
+// sharesPerLot converts the minute volume, in lots of 100 shares, to
+// shares. STAR Market orders start at 200 shares but volume is still
+// counted in lots of 100.
+const sharesPerLot = 100
+
+// MinuteBar is one minute of trading
+type MinuteBar struct {
+	Time   string  `json:"time"` // "2006-01-02 15:04"
+	Open   float64 `json:"open"`
+	Close  float64 `json:"close"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Volume float64 `json:"volume"` // shares
+	Amount float64 `json:"amount"` // turnover, yuan
+}
+
+// IntradaySeries is the current trading day minute by minute, one array
+// per field, each as long as Times
+type IntradaySeries struct {
+	Symbol    string    `json:"symbol"`
+	Date      string    `json:"date"`
+	PrevClose float64   `json:"prevClose"`
+	Session   string    `json:"session"` // market phase now; "closed" once the day is complete
+	Times     []string  `json:"times"`   // "15:04"
+	Price     []float64 `json:"price"`
+	AvgPrice  []float64 `json:"avgPrice"` // running turnover over volume
+	VWAP      []float64 `json:"vwap"`     // running volume-weighted typical price
+	Volume    []float64 `json:"volume"`
+	Amount    []float64 `json:"amount"`
+}
+
+// fetchMinuteBars downloads the minute bars of the latest trading day of
+// symbol with the previous close
+func fetchMinuteBars(symbol string) ([]MinuteBar, float64, error) {
+	if !connectivity.allow() {
+		return nil, 0, errOffline
+	}
+	if err := cooldowns.check(providerEastmoney); err != nil {
+		return nil, 0, err
+	}
+	started := time.Now()
+	resp, err := providerClient.Get("https://push2his.eastmoney.com/api/qt/stock/trends2/get?fields1=f1,f2,f3,f4,f5,f6,f7,f8&fields2=f51,f52,f53,f54,f55,f56,f57,f58&iscr=0&ndays=1&secid=" + eastmoneySecID(symbol))
+	connectivity.report(err)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	logProviderResponse(resp, body, started)
+	if err := checkProviderResponse(providerEastmoney, resp, body); err != nil {
+		return nil, 0, err
+	}
+
+	var payload struct {
+		Data *struct {
+			PrevClose float64  `json:"preClose"`
+			Trends    []string `json:"trends"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, 0, codeErrorf(codeParse, "failed to parse JSON: %v", err)
+	}
+	if payload.Data == nil || len(payload.Data.Trends) == 0 {
+		return nil, 0, codeErrorf(codeNoData, "no minute data for %s", symbol)
+	}
+	bars := make([]MinuteBar, 0, len(payload.Data.Trends))
+	for _, row := range payload.Data.Trends {
+		// time,open,close,high,low,volume,amount,average
+		fields := strings.Split(row, ",")
+		if len(fields) < 7 {
+			return nil, 0, codeErrorf(codeParse, "invalid minute bar: %s", row)
+		}
+		var v [6]float64
+		for i := range v {
+			if v[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+				return nil, 0, codeErrorf(codeParse, "invalid minute bar: %s", row)
+			}
+		}
+		bars = append(bars, MinuteBar{Time: fields[0], Open: v[0], Close: v[1], High: v[2], Low: v[3], Volume: v[4] * sharesPerLot, Amount: v[5]})
+	}
+	return bars, payload.Data.PrevClose, nil
+}
+
+// intradaySeries computes the running averages of a day of minute bars.
+// Before the first trade both averages hold the first price.
+func intradaySeries(symbol string, bars []MinuteBar, prevClose float64, now time.Time) IntradaySeries {
+	s := IntradaySeries{Symbol: symbol, PrevClose: prevClose, Session: phaseClosed}
+	if len(bars) == 0 {
+		return s
+	}
+	s.Date, _, _ = strings.Cut(bars[0].Time, " ")
+	s.Session = barSession(s.Date, now)
+	var volume, amount, weighted float64
+	for _, b := range bars {
+		volume += b.Volume
+		amount += b.Amount
+		weighted += (b.High + b.Low + b.Close) / 3 * b.Volume
+		avg, vwap := b.Close, b.Close
+		if volume > 0 {
+			avg, vwap = amount/volume, weighted/volume
+		} else if n := len(s.AvgPrice); n > 0 {
+			avg, vwap = s.AvgPrice[n-1], s.VWAP[n-1]
+		}
+		_, clock, _ := strings.Cut(b.Time, " ")
+		s.Times = append(s.Times, clock)
+		s.Price = append(s.Price, b.Close)
+		s.AvgPrice = append(s.AvgPrice, avg)
+		s.VWAP = append(s.VWAP, vwap)
+		s.Volume = append(s.Volume, b.Volume)
+		s.Amount = append(s.Amount, b.Amount)
+	}
+	return s
+}
+
+// GetIntraday returns the minute prices of the current trading day of
+// symbol, or the last one outside trading hours, with the average-price
+// line and VWAP
+func (a *App) GetIntraday(symbol string) (string, error) {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+	key := "intraday:" + symbol
+	if v, ok := resultCache.get(key); ok {
+		return toJSON(v)
+	}
+	bars, prevClose, err := fetchMinuteBars(symbol)
+	if err != nil {
+		return "", err
+	}
+	series := intradaySeries(symbol, bars, prevClose, shanghaiNow())
+	resultCache.set(key, series, currentSettings().quoteTTL())
+	return toJSON(series)
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// jobWorkers bounds the queued jobs that run at the same time
+	jobWorkers = 2
+	// jobHistoryLimit is how many finished jobs are kept for GetJob
+	jobHistoryLimit = 50
+
+	eventJobDone = "job:done"
+)
+
+// errJobCanceled is returned by an operation stopped with CancelJob
+var errJobCanceled error = &codedError{codeCanceled, errors.New("job was canceled")}
+
+// Job is a long operation that can be canceled while it runs
+type Job struct {
+	ID         string `json:"id"`
+	Kind       string `json:"kind"`   // "sync", "screen", "backtest", "strategyBacktest", "update" or "stream"
+	Label      string `json:"label"`  // what it works on, e.g. the symbol
+	Status     string `json:"status"` // "queued", "running", "done", "failed" or "canceled"
+	CreatedAt  string `json:"createdAt"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// jobFunc does the work of a job and returns its JSON result
+type jobFunc func(ctx context.Context, job Job) (string, error)
+
+// JobParams are the parameters of SubmitJob; which apply depends on the
+// kind
+type JobParams struct {
+	StrategyID string          `json:"strategyId"`
+	Symbol     string          `json:"symbol"`
+	Days       int             `json:"days"`
+	Config     json.RawMessage `json:"config"` // BacktestConfig of a "backtest"
+}
+
+// jobRegistry runs queued jobs on a small worker pool and tracks running
+// and recently finished jobs with their results
+type jobRegistry struct {
+	mu      sync.Mutex
+	jobs    map[string]*trackedJob
+	queue   []string
+	workers int
+	onDone  func(Job)
+	active  sync.WaitGroup // jobs not finished yet
+}
+
+type trackedJob struct {
+	Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	run    jobFunc
+	result string
+	began  time.Time // when it started running
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[string]*trackedJob)}
+}
+
+// add registers a job in status. Callers hold r.mu.
+func (r *jobRegistry) add(kind, label, status string, run jobFunc) *trackedJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := shanghaiNow().Format(time.RFC3339)
+	t := &trackedJob{
+		Job:    Job{ID: newID(), Kind: kind, Label: label, Status: status, CreatedAt: now},
+		ctx:    ctx,
+		cancel: cancel,
+		run:    run,
+	}
+	if status == "running" {
+		t.StartedAt, t.began = now, time.Now()
+	}
+	r.jobs[t.ID] = t
+	r.active.Add(1)
+	return t
+}
+
+// start registers a job the caller runs itself and returns the context it
+// runs under. Callers must call finish when it ends.
+func (r *jobRegistry) start(kind, label string) (Job, context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.add(kind, label, "running", nil)
+	return t.Job, t.ctx
+}
+
+// run runs fn as a job in the calling goroutine
+func (r *jobRegistry) run(kind, label string, fn jobFunc) (string, error) {
+	job, ctx := r.start(kind, label)
+	result, err := callSafely(kind+" job", func() (string, error) { return fn(ctx, job) })
+	err = canceled(ctx, err)
+	r.finish(job.ID, result, err)
+	return result, err
+}
+
+// submit queues fn as a job and returns it without waiting
+func (r *jobRegistry) submit(kind, label string, fn jobFunc) Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.add(kind, label, "queued", fn)
+	r.queue = append(r.queue, t.ID)
+	if r.workers < jobWorkers {
+		r.workers++
+		go r.work()
+	}
+	return t.Job
+}
+
+// work runs queued jobs until the queue is empty
+func (r *jobRegistry) work() {
+	for {
+		r.mu.Lock()
+		if len(r.queue) == 0 {
+			r.workers--
+			r.mu.Unlock()
+			return
+		}
+		t := r.jobs[r.queue[0]]
+		r.queue = r.queue[1:]
+		if t == nil || t.Status != "queued" {
+			r.mu.Unlock()
+			continue
+		}
+		t.Status = "running"
+		t.StartedAt, t.began = shanghaiNow().Format(time.RFC3339), time.Now()
+		job, ctx, fn := t.Job, t.ctx, t.run
+		r.mu.Unlock()
+
+		result, err := callSafely(job.Kind+" job", func() (string, error) { return fn(ctx, job) })
+		r.finish(job.ID, result, canceled(ctx, err))
+	}
+}
+
+// finish records the outcome of a job and releases its context
+func (r *jobRegistry) finish(id, result string, err error) {
+	r.mu.Lock()
+	t, ok := r.jobs[id]
+	if !ok || t.FinishedAt != "" {
+		r.mu.Unlock()
+		return
+	}
+	r.active.Done()
+	t.cancel()
+	t.FinishedAt = shanghaiNow().Format(time.RFC3339)
+	switch {
+	case err == errJobCanceled:
+		t.Status = "canceled"
+	case err != nil:
+		t.Status = "failed"
+		t.Error = err.Error()
+	default:
+		t.Status = "done"
+		t.result = result
+	}
+	if !t.began.IsZero() {
+		metrics.observeJob(t.Kind, t.Status, time.Since(t.began))
+	}
+	r.prune()
+	logger.Info("job finished", "id", t.ID, "kind", t.Kind, "label", t.Label, "status", t.Status, "error", t.Error)
+	job, notify := t.Job, r.onDone
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify(job)
+	}
+}
+
+// prune drops the oldest finished jobs beyond jobHistoryLimit. Callers
+// hold r.mu.
+func (r *jobRegistry) prune() {
+	var finished []*trackedJob
+	for _, t := range r.jobs {
+		if t.FinishedAt != "" {
+			finished = append(finished, t)
+		}
+	}
+	if len(finished) <= jobHistoryLimit {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt < finished[j].FinishedAt })
+	for _, t := range finished[:len(finished)-jobHistoryLimit] {
+		delete(r.jobs, t.ID)
+	}
+}
+
+// cancel stops a running job or takes a queued one off the queue
+func (r *jobRegistry) cancel(id string) error {
+	r.mu.Lock()
+	t, ok := r.jobs[id]
+	if !ok || t.FinishedAt != "" {
+		r.mu.Unlock()
+		return codeErrorf(codeNotFound, "job not running: %s", id)
+	}
+	t.cancel()
+	queued := t.Status == "queued"
+	r.mu.Unlock()
+
+	// A running job finishes itself once it sees the cancellation
+	if queued {
+		r.finish(id, "", errJobCanceled)
+	}
+	return nil
+}
+
+// cancelAll stops every running and queued job
+func (r *jobRegistry) cancelAll() {
+	r.mu.Lock()
+	var ids []string
+	for id, t := range r.jobs {
+		if t.FinishedAt == "" {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.Unlock()
+	for _, id := range ids {
+		r.cancel(id)
+	}
+}
+
+// unfinished returns how many jobs are queued or running
+func (r *jobRegistry) unfinished() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, t := range r.jobs {
+		if t.FinishedAt == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// wait blocks until every job has finished
+func (r *jobRegistry) wait() {
+	r.active.Wait()
+}
+
+// get returns a job and its result
+func (r *jobRegistry) get(id string) (Job, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.jobs[id]
+	if !ok {
+		return Job{}, "", codeErrorf(codeNotFound, "job not found: %s", id)
+	}
+	return t.Job, t.result, nil
+}
+
+// canceled maps the error of an operation run under ctx to errJobCanceled
+// if the job was canceled
+func canceled(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return errJobCanceled
+	}
+	return err
+}
+
+// watchJobs emits an event whenever a job finishes
+func (a *App) watchJobs() {
+	a.jobs.mu.Lock()
+	defer a.jobs.mu.Unlock()
+	a.jobs.onDone = func(job Job) {
+		wailsruntime.EventsEmit(a.ctx, eventJobDone, job)
+	}
+}
+
+// SubmitJob queues a "screen" (strategyId), "backtest" (symbol, days,
+// config) or "strategyBacktest" (strategyId, symbol) described by
+// paramsJSON (a JobParams) and returns the job. Its result is available
+// from GetJobResult once a job:done event reports it finished.
+func (a *App) SubmitJob(kind string, paramsJSON string) (string, error) {
+	var params JobParams
+	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse job params: %v", err)
+	}
+	var (
+		label string
+		fn    jobFunc
+		err   error
+	)
+	switch kind {
+	case "screen":
+		label, fn, err = a.screenJob(params.StrategyID)
+	case "backtest":
+		label, fn, err = a.backtestJob(params.Symbol, params.Days, string(params.Config))
+	case "strategyBacktest":
+		label, fn, err = a.strategyBacktestJob(params.StrategyID, params.Symbol)
+	default:
+		return "", codeErrorf(codeParse, "unknown job kind: %s", kind)
+	}
+	if err != nil {
+		return "", err
+	}
+	return toJSON(a.jobs.submit(kind, label, fn))
+}
+
+// ListJobs returns the queued, running and recently finished jobs, newest
+// first
+func (a *App) ListJobs() (string, error) {
+	r := a.jobs
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := []Job{}
+	for _, t := range r.jobs {
+		out = append(out, t.Job)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+	return toJSON(out)
+}
+
+// GetJob returns the status of a job
+func (a *App) GetJob(id string) (string, error) {
+	job, _, err := a.jobs.get(id)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(job)
+}
+
+// GetJobResult returns the result of a finished job, in the format the
+// matching synchronous endpoint returns
+func (a *App) GetJobResult(id string) (string, error) {
+	job, result, err := a.jobs.get(id)
+	if err != nil {
+		return "", err
+	}
+	switch job.Status {
+	case "done":
+		return result, nil
+	case "failed":
+		return "", errors.New(job.Error)
+	default:
+		return "", fmt.Errorf("job is %s", job.Status)
+	}
+}
+
+// CancelJob stops a running job or removes a queued one. The ID of a job
+// is in the progress events it emits.
+func (a *App) CancelJob(id string) error {
+	return a.jobs.cancel(id)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// A stock may move at most a fixed share of its reference price a day:
+// 10% on the main boards, 5% while it is under special treatment (ST),
+// 20% on STAR and, since its registration reform of 24 August 2020,
+// ChiNext, and 30% on the Beijing exchange. The limit price is rounded
+// to the fen, and a close at the limit price is a limit-up (涨停). A run
+// of them is a streak counted in boards (连板): the second limit-up in a
+// row is the second board.
+
+// chinextReform is the first day of the 20% limit on ChiNext
+const chinextReform = "2020-08-24"
+
+// LimitUpDay is a limit-up in a streak
+type LimitUpDay struct {
+	Date    string  `json:"date"`
+	Close   float64 `json:"close"`
+	Boards  int     `json:"boards"`  // position in the streak, from 1
+	OneWord bool    `json:"oneWord"` // opened and stayed at the limit (一字板)
+}
+
+// LimitUpStreaks is the limit-up history of a symbol. Boards has one
+// entry per date: the streak length on that day, 0 if it did not close at
+// the limit.
+type LimitUpStreaks struct {
+	Symbol    string       `json:"symbol"`
+	LimitPct  float64      `json:"limitPct"` // current daily limit, %
+	Dates     []string     `json:"dates"`
+	Boards    []int        `json:"boards"`
+	Events    []LimitUpDay `json:"events"`
+	Current   int          `json:"current"` // streak as of the last bar
+	Longest   int          `json:"longest"`
+	LimitUps  int          `json:"limitUps"`
+	Streaks   int          `json:"streaks"`   // runs of two boards or more
+	Continued float64      `json:"continued"` // share of limit-ups followed by another, %
+}
+
+// limitPct returns the daily price limit of symbol on date, in percent.
+// name is the security name, which carries the ST mark; empty if unknown.
+func limitPct(symbol, name, date string) float64 {
+	code := strings.TrimPrefix(symbol, "cn_")
+	st := strings.Contains(strings.ToUpper(name), "ST")
+	switch {
+	case strings.HasPrefix(code, "688") || strings.HasPrefix(code, "689"):
+		return 20
+	case strings.HasPrefix(code, "300") || strings.HasPrefix(code, "301"):
+		if date >= chinextReform {
+			return 20
+		}
+	case strings.HasPrefix(code, "8") || strings.HasPrefix(code, "4") || strings.HasPrefix(code, "92"):
+		return 30
+	}
+	if st {
+		return 5
+	}
+	return 10
+}
+
+// limitUpPrice returns the limit-up price over the reference price prev
+func limitUpPrice(prev, pct float64) float64 {
+	return math.Round(prev*(1+pct/100)*100) / 100
+}
+
+// limitUpBoards returns, for each bar, the length of the limit-up streak
+// it ends, 0 if it did not close at the limit. The reference price is the
+// previous close as the provider adjusted it for distributions, or the
+// close of the bar before for imported bars without a change. Indices
+// have no limit.
+func limitUpBoards(symbol, name string, bars []Bar) []int {
+	boards := make([]int, len(bars))
+	if strings.HasPrefix(symbol, "zs_") {
+		return boards
+	}
+	for i, b := range bars {
+		prev := b.Close - b.Change
+		if b.Change == 0 && i > 0 {
+			prev = bars[i-1].Close
+		}
+		if prev <= 0 || b.Change == 0 && i == 0 {
+			continue
+		}
+		if b.Close >= limitUpPrice(prev, limitPct(symbol, name, b.Date))-0.001 {
+			boards[i] = 1
+			if i > 0 {
+				boards[i] += boards[i-1]
+			}
+		}
+	}
+	return boards
+}
+
+// limitUpStreaks summarizes the limit-ups of bars
+func limitUpStreaks(symbol, name string, bars []Bar) LimitUpStreaks {
+	boards := limitUpBoards(symbol, name, bars)
+	s := LimitUpStreaks{
+		Symbol:   symbol,
+		LimitPct: limitPct(symbol, name, shanghaiNow().Format("2006-01-02")),
+		Dates:    make([]string, len(bars)),
+		Boards:   boards,
+		Events:   []LimitUpDay{},
+	}
+	continued := 0
+	for i, b := range bars {
+		s.Dates[i] = b.Date
+		if boards[i] == 0 {
+			continue
+		}
+		s.LimitUps++
+		s.Longest = max(s.Longest, boards[i])
+		if boards[i] == 2 {
+			s.Streaks++
+		}
+		if i+1 < len(bars) && boards[i+1] > 0 {
+			continued++
+		}
+		s.Events = append(s.Events, LimitUpDay{Date: b.Date, Close: b.Close, Boards: boards[i], OneWord: b.Open == b.Close && b.Low == b.Close})
+	}
+	if n := len(boards); n > 0 {
+		s.Current = boards[n-1]
+	}
+	if s.LimitUps > 0 {
+		s.Continued = float64(continued) / float64(s.LimitUps) * 100
+	}
+	return s
+}
+
+// GetLimitUpStreaks returns the limit-up days of symbol over the last
+// days with their board counts. days of 0 uses the configured lookback
+// and lookbackMax (-1) all available history.
+func (a *App) GetLimitUpStreaks(symbol string, days int) (string, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	name := ""
+	if meta, err := a.metadata.get(symbol, false); err == nil {
+		name = meta.Name
+	}
+	return toJSON(limitUpStreaks(symbol, name, bars))
+}
+
+// limitUpSeries is the BOARDS formula variable: the streak length of each
+// bar as a float series
+func limitUpSeries(ctx *formulaContext) []float64 {
+	name := ""
+	if ctx.meta != nil {
+		name = ctx.meta.Name
+	}
+	boards := limitUpBoards(ctx.symbol, name, ctx.bars)
+	out := make([]float64, len(boards))
+	for i, n := range boards {
+		out[i] = float64(n)
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// logMaxSize is the size at which app.log is rotated
+	logMaxSize = 5 << 20
+	// logKeep is how many rotated files are kept next to app.log
+	logKeep = 3
+	// logRingSize is how many recent entries GetRecentLogs can return
+	logRingSize = 500
+)
+
+// logLevel is the minimum level written to the log
+var logLevel = new(slog.LevelVar)
+
+// logger is the application log. initLogging points it at the data
+// directory; until then it writes to stderr.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+// recentLogs keeps the latest entries for the in-app log viewer
+var recentLogs = &logRing{}
+
+// LogEntry is one line of the log
+type LogEntry struct {
+	Time    string                 `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// logRing is an io.Writer that keeps the last logRingSize JSON log lines
+// as entries
+type logRing struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	next    int
+}
+
+func (r *logRing) Write(p []byte) (int, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return len(p), nil
+	}
+	entry := LogEntry{Attrs: make(map[string]interface{})}
+	for k, v := range fields {
+		switch k {
+		case slog.TimeKey:
+			entry.Time, _ = v.(string)
+		case slog.LevelKey:
+			entry.Level, _ = v.(string)
+		case slog.MessageKey:
+			entry.Message, _ = v.(string)
+		default:
+			entry.Attrs[k] = v
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < logRingSize {
+		r.entries = append(r.entries, entry)
+	} else {
+		r.entries[r.next] = entry
+		r.next = (r.next + 1) % logRingSize
+	}
+	return len(p), nil
+}
+
+// snapshot returns the kept entries, oldest first
+func (r *logRing) snapshot() []LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]LogEntry, 0, len(r.entries))
+	out = append(out, r.entries[r.next:]...)
+	return append(out, r.entries[:r.next]...)
+}
+
+// rotatingFile appends to path and rotates it to path.1, path.2, ...
+// once it grows past logMaxSize
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &rotatingFile{path: path}
+	return f, f.open()
+}
+
+// open opens the current file for appending. Callers hold f.mu.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size+int64(len(p)) > logMaxSize && f.size > 0 {
+		f.file.Close()
+		for i := logKeep - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		}
+		os.Rename(f.path, f.path+".1")
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// initLogging writes the log as JSON lines to logs/app.log in dataDir and
+// keeps recent entries in memory
+func initLogging(dataDir string) {
+	var out io.Writer = recentLogs
+	file, err := openRotatingFile(filepath.Join(dataDir, "logs", "app.log"))
+	if err == nil {
+		out = io.MultiWriter(file, recentLogs)
+	}
+	logger = slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: logLevel}))
+	if err != nil {
+		logger.Warn("log file unavailable, keeping logs in memory only", "error", err)
+	}
+}
+
+// parseLogLevel reads "debug", "info", "warn" or "error"
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, codeErrorf(codeParse, "unknown log level: %s", s)
+	}
+	return level, nil
+}
+
+// GetRecentLogs returns up to limit recent log entries at or above level
+// ("debug", "info", "warn" or "error"; empty for all), newest first
+func (a *App) GetRecentLogs(level string, limit int) (string, error) {
+	min := slog.LevelDebug
+	if level != "" {
+		var err error
+		if min, err = parseLogLevel(level); err != nil {
+			return "", err
+		}
+	}
+	if limit <= 0 || limit > logRingSize {
+		limit = logRingSize
+	}
+	entries := recentLogs.snapshot()
+	out := []LogEntry{}
+	for i := len(entries) - 1; i >= 0 && len(out) < limit; i-- {
+		entryLevel, err := parseLogLevel(strings.ToLower(entries[i].Level))
+		if err == nil && entryLevel >= min {
+			out = append(out, entries[i])
+		}
+	}
+	return toJSON(out)
+}
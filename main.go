@@ -2,18 +2,31 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/mac"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
 func main() {
+	// Flags such as --analyze run the pipeline without the GUI
+	if cliRequested(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	// Create an instance of the app structure
-	app := NewApp()
+	app := NewApp(profileArg(os.Args[1:]))
+
+	// Windows and Linux pass a deep link on the command line; macOS
+	// delivers it through OnUrlOpen
+	if link := deepLinkArg(os.Args[1:]); link != "" {
+		app.openDeepLink(link)
+	}
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -25,6 +38,18 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnDomReady:       app.domReady,
+		OnBeforeClose:    app.beforeClose,
+		OnShutdown:       app.shutdown,
+		ErrorFormatter:   formatError,
+		Logger:           wailsLog{},
+		SingleInstanceLock: &options.SingleInstanceLock{
+			UniqueId:               singleInstanceID,
+			OnSecondInstanceLaunch: app.onSecondInstanceLaunch,
+		},
+		Mac: &mac.Options{
+			OnUrlOpen: app.openDeepLink,
+		},
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"time"
+)
+
+// The Shanghai and Shenzhen exchanges trade in two sessions on trading
+// days. Orders are matched in the opening call auction from 9:15 to 9:25,
+// continuously from 9:30 to 11:30 and 13:00 to 14:57, and in the closing
+// call auction until 15:00. Between those the book does not trade.
+
+// Market phases
+const (
+	phaseCallAuction = "callAuction"
+	phaseContinuous  = "continuous"
+	phaseBreak       = "break" // 9:25 to 9:30 and the lunch break
+	phaseClosed      = "closed"
+)
+
+// marketPhases are the phases of a trading day by minute of the day,
+// each lasting until the next
+var marketPhases = []struct {
+	from  int
+	phase string
+}{
+	{9*60 + 15, phaseCallAuction},
+	{9*60 + 25, phaseBreak},
+	{9*60 + 30, phaseContinuous},
+	{11*60 + 30, phaseBreak},
+	{13 * 60, phaseContinuous},
+	{14*60 + 57, phaseCallAuction},
+	{15 * 60, phaseClosed},
+}
+
+// MarketSession is the state of the market at a moment
+type MarketSession struct {
+	Time       string `json:"time"`
+	Phase      string `json:"phase"` // "callAuction", "continuous", "break" or "closed"
+	Open       bool   `json:"open"`  // orders are being matched
+	TradingDay bool   `json:"tradingDay"`
+	NextOpen   string `json:"nextOpen"`            // when matching next starts
+	NextClose  string `json:"nextClose,omitempty"` // when the current stretch of matching ends; set while open
+}
+
+// isTradingDay reports whether the exchanges trade on the day of t: a
+// weekday that is not a holiday
+func isTradingDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return !holidays.isClosed(t.Format("2006-01-02"))
+}
+
+// marketPhase returns the phase of the market at t, in Shanghai time
+func marketPhase(t time.Time) string {
+	if !isTradingDay(t) {
+		return phaseClosed
+	}
+	minute := t.Hour()*60 + t.Minute()
+	phase := phaseClosed
+	for _, p := range marketPhases {
+		if minute >= p.from {
+			phase = p.phase
+		}
+	}
+	return phase
+}
+
+// phaseOpen reports whether orders are matched in phase
+func phaseOpen(phase string) bool {
+	return phase == phaseCallAuction || phase == phaseContinuous
+}
+
+// atMinute returns the day of t at minute of the day
+func atMinute(t time.Time, minute int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), minute/60, minute%60, 0, 0, t.Location())
+}
+
+// marketSession describes the market at t
+func marketSession(t time.Time) MarketSession {
+	phase := marketPhase(t)
+	s := MarketSession{Time: t.Format(time.RFC3339), Phase: phase, Open: phaseOpen(phase), TradingDay: isTradingDay(t)}
+	minute := t.Hour()*60 + t.Minute()
+
+	// Walk the rest of the day's boundaries for where matching stops and
+	// next starts
+	if s.TradingDay {
+		open := s.Open
+		for _, p := range marketPhases {
+			if p.from <= minute {
+				continue
+			}
+			at := atMinute(t, p.from).Format(time.RFC3339)
+			switch {
+			case open && !phaseOpen(p.phase) && s.NextClose == "":
+				s.NextClose = at
+			case !open && phaseOpen(p.phase):
+				s.NextOpen = at
+			}
+			if s.NextOpen != "" {
+				break
+			}
+			open = phaseOpen(p.phase)
+		}
+	}
+	if s.NextOpen == "" {
+		day := t.AddDate(0, 0, 1)
+		for !isTradingDay(day) {
+			day = day.AddDate(0, 0, 1)
+		}
+		s.NextOpen = atMinute(day, marketPhases[0].from).Format(time.RFC3339)
+	}
+	return s
+}
+
+// lastClose returns the most recent close of a trading day at or before t
+func lastClose(t time.Time) time.Time {
+	day := t
+	if day.Hour()*60+day.Minute() < 15*60 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for !isTradingDay(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return atMinute(day, 15*60)
+}
+
+// barSession returns the phase a bar dated date is in at now: the phase
+// of the market for today's bar, which is still forming until the close,
+// and closed for earlier bars
+func barSession(date string, now time.Time) string {
+	if date != now.Format("2006-01-02") {
+		return phaseClosed
+	}
+	if phase := marketPhase(now); phase != phaseClosed || now.Hour() >= 15 {
+		return phase
+	}
+	// A bar dated today before the open is left over from a provider
+	// clock skew; treat it as forming
+	return phaseBreak
+}
+
+// IsMarketOpen reports whether the exchanges are matching orders now
+func (a *App) IsMarketOpen() bool {
+	return phaseOpen(marketPhase(shanghaiNow()))
+}
+
+// GetMarketSession returns the phase of the market now, and when it next
+// opens and closes
+func (a *App) GetMarketSession() (string, error) {
+	return toJSON(marketSession(shanghaiNow()))
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataMaxAge is how long cached symbol metadata is trusted
+const metadataMaxAge = 7 * 24 * time.Hour
+
+// SymbolMetadata is the slowly-changing reference data of a symbol
+type SymbolMetadata struct {
+	Symbol      string    `json:"symbol"`
+	Name        string    `json:"name"`
+	Exchange    string    `json:"exchange"` // "sh" or "sz"
+	Industry    string    `json:"industry"`
+	ListingDate string    `json:"listingDate"`
+	TotalShares float64   `json:"totalShares"`
+	FloatShares float64   `json:"floatShares"` // 流通股本
+	LotSize     int       `json:"lotSize"`     // minimum buy quantity
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// lotSize returns the minimum buy quantity of symbol. STAR Market (688)
+// orders start at 200 shares; everything else trades in board lots.
+func lotSize(symbol string) int {
+	if strings.HasPrefix(strings.TrimPrefix(symbol, "cn_"), "688") {
+		return 200
+	}
+	return boardLot
+}
+
+// buyQuantity rounds shares down to a quantity symbol can be bought in:
+// whole board lots, or on the STAR Market any quantity from its minimum.
+// It returns 0 below the minimum.
+func buyQuantity(symbol string, shares int) int {
+	if lot := lotSize(symbol); lot != boardLot {
+		if shares < lot {
+			return 0
+		}
+		return shares
+	}
+	return shares / boardLot * boardLot
+}
+
+// eastmoneyNumber reads a numeric field that is "-" when missing
+func eastmoneyNumber(v interface{}) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return 0
+}
+
+// fetchSymbolMetadata downloads the reference data of symbol
+func fetchSymbolMetadata(symbol string) (SymbolMetadata, error) {
+	if err := cooldowns.check(providerEastmoney); err != nil {
+		return SymbolMetadata{}, err
+	}
+	started := time.Now()
+	resp, err := providerClient.Get("https://push2.eastmoney.com/api/qt/stock/get?fields=f57,f58,f84,f85,f127,f189&secid=" + eastmoneySecID(symbol))
+	if err != nil {
+		return SymbolMetadata{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return SymbolMetadata{}, err
+	}
+	logProviderResponse(resp, body, started)
+	if err := checkProviderResponse(providerEastmoney, resp, body); err != nil {
+		return SymbolMetadata{}, err
+	}
+
+	var payload struct {
+		Data *struct {
+			Name        string      `json:"f58"`
+			TotalShares interface{} `json:"f84"`
+			FloatShares interface{} `json:"f85"`
+			Industry    interface{} `json:"f127"`
+			ListingDate interface{} `json:"f189"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return SymbolMetadata{}, codeErrorf(codeParse, "failed to parse JSON: %v", err)
+	}
+	if payload.Data == nil {
+		return SymbolMetadata{}, codeErrorf(codeNoData, "no data for %s", symbol)
+	}
+
+	market, _ := symbolMarket(symbol)
+	meta := SymbolMetadata{
+		Symbol:      symbol,
+		Name:        payload.Data.Name,
+		Exchange:    market,
+		TotalShares: eastmoneyNumber(payload.Data.TotalShares),
+		FloatShares: eastmoneyNumber(payload.Data.FloatShares),
+		LotSize:     lotSize(symbol),
+		UpdatedAt:   time.Now(),
+	}
+	// Missing fields come back as "-"
+	if industry, ok := payload.Data.Industry.(string); ok && industry != "-" {
+		meta.Industry = industry
+	}
+	if listed := eastmoneyNumber(payload.Data.ListingDate); listed > 0 {
+		if t, err := time.Parse("20060102", fmt.Sprintf("%.0f", listed)); err == nil {
+			meta.ListingDate = t.Format("2006-01-02")
+		}
+	}
+	return meta, nil
+}
+
+// metadataStore caches symbol metadata on disk, refreshing entries that
+// are more than a week old
+type metadataStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	meta   map[string]SymbolMetadata
+}
+
+func newMetadataStore(dataDir string) *metadataStore {
+	return &metadataStore{path: filepath.Join(dataDir, "symbol_metadata.json")}
+}
+
+// unload forgets the metadata so the next use reads the file again
+func (s *metadataStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta, s.loaded = nil, false
+}
+
+// load reads the cache from disk on first use. Callers hold s.mu.
+func (s *metadataStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.meta = make(map[string]SymbolMetadata)
+	if err := loadJSON(s.path, &s.meta); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// get returns the metadata of symbol, downloading it when it is missing,
+// stale or refresh is set. A stale entry is returned if the download fails.
+func (s *metadataStore) get(symbol string, refresh bool) (SymbolMetadata, error) {
+	s.mu.Lock()
+	if err := s.load(); err != nil {
+		s.mu.Unlock()
+		return SymbolMetadata{}, err
+	}
+	cached, ok := s.meta[symbol]
+	s.mu.Unlock()
+	if ok && !refresh && time.Since(cached.UpdatedAt) < metadataMaxAge {
+		return cached, nil
+	}
+
+	// Download without holding the lock so lookups of other symbols
+	// are not held up
+	meta, err := fetchSymbolMetadata(symbol)
+	if err != nil {
+		if ok && !refresh {
+			return cached, nil
+		}
+		return SymbolMetadata{}, fmt.Errorf("failed to get metadata for %s: %v", symbol, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The stores may have been reloaded meanwhile
+	if err := s.load(); err != nil {
+		return SymbolMetadata{}, err
+	}
+	s.meta[symbol] = meta
+	return meta, saveJSON(s.path, s.meta)
+}
+
+// GetSymbolMetadata returns name, exchange, industry, listing date, share
+// counts and lot size of symbol from the local cache
+func (a *App) GetSymbolMetadata(symbol string) (string, error) {
+	meta, err := a.metadata.get(normalizeSymbol(symbol), false)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(meta)
+}
+
+// RefreshSymbolMetadata downloads the metadata of symbol again
+func (a *App) RefreshSymbolMetadata(symbol string) (string, error) {
+	meta, err := a.metadata.get(normalizeSymbol(symbol), true)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(meta)
+}
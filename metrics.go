@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The metrics are kept in process and served in the Prometheus text
+// format at /metrics of the push server, next to /ws. Scrapers pass the
+// push token as ?token= or as a bearer token.
+
+// latencyBuckets are the upper bounds, in seconds, of the provider request
+// and job duration histograms
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// histogram counts observations per bucket of latencyBuckets
+type histogram struct {
+	counts []uint64 // per bucket of latencyBuckets, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metricsRegistry holds the counters and histograms of the app
+type metricsRegistry struct {
+	mu               sync.Mutex
+	providerLatency  map[string]*histogram // by provider
+	providerStatus   map[[2]string]uint64  // by provider and status class
+	cacheHits        uint64
+	cacheMisses      uint64
+	jobDurations     map[[2]string]*histogram // by kind and status
+	alertEvaluations uint64
+	alertRules       uint64
+	alertTriggers    uint64
+	lastResponse     map[string]providerResponse // by provider
+	lastAlerts       time.Time                   // last evaluation of the alert rules
+}
+
+// providerResponse is the latest response of a provider
+type providerResponse struct {
+	at     time.Time
+	status int
+}
+
+// metrics is the registry of the app
+var metrics = &metricsRegistry{
+	providerLatency: make(map[string]*histogram),
+	providerStatus:  make(map[[2]string]uint64),
+	jobDurations:    make(map[[2]string]*histogram),
+	lastResponse:    make(map[string]providerResponse),
+}
+
+// observeProvider records a provider response
+func (m *metricsRegistry) observeProvider(provider string, status int, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.providerLatency[provider]
+	if h == nil {
+		h = &histogram{}
+		m.providerLatency[provider] = h
+	}
+	h.observe(took.Seconds())
+	m.providerStatus[[2]string{provider, fmt.Sprintf("%dxx", status/100)}]++
+	m.lastResponse[provider] = providerResponse{at: time.Now(), status: status}
+}
+
+// observeCache records a lookup in the result cache
+func (m *metricsRegistry) observeCache(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// observeJob records a finished job
+func (m *metricsRegistry) observeJob(kind, status string, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]string{kind, status}
+	h := m.jobDurations[key]
+	if h == nil {
+		h = &histogram{}
+		m.jobDurations[key] = h
+	}
+	h.observe(took.Seconds())
+}
+
+// observeAlerts records one evaluation of the alert rules
+func (m *metricsRegistry) observeAlerts(rules, triggers int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alertEvaluations++
+	m.alertRules += uint64(rules)
+	m.alertTriggers += uint64(triggers)
+	m.lastAlerts = time.Now()
+}
+
+// writeHistogram writes h as the series of name with labels
+func writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	var cumulative uint64
+	for i, le := range latencyBuckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%g\"} %d\n", name, labels, le, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %g\n", name, strings.TrimSuffix(labels, ","), h.sum)
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, strings.TrimSuffix(labels, ","), h.count)
+}
+
+// labelValue escapes v for a label value
+func labelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// write writes every metric in the Prometheus text format
+func (m *metricsRegistry) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP stock_analysis_provider_request_duration_seconds Latency of data provider requests.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_provider_request_duration_seconds histogram")
+	providers := make([]string, 0, len(m.providerLatency))
+	for p := range m.providerLatency {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	for _, p := range providers {
+		writeHistogram(w, "stock_analysis_provider_request_duration_seconds",
+			fmt.Sprintf("provider=\"%s\",", labelValue(p)), m.providerLatency[p])
+	}
+
+	fmt.Fprintln(w, "# HELP stock_analysis_provider_responses_total Data provider responses by status class.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_provider_responses_total counter")
+	statuses := make([][2]string, 0, len(m.providerStatus))
+	for k := range m.providerStatus {
+		statuses = append(statuses, k)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i][0] != statuses[j][0] {
+			return statuses[i][0] < statuses[j][0]
+		}
+		return statuses[i][1] < statuses[j][1]
+	})
+	for _, k := range statuses {
+		fmt.Fprintf(w, "stock_analysis_provider_responses_total{provider=\"%s\",status=\"%s\"} %d\n",
+			labelValue(k[0]), k[1], m.providerStatus[k])
+	}
+
+	fmt.Fprintln(w, "# HELP stock_analysis_cache_requests_total Result cache lookups by outcome.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_cache_requests_total counter")
+	fmt.Fprintf(w, "stock_analysis_cache_requests_total{result=\"hit\"} %d\n", m.cacheHits)
+	fmt.Fprintf(w, "stock_analysis_cache_requests_total{result=\"miss\"} %d\n", m.cacheMisses)
+	fmt.Fprintln(w, "# HELP stock_analysis_cache_hit_ratio Share of result cache lookups that hit.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_cache_hit_ratio gauge")
+	ratio := 0.0
+	if total := m.cacheHits + m.cacheMisses; total > 0 {
+		ratio = float64(m.cacheHits) / float64(total)
+	}
+	fmt.Fprintf(w, "stock_analysis_cache_hit_ratio %g\n", ratio)
+
+	fmt.Fprintln(w, "# HELP stock_analysis_job_duration_seconds Run time of background jobs.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_job_duration_seconds histogram")
+	jobs := make([][2]string, 0, len(m.jobDurations))
+	for k := range m.jobDurations {
+		jobs = append(jobs, k)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i][0] != jobs[j][0] {
+			return jobs[i][0] < jobs[j][0]
+		}
+		return jobs[i][1] < jobs[j][1]
+	})
+	for _, k := range jobs {
+		writeHistogram(w, "stock_analysis_job_duration_seconds",
+			fmt.Sprintf("kind=\"%s\",status=\"%s\",", labelValue(k[0]), k[1]), m.jobDurations[k])
+	}
+
+	fmt.Fprintln(w, "# HELP stock_analysis_alert_evaluations_total Evaluations of the alert rules.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_alert_evaluations_total counter")
+	fmt.Fprintf(w, "stock_analysis_alert_evaluations_total %d\n", m.alertEvaluations)
+	fmt.Fprintln(w, "# HELP stock_analysis_alert_rules_evaluated_total Alert rules checked over all evaluations.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_alert_rules_evaluated_total counter")
+	fmt.Fprintf(w, "stock_analysis_alert_rules_evaluated_total %d\n", m.alertRules)
+	fmt.Fprintln(w, "# HELP stock_analysis_alert_triggers_total Alert rules that fired.")
+	fmt.Fprintln(w, "# TYPE stock_analysis_alert_triggers_total counter")
+	fmt.Fprintf(w, "stock_analysis_alert_triggers_total %d\n", m.alertTriggers)
+}
+
+// serveMetrics writes the metrics to scrapers that present token
+func serveMetrics(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			given = bearer
+		}
+		if given != token {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.write(w)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"stock-analysis/internal/store"
+)
+
+// dataMigration is one versioned change to the JSON files in the data
+// directory. Migrations run in order at startup; if one fails every file
+// is put back as it was before the run.
+type dataMigration struct {
+	version int
+	name    string
+	up      func(dataDir string) error
+}
+
+var dataMigrations = []dataMigration{
+	{1, "merge portfolio.json into portfolios.json", migratePortfolioBook},
+}
+
+// historyMigration is one versioned change to the history database,
+// applied in a transaction and tracked in PRAGMA user_version
+type historyMigration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+var historyMigrations = []historyMigration{
+	{1, "create bars and sync_state", func(tx *sql.Tx) error {
+		_, err := tx.Exec(store.HistorySchema)
+		return err
+	}},
+	{2, "create quality", func(tx *sql.Tx) error {
+		_, err := tx.Exec(qualitySchema)
+		return err
+	}},
+	{3, "create bar_archive", func(tx *sql.Tx) error {
+		_, err := tx.Exec(store.ArchiveSchema)
+		return err
+	}},
+	{4, "create audit_log", func(tx *sql.Tx) error {
+		_, err := tx.Exec(auditSchema)
+		return err
+	}},
+}
+
+// AppliedMigration records a migration that ran
+type AppliedMigration struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	AppliedAt string `json:"appliedAt"`
+}
+
+// schemaState is persisted in schema.json
+type schemaState struct {
+	Version int                `json:"version"`
+	Applied []AppliedMigration `json:"applied"`
+}
+
+// SchemaStatus reports the version of the local store
+type SchemaStatus struct {
+	DataVersion    int                `json:"dataVersion"`
+	LatestData     int                `json:"latestData"`
+	HistoryVersion int                `json:"historyVersion"`
+	LatestHistory  int                `json:"latestHistory"`
+	Applied        []AppliedMigration `json:"applied"`
+	Error          string             `json:"error,omitempty"` // why the last run failed
+}
+
+// migratePortfolioBook writes the single-ledger portfolio.json of early
+// versions out as the portfolios.json book and removes it
+func migratePortfolioBook(dataDir string) error {
+	s := newPortfolioStore(dataDir)
+	if _, err := os.Stat(s.legacyPath); os.IsNotExist(err) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := saveJSON(s.path, s.book); err != nil {
+		return err
+	}
+	return os.Remove(s.legacyPath)
+}
+
+// copyFile copies src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// migrateData brings the JSON files in dataDir up to the latest version,
+// restoring the previous files if any migration fails
+func migrateData(dataDir string) error {
+	statePath := filepath.Join(dataDir, "schema.json")
+	var state schemaState
+	if err := loadJSON(statePath, &state); err != nil {
+		return err
+	}
+	latest := dataMigrations[len(dataMigrations)-1].version
+	if state.Version > latest {
+		return fmt.Errorf("data was written by a newer version (schema %d, this version knows %d)", state.Version, latest)
+	}
+	if state.Version == latest {
+		return nil
+	}
+
+	// Snapshot the JSON files so a failed run can be undone
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	snapshot, err := os.MkdirTemp(dataDir, "migrate-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(snapshot)
+	before, err := filepath.Glob(filepath.Join(dataDir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range before {
+		if err := copyFile(path, filepath.Join(snapshot, filepath.Base(path))); err != nil {
+			return err
+		}
+	}
+	rollback := func() {
+		after, _ := filepath.Glob(filepath.Join(dataDir, "*.json"))
+		for _, path := range after {
+			os.Remove(path)
+		}
+		for _, path := range before {
+			copyFile(filepath.Join(snapshot, filepath.Base(path)), path)
+		}
+	}
+
+	for _, m := range dataMigrations {
+		if m.version <= state.Version {
+			continue
+		}
+		if err := m.up(dataDir); err != nil {
+			rollback()
+			return fmt.Errorf("migration %d (%s) failed: %v", m.version, m.name, err)
+		}
+		state.Version = m.version
+		state.Applied = append(state.Applied, AppliedMigration{
+			Version:   m.version,
+			Name:      m.name,
+			AppliedAt: shanghaiNow().Format(time.RFC3339),
+		})
+	}
+	if err := saveJSON(statePath, state); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// migrateHistory brings the history database up to the latest version.
+// Each migration commits together with its version number, so a failure
+// leaves the database at the last good version.
+func migrateHistory(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	latest := historyMigrations[len(historyMigrations)-1].version
+	if version > latest {
+		return fmt.Errorf("history database was written by a newer version (schema %d, this version knows %d)", version, latest)
+	}
+	for _, m := range historyMigrations {
+		if m.version <= version {
+			continue
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("history migration %d (%s) failed: %v", m.version, m.name, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, m.version)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate runs the data migrations and remembers the outcome for
+// GetSchemaStatus
+func (a *App) migrate() {
+	err := migrateData(a.dataDir)
+	if err != nil {
+		logger.Error("data migration failed", "error", err)
+	}
+	a.stateMu.Lock()
+	a.migrationErr = err
+	a.stateMu.Unlock()
+}
+
+// GetSchemaStatus reports the schema versions of the local store and the
+// migrations applied so far
+func (a *App) GetSchemaStatus() (string, error) {
+	var state schemaState
+	if err := loadJSON(filepath.Join(a.dataDir, "schema.json"), &state); err != nil {
+		return "", err
+	}
+	status := SchemaStatus{
+		DataVersion:   state.Version,
+		LatestData:    dataMigrations[len(dataMigrations)-1].version,
+		LatestHistory: historyMigrations[len(historyMigrations)-1].version,
+		Applied:       append([]AppliedMigration{}, state.Applied...),
+	}
+	a.stateMu.Lock()
+	if a.migrationErr != nil {
+		status.Error = a.migrationErr.Error()
+	}
+	a.stateMu.Unlock()
+	db, err := localHistory.Open()
+	if err != nil {
+		status.Error = err.Error()
+	} else if err := db.QueryRow(`PRAGMA user_version`).Scan(&status.HistoryVersion); err != nil {
+		return "", err
+	}
+	return toJSON(status)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/analysis"
+	"stock-analysis/internal/indicators"
+)
+
+// A user-supplied ONNX model scores each bar from a fixed set of
+// engineered features. The app does not link an ONNX runtime; it runs a
+// runner command the user configures, such as a short Python script over
+// onnxruntime, with the model path as its last argument. The runner reads
+// a modelInput from stdin and writes a modelOutput to stdout: one score
+// per row, in order. Rows with a feature still warming up are not sent and
+// score NaN. Screens and alerts reach the scores as the ML_SCORE formula
+// variable.
+
+// modelTimeout bounds one run of the runner
+const modelTimeout = 30 * time.Second
+
+// ModelSettings configure the scoring model; empty Path disables it
+type ModelSettings struct {
+	Path   string   `json:"path"`   // .onnx file
+	Runner []string `json:"runner"` // command and arguments, e.g. ["python3", "score.py"]
+}
+
+// modelFeatures are the names of the features, in column order
+var modelFeatures = []string{
+	"return1d",       // %
+	"return5d",       // %
+	"return20d",      // %
+	"volumeRate5d",   // %
+	"turnoverRate5d", // %
+	"amplitude",      // %
+	"ma20Gap",        // close over its 20-day average, %
+	"macdHist",       // MACD histogram over the close, %
+	"atr14",          // 14-day ATR over the close, %
+	"volatility20",   // annualized 20-day volatility of returns, %
+}
+
+// modelInput is what the runner reads from stdin
+type modelInput struct {
+	Features []string    `json:"features"`
+	Rows     [][]float64 `json:"rows"`
+}
+
+// modelOutput is what the runner writes to stdout
+type modelOutput struct {
+	Scores []float64 `json:"scores"`
+}
+
+// ModelScores is the score of each bar of a symbol, one value per date
+type ModelScores struct {
+	Symbol   string     `json:"symbol"`
+	Model    string     `json:"model"`
+	Features []string   `json:"features"`
+	Dates    []string   `json:"dates"`
+	Scores   []*float64 `json:"scores"`
+	Latest   float64    `json:"latest"`
+}
+
+// normalize checks that an enabled model can be run
+func (m *ModelSettings) normalize() error {
+	m.Path = strings.TrimSpace(m.Path)
+	if m.Path == "" {
+		return nil
+	}
+	if !strings.EqualFold(filepath.Ext(m.Path), ".onnx") {
+		return codeErrorf(codeParse, "model must be an .onnx file: %s", m.Path)
+	}
+	if len(m.Runner) == 0 || strings.TrimSpace(m.Runner[0]) == "" {
+		return codeErrorf(codeParse, "a model needs a runner command")
+	}
+	return nil
+}
+
+// modelFeatureRows computes the features of each bar, one row per bar
+// with NaN while a feature warms up
+func modelFeatureRows(bars []Bar) [][]float64 {
+	n := len(bars)
+	prices := closes(bars)
+	highs, lows := highsLows(bars)
+	opens, volumes, turnovers := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, b := range bars {
+		opens[i], volumes[i], turnovers[i] = b.Open, b.Volume, b.Turnover
+	}
+	change := func(k int) []float64 {
+		prev := indicators.Ref(prices, k)
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = (prices[i]/prev[i] - 1) * 100
+		}
+		return out
+	}
+	relative := func(series []float64) []float64 {
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = series[i] / prices[i] * 100
+		}
+		return out
+	}
+	ma20 := indicators.SMA(prices, 20)
+	gap := make([]float64, n)
+	for i := range gap {
+		gap[i] = (prices[i]/ma20[i] - 1) * 100
+	}
+	_, _, hist := indicators.MACD(prices, 12, 26, 9)
+	returns := change(1)
+	volatility := make([]float64, n)
+	for i := range volatility {
+		volatility[i] = math.NaN()
+		if i >= 20 {
+			volatility[i] = analysis.StdDev(returns[i-19:i+1]) * math.Sqrt(analysis.TradingDaysPerYear)
+		}
+	}
+
+	columns := [][]float64{
+		returns,
+		change(5),
+		change(20),
+		indicators.FiveDayRate(volumes),
+		indicators.FiveDayRate(turnovers),
+		indicators.Amplitude(highs, lows, opens, prices),
+		gap,
+		relative(hist),
+		relative(indicators.ATR(highs, lows, prices, 14)),
+		volatility,
+	}
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, len(columns))
+		for j, c := range columns {
+			rows[i][j] = c[i]
+		}
+	}
+	return rows
+}
+
+// runModel scores rows with the configured model. Rows with a NaN or
+// infinite feature score NaN without being sent.
+func runModel(ctx context.Context, m ModelSettings, rows [][]float64) ([]float64, error) {
+	if m.Path == "" {
+		return nil, codeErrorf(codeNotFound, "no model is configured")
+	}
+	if _, err := os.Stat(m.Path); err != nil {
+		return nil, codeErrorf(codeNotFound, "model not found: %s", m.Path)
+	}
+	input := modelInput{Features: modelFeatures, Rows: [][]float64{}}
+	var sent []int
+	for i, row := range rows {
+		complete := true
+		for _, v := range row {
+			complete = complete && !math.IsNaN(v) && !math.IsInf(v, 0)
+		}
+		if complete {
+			input.Rows = append(input.Rows, row)
+			sent = append(sent, i)
+		}
+	}
+	scores := indicators.NaNSeries(len(rows))
+	if len(sent) == 0 {
+		return scores, nil
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, modelTimeout)
+	defer cancel()
+	args := append(append([]string(nil), m.Runner[1:]...), m.Path)
+	cmd := exec.CommandContext(ctx, m.Runner[0], args...)
+	cmd.Stdin = bytes.NewReader(body)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	started := time.Now()
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("model runner failed: %v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("model runner failed: %v", err)
+	}
+	var output modelOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, codeErrorf(codeParse, "failed to parse model output: %v", err)
+	}
+	if len(output.Scores) != len(sent) {
+		return nil, fmt.Errorf("model returned %d scores for %d rows", len(output.Scores), len(sent))
+	}
+	logger.Debug("model scored", "rows", len(sent), "elapsed", time.Since(started))
+	for k, i := range sent {
+		scores[i] = output.Scores[k]
+	}
+	return scores, nil
+}
+
+// modelScores scores bars, caching the scores of a symbol by its last bar
+// for the analysis TTL so screens do not rerun the model on every formula
+func modelScores(symbol string, bars []Bar) ([]float64, error) {
+	settings := currentSettings()
+	key := ""
+	if symbol != "" && len(bars) > 0 {
+		key = fmt.Sprintf("model:%s:%s:%s:%d", settings.Model.Path, symbol, bars[len(bars)-1].Date, len(bars))
+		if v, ok := resultCache.get(key); ok {
+			return v.([]float64), nil
+		}
+	}
+	scores, err := runModel(context.Background(), settings.Model, modelFeatureRows(bars))
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		resultCache.set(key, scores, settings.analysisTTL())
+	}
+	return scores, nil
+}
+
+// GetModelScores returns the score of the configured ONNX model for each
+// bar of symbol over the last days. days of 0 uses the configured
+// lookback and lookbackMax (-1) all available history.
+func (a *App) GetModelScores(symbol string, days int) (string, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	scores, err := modelScores(symbol, bars)
+	if err != nil {
+		return "", err
+	}
+	out := ModelScores{Symbol: symbol, Model: currentSettings().Model.Path, Features: modelFeatures, Dates: make([]string, len(bars)), Scores: nullable(scores), Latest: indicators.LastValid(scores)}
+	for i, b := range bars {
+		out.Dates[i] = b.Date
+	}
+	return toJSON(out)
+}
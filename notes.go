@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymbolNote is the user's free-form note and tags on a symbol
+type SymbolNote struct {
+	Symbol    string   `json:"symbol"`
+	Note      string   `json:"note"`
+	Tags      []string `json:"tags"`
+	UpdatedAt string   `json:"updatedAt"`
+}
+
+// noteStore persists symbol notes keyed by symbol
+type noteStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	notes  map[string]*SymbolNote
+}
+
+func newNoteStore(dataDir string) *noteStore {
+	return &noteStore{path: filepath.Join(dataDir, "symbol_notes.json")}
+}
+
+// unload forgets the notes so the next use reads the file again
+func (s *noteStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes, s.loaded = nil, false
+}
+
+// load reads the notes from disk on first use. Callers hold s.mu.
+func (s *noteStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.notes = make(map[string]*SymbolNote)
+	if err := loadJSON(s.path, &s.notes); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// lookup returns copies of the notes for symbols that have one
+func (s *noteStore) lookup(symbols []string) map[string]SymbolNote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]SymbolNote)
+	if err := s.load(); err != nil {
+		return out
+	}
+	for _, sym := range symbols {
+		if n, ok := s.notes[sym]; ok {
+			out[sym] = *n
+		}
+	}
+	return out
+}
+
+// normalizeTags trims, de-duplicates and sorts tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// GetSymbolNote returns the note and tags on symbol, empty if none
+func (a *App) GetSymbolNote(symbol string) (string, error) {
+	symbol = normalizeSymbol(symbol)
+	notes := a.notes.lookup([]string{symbol})
+	note, ok := notes[symbol]
+	if !ok {
+		note = SymbolNote{Symbol: symbol, Tags: []string{}}
+	}
+	return toJSON(note)
+}
+
+// SetSymbolNote stores a note and tags (a JSON string array) on symbol.
+// An empty note with no tags removes the entry.
+func (a *App) SetSymbolNote(symbol string, note string, tagsJSON string) error {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return codeErrorf(codeParse, "symbol is required")
+	}
+	var tags []string
+	if tagsJSON != "" {
+		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+			return codeErrorf(codeParse, "failed to parse tags: %v", err)
+		}
+	}
+	tags = normalizeTags(tags)
+	note = strings.TrimSpace(note)
+
+	s := a.notes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if note == "" && len(tags) == 0 {
+		delete(s.notes, symbol)
+	} else {
+		s.notes[symbol] = &SymbolNote{
+			Symbol:    symbol,
+			Note:      note,
+			Tags:      tags,
+			UpdatedAt: shanghaiNow().Format(time.RFC3339),
+		}
+	}
+	return saveJSON(s.path, s.notes)
+}
+
+// ListSymbolTags returns every tag in use with the symbols carrying it
+func (a *App) ListSymbolTags() (string, error) {
+	s := a.notes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	tags := make(map[string][]string)
+	for sym, n := range s.notes {
+		for _, tag := range n.Tags {
+			tags[tag] = append(tags[tag], sym)
+		}
+	}
+	for _, syms := range tags {
+		sort.Strings(syms)
+	}
+	return toJSON(tags)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Events emitted to the frontend
+const (
+	eventAlertTriggered = "alert:triggered"
+	eventOpenSymbol     = "symbol:open"
+)
+
+// notificationCommand builds the OS command that shows a desktop
+// notification. Wails v2 has no notification API, so this goes through
+// notify-send, osascript or a PowerShell toast. On Linux the command waits
+// for the notification to be clicked and prints "open".
+func notificationCommand(title, body string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		script := "display notification " + appleScriptString(body) + " with title " + appleScriptString(title)
+		return exec.Command("osascript", "-e", script)
+	case "windows":
+		script := `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName("text")
+$text.Item(0).AppendChild($xml.CreateTextNode($env:NOTIFY_TITLE)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:NOTIFY_BODY)) | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("stock-analysis").Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		// Pass the text through the environment to avoid quoting issues
+		cmd.Env = append(cmd.Environ(), "NOTIFY_TITLE="+title, "NOTIFY_BODY="+body)
+		return cmd
+	default:
+		return exec.Command("notify-send", "--app-name=stock-analysis", "--action=open=Open", "--wait", title, body)
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// showNotification displays a desktop notification and calls onClick if
+// the user clicks it. Failures are ignored: the in-app event is the
+// fallback when no notification daemon is available.
+func showNotification(title, body string, onClick func()) {
+	go func() {
+		defer recoverPanic("notification")
+		out, err := notificationCommand(title, body).Output()
+		if err == nil && strings.TrimSpace(string(out)) == "open" && onClick != nil {
+			onClick()
+		}
+	}()
+}
+
+// notifyAlerts emits an in-app event, a push message and a desktop
+// notification for each trigger. Clicking the notification brings the
+// window forward and asks the frontend to open the symbol.
+func (a *App) notifyAlerts(triggers []AlertTrigger) {
+	for _, t := range triggers {
+		a.push.publish("alert", t)
+	}
+	if a.ctx == nil {
+		return
+	}
+	for _, t := range triggers {
+		wailsruntime.EventsEmit(a.ctx, eventAlertTriggered, t)
+		symbol := t.Symbol
+		showNotification("stock-analysis: "+symbol, t.Message, func() {
+			wailsruntime.WindowShow(a.ctx)
+			wailsruntime.WindowUnminimise(a.ctx)
+			wailsruntime.EventsEmit(a.ctx, eventOpenSymbol, symbol)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// offlineProbeInterval is how often a request is let through to check
+// whether the network is back after an automatic switch to offline mode
+const offlineProbeInterval = time.Minute
+
+const eventNetworkStatus = "network:status"
+
+// errOffline is returned instead of making a request in offline mode
+var errOffline error = &codedError{codeOffline, errors.New("offline mode: network requests are disabled")}
+
+// NetworkStatus reports whether data is served from the local store
+type NetworkStatus struct {
+	Offline    bool   `json:"offline"`
+	Forced     bool   `json:"forced"`   // switched on by the user
+	Detected   bool   `json:"detected"` // switched on after a network failure
+	LastOnline string `json:"lastOnline,omitempty"`
+}
+
+// networkState tracks the explicit and the auto-detected offline mode
+type networkState struct {
+	mu         sync.Mutex
+	forced     bool
+	detected   bool
+	lastOnline time.Time
+	lastProbe  time.Time
+	onChange   func(NetworkStatus)
+}
+
+// connectivity is shared by every provider request
+var connectivity = &networkState{}
+
+// statusLocked returns the current status. Callers hold n.mu.
+func (n *networkState) statusLocked() NetworkStatus {
+	status := NetworkStatus{
+		Offline:  n.forced || n.detected,
+		Forced:   n.forced,
+		Detected: n.detected,
+	}
+	if !n.lastOnline.IsZero() {
+		status.LastOnline = n.lastOnline.Format(time.RFC3339)
+	}
+	return status
+}
+
+// status returns the current network status
+func (n *networkState) status() NetworkStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.statusLocked()
+}
+
+// offline reports whether responses come from the local store
+func (n *networkState) offline() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.forced || n.detected
+}
+
+// allow reports whether a request may go out. While offline was detected
+// automatically one request per probe interval is let through.
+func (n *networkState) allow() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.forced {
+		return false
+	}
+	if n.detected && time.Since(n.lastProbe) < offlineProbeInterval {
+		return false
+	}
+	n.lastProbe = time.Now()
+	return true
+}
+
+// report records the outcome of a request, switching offline mode on after
+// a network failure and off after a success
+func (n *networkState) report(err error) {
+	n.mu.Lock()
+	before := n.forced || n.detected
+	if err == nil {
+		n.detected = false
+		n.lastOnline = time.Now()
+	} else if isNetworkError(err) {
+		n.detected = true
+	}
+	after := n.forced || n.detected
+	status, notify := n.statusLocked(), n.onChange
+	n.mu.Unlock()
+
+	if before != after {
+		logger.Info("network status changed", "offline", after, "error", err)
+		if notify != nil {
+			notify(status)
+		}
+	}
+}
+
+// setForced switches the explicit offline mode
+func (n *networkState) setForced(forced bool) {
+	n.mu.Lock()
+	n.forced = forced
+	status, notify := n.statusLocked(), n.onChange
+	n.mu.Unlock()
+
+	if notify != nil {
+		notify(status)
+	}
+}
+
+// isNetworkError reports whether err means the provider could not be
+// reached, as opposed to a bad response
+func isNetworkError(err error) bool {
+	// A canceled request says nothing about the network
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// Nor does an error the app raised itself, like a missing fixture
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// staleAsOf returns date when data is being served offline, and "" when
+// it is live
+func staleAsOf(date string) string {
+	if connectivity.offline() {
+		return date
+	}
+	return ""
+}
+
+// offlineStockData renders stored bars of symbol in the Sohu hisHq format
+// GetStockData returns, with a staleAsOf field naming the last bar
+func offlineStockData(symbol string, start, end time.Time, cause error) (string, error) {
+	bars, err := localHistory.load(symbol, start, end)
+	if err != nil || len(bars) == 0 {
+		return "", cause
+	}
+	pct := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) + "%" }
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	hq := make([][]string, 0, len(bars))
+	for i := len(bars) - 1; i >= 0; i-- {
+		b := bars[i]
+		hq = append(hq, []string{b.Date, num(b.Open), num(b.Close), num(b.Change), pct(b.ChangePct),
+			num(b.Low), num(b.High), num(b.Volume), num(b.Turnover), pct(b.TurnoverRate)})
+	}
+	data, err := json.Marshal([]map[string]interface{}{{
+		"status":    0,
+		"code":      symbol,
+		"hq":        hq,
+		"staleAsOf": bars[len(bars)-1].Date,
+	}})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(data), nil
+}
+
+// GetNetworkStatus reports whether the app is serving data offline
+func (a *App) GetNetworkStatus() (string, error) {
+	return toJSON(connectivity.status())
+}
+
+// SetOfflineMode switches the explicit offline mode. While it is on every
+// endpoint serves from the local store.
+func (a *App) SetOfflineMode(offline bool) {
+	connectivity.setForced(offline)
+}
+
+// watchNetworkStatus emits an event whenever offline mode changes
+func (a *App) watchNetworkStatus() {
+	connectivity.mu.Lock()
+	defer connectivity.mu.Unlock()
+	connectivity.onChange = func(status NetworkStatus) {
+		wailsruntime.EventsEmit(a.ctx, eventNetworkStatus, status)
+	}
+}
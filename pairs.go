@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+
+	"stock-analysis/internal/analysis"
+	"stock-analysis/internal/indicators"
+	"stock-analysis/internal/providers"
+)
+
+// Two stocks driven by the same business tend to move together, and a
+// pair trade bets on the gap between them closing again. The gap is the
+// spread log(A) - h*log(B) - c, with the hedge ratio h and constant c
+// fitted by least squares. It only closes reliably when the spread is
+// stationary, which the Engle-Granger test checks: an augmented
+// Dickey-Fuller test on the spread against the critical values for
+// cointegration residuals. The spread is traded on its z-score over a
+// rolling window: opened beyond the entry band, closed inside the exit
+// band or beyond the stop band. The analysis fits the hedge ratio over the
+// whole range; the backtest refits it each session on the sessions before
+// it, so no trade depends on a later price.
+
+const (
+	pairWindow = 60  // default z-score window, sessions
+	pairEntry  = 2   // default entry band, z
+	pairExit   = 0.5 // default exit band, z
+	pairStop   = 3.5 // default stop band, z
+	pairLags   = 1   // lagged differences of the ADF test
+)
+
+// engleGrangerCritical are MacKinnon's critical values of the ADF
+// statistic on the residuals of a two-variable cointegrating regression
+// with a constant, at 1%, 5% and 10%
+var engleGrangerCritical = [3]float64{-3.90, -3.34, -3.05}
+
+// PairRequest selects a pair, its range and the bands
+type PairRequest struct {
+	SymbolA string  `json:"symbolA"`
+	SymbolB string  `json:"symbolB"`
+	Days    int     `json:"days"`   // 0 for the configured lookback, lookbackMax (-1) for all history
+	Window  int     `json:"window"` // z-score window, sessions
+	Entry   float64 `json:"entry"`  // z of the entry band
+	Exit    float64 `json:"exit"`   // z of the exit band
+	Stop    float64 `json:"stop"`   // z of the stop band
+}
+
+// PairBand is a band of the z-score with the price of A that reaches it
+// at the latest price of B
+type PairBand struct {
+	Name   string  `json:"name"` // "stop", "entry", "exit", "mean" with "upper" or "lower"
+	Z      float64 `json:"z"`
+	PriceA float64 `json:"priceA"`
+}
+
+// PairAnalysis is the spread of a pair and its cointegration test
+type PairAnalysis struct {
+	SymbolA      string     `json:"symbolA"`
+	SymbolB      string     `json:"symbolB"`
+	Dates        []string   `json:"dates"`
+	Ratio        []float64  `json:"ratio"`  // price of A over price of B
+	Spread       []float64  `json:"spread"` // log(A) - h*log(B) - c
+	ZScore       []*float64 `json:"zScore"` // of the spread over the window
+	HedgeRatio   float64    `json:"hedgeRatio"`
+	Intercept    float64    `json:"intercept"`
+	Correlation  float64    `json:"correlation"` // of daily returns
+	ADF          float64    `json:"adf"`
+	Critical     [3]float64 `json:"critical"` // at 1%, 5% and 10%
+	Cointegrated bool       `json:"cointegrated"`
+	HalfLife     float64    `json:"halfLife"` // sessions for a gap to halve; 0 if the spread does not revert
+	LatestZ      float64    `json:"latestZ"`  // 0 if the spread was flat over the last window
+	Bands        []PairBand `json:"bands"`    // empty if the spread was flat over the last window
+}
+
+// PairTrade is a round trip of the spread
+type PairTrade struct {
+	Side       string  `json:"side"` // "long" buys A and sells B, "short" the reverse
+	EntryDate  string  `json:"entryDate"`
+	EntryZ     float64 `json:"entryZ"`
+	ExitDate   string  `json:"exitDate"`
+	ExitZ      float64 `json:"exitZ"`
+	ReturnPct  float64 `json:"returnPct"` // on the gross exposure
+	Sessions   int     `json:"sessions"`
+	ExitReason string  `json:"exitReason"` // "exit", "stop" or "end"
+}
+
+// PairBacktest is the result of trading the spread on its bands
+type PairBacktest struct {
+	TradingFrom    string        `json:"tradingFrom"` // first session with a fitted spread and its z-score
+	Trades         []PairTrade   `json:"trades"`
+	Equity         []EquityPoint `json:"equity"` // growth of 1
+	TotalReturnPct float64       `json:"totalReturnPct"`
+	MaxDrawdownPct float64       `json:"maxDrawdownPct"`
+	WinRate        float64       `json:"winRate"`
+}
+
+// normalize fills in the defaults and checks the bands
+func (r *PairRequest) normalize() error {
+	r.SymbolA, r.SymbolB = normalizeSymbol(r.SymbolA), normalizeSymbol(r.SymbolB)
+	if r.SymbolA == "" || r.SymbolB == "" {
+		return codeErrorf(codeParse, "two symbols are required")
+	}
+	if providers.SohuCode(r.SymbolA) == providers.SohuCode(r.SymbolB) {
+		return codeErrorf(codeParse, "a pair needs two different symbols")
+	}
+	if r.Window == 0 {
+		r.Window = pairWindow
+	}
+	if r.Entry == 0 {
+		r.Entry = pairEntry
+	}
+	if r.Exit == 0 {
+		r.Exit = pairExit
+	}
+	if r.Stop == 0 {
+		r.Stop = pairStop
+	}
+	if r.Window < 10 {
+		return codeErrorf(codeParse, "z-score window must be at least 10 sessions")
+	}
+	if r.Exit < 0 || r.Exit >= r.Entry || r.Entry >= r.Stop {
+		return codeErrorf(codeParse, "bands must widen from exit to entry to stop")
+	}
+	return nil
+}
+
+// pairAnalysis fits and tests the spread of the aligned closes a and b
+func pairAnalysis(req PairRequest, dates []string, a, b []float64) (PairAnalysis, error) {
+	if len(dates) < req.Window+10 {
+		return PairAnalysis{}, codeErrorf(codeNoData, "%d shared sessions are too few for a %d-session window", len(dates), req.Window)
+	}
+	p := PairAnalysis{SymbolA: req.SymbolA, SymbolB: req.SymbolB, Dates: dates, Critical: engleGrangerCritical}
+	logA, logB := make([]float64, len(a)), make([]float64, len(b))
+	p.Ratio = make([]float64, len(a))
+	for i := range a {
+		if a[i] <= 0 || b[i] <= 0 {
+			return PairAnalysis{}, codeErrorf(codeParse, "non-positive price on %s", dates[i])
+		}
+		logA[i], logB[i] = math.Log(a[i]), math.Log(b[i])
+		p.Ratio[i] = a[i] / b[i]
+	}
+
+	coef, _, ok := analysis.Regress(logA, [][]float64{logB})
+	if !ok {
+		return PairAnalysis{}, codeErrorf(codeNoData, "the prices of %s do not vary", req.SymbolB)
+	}
+	p.Intercept, p.HedgeRatio = coef[0], coef[1]
+	p.Spread = make([]float64, len(a))
+	for i := range a {
+		p.Spread[i] = logA[i] - p.HedgeRatio*logB[i] - p.Intercept
+	}
+	retA, retB := analysis.SimpleReturns(a), analysis.SimpleReturns(b)
+	if sa, sb := analysis.StdDev(retA), analysis.StdDev(retB); sa > 0 && sb > 0 {
+		p.Correlation = analysis.Covariance(retA, retB) / (sa * sb)
+	}
+	p.ADF, _ = analysis.ADF(p.Spread, pairLags)
+	p.Cointegrated = p.ADF < engleGrangerCritical[1]
+
+	// Half-life from the AR(1) of the spread: ds = a + g*s_t-1
+	ds, lagged := make([]float64, len(a)-1), make([]float64, len(a)-1)
+	for i := 1; i < len(a); i++ {
+		ds[i-1], lagged[i-1] = p.Spread[i]-p.Spread[i-1], p.Spread[i-1]
+	}
+	if c, _, ok := analysis.Regress(ds, [][]float64{lagged}); ok && c[1] < 0 && c[1] > -1 {
+		p.HalfLife = -math.Ln2 / math.Log(1+c[1])
+	}
+
+	mean := indicators.SMA(p.Spread, req.Window)
+	sd := indicators.StdDev(p.Spread, req.Window)
+	z := indicators.NaNSeries(len(a))
+	for i := range z {
+		if sd[i] > 0 {
+			z[i] = (p.Spread[i] - mean[i]) / sd[i]
+		}
+	}
+	p.ZScore = nullable(z)
+	p.Bands = []PairBand{}
+	last := len(a) - 1
+	if math.IsNaN(z[last]) {
+		return p, nil
+	}
+	p.LatestZ = z[last]
+
+	// The price of A that puts the spread at each band today
+	priceA := func(zLevel float64) float64 {
+		return math.Exp(mean[last] + zLevel*sd[last] + p.Intercept + p.HedgeRatio*logB[last])
+	}
+	for _, band := range []struct {
+		name string
+		z    float64
+	}{{"stop", req.Stop}, {"entry", req.Entry}, {"exit", req.Exit}} {
+		p.Bands = append(p.Bands,
+			PairBand{Name: band.name + "Upper", Z: band.z, PriceA: priceA(band.z)},
+			PairBand{Name: band.name + "Lower", Z: -band.z, PriceA: priceA(-band.z)})
+	}
+	p.Bands = append(p.Bands, PairBand{Name: "mean", PriceA: priceA(0)})
+	return p, nil
+}
+
+// pairBacktest trades the spread of the aligned closes a and b: short it
+// above the entry band, long below, closing at the next session's close of
+// a signal. Each session's spread uses the hedge ratio and constant fitted
+// on the sessions before it, over all of them so far, and trading starts
+// once a window of them is in. Returns are on the gross exposure of 1 in
+// A and h in B, with h as fitted at the entry.
+func pairBacktest(req PairRequest, dates []string, a, b []float64) PairBacktest {
+	result := PairBacktest{Trades: []PairTrade{}}
+	logA, logB := make([]float64, len(a)), make([]float64, len(b))
+	for i := range a {
+		logA[i], logB[i] = math.Log(a[i]), math.Log(b[i])
+	}
+	// Running sums of the least-squares fit of log(A) on log(B)
+	var n, sumB, sumA, sumBB, sumAB float64
+	fit := func() (h, c float64, ok bool) {
+		if n < 2 {
+			return 0, 0, false
+		}
+		meanB, meanA := sumB/n, sumA/n
+		varB := sumBB/n - meanB*meanB
+		if varB <= 1e-12 {
+			return 0, 0, false
+		}
+		h = (sumAB/n - meanA*meanB) / varB
+		return h, meanA - h*meanB, true
+	}
+	spread := make([]float64, req.Window)
+	zAt := func(i int) float64 {
+		if i < req.Window {
+			return math.NaN()
+		}
+		h, c, ok := fit()
+		if !ok {
+			return math.NaN()
+		}
+		for k := range spread {
+			j := i - req.Window + 1 + k
+			spread[k] = logA[j] - h*logB[j] - c
+		}
+		// The same window statistics as the analysis
+		last := len(spread) - 1
+		mean, sd := indicators.SMA(spread, req.Window)[last], indicators.StdDev(spread, req.Window)[last]
+		if sd <= 0 {
+			return math.NaN()
+		}
+		return (spread[last] - mean) / sd
+	}
+
+	equity, pos := 1.0, 0
+	var open PairTrade
+	var entryEquity, hedge float64
+	z := math.NaN()
+	for i := range dates {
+		if i > 0 && pos != 0 {
+			r := a[i]/a[i-1] - 1 - hedge*(b[i]/b[i-1]-1)
+			equity *= 1 + float64(pos)*r/(1+math.Abs(hedge))
+			open.Sessions++
+		}
+		result.Equity = append(result.Equity, EquityPoint{Date: dates[i], Equity: equity})
+		z = zAt(i)
+		h, _, _ := fit()
+		n, sumB, sumA, sumBB, sumAB = n+1, sumB+logB[i], sumA+logA[i], sumBB+logB[i]*logB[i], sumAB+logA[i]*logB[i]
+		if math.IsNaN(z) {
+			continue
+		}
+		if result.TradingFrom == "" {
+			result.TradingFrom = dates[i]
+		}
+		closeTrade := func(reason string) {
+			open.ExitDate, open.ExitZ, open.ExitReason = dates[i], z, reason
+			open.ReturnPct = (equity/entryEquity - 1) * 100
+			result.Trades = append(result.Trades, open)
+			pos = 0
+		}
+		switch {
+		case pos != 0 && math.Abs(z) >= req.Stop:
+			closeTrade("stop")
+		case pos > 0 && z >= -req.Exit, pos < 0 && z <= req.Exit:
+			closeTrade("exit")
+		case pos == 0 && math.Abs(z) >= req.Entry && math.Abs(z) < req.Stop:
+			pos, hedge = 1, h
+			open = PairTrade{Side: "long", EntryDate: dates[i], EntryZ: z}
+			if z > 0 {
+				pos = -1
+				open.Side = "short"
+			}
+			entryEquity = equity
+		}
+	}
+	if pos != 0 {
+		open.ExitDate, open.ExitReason = dates[len(dates)-1], "end"
+		if !math.IsNaN(z) {
+			open.ExitZ = z
+		}
+		open.ReturnPct = (equity/entryEquity - 1) * 100
+		result.Trades = append(result.Trades, open)
+	}
+
+	result.TotalReturnPct = (equity - 1) * 100
+	result.MaxDrawdownPct = maxDrawdownPct(result.Equity)
+	wins := 0
+	for _, t := range result.Trades {
+		if t.ReturnPct > 0 {
+			wins++
+		}
+	}
+	if len(result.Trades) > 0 {
+		result.WinRate = float64(wins) / float64(len(result.Trades)) * 100
+	}
+	return result
+}
+
+// pairPrices parses a PairRequest and loads the closes of the pair on the
+// sessions both traded
+func pairPrices(requestJSON string) (PairRequest, []string, []float64, []float64, error) {
+	var req PairRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return req, nil, nil, nil, codeErrorf(codeParse, "failed to parse pair: %v", err)
+	}
+	if err := req.normalize(); err != nil {
+		return req, nil, nil, nil, err
+	}
+	now := shanghaiNow()
+	barsA, err := fetchDailyBars(req.SymbolA, lookbackStart(req.SymbolA, req.Days, now), now)
+	if err != nil {
+		return req, nil, nil, nil, err
+	}
+	barsB, err := fetchDailyBars(req.SymbolB, lookbackStart(req.SymbolB, req.Days, now), now)
+	if err != nil {
+		return req, nil, nil, nil, err
+	}
+	dates, prices := alignCloses(map[string][]Bar{"a": barsA, "b": barsB})
+	return req, dates, prices["a"], prices["b"], nil
+}
+
+// AnalyzePair returns the price ratio and spread of two symbols with the
+// cointegration test, the z-score of the spread and the prices of A at
+// its bands. requestJSON is a PairRequest.
+func (a *App) AnalyzePair(requestJSON string) (string, error) {
+	req, dates, pa, pb, err := pairPrices(requestJSON)
+	if err != nil {
+		return "", err
+	}
+	p, err := pairAnalysis(req, dates, pa, pb)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(p)
+}
+
+// BacktestPair trades the spread of two symbols on its z-score bands and
+// returns the trades and the equity curve. requestJSON is a PairRequest.
+func (a *App) BacktestPair(requestJSON string) (string, error) {
+	req, dates, pa, pb, err := pairPrices(requestJSON)
+	if err != nil {
+		return "", err
+	}
+	if _, err := pairAnalysis(req, dates, pa, pb); err != nil {
+		return "", err
+	}
+	return toJSON(pairBacktest(req, dates, pa, pb))
+}
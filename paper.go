@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A-share trading costs applied to simulated fills
+const (
+	paperCommissionRate = 0.00025 // broker commission, both sides
+	paperMinCommission  = 5.0     // minimum commission per order in CNY
+	paperStampDutyRate  = 0.0005  // stamp duty, sells only
+	paperDefaultCash    = 1000000.0
+)
+
+// PaperPosition is a holding in the simulated account
+type PaperPosition struct {
+	Symbol  string  `json:"symbol"`
+	Shares  int     `json:"shares"`
+	AvgCost float64 `json:"avgCost"`
+}
+
+// PaperOrder is an order placed against the simulated account
+type PaperOrder struct {
+	ID         string  `json:"id"`
+	Time       string  `json:"time"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // "buy" or "sell"
+	Shares     int     `json:"shares"`
+	LimitPrice float64 `json:"limitPrice"` // 0 for market orders
+	FillPrice  float64 `json:"fillPrice"`
+	Fees       float64 `json:"fees"`
+	Status     string  `json:"status"` // "pending", "filled", "canceled" or "rejected"
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// PaperAccount is the persisted state of the simulated account
+type PaperAccount struct {
+	InitialCash float64                   `json:"initialCash"`
+	Cash        float64                   `json:"cash"`
+	Positions   map[string]*PaperPosition `json:"positions"`
+	Orders      []PaperOrder              `json:"orders"`
+	CreatedAt   string                    `json:"createdAt"`
+}
+
+// PaperPositionValue is a position marked to the latest quote
+type PaperPositionValue struct {
+	PaperPosition
+	Price         float64 `json:"price"`
+	MarketValue   float64 `json:"marketValue"`
+	UnrealizedPnL float64 `json:"unrealizedPnL"`
+	UnrealizedPct float64 `json:"unrealizedPct"`
+}
+
+// PaperAccountSummary is the account as presented to the frontend
+type PaperAccountSummary struct {
+	InitialCash float64              `json:"initialCash"`
+	Cash        float64              `json:"cash"`
+	MarketValue float64              `json:"marketValue"`
+	Equity      float64              `json:"equity"`
+	TotalPnL    float64              `json:"totalPnL"`
+	TotalPct    float64              `json:"totalPct"`
+	Positions   []PaperPositionValue `json:"positions"`
+	Orders      []PaperOrder         `json:"orders"`
+}
+
+// paperStore guards the simulated account and its file on disk
+type paperStore struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	account PaperAccount
+}
+
+func newPaperStore(dataDir string) *paperStore {
+	return &paperStore{path: filepath.Join(dataDir, "paper_account.json")}
+}
+
+// unload forgets the account so the next use reads the file again
+func (s *paperStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account, s.loaded = PaperAccount{}, false
+}
+
+// load reads the account from disk on first use. Callers hold s.mu.
+func (s *paperStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.account); err != nil {
+		return err
+	}
+	if s.account.Positions == nil {
+		s.account = newPaperAccount(paperDefaultCash)
+	}
+	s.loaded = true
+	return nil
+}
+
+func newPaperAccount(cash float64) PaperAccount {
+	return PaperAccount{
+		InitialCash: cash,
+		Cash:        cash,
+		Positions:   make(map[string]*PaperPosition),
+		CreatedAt:   shanghaiNow().Format(time.RFC3339),
+	}
+}
+
+// paperFees returns the commission plus stamp duty for a fill
+func paperFees(side string, amount float64) float64 {
+	fees := math.Max(amount*paperCommissionRate, paperMinCommission)
+	if side == "sell" {
+		fees += amount * paperStampDutyRate
+	}
+	return math.Round(fees*100) / 100
+}
+
+// fill tries to execute order at price, updating cash and positions.
+// It returns false when a limit order is not yet marketable.
+func (acct *PaperAccount) fill(order *PaperOrder, price float64) bool {
+	if order.LimitPrice > 0 {
+		if order.Side == "buy" && price > order.LimitPrice {
+			return false
+		}
+		if order.Side == "sell" && price < order.LimitPrice {
+			return false
+		}
+	}
+
+	amount := price * float64(order.Shares)
+	fees := paperFees(order.Side, amount)
+
+	switch order.Side {
+	case "buy":
+		if amount+fees > acct.Cash {
+			order.Status = "rejected"
+			order.Reason = "insufficient cash"
+			return true
+		}
+		pos := acct.Positions[order.Symbol]
+		if pos == nil {
+			pos = &PaperPosition{Symbol: order.Symbol}
+			acct.Positions[order.Symbol] = pos
+		}
+		// Fees are folded into the cost basis
+		pos.AvgCost = (pos.AvgCost*float64(pos.Shares) + amount + fees) / float64(pos.Shares+order.Shares)
+		pos.Shares += order.Shares
+		acct.Cash -= amount + fees
+	case "sell":
+		pos := acct.Positions[order.Symbol]
+		if pos == nil || pos.Shares < order.Shares {
+			order.Status = "rejected"
+			order.Reason = "insufficient shares"
+			return true
+		}
+		pos.Shares -= order.Shares
+		if pos.Shares == 0 {
+			delete(acct.Positions, order.Symbol)
+		}
+		acct.Cash += amount - fees
+	}
+
+	order.FillPrice = price
+	order.Fees = fees
+	order.Status = "filled"
+	return true
+}
+
+// fillPending checks every pending order against the latest quotes
+func (acct *PaperAccount) fillPending(quotes map[string]Quote) {
+	for i := range acct.Orders {
+		order := &acct.Orders[i]
+		if order.Status != "pending" {
+			continue
+		}
+		quote, ok := quotes[order.Symbol]
+		if !ok || quote.Price <= 0 {
+			continue
+		}
+		acct.fill(order, quote.Price)
+	}
+}
+
+// symbols returns every symbol that is held or has a pending order
+func (acct *PaperAccount) symbols() []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for sym := range acct.Positions {
+		seen[sym] = true
+		symbols = append(symbols, sym)
+	}
+	for _, order := range acct.Orders {
+		if order.Status == "pending" && !seen[order.Symbol] {
+			seen[order.Symbol] = true
+			symbols = append(symbols, order.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// fetchQuotes returns quotes for symbols, skipping any that fail to load
+func fetchQuotes(symbols []string) map[string]Quote {
+	quotes := make(map[string]Quote, len(symbols))
+	for _, sym := range symbols {
+		if quote, err := fetchQuote(sym); err == nil {
+			quotes[sym] = quote
+		}
+	}
+	return quotes
+}
+
+// summarize marks the account to market with the given quotes
+func (acct *PaperAccount) summarize(quotes map[string]Quote) PaperAccountSummary {
+	summary := PaperAccountSummary{
+		InitialCash: acct.InitialCash,
+		Cash:        acct.Cash,
+		Orders:      acct.Orders,
+		Positions:   []PaperPositionValue{},
+	}
+	for _, sym := range acct.symbols() {
+		pos, ok := acct.Positions[sym]
+		if !ok {
+			continue
+		}
+		price := pos.AvgCost
+		if quote, ok := quotes[sym]; ok && quote.Price > 0 {
+			price = quote.Price
+		}
+		value := PaperPositionValue{
+			PaperPosition: *pos,
+			Price:         price,
+			MarketValue:   price * float64(pos.Shares),
+		}
+		value.UnrealizedPnL = value.MarketValue - pos.AvgCost*float64(pos.Shares)
+		if pos.AvgCost > 0 {
+			value.UnrealizedPct = (price - pos.AvgCost) / pos.AvgCost * 100
+		}
+		summary.MarketValue += value.MarketValue
+		summary.Positions = append(summary.Positions, value)
+	}
+	summary.Equity = summary.Cash + summary.MarketValue
+	summary.TotalPnL = summary.Equity - acct.InitialCash
+	if acct.InitialCash > 0 {
+		summary.TotalPct = summary.TotalPnL / acct.InitialCash * 100
+	}
+	return summary
+}
+
+// GetPaperAccount fills any marketable pending orders and returns the
+// simulated account marked to the latest quotes
+func (a *App) GetPaperAccount() (string, error) {
+	s := a.paper
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	quotes := fetchQuotes(s.account.symbols())
+	s.account.fillPending(quotes)
+	if err := saveJSON(s.path, s.account); err != nil {
+		return "", fmt.Errorf("failed to save paper account: %v", err)
+	}
+
+	return toJSON(s.account.summarize(quotes))
+}
+
+// PlacePaperOrder places a buy or sell order in the simulated account.
+// A limitPrice of 0 places a market order filled at the latest quote.
+func (a *App) PlacePaperOrder(symbol string, side string, shares int, limitPrice float64) (string, error) {
+	side = strings.ToLower(side)
+	if side != "buy" && side != "sell" {
+		return "", codeErrorf(codeParse, "invalid order side: %s", side)
+	}
+	if shares <= 0 {
+		return "", codeErrorf(codeParse, "shares must be positive")
+	}
+	if side == "buy" && buyQuantity(symbol, shares) != shares {
+		if lot := lotSize(symbol); lot != boardLot {
+			return "", codeErrorf(codeParse, "buy orders of %s must be at least %d shares", symbol, lot)
+		}
+		return "", codeErrorf(codeParse, "buy orders must be in lots of %d shares", boardLot)
+	}
+
+	quote, err := fetchQuote(symbol)
+	if err != nil {
+		return "", err
+	}
+	if quote.Price <= 0 {
+		return "", codeErrorf(codeNoData, "no price available for %s", symbol)
+	}
+
+	s := a.paper
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	order := PaperOrder{
+		ID:         newID(),
+		Time:       shanghaiNow().Format(time.RFC3339),
+		Symbol:     symbol,
+		Side:       side,
+		Shares:     shares,
+		LimitPrice: limitPrice,
+		Status:     "pending",
+	}
+	s.account.fill(&order, quote.Price)
+	s.account.Orders = append(s.account.Orders, order)
+
+	if err := saveJSON(s.path, s.account); err != nil {
+		return "", fmt.Errorf("failed to save paper account: %v", err)
+	}
+
+	return toJSON(order)
+}
+
+// CancelPaperOrder cancels a pending order in the simulated account
+func (a *App) CancelPaperOrder(orderID string) error {
+	s := a.paper
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	for i := range s.account.Orders {
+		order := &s.account.Orders[i]
+		if order.ID != orderID {
+			continue
+		}
+		if order.Status != "pending" {
+			return fmt.Errorf("order %s is already %s", orderID, order.Status)
+		}
+		order.Status = "canceled"
+		return saveJSON(s.path, s.account)
+	}
+
+	return codeErrorf(codeNotFound, "order not found: %s", orderID)
+}
+
+// ResetPaperAccount discards all positions and orders and starts over
+// with the given cash balance
+func (a *App) ResetPaperAccount(initialCash float64) error {
+	if initialCash <= 0 {
+		initialCash = paperDefaultCash
+	}
+
+	s := a.paper
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.account = newPaperAccount(initialCash)
+	s.loaded = true
+	return saveJSON(s.path, s.account)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 portrait in points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+)
+
+// pdfFonts are the page resources. F1 and F2 are standard Helvetica, F3 is
+// the Adobe STSong-Light CJK font every PDF viewer with the Asian font pack
+// provides, used for runs of non-ASCII text.
+const pdfFonts = `<< /F1 5 0 R /F2 6 0 R /F3 7 0 R >>`
+
+// pdfPage accumulates the content stream of one page
+type pdfPage struct {
+	b bytes.Buffer
+	y float64 // baseline of the next line, from the bottom
+}
+
+// text draws s at x, y. Bold selects Helvetica-Bold for ASCII runs.
+func (p *pdfPage) text(x, y, size float64, bold bool, s string) {
+	for _, run := range splitASCII(s) {
+		if run.ascii {
+			font := "F1"
+			if bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&p.b, "BT /%s %g Tf %.1f %.1f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(run.text))
+			x += float64(len(run.text)) * size * 0.52
+			continue
+		}
+		var hex strings.Builder
+		n := 0
+		for _, u := range utf16.Encode([]rune(run.text)) {
+			fmt.Fprintf(&hex, "%04X", u)
+			n++
+		}
+		fmt.Fprintf(&p.b, "BT /F3 %g Tf %.1f %.1f Td <%s> Tj ET\n", size, x, y, hex.String())
+		x += float64(n) * size
+	}
+}
+
+// line draws a text line at the left margin and moves down
+func (p *pdfPage) line(size float64, bold bool, s string) {
+	p.text(pdfMargin, p.y, size, bold, s)
+	p.y -= size * 1.5
+}
+
+// columns draws cells at the given x offsets from the left margin and
+// moves down
+func (p *pdfPage) columns(size float64, offsets []float64, cells ...string) {
+	for i, c := range cells {
+		p.text(pdfMargin+offsets[i], p.y, size, false, c)
+	}
+	p.y -= size * 1.5
+}
+
+// chart draws the series as a line chart whose top edge is at p.y
+func (p *pdfPage) chart(series []chartSeries, height float64) {
+	w := pdfPageWidth - 2*pdfMargin
+	f := newChartFrame(series, w, height)
+	bottom := p.y - height
+	fmt.Fprintf(&p.b, "0.8 G 0.5 w %.1f %.1f %.1f %.1f re S\n", pdfMargin, bottom, w, height)
+	for _, s := range series {
+		r, g, b := hexColor(s.Color)
+		fmt.Fprintf(&p.b, "%.3f %.3f %.3f RG 1 w\n", r, g, b)
+		started := false
+		for i, v := range s.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			op := "l"
+			if !started {
+				op, started = "m", true
+			}
+			fmt.Fprintf(&p.b, "%.1f %.1f %s\n", pdfMargin+f.x(i), bottom+f.y(v), op)
+		}
+		if started {
+			p.b.WriteString("S\n")
+		}
+	}
+	p.b.WriteString("0 G\n")
+	p.text(pdfMargin+2, p.y-10, 8, false, fmt.Sprintf("%.2f", f.max))
+	p.text(pdfMargin+2, bottom+3, 8, false, fmt.Sprintf("%.2f", f.min))
+	p.y = bottom - 16
+}
+
+// textRun is a stretch of text that is entirely ASCII or entirely not
+type textRun struct {
+	text  string
+	ascii bool
+}
+
+func splitASCII(s string) []textRun {
+	var runs []textRun
+	for _, r := range s {
+		ascii := r < 128
+		if n := len(runs); n > 0 && runs[n-1].ascii == ascii {
+			runs[n-1].text += string(r)
+		} else {
+			runs = append(runs, textRun{string(r), ascii})
+		}
+	}
+	return runs
+}
+
+// pdfEscape escapes a PDF literal string
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
+
+// hexColor parses #rrggbb into PDF color components
+func hexColor(c string) (r, g, b float64) {
+	var ri, gi, bi int
+	fmt.Sscanf(strings.TrimPrefix(c, "#"), "%02x%02x%02x", &ri, &gi, &bi)
+	return float64(ri) / 255, float64(gi) / 255, float64(bi) / 255
+}
+
+// buildPDF assembles pages into a PDF file
+func buildPDF(pages []*pdfPage) []byte {
+	// Objects 1-3 are the catalog, page tree and info dictionary, 4 is the
+	// CJK font descriptor and 5-8 are the fonts. Each page and its content
+	// stream follow from 9.
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 9+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Producer (stock-analysis) >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [8 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 4 0 R /DW 1000 >>",
+	)
+	for i, p := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font %s >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, pdfFonts, 10+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.b.Len(), p.b.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdf renders the report as a PDF document
+func (r AnalysisReport) pdf() []byte {
+	var pages []*pdfPage
+	newPage := func() *pdfPage {
+		p := &pdfPage{y: pdfPageHeight - pdfMargin - 12}
+		pages = append(pages, p)
+		return p
+	}
+	p := newPage()
+
+	p.line(18, true, strings.TrimSpace(r.Symbol+" "+r.Name))
+	meta := r.Stats.From + " - " + r.Stats.To + "   " + tr("report.generated", r.GeneratedAt)
+	if r.Industry != "" {
+		meta = r.Industry + "   " + meta
+	}
+	p.line(9, false, meta)
+	p.y -= 8
+
+	s := r.Stats
+	cols := []float64{0, 130, 260, 390}
+	p.line(12, true, tr("report.summary"))
+	p.columns(10, cols, tr("report.sessions"), fmt.Sprint(s.Sessions), tr("report.return"), fmt.Sprintf("%.2f%%", s.Return))
+	p.columns(10, cols, tr("report.firstClose"), fmt.Sprintf("%.2f", s.FirstClose), tr("report.lastClose"), fmt.Sprintf("%.2f", s.LastClose))
+	p.columns(10, cols, tr("report.high"), fmt.Sprintf("%.2f", s.High), tr("report.low"), fmt.Sprintf("%.2f", s.Low))
+	p.columns(10, cols, tr("report.avgVolume"), fmt.Sprintf("%.0f", s.AvgVolume), tr("report.volatility"), fmt.Sprintf("%.2f%%", s.Volatility))
+	p.columns(10, cols, tr("report.maxDrawdown"), fmt.Sprintf("%.2f%%", s.MaxDrawdown))
+	p.y -= 8
+
+	legend := make([]string, len(r.series))
+	for i, sr := range r.series {
+		legend[i] = sr.Name
+	}
+	p.line(12, true, tr("report.chart")+" ("+strings.Join(legend, ", ")+")")
+	p.chart(r.series, 220)
+
+	p.line(12, true, tr("report.indicators"))
+	for i := 0; i < len(r.Indicators); i += 2 {
+		cells := []string{r.Indicators[i].Name, fmt.Sprintf("%.2f", r.Indicators[i].Value)}
+		if i+1 < len(r.Indicators) {
+			cells = append(cells, r.Indicators[i+1].Name, fmt.Sprintf("%.2f", r.Indicators[i+1].Value))
+		}
+		p.columns(10, cols, cells...)
+	}
+	p.y -= 8
+
+	p.line(12, true, tr("report.signals"))
+	if len(r.Signals) == 0 {
+		p.line(10, false, tr("report.noSignals"))
+	}
+	for _, sig := range r.Signals {
+		if p.y < pdfMargin {
+			p = newPage()
+		}
+		p.columns(9, []float64{0, 80, 200}, sig.Date, sig.Type, sig.Message)
+	}
+	return buildPDF(pages)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// PerformancePoint is one day of the portfolio/benchmark comparison
+type PerformancePoint struct {
+	Date      string  `json:"date"`
+	Value     float64 `json:"value"`
+	Portfolio float64 `json:"portfolio"` // cumulative time-weighted return, %
+	Benchmark float64 `json:"benchmark"` // cumulative benchmark return, %
+}
+
+// PeriodReturn compares returns over a reporting period
+type PeriodReturn struct {
+	Period    string  `json:"period"` // "MTD", "QTD", "YTD" or "ITD"
+	Start     string  `json:"start"`
+	Portfolio float64 `json:"portfolio"`
+	Benchmark float64 `json:"benchmark"`
+	Excess    float64 `json:"excess"`
+}
+
+// PerformanceReport is the time-weighted return of the portfolio against
+// a benchmark index
+type PerformanceReport struct {
+	Benchmark string             `json:"benchmark"`
+	Series    []PerformancePoint `json:"series"`
+	Periods   []PeriodReturn     `json:"periods"`
+}
+
+// twrSeries computes the daily time-weighted return index of the ledger
+// on the given trading dates. closes maps symbol to date to close price.
+// Buys are assumed to happen at the start of the day and sells at its
+// end, so the proceeds of a sale count toward that day's value; dividends
+// count as return.
+func twrSeries(p *Portfolio, dates []string, closes map[string]map[string]float64) (values, index []float64) {
+	txs := p.sortedTransactions()
+	shares := make(map[string]float64)
+	lastClose := make(map[string]float64)
+	values = make([]float64, len(dates))
+	index = make([]float64, len(dates))
+
+	next := 0
+	prevValue, level := 0.0, 1.0
+	for d, date := range dates {
+		inflow, outflow, income := 0.0, 0.0, 0.0
+		// Apply every transaction up to and including this trading day;
+		// trades on non-trading days roll into the next session
+		for next < len(txs) && txs[next].Date <= date {
+			tx := txs[next]
+			switch tx.Type {
+			case "buy":
+				shares[tx.Symbol] += tx.Shares
+				inflow += tx.Shares*tx.Price + tx.Fees
+				lastClose[tx.Symbol] = tx.Price
+			case "sell":
+				shares[tx.Symbol] -= tx.Shares
+				outflow += tx.Shares*tx.Price - tx.Fees
+			case "dividend":
+				income += tx.Shares*tx.Price - tx.Fees
+			case "bonus":
+				shares[tx.Symbol] += tx.Shares
+			}
+			next++
+		}
+
+		value := 0.0
+		for sym, n := range shares {
+			if c, ok := closes[sym][date]; ok && c > 0 {
+				lastClose[sym] = c // suspended stocks keep their last price
+			}
+			value += n * lastClose[sym]
+		}
+
+		if base := prevValue + inflow; base > 0 {
+			level *= (value + outflow + income) / base
+		}
+		values[d] = value
+		index[d] = level
+		prevValue = value
+	}
+	return values, index
+}
+
+// periodStart returns the first day of the month, quarter or year of t
+func periodStart(period string, t time.Time) string {
+	switch period {
+	case "MTD":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Format("2006-01-02")
+	case "QTD":
+		q := (int(t.Month()) - 1) / 3
+		return time.Date(t.Year(), time.Month(q*3+1), 1, 0, 0, 0, 0, t.Location()).Format("2006-01-02")
+	case "YTD":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location()).Format("2006-01-02")
+	}
+	return ""
+}
+
+// periodReturn returns the growth of index from the last point before
+// start to the final point, in %
+func periodReturn(dates []string, index []float64, start string) float64 {
+	if len(index) == 0 {
+		return 0
+	}
+	base := 1.0
+	for i, date := range dates {
+		if date >= start {
+			break
+		}
+		base = index[i]
+	}
+	if base == 0 {
+		return 0
+	}
+	return (index[len(index)-1]/base - 1) * 100
+}
+
+// GetPortfolioPerformance returns the daily time-weighted return of the
+// portfolio against benchmark (defaults to the Shanghai Composite) since
+// the first transaction, with MTD/QTD/YTD/inception breakdowns
+func (a *App) GetPortfolioPerformance(benchmark string) (string, error) {
+	if benchmark == "" {
+		benchmark = defaultBenchmark
+	}
+
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+	txs := p.sortedTransactions()
+	if len(txs) == 0 {
+		return "", codeErrorf(codeNoData, "portfolio has no transactions")
+	}
+
+	now := shanghaiNow()
+	first, err := time.Parse("2006-01-02", txs[0].Date)
+	if err != nil {
+		return "", err
+	}
+	// Start a little early so the first trade date has a prior close
+	start := first.AddDate(0, 0, -7)
+
+	benchBars, err := fetchDailyBars(benchmark, start, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to get benchmark data: %w", err)
+	}
+
+	closeMap := make(map[string]map[string]float64)
+	for _, tx := range txs {
+		if _, ok := closeMap[tx.Symbol]; ok {
+			continue
+		}
+		bars, err := fetchDailyBars(tx.Symbol, start, now)
+		if err != nil {
+			return "", fmt.Errorf("failed to get stock data for %s: %v", tx.Symbol, err)
+		}
+		m := make(map[string]float64, len(bars))
+		for _, bar := range bars {
+			m[bar.Date] = bar.Close
+		}
+		closeMap[tx.Symbol] = m
+	}
+
+	var dates []string
+	var bench []float64
+	for _, bar := range benchBars {
+		if bar.Date >= txs[0].Date {
+			dates = append(dates, bar.Date)
+			bench = append(bench, bar.Close)
+		}
+	}
+	if len(dates) == 0 {
+		return "", codeErrorf(codeNoData, "no trading days since %s", txs[0].Date)
+	}
+	// Benchmark returns start from the close before the first trade
+	benchBase := bench[0]
+	for _, bar := range benchBars {
+		if bar.Date < txs[0].Date {
+			benchBase = bar.Close
+		}
+	}
+	benchIndex := make([]float64, len(bench))
+	for i, c := range bench {
+		benchIndex[i] = c / benchBase
+	}
+
+	values, index := twrSeries(&p, dates, closeMap)
+	report := PerformanceReport{Benchmark: benchmark, Series: make([]PerformancePoint, len(dates))}
+	for i, date := range dates {
+		report.Series[i] = PerformancePoint{
+			Date:      date,
+			Value:     values[i],
+			Portfolio: (index[i] - 1) * 100,
+			Benchmark: (benchIndex[i] - 1) * 100,
+		}
+	}
+
+	lastDate, err := time.Parse("2006-01-02", dates[len(dates)-1])
+	if err != nil {
+		lastDate = now
+	}
+	for _, period := range []string{"MTD", "QTD", "YTD", "ITD"} {
+		start := periodStart(period, lastDate)
+		if period == "ITD" {
+			start = dates[0]
+		}
+		pr := PeriodReturn{
+			Period:    period,
+			Start:     start,
+			Portfolio: periodReturn(dates, index, start),
+			Benchmark: periodReturn(dates, benchIndex, start),
+		}
+		pr.Excess = pr.Portfolio - pr.Benchmark
+		report.Periods = append(report.Periods, pr)
+	}
+
+	return toJSON(report)
+}
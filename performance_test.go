@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTWRSeries(t *testing.T) {
+	dates := []string{"2024-09-02", "2024-09-03", "2024-09-04"}
+	tests := []struct {
+		name   string
+		txs    []Transaction
+		closes map[string]float64 // closes of 600519 on the three dates
+		want   []float64
+	}{
+		{
+			name:   "hold",
+			txs:    []Transaction{{Date: "2024-09-02", Symbol: "600519", Type: "buy", Shares: 100, Price: 10}},
+			closes: map[string]float64{"2024-09-02": 10, "2024-09-03": 11, "2024-09-04": 9.9},
+			want:   []float64{1, 1.1, 0.99},
+		},
+		{
+			// A full exit above the previous close keeps the day's gain
+			name: "full exit",
+			txs: []Transaction{
+				{Date: "2024-09-02", Symbol: "600519", Type: "buy", Shares: 100, Price: 10},
+				{Date: "2024-09-03", Symbol: "600519", Type: "sell", Shares: 100, Price: 11},
+			},
+			closes: map[string]float64{"2024-09-02": 10, "2024-09-03": 11.5, "2024-09-04": 12},
+			want:   []float64{1, 1.1, 1.1},
+		},
+		{
+			name: "partial exit",
+			txs: []Transaction{
+				{Date: "2024-09-02", Symbol: "600519", Type: "buy", Shares: 200, Price: 10},
+				{Date: "2024-09-03", Symbol: "600519", Type: "sell", Shares: 100, Price: 11},
+			},
+			closes: map[string]float64{"2024-09-02": 10, "2024-09-03": 11, "2024-09-04": 12.1},
+			want:   []float64{1, 1.1, 1.21},
+		},
+		{
+			name: "dividend",
+			txs: []Transaction{
+				{Date: "2024-09-02", Symbol: "600519", Type: "buy", Shares: 100, Price: 10},
+				{Date: "2024-09-03", Symbol: "600519", Type: "dividend", Shares: 100, Price: 0.5},
+			},
+			closes: map[string]float64{"2024-09-02": 10, "2024-09-03": 10, "2024-09-04": 10},
+			want:   []float64{1, 1.05, 1.05},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Portfolio{Transactions: tt.txs}
+			_, index := twrSeries(p, dates, map[string]map[string]float64{"600519": tt.closes})
+			for i, want := range tt.want {
+				if math.Abs(index[i]-want) > 1e-9 {
+					t.Errorf("%s: index %v, want %v", dates[i], index[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transaction is a single trade recorded in the portfolio ledger
+type Transaction struct {
+	ID     string  `json:"id"`
+	Date   string  `json:"date"` // YYYY-MM-DD
+	Symbol string  `json:"symbol"`
+	Type   string  `json:"type"` // "buy", "sell", "dividend" or "bonus"
+	Shares float64 `json:"shares"`
+	Price  float64 `json:"price"` // per-share cash for dividends, 0 for bonus shares
+	Fees   float64 `json:"fees"`  // withholding tax for dividends
+	Note   string  `json:"note,omitempty"`
+}
+
+// Portfolio is the persisted transaction ledger
+type Portfolio struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Currency     string        `json:"currency"` // base currency of the account
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Holding is a position derived from the ledger using average cost
+type Holding struct {
+	Symbol      string  `json:"symbol"`
+	Shares      float64 `json:"shares"`
+	CostBasis   float64 `json:"costBasis"`
+	AvgCost     float64 `json:"avgCost"`
+	RealizedPnL float64 `json:"realizedPnL"`
+	Dividends   float64 `json:"dividends"`
+	FirstBought string  `json:"firstBought"`
+}
+
+// HoldingValuation is a holding priced with the latest quote
+type HoldingValuation struct {
+	Holding
+	Price         float64 `json:"price"`
+	QuoteDate     string  `json:"quoteDate"`
+	MarketValue   float64 `json:"marketValue"`
+	UnrealizedPnL float64 `json:"unrealizedPnL"`
+	UnrealizedPct float64 `json:"unrealizedPct"`
+	DayChange     float64 `json:"dayChange"`
+	Weight        float64 `json:"weight"`
+}
+
+// PortfolioValuation is the portfolio priced with live quotes
+type PortfolioValuation struct {
+	ID            string             `json:"id"`
+	Name          string             `json:"name"`
+	Currency      string             `json:"currency"`
+	AsOf          string             `json:"asOf"`
+	Positions     []HoldingValuation `json:"positions"`
+	CostBasis     float64            `json:"costBasis"`
+	MarketValue   float64            `json:"marketValue"`
+	UnrealizedPnL float64            `json:"unrealizedPnL"`
+	UnrealizedPct float64            `json:"unrealizedPct"`
+	RealizedPnL   float64            `json:"realizedPnL"`
+	Dividends     float64            `json:"dividends"`
+	TotalPnL      float64            `json:"totalPnL"`
+	TotalReturn   float64            `json:"totalReturnPct"`
+	DayChange     float64            `json:"dayChange"`
+	Errors        []string           `json:"errors,omitempty"`
+}
+
+// validate checks a transaction's fields before it enters the ledger
+func (t *Transaction) validate() error {
+	t.Symbol = strings.TrimSpace(t.Symbol)
+	t.Type = strings.ToLower(t.Type)
+	if t.Symbol == "" {
+		return codeErrorf(codeParse, "symbol is required")
+	}
+	if _, err := time.Parse("2006-01-02", t.Date); err != nil {
+		return codeErrorf(codeParse, "invalid transaction date: %s", t.Date)
+	}
+	switch t.Type {
+	case "buy", "sell", "dividend":
+		if t.Shares <= 0 || t.Price <= 0 {
+			return codeErrorf(codeParse, "shares and price must be positive")
+		}
+	case "bonus":
+		if t.Shares <= 0 {
+			return codeErrorf(codeParse, "bonus shares must be positive")
+		}
+	default:
+		return codeErrorf(codeParse, "unknown transaction type: %s", t.Type)
+	}
+	if t.Fees < 0 {
+		return codeErrorf(codeParse, "fees cannot be negative")
+	}
+	return nil
+}
+
+// sortedTransactions returns the ledger in date order, keeping the entry
+// order for transactions on the same day
+func (p *Portfolio) sortedTransactions() []Transaction {
+	txs := append([]Transaction(nil), p.Transactions...)
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Date < txs[j].Date })
+	return txs
+}
+
+// holdings replays the ledger and returns open positions sorted by symbol.
+// It fails if the ledger ever sells more shares than are held.
+func (p *Portfolio) holdings() ([]Holding, error) {
+	bySymbol := make(map[string]*Holding)
+	for _, tx := range p.sortedTransactions() {
+		h := bySymbol[tx.Symbol]
+		if h == nil {
+			h = &Holding{Symbol: tx.Symbol}
+			bySymbol[tx.Symbol] = h
+		}
+		switch tx.Type {
+		case "buy":
+			if h.Shares == 0 {
+				h.FirstBought = tx.Date
+			}
+			h.Shares += tx.Shares
+			h.CostBasis += tx.Shares*tx.Price + tx.Fees
+		case "sell":
+			if tx.Shares > h.Shares+1e-9 {
+				return nil, codeErrorf(codeParse, "%s: sell of %g shares on %s exceeds holding of %g", tx.Symbol, tx.Shares, tx.Date, h.Shares)
+			}
+			avg := h.CostBasis / h.Shares
+			h.RealizedPnL += tx.Shares*(tx.Price-avg) - tx.Fees
+			h.CostBasis -= tx.Shares * avg
+			h.Shares -= tx.Shares
+		case "dividend":
+			h.Dividends += tx.Shares*tx.Price - tx.Fees
+		case "bonus":
+			// 送转 shares arrive at zero cost, lowering the average cost
+			h.Shares += tx.Shares
+		}
+	}
+
+	holdings := []Holding{}
+	for _, h := range bySymbol {
+		if h.Shares > 0 {
+			h.AvgCost = h.CostBasis / h.Shares
+		}
+		holdings = append(holdings, *h)
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Symbol < holdings[j].Symbol })
+	return holdings, nil
+}
+
+// invested returns the total cash spent on purchases including fees
+func (p *Portfolio) invested() float64 {
+	total := 0.0
+	for _, tx := range p.Transactions {
+		if tx.Type == "buy" {
+			total += tx.Shares*tx.Price + tx.Fees
+		}
+	}
+	return total
+}
+
+// value prices the holdings with quotes
+func (p *Portfolio) value(holdings []Holding, quotes map[string]Quote) PortfolioValuation {
+	val := PortfolioValuation{
+		ID:        p.ID,
+		Name:      p.Name,
+		Currency:  p.Currency,
+		AsOf:      shanghaiNow().Format(time.RFC3339),
+		Positions: []HoldingValuation{},
+	}
+	for _, h := range holdings {
+		val.RealizedPnL += h.RealizedPnL
+		val.Dividends += h.Dividends
+		if h.Shares == 0 {
+			continue
+		}
+		hv := HoldingValuation{Holding: h, Price: h.AvgCost}
+		if quote, ok := quotes[h.Symbol]; ok && quote.Price > 0 {
+			hv.Price = quote.Price
+			hv.QuoteDate = quote.Date
+			hv.DayChange = quote.Change * h.Shares
+		} else {
+			val.Errors = append(val.Errors, fmt.Sprintf("no quote for %s, valued at cost", h.Symbol))
+		}
+		hv.MarketValue = hv.Price * h.Shares
+		hv.UnrealizedPnL = hv.MarketValue - h.CostBasis
+		if h.CostBasis > 0 {
+			hv.UnrealizedPct = hv.UnrealizedPnL / h.CostBasis * 100
+		}
+		val.CostBasis += h.CostBasis
+		val.MarketValue += hv.MarketValue
+		val.UnrealizedPnL += hv.UnrealizedPnL
+		val.DayChange += hv.DayChange
+		val.Positions = append(val.Positions, hv)
+	}
+	for i := range val.Positions {
+		if val.MarketValue > 0 {
+			val.Positions[i].Weight = val.Positions[i].MarketValue / val.MarketValue * 100
+		}
+	}
+	if val.CostBasis > 0 {
+		val.UnrealizedPct = val.UnrealizedPnL / val.CostBasis * 100
+	}
+	val.TotalPnL = val.UnrealizedPnL + val.RealizedPnL + val.Dividends
+	if invested := p.invested(); invested > 0 {
+		val.TotalReturn = val.TotalPnL / invested * 100
+	}
+	return val
+}
+
+// defaultPortfolioName is the name given to the first portfolio
+const defaultPortfolioName = "默认组合"
+
+// portfolioBook is the persisted set of portfolios
+type portfolioBook struct {
+	Active     string      `json:"active"`
+	Portfolios []Portfolio `json:"portfolios"`
+}
+
+// find returns the portfolio with id, or nil
+func (b *portfolioBook) find(id string) *Portfolio {
+	for i := range b.Portfolios {
+		if b.Portfolios[i].ID == id {
+			return &b.Portfolios[i]
+		}
+	}
+	return nil
+}
+
+// portfolioStore guards the portfolio ledgers and their file on disk.
+// update and snapshot work on the active portfolio.
+type portfolioStore struct {
+	mu         sync.Mutex
+	path       string
+	legacyPath string
+	loaded     bool
+	book       portfolioBook
+}
+
+func newPortfolioStore(dataDir string) *portfolioStore {
+	return &portfolioStore{
+		path:       filepath.Join(dataDir, "portfolios.json"),
+		legacyPath: filepath.Join(dataDir, "portfolio.json"),
+	}
+}
+
+// unload forgets the book so the next use reads the file again
+func (s *portfolioStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.book, s.loaded = portfolioBook{}, false
+}
+
+// load reads the ledgers from disk on first use, migrating the single
+// portfolio file of earlier versions. Callers hold s.mu.
+func (s *portfolioStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.book); err != nil {
+		return err
+	}
+	if len(s.book.Portfolios) == 0 {
+		var legacy Portfolio
+		if err := loadJSON(s.legacyPath, &legacy); err != nil {
+			return err
+		}
+		s.book.Portfolios = []Portfolio{legacy}
+	}
+	for i := range s.book.Portfolios {
+		p := &s.book.Portfolios[i]
+		if p.ID == "" {
+			p.ID = newID()
+		}
+		if p.Name == "" {
+			p.Name = defaultPortfolioName
+		}
+		p.Currency = normalizeCurrency(p.Currency)
+	}
+	if s.book.find(s.book.Active) == nil {
+		s.book.Active = s.book.Portfolios[0].ID
+	}
+	s.loaded = true
+	return nil
+}
+
+// update applies fn to the active ledger and saves it, rolling back if the
+// resulting ledger is inconsistent
+func (s *portfolioStore) update(fn func(p *Portfolio) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	p := s.book.find(s.book.Active)
+	backup := append([]Transaction(nil), p.Transactions...)
+	if err := fn(p); err != nil {
+		p.Transactions = backup
+		return err
+	}
+	if _, err := p.holdings(); err != nil {
+		p.Transactions = backup
+		return err
+	}
+	return saveJSON(s.path, s.book)
+}
+
+// updateBook applies fn to the set of portfolios and saves it
+func (s *portfolioStore) updateBook(fn func(b *portfolioBook) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := fn(&s.book); err != nil {
+		return err
+	}
+	return saveJSON(s.path, s.book)
+}
+
+// snapshot returns a copy of the active ledger
+func (s *portfolioStore) snapshot() (Portfolio, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return Portfolio{}, err
+	}
+	p := *s.book.find(s.book.Active)
+	p.Transactions = append([]Transaction(nil), p.Transactions...)
+	return p, nil
+}
+
+// snapshotAll returns copies of every ledger and the active portfolio ID
+func (s *portfolioStore) snapshotAll() ([]Portfolio, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, "", err
+	}
+	all := make([]Portfolio, len(s.book.Portfolios))
+	for i, p := range s.book.Portfolios {
+		p.Transactions = append([]Transaction(nil), p.Transactions...)
+		all[i] = p
+	}
+	return all, s.book.Active, nil
+}
+
+// PortfolioDetail is the ledger together with the derived holdings
+type PortfolioDetail struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Currency     string        `json:"currency"`
+	Transactions []Transaction `json:"transactions"`
+	Holdings     []Holding     `json:"holdings"`
+}
+
+// GetPortfolio returns the transaction ledger and current holdings of the
+// active portfolio
+func (a *App) GetPortfolio() (string, error) {
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+	holdings, err := p.holdings()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(PortfolioDetail{
+		ID:           p.ID,
+		Name:         p.Name,
+		Currency:     p.Currency,
+		Transactions: p.sortedTransactions(),
+		Holdings:     holdings,
+	})
+}
+
+// RenamePortfolio changes the active portfolio's display name
+func (a *App) RenamePortfolio(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return codeErrorf(codeParse, "portfolio name is required")
+	}
+	return a.portfolio.update(func(p *Portfolio) error {
+		p.Name = name
+		return nil
+	})
+}
+
+// AddPortfolioTransaction records a transaction and returns it with its ID
+func (a *App) AddPortfolioTransaction(txJSON string) (string, error) {
+	var tx Transaction
+	if err := json.Unmarshal([]byte(txJSON), &tx); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse transaction: %v", err)
+	}
+	if err := tx.validate(); err != nil {
+		return "", err
+	}
+	tx.ID = newID()
+
+	err := a.portfolio.update(func(p *Portfolio) error {
+		p.Transactions = append(p.Transactions, tx)
+		return nil
+	})
+	recordAudit(auditPortfolio, "add transaction", a.activePortfolio(), tx.ID+" "+tx.Type, err)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(tx)
+}
+
+// UpdatePortfolioTransaction replaces the transaction with the same ID
+func (a *App) UpdatePortfolioTransaction(txJSON string) error {
+	var tx Transaction
+	if err := json.Unmarshal([]byte(txJSON), &tx); err != nil {
+		return codeErrorf(codeParse, "failed to parse transaction: %v", err)
+	}
+	if err := tx.validate(); err != nil {
+		return err
+	}
+
+	err := a.portfolio.update(func(p *Portfolio) error {
+		for i := range p.Transactions {
+			if p.Transactions[i].ID == tx.ID {
+				p.Transactions[i] = tx
+				return nil
+			}
+		}
+		return codeErrorf(codeNotFound, "transaction not found: %s", tx.ID)
+	})
+	recordAudit(auditPortfolio, "update transaction", a.activePortfolio(), tx.ID+" "+tx.Type, err)
+	return err
+}
+
+// DeletePortfolioTransaction removes a transaction from the ledger
+func (a *App) DeletePortfolioTransaction(id string) error {
+	err := a.portfolio.update(func(p *Portfolio) error {
+		for i := range p.Transactions {
+			if p.Transactions[i].ID == id {
+				p.Transactions = append(p.Transactions[:i], p.Transactions[i+1:]...)
+				return nil
+			}
+		}
+		return codeErrorf(codeNotFound, "transaction not found: %s", id)
+	})
+	recordAudit(auditPortfolio, "delete transaction", a.activePortfolio(), id, err)
+	return err
+}
+
+// GetPortfolioValuation prices every holding with the latest quote and
+// returns P&L per position and for the whole portfolio
+func (a *App) GetPortfolioValuation() (string, error) {
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+	holdings, err := p.holdings()
+	if err != nil {
+		return "", err
+	}
+
+	symbols := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		if h.Shares > 0 {
+			symbols = append(symbols, h.Symbol)
+		}
+	}
+
+	return toJSON(p.value(holdings, fetchQuotes(symbols)))
+}
+
+// PortfolioInfo summarizes one portfolio for the account switcher
+type PortfolioInfo struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Currency     string `json:"currency"`
+	Transactions int    `json:"transactions"`
+	Active       bool   `json:"active"`
+}
+
+// ListPortfolios returns every portfolio and marks the active one
+func (a *App) ListPortfolios() (string, error) {
+	all, active, err := a.portfolio.snapshotAll()
+	if err != nil {
+		return "", err
+	}
+	infos := make([]PortfolioInfo, len(all))
+	for i, p := range all {
+		infos[i] = PortfolioInfo{
+			ID:           p.ID,
+			Name:         p.Name,
+			Currency:     p.Currency,
+			Transactions: len(p.Transactions),
+			Active:       p.ID == active,
+		}
+	}
+	return toJSON(infos)
+}
+
+// CreatePortfolio adds an empty portfolio with the given base currency
+// (defaults to CNY), makes it active and returns its ID
+func (a *App) CreatePortfolio(name string, currency string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", codeErrorf(codeParse, "portfolio name is required")
+	}
+	p := Portfolio{ID: newID(), Name: name, Currency: normalizeCurrency(currency), Transactions: []Transaction{}}
+	err := a.portfolio.updateBook(func(b *portfolioBook) error {
+		b.Portfolios = append(b.Portfolios, p)
+		b.Active = p.ID
+		return nil
+	})
+	recordAudit(auditPortfolio, "create portfolio", p.ID, p.Currency, err)
+	if err != nil {
+		return "", err
+	}
+	return p.ID, nil
+}
+
+// SelectPortfolio makes id the portfolio the other portfolio calls use
+func (a *App) SelectPortfolio(id string) error {
+	return a.portfolio.updateBook(func(b *portfolioBook) error {
+		if b.find(id) == nil {
+			return codeErrorf(codeNotFound, "portfolio not found: %s", id)
+		}
+		b.Active = id
+		return nil
+	})
+}
+
+// SetPortfolioCurrency changes the base currency of the active portfolio
+func (a *App) SetPortfolioCurrency(currency string) error {
+	err := a.portfolio.update(func(p *Portfolio) error {
+		p.Currency = normalizeCurrency(currency)
+		return nil
+	})
+	recordAudit(auditPortfolio, "set currency", a.activePortfolio(), normalizeCurrency(currency), err)
+	return err
+}
+
+// DeletePortfolio removes a portfolio and its ledger. The last portfolio
+// cannot be deleted.
+func (a *App) DeletePortfolio(id string) error {
+	err := a.portfolio.updateBook(func(b *portfolioBook) error {
+		if len(b.Portfolios) == 1 {
+			return fmt.Errorf("cannot delete the only portfolio")
+		}
+		for i := range b.Portfolios {
+			if b.Portfolios[i].ID == id {
+				b.Portfolios = append(b.Portfolios[:i], b.Portfolios[i+1:]...)
+				if b.Active == id {
+					b.Active = b.Portfolios[0].ID
+				}
+				return nil
+			}
+		}
+		return codeErrorf(codeNotFound, "portfolio not found: %s", id)
+	})
+	recordAudit(auditPortfolio, "delete portfolio", id, "", err)
+	return err
+}
+
+// AggregatedValuation combines every portfolio in a single currency
+type AggregatedValuation struct {
+	Currency      string               `json:"currency"`
+	AsOf          string               `json:"asOf"`
+	Accounts      []PortfolioValuation `json:"accounts"` // in their own currency
+	CostBasis     float64              `json:"costBasis"`
+	MarketValue   float64              `json:"marketValue"`
+	UnrealizedPnL float64              `json:"unrealizedPnL"`
+	RealizedPnL   float64              `json:"realizedPnL"`
+	Dividends     float64              `json:"dividends"`
+	TotalPnL      float64              `json:"totalPnL"`
+	DayChange     float64              `json:"dayChange"`
+	Errors        []string             `json:"errors,omitempty"`
+}
+
+// GetAggregatedValuation values every portfolio and sums them in currency
+// (defaults to CNY) using the configured exchange rates. Accounts whose
+// currency has no rate are listed but left out of the totals.
+func (a *App) GetAggregatedValuation(currency string) (string, error) {
+	currency = normalizeCurrency(currency)
+	all, _, err := a.portfolio.snapshotAll()
+	if err != nil {
+		return "", err
+	}
+
+	agg := AggregatedValuation{
+		Currency: currency,
+		AsOf:     shanghaiNow().Format(time.RFC3339),
+		Accounts: []PortfolioValuation{},
+	}
+	for i := range all {
+		p := &all[i]
+		holdings, err := p.holdings()
+		if err != nil {
+			agg.Errors = append(agg.Errors, fmt.Sprintf("%s: %v", p.Name, err))
+			continue
+		}
+		symbols := make([]string, 0, len(holdings))
+		for _, h := range holdings {
+			if h.Shares > 0 {
+				symbols = append(symbols, h.Symbol)
+			}
+		}
+		val := p.value(holdings, fetchQuotes(symbols))
+		agg.Accounts = append(agg.Accounts, val)
+		for _, e := range val.Errors {
+			agg.Errors = append(agg.Errors, p.Name+": "+e)
+		}
+
+		rate, err := a.fx.convert(1, p.Currency, currency)
+		if err != nil {
+			agg.Errors = append(agg.Errors, fmt.Sprintf("%s: %v, excluded from totals", p.Name, err))
+			continue
+		}
+		agg.CostBasis += val.CostBasis * rate
+		agg.MarketValue += val.MarketValue * rate
+		agg.UnrealizedPnL += val.UnrealizedPnL * rate
+		agg.RealizedPnL += val.RealizedPnL * rate
+		agg.Dividends += val.Dividends * rate
+		agg.TotalPnL += val.TotalPnL * rate
+		agg.DayChange += val.DayChange * rate
+	}
+	return toJSON(agg)
+}
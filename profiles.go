@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profiles keep separate settings, watchlists, portfolios, history and
+// caches side by side, e.g. for a shared computer or real versus
+// experimental portfolios. The default profile is the data directory
+// itself, so data from before profiles existed stays where it is; every
+// other profile has a directory under profiles/. The profile is chosen
+// when the app starts, from --profile or else the startup profile.
+
+// defaultProfileID is the profile that lives in the data directory itself
+const defaultProfileID = "default"
+
+// maxProfileName is the longest profile name accepted
+const maxProfileName = 40
+
+// Profile is a named set of app data
+type Profile struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// ProfileList is every profile with the one in use and the one the next
+// launch opens
+type ProfileList struct {
+	Active   string    `json:"active"`
+	Startup  string    `json:"startup"`
+	Profiles []Profile `json:"profiles"`
+}
+
+// profileIndex is the profiles file, shared by all profiles
+type profileIndex struct {
+	Profiles []Profile `json:"profiles"`
+	Startup  string    `json:"startup,omitempty"`
+}
+
+// profileStore guards the profiles file
+type profileStore struct {
+	mu   sync.Mutex
+	root string
+}
+
+// profiles is read before the app and its stores exist
+var profiles = &profileStore{root: appDataDir()}
+
+func (s *profileStore) path() string {
+	return filepath.Join(s.root, "profiles", "profiles.json")
+}
+
+// dir returns the data directory of the profile with id
+func (s *profileStore) dir(id string) string {
+	if id == defaultProfileID {
+		return s.root
+	}
+	return filepath.Join(s.root, "profiles", id)
+}
+
+// read returns the profiles file with the default profile first. Callers
+// hold s.mu.
+func (s *profileStore) read() (profileIndex, error) {
+	var index profileIndex
+	if err := loadJSON(s.path(), &index); err != nil {
+		return profileIndex{}, err
+	}
+	if len(index.Profiles) == 0 || index.Profiles[0].ID != defaultProfileID {
+		index.Profiles = append([]Profile{{ID: defaultProfileID, Name: "Default"}}, index.Profiles...)
+	}
+	if index.find(index.Startup) < 0 {
+		index.Startup = defaultProfileID
+	}
+	return index, nil
+}
+
+// find returns the position of the profile with id, or -1
+func (index profileIndex) find(id string) int {
+	for i, p := range index.Profiles {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookup returns the profile named by ref, an id or a name in any case
+func (index profileIndex) lookup(ref string) (Profile, bool) {
+	ref = strings.TrimSpace(ref)
+	for _, p := range index.Profiles {
+		if p.ID == ref || strings.EqualFold(p.Name, ref) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// checkName validates a new name for the profile with id
+func (index profileIndex) checkName(id, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", codeErrorf(codeParse, "profile name is required")
+	}
+	if len([]rune(name)) > maxProfileName {
+		return "", codeErrorf(codeParse, "profile name is longer than %d characters", maxProfileName)
+	}
+	if p, ok := index.lookup(name); ok && p.ID != id {
+		return "", codeErrorf(codeParse, "a profile named %s already exists", p.Name)
+	}
+	return name, nil
+}
+
+// resolve picks the profile to open: the one named by ref, else the
+// startup profile. An unknown ref falls back to the startup profile.
+func (s *profileStore) resolve(ref string) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.read()
+	if err != nil {
+		return Profile{ID: defaultProfileID, Name: "Default"}, err
+	}
+	if ref != "" {
+		if p, ok := index.lookup(ref); ok {
+			return p, nil
+		}
+		return index.Profiles[index.find(index.Startup)], codeErrorf(codeNotFound, "unknown profile: %s", ref)
+	}
+	return index.Profiles[index.find(index.Startup)], nil
+}
+
+// update applies fn to the profiles file and saves the result
+func (s *profileStore) update(fn func(index *profileIndex) error) (profileIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.read()
+	if err != nil {
+		return index, err
+	}
+	if err := fn(&index); err != nil {
+		return index, err
+	}
+	return index, saveJSON(s.path(), index)
+}
+
+// profileArg returns the value of --profile in args, or ""
+func profileArg(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "profile" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// list returns the profiles file as seen by the app using profile active
+func (index profileIndex) list(active string) ProfileList {
+	return ProfileList{Active: active, Startup: index.Startup, Profiles: index.Profiles}
+}
+
+// ListProfiles returns every profile, the one in use and the one the
+// next launch opens
+func (a *App) ListProfiles() (string, error) {
+	profiles.mu.Lock()
+	index, err := profiles.read()
+	profiles.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(index.list(a.profile))
+}
+
+// CreateProfile adds an empty profile and returns it. The profile starts
+// with default settings; open it with SetStartupProfile and a restart, or
+// by launching with --profile NAME.
+func (a *App) CreateProfile(name string) (string, error) {
+	p := Profile{ID: newID(), CreatedAt: shanghaiNow().Format(time.RFC3339)}
+	_, err := profiles.update(func(index *profileIndex) error {
+		var err error
+		if p.Name, err = index.checkName(p.ID, name); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(profiles.dir(p.ID), 0o755); err != nil {
+			return err
+		}
+		index.Profiles = append(index.Profiles, p)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	logger.Info("profile created", "profile", p.ID, "name", p.Name)
+	return toJSON(p)
+}
+
+// RenameProfile changes a profile's name
+func (a *App) RenameProfile(id string, name string) error {
+	_, err := profiles.update(func(index *profileIndex) error {
+		i := index.find(id)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "profile not found: %s", id)
+		}
+		checked, err := index.checkName(id, name)
+		if err != nil {
+			return err
+		}
+		index.Profiles[i].Name = checked
+		return nil
+	})
+	return err
+}
+
+// DeleteProfile removes a profile and all of its data. The default
+// profile and the one in use cannot be deleted.
+func (a *App) DeleteProfile(id string) error {
+	if id == defaultProfileID {
+		return fmt.Errorf("the default profile cannot be deleted")
+	}
+	if id == a.profile {
+		return codeErrorf(codeBusy, "the profile in use cannot be deleted")
+	}
+	_, err := profiles.update(func(index *profileIndex) error {
+		i := index.find(id)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "profile not found: %s", id)
+		}
+		if err := os.RemoveAll(profiles.dir(id)); err != nil {
+			return err
+		}
+		index.Profiles = append(index.Profiles[:i], index.Profiles[i+1:]...)
+		if index.Startup == id {
+			index.Startup = defaultProfileID
+		}
+		return nil
+	})
+	if err == nil {
+		logger.Info("profile deleted", "profile", id)
+	}
+	return err
+}
+
+// SetStartupProfile makes the next launch open the profile with id. The
+// profile in use does not change until then.
+func (a *App) SetStartupProfile(id string) (string, error) {
+	index, err := profiles.update(func(index *profileIndex) error {
+		if index.find(id) < 0 {
+			return codeErrorf(codeNotFound, "profile not found: %s", id)
+		}
+		index.Startup = id
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return toJSON(index.list(a.profile))
+}
@@ -0,0 +1,55 @@
+package main
+
+import wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+const (
+	eventFetchProgress    = "fetch:progress"
+	eventScanProgress     = "scan:progress"
+	eventBacktestProgress = "backtest:progress"
+)
+
+// Progress reports how far a long operation has got
+type Progress struct {
+	Job       string  `json:"job"`       // ID to pass to CancelJob
+	Operation string  `json:"operation"` // "sync", "screen" or "backtest"
+	Stage     string  `json:"stage,omitempty"`
+	Symbol    string  `json:"symbol,omitempty"` // the symbol being worked on
+	Done      int     `json:"done"`
+	Total     int     `json:"total"`
+	Percent   float64 `json:"percent"`
+}
+
+// progressReporter emits the progress events of one operation, at most
+// once per percentage point
+type progressReporter struct {
+	a       *App
+	event   string
+	last    int
+	current Progress
+}
+
+func (a *App) newProgress(event string, job Job, total int) *progressReporter {
+	return &progressReporter{a: a, event: event, last: -1, current: Progress{Job: job.ID, Operation: job.Kind, Total: total}}
+}
+
+// step records that done of the total units are finished
+func (r *progressReporter) step(done int, stage, symbol string) {
+	if r == nil {
+		return
+	}
+	r.current.Done = done
+	r.current.Stage = stage
+	r.current.Symbol = symbol
+	r.current.Percent = 0
+	if r.current.Total > 0 {
+		r.current.Percent = float64(done) / float64(r.current.Total) * 100
+	}
+	pct := int(r.current.Percent)
+	if pct == r.last && done != r.current.Total {
+		return
+	}
+	r.last = pct
+	if r.a != nil && r.a.ctx != nil {
+		wailsruntime.EventsEmit(r.a.ctx, r.event, r.current)
+	}
+}
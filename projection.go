@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"stock-analysis/internal/analysis"
+)
+
+// A projection extends the recent path of price and volume a few sessions
+// ahead with a classical time-series model: Holt's damped-trend
+// exponential smoothing or an ARIMA(p,1,0) with drift. Both are fitted to
+// the logarithms, so the bands are skewed like prices and never fall
+// below zero. The bands only reflect how noisy the series has been and
+// say nothing of news or regime changes, so every projection carries the
+// projection.label caption for the chart overlay.
+
+const (
+	projectionHorizon    = 10 // default sessions ahead
+	projectionMaxHorizon = 60
+	projectionARLags     = 1 // AR order of the ARIMA model
+)
+
+// Methods of a projection
+const (
+	projectionHolt  = "holt"
+	projectionARIMA = "arima"
+)
+
+// z-scores of the two-sided 80% and 95% bands
+const (
+	z80 = 1.2816
+	z95 = 1.96
+)
+
+// ProjectionBand is a projected series with its confidence bands, one
+// value per projected date
+type ProjectionBand struct {
+	Forecast []float64 `json:"forecast"`
+	Lower80  []float64 `json:"lower80"`
+	Upper80  []float64 `json:"upper80"`
+	Lower95  []float64 `json:"lower95"`
+	Upper95  []float64 `json:"upper95"`
+}
+
+// Projection is the statistical extrapolation of a symbol's closes and
+// volumes over the next sessions
+type Projection struct {
+	Symbol string             `json:"symbol"`
+	Method string             `json:"method"`
+	Label  string             `json:"label"` // caption the overlay must show
+	From   string             `json:"from"`  // first bar of the fit
+	To     string             `json:"to"`    // last bar of the fit
+	Dates  []string           `json:"dates"` // projected trading days
+	Price  ProjectionBand     `json:"price"`
+	Volume ProjectionBand     `json:"volume"`
+	Params map[string]float64 `json:"params"` // fitted parameters of the price model
+}
+
+// projectionBand turns a forecast of the logarithm of a series into bands
+// of the series, less offset
+func projectionBand(f analysis.Forecast, offset float64) ProjectionBand {
+	n := len(f.Mean)
+	b := ProjectionBand{make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)}
+	at := func(h int, z float64) float64 {
+		return math.Max(math.Exp(f.Mean[h]+z*f.StdErr[h])-offset, 0)
+	}
+	for h := 0; h < n; h++ {
+		b.Forecast[h] = at(h, 0)
+		b.Lower80[h], b.Upper80[h] = at(h, -z80), at(h, z80)
+		b.Lower95[h], b.Upper95[h] = at(h, -z95), at(h, z95)
+	}
+	return b
+}
+
+// forecastLog forecasts values with method
+func forecastLog(values []float64, method string, horizon int) (analysis.Forecast, map[string]float64, bool) {
+	switch method {
+	case projectionARIMA:
+		f, coef, ok := analysis.ARIMA(values, projectionARLags, horizon)
+		if !ok {
+			return f, nil, false
+		}
+		params := map[string]float64{"drift": coef[0]}
+		for i, c := range coef[1:] {
+			params[fmt.Sprintf("ar%d", i+1)] = c
+		}
+		return f, params, true
+	default:
+		f, p, ok := analysis.Holt(values, horizon)
+		return f, map[string]float64{"alpha": p.Alpha, "beta": p.Beta, "phi": p.Phi}, ok
+	}
+}
+
+// nextTradingDays returns the n trading days after date
+func nextTradingDays(date string, n int) []string {
+	day, err := time.ParseInLocation("2006-01-02", date, shanghaiNow().Location())
+	if err != nil {
+		return nil
+	}
+	out := make([]string, 0, n)
+	for len(out) < n {
+		day = day.AddDate(0, 0, 1)
+		if isTradingDay(day) {
+			out = append(out, day.Format("2006-01-02"))
+		}
+	}
+	return out
+}
+
+// projection extrapolates bars horizon sessions ahead with method
+func projection(symbol, method string, bars []Bar, horizon int) (Projection, error) {
+	if len(bars) < 30 {
+		return Projection{}, codeErrorf(codeNoData, "%d bars are too few to project", len(bars))
+	}
+	logPrices, logVolumes := make([]float64, len(bars)), make([]float64, len(bars))
+	for i, b := range bars {
+		if b.Close <= 0 {
+			return Projection{}, codeErrorf(codeParse, "non-positive close on %s", b.Date)
+		}
+		logPrices[i] = math.Log(b.Close)
+		logVolumes[i] = math.Log(b.Volume + 1) // suspended days trade nothing
+	}
+	price, params, ok := forecastLog(logPrices, method, horizon)
+	if !ok {
+		return Projection{}, fmt.Errorf("failed to fit the %s model to the prices", method)
+	}
+	volume, _, ok := forecastLog(logVolumes, method, horizon)
+	if !ok {
+		return Projection{}, fmt.Errorf("failed to fit the %s model to the volumes", method)
+	}
+	return Projection{
+		Symbol: symbol,
+		Method: method,
+		Label:  tr("projection.label"),
+		From:   bars[0].Date,
+		To:     bars[len(bars)-1].Date,
+		Dates:  nextTradingDays(bars[len(bars)-1].Date, horizon),
+		Price:  projectionBand(price, 0),
+		Volume: projectionBand(volume, 1),
+		Params: params,
+	}, nil
+}
+
+// GetProjection extrapolates the closes and volumes of symbol horizon
+// sessions ahead with 80% and 95% bands, fitted over the configured
+// lookback. method is "holt" (the default) or "arima"; horizon of 0 means
+// 10 sessions. The result is a statistical extrapolation for a chart
+// overlay, not a forecast to trade on.
+func (a *App) GetProjection(symbol string, horizon int, method string) (string, error) {
+	switch method {
+	case "":
+		method = projectionHolt
+	case projectionHolt, projectionARIMA:
+	default:
+		return "", codeErrorf(codeParse, "unknown projection method: %s", method)
+	}
+	if horizon == 0 {
+		horizon = projectionHorizon
+	}
+	if horizon < 1 || horizon > projectionMaxHorizon {
+		return "", codeErrorf(codeParse, "horizon must be 1 to %d sessions", projectionMaxHorizon)
+	}
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, 0, now), now)
+	if err != nil {
+		return "", err
+	}
+	p, err := projection(symbol, method, bars, horizon)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(p)
+}
@@ -0,0 +1,1152 @@
+// Service definitions of the analysis engine for typed cross-language
+// clients. The messages mirror the JSON the app's bound methods return:
+// prices in yuan, volumes in lots, percentages as 12.5 for 12.5%, dates as
+// YYYY-MM-DD in China Standard Time.
+//
+// The app serves them on the loopback interface when the grpc setting is
+// enabled; clients send the configured token as "authorization: Bearer
+// <token>" metadata. The Go stubs next to this file are generated with
+// protoc-gen-go and protoc-gen-go-grpc:
+//
+//   protoc --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. \
+//     proto/stockanalysis/v1/analysis.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: proto/stockanalysis/v1/analysis.proto
+
+package stockanalysisv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type IndicatorSpec_Kind int32
+
+const (
+	IndicatorSpec_KIND_UNSPECIFIED IndicatorSpec_Kind = 0
+	IndicatorSpec_KIND_SMA         IndicatorSpec_Kind = 1
+	IndicatorSpec_KIND_EMA         IndicatorSpec_Kind = 2
+	IndicatorSpec_KIND_STD         IndicatorSpec_Kind = 3
+	IndicatorSpec_KIND_ATR         IndicatorSpec_Kind = 4
+	IndicatorSpec_KIND_MACD        IndicatorSpec_Kind = 5
+)
+
+// Enum value maps for IndicatorSpec_Kind.
+var (
+	IndicatorSpec_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_SMA",
+		2: "KIND_EMA",
+		3: "KIND_STD",
+		4: "KIND_ATR",
+		5: "KIND_MACD",
+	}
+	IndicatorSpec_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_SMA":         1,
+		"KIND_EMA":         2,
+		"KIND_STD":         3,
+		"KIND_ATR":         4,
+		"KIND_MACD":        5,
+	}
+)
+
+func (x IndicatorSpec_Kind) Enum() *IndicatorSpec_Kind {
+	p := new(IndicatorSpec_Kind)
+	*p = x
+	return p
+}
+
+func (x IndicatorSpec_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IndicatorSpec_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_stockanalysis_v1_analysis_proto_enumTypes[0].Descriptor()
+}
+
+func (IndicatorSpec_Kind) Type() protoreflect.EnumType {
+	return &file_proto_stockanalysis_v1_analysis_proto_enumTypes[0]
+}
+
+func (x IndicatorSpec_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IndicatorSpec_Kind.Descriptor instead.
+func (IndicatorSpec_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{5, 0}
+}
+
+type Bar struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Open          float64                `protobuf:"fixed64,2,opt,name=open,proto3" json:"open,omitempty"`
+	Close         float64                `protobuf:"fixed64,3,opt,name=close,proto3" json:"close,omitempty"`
+	Change        float64                `protobuf:"fixed64,4,opt,name=change,proto3" json:"change,omitempty"`
+	ChangePct     float64                `protobuf:"fixed64,5,opt,name=change_pct,json=changePct,proto3" json:"change_pct,omitempty"`
+	Low           float64                `protobuf:"fixed64,6,opt,name=low,proto3" json:"low,omitempty"`
+	High          float64                `protobuf:"fixed64,7,opt,name=high,proto3" json:"high,omitempty"`
+	Volume        float64                `protobuf:"fixed64,8,opt,name=volume,proto3" json:"volume,omitempty"`     // lots
+	Turnover      float64                `protobuf:"fixed64,9,opt,name=turnover,proto3" json:"turnover,omitempty"` // 10k yuan
+	TurnoverRate  float64                `protobuf:"fixed64,10,opt,name=turnover_rate,json=turnoverRate,proto3" json:"turnover_rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bar) Reset() {
+	*x = Bar{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bar) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bar) ProtoMessage() {}
+
+func (x *Bar) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bar.ProtoReflect.Descriptor instead.
+func (*Bar) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Bar) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Bar) GetOpen() float64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *Bar) GetClose() float64 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+func (x *Bar) GetChange() float64 {
+	if x != nil {
+		return x.Change
+	}
+	return 0
+}
+
+func (x *Bar) GetChangePct() float64 {
+	if x != nil {
+		return x.ChangePct
+	}
+	return 0
+}
+
+func (x *Bar) GetLow() float64 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Bar) GetHigh() float64 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Bar) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Bar) GetTurnover() float64 {
+	if x != nil {
+		return x.Turnover
+	}
+	return 0
+}
+
+func (x *Bar) GetTurnoverRate() float64 {
+	if x != nil {
+		return x.TurnoverRate
+	}
+	return 0
+}
+
+type GetBarsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Symbol string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"` // e.g. 600519 or zs_000001
+	// Calendar days of history; 0 uses the configured lookback and -1 all
+	// stored history
+	Days          int32 `protobuf:"varint,2,opt,name=days,proto3" json:"days,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBarsRequest) Reset() {
+	*x = GetBarsRequest{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBarsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBarsRequest) ProtoMessage() {}
+
+func (x *GetBarsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBarsRequest.ProtoReflect.Descriptor instead.
+func (*GetBarsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetBarsRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetBarsRequest) GetDays() int32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+type GetBarsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Bars          []*Bar                 `protobuf:"bytes,2,rep,name=bars,proto3" json:"bars,omitempty"` // oldest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBarsResponse) Reset() {
+	*x = GetBarsResponse{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBarsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBarsResponse) ProtoMessage() {}
+
+func (x *GetBarsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBarsResponse.ProtoReflect.Descriptor instead.
+func (*GetBarsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetBarsResponse) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *GetBarsResponse) GetBars() []*Bar {
+	if x != nil {
+		return x.Bars
+	}
+	return nil
+}
+
+type GetQuoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuoteRequest) Reset() {
+	*x = GetQuoteRequest{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuoteRequest) ProtoMessage() {}
+
+func (x *GetQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuoteRequest.ProtoReflect.Descriptor instead.
+func (*GetQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetQuoteRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+type Quote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Date          string                 `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	Price         float64                `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	PrevClose     float64                `protobuf:"fixed64,4,opt,name=prev_close,json=prevClose,proto3" json:"prev_close,omitempty"`
+	Change        float64                `protobuf:"fixed64,5,opt,name=change,proto3" json:"change,omitempty"`
+	ChangePct     float64                `protobuf:"fixed64,6,opt,name=change_pct,json=changePct,proto3" json:"change_pct,omitempty"`
+	Volume        float64                `protobuf:"fixed64,7,opt,name=volume,proto3" json:"volume,omitempty"`
+	Turnover      float64                `protobuf:"fixed64,8,opt,name=turnover,proto3" json:"turnover,omitempty"`
+	StaleAsOf     string                 `protobuf:"bytes,9,opt,name=stale_as_of,json=staleAsOf,proto3" json:"stale_as_of,omitempty"` // set when served from the local history offline
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quote) Reset() {
+	*x = Quote{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quote) ProtoMessage() {}
+
+func (x *Quote) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quote.ProtoReflect.Descriptor instead.
+func (*Quote) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Quote) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Quote) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Quote) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Quote) GetPrevClose() float64 {
+	if x != nil {
+		return x.PrevClose
+	}
+	return 0
+}
+
+func (x *Quote) GetChange() float64 {
+	if x != nil {
+		return x.Change
+	}
+	return 0
+}
+
+func (x *Quote) GetChangePct() float64 {
+	if x != nil {
+		return x.ChangePct
+	}
+	return 0
+}
+
+func (x *Quote) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Quote) GetTurnover() float64 {
+	if x != nil {
+		return x.Turnover
+	}
+	return 0
+}
+
+func (x *Quote) GetStaleAsOf() string {
+	if x != nil {
+		return x.StaleAsOf
+	}
+	return ""
+}
+
+// IndicatorSpec selects one indicator. Periods left at 0 use the app's
+// defaults: 20 for SMA, EMA, STD and 14 for ATR; MACD uses 12, 26, 9.
+type IndicatorSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          IndicatorSpec_Kind     `protobuf:"varint,1,opt,name=kind,proto3,enum=stockanalysis.v1.IndicatorSpec_Kind" json:"kind,omitempty"`
+	Period        int32                  `protobuf:"varint,2,opt,name=period,proto3" json:"period,omitempty"`
+	Fast          int32                  `protobuf:"varint,3,opt,name=fast,proto3" json:"fast,omitempty"`     // MACD only
+	Slow          int32                  `protobuf:"varint,4,opt,name=slow,proto3" json:"slow,omitempty"`     // MACD only
+	Signal        int32                  `protobuf:"varint,5,opt,name=signal,proto3" json:"signal,omitempty"` // MACD only
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndicatorSpec) Reset() {
+	*x = IndicatorSpec{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndicatorSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndicatorSpec) ProtoMessage() {}
+
+func (x *IndicatorSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndicatorSpec.ProtoReflect.Descriptor instead.
+func (*IndicatorSpec) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IndicatorSpec) GetKind() IndicatorSpec_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return IndicatorSpec_KIND_UNSPECIFIED
+}
+
+func (x *IndicatorSpec) GetPeriod() int32 {
+	if x != nil {
+		return x.Period
+	}
+	return 0
+}
+
+func (x *IndicatorSpec) GetFast() int32 {
+	if x != nil {
+		return x.Fast
+	}
+	return 0
+}
+
+func (x *IndicatorSpec) GetSlow() int32 {
+	if x != nil {
+		return x.Slow
+	}
+	return 0
+}
+
+func (x *IndicatorSpec) GetSignal() int32 {
+	if x != nil {
+		return x.Signal
+	}
+	return 0
+}
+
+type ComputeIndicatorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Days          int32                  `protobuf:"varint,2,opt,name=days,proto3" json:"days,omitempty"` // as in GetBarsRequest
+	Indicators    []*IndicatorSpec       `protobuf:"bytes,3,rep,name=indicators,proto3" json:"indicators,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComputeIndicatorsRequest) Reset() {
+	*x = ComputeIndicatorsRequest{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComputeIndicatorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputeIndicatorsRequest) ProtoMessage() {}
+
+func (x *ComputeIndicatorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputeIndicatorsRequest.ProtoReflect.Descriptor instead.
+func (*ComputeIndicatorsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ComputeIndicatorsRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *ComputeIndicatorsRequest) GetDays() int32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+func (x *ComputeIndicatorsRequest) GetIndicators() []*IndicatorSpec {
+	if x != nil {
+		return x.Indicators
+	}
+	return nil
+}
+
+// Series is one line of values aligned with the dates of the response.
+// Values are NaN until the indicator has enough history.
+type Series struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // e.g. SMA20, or DIF, DEA and MACD for a MACD spec
+	Values        []float64              `protobuf:"fixed64,2,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Series) Reset() {
+	*x = Series{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Series) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Series) ProtoMessage() {}
+
+func (x *Series) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Series.ProtoReflect.Descriptor instead.
+func (*Series) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Series) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Series) GetValues() []float64 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type ComputeIndicatorsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Dates         []string               `protobuf:"bytes,2,rep,name=dates,proto3" json:"dates,omitempty"`
+	Series        []*Series              `protobuf:"bytes,3,rep,name=series,proto3" json:"series,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComputeIndicatorsResponse) Reset() {
+	*x = ComputeIndicatorsResponse{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComputeIndicatorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputeIndicatorsResponse) ProtoMessage() {}
+
+func (x *ComputeIndicatorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputeIndicatorsResponse.ProtoReflect.Descriptor instead.
+func (*ComputeIndicatorsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ComputeIndicatorsResponse) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *ComputeIndicatorsResponse) GetDates() []string {
+	if x != nil {
+		return x.Dates
+	}
+	return nil
+}
+
+func (x *ComputeIndicatorsResponse) GetSeries() []*Series {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+// ScreenRequest runs a saved screen, or an ad-hoc formula over a universe
+type ScreenRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Screen:
+	//
+	//	*ScreenRequest_StrategyId
+	//	*ScreenRequest_AdHoc
+	Screen        isScreenRequest_Screen `protobuf_oneof:"screen"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScreenRequest) Reset() {
+	*x = ScreenRequest{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScreenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScreenRequest) ProtoMessage() {}
+
+func (x *ScreenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScreenRequest.ProtoReflect.Descriptor instead.
+func (*ScreenRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ScreenRequest) GetScreen() isScreenRequest_Screen {
+	if x != nil {
+		return x.Screen
+	}
+	return nil
+}
+
+func (x *ScreenRequest) GetStrategyId() string {
+	if x != nil {
+		if x, ok := x.Screen.(*ScreenRequest_StrategyId); ok {
+			return x.StrategyId
+		}
+	}
+	return ""
+}
+
+func (x *ScreenRequest) GetAdHoc() *AdHocScreen {
+	if x != nil {
+		if x, ok := x.Screen.(*ScreenRequest_AdHoc); ok {
+			return x.AdHoc
+		}
+	}
+	return nil
+}
+
+type isScreenRequest_Screen interface {
+	isScreenRequest_Screen()
+}
+
+type ScreenRequest_StrategyId struct {
+	StrategyId string `protobuf:"bytes,1,opt,name=strategy_id,json=strategyId,proto3,oneof"`
+}
+
+type ScreenRequest_AdHoc struct {
+	AdHoc *AdHocScreen `protobuf:"bytes,2,opt,name=ad_hoc,json=adHoc,proto3,oneof"`
+}
+
+func (*ScreenRequest_StrategyId) isScreenRequest_Screen() {}
+
+func (*ScreenRequest_AdHoc) isScreenRequest_Screen() {}
+
+type AdHocScreen struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         string                 `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"` // formula expression, e.g. CROSS(MA(C,5),MA(C,20))
+	Universe      []string               `protobuf:"bytes,2,rep,name=universe,proto3" json:"universe,omitempty"`
+	LookbackDays  int32                  `protobuf:"varint,3,opt,name=lookback_days,json=lookbackDays,proto3" json:"lookback_days,omitempty"` // 0 uses 365 days like saved strategies
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AdHocScreen) Reset() {
+	*x = AdHocScreen{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdHocScreen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdHocScreen) ProtoMessage() {}
+
+func (x *AdHocScreen) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdHocScreen.ProtoReflect.Descriptor instead.
+func (*AdHocScreen) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AdHocScreen) GetEntry() string {
+	if x != nil {
+		return x.Entry
+	}
+	return ""
+}
+
+func (x *AdHocScreen) GetUniverse() []string {
+	if x != nil {
+		return x.Universe
+	}
+	return nil
+}
+
+func (x *AdHocScreen) GetLookbackDays() int32 {
+	if x != nil {
+		return x.LookbackDays
+	}
+	return 0
+}
+
+type ScreenMatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Industry      string                 `protobuf:"bytes,3,opt,name=industry,proto3" json:"industry,omitempty"`
+	Date          string                 `protobuf:"bytes,4,opt,name=date,proto3" json:"date,omitempty"`
+	Close         float64                `protobuf:"fixed64,5,opt,name=close,proto3" json:"close,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScreenMatch) Reset() {
+	*x = ScreenMatch{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScreenMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScreenMatch) ProtoMessage() {}
+
+func (x *ScreenMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScreenMatch.ProtoReflect.Descriptor instead.
+func (*ScreenMatch) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ScreenMatch) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *ScreenMatch) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ScreenMatch) GetIndustry() string {
+	if x != nil {
+		return x.Industry
+	}
+	return ""
+}
+
+func (x *ScreenMatch) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *ScreenMatch) GetClose() float64 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+type ScreenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Matches       []*ScreenMatch         `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScreenResponse) Reset() {
+	*x = ScreenResponse{}
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScreenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScreenResponse) ProtoMessage() {}
+
+func (x *ScreenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stockanalysis_v1_analysis_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScreenResponse.ProtoReflect.Descriptor instead.
+func (*ScreenResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ScreenResponse) GetMatches() []*ScreenMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+var File_proto_stockanalysis_v1_analysis_proto protoreflect.FileDescriptor
+
+const file_proto_stockanalysis_v1_analysis_proto_rawDesc = "" +
+	"\n" +
+	"%proto/stockanalysis/v1/analysis.proto\x12\x10stockanalysis.v1\"\xf9\x01\n" +
+	"\x03Bar\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x12\n" +
+	"\x04open\x18\x02 \x01(\x01R\x04open\x12\x14\n" +
+	"\x05close\x18\x03 \x01(\x01R\x05close\x12\x16\n" +
+	"\x06change\x18\x04 \x01(\x01R\x06change\x12\x1d\n" +
+	"\n" +
+	"change_pct\x18\x05 \x01(\x01R\tchangePct\x12\x10\n" +
+	"\x03low\x18\x06 \x01(\x01R\x03low\x12\x12\n" +
+	"\x04high\x18\a \x01(\x01R\x04high\x12\x16\n" +
+	"\x06volume\x18\b \x01(\x01R\x06volume\x12\x1a\n" +
+	"\bturnover\x18\t \x01(\x01R\bturnover\x12#\n" +
+	"\rturnover_rate\x18\n" +
+	" \x01(\x01R\fturnoverRate\"<\n" +
+	"\x0eGetBarsRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04days\x18\x02 \x01(\x05R\x04days\"T\n" +
+	"\x0fGetBarsResponse\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12)\n" +
+	"\x04bars\x18\x02 \x03(\v2\x15.stockanalysis.v1.BarR\x04bars\")\n" +
+	"\x0fGetQuoteRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\"\xf3\x01\n" +
+	"\x05Quote\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04date\x18\x02 \x01(\tR\x04date\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x01R\x05price\x12\x1d\n" +
+	"\n" +
+	"prev_close\x18\x04 \x01(\x01R\tprevClose\x12\x16\n" +
+	"\x06change\x18\x05 \x01(\x01R\x06change\x12\x1d\n" +
+	"\n" +
+	"change_pct\x18\x06 \x01(\x01R\tchangePct\x12\x16\n" +
+	"\x06volume\x18\a \x01(\x01R\x06volume\x12\x1a\n" +
+	"\bturnover\x18\b \x01(\x01R\bturnover\x12\x1e\n" +
+	"\vstale_as_of\x18\t \x01(\tR\tstaleAsOf\"\x86\x02\n" +
+	"\rIndicatorSpec\x128\n" +
+	"\x04kind\x18\x01 \x01(\x0e2$.stockanalysis.v1.IndicatorSpec.KindR\x04kind\x12\x16\n" +
+	"\x06period\x18\x02 \x01(\x05R\x06period\x12\x12\n" +
+	"\x04fast\x18\x03 \x01(\x05R\x04fast\x12\x12\n" +
+	"\x04slow\x18\x04 \x01(\x05R\x04slow\x12\x16\n" +
+	"\x06signal\x18\x05 \x01(\x05R\x06signal\"c\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bKIND_SMA\x10\x01\x12\f\n" +
+	"\bKIND_EMA\x10\x02\x12\f\n" +
+	"\bKIND_STD\x10\x03\x12\f\n" +
+	"\bKIND_ATR\x10\x04\x12\r\n" +
+	"\tKIND_MACD\x10\x05\"\x87\x01\n" +
+	"\x18ComputeIndicatorsRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04days\x18\x02 \x01(\x05R\x04days\x12?\n" +
+	"\n" +
+	"indicators\x18\x03 \x03(\v2\x1f.stockanalysis.v1.IndicatorSpecR\n" +
+	"indicators\"4\n" +
+	"\x06Series\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06values\x18\x02 \x03(\x01R\x06values\"{\n" +
+	"\x19ComputeIndicatorsResponse\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x14\n" +
+	"\x05dates\x18\x02 \x03(\tR\x05dates\x120\n" +
+	"\x06series\x18\x03 \x03(\v2\x18.stockanalysis.v1.SeriesR\x06series\"t\n" +
+	"\rScreenRequest\x12!\n" +
+	"\vstrategy_id\x18\x01 \x01(\tH\x00R\n" +
+	"strategyId\x126\n" +
+	"\x06ad_hoc\x18\x02 \x01(\v2\x1d.stockanalysis.v1.AdHocScreenH\x00R\x05adHocB\b\n" +
+	"\x06screen\"d\n" +
+	"\vAdHocScreen\x12\x14\n" +
+	"\x05entry\x18\x01 \x01(\tR\x05entry\x12\x1a\n" +
+	"\buniverse\x18\x02 \x03(\tR\buniverse\x12#\n" +
+	"\rlookback_days\x18\x03 \x01(\x05R\flookbackDays\"\x7f\n" +
+	"\vScreenMatch\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bindustry\x18\x03 \x01(\tR\bindustry\x12\x12\n" +
+	"\x04date\x18\x04 \x01(\tR\x04date\x12\x14\n" +
+	"\x05close\x18\x05 \x01(\x01R\x05close\"I\n" +
+	"\x0eScreenResponse\x127\n" +
+	"\amatches\x18\x01 \x03(\v2\x1d.stockanalysis.v1.ScreenMatchR\amatches2\xa5\x01\n" +
+	"\vDataService\x12N\n" +
+	"\aGetBars\x12 .stockanalysis.v1.GetBarsRequest\x1a!.stockanalysis.v1.GetBarsResponse\x12F\n" +
+	"\bGetQuote\x12!.stockanalysis.v1.GetQuoteRequest\x1a\x17.stockanalysis.v1.Quote2\x80\x01\n" +
+	"\x10IndicatorService\x12l\n" +
+	"\x11ComputeIndicators\x12*.stockanalysis.v1.ComputeIndicatorsRequest\x1a+.stockanalysis.v1.ComputeIndicatorsResponse2^\n" +
+	"\x0fScreenerService\x12K\n" +
+	"\x06Screen\x12\x1f.stockanalysis.v1.ScreenRequest\x1a .stockanalysis.v1.ScreenResponseB7Z5stock-analysis/proto/stockanalysis/v1;stockanalysisv1b\x06proto3"
+
+var (
+	file_proto_stockanalysis_v1_analysis_proto_rawDescOnce sync.Once
+	file_proto_stockanalysis_v1_analysis_proto_rawDescData []byte
+)
+
+func file_proto_stockanalysis_v1_analysis_proto_rawDescGZIP() []byte {
+	file_proto_stockanalysis_v1_analysis_proto_rawDescOnce.Do(func() {
+		file_proto_stockanalysis_v1_analysis_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_stockanalysis_v1_analysis_proto_rawDesc), len(file_proto_stockanalysis_v1_analysis_proto_rawDesc)))
+	})
+	return file_proto_stockanalysis_v1_analysis_proto_rawDescData
+}
+
+var file_proto_stockanalysis_v1_analysis_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_stockanalysis_v1_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proto_stockanalysis_v1_analysis_proto_goTypes = []any{
+	(IndicatorSpec_Kind)(0),           // 0: stockanalysis.v1.IndicatorSpec.Kind
+	(*Bar)(nil),                       // 1: stockanalysis.v1.Bar
+	(*GetBarsRequest)(nil),            // 2: stockanalysis.v1.GetBarsRequest
+	(*GetBarsResponse)(nil),           // 3: stockanalysis.v1.GetBarsResponse
+	(*GetQuoteRequest)(nil),           // 4: stockanalysis.v1.GetQuoteRequest
+	(*Quote)(nil),                     // 5: stockanalysis.v1.Quote
+	(*IndicatorSpec)(nil),             // 6: stockanalysis.v1.IndicatorSpec
+	(*ComputeIndicatorsRequest)(nil),  // 7: stockanalysis.v1.ComputeIndicatorsRequest
+	(*Series)(nil),                    // 8: stockanalysis.v1.Series
+	(*ComputeIndicatorsResponse)(nil), // 9: stockanalysis.v1.ComputeIndicatorsResponse
+	(*ScreenRequest)(nil),             // 10: stockanalysis.v1.ScreenRequest
+	(*AdHocScreen)(nil),               // 11: stockanalysis.v1.AdHocScreen
+	(*ScreenMatch)(nil),               // 12: stockanalysis.v1.ScreenMatch
+	(*ScreenResponse)(nil),            // 13: stockanalysis.v1.ScreenResponse
+}
+var file_proto_stockanalysis_v1_analysis_proto_depIdxs = []int32{
+	1,  // 0: stockanalysis.v1.GetBarsResponse.bars:type_name -> stockanalysis.v1.Bar
+	0,  // 1: stockanalysis.v1.IndicatorSpec.kind:type_name -> stockanalysis.v1.IndicatorSpec.Kind
+	6,  // 2: stockanalysis.v1.ComputeIndicatorsRequest.indicators:type_name -> stockanalysis.v1.IndicatorSpec
+	8,  // 3: stockanalysis.v1.ComputeIndicatorsResponse.series:type_name -> stockanalysis.v1.Series
+	11, // 4: stockanalysis.v1.ScreenRequest.ad_hoc:type_name -> stockanalysis.v1.AdHocScreen
+	12, // 5: stockanalysis.v1.ScreenResponse.matches:type_name -> stockanalysis.v1.ScreenMatch
+	2,  // 6: stockanalysis.v1.DataService.GetBars:input_type -> stockanalysis.v1.GetBarsRequest
+	4,  // 7: stockanalysis.v1.DataService.GetQuote:input_type -> stockanalysis.v1.GetQuoteRequest
+	7,  // 8: stockanalysis.v1.IndicatorService.ComputeIndicators:input_type -> stockanalysis.v1.ComputeIndicatorsRequest
+	10, // 9: stockanalysis.v1.ScreenerService.Screen:input_type -> stockanalysis.v1.ScreenRequest
+	3,  // 10: stockanalysis.v1.DataService.GetBars:output_type -> stockanalysis.v1.GetBarsResponse
+	5,  // 11: stockanalysis.v1.DataService.GetQuote:output_type -> stockanalysis.v1.Quote
+	9,  // 12: stockanalysis.v1.IndicatorService.ComputeIndicators:output_type -> stockanalysis.v1.ComputeIndicatorsResponse
+	13, // 13: stockanalysis.v1.ScreenerService.Screen:output_type -> stockanalysis.v1.ScreenResponse
+	10, // [10:14] is the sub-list for method output_type
+	6,  // [6:10] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_proto_stockanalysis_v1_analysis_proto_init() }
+func file_proto_stockanalysis_v1_analysis_proto_init() {
+	if File_proto_stockanalysis_v1_analysis_proto != nil {
+		return
+	}
+	file_proto_stockanalysis_v1_analysis_proto_msgTypes[9].OneofWrappers = []any{
+		(*ScreenRequest_StrategyId)(nil),
+		(*ScreenRequest_AdHoc)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_stockanalysis_v1_analysis_proto_rawDesc), len(file_proto_stockanalysis_v1_analysis_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_proto_stockanalysis_v1_analysis_proto_goTypes,
+		DependencyIndexes: file_proto_stockanalysis_v1_analysis_proto_depIdxs,
+		EnumInfos:         file_proto_stockanalysis_v1_analysis_proto_enumTypes,
+		MessageInfos:      file_proto_stockanalysis_v1_analysis_proto_msgTypes,
+	}.Build()
+	File_proto_stockanalysis_v1_analysis_proto = out.File
+	file_proto_stockanalysis_v1_analysis_proto_goTypes = nil
+	file_proto_stockanalysis_v1_analysis_proto_depIdxs = nil
+}
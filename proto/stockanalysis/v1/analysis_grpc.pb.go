@@ -0,0 +1,390 @@
+// Service definitions of the analysis engine for typed cross-language
+// clients. The messages mirror the JSON the app's bound methods return:
+// prices in yuan, volumes in lots, percentages as 12.5 for 12.5%, dates as
+// YYYY-MM-DD in China Standard Time.
+//
+// The app serves them on the loopback interface when the grpc setting is
+// enabled; clients send the configured token as "authorization: Bearer
+// <token>" metadata. The Go stubs next to this file are generated with
+// protoc-gen-go and protoc-gen-go-grpc:
+//
+//   protoc --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. \
+//     proto/stockanalysis/v1/analysis.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/stockanalysis/v1/analysis.proto
+
+package stockanalysisv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DataService_GetBars_FullMethodName  = "/stockanalysis.v1.DataService/GetBars"
+	DataService_GetQuote_FullMethodName = "/stockanalysis.v1.DataService/GetQuote"
+)
+
+// DataServiceClient is the client API for DataService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// DataService serves daily bars and quotes from the local history and the
+// configured providers
+type DataServiceClient interface {
+	GetBars(ctx context.Context, in *GetBarsRequest, opts ...grpc.CallOption) (*GetBarsResponse, error)
+	GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*Quote, error)
+}
+
+type dataServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataServiceClient(cc grpc.ClientConnInterface) DataServiceClient {
+	return &dataServiceClient{cc}
+}
+
+func (c *dataServiceClient) GetBars(ctx context.Context, in *GetBarsRequest, opts ...grpc.CallOption) (*GetBarsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBarsResponse)
+	err := c.cc.Invoke(ctx, DataService_GetBars_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*Quote, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Quote)
+	err := c.cc.Invoke(ctx, DataService_GetQuote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataServiceServer is the server API for DataService service.
+// All implementations must embed UnimplementedDataServiceServer
+// for forward compatibility.
+//
+// DataService serves daily bars and quotes from the local history and the
+// configured providers
+type DataServiceServer interface {
+	GetBars(context.Context, *GetBarsRequest) (*GetBarsResponse, error)
+	GetQuote(context.Context, *GetQuoteRequest) (*Quote, error)
+	mustEmbedUnimplementedDataServiceServer()
+}
+
+// UnimplementedDataServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDataServiceServer struct{}
+
+func (UnimplementedDataServiceServer) GetBars(context.Context, *GetBarsRequest) (*GetBarsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBars not implemented")
+}
+func (UnimplementedDataServiceServer) GetQuote(context.Context, *GetQuoteRequest) (*Quote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuote not implemented")
+}
+func (UnimplementedDataServiceServer) mustEmbedUnimplementedDataServiceServer() {}
+func (UnimplementedDataServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeDataServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataServiceServer will
+// result in compilation errors.
+type UnsafeDataServiceServer interface {
+	mustEmbedUnimplementedDataServiceServer()
+}
+
+func RegisterDataServiceServer(s grpc.ServiceRegistrar, srv DataServiceServer) {
+	// If the following call pancis, it indicates UnimplementedDataServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DataService_ServiceDesc, srv)
+}
+
+func _DataService_GetBars_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBarsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).GetBars(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_GetBars_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).GetBars(ctx, req.(*GetBarsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_GetQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).GetQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_GetQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).GetQuote(ctx, req.(*GetQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DataService_ServiceDesc is the grpc.ServiceDesc for DataService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DataService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stockanalysis.v1.DataService",
+	HandlerType: (*DataServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBars",
+			Handler:    _DataService_GetBars_Handler,
+		},
+		{
+			MethodName: "GetQuote",
+			Handler:    _DataService_GetQuote_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/stockanalysis/v1/analysis.proto",
+}
+
+const (
+	IndicatorService_ComputeIndicators_FullMethodName = "/stockanalysis.v1.IndicatorService/ComputeIndicators"
+)
+
+// IndicatorServiceClient is the client API for IndicatorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// IndicatorService computes indicator series over daily bars
+type IndicatorServiceClient interface {
+	ComputeIndicators(ctx context.Context, in *ComputeIndicatorsRequest, opts ...grpc.CallOption) (*ComputeIndicatorsResponse, error)
+}
+
+type indicatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIndicatorServiceClient(cc grpc.ClientConnInterface) IndicatorServiceClient {
+	return &indicatorServiceClient{cc}
+}
+
+func (c *indicatorServiceClient) ComputeIndicators(ctx context.Context, in *ComputeIndicatorsRequest, opts ...grpc.CallOption) (*ComputeIndicatorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ComputeIndicatorsResponse)
+	err := c.cc.Invoke(ctx, IndicatorService_ComputeIndicators_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IndicatorServiceServer is the server API for IndicatorService service.
+// All implementations must embed UnimplementedIndicatorServiceServer
+// for forward compatibility.
+//
+// IndicatorService computes indicator series over daily bars
+type IndicatorServiceServer interface {
+	ComputeIndicators(context.Context, *ComputeIndicatorsRequest) (*ComputeIndicatorsResponse, error)
+	mustEmbedUnimplementedIndicatorServiceServer()
+}
+
+// UnimplementedIndicatorServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIndicatorServiceServer struct{}
+
+func (UnimplementedIndicatorServiceServer) ComputeIndicators(context.Context, *ComputeIndicatorsRequest) (*ComputeIndicatorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ComputeIndicators not implemented")
+}
+func (UnimplementedIndicatorServiceServer) mustEmbedUnimplementedIndicatorServiceServer() {}
+func (UnimplementedIndicatorServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeIndicatorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IndicatorServiceServer will
+// result in compilation errors.
+type UnsafeIndicatorServiceServer interface {
+	mustEmbedUnimplementedIndicatorServiceServer()
+}
+
+func RegisterIndicatorServiceServer(s grpc.ServiceRegistrar, srv IndicatorServiceServer) {
+	// If the following call pancis, it indicates UnimplementedIndicatorServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&IndicatorService_ServiceDesc, srv)
+}
+
+func _IndicatorService_ComputeIndicators_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ComputeIndicatorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndicatorServiceServer).ComputeIndicators(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IndicatorService_ComputeIndicators_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndicatorServiceServer).ComputeIndicators(ctx, req.(*ComputeIndicatorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IndicatorService_ServiceDesc is the grpc.ServiceDesc for IndicatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IndicatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stockanalysis.v1.IndicatorService",
+	HandlerType: (*IndicatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ComputeIndicators",
+			Handler:    _IndicatorService_ComputeIndicators_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/stockanalysis/v1/analysis.proto",
+}
+
+const (
+	ScreenerService_Screen_FullMethodName = "/stockanalysis.v1.ScreenerService/Screen"
+)
+
+// ScreenerServiceClient is the client API for ScreenerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ScreenerService evaluates screens over a universe of symbols
+type ScreenerServiceClient interface {
+	Screen(ctx context.Context, in *ScreenRequest, opts ...grpc.CallOption) (*ScreenResponse, error)
+}
+
+type screenerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScreenerServiceClient(cc grpc.ClientConnInterface) ScreenerServiceClient {
+	return &screenerServiceClient{cc}
+}
+
+func (c *screenerServiceClient) Screen(ctx context.Context, in *ScreenRequest, opts ...grpc.CallOption) (*ScreenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScreenResponse)
+	err := c.cc.Invoke(ctx, ScreenerService_Screen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScreenerServiceServer is the server API for ScreenerService service.
+// All implementations must embed UnimplementedScreenerServiceServer
+// for forward compatibility.
+//
+// ScreenerService evaluates screens over a universe of symbols
+type ScreenerServiceServer interface {
+	Screen(context.Context, *ScreenRequest) (*ScreenResponse, error)
+	mustEmbedUnimplementedScreenerServiceServer()
+}
+
+// UnimplementedScreenerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedScreenerServiceServer struct{}
+
+func (UnimplementedScreenerServiceServer) Screen(context.Context, *ScreenRequest) (*ScreenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Screen not implemented")
+}
+func (UnimplementedScreenerServiceServer) mustEmbedUnimplementedScreenerServiceServer() {}
+func (UnimplementedScreenerServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeScreenerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScreenerServiceServer will
+// result in compilation errors.
+type UnsafeScreenerServiceServer interface {
+	mustEmbedUnimplementedScreenerServiceServer()
+}
+
+func RegisterScreenerServiceServer(s grpc.ServiceRegistrar, srv ScreenerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedScreenerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ScreenerService_ServiceDesc, srv)
+}
+
+func _ScreenerService_Screen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScreenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScreenerServiceServer).Screen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ScreenerService_Screen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScreenerServiceServer).Screen(ctx, req.(*ScreenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ScreenerService_ServiceDesc is the grpc.ServiceDesc for ScreenerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ScreenerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stockanalysis.v1.ScreenerService",
+	HandlerType: (*ScreenerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Screen",
+			Handler:    _ScreenerService_Screen_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/stockanalysis/v1/analysis.proto",
+}
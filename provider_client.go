@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+
+	"stock-analysis/internal/providers"
+)
+
+// providerTransport is the connection pool of every provider request
+var providerTransport = providers.NewTransport()
+
+func init() {
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		t.Proxy = providers.RouteProxy
+	}
+}
+
+// providerClient is shared by every provider request. Its requests pass
+// through fixtures and validators before reaching providerTransport.
+var providerClient = &http.Client{Transport: fixtures, Timeout: providers.Timeout}
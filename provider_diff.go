@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// localSource names the local history store as a source to compare
+const localSource = "local"
+
+// PriceMismatch is a field that differs between two sources on a date
+type PriceMismatch struct {
+	Date    string  `json:"date"`
+	Field   string  `json:"field"`
+	A       float64 `json:"a"`
+	B       float64 `json:"b"`
+	DiffPct float64 `json:"diffPct"` // relative to A, %
+}
+
+// ProviderDiff reports how two sources disagree about a symbol's bars
+type ProviderDiff struct {
+	Symbol       string          `json:"symbol"`
+	A            string          `json:"a"`
+	B            string          `json:"b"`
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	DaysA        int             `json:"daysA"`
+	DaysB        int             `json:"daysB"`
+	MissingInA   []string        `json:"missingInA"` // dates only B has
+	MissingInB   []string        `json:"missingInB"` // dates only A has
+	Mismatches   []PriceMismatch `json:"mismatches"`
+	TolerancePct float64         `json:"tolerancePct"`
+	MaxDiffPct   float64         `json:"maxDiffPct"`
+	Agreement    float64         `json:"agreement"` // share of common days without a mismatch, %
+}
+
+// sourceBars returns the bars of symbol from one source: a provider
+// downloaded directly, or the local history store
+func sourceBars(ctx context.Context, source, symbol string, start, end time.Time) ([]Bar, error) {
+	switch source {
+	case "sohu":
+		return downloadDailyBars(ctx, symbol, start, end)
+	case demoProvider:
+		return demoDailyBars(symbol, start, end)
+	case localSource:
+		return localHistory.load(symbol, start, end)
+	default:
+		return nil, codeErrorf(codeParse, "unknown provider: %s", source)
+	}
+}
+
+// diffBars compares a and b date by date. Prices differing by more than
+// tolerancePct and volumes by more than ten times that are mismatches.
+func diffBars(a, b []Bar, tolerancePct float64) ProviderDiff {
+	diff := ProviderDiff{
+		DaysA:        len(a),
+		DaysB:        len(b),
+		MissingInA:   []string{},
+		MissingInB:   []string{},
+		Mismatches:   []PriceMismatch{},
+		TolerancePct: tolerancePct,
+	}
+	byDate := make(map[string]Bar, len(b))
+	for _, bar := range b {
+		byDate[bar.Date] = bar
+	}
+	common, agreeing := 0, 0
+	for _, barA := range a {
+		barB, ok := byDate[barA.Date]
+		if !ok {
+			diff.MissingInB = append(diff.MissingInB, barA.Date)
+			continue
+		}
+		delete(byDate, barA.Date)
+		common++
+		fields := []struct {
+			name      string
+			a, b, tol float64
+		}{
+			{"open", barA.Open, barB.Open, tolerancePct},
+			{"high", barA.High, barB.High, tolerancePct},
+			{"low", barA.Low, barB.Low, tolerancePct},
+			{"close", barA.Close, barB.Close, tolerancePct},
+			{"volume", barA.Volume, barB.Volume, tolerancePct * 10},
+		}
+		agrees := true
+		for _, f := range fields {
+			pct := 0.0
+			if f.a != 0 {
+				pct = math.Abs(f.b-f.a) / math.Abs(f.a) * 100
+			} else if f.b != 0 {
+				pct = 100
+			}
+			if f.name != "volume" {
+				diff.MaxDiffPct = math.Max(diff.MaxDiffPct, pct)
+			}
+			if pct > f.tol {
+				agrees = false
+				diff.Mismatches = append(diff.Mismatches, PriceMismatch{Date: barA.Date, Field: f.name, A: f.a, B: f.b, DiffPct: pct})
+			}
+		}
+		if agrees {
+			agreeing++
+		}
+	}
+	for date := range byDate {
+		diff.MissingInA = append(diff.MissingInA, date)
+	}
+	sort.Strings(diff.MissingInA)
+	if common > 0 {
+		diff.Agreement = float64(agreeing) / float64(common) * 100
+	}
+	return diff
+}
+
+// CompareProviders fetches the last days of symbol from two sources
+// ("sohu", "demo" or "local" for the stored history) and reports the days
+// only one of them has and the prices that differ by more than
+// tolerancePct (0 uses 0.5%). days of 0 uses the configured lookback.
+func (a *App) CompareProviders(symbol, providerA, providerB string, days int, tolerancePct float64) (string, error) {
+	if providerA == providerB {
+		return "", codeErrorf(codeParse, "choose two different providers")
+	}
+	if tolerancePct <= 0 {
+		tolerancePct = 0.5
+	}
+	now := shanghaiNow()
+	start := lookbackStart(symbol, days, now)
+	barsA, err := sourceBars(context.Background(), providerA, symbol, start, now)
+	if err != nil && err != providers.ErrNoBars {
+		return "", fmt.Errorf("failed to get %s data: %w", providerA, err)
+	}
+	barsB, err := sourceBars(context.Background(), providerB, symbol, start, now)
+	if err != nil && err != providers.ErrNoBars {
+		return "", fmt.Errorf("failed to get %s data: %w", providerB, err)
+	}
+	diff := diffBars(barsA, barsB, tolerancePct)
+	diff.Symbol, diff.A, diff.B = symbol, providerA, providerB
+	diff.From, diff.To = start.Format("2006-01-02"), now.Format("2006-01-02")
+	return toJSON(diff)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// responseSampleBytes is how much of a bad response body is kept for
+	// diagnostics
+	responseSampleBytes = 256
+	// responsePeekBytes is how much of a streamed body is checked before
+	// it is decoded
+	responsePeekBytes = 512
+)
+
+// ProviderResponseError describes a provider response that is not usable
+// data. It travels to the frontend in AppError.Response.
+type ProviderResponseError struct {
+	Provider    string `json:"provider"`
+	URL         string `json:"url"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Reason      string `json:"reason"`
+	Sample      string `json:"sample"` // start of the body
+}
+
+func (e *ProviderResponseError) Error() string {
+	return fmt.Sprintf("%s returned %s (HTTP %d, %s): %q", e.Provider, e.Reason, e.Status, e.ContentType, e.Sample)
+}
+
+// responseSample returns the start of body as valid text
+func responseSample(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) > responseSampleBytes {
+		body = body[:responseSampleBytes]
+	}
+	return strings.ToValidUTF8(string(body), string(utf8.RuneError))
+}
+
+// throttleReason recognizes a throttling response: 429, a ban page served
+// as HTML, or an empty body. It returns "" for other responses.
+func throttleReason(resp *http.Response, body []byte) string {
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "HTTP 429"
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return "an HTML page instead of data"
+	case len(trimmed) == 0 && resp.StatusCode < 300:
+		return "an empty response"
+	}
+	return ""
+}
+
+// retryAfter reads a Retry-After header given in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After")))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// checkProviderResponse returns a classified error if the response is not
+// JSON data: a throttling response puts provider in cool-down and is
+// rate-limited, another non-2xx status is a provider error, and any other
+// body is a parse error. A good response clears the provider's strikes.
+// Providers label JSON as text/html at times, so the body decides, not
+// the Content-Type.
+func checkProviderResponse(provider string, resp *http.Response, body []byte) error {
+	respErr := &ProviderResponseError{
+		Provider:    provider,
+		URL:         resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Sample:      responseSample(body),
+	}
+	if reason := throttleReason(resp, body); reason != "" {
+		respErr.Reason = reason
+		cooldowns.strike(provider, reason, retryAfter(resp))
+		return &codedError{codeRateLimited, respErr}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respErr.Reason = "an error status"
+		return &codedError{codeProvider, respErr}
+	}
+	if trimmed := bytes.TrimSpace(body); trimmed[0] != '[' && trimmed[0] != '{' {
+		respErr.Reason = "a body that is not JSON"
+		return &codedError{codeParse, respErr}
+	}
+	cooldowns.succeed(provider)
+	return nil
+}
+
+// countingReader counts the bytes read through it and keeps the first
+// keep of them
+type countingReader struct {
+	r    io.Reader
+	n    int
+	keep int
+	kept []byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	if room := c.keep - len(c.kept); room > 0 {
+		c.kept = append(c.kept, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+// decodeProviderResponse checks a provider response like
+// checkProviderResponse, judging by the start of the body, then hands the
+// body to decode as it streams in. Big payloads are never held whole as
+// text on top of the values decoded from them. The response is logged as
+// logProviderResponse would.
+func decodeProviderResponse(provider string, resp *http.Response, started time.Time, decode func(dec *json.Decoder) error) error {
+	br := bufio.NewReaderSize(resp.Body, 4<<10)
+	head, _ := br.Peek(responsePeekBytes)
+	if err := checkProviderResponse(provider, resp, head); err != nil {
+		body, _ := io.ReadAll(io.LimitReader(br, payloadMaxBytes))
+		logProviderResponse(resp, body, started)
+		return err
+	}
+
+	body := &countingReader{r: br}
+	if debugMode.Load() {
+		body.keep = payloadMaxBytes
+	}
+	err := decode(json.NewDecoder(body))
+	// Read what the decoder left so the size is known and the connection
+	// can be reused
+	io.Copy(io.Discard, body)
+	recordProviderResponse(resp, body.kept, body.n, started)
+	return err
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultPushPort is where the push server listens unless configured
+	defaultPushPort = 8765
+	// pushSendBuffer is how many messages a client may fall behind before
+	// it is disconnected
+	pushSendBuffer = 64
+	pushWriteWait  = 10 * time.Second
+	pushPingPeriod = 30 * time.Second
+)
+
+// PushSettings configure the WebSocket push server for external
+// dashboards. It only listens on the loopback interface.
+type PushSettings struct {
+	Enabled bool   `json:"enabled"`
+	Port    int    `json:"port"`
+	Token   string `json:"token"` // clients pass it as ?token=; generated when empty
+}
+
+// PushMessage is one message sent to push clients
+type PushMessage struct {
+	Type string      `json:"type"` // "hello", "quote" or "alert"
+	Time string      `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// PushStatus describes the push server
+type PushStatus struct {
+	Enabled bool   `json:"enabled"`
+	Running bool   `json:"running"`
+	URL     string `json:"url,omitempty"`
+	Metrics string `json:"metrics,omitempty"` // Prometheus scrape URL
+	Clients int    `json:"clients"`
+	Error   string `json:"error,omitempty"`
+}
+
+// pushHub fans messages out to the connected WebSocket clients
+type pushHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]bool
+	server  *http.Server
+	addr    string
+	err     error // of the last start
+}
+
+func newPushHub() *pushHub {
+	return &pushHub{clients: make(map[chan []byte]bool)}
+}
+
+// publish sends a message to every client. Clients too slow to keep up
+// are dropped rather than blocking the caller.
+func (h *pushHub) publish(kind string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) == 0 {
+		return
+	}
+	msg, err := json.Marshal(PushMessage{Type: kind, Time: shanghaiNow().Format(time.RFC3339), Data: data})
+	if err != nil {
+		logger.Warn("failed to encode push message", "type", kind, "error", err)
+		return
+	}
+	for send := range h.clients {
+		select {
+		case send <- msg:
+		default:
+			delete(h.clients, send)
+			close(send)
+		}
+	}
+}
+
+// clientCount returns the number of connected clients
+func (h *pushHub) clientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+var pushUpgrader = websocket.Upgrader{
+	// Browser dashboards are served from anywhere; the token guards access
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// serveWS upgrades a request to a WebSocket and streams messages to it
+func (h *pushHub) serveWS(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != token {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		conn, err := pushUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		send := make(chan []byte, pushSendBuffer)
+		h.mu.Lock()
+		h.clients[send] = true
+		h.mu.Unlock()
+		logger.Info("push client connected", "remote", r.RemoteAddr)
+
+		// The read loop only notices the client going away
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			defer recoverPanic("push client")
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		hello, _ := json.Marshal(PushMessage{Type: "hello", Time: shanghaiNow().Format(time.RFC3339),
+			Data: []string{"quote", "alert"}})
+		send <- hello
+		ping := time.NewTicker(pushPingPeriod)
+		defer func() {
+			ping.Stop()
+			h.mu.Lock()
+			if h.clients[send] {
+				delete(h.clients, send)
+				close(send)
+			}
+			h.mu.Unlock()
+			conn.Close()
+			logger.Info("push client disconnected", "remote", r.RemoteAddr)
+		}()
+		for {
+			select {
+			case msg, ok := <-send:
+				conn.SetWriteDeadline(time.Now().Add(pushWriteWait))
+				if !ok { // dropped as too slow or the server stopped
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+					return
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					return
+				}
+			case <-ping.C:
+				conn.SetWriteDeadline(time.Now().Add(pushWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}
+}
+
+// start listens on the loopback port of settings. Callers hold h.mu.
+func (h *pushHub) start(settings PushSettings) {
+	addr := fmt.Sprintf("127.0.0.1:%d", settings.Port)
+	ln, err := net.Listen("tcp", addr)
+	h.err = err
+	if err != nil {
+		logger.Warn("failed to start push server", "addr", addr, "error", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", h.serveWS(settings.Token))
+	mux.HandleFunc("/metrics", serveMetrics(settings.Token))
+	h.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	h.addr = addr
+	go h.server.Serve(ln)
+	logger.Info("push server started", "addr", addr)
+}
+
+// stop closes the server and every client. Callers hold h.mu.
+func (h *pushHub) stop() {
+	if h.server == nil {
+		return
+	}
+	h.server.Close()
+	for send := range h.clients {
+		delete(h.clients, send)
+		close(send)
+	}
+	h.server, h.addr = nil, ""
+	logger.Info("push server stopped")
+}
+
+// apply starts, restarts or stops the server to match settings
+func (h *pushHub) apply(settings PushSettings, running *PushSettings) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if settings == *running && (h.server != nil || !settings.Enabled || h.err != nil) {
+		return
+	}
+	h.stop()
+	h.err = nil
+	if settings.Enabled {
+		h.start(settings)
+	}
+	*running = settings
+}
+
+// runPushServer keeps the push server in line with the settings and, while
+// clients are connected, streams the watchlist quotes at the quote refresh
+// interval
+func (a *App) runPushServer(ctx context.Context) {
+	var running PushSettings
+	check := time.NewTicker(settingsPollInterval)
+	defer check.Stop()
+	lastQuotes := time.Time{}
+	for {
+		settings := currentSettings()
+		a.push.apply(settings.Push, &running)
+		if a.push.clientCount() > 0 && time.Since(lastQuotes) >= settings.quoteTTL() {
+			lastQuotes = time.Now()
+			if symbols, err := a.watchlistSymbols(); err == nil {
+				for _, sym := range symbols {
+					a.push.publish("quote", watchlistQuote(sym))
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			a.push.mu.Lock()
+			a.push.stop()
+			a.push.mu.Unlock()
+			return
+		case <-check.C:
+		}
+	}
+}
+
+// GetPushStatus returns whether the push server runs and the URL clients
+// connect to
+func (a *App) GetPushStatus() (string, error) {
+	settings := currentSettings().Push
+	h := a.push
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := PushStatus{Enabled: settings.Enabled, Running: h.server != nil, Clients: len(h.clients)}
+	if h.server != nil {
+		status.URL = fmt.Sprintf("ws://%s/ws?token=%s", h.addr, settings.Token)
+		status.Metrics = fmt.Sprintf("http://%s/metrics?token=%s", h.addr, settings.Token)
+	}
+	if h.err != nil {
+		status.Error = h.err.Error()
+	}
+	return toJSON(status)
+}
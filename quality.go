@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// qualitySchema stores the latest quality score of each stored series
+const qualitySchema = `
+CREATE TABLE IF NOT EXISTS quality (
+	symbol       TEXT PRIMARY KEY,
+	score        REAL NOT NULL,
+	completeness REAL NOT NULL,
+	freshness    REAL NOT NULL,
+	agreement    REAL,          -- NULL until checked against the provider
+	bars         INTEGER NOT NULL,
+	gaps         INTEGER NOT NULL,
+	invalid      INTEGER NOT NULL,
+	last_date    TEXT NOT NULL,
+	computed_at  TEXT NOT NULL
+);
+`
+
+// qualityAgreementSessions is how many recent sessions are compared with
+// the provider when agreement is checked
+const qualityAgreementSessions = 20
+
+// DataQuality scores how far an analysis can trust the stored series of
+// a symbol. Every part is 0-100.
+type DataQuality struct {
+	Symbol       string   `json:"symbol"`
+	Score        float64  `json:"score"`
+	Grade        string   `json:"grade"`        // "good", "fair" or "poor"
+	Completeness float64  `json:"completeness"` // stored sessions against trading days in the range
+	Freshness    float64  `json:"freshness"`    // 100 when current, less per session behind
+	Agreement    *float64 `json:"agreement"`    // recent sessions matching the provider; nil if unchecked
+	Bars         int      `json:"bars"`
+	Gaps         int      `json:"gaps"`    // runs of more than 5 missing trading days, e.g. suspensions
+	Invalid      int      `json:"invalid"` // bars with impossible prices
+	LastDate     string   `json:"lastDate"`
+	ComputedAt   string   `json:"computedAt"`
+}
+
+// scoreQuality computes completeness and freshness of bars as of now and
+// combines them with agreement, if known. Holidays the calendar does not
+// know count as missing sessions.
+func scoreQuality(symbol string, bars []Bar, agreement *float64, now time.Time) DataQuality {
+	q := DataQuality{Symbol: symbol, Agreement: agreement, Bars: len(bars), ComputedAt: now.Format(time.RFC3339)}
+	if len(bars) == 0 {
+		q.Grade = qualityGrade(0)
+		return q
+	}
+	const layout = "2006-01-02"
+	first, _ := time.ParseInLocation(layout, bars[0].Date, now.Location())
+	last, _ := time.ParseInLocation(layout, bars[len(bars)-1].Date, now.Location())
+	q.LastDate = bars[len(bars)-1].Date
+
+	prev := first
+	for i, b := range bars {
+		if b.Close <= 0 || b.Low > b.High || b.Close > b.High*1.0001 || b.Close < b.Low*0.9999 {
+			q.Invalid++
+		}
+		if i > 0 {
+			day, _ := time.ParseInLocation(layout, b.Date, now.Location())
+			if tradingDaysBetween(prev, day) > 6 {
+				q.Gaps++
+			}
+			prev = day
+		}
+	}
+	expected := tradingDaysBetween(first, last) + 1
+	q.Completeness = math.Min(100, float64(len(bars)-q.Invalid)/float64(expected)*100)
+	behind := tradingDaysBetween(last, completeThrough(now))
+	q.Freshness = math.Max(0, 100-20*float64(behind))
+
+	if agreement != nil {
+		q.Score = 0.4*q.Completeness + 0.3*q.Freshness + 0.3**agreement
+	} else {
+		q.Score = (0.4*q.Completeness + 0.3*q.Freshness) / 0.7
+	}
+	q.Grade = qualityGrade(q.Score)
+	return q
+}
+
+// qualityGrade names a quality score
+func qualityGrade(score float64) string {
+	switch {
+	case score >= 80:
+		return "good"
+	case score >= 50:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
+
+// saveQuality records the quality of a symbol's series
+func (s *historyStore) saveQuality(q DataQuality) error {
+	db, err := s.Open()
+	if err != nil {
+		return err
+	}
+	var agreement sql.NullFloat64
+	if q.Agreement != nil {
+		agreement = sql.NullFloat64{Float64: *q.Agreement, Valid: true}
+	}
+	_, err = db.Exec(`INSERT OR REPLACE INTO quality
+		(symbol, score, completeness, freshness, agreement, bars, gaps, invalid, last_date, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		providers.SohuCode(q.Symbol), q.Score, q.Completeness, q.Freshness, agreement, q.Bars, q.Gaps, q.Invalid, q.LastDate, q.ComputedAt)
+	return err
+}
+
+// qualities returns the recorded quality of every symbol, or of one
+// symbol if symbol is not empty
+func (s *historyStore) qualities(symbol string) ([]DataQuality, error) {
+	db, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT symbol, score, completeness, freshness, agreement, bars, gaps, invalid, last_date, computed_at FROM quality`
+	var args []interface{}
+	if symbol != "" {
+		query += ` WHERE symbol = ?`
+		args = append(args, providers.SohuCode(symbol))
+	}
+	rows, err := db.Query(query+` ORDER BY symbol`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []DataQuality{}
+	for rows.Next() {
+		var q DataQuality
+		var agreement sql.NullFloat64
+		if err := rows.Scan(&q.Symbol, &q.Score, &q.Completeness, &q.Freshness, &agreement,
+			&q.Bars, &q.Gaps, &q.Invalid, &q.LastDate, &q.ComputedAt); err != nil {
+			return nil, err
+		}
+		if agreement.Valid {
+			q.Agreement = &agreement.Float64
+		}
+		q.Grade = qualityGrade(q.Score)
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
+
+// GetDataQuality scores the stored series of symbol and records the
+// result. checkAgreement also downloads the last sessions from the
+// provider and compares them; otherwise the last checked agreement is
+// kept.
+func (a *App) GetDataQuality(symbol string, checkAgreement bool) (string, error) {
+	now := shanghaiNow()
+	first, ok, err := localHistory.firstDate(symbol)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", codeErrorf(codeNoData, "no stored history for %s", symbol)
+	}
+	start, _ := time.ParseInLocation("2006-01-02", first, now.Location())
+	bars, err := localHistory.load(symbol, start, now)
+	if err != nil {
+		return "", err
+	}
+
+	var agreement *float64
+	if previous, err := localHistory.qualities(symbol); err == nil && len(previous) == 1 {
+		agreement = previous[0].Agreement
+	}
+	if checkAgreement {
+		recent := bars
+		if len(recent) > qualityAgreementSessions {
+			recent = recent[len(recent)-qualityAgreementSessions:]
+		}
+		from, _ := time.ParseInLocation("2006-01-02", recent[0].Date, now.Location())
+		to, _ := time.ParseInLocation("2006-01-02", recent[len(recent)-1].Date, now.Location())
+		remote, err := downloadDailyBars(context.Background(), symbol, from, to)
+		if err != nil {
+			return "", fmt.Errorf("failed to check agreement: %w", err)
+		}
+		diff := diffBars(recent, remote, 0.5)
+		agreement = &diff.Agreement
+	}
+
+	q := scoreQuality(symbol, bars, agreement, now)
+	if err := localHistory.saveQuality(q); err != nil {
+		return "", err
+	}
+	return toJSON(q)
+}
+
+// ListDataQuality returns the recorded quality of every stored series
+func (a *App) ListDataQuality() (string, error) {
+	out, err := localHistory.qualities("")
+	if err != nil {
+		return "", err
+	}
+	return toJSON(out)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"stock-analysis/internal/providers"
+)
+
+// Quote is the latest known price for a symbol with what the app adds to
+// it
+type Quote struct {
+	providers.Quote
+	Session   string   `json:"session,omitempty"`   // market phase of the bar; not "closed" while it is forming
+	StaleAsOf string   `json:"staleAsOf,omitempty"` // set when served offline
+	Note      string   `json:"note,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// fetchQuote returns the most recent bar for symbol as a quote
+func fetchQuote(symbol string) (Quote, error) {
+	key := "quote:" + providers.SohuCode(symbol)
+	if v, ok := resultCache.get(key); ok {
+		return v.(Quote), nil
+	}
+	now := shanghaiNow()
+	// A few sessions back leaves room for a provider that is a day behind
+	bars, err := fetchDailyBars(symbol, tradingDaysBefore(now, 5), now)
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to get quote for %s: %v", symbol, err)
+	}
+	quote := Quote{Quote: providers.QuoteFromBars(symbol, bars)}
+	resultCache.set(key, quote, currentSettings().quoteTTL())
+	return quote, nil
+}
+
+// quote returns the latest quote for a symbol with its session, the
+// user's note and, offline, the date it is stale as of
+func (a *App) quote(symbol string) (Quote, error) {
+	quote, err := fetchQuote(symbol)
+	if err != nil {
+		return Quote{}, err
+	}
+	quote.StaleAsOf = staleAsOf(quote.Date)
+	quote.Session = barSession(quote.Date, shanghaiNow())
+	if n, ok := a.notes.lookup([]string{symbol})[symbol]; ok {
+		quote.Note = n.Note
+		quote.Tags = n.Tags
+	}
+	return quote, nil
+}
+
+// GetQuote returns the latest quote for a symbol with the user's note
+func (a *App) GetQuote(symbol string) (string, error) {
+	quote, err := a.quote(symbol)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(quote)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"stock-analysis/internal/indicators"
+)
+
+// Amplitude (振幅) is a bar's high-low range over the previous close. Its
+// rolling average is a plain volatility gauge that needs no more than the
+// bars, and a day ranging well beyond that average often starts a move.
+// Screens reach the same series through the AMPLITUDE, AMPLITUDE_MA20 and
+// RANGE_EXPANSION formula variables.
+
+const (
+	// rangeAvgPeriod is the window of the average amplitude
+	rangeAvgPeriod = 20
+	// rangeExpansionFactor is how many times its average a day's
+	// amplitude must reach to flag a range expansion
+	rangeExpansionFactor = 1.5
+)
+
+// RangeStats is the amplitude of a symbol day by day, one value per date
+type RangeStats struct {
+	Symbol       string     `json:"symbol"`
+	Dates        []string   `json:"dates"`
+	Amplitude    []*float64 `json:"amplitude"`    // %
+	AvgAmplitude []*float64 `json:"avgAmplitude"` // rangeAvgPeriod-day average, %
+	Expansions   []string   `json:"expansions"`   // dates of range expansions
+	Latest       float64    `json:"latest"`
+	LatestAvg    float64    `json:"latestAvg"`
+	Percentile   float64    `json:"percentile"` // share of the amplitudes in range below the latest, %
+}
+
+// rangeStats computes the amplitude statistics of bars
+func rangeStats(symbol string, bars []Bar) RangeStats {
+	opens := make([]float64, len(bars))
+	for i, b := range bars {
+		opens[i] = b.Open
+	}
+	highs, lows := highsLows(bars)
+	amp := indicators.Amplitude(highs, lows, opens, closes(bars))
+	avg := indicators.SMA(amp, rangeAvgPeriod)
+	expansion := indicators.RangeExpansion(amp, rangeAvgPeriod, rangeExpansionFactor)
+
+	s := RangeStats{Symbol: symbol, Dates: make([]string, len(bars)), Amplitude: nullable(amp), AvgAmplitude: nullable(avg), Expansions: []string{}}
+	for i, b := range bars {
+		s.Dates[i] = b.Date
+		if expansion[i] == 1 {
+			s.Expansions = append(s.Expansions, b.Date)
+		}
+	}
+	if len(bars) == 0 {
+		return s
+	}
+	s.Latest = amp[len(amp)-1]
+	s.LatestAvg = indicators.LastValid(avg)
+	sorted := make([]float64, 0, len(amp))
+	for _, v := range amp {
+		if !math.IsNaN(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Float64s(sorted)
+	if n := len(sorted); n > 0 {
+		s.Percentile = float64(sort.SearchFloat64s(sorted, s.Latest)) / float64(n) * 100
+	}
+	return s
+}
+
+// GetRangeStats returns the daily amplitude of symbol over the last days
+// with its average and the days the range expanded. days of 0 uses the
+// configured lookback and lookbackMax (-1) all available history.
+func (a *App) GetRangeStats(symbol string, days int) (string, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(rangeStats(symbol, bars))
+}
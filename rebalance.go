@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RebalanceTrade is one order needed to reach the target weights
+type RebalanceTrade struct {
+	Symbol        string  `json:"symbol"`
+	Side          string  `json:"side"` // "buy" or "sell"
+	Shares        float64 `json:"shares"`
+	Price         float64 `json:"price"`
+	Amount        float64 `json:"amount"`
+	EstFees       float64 `json:"estFees"`
+	CurrentWeight float64 `json:"currentWeight"`
+	TargetWeight  float64 `json:"targetWeight"`
+	ResultWeight  float64 `json:"resultWeight"`
+}
+
+// RebalancePlan is the trade list together with the resulting cash
+type RebalancePlan struct {
+	TotalValue float64          `json:"totalValue"`
+	CashBefore float64          `json:"cashBefore"`
+	CashAfter  float64          `json:"cashAfter"`
+	CashBuffer float64          `json:"cashBuffer"`
+	Trades     []RebalanceTrade `json:"trades"`
+	Warnings   []string         `json:"warnings,omitempty"`
+}
+
+// rebalancePosition is the input state of one symbol
+type rebalancePosition struct {
+	shares float64
+	price  float64
+	target float64 // fraction of investable value
+}
+
+// planRebalance computes the lot-rounded trades that move positions
+// towards their targets while keeping bufferPct of the total in cash.
+// Sells are sized first so their proceeds can fund the buys.
+func planRebalance(positions map[string]rebalancePosition, cash, bufferPct float64) RebalancePlan {
+	symbols := make([]string, 0, len(positions))
+	total := cash
+	for sym, pos := range positions {
+		symbols = append(symbols, sym)
+		total += pos.shares * pos.price
+	}
+	sort.Strings(symbols)
+
+	plan := RebalancePlan{TotalValue: total, CashBefore: cash, CashBuffer: total * bufferPct / 100, Trades: []RebalanceTrade{}}
+	investable := total - plan.CashBuffer
+	available := cash - plan.CashBuffer
+	resultShares := make(map[string]float64, len(positions))
+
+	var buys []RebalanceTrade
+	for _, sym := range symbols {
+		pos := positions[sym]
+		resultShares[sym] = pos.shares
+		if pos.price <= 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("no price for %s, skipped", sym))
+			continue
+		}
+		trade := RebalanceTrade{
+			Symbol:        sym,
+			Price:         pos.price,
+			CurrentWeight: pos.shares * pos.price / total * 100,
+			TargetWeight:  pos.target * 100,
+		}
+		diff := pos.target*investable - pos.shares*pos.price
+		lots := math.Floor(math.Abs(diff) / pos.price / boardLot)
+		buy := 0.0
+		if diff > 0 {
+			buy = float64(buyQuantity(sym, int(diff/pos.price)))
+		}
+		switch {
+		case pos.target == 0 && pos.shares > 0:
+			// Exiting a position may sell odd lots
+			trade.Side = "sell"
+			trade.Shares = pos.shares
+		case diff < 0 && lots > 0:
+			trade.Side = "sell"
+			trade.Shares = math.Min(lots*boardLot, pos.shares)
+		case buy > 0:
+			trade.Side = "buy"
+			trade.Shares = buy
+		default:
+			continue
+		}
+		trade.Amount = trade.Shares * pos.price
+		trade.EstFees = paperFees(trade.Side, trade.Amount)
+		if trade.Side == "sell" {
+			available += trade.Amount - trade.EstFees
+			resultShares[sym] -= trade.Shares
+			plan.Trades = append(plan.Trades, trade)
+		} else {
+			buys = append(buys, trade)
+		}
+	}
+
+	// Fund the largest shortfalls first and trim buys to the cash left
+	sort.Slice(buys, func(i, j int) bool {
+		return buys[i].TargetWeight-buys[i].CurrentWeight > buys[j].TargetWeight-buys[j].CurrentWeight
+	})
+	for _, trade := range buys {
+		for trade.Shares > 0 && trade.Amount+trade.EstFees > available {
+			trade.Shares = float64(buyQuantity(trade.Symbol, int(trade.Shares)-1))
+			trade.Amount = trade.Shares * trade.Price
+			trade.EstFees = paperFees("buy", trade.Amount)
+		}
+		if trade.Shares <= 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("not enough cash to buy %s", trade.Symbol))
+			continue
+		}
+		available -= trade.Amount + trade.EstFees
+		resultShares[trade.Symbol] += trade.Shares
+		plan.Trades = append(plan.Trades, trade)
+	}
+
+	plan.CashAfter = available + plan.CashBuffer
+	for i := range plan.Trades {
+		t := &plan.Trades[i]
+		t.ResultWeight = resultShares[t.Symbol] * t.Price / total * 100
+	}
+	return plan
+}
+
+// GetRebalancePlan returns the trades that bring the portfolio to the
+// target weights. targetsJSON maps symbol to target weight in %; holdings
+// missing from it are sold. cash is the uninvested cash available and
+// cashBufferPct the share of total value to keep in cash.
+func (a *App) GetRebalancePlan(targetsJSON string, cash float64, cashBufferPct float64) (string, error) {
+	var targets map[string]float64
+	if err := json.Unmarshal([]byte(targetsJSON), &targets); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse target weights: %v", err)
+	}
+	sum := 0.0
+	for sym, w := range targets {
+		if w < 0 {
+			return "", codeErrorf(codeParse, "negative target weight for %s", sym)
+		}
+		sum += w
+	}
+	if sum > 100+1e-6 {
+		return "", codeErrorf(codeParse, "target weights add up to %.2f%%, more than 100%%", sum)
+	}
+	if cashBufferPct < 0 || cashBufferPct >= 100 {
+		return "", codeErrorf(codeParse, "cash buffer must be between 0 and 100%%")
+	}
+
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+	holdings, err := p.holdings()
+	if err != nil {
+		return "", err
+	}
+
+	positions := make(map[string]rebalancePosition)
+	for _, h := range holdings {
+		if h.Shares > 0 {
+			positions[h.Symbol] = rebalancePosition{shares: h.Shares}
+		}
+	}
+	for sym, w := range targets {
+		pos := positions[sym]
+		pos.target = w / 100
+		positions[sym] = pos
+	}
+
+	symbols := make([]string, 0, len(positions))
+	for sym := range positions {
+		symbols = append(symbols, sym)
+	}
+	quotes := fetchQuotes(symbols)
+	for sym, pos := range positions {
+		pos.price = quotes[sym].Price
+		positions[sym] = pos
+	}
+
+	return toJSON(planRebalance(positions, cash, cashBufferPct))
+}
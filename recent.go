@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recentMaxSymbols bounds the recent symbols history
+const recentMaxSymbols = 50
+
+// RecentSymbol is a symbol the user worked with recently
+type RecentSymbol struct {
+	Symbol   string `json:"symbol"`
+	LastUsed string `json:"lastUsed"`
+	Count    int    `json:"count"` // how often it was used
+}
+
+// recentStore persists the recent symbols, most recent first
+type recentStore struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	symbols []RecentSymbol
+}
+
+func newRecentStore(dataDir string) *recentStore {
+	return &recentStore{path: filepath.Join(dataDir, "recent_symbols.json")}
+}
+
+// unload forgets the recent symbols so the next use reads the file again
+func (s *recentStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.symbols, s.loaded = nil, false
+}
+
+// load reads the history from disk on first use. Callers hold s.mu.
+func (s *recentStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.symbols); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// touch moves symbol to the front of the history. Failures are only
+// logged: the history must never break the operation that records it.
+func (s *recentStore) touch(symbol string) {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		logger.Warn("failed to load recent symbols", "error", err)
+		return
+	}
+	entry := RecentSymbol{Symbol: symbol}
+	for i, r := range s.symbols {
+		if r.Symbol == symbol {
+			entry = r
+			s.symbols = append(s.symbols[:i], s.symbols[i+1:]...)
+			break
+		}
+	}
+	entry.LastUsed = shanghaiNow().Format(time.RFC3339)
+	entry.Count++
+	s.symbols = append([]RecentSymbol{entry}, s.symbols...)
+	if len(s.symbols) > recentMaxSymbols {
+		s.symbols = s.symbols[:recentMaxSymbols]
+	}
+	if err := saveJSON(s.path, s.symbols); err != nil {
+		logger.Warn("failed to save recent symbols", "error", err)
+	}
+}
+
+// GetRecentSymbols returns up to limit recently used symbols, most recent
+// first, or most used first if byCount is set
+func (a *App) GetRecentSymbols(limit int, byCount bool) (string, error) {
+	s := a.recent
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	out := append([]RecentSymbol{}, s.symbols...)
+	if byCount {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return toJSON(out)
+}
+
+// RecordRecentSymbol adds a symbol the frontend opened to the history
+func (a *App) RecordRecentSymbol(symbol string) {
+	a.recent.touch(symbol)
+}
+
+// ClearRecentSymbols empties the history
+func (a *App) ClearRecentSymbols() error {
+	s := a.recent
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.symbols = nil
+	s.loaded = true
+	return saveJSON(s.path, []RecentSymbol{})
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/analysis"
+	"stock-analysis/internal/indicators"
+	"stock-analysis/internal/providers"
+)
+
+// SymbolStats summarizes a window of daily bars
+type SymbolStats struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Sessions    int     `json:"sessions"`
+	FirstClose  float64 `json:"firstClose"`
+	LastClose   float64 `json:"lastClose"`
+	Return      float64 `json:"return"` // %
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	AvgVolume   float64 `json:"avgVolume"`   // lots
+	Volatility  float64 `json:"volatility"`  // annualized, %
+	MaxDrawdown float64 `json:"maxDrawdown"` // %
+}
+
+// symbolStats computes the summary statistics of bars, which must not be
+// empty
+func symbolStats(bars []Bar) SymbolStats {
+	first, last := bars[0], bars[len(bars)-1]
+	stats := SymbolStats{
+		From:       first.Date,
+		To:         last.Date,
+		Sessions:   len(bars),
+		FirstClose: first.Close,
+		LastClose:  last.Close,
+		High:       math.Inf(-1),
+		Low:        math.Inf(1),
+	}
+	if first.Close != 0 {
+		stats.Return = (last.Close/first.Close - 1) * 100
+	}
+	curve := make([]EquityPoint, len(bars))
+	volumes := make([]float64, len(bars))
+	for i, b := range bars {
+		stats.High = math.Max(stats.High, b.High)
+		stats.Low = math.Min(stats.Low, b.Low)
+		volumes[i] = b.Volume
+		curve[i] = EquityPoint{Date: b.Date, Equity: b.Close}
+	}
+	stats.AvgVolume = analysis.Mean(volumes)
+	stats.MaxDrawdown = maxDrawdownPct(curve)
+	if returns := analysis.SimpleReturns(closes(bars)); len(returns) > 1 {
+		stats.Volatility = analysis.StdDev(returns) * math.Sqrt(analysis.TradingDaysPerYear) * 100
+	}
+	return stats
+}
+
+// IndicatorReading is the latest value of one indicator
+type IndicatorReading struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// ReportSignal is a signal that fired within the report window
+type ReportSignal struct {
+	Date    string `json:"date"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// AnalysisReport is the content of a shareable symbol report
+type AnalysisReport struct {
+	Symbol      string             `json:"symbol"`
+	Name        string             `json:"name"`
+	Industry    string             `json:"industry"`
+	GeneratedAt string             `json:"generatedAt"`
+	Stats       SymbolStats        `json:"stats"`
+	Indicators  []IndicatorReading `json:"indicators"`
+	Signals     []ReportSignal     `json:"signals"`
+
+	bars   []Bar
+	series []chartSeries
+}
+
+// chartSeries is one line of a report chart
+type chartSeries struct {
+	Name   string
+	Color  string // #rrggbb
+	Values []float64
+}
+
+// crossSignals returns the dates where fast crosses above or below slow
+func crossSignals(bars []Bar, fast, slow []float64, up, down, label string) []ReportSignal {
+	var out []ReportSignal
+	for i := 1; i < len(bars); i++ {
+		prevDiff, diff := fast[i-1]-slow[i-1], fast[i]-slow[i]
+		if math.IsNaN(prevDiff) || math.IsNaN(diff) {
+			continue
+		}
+		switch {
+		case prevDiff <= 0 && diff > 0:
+			out = append(out, ReportSignal{Date: bars[i].Date, Type: up, Message: tr("signal.crossAbove", label)})
+		case prevDiff >= 0 && diff < 0:
+			out = append(out, ReportSignal{Date: bars[i].Date, Type: down, Message: tr("signal.crossBelow", label)})
+		}
+	}
+	return out
+}
+
+// buildReport computes the report of symbol over bars
+func (a *App) buildReport(symbol string, bars []Bar) AnalysisReport {
+	prices := closes(bars)
+	set := symbolIndicators(symbol, bars)
+
+	report := AnalysisReport{
+		Symbol:      symbol,
+		GeneratedAt: shanghaiNow().Format(time.RFC3339),
+		Stats:       symbolStats(bars),
+		Indicators: []IndicatorReading{
+			{"MA5", indicators.LastValid(set.MA5)},
+			{"MA10", indicators.LastValid(set.MA10)},
+			{"MA20", indicators.LastValid(set.MA20)},
+			{"DIF", indicators.LastValid(set.DIF)},
+			{"DEA", indicators.LastValid(set.DEA)},
+			{"MACD", indicators.LastValid(set.Hist)},
+			{"ATR14", indicators.LastValid(set.ATR14)},
+			{tr("report.volume5d"), indicators.LastValid(set.VolumeRate5D)},
+		},
+		bars: bars,
+		series: []chartSeries{
+			{Name: tr("report.close"), Color: "#1f77b4", Values: prices},
+			{Name: "MA20", Color: "#ff7f0e", Values: set.MA20},
+		},
+	}
+	if meta, err := a.metadata.get(symbol, false); err == nil {
+		report.Name, report.Industry = meta.Name, meta.Industry
+	}
+
+	signals := crossSignals(bars, set.DIF, set.DEA, "macdGoldenCross", "macdDeathCross", "DIF/DEA")
+	signals = append(signals, crossSignals(bars, set.MA5, set.MA20, "maGoldenCross", "maDeathCross", "MA5/MA20")...)
+	signals = append(signals, divergenceSignals(divergenceEvents(bars, divergenceFlags(bars, set.VolumeRate5D), set.VolumeRate5D))...)
+	s := a.alerts
+	s.mu.Lock()
+	if err := s.load(); err == nil {
+		code := providers.SohuCode(symbol)
+		for _, t := range s.triggers {
+			if providers.SohuCode(t.Symbol) == code && t.Date >= report.Stats.From {
+				signals = append(signals, ReportSignal{Date: t.Date, Type: t.Type, Message: t.Message})
+			}
+		}
+	}
+	s.mu.Unlock()
+	sort.SliceStable(signals, func(i, j int) bool { return signals[i].Date > signals[j].Date })
+	report.Signals = append([]ReportSignal{}, signals...)
+	return report
+}
+
+// chartFrame maps series values onto a w×h plot area
+type chartFrame struct {
+	n        int
+	min, max float64
+	w, h     float64
+}
+
+func newChartFrame(series []chartSeries, w, h float64) chartFrame {
+	f := chartFrame{min: math.Inf(1), max: math.Inf(-1), w: w, h: h}
+	for _, s := range series {
+		f.n = len(s.Values)
+		for _, v := range s.Values {
+			if !math.IsNaN(v) {
+				f.min = math.Min(f.min, v)
+				f.max = math.Max(f.max, v)
+			}
+		}
+	}
+	if f.max <= f.min {
+		f.max = f.min + 1
+	}
+	return f
+}
+
+// x returns the horizontal offset of point i
+func (f chartFrame) x(i int) float64 {
+	if f.n < 2 {
+		return 0
+	}
+	return float64(i) / float64(f.n-1) * f.w
+}
+
+// y returns the distance of v from the bottom of the plot area
+func (f chartFrame) y(v float64) float64 {
+	return (v - f.min) / (f.max - f.min) * f.h
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"num":  func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"int":  func(v float64) string { return fmt.Sprintf("%.0f", v) },
+	"t":    tr,
+	"lang": func() string { return currentSettings().Locale },
+}).Parse(`<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{t "report.title" .Symbol .Name}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 32px auto; max-width: 820px; color: #222; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+h2 { font-size: 16px; border-bottom: 1px solid #ddd; padding-bottom: 4px; margin-top: 28px; }
+.meta { color: #666; font-size: 13px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+td, th { padding: 4px 8px; border-bottom: 1px solid #eee; text-align: left; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+</style>
+</head>
+<body>
+<h1>{{.Symbol}} {{.Name}}</h1>
+<div class="meta">{{if .Industry}}{{.Industry}} · {{end}}{{.Stats.From}} – {{.Stats.To}} · {{t "report.generated" .GeneratedAt}}</div>
+
+<h2>{{t "report.summary"}}</h2>
+<table>
+<tr><td>{{t "report.sessions"}}</td><td class="n">{{.Stats.Sessions}}</td><td>{{t "report.return"}}</td><td class="n">{{num .Stats.Return}}%</td></tr>
+<tr><td>{{t "report.firstClose"}}</td><td class="n">{{num .Stats.FirstClose}}</td><td>{{t "report.lastClose"}}</td><td class="n">{{num .Stats.LastClose}}</td></tr>
+<tr><td>{{t "report.high"}}</td><td class="n">{{num .Stats.High}}</td><td>{{t "report.low"}}</td><td class="n">{{num .Stats.Low}}</td></tr>
+<tr><td>{{t "report.avgVolume"}}</td><td class="n">{{int .Stats.AvgVolume}}</td><td>{{t "report.volatility"}}</td><td class="n">{{num .Stats.Volatility}}%</td></tr>
+<tr><td>{{t "report.maxDrawdown"}}</td><td class="n">{{num .Stats.MaxDrawdown}}%</td><td></td><td></td></tr>
+</table>
+
+<h2>{{t "report.chart"}}</h2>
+{{.Chart}}
+
+<h2>{{t "report.indicators"}}</h2>
+<table>
+{{range .Indicators}}<tr><td>{{.Name}}</td><td class="n">{{num .Value}}</td></tr>
+{{end}}</table>
+
+<h2>{{t "report.signals"}}</h2>
+{{if .Signals}}<table>
+<tr><th>{{t "report.date"}}</th><th>{{t "report.type"}}</th><th>{{t "report.message"}}</th></tr>
+{{range .Signals}}<tr><td>{{.Date}}</td><td>{{.Type}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{else}}<p class="meta">{{t "report.noSignals"}}</p>{{end}}
+</body>
+</html>
+`))
+
+// html renders the report as a standalone HTML page with an inline chart
+func (r AnalysisReport) html() (string, error) {
+	opts := ChartOptions{Width: 760, Height: 420}
+	opts.normalize()
+	chart, err := renderCandleChart(strings.TrimSpace(r.Symbol+" "+r.Name), r.bars, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to render chart: %v", err)
+	}
+	var b bytes.Buffer
+	err = reportHTML.Execute(&b, struct {
+		AnalysisReport
+		Chart template.HTML
+	}{r, template.HTML(chart)})
+	if err != nil {
+		return "", fmt.Errorf("failed to render report: %v", err)
+	}
+	return b.String(), nil
+}
+
+// GenerateReport builds an analysis report of the last days of symbol as
+// "html" (a standalone page) or "pdf" (base64-encoded). days of 0 uses the
+// configured lookback and lookbackMax (-1) all available history.
+func (a *App) GenerateReport(symbol string, days int, format string) (string, error) {
+	a.recent.touch(symbol)
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	report := a.buildReport(symbol, bars)
+	var out string
+	switch format {
+	case "html", "":
+		format = "html"
+		out, err = report.html()
+	case "pdf":
+		out = base64.StdEncoding.EncodeToString(report.pdf())
+	default:
+		return "", codeErrorf(codeParse, "unknown report format: %s", format)
+	}
+	recordAudit(auditExport, "report", symbol, fmt.Sprintf("%s, %d bars", format, len(bars)), err)
+	return out, err
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/analysis"
+)
+
+// defaultBenchmark is the index portfolios are compared against
+const defaultBenchmark = "zs_000001"
+
+// PositionRisk is the risk attributed to a single holding
+type PositionRisk struct {
+	Symbol           string  `json:"symbol"`
+	Sector           string  `json:"sector"`
+	Weight           float64 `json:"weight"`
+	Volatility       float64 `json:"volatility"`
+	Beta             float64 `json:"beta"`
+	RiskContribution float64 `json:"riskContribution"` // share of portfolio variance, %
+}
+
+// SectorWeight is the portfolio weight of one industry
+type SectorWeight struct {
+	Sector string  `json:"sector"`
+	Weight float64 `json:"weight"`
+}
+
+// RiskReport summarizes portfolio risk over the lookback window
+type RiskReport struct {
+	AsOf          string         `json:"asOf"`
+	Benchmark     string         `json:"benchmark"`
+	Observations  int            `json:"observations"`
+	MarketValue   float64        `json:"marketValue"`
+	Volatility    float64        `json:"volatility"` // annualized, %
+	Beta          float64        `json:"beta"`
+	VaR95         float64        `json:"var95"` // one-day historical VaR in CNY
+	VaR99         float64        `json:"var99"`
+	VaR95Pct      float64        `json:"var95Pct"`
+	VaR99Pct      float64        `json:"var99Pct"`
+	Sectors       []SectorWeight `json:"sectors"`
+	SectorHHI     float64        `json:"sectorHHI"` // Herfindahl index of sector weights, 0-1
+	LargestWeight float64        `json:"largestWeight"`
+	Positions     []PositionRisk `json:"positions"`
+}
+
+// eastmoneySecID returns the secid Eastmoney uses for an A-share symbol
+func eastmoneySecID(symbol string) string {
+	code := strings.TrimPrefix(strings.TrimPrefix(symbol, "cn_"), "zs_")
+	if strings.HasPrefix(symbol, "zs_") {
+		// Shanghai indices start with 000, Shenzhen indices with 399
+		if strings.HasPrefix(code, "399") {
+			return "0." + code
+		}
+		return "1." + code
+	}
+	if strings.HasPrefix(code, "6") || strings.HasPrefix(code, "9") || strings.HasPrefix(code, "5") {
+		return "1." + code
+	}
+	return "0." + code
+}
+
+// portfolioRisk computes the risk report for weights (by symbol) from the
+// aligned close prices of the holdings and the benchmark
+func portfolioRisk(weights map[string]float64, prices map[string][]float64, benchmark string) RiskReport {
+	report := RiskReport{Benchmark: benchmark, Positions: []PositionRisk{}, Sectors: []SectorWeight{}}
+
+	symbols := make([]string, 0, len(weights))
+	for sym := range weights {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	returns := make(map[string][]float64, len(symbols))
+	for _, sym := range symbols {
+		returns[sym] = analysis.SimpleReturns(prices[sym])
+	}
+	benchReturns := analysis.SimpleReturns(prices[benchmark])
+	n := len(benchReturns)
+	report.Observations = n
+	if n < 2 {
+		return report
+	}
+
+	// Portfolio returns assume the current weights were held throughout
+	portReturns := make([]float64, n)
+	for _, sym := range symbols {
+		for t, r := range returns[sym] {
+			portReturns[t] += weights[sym] * r
+		}
+	}
+
+	annualize := math.Sqrt(analysis.TradingDaysPerYear) * 100
+	benchVar := analysis.Variance(benchReturns)
+	portVar := analysis.Variance(portReturns)
+	report.Volatility = math.Sqrt(portVar) * annualize
+	if benchVar > 0 {
+		report.Beta = analysis.Covariance(portReturns, benchReturns) / benchVar
+	}
+	report.VaR95Pct = math.Max(0, -analysis.Percentile(portReturns, 5)*100)
+	report.VaR99Pct = math.Max(0, -analysis.Percentile(portReturns, 1)*100)
+
+	for _, sym := range symbols {
+		pr := PositionRisk{
+			Symbol:     sym,
+			Weight:     weights[sym] * 100,
+			Volatility: analysis.StdDev(returns[sym]) * annualize,
+		}
+		if benchVar > 0 {
+			pr.Beta = analysis.Covariance(returns[sym], benchReturns) / benchVar
+		}
+		if portVar > 0 {
+			// w_i * cov(r_i, r_p) / var(r_p) sums to 1 across positions
+			pr.RiskContribution = weights[sym] * analysis.Covariance(returns[sym], portReturns) / portVar * 100
+		}
+		report.LargestWeight = math.Max(report.LargestWeight, pr.Weight)
+		report.Positions = append(report.Positions, pr)
+	}
+	return report
+}
+
+// GetPortfolioRiskReport computes volatility, beta against benchmark
+// (defaults to the Shanghai Composite), historical VaR, sector
+// concentration and per-position risk contribution over the last days
+// calendar days
+func (a *App) GetPortfolioRiskReport(benchmark string, days int) (string, error) {
+	if benchmark == "" {
+		benchmark = defaultBenchmark
+	}
+	if days <= 0 {
+		days = 365
+	}
+
+	p, err := a.portfolio.snapshot()
+	if err != nil {
+		return "", err
+	}
+	holdings, err := p.holdings()
+	if err != nil {
+		return "", err
+	}
+
+	now := shanghaiNow()
+	start := now.AddDate(0, 0, -days)
+	series := make(map[string][]Bar)
+	bench, err := fetchDailyBars(benchmark, start, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to get benchmark data: %w", err)
+	}
+	series[benchmark] = bench
+
+	marketValues := make(map[string]float64)
+	total := 0.0
+	for _, h := range holdings {
+		if h.Shares <= 0 {
+			continue
+		}
+		bars, err := fetchDailyBars(h.Symbol, start, now)
+		if err != nil || len(bars) == 0 {
+			return "", fmt.Errorf("failed to get stock data for %s: %v", h.Symbol, err)
+		}
+		series[h.Symbol] = bars
+		marketValues[h.Symbol] = h.Shares * bars[len(bars)-1].Close
+		total += marketValues[h.Symbol]
+	}
+	if total == 0 {
+		return "", codeErrorf(codeNoData, "portfolio has no open positions")
+	}
+
+	weights := make(map[string]float64, len(marketValues))
+	for sym, mv := range marketValues {
+		weights[sym] = mv / total
+	}
+
+	_, prices := alignCloses(series)
+	report := portfolioRisk(weights, prices, benchmark)
+	report.AsOf = now.Format(time.RFC3339)
+	report.MarketValue = total
+	report.VaR95 = report.VaR95Pct / 100 * total
+	report.VaR99 = report.VaR99Pct / 100 * total
+
+	sectorWeights := make(map[string]float64)
+	for i := range report.Positions {
+		pos := &report.Positions[i]
+		sector := tr("risk.unclassified")
+		if meta, err := a.metadata.get(pos.Symbol, false); err == nil && meta.Industry != "" {
+			sector = meta.Industry
+		}
+		pos.Sector = sector
+		sectorWeights[sector] += pos.Weight
+	}
+	for sector, w := range sectorWeights {
+		report.Sectors = append(report.Sectors, SectorWeight{Sector: sector, Weight: w})
+		report.SectorHHI += (w / 100) * (w / 100)
+	}
+	sort.Slice(report.Sectors, func(i, j int) bool { return report.Sectors[i].Weight > report.Sectors[j].Weight })
+
+	return toJSON(report)
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"stock-analysis/internal/analysis"
+)
+
+// Headlines about a stock come from the Eastmoney news search and are
+// scored from -1 (negative) to 1 (positive). The default scorer is a
+// lexicon of Chinese market terms, extended by the user's own words in
+// the settings; a negation just before a term flips it. The scorer can
+// instead be an external API taking {"texts": [...]} and answering
+// {"scores": [...]}, with the "sentiment" API key sent as a bearer token
+// if one is stored. A headline counts toward the session it can first
+// affect: the same day if it appears before the close on a trading day,
+// otherwise the next session. The daily average is set against the
+// 5-day volume rate to see whether news drives the volume spikes.
+
+const (
+	sentimentLexicon = "lexicon"
+	sentimentAPI     = "api"
+
+	// sentimentHeadlines is how many recent headlines are fetched
+	sentimentHeadlines = 100
+	// sentimentSpikeRate is the 5-day volume rate, in %, above which a
+	// session counts as a volume spike
+	sentimentSpikeRate = 50
+)
+
+// SentimentSettings configure how headlines are scored
+type SentimentSettings struct {
+	Scorer  string             `json:"scorer"`  // "lexicon" or "api"
+	URL     string             `json:"url"`     // of the API scorer
+	Lexicon map[string]float64 `json:"lexicon"` // extra terms and weights, positive or negative, for the lexicon scorer
+}
+
+// sentimentTerms is the built-in lexicon: a weight per term
+var sentimentTerms = map[string]float64{
+	"涨停": 2, "大涨": 1.5, "上涨": 1, "增长": 1, "预增": 1.5, "扭亏": 1.5, "盈利": 1, "超预期": 1.5,
+	"中标": 1, "签约": 1, "回购": 1, "增持": 1.5, "分红": 1, "突破": 1, "新高": 1, "创新高": 1, "利好": 1.5,
+	"获批": 1, "合作": 0.5, "创新": 0.5, "提升": 0.5, "买入": 1, "推荐": 0.5, "看好": 1, "反弹": 0.5,
+	"跌停": -2, "大跌": -1.5, "下跌": -1, "下滑": -1, "预减": -1.5, "亏损": -1.5, "首亏": -1.5, "不及预期": -1.5,
+	"减持": -1.5, "质押": -0.5, "违规": -1.5, "处罚": -1.5, "立案": -2, "调查": -1, "诉讼": -1, "退市": -2,
+	"风险": -0.5, "警示": -1, "问询": -0.5, "新低": -1, "利空": -1.5, "暴雷": -2, "爆雷": -2, "解禁": -1,
+}
+
+// sentimentNegations flip a term they directly precede
+var sentimentNegations = []string{"不", "未", "没有", "无", "非"}
+
+// Headline is a scored news headline
+type Headline struct {
+	Time    string  `json:"time"` // "2006-01-02 15:04:05"
+	Session string  `json:"session"`
+	Title   string  `json:"title"`
+	Source  string  `json:"source"`
+	URL     string  `json:"url"`
+	Score   float64 `json:"score"`
+}
+
+// SentimentSeries is the daily news sentiment of a symbol against its
+// volume, one entry per session from the oldest headline
+type SentimentSeries struct {
+	Symbol     string     `json:"symbol"`
+	Scorer     string     `json:"scorer"`
+	Dates      []string   `json:"dates"`
+	Sentiment  []*float64 `json:"sentiment"` // average score; null without news
+	Headlines  []int      `json:"headlines"` // count
+	VolumeRate []*float64 `json:"volumeRate"`
+	Spikes     []string   `json:"spikes"` // sessions with a volume spike
+	// Correlation of the sentiment and of the headline count with the
+	// volume rate, over the sessions with news and all sessions
+	SentimentCorrelation float64    `json:"sentimentCorrelation"`
+	CountCorrelation     float64    `json:"countCorrelation"`
+	SpikesWithNews       float64    `json:"spikesWithNews"` // share of spikes with headlines, %
+	Items                []Headline `json:"items"`
+}
+
+// normalize fills in the scorer and checks the API URL
+func (s *SentimentSettings) normalize() error {
+	switch s.Scorer {
+	case "":
+		s.Scorer = sentimentLexicon
+	case sentimentLexicon:
+	case sentimentAPI:
+		u, err := url.Parse(strings.TrimSpace(s.URL))
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return codeErrorf(codeParse, "invalid sentiment API URL: %s", s.URL)
+		}
+		s.URL = u.String()
+	default:
+		return codeErrorf(codeParse, "unknown sentiment scorer: %s", s.Scorer)
+	}
+	return nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// fetchHeadlines downloads the latest headlines mentioning symbol, newest
+// first
+func fetchHeadlines(symbol string) ([]Headline, error) {
+	if !connectivity.allow() {
+		return nil, errOffline
+	}
+	if err := cooldowns.check(providerEastmoney); err != nil {
+		return nil, err
+	}
+	code := strings.TrimPrefix(strings.TrimPrefix(symbol, "cn_"), "zs_")
+	param, _ := json.Marshal(map[string]interface{}{
+		"uid":           "",
+		"keyword":       code,
+		"type":          []string{"cmsArticleWebOld"},
+		"client":        "web",
+		"clientType":    "web",
+		"clientVersion": "curr",
+		"param": map[string]interface{}{
+			"cmsArticleWebOld": map[string]interface{}{"searchScope": "default", "sort": "time", "pageIndex": 1, "pageSize": sentimentHeadlines, "preTag": "", "postTag": ""},
+		},
+	})
+	started := time.Now()
+	resp, err := providerClient.Get("https://search-api-web.eastmoney.com/search/jsonp?cb=cb&param=" + url.QueryEscape(string(param)))
+	connectivity.report(err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logProviderResponse(resp, body, started)
+	if err := checkProviderResponse(providerEastmoney, resp, body); err != nil {
+		return nil, err
+	}
+
+	// Unwrap the JSONP callback
+	start, end := bytes.IndexByte(body, '('), bytes.LastIndexByte(body, ')')
+	if start < 0 || end < start {
+		return nil, codeErrorf(codeParse, "unexpected news response")
+	}
+	var payload struct {
+		Result struct {
+			Articles []struct {
+				Date      string `json:"date"`
+				Title     string `json:"title"`
+				MediaName string `json:"mediaName"`
+				URL       string `json:"url"`
+			} `json:"cmsArticleWebOld"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body[start+1:end], &payload); err != nil {
+		return nil, codeErrorf(codeParse, "failed to parse JSON: %v", err)
+	}
+	headlines := make([]Headline, 0, len(payload.Result.Articles))
+	for _, a := range payload.Result.Articles {
+		headlines = append(headlines, Headline{
+			Time:   a.Date,
+			Title:  strings.TrimSpace(htmlTag.ReplaceAllString(a.Title, "")),
+			Source: a.MediaName,
+			URL:    a.URL,
+		})
+	}
+	return headlines, nil
+}
+
+// lexiconScore scores text from -1 to 1 by the terms of lexicon it
+// contains, 0 if none
+func lexiconScore(text string, lexicon map[string]float64) float64 {
+	// Longer terms first, so 创新高 (a new high) is not read as 创新
+	// (innovation)
+	terms := make([]string, 0, len(lexicon))
+	for term := range lexicon {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) > len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	sum, weight := 0.0, 0.0
+	for _, term := range terms {
+		for {
+			i := strings.Index(text, term)
+			if i < 0 {
+				break
+			}
+			w := lexicon[term]
+			for _, neg := range sentimentNegations {
+				if strings.HasSuffix(text[:i], neg) {
+					w = -w
+					break
+				}
+			}
+			sum += w
+			weight += math.Abs(w)
+			// Blank the match so shorter terms inside it do not count again
+			text = text[:i] + strings.Repeat(" ", len(term)) + text[i+len(term):]
+		}
+	}
+	if weight == 0 {
+		return 0
+	}
+	return sum / weight
+}
+
+// scoreHeadlines sets the score of each headline with the configured
+// scorer
+func (a *App) scoreHeadlines(settings SentimentSettings, headlines []Headline) error {
+	if len(headlines) == 0 {
+		return nil
+	}
+	if settings.Scorer != sentimentAPI {
+		lexicon := make(map[string]float64, len(sentimentTerms)+len(settings.Lexicon))
+		for term, w := range sentimentTerms {
+			lexicon[term] = w
+		}
+		for term, w := range settings.Lexicon {
+			if term = strings.TrimSpace(term); term != "" {
+				lexicon[term] = w
+			}
+		}
+		for i := range headlines {
+			headlines[i].Score = lexiconScore(headlines[i].Title, lexicon)
+		}
+		return nil
+	}
+
+	texts := make([]string, len(headlines))
+	for i, h := range headlines {
+		texts[i] = h.Title
+	}
+	scores, err := callSentimentAPI(settings.URL, a.sentimentToken(), texts)
+	if err != nil {
+		return err
+	}
+	for i := range headlines {
+		headlines[i].Score = math.Max(-1, math.Min(1, scores[i]))
+	}
+	return nil
+}
+
+// sentimentToken returns the stored sentiment API key, empty if none
+func (a *App) sentimentToken() string {
+	if a.apiKeys == nil {
+		return ""
+	}
+	token, err := a.apiKeys.token("sentiment")
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// callSentimentAPI scores texts with the API at endpoint
+func callSentimentAPI(endpoint, token string, texts []string) ([]float64, error) {
+	data, err := json.Marshal(map[string][]string{"texts": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	// Straight to the connection pool: fixtures would write the token to disk
+	client := &http.Client{Transport: providerTransport, Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sentiment API returned %s: %s", resp.Status, strings.TrimSpace(string(reply)))
+	}
+	var result struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return nil, codeErrorf(codeParse, "failed to parse sentiment scores: %v", err)
+	}
+	if len(result.Scores) != len(texts) {
+		return nil, fmt.Errorf("sentiment API returned %d scores for %d headlines", len(result.Scores), len(texts))
+	}
+	return result.Scores, nil
+}
+
+// headlineSession returns the session a headline published at stamp can
+// first affect
+func headlineSession(stamp string) string {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", stamp, shanghaiNow().Location())
+	if err != nil {
+		return ""
+	}
+	date := t.Format("2006-01-02")
+	if isTradingDay(t) && t.Hour() < 15 {
+		return date
+	}
+	if next := nextTradingDays(date, 1); len(next) == 1 {
+		return next[0]
+	}
+	return ""
+}
+
+// sentimentSeries aggregates scored headlines by session against the
+// volume rate of bars
+func sentimentSeries(symbol, scorer string, headlines []Headline, bars []Bar) SentimentSeries {
+	s := SentimentSeries{Symbol: symbol, Scorer: scorer, Spikes: []string{}, Items: headlines}
+	sum, count := map[string]float64{}, map[string]int{}
+	oldest := ""
+	for i := range headlines {
+		h := &headlines[i]
+		h.Session = headlineSession(h.Time)
+		if h.Session == "" {
+			continue
+		}
+		sum[h.Session] += h.Score
+		count[h.Session]++
+		if oldest == "" || h.Session < oldest {
+			oldest = h.Session
+		}
+	}
+	rates := symbolIndicators(symbol, bars).VolumeRate5D
+
+	var withNews, newsRates, counts, allRates []float64
+	spikesWithNews := 0
+	for i, b := range bars {
+		if oldest == "" || b.Date < oldest {
+			continue
+		}
+		s.Dates = append(s.Dates, b.Date)
+		n := count[b.Date]
+		s.Headlines = append(s.Headlines, n)
+		if rate := rates[i]; math.IsNaN(rate) {
+			s.VolumeRate = append(s.VolumeRate, nil)
+		} else {
+			s.VolumeRate = append(s.VolumeRate, &rate)
+		}
+		if n > 0 {
+			avg := sum[b.Date] / float64(n)
+			s.Sentiment = append(s.Sentiment, &avg)
+		} else {
+			s.Sentiment = append(s.Sentiment, nil)
+		}
+		if math.IsNaN(rates[i]) {
+			continue
+		}
+		counts, allRates = append(counts, float64(n)), append(allRates, rates[i])
+		if n > 0 {
+			withNews, newsRates = append(withNews, sum[b.Date]/float64(n)), append(newsRates, rates[i])
+		}
+		if rates[i] > sentimentSpikeRate {
+			s.Spikes = append(s.Spikes, b.Date)
+			if n > 0 {
+				spikesWithNews++
+			}
+		}
+	}
+	s.SentimentCorrelation = correlation(withNews, newsRates)
+	s.CountCorrelation = correlation(counts, allRates)
+	if len(s.Spikes) > 0 {
+		s.SpikesWithNews = float64(spikesWithNews) / float64(len(s.Spikes)) * 100
+	}
+	return s
+}
+
+// correlation returns the Pearson correlation of a and b, 0 when either
+// does not vary
+func correlation(a, b []float64) float64 {
+	sa, sb := analysis.StdDev(a), analysis.StdDev(b)
+	if sa == 0 || sb == 0 {
+		return 0
+	}
+	return analysis.Covariance(a, b) / (sa * sb)
+}
+
+// GetNewsSentiment returns the latest headlines about symbol with their
+// sentiment, and the daily sentiment set against the volume rate
+func (a *App) GetNewsSentiment(symbol string) (string, error) {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+	settings := currentSettings()
+	key := "sentiment:" + settings.Sentiment.Scorer + ":" + symbol
+	if v, ok := resultCache.get(key); ok {
+		return toJSON(v)
+	}
+	headlines, err := fetchHeadlines(symbol)
+	if err != nil {
+		return "", err
+	}
+	if err := a.scoreHeadlines(settings.Sentiment, headlines); err != nil {
+		return "", err
+	}
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, 0, now), now)
+	if err != nil {
+		return "", err
+	}
+	series := sentimentSeries(symbol, settings.Sentiment.Scorer, headlines, bars)
+	resultCache.set(key, series, settings.analysisTTL())
+	return toJSON(series)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// sessionMaxSymbols bounds the last-viewed symbols kept in the session
+const sessionMaxSymbols = 20
+
+// WindowLayout is the size and position of the main window
+type WindowLayout struct {
+	Width     int  `json:"width"`
+	Height    int  `json:"height"`
+	X         int  `json:"x"`
+	Y         int  `json:"y"`
+	Maximised bool `json:"maximised"`
+}
+
+// SessionState is what the app reopens with
+type SessionState struct {
+	Symbols         []string          `json:"symbols"` // last viewed, most recent first
+	ActiveSymbol    string            `json:"activeSymbol"`
+	ActiveWatchlist string            `json:"activeWatchlist"`
+	Window          WindowLayout      `json:"window"`
+	Layout          map[string]string `json:"layout"`      // free-form layout hints of the frontend
+	ChartRanges     map[string]int    `json:"chartRanges"` // days shown per chart
+	Windows         []SymbolWindow    `json:"windows"`     // open symbol windows, bottom first
+	Hotkeys         map[string]string `json:"hotkeys"`     // command hotkeys changed from the defaults
+	SavedAt         string            `json:"savedAt"`
+}
+
+// normalize drops empty and duplicate symbols and fills nil maps
+func (s *SessionState) normalize() {
+	seen := make(map[string]bool)
+	symbols := []string{}
+	for _, sym := range s.Symbols {
+		if sym = normalizeSymbol(sym); sym != "" && !seen[sym] && len(symbols) < sessionMaxSymbols {
+			seen[sym] = true
+			symbols = append(symbols, sym)
+		}
+	}
+	s.Symbols = symbols
+	s.ActiveSymbol = normalizeSymbol(s.ActiveSymbol)
+	if s.Layout == nil {
+		s.Layout = map[string]string{}
+	}
+	if s.ChartRanges == nil {
+		s.ChartRanges = map[string]int{}
+	}
+	if s.Hotkeys == nil {
+		s.Hotkeys = map[string]string{}
+	}
+	windows := []SymbolWindow{}
+	for _, w := range s.Windows {
+		if w.Symbol = normalizeSymbol(w.Symbol); w.Symbol != "" && w.ID != "" && len(windows) < maxSymbolWindows {
+			if w.Layout == nil {
+				w.Layout = map[string]string{}
+			}
+			windows = append(windows, w)
+		}
+	}
+	s.Windows = windows
+}
+
+// clone returns a copy that shares no slices or maps with s
+func (s SessionState) clone() SessionState {
+	out := s
+	out.Symbols = append([]string(nil), s.Symbols...)
+	out.Layout = make(map[string]string, len(s.Layout))
+	for k, v := range s.Layout {
+		out.Layout[k] = v
+	}
+	out.ChartRanges = make(map[string]int, len(s.ChartRanges))
+	for k, v := range s.ChartRanges {
+		out.ChartRanges[k] = v
+	}
+	out.Hotkeys = make(map[string]string, len(s.Hotkeys))
+	for k, v := range s.Hotkeys {
+		out.Hotkeys[k] = v
+	}
+	out.Windows = make([]SymbolWindow, len(s.Windows))
+	for i, w := range s.Windows {
+		out.Windows[i] = w.clone()
+	}
+	return out
+}
+
+// sessionStore persists the session
+type sessionStore struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	session SessionState
+}
+
+func newSessionStore(dataDir string) *sessionStore {
+	return &sessionStore{path: filepath.Join(dataDir, "session.json")}
+}
+
+// unload forgets the session so the next use reads the file again
+func (s *sessionStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session, s.loaded = SessionState{}, false
+}
+
+// load reads the session from disk on first use. Callers hold s.mu.
+func (s *sessionStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.session = SessionState{}
+	if err := loadJSON(s.path, &s.session); err != nil {
+		return err
+	}
+	s.session.normalize()
+	s.loaded = true
+	return nil
+}
+
+// update applies fn to the session and saves it
+func (s *sessionStore) update(fn func(session *SessionState) error) (SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return SessionState{}, err
+	}
+	updated := s.session.clone()
+	if err := fn(&updated); err != nil {
+		return SessionState{}, err
+	}
+	updated.normalize()
+	updated.SavedAt = shanghaiNow().Format(time.RFC3339)
+	if err := saveJSON(s.path, updated); err != nil {
+		return SessionState{}, err
+	}
+	s.session = updated
+	return updated.clone(), nil
+}
+
+// get returns the session
+func (s *sessionStore) get() (SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return SessionState{}, err
+	}
+	return s.session.clone(), nil
+}
+
+// captureWindow records the size and position of the main window
+func (a *App) captureWindow() {
+	if a.ctx == nil {
+		return
+	}
+	var layout WindowLayout
+	layout.Maximised = wailsruntime.WindowIsMaximised(a.ctx)
+	layout.Width, layout.Height = wailsruntime.WindowGetSize(a.ctx)
+	layout.X, layout.Y = wailsruntime.WindowGetPosition(a.ctx)
+	if _, err := a.session.update(func(s *SessionState) error {
+		s.Window = layout
+		return nil
+	}); err != nil {
+		logger.Warn("failed to save window layout", "error", err)
+	}
+}
+
+// restoreWindow puts the main window back where the last session left it
+func (a *App) restoreWindow() {
+	session, err := a.session.get()
+	if err != nil || session.Window.Width <= 0 || session.Window.Height <= 0 {
+		return
+	}
+	if session.Window.Maximised {
+		wailsruntime.WindowMaximise(a.ctx)
+		return
+	}
+	wailsruntime.WindowSetSize(a.ctx, session.Window.Width, session.Window.Height)
+	wailsruntime.WindowSetPosition(a.ctx, session.Window.X, session.Window.Y)
+}
+
+// GetSession returns the state the app should reopen with
+func (a *App) GetSession() (string, error) {
+	session, err := a.session.get()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(session)
+}
+
+// SaveSession merges sessionJSON (a partial SessionState) into the
+// session and returns the result
+func (a *App) SaveSession(sessionJSON string) (string, error) {
+	session, err := a.session.update(func(s *SessionState) error {
+		if err := json.Unmarshal([]byte(sessionJSON), s); err != nil {
+			return codeErrorf(codeParse, "failed to parse session: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return toJSON(session)
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"stock-analysis/internal/providers"
+)
+
+const (
+	// settingsPollInterval is how often settings.json is checked for edits
+	settingsPollInterval = 2 * time.Second
+
+	eventSettingsChanged = "settings:changed"
+	eventSettingsError   = "settings:error"
+)
+
+// knownProviders are the daily bar providers the app can download from
+var knownProviders = []string{"sohu", demoProvider}
+
+// lookbackMax as a window length selects all history available: whatever
+// the local store holds, topped up from the provider
+const lookbackMax = -1
+
+// RefreshSettings are the refresh intervals, in seconds
+type RefreshSettings struct {
+	QuoteSeconds    int `json:"quoteSeconds"`    // how long a quote is cached
+	AnalysisSeconds int `json:"analysisSeconds"` // how long an analysis result is cached
+	AlertSeconds    int `json:"alertSeconds"`    // how often alert rules are evaluated
+}
+
+// Settings are the user preferences persisted in settings.json
+type Settings struct {
+	DefaultSymbols []string          `json:"defaultSymbols"` // the first is the market overview index
+	LookbackDays   int               `json:"lookbackDays"`   // default history window in calendar days, or lookbackMax
+	Refresh        RefreshSettings   `json:"refresh"`
+	Providers      []string          `json:"providers"` // bar providers in priority order
+	Proxy          string            `json:"proxy"`     // http, https or socks5 URL; empty uses the environment
+	TrayMode       bool              `json:"trayMode"`  // closing the window hides it and keeps quotes updating
+	Push           PushSettings      `json:"push"`
+	GRPC           GRPCSettings      `json:"grpc"`
+	Fixtures       string            `json:"fixtures"`       // "record" or "replay" provider responses; empty for neither
+	Quotas         map[string]int    `json:"quotas"`         // daily request limits by provider overriding the known ones; 0 for none
+	CrashReportURL string            `json:"crashReportUrl"` // where SendCrashReport posts; empty disables sending
+	UpdateChannel  string            `json:"updateChannel"`  // "stable" or "beta"
+	Locale         string            `json:"locale"`         // language of backend messages: "zh-CN" or "en"
+	Model          ModelSettings     `json:"model"`          // ONNX model behind the ML_SCORE variable
+	Sentiment      SentimentSettings `json:"sentiment"`      // how news headlines are scored
+}
+
+// defaultSettings returns the values used before the user changes anything
+func defaultSettings() Settings {
+	return Settings{
+		DefaultSymbols: []string{defaultBenchmark},
+		LookbackDays:   180,
+		Refresh: RefreshSettings{
+			QuoteSeconds:    int(quoteCacheTTL / time.Second),
+			AnalysisSeconds: int(analysisCacheTTL / time.Second),
+			AlertSeconds:    int(alertCheckInterval / time.Second),
+		},
+		Providers:     []string{"sohu"},
+		UpdateChannel: updateChannelStable,
+		Locale:        defaultLocale,
+	}
+}
+
+// normalize fills unset fields with defaults and validates the rest
+func (s *Settings) normalize() error {
+	def := defaultSettings()
+	var symbols []string
+	for _, sym := range s.DefaultSymbols {
+		if sym = normalizeSymbol(sym); sym != "" {
+			symbols = append(symbols, sym)
+		}
+	}
+	s.DefaultSymbols = symbols
+	if len(s.DefaultSymbols) == 0 {
+		s.DefaultSymbols = def.DefaultSymbols
+	}
+	if s.LookbackDays == 0 || s.LookbackDays < lookbackMax {
+		s.LookbackDays = def.LookbackDays
+	}
+	if s.Refresh.QuoteSeconds <= 0 {
+		s.Refresh.QuoteSeconds = def.Refresh.QuoteSeconds
+	}
+	if s.Refresh.AnalysisSeconds <= 0 {
+		s.Refresh.AnalysisSeconds = def.Refresh.AnalysisSeconds
+	}
+	if s.Refresh.AlertSeconds < 10 {
+		s.Refresh.AlertSeconds = def.Refresh.AlertSeconds
+	}
+	for _, p := range s.Providers {
+		known := false
+		for _, k := range knownProviders {
+			known = known || p == k
+		}
+		if !known {
+			return codeErrorf(codeParse, "unknown provider: %s", p)
+		}
+	}
+	if len(s.Providers) == 0 {
+		s.Providers = def.Providers
+	}
+	if s.demoMode() && len(s.Providers) > 1 {
+		return codeErrorf(codeParse, "the demo provider cannot be combined with other providers")
+	}
+	for provider, quota := range s.Quotas {
+		if quota < 0 {
+			return codeErrorf(codeParse, "invalid quota for %s: %d", provider, quota)
+		}
+	}
+	switch s.Fixtures {
+	case fixturesOff, fixturesRecord, fixturesReplay:
+	default:
+		return codeErrorf(codeParse, "unknown fixtures mode: %s", s.Fixtures)
+	}
+	if s.Push.Port == 0 {
+		s.Push.Port = defaultPushPort
+	}
+	if s.Push.Port < 1 || s.Push.Port > 65535 {
+		return codeErrorf(codeParse, "invalid push port: %d", s.Push.Port)
+	}
+	if s.Push.Enabled && s.Push.Token == "" {
+		s.Push.Token = newID()
+	}
+	if s.GRPC.Port == 0 {
+		s.GRPC.Port = defaultGRPCPort
+	}
+	if s.GRPC.Port < 1 || s.GRPC.Port > 65535 {
+		return codeErrorf(codeParse, "invalid grpc port: %d", s.GRPC.Port)
+	}
+	if s.GRPC.Enabled && s.Push.Enabled && s.GRPC.Port == s.Push.Port {
+		return codeErrorf(codeParse, "the grpc and push servers cannot share port %d", s.GRPC.Port)
+	}
+	if s.GRPC.Enabled && s.GRPC.Token == "" {
+		s.GRPC.Token = newID()
+	}
+	if s.Locale == "" {
+		s.Locale = def.Locale
+	}
+	if _, ok := catalogs[s.Locale]; !ok {
+		return codeErrorf(codeParse, "unsupported locale: %s", s.Locale)
+	}
+	switch s.UpdateChannel {
+	case "":
+		s.UpdateChannel = def.UpdateChannel
+	case updateChannelStable, updateChannelBeta:
+	default:
+		return codeErrorf(codeParse, "unknown update channel: %s", s.UpdateChannel)
+	}
+	if err := s.Model.normalize(); err != nil {
+		return err
+	}
+	if err := s.Sentiment.normalize(); err != nil {
+		return err
+	}
+	s.CrashReportURL = strings.TrimSpace(s.CrashReportURL)
+	if s.CrashReportURL != "" {
+		u, err := url.Parse(s.CrashReportURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return codeErrorf(codeParse, "invalid crash report URL: %s", s.CrashReportURL)
+		}
+	}
+	s.Proxy = strings.TrimSpace(s.Proxy)
+	if s.Proxy != "" {
+		u, err := url.Parse(s.Proxy)
+		if err != nil || u.Host == "" {
+			return codeErrorf(codeParse, "invalid proxy URL: %s", s.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return codeErrorf(codeParse, "unsupported proxy scheme: %s", u.Scheme)
+		}
+	}
+	return nil
+}
+
+// clone returns a copy of s that shares no slice or map with it, so it
+// can be changed or unmarshalled into without touching s
+func (s Settings) clone() Settings {
+	out := s
+	out.DefaultSymbols = append([]string(nil), s.DefaultSymbols...)
+	out.Providers = append([]string(nil), s.Providers...)
+	out.Model.Runner = append([]string(nil), s.Model.Runner...)
+	if s.Quotas != nil {
+		out.Quotas = make(map[string]int, len(s.Quotas))
+		for k, v := range s.Quotas {
+			out.Quotas[k] = v
+		}
+	}
+	if s.Sentiment.Lexicon != nil {
+		out.Sentiment.Lexicon = make(map[string]float64, len(s.Sentiment.Lexicon))
+		for term, w := range s.Sentiment.Lexicon {
+			out.Sentiment.Lexicon[term] = w
+		}
+	}
+	return out
+}
+
+// demoMode reports whether the synthetic demo provider is selected
+func (s Settings) demoMode() bool {
+	for _, p := range s.Providers {
+		if p == demoProvider {
+			return true
+		}
+	}
+	return false
+}
+
+// switchProviders drops the cached quotes and analyses when the demo
+// provider is switched on or off, so real and demo data do not mix
+func switchProviders(prev, next Settings) {
+	if prev.demoMode() != next.demoMode() {
+		resultCache.clear()
+	}
+}
+
+func (s Settings) quoteTTL() time.Duration {
+	return time.Duration(s.Refresh.QuoteSeconds) * time.Second
+}
+
+func (s Settings) analysisTTL() time.Duration {
+	return time.Duration(s.Refresh.AnalysisSeconds) * time.Second
+}
+
+func (s Settings) alertInterval() time.Duration {
+	return time.Duration(s.Refresh.AlertSeconds) * time.Second
+}
+
+// settingsStore persists the settings
+type settingsStore struct {
+	mu       sync.Mutex
+	path     string
+	loaded   bool
+	settings Settings
+	modTime  time.Time // of the file when it was last read or written
+}
+
+// appSettings is read by the free functions that fetch and cache data.
+// openStores points it at the data directory.
+var appSettings *settingsStore
+
+func newSettingsStore(dataDir string) *settingsStore {
+	return &settingsStore{path: filepath.Join(dataDir, "settings.json")}
+}
+
+// unload forgets the settings so the next use reads the file again
+func (s *settingsStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings, s.modTime, s.loaded = Settings{}, time.Time{}, false
+}
+
+// load reads the settings from disk on first use. Callers hold s.mu.
+func (s *settingsStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.settings = defaultSettings()
+	if err := loadJSON(s.path, &s.settings); err != nil {
+		return err
+	}
+	if err := s.settings.normalize(); err != nil {
+		return err
+	}
+	applyProxy(s.settings.Proxy)
+	applyFixtures(s.settings.Fixtures, filepath.Dir(s.path))
+	s.loaded = true
+	s.stat()
+	return nil
+}
+
+// save writes settings to disk and makes them current. Callers hold s.mu.
+func (s *settingsStore) save(settings Settings) error {
+	if err := saveJSON(s.path, settings); err != nil {
+		return err
+	}
+	switchProviders(s.settings, settings)
+	s.settings = settings
+	s.loaded = true
+	s.stat()
+	applyProxy(settings.Proxy)
+	applyFixtures(settings.Fixtures, filepath.Dir(s.path))
+	return nil
+}
+
+// stat records the modification time of the file. Callers hold s.mu.
+func (s *settingsStore) stat() {
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+}
+
+// reload re-reads the file if it was changed outside the app. changed is
+// false if it was not; an invalid file leaves the settings as they were.
+func (s *settingsStore) reload() (settings Settings, changed bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return Settings{}, false, nil
+	} else if err != nil {
+		return Settings{}, false, err
+	}
+	if s.loaded && info.ModTime().Equal(s.modTime) {
+		return Settings{}, false, nil
+	}
+	// Remember the time even if the file is invalid so it is reported once
+	s.modTime = info.ModTime()
+	next := defaultSettings()
+	if err := loadJSON(s.path, &next); err != nil {
+		return Settings{}, false, err
+	}
+	if err := next.normalize(); err != nil {
+		return Settings{}, false, err
+	}
+	switchProviders(s.settings, next)
+	s.settings = next
+	s.loaded = true
+	applyProxy(next.Proxy)
+	applyFixtures(next.Fixtures, filepath.Dir(s.path))
+	return next, true, nil
+}
+
+// get returns the current settings
+func (s *settingsStore) get() (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return Settings{}, err
+	}
+	return s.settings.clone(), nil
+}
+
+// currentSettings returns the settings, falling back to the defaults if
+// they cannot be read
+func currentSettings() Settings {
+	if appSettings == nil {
+		return defaultSettings()
+	}
+	settings, err := appSettings.get()
+	if err != nil {
+		return defaultSettings()
+	}
+	return settings
+}
+
+// lookbackStart returns the first day of a history window of symbol
+// ending at now. days of 0 uses the configured default. lookbackMax starts
+// at the oldest stored bar, or at the built-in default window if nothing
+// is stored yet.
+func lookbackStart(symbol string, days int, now time.Time) time.Time {
+	if days == 0 || days < lookbackMax {
+		days = currentSettings().LookbackDays
+	}
+	if days != lookbackMax {
+		return now.AddDate(0, 0, -days)
+	}
+	if first, ok, err := localHistory.firstDate(symbol); err == nil && ok {
+		if t, err := time.ParseInLocation("2006-01-02", first, now.Location()); err == nil && t.Before(now) {
+			return t
+		}
+	}
+	return now.AddDate(0, 0, -defaultSettings().LookbackDays)
+}
+
+// applyProxy routes provider and other outgoing requests through proxy,
+// or through the proxy named by the environment if it is empty
+func applyProxy(proxy string) {
+	if proxy == "" {
+		providers.SetProxy(nil)
+		return
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return
+	}
+	providers.SetProxy(u)
+}
+
+// runSettingsWatcher applies edits made to settings.json while the app is
+// running and tells the frontend about them
+func (a *App) runSettingsWatcher(ctx context.Context) {
+	ticker := time.NewTicker(settingsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			settings, changed, err := a.settings.reload()
+			if err != nil {
+				logger.Warn("ignoring invalid settings file", "error", err)
+				wailsruntime.EventsEmit(a.ctx, eventSettingsError, err.Error())
+			} else if changed {
+				logger.Info("settings file changed, reloaded")
+				recordAudit(auditSettings, "reload", "settings", "settings.json changed on disk", nil)
+				wailsruntime.EventsEmit(a.ctx, eventSettingsChanged, settings)
+			}
+		}
+	}
+}
+
+// emitSettingsChanged tells the frontend the settings were changed
+func (a *App) emitSettingsChanged(settings Settings) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventSettingsChanged, settings)
+	}
+}
+
+// GetSettings returns the user settings
+func (a *App) GetSettings() (string, error) {
+	settings, err := a.settings.get()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(settings)
+}
+
+// UpdateSettings merges settingsJSON (a partial Settings) into the
+// settings, validates and saves them, and returns the result
+func (a *App) UpdateSettings(settingsJSON string) (string, error) {
+	s := a.settings
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	updated := s.settings.clone()
+	if err := json.Unmarshal([]byte(settingsJSON), &updated); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse settings: %v", err)
+	}
+	if err := updated.normalize(); err != nil {
+		return "", err
+	}
+	changed := changedKeys(s.settings, updated)
+	if err := s.save(updated); err != nil {
+		recordAudit(auditSettings, "update", "settings", changed, err)
+		return "", err
+	}
+	recordAudit(auditSettings, "update", "settings", changed, nil)
+	a.emitSettingsChanged(updated)
+	return toJSON(updated)
+}
+
+// ResetSettings restores the default settings
+func (a *App) ResetSettings() (string, error) {
+	s := a.settings
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.save(defaultSettings())
+	recordAudit(auditSettings, "reset", "settings", "", err)
+	if err != nil {
+		return "", err
+	}
+	a.emitSettingsChanged(s.settings)
+	return toJSON(s.settings)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+)
+
+// shutdownTimeout bounds how long shutdown waits for loops and jobs
+const shutdownTimeout = 5 * time.Second
+
+// runState records whether the previous run shut down cleanly
+type runState struct {
+	StartedAt string `json:"startedAt"`
+	StoppedAt string `json:"stoppedAt,omitempty"`
+	Clean     bool   `json:"clean"`
+}
+
+func (a *App) runStatePath() string {
+	return filepath.Join(a.dataDir, "run_state.json")
+}
+
+// markStarted records the start of a run and logs if the previous one
+// did not shut down cleanly
+func (a *App) markStarted() {
+	var previous runState
+	if err := loadJSON(a.runStatePath(), &previous); err == nil && previous.StartedAt != "" && !previous.Clean {
+		logger.Warn("previous run did not shut down cleanly", "startedAt", previous.StartedAt)
+	}
+	state := runState{StartedAt: shanghaiNow().Format(time.RFC3339)}
+	if err := saveJSON(a.runStatePath(), state); err != nil {
+		logger.Warn("failed to record run state", "error", err)
+	}
+}
+
+// goLoop runs a background loop that stops when the app shuts down. A
+// loop that panics is restarted.
+func (a *App) goLoop(ctx context.Context, loop func(ctx context.Context)) {
+	a.loops.Add(1)
+	where := funcName(loop)
+	go func() {
+		defer a.loops.Done()
+		runLoop(ctx, where, loop)
+	}()
+}
+
+// cancelLoops tells the pollers and schedulers to stop and reports
+// whether they were running
+func (a *App) cancelLoops() bool {
+	a.stateMu.Lock()
+	stop := a.stopLoops
+	a.stopLoops = nil
+	a.stateMu.Unlock()
+	if stop == nil {
+		return false
+	}
+	stop()
+	return true
+}
+
+// loopsRunning reports whether the pollers and schedulers are running
+func (a *App) loopsRunning() bool {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	return a.stopLoops != nil
+}
+
+// shutdown is called when the app is closing. It stops the pollers and
+// schedulers, cancels running jobs, waits briefly for them to wind down
+// and closes the history database so its write-ahead log is checkpointed.
+func (a *App) shutdown(ctx context.Context) {
+	logger.Info("shutting down")
+	a.cancelLoops()
+	a.jobs.cancelAll()
+
+	done := make(chan struct{})
+	go func() {
+		a.loops.Wait()
+		a.jobs.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		logger.Warn("shutdown timed out waiting for background work")
+	}
+
+	if err := localHistory.Close(); err != nil {
+		logger.Warn("failed to close history database", "error", err)
+	}
+	var state runState
+	loadJSON(a.runStatePath(), &state)
+	state.StoppedAt = shanghaiNow().Format(time.RFC3339)
+	state.Clean = true
+	if err := saveJSON(a.runStatePath(), state); err != nil {
+		logger.Warn("failed to record run state", "error", err)
+	}
+}
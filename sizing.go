@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"stock-analysis/internal/indicators"
+	"stock-analysis/internal/providers"
+)
+
+// boardLot is the A-share trading unit for buy orders
+const boardLot = 100
+
+// SizingParams configures a position sizing calculation.
+//
+// Supported methods:
+//   - "fixed": allocate Fraction of equity to the position
+//   - "atr":   risk RiskFraction of equity against a stop ATRMultiple ATRs away
+//   - "kelly": allocate the Kelly fraction implied by WinRate and PayoffRatio,
+//     scaled down by KellyScale (e.g. 0.5 for half-Kelly)
+type SizingParams struct {
+	Method       string  `json:"method"`
+	Fraction     float64 `json:"fraction,omitempty"`
+	RiskFraction float64 `json:"riskFraction,omitempty"`
+	ATRMultiple  float64 `json:"atrMultiple,omitempty"`
+	ATRPeriod    int     `json:"atrPeriod,omitempty"`
+	WinRate      float64 `json:"winRate,omitempty"`
+	PayoffRatio  float64 `json:"payoffRatio,omitempty"`
+	KellyScale   float64 `json:"kellyScale,omitempty"`
+	MaxFraction  float64 `json:"maxFraction,omitempty"` // cap on position value as a fraction of equity
+}
+
+// SizingResult is the outcome of a sizing calculation
+type SizingResult struct {
+	Method        string  `json:"method"`
+	Price         float64 `json:"price"`
+	ATR           float64 `json:"atr,omitempty"`
+	Shares        int     `json:"shares"`
+	Lots          int     `json:"lots"`
+	PositionValue float64 `json:"positionValue"`
+	EquityPct     float64 `json:"equityPct"`
+	RiskAmount    float64 `json:"riskAmount,omitempty"`
+}
+
+// withDefaults fills unset parameters with conservative defaults
+func (p SizingParams) withDefaults() SizingParams {
+	if p.Method == "" {
+		p.Method = "fixed"
+	}
+	if p.Fraction <= 0 {
+		p.Fraction = 0.1
+	}
+	if p.RiskFraction <= 0 {
+		p.RiskFraction = 0.01
+	}
+	if p.ATRMultiple <= 0 {
+		p.ATRMultiple = 2
+	}
+	if p.ATRPeriod <= 0 {
+		p.ATRPeriod = 14
+	}
+	if p.KellyScale <= 0 {
+		p.KellyScale = 0.5
+	}
+	if p.MaxFraction <= 0 || p.MaxFraction > 1 {
+		p.MaxFraction = 1
+	}
+	return p
+}
+
+// size returns the number of shares of symbol to buy, rounded down to a
+// quantity it can be bought in
+func (p SizingParams) size(symbol string, equity, price, atr float64) (SizingResult, error) {
+	p = p.withDefaults()
+	result := SizingResult{Method: p.Method, Price: price, ATR: atr}
+	if equity <= 0 || price <= 0 {
+		return result, codeErrorf(codeParse, "equity and price must be positive")
+	}
+
+	var value float64
+	switch p.Method {
+	case "fixed":
+		value = equity * p.Fraction
+	case "atr":
+		if atr <= 0 || math.IsNaN(atr) {
+			return result, codeErrorf(codeParse, "ATR sizing requires a positive ATR")
+		}
+		result.RiskAmount = equity * p.RiskFraction
+		value = result.RiskAmount / (atr * p.ATRMultiple) * price
+	case "kelly":
+		if p.WinRate <= 0 || p.WinRate >= 1 || p.PayoffRatio <= 0 {
+			return result, codeErrorf(codeParse, "Kelly sizing requires 0 < winRate < 1 and payoffRatio > 0")
+		}
+		kelly := p.WinRate - (1-p.WinRate)/p.PayoffRatio
+		if kelly <= 0 {
+			// Negative edge: the strategy should not be traded at all
+			return result, nil
+		}
+		value = equity * kelly * p.KellyScale
+	default:
+		return result, codeErrorf(codeParse, "unknown sizing method: %s", p.Method)
+	}
+
+	value = math.Min(value, equity*p.MaxFraction)
+	result.Shares = buyQuantity(symbol, int(value/price))
+	result.Lots = result.Shares / boardLot
+	result.PositionValue = float64(result.Shares) * price
+	result.EquityPct = result.PositionValue / equity * 100
+	if p.Method == "atr" {
+		result.RiskAmount = float64(result.Shares) * atr * p.ATRMultiple
+	}
+	return result, nil
+}
+
+// CalculatePositionSize answers "how many shares should I buy" for symbol
+// given the account equity. paramsJSON is a SizingParams object; the
+// latest price and ATR are looked up automatically.
+func (a *App) CalculatePositionSize(symbol string, equity float64, paramsJSON string) (string, error) {
+	var params SizingParams
+	if paramsJSON != "" {
+		if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+			return "", codeErrorf(codeParse, "failed to parse sizing params: %v", err)
+		}
+	}
+	params = params.withDefaults()
+
+	now := shanghaiNow()
+	// Fetch enough history to warm up the ATR
+	bars, err := fetchDailyBars(symbol, now.AddDate(0, 0, -(params.ATRPeriod*2+30)), now)
+	if err != nil {
+		return "", fmt.Errorf("failed to get stock data: %w", err)
+	}
+	quote := providers.QuoteFromBars(symbol, bars)
+	atr := indicators.LastValid(ATR(bars, params.ATRPeriod))
+
+	result, err := params.size(symbol, equity, quote.Price, atr)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(result)
+}
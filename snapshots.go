@@ -0,0 +1,244 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnalysisSnapshot is a saved analysis of a symbol that can be reopened
+// and compared with the symbol's current state
+type AnalysisSnapshot struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Symbol     string             `json:"symbol"`
+	Days       int                `json:"days"` // calendar days the analysis covered, or lookbackMax
+	Note       string             `json:"note"`
+	CreatedAt  string             `json:"createdAt"`
+	Stats      SymbolStats        `json:"stats"`
+	Indicators []IndicatorReading `json:"indicators"`
+	Signals    []ReportSignal     `json:"signals"`
+}
+
+// IndicatorDelta compares one indicator between a snapshot and now
+type IndicatorDelta struct {
+	Name   string  `json:"name"`
+	Then   float64 `json:"then"`
+	Now    float64 `json:"now"`
+	Change float64 `json:"change"`
+}
+
+// SnapshotComparison is a snapshot next to the current analysis of the
+// same symbol over the same window length
+type SnapshotComparison struct {
+	Snapshot    AnalysisSnapshot `json:"snapshot"`
+	Current     AnalysisSnapshot `json:"current"`
+	PriceChange float64          `json:"priceChange"` // % since the snapshot's last close
+	Indicators  []IndicatorDelta `json:"indicators"`
+	NewSignals  []ReportSignal   `json:"newSignals"` // fired after the snapshot's last bar
+}
+
+// snapshotStore persists analysis snapshots
+type snapshotStore struct {
+	mu        sync.Mutex
+	path      string
+	loaded    bool
+	snapshots []AnalysisSnapshot
+}
+
+func newSnapshotStore(dataDir string) *snapshotStore {
+	return &snapshotStore{path: filepath.Join(dataDir, "analysis_snapshots.json")}
+}
+
+// unload forgets the snapshots so the next use reads the file again
+func (s *snapshotStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots, s.loaded = nil, false
+}
+
+// load reads the snapshots from disk on first use. Callers hold s.mu.
+func (s *snapshotStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.snapshots); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// get returns the snapshot with id
+func (s *snapshotStore) get(id string) (AnalysisSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return AnalysisSnapshot{}, err
+	}
+	for _, snap := range s.snapshots {
+		if snap.ID == id {
+			return snap, nil
+		}
+	}
+	return AnalysisSnapshot{}, codeErrorf(codeNotFound, "snapshot not found: %s", id)
+}
+
+// analyze builds the analysis of the last days of symbol in snapshot form
+func (a *App) analyze(symbol string, days int) (AnalysisSnapshot, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return AnalysisSnapshot{}, err
+	}
+	report := a.buildReport(symbol, bars)
+	return AnalysisSnapshot{
+		Symbol:     symbol,
+		Days:       days,
+		CreatedAt:  now.Format(time.RFC3339),
+		Stats:      report.Stats,
+		Indicators: report.Indicators,
+		Signals:    report.Signals,
+	}, nil
+}
+
+// SaveAnalysisSnapshot analyzes the last days of symbol and saves the
+// result under name with a free-form note. days of 0 uses the configured
+// lookback and lookbackMax (-1) all available history.
+func (a *App) SaveAnalysisSnapshot(symbol string, days int, name string, note string) (string, error) {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+	if days == 0 {
+		days = currentSettings().LookbackDays
+	}
+	a.recent.touch(symbol)
+	snap, err := a.analyze(symbol, days)
+	if err != nil {
+		return "", err
+	}
+	snap.ID = newID()
+	snap.Name = strings.TrimSpace(name)
+	if snap.Name == "" {
+		snap.Name = symbol + " " + snap.Stats.To
+	}
+	snap.Note = note
+
+	s := a.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	s.snapshots = append(s.snapshots, snap)
+	if err := saveJSON(s.path, s.snapshots); err != nil {
+		return "", err
+	}
+	return toJSON(snap)
+}
+
+// ListAnalysisSnapshots returns the saved snapshots of symbol, or of every
+// symbol if it is empty, newest first
+func (a *App) ListAnalysisSnapshots(symbol string) (string, error) {
+	s := a.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	symbol = normalizeSymbol(symbol)
+	out := []AnalysisSnapshot{}
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if symbol == "" || s.snapshots[i].Symbol == symbol {
+			out = append(out, s.snapshots[i])
+		}
+	}
+	return toJSON(out)
+}
+
+// GetAnalysisSnapshot reopens a saved snapshot
+func (a *App) GetAnalysisSnapshot(id string) (string, error) {
+	snap, err := a.snapshots.get(id)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(snap)
+}
+
+// UpdateAnalysisSnapshotNote replaces the note of a snapshot
+func (a *App) UpdateAnalysisSnapshotNote(id string, note string) error {
+	s := a.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i := range s.snapshots {
+		if s.snapshots[i].ID == id {
+			s.snapshots[i].Note = note
+			return saveJSON(s.path, s.snapshots)
+		}
+	}
+	return codeErrorf(codeNotFound, "snapshot not found: %s", id)
+}
+
+// DeleteAnalysisSnapshot removes a snapshot
+func (a *App) DeleteAnalysisSnapshot(id string) error {
+	s := a.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i := range s.snapshots {
+		if s.snapshots[i].ID == id {
+			s.snapshots = append(s.snapshots[:i], s.snapshots[i+1:]...)
+			return saveJSON(s.path, s.snapshots)
+		}
+	}
+	return codeErrorf(codeNotFound, "snapshot not found: %s", id)
+}
+
+// CompareAnalysisSnapshot compares a snapshot with a fresh analysis of the
+// same symbol over a window of the same length
+func (a *App) CompareAnalysisSnapshot(id string) (string, error) {
+	snap, err := a.snapshots.get(id)
+	if err != nil {
+		return "", err
+	}
+	current, err := a.analyze(snap.Symbol, snap.Days)
+	if err != nil {
+		return "", err
+	}
+
+	cmp := SnapshotComparison{
+		Snapshot:   snap,
+		Current:    current,
+		Indicators: []IndicatorDelta{},
+		NewSignals: []ReportSignal{},
+	}
+	if snap.Stats.LastClose != 0 {
+		cmp.PriceChange = (current.Stats.LastClose/snap.Stats.LastClose - 1) * 100
+	}
+	now := make(map[string]float64, len(current.Indicators))
+	for _, r := range current.Indicators {
+		now[r.Name] = r.Value
+	}
+	for _, r := range snap.Indicators {
+		if v, ok := now[r.Name]; ok {
+			cmp.Indicators = append(cmp.Indicators, IndicatorDelta{Name: r.Name, Then: r.Value, Now: v, Change: v - r.Value})
+		}
+	}
+	for _, sig := range current.Signals {
+		if sig.Date > snap.Stats.To {
+			cmp.NewSignals = append(cmp.NewSignals, sig)
+		}
+	}
+	return toJSON(cmp)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"stock-analysis/internal/providers"
+)
+
+// Bar is a single daily bar as returned by the Sohu hisHq API
+type Bar = providers.Bar
+
+// shanghaiNow returns the time of the app clock in China Standard Time
+func shanghaiNow() time.Time {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		loc = time.Local // fallback to local time
+	}
+	return clockNow().In(loc)
+}
+
+// downloadDailyBars downloads daily bars for symbol between start and end,
+// returned oldest first. The request is abandoned when ctx ends.
+func downloadDailyBars(ctx context.Context, symbol string, start, end time.Time) ([]Bar, error) {
+	if !connectivity.allow() {
+		return nil, errOffline
+	}
+	if err := cooldowns.check(providerSohu); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, providers.SohuDailyURL(symbol, start, end), nil)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	resp, err := providerClient.Do(req)
+	connectivity.report(err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Years of history run to megabytes, so the rows are decoded as they
+	// arrive
+	var bars []Bar
+	err = decodeProviderResponse(providerSohu, resp, started, func(dec *json.Decoder) error {
+		bars, err = providers.DecodeSohuBars(dec)
+		return err
+	})
+	return bars, err
+}
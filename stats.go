@@ -0,0 +1,37 @@
+package main
+
+import "sort"
+
+// alignCloses returns the close prices of each series on the dates that
+// all series share, together with those dates
+func alignCloses(series map[string][]Bar) ([]string, map[string][]float64) {
+	counts := make(map[string]int)
+	for _, bars := range series {
+		for _, bar := range bars {
+			counts[bar.Date]++
+		}
+	}
+	var dates []string
+	for date, n := range counts {
+		if n == len(series) {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+
+	index := make(map[string]int, len(dates))
+	for i, date := range dates {
+		index[date] = i
+	}
+	aligned := make(map[string][]float64, len(series))
+	for key, bars := range series {
+		prices := make([]float64, len(dates))
+		for _, bar := range bars {
+			if i, ok := index[bar.Date]; ok {
+				prices[i] = bar.Close
+			}
+		}
+		aligned[key] = prices
+	}
+	return dates, aligned
+}
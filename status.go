@@ -0,0 +1,204 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// ProviderHealth is what the app knows about reaching a provider. It is
+// derived from the responses seen so far; no request is made to check.
+type ProviderHealth struct {
+	Provider     string           `json:"provider"`
+	State        string           `json:"state"` // "ok", "failing", "cooldown", "offline" or "unknown"
+	LastResponse string           `json:"lastResponse,omitempty"`
+	LastStatus   int              `json:"lastStatus,omitempty"`
+	Cooldown     ProviderCooldown `json:"cooldown"`
+}
+
+// CacheSizes are the sizes of the in-memory and on-disk caches
+type CacheSizes struct {
+	Results    int   `json:"results"`    // entries of the quote and analysis cache
+	Indicators int   `json:"indicators"` // symbols with indicator series kept for extending
+	Tables     int   `json:"tables"`     // cached indicator tables
+	Responses  int   `json:"responses"`  // provider responses kept for conditional requests
+	Payloads   int   `json:"payloads"`   // provider responses captured in debug mode
+	Fixtures   int   `json:"fixtures"`   // recorded provider responses
+	Logs       int   `json:"logs"`       // entries kept for the log viewer
+	History    int64 `json:"history"`    // bytes of the history database and its write-ahead log
+}
+
+// SchedulerStatus describes the background loops and jobs
+type SchedulerStatus struct {
+	Running        bool           `json:"running"` // whether the background loops were started
+	AlertInterval  string         `json:"alertInterval"`
+	LastAlertCheck string         `json:"lastAlertCheck,omitempty"`
+	SyncDue        bool           `json:"syncDue"`
+	SummaryDue     bool           `json:"summaryDue"`
+	Jobs           map[string]int `json:"jobs"` // by status
+}
+
+// DatabaseHealth is the state of the history database
+type DatabaseHealth struct {
+	OK      bool   `json:"ok"`
+	Version int    `json:"version"`
+	Latest  int    `json:"latest"`
+	Symbols int    `json:"symbols"`
+	Bars    int    `json:"bars"`
+	TookMs  int64  `json:"tookMs"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AppStatus is the state of the app for the diagnostics page
+type AppStatus struct {
+	CheckedAt string           `json:"checkedAt"`
+	Profile   string           `json:"profile"`
+	Network   NetworkStatus    `json:"network"`
+	Providers []ProviderHealth `json:"providers"`
+	Sync      SyncStatus       `json:"sync"`
+	Caches    CacheSizes       `json:"caches"`
+	Scheduler SchedulerStatus  `json:"scheduler"`
+	Database  DatabaseHealth   `json:"database"`
+}
+
+// providerHealth combines the latest responses and cooldowns of every
+// provider the app used or is set to use
+func providerHealth(network NetworkStatus) []ProviderHealth {
+	metrics.mu.Lock()
+	last := make(map[string]providerResponse, len(metrics.lastResponse))
+	for name, r := range metrics.lastResponse {
+		last[name] = r
+	}
+	metrics.mu.Unlock()
+
+	seen := map[string]bool{providerSohu: true, providerEastmoney: true}
+	for name := range last {
+		seen[name] = true
+	}
+	cooldowns.mu.Lock()
+	for name := range cooldowns.providers {
+		seen[name] = true
+	}
+	cooldowns.mu.Unlock()
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]ProviderHealth, 0, len(names))
+	for _, name := range names {
+		cooldowns.mu.Lock()
+		h := ProviderHealth{Provider: name, State: "unknown", Cooldown: cooldowns.statusLocked(name)}
+		cooldowns.mu.Unlock()
+		if r, ok := last[name]; ok {
+			h.LastResponse = r.at.In(shanghaiNow().Location()).Format(time.RFC3339)
+			h.LastStatus = r.status
+			h.State = "ok"
+			if r.status < 200 || r.status > 299 {
+				h.State = "failing"
+			}
+		}
+		switch {
+		case network.Offline:
+			h.State = "offline"
+		case h.Cooldown.Active:
+			h.State = "cooldown"
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// databaseHealth runs a quick integrity check of the history database
+func databaseHealth() (health DatabaseHealth) {
+	health = DatabaseHealth{Latest: historyMigrations[len(historyMigrations)-1].version}
+	started := time.Now()
+	defer func() { health.TookMs = time.Since(started).Milliseconds() }()
+
+	db, err := localHistory.Open()
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	var check string
+	if err := db.QueryRow(`PRAGMA quick_check`).Scan(&check); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	if check != "ok" {
+		health.Error = check
+		return health
+	}
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&health.Version); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	if err := db.QueryRow(`SELECT COUNT(DISTINCT symbol), COALESCE(SUM(count), 0) FROM (
+		SELECT symbol, COUNT(*) AS count FROM bars GROUP BY symbol
+		UNION ALL SELECT symbol, count FROM bar_archive)`).Scan(&health.Symbols, &health.Bars); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.OK = health.Version == health.Latest
+	if !health.OK {
+		health.Error = "history database is not at the latest version"
+	}
+	return health
+}
+
+// appStatus collects the status of every part of the app
+func (a *App) appStatus() AppStatus {
+	now := shanghaiNow()
+	network := connectivity.status()
+	status := AppStatus{
+		CheckedAt: now.Format(time.RFC3339),
+		Profile:   a.profile,
+		Network:   network,
+		Providers: providerHealth(network),
+		Database:  databaseHealth(),
+	}
+	if sync, err := a.syncs.current(); err == nil {
+		status.Sync = sync
+	}
+	if status.Sync.Failed == nil {
+		status.Sync.Failed = []SyncFailure{}
+	}
+
+	status.Caches = CacheSizes{
+		Results:    resultCache.len(),
+		Indicators: indicatorCache.len(),
+		Tables:     analysisCache.len(),
+		Responses:  validators.count(),
+		Payloads:   len(capturedPayloads()),
+		Logs:       len(recentLogs.snapshot()),
+	}
+	status.Caches.Fixtures, _ = a.CountFixtures()
+	status.Caches.History = localHistory.Size()
+
+	scheduler := SchedulerStatus{
+		Running:       a.loopsRunning(),
+		AlertInterval: currentSettings().alertInterval().String(),
+		SyncDue:       a.syncDue(now),
+		SummaryDue:    a.summaryDue(now),
+		Jobs:          map[string]int{},
+	}
+	metrics.mu.Lock()
+	if !metrics.lastAlerts.IsZero() {
+		scheduler.LastAlertCheck = metrics.lastAlerts.In(now.Location()).Format(time.RFC3339)
+	}
+	metrics.mu.Unlock()
+	a.jobs.mu.Lock()
+	for _, t := range a.jobs.jobs {
+		scheduler.Jobs[t.Status]++
+	}
+	a.jobs.mu.Unlock()
+	status.Scheduler = scheduler
+	return status
+}
+
+// GetAppStatus returns provider reachability, the last sync, cache sizes,
+// the scheduler state and the health of the history database in one
+// structure for the diagnostics page
+func (a *App) GetAppStatus() (string, error) {
+	return toJSON(a.appStatus())
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"stock-analysis/internal/store"
+)
+
+// appDataDir returns the directory where the app persists local state
+func appDataDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "stock-analysis")
+}
+
+// loadJSON reads the JSON file at path into v, decrypting it if needed.
+// A missing file is not an error and leaves v untouched.
+func loadJSON(path string, v interface{}) error {
+	return store.LoadJSON(path, v, vault)
+}
+
+// saveJSON writes v to path, going through a temp file so a crash never
+// leaves a half-written file behind. Files holding personal data are
+// encrypted while encryption is on.
+func saveJSON(path string, v interface{}) error {
+	return store.SaveJSON(path, v, vault)
+}
+
+// toJSON marshals a result for returning to the frontend
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(data), nil
+}
+
+// newID returns a short random identifier
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StrategyDefinition is a declarative strategy or screen that can be
+// shared as a JSON or YAML file.
+//
+// Entry and Exit are formula expressions evaluated on daily bars. A
+// strategy without Exit relies on ExitRules alone. Screens evaluate Entry
+// on the latest bar of every symbol in Universe.
+type StrategyDefinition struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Description  string       `json:"description,omitempty"`
+	Author       string       `json:"author,omitempty"`
+	Universe     []string     `json:"universe,omitempty"`
+	Entry        string       `json:"entry"`
+	Exit         string       `json:"exit,omitempty"`
+	ExitRules    []ExitRule   `json:"exitRules,omitempty"`
+	Sizing       SizingParams `json:"sizing"`
+	InitialCash  float64      `json:"initialCash,omitempty"`
+	LookbackDays int          `json:"lookbackDays,omitempty"`
+}
+
+// ScreenMatch is a symbol whose latest bar satisfies a screen
+type ScreenMatch struct {
+	Symbol   string  `json:"symbol"`
+	Name     string  `json:"name,omitempty"`
+	Industry string  `json:"industry,omitempty"`
+	Date     string  `json:"date"`
+	Close    float64 `json:"close"`
+	Boards   int     `json:"boards,omitempty"` // limit-up streak ending on Date
+}
+
+// validate compiles the expressions and checks the exit rules
+func (s *StrategyDefinition) validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return codeErrorf(codeParse, "strategy name is required")
+	}
+	if _, err := compileFormula(s.Entry); err != nil {
+		return codeErrorf(codeParse, "invalid entry expression: %v", err)
+	}
+	if s.Exit != "" {
+		if _, err := compileFormula(s.Exit); err != nil {
+			return codeErrorf(codeParse, "invalid exit expression: %v", err)
+		}
+	}
+	return validateExitRules(s.ExitRules)
+}
+
+// lookback returns the number of calendar days of history to load
+func (s *StrategyDefinition) lookback() int {
+	if s.LookbackDays > 0 {
+		return s.LookbackDays
+	}
+	return 365
+}
+
+// parseStrategy decodes a strategy file. YAML is a superset of JSON, so
+// both formats go through the YAML decoder and are then mapped onto the
+// JSON field names.
+func parseStrategy(content string) (StrategyDefinition, error) {
+	var def StrategyDefinition
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(content), &raw); err != nil {
+		return def, codeErrorf(codeParse, "failed to parse strategy: %v", err)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return def, codeErrorf(codeParse, "failed to parse strategy: %v", err)
+	}
+	if err := json.Unmarshal(data, &def); err != nil {
+		return def, codeErrorf(codeParse, "failed to parse strategy: %v", err)
+	}
+	return def, nil
+}
+
+// formatStrategy encodes a strategy as "json" or "yaml"
+func formatStrategy(def StrategyDefinition, format string) (string, error) {
+	data, err := json.MarshalIndent(def, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return string(data), nil
+	case "yaml", "yml":
+		// Decoding the JSON as YAML keeps the field order of the struct
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return "", err
+		}
+		blockStyle(&node)
+		out, err := yaml.Marshal(&node)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	return "", codeErrorf(codeParse, "unsupported strategy format: %s", format)
+}
+
+// blockStyle clears the flow style JSON input leaves on YAML nodes
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// strategyStore keeps one file per strategy in the strategies directory
+type strategyStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func newStrategyStore(dataDir string) *strategyStore {
+	return &strategyStore{dir: filepath.Join(dataDir, "strategies")}
+}
+
+func (s *strategyStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *strategyStore) get(id string) (StrategyDefinition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var def StrategyDefinition
+	if err := loadJSON(s.path(filepath.Base(id)), &def); err != nil {
+		return def, err
+	}
+	if def.ID == "" {
+		return def, codeErrorf(codeNotFound, "strategy not found: %s", id)
+	}
+	return def, nil
+}
+
+func (s *strategyStore) list() ([]StrategyDefinition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	defs := []StrategyDefinition{}
+	for _, file := range files {
+		var def StrategyDefinition
+		if err := loadJSON(file, &def); err != nil {
+			continue
+		}
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
+func (s *strategyStore) save(def StrategyDefinition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return saveJSON(s.path(def.ID), def)
+}
+
+func (s *strategyStore) remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.path(filepath.Base(id)))
+	if os.IsNotExist(err) {
+		return codeErrorf(codeNotFound, "strategy not found: %s", id)
+	}
+	return err
+}
+
+// ListStrategies returns all saved strategy definitions
+func (a *App) ListStrategies() (string, error) {
+	defs, err := a.strategies.list()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(defs)
+}
+
+// ImportStrategy validates and saves a strategy from JSON or YAML content.
+// A strategy without an ID is given a new one; an existing ID is
+// overwritten.
+func (a *App) ImportStrategy(content string) (string, error) {
+	def, err := parseStrategy(content)
+	if err == nil {
+		err = def.validate()
+	}
+	if err != nil {
+		recordAudit(auditImport, "strategy", "", "", err)
+		return "", err
+	}
+	if def.ID == "" {
+		def.ID = newID()
+	}
+	def.ID = filepath.Base(def.ID)
+	if err := a.strategies.save(def); err != nil {
+		err = fmt.Errorf("failed to save strategy: %v", err)
+		recordAudit(auditImport, "strategy", def.ID, def.Name, err)
+		return "", err
+	}
+	recordAudit(auditImport, "strategy", def.ID, def.Name, nil)
+	return toJSON(def)
+}
+
+// ExportStrategy returns a strategy file in "json" or "yaml" format
+func (a *App) ExportStrategy(id string, format string) (string, error) {
+	def, err := a.strategies.get(id)
+	if err != nil {
+		return "", err
+	}
+	text, err := formatStrategy(def, format)
+	recordAudit(auditExport, "strategy", id, format, err)
+	return text, err
+}
+
+// DeleteStrategy removes a saved strategy
+func (a *App) DeleteStrategy(id string) error {
+	return a.strategies.remove(id)
+}
+
+// RunStrategyBacktest backtests a saved strategy on symbol over the
+// strategy's lookback window
+func (a *App) RunStrategyBacktest(id string, symbol string) (string, error) {
+	label, fn, err := a.strategyBacktestJob(id, symbol)
+	if err != nil {
+		return "", err
+	}
+	a.recent.touch(symbol)
+	return a.jobs.run("strategyBacktest", label, fn)
+}
+
+// strategyBacktestJob returns the job that runs RunStrategyBacktest
+func (a *App) strategyBacktestJob(id string, symbol string) (string, jobFunc, error) {
+	def, err := a.strategies.get(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return def.Name + " " + symbol, func(ctx context.Context, job Job) (string, error) {
+		progress := a.newProgress(eventBacktestProgress, job, 0)
+		progress.step(0, "fetch", symbol)
+		now := shanghaiNow()
+		bars, err := fetchDailyBarsContext(ctx, symbol, now.AddDate(0, 0, -def.lookback()), now)
+		if err != nil {
+			return "", fmt.Errorf("failed to get stock data: %w", canceled(ctx, err))
+		}
+		progress.current.Total = len(bars)
+
+		entry, err := formulaSignal(def.Entry, symbol, bars)
+		if err != nil {
+			return "", err
+		}
+		var exit entryFunc
+		if def.Exit != "" {
+			if exit, err = formulaSignal(def.Exit, symbol, bars); err != nil {
+				return "", err
+			}
+		}
+
+		cfg := BacktestConfig{
+			InitialCash: def.InitialCash,
+			Sizing:      def.Sizing,
+			Exits:       def.ExitRules,
+		}
+		result, err := runBacktest(symbol, bars, cfg, entry, exit, progress)
+		if err != nil {
+			return "", err
+		}
+		return toJSON(result)
+	}, nil
+}
+
+// RunStrategyScreen evaluates a strategy's entry expression on the latest
+// bar of every symbol in its universe and returns the matches
+func (a *App) RunStrategyScreen(id string) (string, error) {
+	label, fn, err := a.screenJob(id)
+	if err != nil {
+		return "", err
+	}
+	return a.jobs.run("screen", label, fn)
+}
+
+// screenJob returns the job that runs RunStrategyScreen
+func (a *App) screenJob(id string) (string, jobFunc, error) {
+	def, err := a.strategies.get(id)
+	if err != nil {
+		return "", nil, err
+	}
+	return a.definitionScreenJob(def)
+}
+
+// definitionScreenJob returns the job that screens the universe of def,
+// saved or not
+func (a *App) definitionScreenJob(def StrategyDefinition) (string, jobFunc, error) {
+	formula, err := compileFormula(def.Entry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return def.Name, func(jobCtx context.Context, job Job) (string, error) {
+		now := shanghaiNow()
+		matches := []ScreenMatch{}
+		progress := a.newProgress(eventScanProgress, job, len(def.Universe))
+		progress.step(0, "screen", "")
+		for i, symbol := range def.Universe {
+			bars, err := fetchDailyBarsContext(jobCtx, symbol, now.AddDate(0, 0, -def.lookback()), now)
+			if jobCtx.Err() != nil {
+				return "", errJobCanceled
+			}
+			progress.step(i+1, "screen", symbol)
+			if err != nil || len(bars) == 0 {
+				continue
+			}
+			ctx := newFormulaContext(bars)
+			ctx.symbol = symbol
+			if meta, err := a.metadata.get(symbol, false); err == nil {
+				ctx.meta = &meta
+			}
+			values, err := formula.EvalContext(ctx)
+			if err != nil {
+				return "", err
+			}
+			last := len(bars) - 1
+			if truthy(values[last]) {
+				match := ScreenMatch{Symbol: symbol, Date: bars[last].Date, Close: bars[last].Close}
+				if boards, _ := ctx.variable("BOARDS"); boards[last] > 0 {
+					match.Boards = int(boards[last])
+				}
+				if ctx.meta != nil {
+					match.Name = ctx.meta.Name
+					match.Industry = ctx.meta.Industry
+				}
+				matches = append(matches, match)
+			}
+		}
+		return toJSON(matches)
+	}, nil
+}
+
+// formulaSignal compiles an expression into a per-bar signal function
+// over the bars of symbol
+func formulaSignal(source string, symbol string, bars []Bar) (entryFunc, error) {
+	formula, err := compileFormula(source)
+	if err != nil {
+		return nil, err
+	}
+	ctx := newFormulaContext(bars)
+	ctx.symbol = symbol
+	values, err := formula.EvalContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) bool { return truthy(values[i]) }, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Long histories serialized as one JSON string stall the webview, so they
+// can be sent in chunks instead: StreamBars emits numbered batches as
+// events and GetBarsPage fetches any one of them, e.g. to fill a gap. The
+// caller names the stream with a key of its own, so it can listen for the
+// events before asking and claim chunks that arrive before StreamBars
+// returns.
+
+const (
+	defaultChunkSize = 2000
+	maxChunkSize     = 20000
+
+	eventBarsChunk = "bars:chunk"
+	eventBarsDone  = "bars:done"
+)
+
+// BarStream describes a stream of bars started by StreamBars
+type BarStream struct {
+	Stream    string `json:"stream"` // job ID; pass it to CancelJob to stop
+	Key       string `json:"key"`    // the caller's key, echoed in every event
+	Symbol    string `json:"symbol"`
+	Total     int    `json:"total"`
+	Chunks    int    `json:"chunks"`
+	ChunkSize int    `json:"chunkSize"`
+}
+
+// BarChunk is one batch of a stream or one page, oldest bars first. Seq
+// counts from 0 to Chunks-1.
+type BarChunk struct {
+	Stream string `json:"stream,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Symbol string `json:"symbol"`
+	Seq    int    `json:"seq"`
+	Chunks int    `json:"chunks"`
+	Total  int    `json:"total"`
+	Bars   []Bar  `json:"bars"`
+}
+
+// BarStreamDone ends a stream
+type BarStreamDone struct {
+	Stream   string `json:"stream"`
+	Key      string `json:"key"`
+	Sent     int    `json:"sent"` // chunks emitted
+	Canceled bool   `json:"canceled"`
+}
+
+// chunkSize clamps a requested chunk size
+func chunkSize(size int) int {
+	if size <= 0 {
+		return defaultChunkSize
+	}
+	return min(size, maxChunkSize)
+}
+
+// barChunk returns chunk seq of bars split into chunks of size
+func barChunk(symbol string, bars []Bar, seq, size int) BarChunk {
+	chunks := (len(bars) + size - 1) / size
+	chunk := BarChunk{Symbol: symbol, Seq: seq, Chunks: chunks, Total: len(bars), Bars: []Bar{}}
+	if seq >= 0 && seq < chunks {
+		chunk.Bars = bars[seq*size : min((seq+1)*size, len(bars))]
+	}
+	return chunk
+}
+
+// windowBars loads the bars of symbol over the last days
+func windowBars(symbol string, days int) ([]Bar, error) {
+	now := shanghaiNow()
+	return fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+}
+
+// StreamBars loads the daily bars of symbol over the last days and emits
+// them as bars:chunk events of up to chunkSize bars in sequence, then
+// bars:done, each carrying key. days of 0 uses the configured lookback and
+// lookbackMax (-1) all available history. It returns as soon as the bars
+// are loaded, possibly after the first chunks were emitted.
+func (a *App) StreamBars(symbol string, days int, chunkSizeHint int, key string) (string, error) {
+	bars, err := windowBars(symbol, days)
+	if err != nil {
+		return "", err
+	}
+	size := chunkSize(chunkSizeHint)
+	stream := BarStream{Key: key, Symbol: symbol, Total: len(bars), Chunks: (len(bars) + size - 1) / size, ChunkSize: size}
+	job := a.jobs.submit("stream", symbol, func(ctx context.Context, job Job) (string, error) {
+		done := BarStreamDone{Stream: job.ID, Key: key}
+		for seq := 0; seq < stream.Chunks; seq++ {
+			if ctx.Err() != nil {
+				break
+			}
+			chunk := barChunk(symbol, bars, seq, size)
+			chunk.Stream, chunk.Key = job.ID, key
+			if a.ctx != nil {
+				wailsruntime.EventsEmit(a.ctx, eventBarsChunk, chunk)
+			}
+			done.Sent++
+		}
+		done.Canceled = ctx.Err() != nil
+		if a.ctx != nil {
+			wailsruntime.EventsEmit(a.ctx, eventBarsDone, done)
+		}
+		return toJSON(done)
+	})
+	stream.Stream = job.ID
+	logger.Debug("streaming bars", "symbol", symbol, "bars", stream.Total, "chunks", stream.Chunks)
+	return toJSON(stream)
+}
+
+// GetBarsPage returns page (from 0) of the daily bars of symbol over the
+// last days, split like StreamBars splits them with the same size
+func (a *App) GetBarsPage(symbol string, days int, page int, pageSize int) (string, error) {
+	bars, err := windowBars(symbol, days)
+	if err != nil {
+		return "", err
+	}
+	chunk := barChunk(symbol, bars, page, chunkSize(pageSize))
+	if page < 0 || (page >= chunk.Chunks && chunk.Chunks > 0) {
+		return "", codeErrorf(codeNotFound, "page %d out of range (%d pages)", page, chunk.Chunks)
+	}
+	return toJSON(chunk)
+}
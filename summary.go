@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// summaryHour and summaryMinute are when the daily summary is built,
+	// after the 15:00 close has settled
+	summaryHour   = 15
+	summaryMinute = 30
+	// maxDailySummaries caps the stored reports
+	maxDailySummaries = 30
+
+	deliveryEventReport = "report"
+	eventDailySummary   = "report:daily"
+)
+
+// DailySummary is the end-of-day report over every watchlist
+type DailySummary struct {
+	Date        string           `json:"date"`
+	Session     string           `json:"session"` // latest bar date in the report
+	GeneratedAt string           `json:"generatedAt"`
+	Rows        []WatchlistQuote `json:"rows"`
+	Gainers     []WatchlistQuote `json:"gainers"`
+	Losers      []WatchlistQuote `json:"losers"`
+	Signals     []AlertTrigger   `json:"signals"`
+}
+
+// text renders the summary for chat and email delivery
+func (s DailySummary) text() string {
+	var b strings.Builder
+	b.WriteString(tr("summary.counts", len(s.Rows), len(s.Signals)) + "\n")
+	section := func(title string, rows []WatchlistQuote) {
+		if len(rows) == 0 {
+			return
+		}
+		b.WriteString("\n" + title + "\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "%s %.2f %+.2f%% vol5d %+.1f%%\n", r.Symbol, r.Price, r.ChangePct, r.VolumeRate5D)
+		}
+	}
+	section(tr("summary.gainers"), s.Gainers)
+	section(tr("summary.losers"), s.Losers)
+	if len(s.Signals) > 0 {
+		b.WriteString("\n" + tr("summary.signals") + "\n")
+		for _, t := range s.Signals {
+			b.WriteString(t.Message + "\n")
+		}
+	}
+	return b.String()
+}
+
+// summaryStore keeps the recent daily summaries
+type summaryStore struct {
+	mu        sync.Mutex
+	path      string
+	loaded    bool
+	summaries []DailySummary
+}
+
+func newSummaryStore(dataDir string) *summaryStore {
+	return &summaryStore{path: filepath.Join(dataDir, "daily_summaries.json")}
+}
+
+// unload forgets the summaries so the next use reads the file again
+func (s *summaryStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries, s.loaded = nil, false
+}
+
+// load reads the summaries from disk on first use. Callers hold s.mu.
+func (s *summaryStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.summaries); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// has reports whether a summary exists for date
+func (s *summaryStore) has(date string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return false
+	}
+	for _, summary := range s.summaries {
+		if summary.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+// save stores summary, replacing an earlier one for the same date
+func (s *summaryStore) save(summary DailySummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	kept := s.summaries[:0]
+	for _, existing := range s.summaries {
+		if existing.Date != summary.Date {
+			kept = append(kept, existing)
+		}
+	}
+	s.summaries = append(kept, summary)
+	if len(s.summaries) > maxDailySummaries {
+		s.summaries = s.summaries[len(s.summaries)-maxDailySummaries:]
+	}
+	return saveJSON(s.path, s.summaries)
+}
+
+// watchlistSymbols returns the symbols of every watchlist without
+// duplicates, in list order
+func (a *App) watchlistSymbols() ([]string, error) {
+	s := a.watchlists
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, w := range s.lists {
+		for _, sym := range w.Symbols {
+			if !seen[sym] {
+				seen[sym] = true
+				symbols = append(symbols, sym)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// buildDailySummary quotes every watchlist symbol and collects the alert
+// triggers of the session
+func (a *App) buildDailySummary() (DailySummary, error) {
+	symbols, err := a.watchlistSymbols()
+	if err != nil {
+		return DailySummary{}, err
+	}
+
+	rows := make([]WatchlistQuote, len(symbols))
+	var wg sync.WaitGroup
+	for i, sym := range symbols {
+		wg.Add(1)
+		go func(i int, sym string) {
+			defer wg.Done()
+			defer recoverPanic("quote " + sym)
+			rows[i] = watchlistQuote(sym)
+		}(i, sym)
+	}
+	wg.Wait()
+
+	summary := DailySummary{
+		Date:        shanghaiNow().Format("2006-01-02"),
+		GeneratedAt: shanghaiNow().Format(time.RFC3339),
+		Rows:        rows,
+		Signals:     []AlertTrigger{},
+	}
+	// The session is the latest bar date any symbol reports
+	var valid []WatchlistQuote
+	for _, r := range rows {
+		if r.Error != "" {
+			continue
+		}
+		valid = append(valid, r)
+		if r.Date > summary.Session {
+			summary.Session = r.Date
+		}
+	}
+
+	sort.Slice(valid, func(i, j int) bool { return valid[i].ChangePct > valid[j].ChangePct })
+	for i := 0; i < len(valid) && i < 5 && valid[i].ChangePct > 0; i++ {
+		summary.Gainers = append(summary.Gainers, valid[i])
+	}
+	for i := len(valid) - 1; i >= 0 && len(valid)-1-i < 5 && valid[i].ChangePct < 0; i-- {
+		summary.Losers = append(summary.Losers, valid[i])
+	}
+
+	s := a.alerts
+	s.mu.Lock()
+	if err := s.load(); err == nil {
+		for _, t := range s.triggers {
+			if t.Date == summary.Session {
+				summary.Signals = append(summary.Signals, t)
+			}
+		}
+	}
+	s.mu.Unlock()
+	return summary, nil
+}
+
+// publishDailySummary builds, stores and delivers the daily summary
+func (a *App) publishDailySummary() (DailySummary, error) {
+	summary, err := a.buildDailySummary()
+	if err != nil {
+		return DailySummary{}, err
+	}
+	if err := a.summaries.save(summary); err != nil {
+		return DailySummary{}, err
+	}
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventDailySummary, summary)
+	}
+	a.dispatch(outboundMessage{
+		Event: deliveryEventReport,
+		Title: tr("summary.title", summary.Session),
+		Text:  summary.text(),
+		Data:  summary,
+	})
+	return summary, nil
+}
+
+// runDailySummaryLoop publishes the summary once per weekday after the
+// close until ctx ends
+func (a *App) runDailySummaryLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.summaryDue(shanghaiNow()) {
+				a.publishDailySummary()
+			}
+		}
+	}
+}
+
+// summaryDue reports whether the daily summary of now's session is due:
+// on trading days after the summary time, once per day
+func (a *App) summaryDue(now time.Time) bool {
+	if !isTradingDay(now) {
+		return false
+	}
+	if now.Hour()*60+now.Minute() < summaryHour*60+summaryMinute {
+		return false
+	}
+	return !a.summaries.has(now.Format("2006-01-02"))
+}
+
+// GenerateDailySummary builds and delivers the watchlist summary now
+func (a *App) GenerateDailySummary() (string, error) {
+	summary, err := a.publishDailySummary()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(summary)
+}
+
+// GetDailySummaries returns the stored daily summaries, newest first
+func (a *App) GetDailySummaries() (string, error) {
+	s := a.summaries
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	out := []DailySummary{}
+	for i := len(s.summaries) - 1; i >= 0; i-- {
+		out = append(out, s.summaries[i])
+	}
+	return toJSON(out)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// symbolListMaxAge is how long the cached symbol master list is used
+// before it is downloaded again
+const symbolListMaxAge = 7 * 24 * time.Hour
+
+// SymbolInfo is one entry of the symbol master list
+type SymbolInfo struct {
+	Symbol string `json:"symbol"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Pinyin string `json:"pinyin"` // initials, e.g. "gzmt" for 贵州茅台
+	Market string `json:"market"` // "sh" or "sz"
+	Index  bool   `json:"index,omitempty"`
+}
+
+// symbolList is the cached master list
+type symbolList struct {
+	UpdatedAt time.Time    `json:"updatedAt"`
+	Symbols   []SymbolInfo `json:"symbols"`
+}
+
+// symbolStore caches the master list on disk
+type symbolStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	list   symbolList
+}
+
+func newSymbolStore(dataDir string) *symbolStore {
+	return &symbolStore{path: filepath.Join(dataDir, "symbols.json")}
+}
+
+// unload forgets the list so the next use reads the file again
+func (s *symbolStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list, s.loaded = symbolList{}, false
+}
+
+// gb2312InitialBounds are the first GB2312 code points of each pinyin
+// initial. Level-1 hanzi (0xB0A1-0xD7F9) are ordered by pinyin, so the
+// initial of a character is the last bound not above it.
+var gb2312InitialBounds = []struct {
+	code    int
+	initial byte
+}{
+	{0xB0A1, 'a'}, {0xB0C5, 'b'}, {0xB2C1, 'c'}, {0xB4EE, 'd'}, {0xB6EA, 'e'},
+	{0xB7A2, 'f'}, {0xB8C1, 'g'}, {0xB9FE, 'h'}, {0xBBF7, 'j'}, {0xBFA6, 'k'},
+	{0xC0AC, 'l'}, {0xC2E8, 'm'}, {0xC4C3, 'n'}, {0xC5B6, 'o'}, {0xC5BE, 'p'},
+	{0xC6DA, 'q'}, {0xC8BB, 'r'}, {0xC8F6, 's'}, {0xCBFA, 't'}, {0xCDDA, 'w'},
+	{0xCEF4, 'x'}, {0xD1B9, 'y'}, {0xD4D1, 'z'},
+}
+
+// pinyinOverrides fixes polyphonic characters whose GB2312 position does
+// not match the reading used in company names (银行, 长江, 重庆, 厦门)
+var pinyinOverrides = map[rune]byte{
+	'行': 'h', '长': 'c', '重': 'c', '厦': 'x', '乐': 'l', '藏': 'z',
+}
+
+// pinyinInitials returns the lower-case pinyin initials of name. Letters
+// and digits are kept as they are; level-2 hanzi, which GB2312 orders by
+// radical rather than pronunciation, are skipped.
+func pinyinInitials(name string) string {
+	encoder := simplifiedchinese.GBK.NewEncoder()
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				b.WriteRune(unicode.ToLower(r))
+			}
+			continue
+		}
+		if !unicode.Is(unicode.Han, r) {
+			continue
+		}
+		if initial, ok := pinyinOverrides[r]; ok {
+			b.WriteByte(initial)
+			continue
+		}
+		gb, err := encoder.Bytes([]byte(string(r)))
+		if err != nil || len(gb) != 2 {
+			continue
+		}
+		code := int(gb[0])<<8 | int(gb[1])
+		if code < 0xB0A1 || code > 0xD7F9 {
+			continue
+		}
+		initial := gb2312InitialBounds[0].initial
+		for _, bound := range gb2312InitialBounds {
+			if code < bound.code {
+				break
+			}
+			initial = bound.initial
+		}
+		b.WriteByte(initial)
+	}
+	return b.String()
+}
+
+// symbolListFilters are the Eastmoney market filters for A-shares and the
+// exchange indices
+var symbolListFilters = []struct {
+	filter string
+	index  bool
+}{
+	{"m:0+t:6,m:0+t:80,m:1+t:2,m:1+t:23,m:0+t:81+s:2048", false},
+	{"m:1+s:2,m:0+t:5", true},
+}
+
+// fetchSymbolList downloads every A-share and index from Eastmoney
+func fetchSymbolList() ([]SymbolInfo, error) {
+	var symbols []SymbolInfo
+	for _, f := range symbolListFilters {
+		for page := 1; ; page++ {
+			params := url.Values{}
+			params.Set("pn", fmt.Sprint(page))
+			params.Set("pz", "100")
+			params.Set("po", "0")
+			params.Set("np", "1")
+			params.Set("fid", "f12")
+			params.Set("fs", f.filter)
+			params.Set("fields", "f12,f13,f14")
+
+			if err := cooldowns.check(providerEastmoney); err != nil {
+				return nil, err
+			}
+			started := time.Now()
+			resp, err := providerClient.Get("https://push2.eastmoney.com/api/qt/clist/get?" + params.Encode())
+			if err != nil {
+				return nil, err
+			}
+			var payload struct {
+				Data *struct {
+					Total int `json:"total"`
+					Diff  []struct {
+						Code   string `json:"f12"`
+						Market int    `json:"f13"`
+						Name   string `json:"f14"`
+					} `json:"diff"`
+				} `json:"data"`
+			}
+			err = decodeProviderResponse(providerEastmoney, resp, started, func(dec *json.Decoder) error {
+				if err := dec.Decode(&payload); err != nil {
+					return codeErrorf(codeParse, "failed to parse JSON: %v", err)
+				}
+				return nil
+			})
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			if payload.Data == nil || len(payload.Data.Diff) == 0 {
+				break
+			}
+			for _, row := range payload.Data.Diff {
+				info := SymbolInfo{
+					Code:   row.Code,
+					Name:   row.Name,
+					Pinyin: pinyinInitials(row.Name),
+					Market: "sz",
+					Index:  f.index,
+				}
+				if row.Market == 1 {
+					info.Market = "sh"
+				}
+				if f.index {
+					info.Symbol = "zs_" + row.Code
+				} else {
+					info.Symbol = "cn_" + row.Code
+				}
+				symbols = append(symbols, info)
+			}
+			if page*100 >= payload.Data.Total {
+				break
+			}
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, codeErrorf(codeParse, "symbol list is empty")
+	}
+	return symbols, nil
+}
+
+// symbols returns the master list, downloading it when the cache is
+// missing or stale. A stale cache is still used if the download fails.
+func (s *symbolStore) symbols(refresh bool) ([]SymbolInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		if err := loadJSON(s.path, &s.list); err != nil {
+			return nil, err
+		}
+		s.loaded = true
+	}
+	if refresh || len(s.list.Symbols) == 0 || time.Since(s.list.UpdatedAt) > symbolListMaxAge {
+		symbols, err := fetchSymbolList()
+		if err != nil {
+			if len(s.list.Symbols) > 0 && !refresh {
+				return s.list.Symbols, nil
+			}
+			return nil, fmt.Errorf("failed to get symbol list: %v", err)
+		}
+		s.list = symbolList{UpdatedAt: time.Now(), Symbols: symbols}
+		if err := saveJSON(s.path, s.list); err != nil {
+			return nil, err
+		}
+	}
+	return s.list.Symbols, nil
+}
+
+// isSubsequence reports whether every byte of sub appears in s in order
+func isSubsequence(sub, s string) bool {
+	i := 0
+	for j := 0; i < len(sub) && j < len(s); j++ {
+		if sub[i] == s[j] {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+// symbolScore ranks how well info matches query; 0 means no match
+func symbolScore(info SymbolInfo, query string) int {
+	switch {
+	case info.Code == query || info.Symbol == query:
+		return 100
+	case strings.HasPrefix(info.Code, query):
+		return 90
+	case info.Pinyin == query:
+		return 85
+	case strings.HasPrefix(info.Pinyin, query):
+		return 80
+	case strings.HasPrefix(info.Name, query):
+		return 75
+	case strings.Contains(info.Name, query):
+		return 60
+	case strings.Contains(info.Pinyin, query):
+		return 50
+	case strings.Contains(info.Code, query):
+		return 40
+	case len(query) > 1 && isSubsequence(query, info.Pinyin):
+		return 20
+	}
+	return 0
+}
+
+// searchSymbols returns up to limit best matches for query
+func searchSymbols(symbols []SymbolInfo, query string, limit int) []SymbolInfo {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []SymbolInfo{}
+	}
+	type match struct {
+		info  SymbolInfo
+		score int
+	}
+	var matches []match
+	for _, info := range symbols {
+		if score := symbolScore(info, query); score > 0 {
+			matches = append(matches, match{info, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		// Stocks before indices, then by code
+		if matches[i].info.Index != matches[j].info.Index {
+			return !matches[i].info.Index
+		}
+		return matches[i].info.Code < matches[j].info.Code
+	})
+
+	out := []SymbolInfo{}
+	for i := 0; i < len(matches) && i < limit; i++ {
+		out = append(out, matches[i].info)
+	}
+	return out
+}
+
+// SearchSymbols finds symbols by code, Chinese name or pinyin initials
+// for autocomplete. limit defaults to 10.
+func (a *App) SearchSymbols(query string, limit int) (string, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	symbols, err := a.symbols.symbols(false)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(searchSymbols(symbols, query, limit))
+}
+
+// RefreshSymbolList downloads the symbol master list again and returns
+// the number of symbols
+func (a *App) RefreshSymbolList() (int, error) {
+	symbols, err := a.symbols.symbols(true)
+	if err != nil {
+		return 0, err
+	}
+	return len(symbols), nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// syncHour and syncMinute are when the end-of-day sync starts, shortly
+	// after the 15:00 close and ahead of the daily summary
+	syncHour   = 15
+	syncMinute = 10
+	// syncLookbackDays is the history window kept in sync for each symbol
+	syncLookbackDays = 365
+	// syncWorkers bounds the concurrent downloads of a sync run
+	syncWorkers = 4
+
+	eventSyncProgress = "sync:progress"
+	eventSyncDone     = "sync:done"
+)
+
+var errSyncRunning error = &codedError{codeBusy, errors.New("a sync is already running")}
+
+// SyncFailure is a symbol that could not be synced
+type SyncFailure struct {
+	Symbol string `json:"symbol"`
+	Error  string `json:"error"`
+}
+
+// SyncStatus describes the latest end-of-day sync run
+type SyncStatus struct {
+	Running    bool          `json:"running"`
+	Job        string        `json:"job,omitempty"` // ID to pass to CancelJob
+	Canceled   bool          `json:"canceled,omitempty"`
+	Trigger    string        `json:"trigger"` // "schedule" or "manual"
+	Date       string        `json:"date"`    // trading day the run was for
+	StartedAt  string        `json:"startedAt"`
+	FinishedAt string        `json:"finishedAt,omitempty"`
+	Total      int           `json:"total"`
+	Done       int           `json:"done"`
+	Failed     []SyncFailure `json:"failed"`
+}
+
+// SyncProgress is emitted after each symbol of a sync run
+type SyncProgress struct {
+	Symbol string `json:"symbol"`
+	Done   int    `json:"done"`
+	Total  int    `json:"total"`
+	Error  string `json:"error,omitempty"`
+}
+
+// syncStore keeps the status of the latest sync run
+type syncStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	status SyncStatus
+}
+
+func newSyncStore(dataDir string) *syncStore {
+	return &syncStore{path: filepath.Join(dataDir, "sync_status.json")}
+}
+
+// unload forgets the sync status so the next use reads the file again
+func (s *syncStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.loaded = SyncStatus{}, false
+}
+
+// load reads the status from disk on first use. Callers hold s.mu.
+func (s *syncStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.status); err != nil {
+		return err
+	}
+	// A run cut short by a restart is not running anymore
+	s.status.Running = false
+	s.loaded = true
+	return nil
+}
+
+// update applies fn to the status and saves it
+func (s *syncStore) update(fn func(status *SyncStatus)) (SyncStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return SyncStatus{}, err
+	}
+	fn(&s.status)
+	return s.status, saveJSON(s.path, s.status)
+}
+
+// current returns the latest status
+func (s *syncStore) current() (SyncStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return SyncStatus{}, err
+	}
+	status := s.status
+	status.Failed = append([]SyncFailure{}, s.status.Failed...)
+	return status, nil
+}
+
+// begin marks a new run as started unless one is running already
+func (s *syncStore) begin(job Job, trigger string, total int) (SyncStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return SyncStatus{}, err
+	}
+	if s.status.Running {
+		return SyncStatus{}, errSyncRunning
+	}
+	now := shanghaiNow()
+	s.status = SyncStatus{
+		Running:   true,
+		Job:       job.ID,
+		Trigger:   trigger,
+		Date:      now.Format("2006-01-02"),
+		StartedAt: now.Format(time.RFC3339),
+		Total:     total,
+		Failed:    []SyncFailure{},
+	}
+	return s.status, saveJSON(s.path, s.status)
+}
+
+// syncSymbols returns every watchlist symbol and every symbol held in a
+// portfolio, without duplicates
+func (a *App) syncSymbols() ([]string, error) {
+	symbols, err := a.watchlistSymbols()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, sym := range symbols {
+		seen[sym] = true
+	}
+	portfolios, _, err := a.portfolio.snapshotAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range portfolios {
+		holdings, err := p.holdings()
+		if err != nil {
+			continue
+		}
+		for _, h := range holdings {
+			sym := normalizeSymbol(h.Symbol)
+			if h.Shares > 0 && !seen[sym] {
+				seen[sym] = true
+				symbols = append(symbols, sym)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// startSync begins a sync run in the background and returns its initial
+// status
+func (a *App) startSync(trigger string) (SyncStatus, error) {
+	symbols, err := a.syncSymbols()
+	if err != nil {
+		return SyncStatus{}, err
+	}
+	job, ctx := a.jobs.start("sync", trigger)
+	status, err := a.syncs.begin(job, trigger, len(symbols))
+	if err != nil {
+		a.jobs.finish(job.ID, "", err)
+		return SyncStatus{}, err
+	}
+	go a.runSync(ctx, job, symbols)
+	return status, nil
+}
+
+// runSync brings the local history of symbols up to date, emitting a
+// progress event per symbol and a final status event. It stops early when
+// ctx ends.
+func (a *App) runSync(ctx context.Context, job Job, symbols []string) {
+	now := shanghaiNow()
+	start := now.AddDate(0, 0, -syncLookbackDays)
+	fetch := a.newProgress(eventFetchProgress, job, len(symbols))
+	var fetchMu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < syncWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sym := range jobs {
+				func() {
+					defer recoverPanic("sync " + sym)
+					_, err := fetchDailyBarsContext(ctx, sym, start, now)
+					if ctx.Err() != nil {
+						return
+					}
+					status, _ := a.syncs.update(func(status *SyncStatus) {
+						status.Done++
+						if err != nil {
+							status.Failed = append(status.Failed, SyncFailure{Symbol: sym, Error: err.Error()})
+						}
+					})
+					progress := SyncProgress{Symbol: sym, Done: status.Done, Total: status.Total}
+					if err != nil {
+						progress.Error = err.Error()
+					}
+					if a.ctx != nil {
+						wailsruntime.EventsEmit(a.ctx, eventSyncProgress, progress)
+					}
+					fetchMu.Lock()
+					fetch.step(status.Done, "download", sym)
+					fetchMu.Unlock()
+				}()
+			}
+		}()
+	}
+feed:
+	for _, sym := range symbols {
+		select {
+		case jobs <- sym:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	status, _ := a.syncs.update(func(status *SyncStatus) {
+		status.Running = false
+		status.Canceled = ctx.Err() != nil
+		status.FinishedAt = shanghaiNow().Format(time.RFC3339)
+	})
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventSyncDone, status)
+	}
+	result, _ := toJSON(status)
+	a.jobs.finish(job.ID, result, canceled(ctx, nil))
+}
+
+// runSyncLoop starts the end-of-day sync once per weekday after the close
+// until ctx ends
+func (a *App) runSyncLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.syncDue(shanghaiNow()) {
+				a.startSync("schedule")
+			}
+		}
+	}
+}
+
+// syncDue reports whether the scheduled sync of now's session is due: on
+// trading days after the sync time, once per day and not while one runs
+func (a *App) syncDue(now time.Time) bool {
+	if !isTradingDay(now) {
+		return false
+	}
+	if now.Hour()*60+now.Minute() < syncHour*60+syncMinute {
+		return false
+	}
+	status, err := a.syncs.current()
+	return err == nil && !status.Running && status.Date != now.Format("2006-01-02")
+}
+
+// SyncNow starts syncing every watchlist and portfolio symbol in the
+// background. Progress arrives as sync:progress events.
+func (a *App) SyncNow() (string, error) {
+	status, err := a.startSync("manual")
+	if err != nil {
+		return "", err
+	}
+	return toJSON(status)
+}
+
+// GetSyncStatus returns the status of the latest sync run
+func (a *App) GetSyncStatus() (string, error) {
+	status, err := a.syncs.current()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(status)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"stock-analysis/internal/providers"
+)
+
+// Wails v2 has no system tray, so the tray service only produces what a
+// tray shows: the index change and mini quotes of the watchlist symbols.
+// The frontend renders them in a compact bar, and in tray mode closing
+// the window hides it instead of quitting; launching the app again brings
+// it back through the single-instance handler.
+
+const (
+	// trayMaxSymbols bounds the watchlist symbols in the quick menu
+	trayMaxSymbols = 10
+
+	eventTrayUpdate = "tray:update"
+)
+
+// TrayState is the content of the tray: the market index and the first
+// watchlist symbols with their latest prices
+type TrayState struct {
+	Index     Quote            `json:"index"`
+	Quotes    []WatchlistQuote `json:"quotes"`
+	Hidden    bool             `json:"hidden"` // whether the main window is hidden
+	UpdatedAt string           `json:"updatedAt"`
+}
+
+// windowHidden tracks whether the main window was hidden by the app
+var windowHidden atomic.Bool
+
+// quitting is set once the user quits for real, so closing is not
+// turned into hiding
+var quitting atomic.Bool
+
+// trayState quotes the index and the watchlist symbols
+func (a *App) trayState() TrayState {
+	state := TrayState{
+		Quotes:    []WatchlistQuote{},
+		Hidden:    windowHidden.Load(),
+		UpdatedAt: shanghaiNow().Format(time.RFC3339),
+	}
+	index := currentSettings().DefaultSymbols[0]
+	if quote, err := fetchQuote(index); err == nil {
+		quote.StaleAsOf = staleAsOf(quote.Date)
+		state.Index = quote
+	} else {
+		state.Index = Quote{Quote: providers.Quote{Symbol: index}}
+	}
+
+	symbols, err := a.watchlistSymbols()
+	if err != nil {
+		return state
+	}
+	if len(symbols) > trayMaxSymbols {
+		symbols = symbols[:trayMaxSymbols]
+	}
+	state.Quotes = make([]WatchlistQuote, len(symbols))
+	var wg sync.WaitGroup
+	for i, sym := range symbols {
+		wg.Add(1)
+		go func(i int, sym string) {
+			defer wg.Done()
+			defer recoverPanic("quote " + sym)
+			state.Quotes[i] = watchlistQuote(sym)
+		}(i, sym)
+	}
+	wg.Wait()
+	return state
+}
+
+// runTrayLoop emits the tray content at the quote refresh interval while
+// tray mode is on
+func (a *App) runTrayLoop(ctx context.Context) {
+	interval := currentSettings().quoteTTL()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			settings := currentSettings()
+			if settings.TrayMode {
+				wailsruntime.EventsEmit(a.ctx, eventTrayUpdate, a.trayState())
+			}
+			if next := settings.quoteTTL(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// beforeClose records the window layout for the next session and hides
+// the window instead of closing it in tray mode
+func (a *App) beforeClose(ctx context.Context) bool {
+	a.captureWindow()
+	if quitting.Load() || !currentSettings().TrayMode {
+		return false
+	}
+	a.HideWindow()
+	return true
+}
+
+// GetTrayState returns the index change and the watchlist mini quotes
+func (a *App) GetTrayState() (string, error) {
+	return toJSON(a.trayState())
+}
+
+// ShowWindow brings the main window back
+func (a *App) ShowWindow() {
+	windowHidden.Store(false)
+	wailsruntime.WindowShow(a.ctx)
+	wailsruntime.WindowUnminimise(a.ctx)
+}
+
+// HideWindow hides the main window; the app keeps running
+func (a *App) HideWindow() {
+	windowHidden.Store(true)
+	wailsruntime.WindowHide(a.ctx)
+}
+
+// ToggleWindow shows the main window if it is hidden and hides it
+// otherwise
+func (a *App) ToggleWindow() {
+	if windowHidden.Load() {
+		a.ShowWindow()
+	} else {
+		a.HideWindow()
+	}
+}
+
+// QuitApp exits the app, also in tray mode
+func (a *App) QuitApp() {
+	quitting.Store(true)
+	wailsruntime.Quit(a.ctx)
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Release builds set these with
+//
+//	-ldflags "-X main.appVersion=1.2.0 -X main.updateFeedURL=... -X main.updatePublicKey=..."
+//
+// Without a feed URL and a public key the updater stays off.
+var (
+	appVersion      = "0.0.0-dev"
+	updateFeedURL   = ""
+	updatePublicKey = "" // base64 Ed25519 key the installers are signed with
+)
+
+const (
+	updateChannelStable = "stable"
+	updateChannelBeta   = "beta"
+
+	// updateCheckInterval is how often the feed is checked in the background
+	updateCheckInterval = 24 * time.Hour
+	// updateFeedMaxBytes bounds the size of the feed
+	updateFeedMaxBytes = 1 << 20
+	// updateFeedTimeout bounds a check of the feed
+	updateFeedTimeout = 30 * time.Second
+	// updateDownloadTimeout bounds any request of the updater, the
+	// installer download included
+	updateDownloadTimeout = 10 * time.Minute
+
+	eventUpdateAvailable = "update:available"
+	eventUpdateReady     = "update:ready"
+)
+
+// UpdateAsset is the installer of a release for one platform. Signature
+// is the base64 Ed25519 signature of the SHA-256 digest of the file.
+type UpdateAsset struct {
+	Platform  string `json:"platform"` // GOOS/GOARCH, e.g. "windows/amd64"
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// UpdateRelease is one release in the update feed
+type UpdateRelease struct {
+	Version     string        `json:"version"`
+	Channel     string        `json:"channel"` // "stable" or "beta"
+	PublishedAt string        `json:"publishedAt"`
+	Notes       string        `json:"notes"`
+	Assets      []UpdateAsset `json:"assets"`
+}
+
+// updateFeed is the JSON document at updateFeedURL
+type updateFeed struct {
+	Releases []UpdateRelease `json:"releases"`
+}
+
+// UpdateStatus describes the updater
+type UpdateStatus struct {
+	Enabled     bool           `json:"enabled"` // whether this build has a feed and a key
+	Current     string         `json:"current"`
+	Channel     string         `json:"channel"`
+	CheckedAt   string         `json:"checkedAt,omitempty"`
+	Available   *UpdateRelease `json:"available,omitempty"`
+	Downloading bool           `json:"downloading"`
+	Ready       bool           `json:"ready"` // downloaded and verified; InstallUpdate runs it
+	Error       string         `json:"error,omitempty"`
+}
+
+// updater tracks the latest release found and its download
+type updater struct {
+	mu          sync.Mutex
+	checkedAt   time.Time
+	available   *UpdateRelease
+	downloading bool
+	installer   string // verified installer of available
+	err         error
+}
+
+// updateClient bypasses the fixture transport so recording provider
+// responses never captures or replays the feed
+var updateClient = &http.Client{Transport: http.DefaultTransport, Timeout: updateDownloadTimeout}
+
+// updateEnabled reports whether the build was given a feed and a key
+func updateEnabled() bool {
+	return updateFeedURL != "" && updatePublicKey != ""
+}
+
+// parseVersion splits "v1.2.3-beta.1" into its numbers and pre-release
+func parseVersion(v string) (nums [3]int, pre string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, pre, _ = strings.Cut(v, "-")
+	for i, part := range strings.SplitN(v, ".", 3) {
+		nums[i], _ = strconv.Atoi(part)
+	}
+	return nums, pre
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, equal to or newer
+// than b. A pre-release is older than its release.
+func compareVersions(a, b string) int {
+	an, ap := parseVersion(a)
+	bn, bp := parseVersion(b)
+	for i := range an {
+		if an[i] != bn[i] {
+			if an[i] < bn[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case ap == bp:
+		return 0
+	case ap == "":
+		return 1
+	case bp == "":
+		return -1
+	case ap < bp:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// platformAsset returns the asset of the running platform
+func (r UpdateRelease) platformAsset() (UpdateAsset, bool) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	for _, asset := range r.Assets {
+		if asset.Platform == platform {
+			return asset, true
+		}
+	}
+	return UpdateAsset{}, false
+}
+
+// latestRelease picks the newest release of feed newer than current on
+// channel. The beta channel also sees stable releases.
+func latestRelease(feed updateFeed, channel, current string) *UpdateRelease {
+	var best *UpdateRelease
+	for i, r := range feed.Releases {
+		if r.Channel != updateChannelStable && !(channel == updateChannelBeta && r.Channel == updateChannelBeta) {
+			continue
+		}
+		if _, ok := r.platformAsset(); !ok || compareVersions(r.Version, current) <= 0 {
+			continue
+		}
+		if best == nil || compareVersions(r.Version, best.Version) > 0 {
+			best = &feed.Releases[i]
+		}
+	}
+	return best
+}
+
+// fetchUpdateFeed downloads and parses the update feed
+func fetchUpdateFeed(ctx context.Context) (updateFeed, error) {
+	var feed updateFeed
+	ctx, cancel := context.WithTimeout(ctx, updateFeedTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, updateFeedURL, nil)
+	if err != nil {
+		return feed, err
+	}
+	resp, err := updateClient.Do(req)
+	if err != nil {
+		return feed, codeErrorf(codeNetwork, "failed to check for updates: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return feed, codeErrorf(codeProvider, "update feed returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, updateFeedMaxBytes)).Decode(&feed); err != nil {
+		return feed, codeErrorf(codeParse, "failed to parse update feed: %v", err)
+	}
+	return feed, nil
+}
+
+// verifyInstaller checks the digest and the signature of the file at
+// path against asset
+func verifyInstaller(path string, asset UpdateAsset) error {
+	key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid update public key")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	digest := h.Sum(nil)
+	if !strings.EqualFold(hex.EncodeToString(digest), asset.SHA256) {
+		return fmt.Errorf("installer checksum does not match")
+	}
+	sig, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), digest, sig) {
+		return fmt.Errorf("installer signature is not valid")
+	}
+	return nil
+}
+
+// downloadInstaller saves asset to dir and verifies it. A file that
+// fails verification is removed.
+func downloadInstaller(ctx context.Context, dir string, asset UpdateAsset) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := updateClient.Do(req)
+	if err != nil {
+		return "", codeErrorf(codeNetwork, "failed to download update: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", codeErrorf(codeProvider, "update download returned %s", resp.Status)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := path.Base(req.URL.Path)
+	if name == "." || name == "/" {
+		name = "installer"
+	}
+	dest := filepath.Join(dir, name)
+	f, err := os.Create(dest + ".part")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = verifyInstaller(dest+".part", asset)
+	}
+	if err == nil {
+		err = os.Rename(dest+".part", dest)
+	}
+	if err != nil {
+		os.Remove(dest + ".part")
+		return "", err
+	}
+	return dest, nil
+}
+
+// checkForUpdate fetches the feed and, if a newer release is out, starts
+// downloading it as a job
+func (a *App) checkForUpdate(ctx context.Context) (UpdateStatus, error) {
+	if !updateEnabled() {
+		return a.updateStatus(), nil
+	}
+	feed, err := fetchUpdateFeed(ctx)
+	u := a.updates
+	u.mu.Lock()
+	u.checkedAt, u.err = time.Now(), err
+	if err != nil {
+		u.mu.Unlock()
+		return UpdateStatus{}, err
+	}
+	release := latestRelease(feed, currentSettings().UpdateChannel, appVersion)
+	fresh := release != nil && (u.available == nil || u.available.Version != release.Version)
+	if fresh {
+		u.available, u.installer = release, ""
+	} else if release == nil {
+		u.available, u.installer = nil, ""
+	}
+	start := u.available != nil && u.installer == "" && !u.downloading
+	if start {
+		u.downloading = true
+	}
+	u.mu.Unlock()
+
+	if fresh {
+		logger.Info("update available", "version", release.Version, "current", appVersion)
+		if a.ctx != nil {
+			wailsruntime.EventsEmit(a.ctx, eventUpdateAvailable, *release)
+		}
+	}
+	if start {
+		a.jobs.submit("update", release.Version, a.downloadUpdate)
+	}
+	return a.updateStatus(), nil
+}
+
+// downloadUpdate is the job that downloads and verifies the available
+// release and tells the frontend to prompt for the install
+func (a *App) downloadUpdate(ctx context.Context, job Job) (string, error) {
+	u := a.updates
+	u.mu.Lock()
+	release := u.available
+	u.mu.Unlock()
+
+	var installer string
+	err := fmt.Errorf("no update to download")
+	if release != nil {
+		asset, _ := release.platformAsset()
+		installer, err = downloadInstaller(ctx, filepath.Join(a.dataDir, "updates"), asset)
+	}
+
+	u.mu.Lock()
+	u.downloading = false
+	u.err = err
+	if err == nil && u.available == release {
+		u.installer = installer
+	}
+	u.mu.Unlock()
+	if err != nil {
+		logger.Warn("failed to download update", "error", err)
+		return "", err
+	}
+	logger.Info("update downloaded", "version", release.Version, "path", installer)
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventUpdateReady, *release)
+	}
+	return toJSON(a.updateStatus())
+}
+
+// updateStatus returns the state of the updater
+func (a *App) updateStatus() UpdateStatus {
+	u := a.updates
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	status := UpdateStatus{
+		Enabled:     updateEnabled(),
+		Current:     appVersion,
+		Channel:     currentSettings().UpdateChannel,
+		Available:   u.available,
+		Downloading: u.downloading,
+		Ready:       u.installer != "",
+	}
+	if !u.checkedAt.IsZero() {
+		status.CheckedAt = u.checkedAt.In(shanghaiNow().Location()).Format(time.RFC3339)
+	}
+	if u.err != nil {
+		status.Error = u.err.Error()
+	}
+	return status
+}
+
+// runUpdateLoop checks for updates shortly after startup and then every
+// updateCheckInterval
+func (a *App) runUpdateLoop(ctx context.Context) {
+	if !updateEnabled() {
+		return
+	}
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if _, err := a.checkForUpdate(ctx); err != nil {
+				logger.Warn("update check failed", "error", err)
+			}
+			timer.Reset(updateCheckInterval)
+		}
+	}
+}
+
+// installerCommand opens the installer the way the platform runs one
+func installerCommand(path string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command(path)
+	case "darwin":
+		return exec.Command("open", path)
+	default:
+		os.Chmod(path, 0o755)
+		return exec.Command("xdg-open", path)
+	}
+}
+
+// CheckForUpdate checks the feed of the update channel now. A newer
+// release is downloaded in the background; update:ready follows once it
+// is verified.
+func (a *App) CheckForUpdate() (string, error) {
+	status, err := a.checkForUpdate(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return toJSON(status)
+}
+
+// GetUpdateStatus returns the current version, the release found by the
+// last check and whether it is ready to install
+func (a *App) GetUpdateStatus() (string, error) {
+	return toJSON(a.updateStatus())
+}
+
+// InstallUpdate starts the verified installer and quits so it can replace
+// the app. The frontend calls it once the user agrees.
+func (a *App) InstallUpdate() error {
+	a.updates.mu.Lock()
+	installer := a.updates.installer
+	a.updates.mu.Unlock()
+	if installer == "" {
+		return codeErrorf(codeNotFound, "no update is ready to install")
+	}
+	if err := installerCommand(installer).Start(); err != nil {
+		return fmt.Errorf("failed to start installer: %v", err)
+	}
+	logger.Info("installing update", "path", installer)
+	a.QuitApp()
+	return nil
+}
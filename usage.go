@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// usageKeepDays is how many days of usage are kept
+	usageKeepDays = 30
+	// usageFlushInterval is how often counted requests are written out
+	usageFlushInterval = 30 * time.Second
+	// quotaWarnPct is the share of a daily quota that triggers a warning
+	quotaWarnPct = 80
+
+	eventProviderQuota = "provider:quota"
+)
+
+// defaultQuotas are the daily request limits of the free tiers of paid
+// APIs. Settings.Quotas overrides them.
+var defaultQuotas = map[string]int{"alphavantage": 25}
+
+// providerHosts maps the hosts the app calls to provider names
+var providerHosts = map[string]string{
+	"sohu.com":        providerSohu,
+	"eastmoney.com":   providerEastmoney,
+	"tushare.pro":     "tushare",
+	"alphavantage.co": "alphavantage",
+}
+
+// ProviderUsage is the request count of a provider on one day
+type ProviderUsage struct {
+	Requests int   `json:"requests"`
+	Errors   int   `json:"errors"` // responses with a non-2xx status
+	Bytes    int64 `json:"bytes"`
+}
+
+// UsageReport is the usage dashboard: today's counts against the quotas
+// and the daily history
+type UsageReport struct {
+	Date      string                              `json:"date"`
+	Providers []ProviderQuota                     `json:"providers"`
+	History   map[string]map[string]ProviderUsage `json:"history"` // date -> provider -> usage
+}
+
+// ProviderQuota is today's usage of a provider
+type ProviderQuota struct {
+	Provider string `json:"provider"`
+	ProviderUsage
+	Quota   int     `json:"quota"`   // daily limit, 0 if none is known
+	UsedPct float64 `json:"usedPct"` // of the quota
+}
+
+// QuotaWarning is emitted when a provider nears or reaches its quota
+type QuotaWarning struct {
+	Provider string `json:"provider"`
+	Requests int    `json:"requests"`
+	Quota    int    `json:"quota"`
+	Exceeded bool   `json:"exceeded"`
+}
+
+// usageStore counts provider requests per day. Counts are kept in memory
+// and flushed to usage.json periodically and at shutdown.
+type usageStore struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	days    map[string]map[string]ProviderUsage
+	dirty   bool
+	warned  map[string]bool // provider|date|level already warned about
+	onQuota func(QuotaWarning)
+}
+
+// providerUsage is counted by logProviderResponse. openStores points it
+// at the data directory.
+var providerUsage *usageStore
+
+func newUsageStore(dataDir string) *usageStore {
+	return &usageStore{path: filepath.Join(dataDir, "usage.json"), warned: make(map[string]bool)}
+}
+
+// unload forgets the counts, flushed or not, so the next use reads the file again
+func (s *usageStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.days, s.dirty, s.loaded = nil, false, false
+}
+
+// load reads the counts from disk on first use. Callers hold s.mu.
+func (s *usageStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.days = make(map[string]map[string]ProviderUsage)
+	if err := loadJSON(s.path, &s.days); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// providerForURL names the provider serving u, or its host if the
+// provider is unknown
+func providerForURL(u *url.URL) string {
+	host := u.Hostname()
+	for suffix, name := range providerHosts {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return name
+		}
+	}
+	return host
+}
+
+// quotaFor returns the daily quota of provider, 0 if none is known
+func quotaFor(provider string) int {
+	if q, ok := currentSettings().Quotas[provider]; ok {
+		return q
+	}
+	return defaultQuotas[provider]
+}
+
+// record counts a response of provider and warns once per day when the
+// count reaches quotaWarnPct and 100% of its quota
+func (s *usageStore) record(provider string, status int, bytes int) {
+	if s == nil {
+		return
+	}
+	date := shanghaiNow().Format("2006-01-02")
+	s.mu.Lock()
+	if err := s.load(); err != nil {
+		s.mu.Unlock()
+		logger.Warn("failed to load provider usage", "error", err)
+		return
+	}
+	day := s.days[date]
+	if day == nil {
+		day = make(map[string]ProviderUsage)
+		s.days[date] = day
+	}
+	u := day[provider]
+	u.Requests++
+	u.Bytes += int64(bytes)
+	if status < 200 || status > 299 {
+		u.Errors++
+	}
+	day[provider] = u
+	s.dirty = true
+
+	var warning *QuotaWarning
+	if quota := quotaFor(provider); quota > 0 {
+		level := ""
+		switch {
+		case u.Requests >= quota:
+			level = "exceeded"
+		case u.Requests*100 >= quota*quotaWarnPct:
+			level = "near"
+		}
+		if key := provider + "|" + date + "|" + level; level != "" && !s.warned[key] {
+			s.warned[key] = true
+			warning = &QuotaWarning{Provider: provider, Requests: u.Requests, Quota: quota, Exceeded: level == "exceeded"}
+		}
+	}
+	notify := s.onQuota
+	s.mu.Unlock()
+
+	if warning != nil {
+		logger.Warn("provider quota", "provider", provider, "requests", warning.Requests, "quota", warning.Quota)
+		if notify != nil {
+			notify(*warning)
+		}
+	}
+}
+
+// flush writes the counts if they changed, dropping days older than
+// usageKeepDays
+func (s *usageStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	cutoff := shanghaiNow().AddDate(0, 0, -usageKeepDays).Format("2006-01-02")
+	for date := range s.days {
+		if date < cutoff {
+			delete(s.days, date)
+		}
+	}
+	if err := saveJSON(s.path, s.days); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// runUsageFlusher writes the usage counts periodically and once more when
+// the app shuts down
+func (a *App) runUsageFlusher(ctx context.Context) {
+	providerUsage.mu.Lock()
+	providerUsage.onQuota = func(w QuotaWarning) {
+		wailsruntime.EventsEmit(a.ctx, eventProviderQuota, w)
+	}
+	providerUsage.mu.Unlock()
+
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := providerUsage.flush(); err != nil {
+				logger.Warn("failed to save provider usage", "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := providerUsage.flush(); err != nil {
+				logger.Warn("failed to save provider usage", "error", err)
+			}
+		}
+	}
+}
+
+// GetProviderUsage returns today's request counts per provider against
+// their daily quotas, and the counts of the last days
+func (a *App) GetProviderUsage() (string, error) {
+	s := providerUsage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	date := shanghaiNow().Format("2006-01-02")
+	report := UsageReport{Date: date, Providers: []ProviderQuota{}, History: make(map[string]map[string]ProviderUsage)}
+	names := map[string]bool{}
+	for name := range s.days[date] {
+		names[name] = true
+	}
+	for name := range defaultQuotas {
+		names[name] = true
+	}
+	for name := range currentSettings().Quotas {
+		names[name] = true
+	}
+	for name := range names {
+		q := ProviderQuota{Provider: name, ProviderUsage: s.days[date][name], Quota: quotaFor(name)}
+		if q.Quota > 0 {
+			q.UsedPct = float64(q.Requests) / float64(q.Quota) * 100
+		}
+		report.Providers = append(report.Providers, q)
+	}
+	sort.Slice(report.Providers, func(i, j int) bool { return report.Providers[i].Provider < report.Providers[j].Provider })
+	for d, day := range s.days {
+		copied := make(map[string]ProviderUsage, len(day))
+		for name, u := range day {
+			copied[name] = u
+		}
+		report.History[d] = copied
+	}
+	return toJSON(report)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"stock-analysis/internal/analysis"
+)
+
+// Realized volatility depends on the horizon it is measured over: short
+// windows swing widely while long ones settle near the stock's norm. A
+// volatility cone lays out, for each horizon, the spread of every rolling
+// realized volatility in the history, so the current reading of a horizon
+// can be placed against its own past rather than against the other
+// horizons. Volatility is the annualized standard deviation of daily log
+// returns.
+
+// volConeHorizons are the windows of the cone, in sessions
+var volConeHorizons = []int{5, 10, 20, 60, 120, 250}
+
+// volConePercentiles are the percentiles of each horizon's history
+var volConePercentiles = []float64{10, 25, 50, 75, 90}
+
+// VolConeHorizon is the realized volatility of one horizon against its
+// history, all annualized in %
+type VolConeHorizon struct {
+	Sessions    int       `json:"sessions"`
+	Current     float64   `json:"current"`
+	Min         float64   `json:"min"`
+	Max         float64   `json:"max"`
+	Percentiles []float64 `json:"percentiles"` // at VolatilityCone.Levels
+	Rank        float64   `json:"rank"`        // share of the history below Current, %
+	Samples     int       `json:"samples"`     // rolling windows in the history
+	Level       string    `json:"level"`       // "high" above the 90th percentile, "low" below the 10th, else "normal"
+}
+
+// VolatilityCone is the realized volatility of a symbol over each horizon.
+// Horizons longer than the history are left out.
+type VolatilityCone struct {
+	Symbol   string           `json:"symbol"`
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	Levels   []float64        `json:"levels"`
+	Horizons []VolConeHorizon `json:"horizons"`
+}
+
+// rollingVolatility returns the annualized volatility, in %, of each
+// window of n log returns
+func rollingVolatility(logReturns []float64, n int) []float64 {
+	if len(logReturns) < n {
+		return nil
+	}
+	annualize := math.Sqrt(analysis.TradingDaysPerYear) * 100
+	out := make([]float64, 0, len(logReturns)-n+1)
+	for i := n; i <= len(logReturns); i++ {
+		out = append(out, analysis.StdDev(logReturns[i-n:i])*annualize)
+	}
+	return out
+}
+
+// volatilityCone computes the cone of bars
+func volatilityCone(symbol string, bars []Bar) VolatilityCone {
+	cone := VolatilityCone{Symbol: symbol, Levels: volConePercentiles, Horizons: []VolConeHorizon{}}
+	if len(bars) == 0 {
+		return cone
+	}
+	cone.From, cone.To = bars[0].Date, bars[len(bars)-1].Date
+	var logReturns []float64
+	for i := 1; i < len(bars); i++ {
+		if bars[i-1].Close > 0 && bars[i].Close > 0 {
+			logReturns = append(logReturns, math.Log(bars[i].Close/bars[i-1].Close))
+		}
+	}
+	for _, n := range volConeHorizons {
+		vols := rollingVolatility(logReturns, n)
+		if len(vols) == 0 {
+			continue
+		}
+		h := VolConeHorizon{Sessions: n, Current: vols[len(vols)-1], Samples: len(vols)}
+		for _, p := range volConePercentiles {
+			h.Percentiles = append(h.Percentiles, analysis.Percentile(vols, p))
+		}
+		sorted := append([]float64(nil), vols...)
+		sort.Float64s(sorted)
+		h.Min, h.Max = sorted[0], sorted[len(sorted)-1]
+		h.Rank = float64(sort.SearchFloat64s(sorted, h.Current)) / float64(len(sorted)) * 100
+		switch {
+		case h.Current > h.Percentiles[len(h.Percentiles)-1]:
+			h.Level = "high"
+		case h.Current < h.Percentiles[0]:
+			h.Level = "low"
+		default:
+			h.Level = "normal"
+		}
+		cone.Horizons = append(cone.Horizons, h)
+	}
+	return cone
+}
+
+// GetVolatilityCone returns the realized volatility of symbol over several
+// horizons with the percentiles of each over the last days. days of 0
+// uses the configured lookback and lookbackMax (-1) all available
+// history; a cone needs a few years to be meaningful.
+func (a *App) GetVolatilityCone(symbol string, days int) (string, error) {
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	return toJSON(volatilityCone(symbol, bars))
+}
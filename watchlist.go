@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"stock-analysis/internal/indicators"
+	"stock-analysis/internal/providers"
+)
+
+// Watchlist is a named, ordered list of symbols
+type Watchlist struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Symbols   []string `json:"symbols"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+// indexOf returns the position of symbol in the list, or -1
+func (w *Watchlist) indexOf(symbol string) int {
+	for i, s := range w.Symbols {
+		if s == symbol {
+			return i
+		}
+	}
+	return -1
+}
+
+// watchlistStore guards the watchlists and their file on disk
+type watchlistStore struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	lists  []*Watchlist
+}
+
+func newWatchlistStore(dataDir string) *watchlistStore {
+	return &watchlistStore{path: filepath.Join(dataDir, "watchlists.json")}
+}
+
+// unload forgets the lists so the next use reads the file again
+func (s *watchlistStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists, s.loaded = nil, false
+}
+
+// load reads the watchlists from disk on first use. Callers hold s.mu.
+func (s *watchlistStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.lists); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// find returns the watchlist with id. Callers hold s.mu.
+func (s *watchlistStore) find(id string) (*Watchlist, error) {
+	for _, w := range s.lists {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, codeErrorf(codeNotFound, "watchlist not found: %s", id)
+}
+
+// update applies fn to the watchlist with id and saves the result
+func (s *watchlistStore) update(id string, fn func(w *Watchlist) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	w, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	backup := *w
+	backup.Symbols = append([]string(nil), w.Symbols...)
+	if err := fn(w); err != nil {
+		*w = backup
+		return err
+	}
+	return saveJSON(s.path, s.lists)
+}
+
+// get returns a copy of the watchlist with id
+func (s *watchlistStore) get(id string) (Watchlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return Watchlist{}, err
+	}
+	w, err := s.find(id)
+	if err != nil {
+		return Watchlist{}, err
+	}
+	out := *w
+	out.Symbols = append([]string{}, w.Symbols...)
+	return out, nil
+}
+
+// normalizeSymbol trims a user-entered symbol
+func normalizeSymbol(symbol string) string {
+	return strings.ToLower(strings.TrimSpace(symbol))
+}
+
+// ListWatchlists returns every watchlist in display order
+func (a *App) ListWatchlists() (string, error) {
+	s := a.watchlists
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	if s.lists == nil {
+		return "[]", nil
+	}
+	return toJSON(s.lists)
+}
+
+// CreateWatchlist adds an empty watchlist and returns it
+func (a *App) CreateWatchlist(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", codeErrorf(codeParse, "watchlist name is required")
+	}
+	w := &Watchlist{
+		ID:        newID(),
+		Name:      name,
+		Symbols:   []string{},
+		CreatedAt: shanghaiNow().Format(time.RFC3339),
+	}
+
+	s := a.watchlists
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	s.lists = append(s.lists, w)
+	if err := saveJSON(s.path, s.lists); err != nil {
+		return "", err
+	}
+	return toJSON(w)
+}
+
+// RenameWatchlist changes a watchlist's name
+func (a *App) RenameWatchlist(id string, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return codeErrorf(codeParse, "watchlist name is required")
+	}
+	return a.watchlists.update(id, func(w *Watchlist) error {
+		w.Name = name
+		return nil
+	})
+}
+
+// DeleteWatchlist removes a watchlist
+func (a *App) DeleteWatchlist(id string) error {
+	s := a.watchlists
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i, w := range s.lists {
+		if w.ID == id {
+			s.lists = append(s.lists[:i], s.lists[i+1:]...)
+			return saveJSON(s.path, s.lists)
+		}
+	}
+	return codeErrorf(codeNotFound, "watchlist not found: %s", id)
+}
+
+// AddWatchlistSymbol appends symbol to a watchlist
+func (a *App) AddWatchlistSymbol(id string, symbol string) error {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return codeErrorf(codeParse, "symbol is required")
+	}
+	return a.watchlists.update(id, func(w *Watchlist) error {
+		if w.indexOf(symbol) >= 0 {
+			return fmt.Errorf("%s is already in %s", symbol, w.Name)
+		}
+		w.Symbols = append(w.Symbols, symbol)
+		return nil
+	})
+}
+
+// RemoveWatchlistSymbol removes symbol from a watchlist
+func (a *App) RemoveWatchlistSymbol(id string, symbol string) error {
+	symbol = normalizeSymbol(symbol)
+	return a.watchlists.update(id, func(w *Watchlist) error {
+		i := w.indexOf(symbol)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "%s is not in %s", symbol, w.Name)
+		}
+		w.Symbols = append(w.Symbols[:i], w.Symbols[i+1:]...)
+		return nil
+	})
+}
+
+// ReorderWatchlist sets the order of a watchlist's symbols. symbolsJSON
+// must contain exactly the symbols already in the list.
+func (a *App) ReorderWatchlist(id string, symbolsJSON string) error {
+	var symbols []string
+	if err := json.Unmarshal([]byte(symbolsJSON), &symbols); err != nil {
+		return codeErrorf(codeParse, "failed to parse symbols: %v", err)
+	}
+	return a.watchlists.update(id, func(w *Watchlist) error {
+		if len(symbols) != len(w.Symbols) {
+			return codeErrorf(codeParse, "new order has %d symbols, watchlist has %d", len(symbols), len(w.Symbols))
+		}
+		seen := make(map[string]bool, len(symbols))
+		for i, sym := range symbols {
+			sym = normalizeSymbol(sym)
+			if seen[sym] || w.indexOf(sym) < 0 {
+				return codeErrorf(codeParse, "new order does not match the watchlist at %s", sym)
+			}
+			seen[sym] = true
+			symbols[i] = sym
+		}
+		w.Symbols = symbols
+		return nil
+	})
+}
+
+// WatchlistQuote is one row of a watchlist view
+type WatchlistQuote struct {
+	Symbol       string   `json:"symbol"`
+	Date         string   `json:"date"`
+	Price        float64  `json:"price"`
+	Change       float64  `json:"change"`
+	ChangePct    float64  `json:"changePct"`
+	Volume       float64  `json:"volume"`
+	VolumeRate5D float64  `json:"volumeRate5d"`        // volume change against 5 sessions ago, %
+	Amplitude    float64  `json:"amplitude"`           // high-low range over the previous close, %
+	Session      string   `json:"session,omitempty"`   // market phase of the bar; not "closed" while it is forming
+	StaleAsOf    string   `json:"staleAsOf,omitempty"` // set when served offline
+	Note         string   `json:"note,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// watchlistQuote fetches the quote row for one symbol
+func watchlistQuote(symbol string) WatchlistQuote {
+	key := "watchlistQuote:" + providers.SohuCode(symbol)
+	if v, ok := resultCache.get(key); ok {
+		return v.(WatchlistQuote)
+	}
+	row := WatchlistQuote{Symbol: symbol}
+	now := shanghaiNow()
+	// The 5-day rate needs 6 sessions; two more allow for a provider that
+	// is behind
+	bars, err := fetchDailyBars(symbol, tradingDaysBefore(now, 8), now)
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	if len(bars) == 0 {
+		row.Error = "no data"
+		return row
+	}
+	quote := providers.QuoteFromBars(symbol, bars)
+	row.Date = quote.Date
+	row.Price = quote.Price
+	row.Change = quote.Change
+	row.ChangePct = quote.ChangePct
+	row.Volume = quote.Volume
+
+	volumes := make([]float64, len(bars))
+	for i, bar := range bars {
+		volumes[i] = bar.Volume
+	}
+	row.VolumeRate5D = indicators.FiveDayRate(volumes)[len(volumes)-1]
+	if n := len(bars); n > 1 && bars[n-2].Close != 0 {
+		row.Amplitude = (bars[n-1].High - bars[n-1].Low) / bars[n-2].Close * 100
+	}
+	resultCache.set(key, row, currentSettings().quoteTTL())
+	return row
+}
+
+// GetWatchlistQuotes returns price, change, volume and 5-day volume rate
+// for every symbol in a watchlist, in list order, together with the
+// user's notes and tags. Symbols that fail to
+// load carry an error instead of failing the whole call.
+func (a *App) GetWatchlistQuotes(listID string) (string, error) {
+	w, err := a.watchlists.get(listID)
+	if err != nil {
+		return "", err
+	}
+
+	rows := make([]WatchlistQuote, len(w.Symbols))
+	var wg sync.WaitGroup
+	for i, sym := range w.Symbols {
+		wg.Add(1)
+		go func(i int, sym string) {
+			defer wg.Done()
+			defer recoverPanic("quote " + sym)
+			rows[i] = watchlistQuote(sym)
+		}(i, sym)
+	}
+	wg.Wait()
+
+	notes := a.notes.lookup(w.Symbols)
+	now := shanghaiNow()
+	for i := range rows {
+		if rows[i].Error == "" {
+			rows[i].StaleAsOf = staleAsOf(rows[i].Date)
+			rows[i].Session = barSession(rows[i].Date, now)
+		}
+		if n, ok := notes[rows[i].Symbol]; ok {
+			rows[i].Note = n.Note
+			rows[i].Tags = n.Tags
+		}
+	}
+	return toJSON(rows)
+}
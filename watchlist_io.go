@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WatchlistImportResult reports the list created by an import and the
+// lines that were not recognised as symbols
+type WatchlistImportResult struct {
+	Watchlist *Watchlist `json:"watchlist"`
+	Skipped   []string   `json:"skipped"`
+}
+
+var (
+	// 通达信 .EBK lines: market digit (1 Shanghai, 0 Shenzhen) + code
+	tdxCodePattern = regexp.MustCompile(`^([01])(\d{6})$`)
+	// 同花顺 and most tools: SH600519, 600519.SH, sh600519
+	exchangeCodePattern = regexp.MustCompile(`^(?i)(?:(sh|sz)(\d{6})|(\d{6})\.(sh|sz))$`)
+	plainCodePattern    = regexp.MustCompile(`^\d{6}$`)
+)
+
+// exchangeSymbol converts an exchange-qualified code to this app's symbol.
+// Shanghai codes starting with 000 and Shenzhen codes starting with 399
+// are indices.
+func exchangeSymbol(exchange, code string) string {
+	if (exchange == "sh" && strings.HasPrefix(code, "000")) || (exchange == "sz" && strings.HasPrefix(code, "399")) {
+		return "zs_" + code
+	}
+	return "cn_" + code
+}
+
+// parseWatchlistSymbol recognises the code formats other tools export
+func parseWatchlistSymbol(field string) (string, bool) {
+	field = strings.TrimSpace(field)
+	if strings.HasPrefix(field, "cn_") || strings.HasPrefix(field, "zs_") {
+		return field, plainCodePattern.MatchString(field[3:])
+	}
+	if m := tdxCodePattern.FindStringSubmatch(field); m != nil {
+		if m[1] == "1" {
+			return exchangeSymbol("sh", m[2]), true
+		}
+		return exchangeSymbol("sz", m[2]), true
+	}
+	if m := exchangeCodePattern.FindStringSubmatch(field); m != nil {
+		if m[1] != "" {
+			return exchangeSymbol(strings.ToLower(m[1]), m[2]), true
+		}
+		return exchangeSymbol(strings.ToLower(m[4]), m[3]), true
+	}
+	if plainCodePattern.MatchString(field) {
+		return "cn_" + field, true
+	}
+	return "", false
+}
+
+// parseWatchlistText extracts symbols from the first column of a text or
+// CSV export, skipping headers and duplicates
+func parseWatchlistText(content string) ([]string, []string, error) {
+	records, err := readCSVRecords(decodeCSVText([]byte(content)))
+	if err != nil {
+		return nil, nil, err
+	}
+	symbols := []string{}
+	skipped := []string{}
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		symbol, ok := parseWatchlistSymbol(record[0])
+		if !ok {
+			skipped = append(skipped, strings.Join(record, ","))
+			continue
+		}
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols, skipped, nil
+}
+
+// formatWatchlist renders symbols in an export format: "tdx" (通达信 .EBK),
+// "ths" (同花顺 text, one SH/SZ-prefixed code per line) or "csv"
+func formatWatchlist(w Watchlist, format string) (string, error) {
+	var b strings.Builder
+	switch format {
+	case "tdx":
+		// EBK files start with an empty line and use CRLF
+		b.WriteString("\r\n")
+		for _, sym := range w.Symbols {
+			market, code := symbolMarket(sym)
+			digit := "0"
+			if market == "sh" {
+				digit = "1"
+			}
+			b.WriteString(digit + code + "\r\n")
+		}
+	case "ths":
+		for _, sym := range w.Symbols {
+			market, code := symbolMarket(sym)
+			b.WriteString(strings.ToUpper(market) + code + "\r\n")
+		}
+	case "csv", "":
+		b.WriteString("symbol\n")
+		for _, sym := range w.Symbols {
+			b.WriteString(sym + "\n")
+		}
+	default:
+		return "", codeErrorf(codeParse, "unknown export format: %s", format)
+	}
+	return b.String(), nil
+}
+
+// symbolMarket returns the exchange ("sh" or "sz") and bare code of symbol
+func symbolMarket(symbol string) (string, string) {
+	if strings.HasPrefix(eastmoneySecID(symbol), "1.") {
+		return "sh", strings.TrimPrefix(strings.TrimPrefix(symbol, "cn_"), "zs_")
+	}
+	return "sz", strings.TrimPrefix(strings.TrimPrefix(symbol, "cn_"), "zs_")
+}
+
+// ImportWatchlist creates a watchlist named name from a plain text, CSV,
+// 通达信 .EBK or 同花顺 export. Lines that are not symbols are reported
+// back rather than failing the import.
+func (a *App) ImportWatchlist(name string, content string) (_ string, err error) {
+	var symbols []string
+	defer func() {
+		recordAudit(auditImport, "watchlist", name, fmt.Sprintf("%d symbols", len(symbols)), err)
+	}()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", codeErrorf(codeParse, "watchlist name is required")
+	}
+	symbols, skipped, err := parseWatchlistText(content)
+	if err != nil {
+		return "", err
+	}
+	if len(symbols) == 0 {
+		return "", codeErrorf(codeNoData, "no symbols found")
+	}
+	w := &Watchlist{
+		ID:        newID(),
+		Name:      name,
+		Symbols:   symbols,
+		CreatedAt: shanghaiNow().Format(time.RFC3339),
+	}
+
+	s := a.watchlists
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	s.lists = append(s.lists, w)
+	if err := saveJSON(s.path, s.lists); err != nil {
+		return "", err
+	}
+	return toJSON(WatchlistImportResult{Watchlist: w, Skipped: skipped})
+}
+
+// ExportWatchlist renders a watchlist as "tdx", "ths" or "csv" text
+func (a *App) ExportWatchlist(id string, format string) (string, error) {
+	w, err := a.watchlists.get(id)
+	if err != nil {
+		return "", err
+	}
+	text, err := formatWatchlist(w, format)
+	recordAudit(auditExport, "watchlist", id, format, err)
+	return text, err
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Events a delivery channel can subscribe to
+const (
+	deliveryEventAlert = "alert"
+	deliveryEventScan  = "scan"
+)
+
+// outboundMessage is a notification pushed to external channels
+type outboundMessage struct {
+	Event string      `json:"event"`
+	Title string      `json:"title"`
+	Text  string      `json:"text"`
+	Data  interface{} `json:"data,omitempty"`
+
+	symbol string // whose chart targets with Chart set receive
+	chart  string // base64 PNG, drawn once for all targets
+}
+
+// WebhookTarget is a chat tool or HTTP endpoint that receives alerts and
+// scan results
+type WebhookTarget struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"` // "wecom", "dingtalk", "telegram" or "generic"
+	URL      string   `json:"url,omitempty"`
+	Secret   string   `json:"secret,omitempty"` // DingTalk signing secret
+	Token    string   `json:"token,omitempty"`  // Telegram bot token
+	ChatID   string   `json:"chatId,omitempty"` // Telegram chat
+	Template string   `json:"template,omitempty"`
+	Events   []string `json:"events"`          // empty means every event
+	Chart    bool     `json:"chart,omitempty"` // generic only: add a PNG chart of the symbol
+	Enabled  bool     `json:"enabled"`
+}
+
+// defaultWebhookTemplate renders a message as plain text
+const defaultWebhookTemplate = "{{.Title}}\n{{.Text}}"
+
+// validate checks the fields each kind of target needs and compiles the
+// message template
+func (w *WebhookTarget) validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return codeErrorf(codeParse, "webhook name is required")
+	}
+	switch w.Kind {
+	case "wecom", "dingtalk", "generic":
+		if _, err := url.ParseRequestURI(w.URL); err != nil {
+			return codeErrorf(codeParse, "invalid webhook URL: %s", w.URL)
+		}
+	case "telegram":
+		if w.Token == "" || w.ChatID == "" {
+			return codeErrorf(codeParse, "telegram webhooks need a bot token and chat ID")
+		}
+	default:
+		return codeErrorf(codeParse, "unknown webhook kind: %s", w.Kind)
+	}
+	_, err := w.template()
+	return err
+}
+
+// template compiles the target's message template
+func (w *WebhookTarget) template() (*template.Template, error) {
+	src := w.Template
+	if src == "" {
+		src = defaultWebhookTemplate
+	}
+	tmpl, err := template.New(w.Name).Parse(src)
+	if err != nil {
+		return nil, codeErrorf(codeParse, "invalid message template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// wants reports whether the target subscribes to event
+func (w *WebhookTarget) wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// dingtalkSign appends the timestamp and HMAC signature DingTalk robots
+// with 加签 security require
+func dingtalkSign(endpoint, secret string, now time.Time) string {
+	ts := fmt.Sprint(now.UnixMilli())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	sign := url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + "timestamp=" + ts + "&sign=" + sign
+}
+
+// request builds the HTTP endpoint and JSON body for msg
+func (w *WebhookTarget) request(msg outboundMessage) (string, interface{}, error) {
+	tmpl, err := w.template()
+	if err != nil {
+		return "", nil, err
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return "", nil, fmt.Errorf("failed to render message: %v", err)
+	}
+
+	switch w.Kind {
+	case "wecom":
+		return w.URL, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text.String()},
+		}, nil
+	case "dingtalk":
+		endpoint := w.URL
+		if w.Secret != "" {
+			endpoint = dingtalkSign(endpoint, w.Secret, time.Now())
+		}
+		return endpoint, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text.String()},
+		}, nil
+	case "telegram":
+		return "https://api.telegram.org/bot" + w.Token + "/sendMessage", map[string]string{
+			"chat_id": w.ChatID,
+			"text":    text.String(),
+		}, nil
+	}
+	body := map[string]interface{}{
+		"event": msg.Event,
+		"title": msg.Title,
+		"text":  text.String(),
+		"data":  msg.Data,
+	}
+	if w.Chart && msg.chart != "" {
+		body["chart"] = msg.chart
+	}
+	return w.URL, body, nil
+}
+
+// webhookClient sends through the connection pool with a deadline, so an
+// endpoint that stops answering cannot hold up a delivery
+var webhookClient = &http.Client{Transport: providerTransport, Timeout: 15 * time.Second}
+
+// send posts msg to the target
+func (w *WebhookTarget) send(msg outboundMessage) error {
+	endpoint, body, err := w.request(msg)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s: %s", w.Name, resp.Status, strings.TrimSpace(string(reply)))
+	}
+	// WeCom and DingTalk report failures in the body with HTTP 200
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(reply, &result) == nil && result.ErrCode != 0 {
+		return fmt.Errorf("%s rejected the message: %s", w.Name, result.ErrMsg)
+	}
+	return nil
+}
+
+// webhookStore persists the webhook targets
+type webhookStore struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	targets []*WebhookTarget
+}
+
+func newWebhookStore(dataDir string) *webhookStore {
+	return &webhookStore{path: filepath.Join(dataDir, "webhooks.json")}
+}
+
+// unload forgets the targets so the next use reads the file again
+func (s *webhookStore) unload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets, s.loaded = nil, false
+}
+
+// load reads the targets from disk on first use. Callers hold s.mu.
+func (s *webhookStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if err := loadJSON(s.path, &s.targets); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+// subscribers returns copies of the enabled targets that want event
+func (s *webhookStore) subscribers(event string) []WebhookTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil
+	}
+	var out []WebhookTarget
+	for _, t := range s.targets {
+		if t.Enabled && t.wants(event) {
+			out = append(out, *t)
+		}
+	}
+	return out
+}
+
+// dispatch pushes msg to every subscribed channel in the background
+func (a *App) dispatch(msg outboundMessage) {
+	targets := a.webhooks.subscribers(msg.Event)
+	for _, target := range targets {
+		if target.Chart && msg.symbol != "" && msg.chart == "" {
+			msg.chart = a.messageChart(msg.symbol)
+		}
+	}
+	for _, target := range targets {
+		go func(target WebhookTarget) {
+			defer recoverPanic("webhook " + target.Name)
+			target.send(msg)
+		}(target)
+	}
+	a.emailMessage(msg)
+}
+
+// dispatchAlerts pushes each trigger to the alert subscribers
+func (a *App) dispatchAlerts(triggers []AlertTrigger) {
+	for _, t := range triggers {
+		a.dispatch(outboundMessage{
+			Event: deliveryEventAlert,
+			Title: tr("message.alert", t.Symbol),
+			Text:  t.Message,
+			Data:  t,
+
+			symbol: t.Symbol,
+		})
+	}
+}
+
+// messageChart draws the PNG chart attached to messages about symbol, or
+// returns "" if it cannot be drawn
+func (a *App) messageChart(symbol string) string {
+	opts := ChartOptions{Format: "png", Width: 800, Height: 480}
+	opts.normalize()
+	data, err := a.symbolChart(symbol, opts)
+	if err != nil {
+		logger.Warn("failed to draw message chart", "symbol", symbol, "error", err)
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// ListWebhooks returns the configured webhook targets
+func (a *App) ListWebhooks() (string, error) {
+	s := a.webhooks
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	if s.targets == nil {
+		return "[]", nil
+	}
+	return toJSON(s.targets)
+}
+
+// SaveWebhook creates a target, or replaces the one with the same ID, and
+// returns it
+func (a *App) SaveWebhook(targetJSON string) (string, error) {
+	var target WebhookTarget
+	if err := json.Unmarshal([]byte(targetJSON), &target); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse webhook: %v", err)
+	}
+	if err := target.validate(); err != nil {
+		return "", err
+	}
+
+	s := a.webhooks
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	replaced := false
+	if target.ID != "" {
+		for i, t := range s.targets {
+			if t.ID == target.ID {
+				s.targets[i] = &target
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			return "", codeErrorf(codeNotFound, "webhook not found: %s", target.ID)
+		}
+	} else {
+		target.ID = newID()
+		s.targets = append(s.targets, &target)
+	}
+	if err := saveJSON(s.path, s.targets); err != nil {
+		return "", err
+	}
+	return toJSON(target)
+}
+
+// DeleteWebhook removes a webhook target
+func (a *App) DeleteWebhook(id string) error {
+	s := a.webhooks
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i, t := range s.targets {
+		if t.ID == id {
+			s.targets = append(s.targets[:i], s.targets[i+1:]...)
+			return saveJSON(s.path, s.targets)
+		}
+	}
+	return codeErrorf(codeNotFound, "webhook not found: %s", id)
+}
+
+// TestWebhook sends a test message to a target and reports any error
+func (a *App) TestWebhook(id string) error {
+	s := a.webhooks
+	s.mu.Lock()
+	var target *WebhookTarget
+	if err := s.load(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	for _, t := range s.targets {
+		if t.ID == id {
+			copied := *t
+			target = &copied
+		}
+	}
+	s.mu.Unlock()
+	if target == nil {
+		return codeErrorf(codeNotFound, "webhook not found: %s", id)
+	}
+	return target.send(outboundMessage{
+		Event: "test",
+		Title: "stock-analysis",
+		Text:  tr("message.test", shanghaiNow().Format("2006-01-02 15:04:05")),
+	})
+}
+
+// PushStrategyScreen runs a strategy screen and pushes the matches to the
+// webhooks subscribed to scan results
+func (a *App) PushStrategyScreen(id string) (string, error) {
+	result, err := a.RunStrategyScreen(id)
+	if err != nil {
+		return "", err
+	}
+	var matches []ScreenMatch
+	if err := json.Unmarshal([]byte(result), &matches); err != nil {
+		return "", err
+	}
+	def, err := a.strategies.get(id)
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, len(matches))
+	for i, m := range matches {
+		lines[i] = fmt.Sprintf("%s %s %.2f", m.Symbol, m.Name, m.Close)
+	}
+	text := tr("message.matches", len(matches))
+	if len(lines) > 0 {
+		text += "\n" + strings.Join(lines, "\n")
+	}
+	a.dispatch(outboundMessage{
+		Event: deliveryEventScan,
+		Title: tr("message.screen", def.Name),
+		Text:  text,
+		Data:  matches,
+	})
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Symbol windows let the user watch several stocks side by side. Wails v2
+// drives a single native window per process, and a second process would
+// race this one over the data directory, so the frontend draws each
+// symbol window as a movable frame inside the main window. The backend
+// owns the windows: their symbol, layout and place are part of the
+// session, so they come back after a restart, and every change is
+// broadcast so each frame follows its own context.
+
+const (
+	// maxSymbolWindows is the most symbol windows open at once
+	maxSymbolWindows = 8
+
+	eventWindowsChanged = "window:changed"
+	eventWindowFocus    = "window:focus"
+)
+
+// WindowBounds is where a symbol window sits in the main window, in CSS
+// pixels
+type WindowBounds struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// SymbolWindow is one symbol window and the context it shows
+type SymbolWindow struct {
+	ID        string            `json:"id"`
+	Symbol    string            `json:"symbol"`
+	ChartDays int               `json:"chartDays"` // 0 for the configured lookback
+	Layout    map[string]string `json:"layout"`    // free-form layout hints of the frontend
+	Bounds    WindowBounds      `json:"bounds"`
+	Pinned    bool              `json:"pinned"` // kept above the others
+	CreatedAt string            `json:"createdAt"`
+}
+
+// clone returns a copy that shares no map with w
+func (w SymbolWindow) clone() SymbolWindow {
+	out := w
+	out.Layout = make(map[string]string, len(w.Layout))
+	for k, v := range w.Layout {
+		out.Layout[k] = v
+	}
+	return out
+}
+
+// findWindow returns the position of the window with id, or -1
+func findWindow(windows []SymbolWindow, id string) int {
+	for i, w := range windows {
+		if w.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// emitWindows tells the frontend the symbol windows changed
+func (a *App) emitWindows(windows []SymbolWindow) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventWindowsChanged, windows)
+	}
+}
+
+// ListSymbolWindows returns the open symbol windows in stacking order,
+// bottom first
+func (a *App) ListSymbolWindows() (string, error) {
+	session, err := a.session.get()
+	if err != nil {
+		return "", err
+	}
+	return toJSON(session.Windows)
+}
+
+// OpenSymbolWindow opens a window on symbol and returns it. boundsJSON
+// is a WindowBounds object, empty to let the frontend place it; windows
+// opened without bounds cascade from the last one.
+func (a *App) OpenSymbolWindow(symbol string, boundsJSON string) (string, error) {
+	symbol = normalizeSymbol(symbol)
+	if symbol == "" {
+		return "", codeErrorf(codeParse, "symbol is required")
+	}
+	w := SymbolWindow{ID: newID(), Symbol: symbol, Layout: map[string]string{}, CreatedAt: shanghaiNow().Format(time.RFC3339)}
+	if boundsJSON != "" {
+		if err := json.Unmarshal([]byte(boundsJSON), &w.Bounds); err != nil {
+			return "", codeErrorf(codeParse, "invalid window bounds: %v", err)
+		}
+	}
+	session, err := a.session.update(func(s *SessionState) error {
+		if len(s.Windows) >= maxSymbolWindows {
+			return codeErrorf(codeBusy, "at most %d symbol windows can be open", maxSymbolWindows)
+		}
+		if boundsJSON == "" {
+			w.Bounds = WindowBounds{X: 40, Y: 40, Width: 480, Height: 360}
+			if n := len(s.Windows); n > 0 {
+				last := s.Windows[n-1].Bounds
+				w.Bounds.X, w.Bounds.Y = last.X+24, last.Y+24
+			}
+		}
+		s.Windows = append(s.Windows, w)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	a.recent.touch(symbol)
+	a.emitWindows(session.Windows)
+	return toJSON(w)
+}
+
+// UpdateSymbolWindow changes the symbol, chart range, layout, bounds or
+// pinning of a window. windowJSON is a partial SymbolWindow with its id.
+func (a *App) UpdateSymbolWindow(windowJSON string) (string, error) {
+	var patch struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(windowJSON), &patch); err != nil {
+		return "", codeErrorf(codeParse, "failed to parse window: %v", err)
+	}
+	var updated SymbolWindow
+	session, err := a.session.update(func(s *SessionState) error {
+		i := findWindow(s.Windows, patch.ID)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "window not found: %s", patch.ID)
+		}
+		w := s.Windows[i]
+		if err := json.Unmarshal([]byte(windowJSON), &w); err != nil {
+			return codeErrorf(codeParse, "failed to parse window: %v", err)
+		}
+		w.ID, w.CreatedAt = s.Windows[i].ID, s.Windows[i].CreatedAt
+		if w.Symbol = normalizeSymbol(w.Symbol); w.Symbol == "" {
+			return codeErrorf(codeParse, "symbol is required")
+		}
+		s.Windows[i] = w
+		updated = w
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if updated.Symbol != "" {
+		a.recent.touch(updated.Symbol)
+	}
+	a.emitWindows(session.Windows)
+	return toJSON(updated)
+}
+
+// FocusSymbolWindow raises a window above the others and tells the
+// frontend to focus it
+func (a *App) FocusSymbolWindow(id string) error {
+	session, err := a.session.update(func(s *SessionState) error {
+		i := findWindow(s.Windows, id)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "window not found: %s", id)
+		}
+		w := s.Windows[i]
+		s.Windows = append(append(s.Windows[:i:i], s.Windows[i+1:]...), w)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.emitWindows(session.Windows)
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, eventWindowFocus, id)
+	}
+	return nil
+}
+
+// CloseSymbolWindow closes a window
+func (a *App) CloseSymbolWindow(id string) error {
+	session, err := a.session.update(func(s *SessionState) error {
+		i := findWindow(s.Windows, id)
+		if i < 0 {
+			return codeErrorf(codeNotFound, "window not found: %s", id)
+		}
+		s.Windows = append(s.Windows[:i:i], s.Windows[i+1:]...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.emitWindows(session.Windows)
+	return nil
+}
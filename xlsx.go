@@ -0,0 +1,331 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cell styles defined in xlsxStyles, by index into cellXfs
+const (
+	xlsxGeneral = iota
+	xlsxHeader
+	xlsxDecimal  // 0.00
+	xlsxInteger  // #,##0
+	xlsxPercent  // 0.00%
+	xlsxDecimal4 // 0.0000
+)
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="0.0000"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FFDDEBF7"/><bgColor indexed="64"/></patternFill></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>
+<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="3" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="10" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+// xlsxCell is a worksheet cell. Value is a string, a float64 or nil for an
+// empty cell; NaN numbers are written as empty cells too.
+type xlsxCell struct {
+	Value interface{}
+	Style int
+}
+
+// xlsxChart is a line chart drawn on a sheet. Series are ranges on other
+// sheets sharing one category range.
+type xlsxChart struct {
+	Title      string
+	Categories string // e.g. 'Data'!$A$2:$A$100
+	Series     []xlsxSeries
+}
+
+type xlsxSeries struct {
+	Name   string // range of the series name cell
+	Values string
+}
+
+// xlsxSheet is one worksheet of a workbook
+type xlsxSheet struct {
+	Name   string
+	Widths []float64 // column widths in characters, 0 keeps the default
+	Rows   [][]xlsxCell
+	Chart  *xlsxChart
+}
+
+// xlsxColumn returns the column letters of the zero-based column i
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxRange returns an absolute reference to rows first..last of column col
+func xlsxRange(sheet string, col, first, last int) string {
+	c := xlsxColumn(col)
+	return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", sheet, c, first, c, last)
+}
+
+// sheetXML renders the worksheet part
+func (s xlsxSheet) sheetXML() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	// Keep the header row visible while scrolling
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(s.Widths) > 0 {
+		b.WriteString("<cols>")
+		for i, w := range s.Widths {
+			if w > 0 {
+				fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, w)
+			}
+		}
+		b.WriteString("</cols>")
+	}
+	b.WriteString("<sheetData>")
+	for r, row := range s.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxColumn(c) + strconv.Itoa(r+1)
+			switch v := cell.Value.(type) {
+			case string:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, ref, cell.Style, html.EscapeString(v))
+			case float64:
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					continue
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.Style, strconv.FormatFloat(v, 'g', -1, 64))
+			}
+		}
+		b.WriteString("</row>")
+	}
+	b.WriteString("</sheetData>")
+	if s.Chart != nil {
+		b.WriteString(`<drawing r:id="rId1"/>`)
+	}
+	b.WriteString("</worksheet>")
+	return b.String()
+}
+
+// chartXML renders a line chart part
+func (c xlsxChart) chartXML() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><c:chart>`)
+	fmt.Fprintf(&b, `<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>%s</a:t></a:r></a:p></c:rich></c:tx><c:overlay val="0"/></c:title>`, html.EscapeString(c.Title))
+	b.WriteString(`<c:autoTitleDeleted val="0"/><c:plotArea><c:layout/><c:lineChart><c:grouping val="standard"/><c:varyColors val="0"/>`)
+	for i, s := range c.Series {
+		fmt.Fprintf(&b, `<c:ser><c:idx val="%d"/><c:order val="%d"/><c:tx><c:strRef><c:f>%s</c:f></c:strRef></c:tx><c:marker><c:symbol val="none"/></c:marker>`, i, i, html.EscapeString(s.Name))
+		fmt.Fprintf(&b, `<c:cat><c:strRef><c:f>%s</c:f></c:strRef></c:cat><c:val><c:numRef><c:f>%s</c:f></c:numRef></c:val><c:smooth val="0"/></c:ser>`, html.EscapeString(c.Categories), html.EscapeString(s.Values))
+	}
+	b.WriteString(`<c:marker val="1"/><c:axId val="1"/><c:axId val="2"/></c:lineChart>`)
+	b.WriteString(`<c:catAx><c:axId val="1"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="b"/><c:tickLblPos val="nextTo"/><c:crossAx val="2"/><c:crosses val="autoZero"/><c:auto val="1"/><c:lblAlgn val="ctr"/><c:lblOffset val="100"/></c:catAx>`)
+	b.WriteString(`<c:valAx><c:axId val="2"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="l"/><c:majorGridlines/><c:numFmt formatCode="0.00" sourceLinked="0"/><c:tickLblPos val="nextTo"/><c:crossAx val="1"/><c:crosses val="autoZero"/><c:crossBetween val="between"/></c:valAx>`)
+	b.WriteString(`</c:plotArea><c:legend><c:legendPos val="b"/><c:overlay val="0"/></c:legend><c:plotVisOnly val="1"/></c:chart></c:chartSpace>`)
+	return b.String()
+}
+
+// drawingXML anchors a chart over columns D to N of its sheet
+func drawingXML() string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><xdr:twoCellAnchor>` +
+		`<xdr:from><xdr:col>3</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+		`<xdr:to><xdr:col>14</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>24</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to>` +
+		`<xdr:graphicFrame macro=""><xdr:nvGraphicFramePr><xdr:cNvPr id="2" name="Chart 1"/><xdr:cNvGraphicFramePr/></xdr:nvGraphicFramePr>` +
+		`<xdr:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/></xdr:xfrm><a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/chart">` +
+		`<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1"/>` +
+		`</a:graphicData></a:graphic></xdr:graphicFrame><xdr:clientData/></xdr:twoCellAnchor></xdr:wsDr>`
+}
+
+// buildXLSX packs sheets into an .xlsx workbook
+func buildXLSX(sheets []xlsxSheet) ([]byte, error) {
+	const (
+		xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+		relsNS    = `http://schemas.openxmlformats.org/package/2006/relationships`
+		relType   = `http://schemas.openxmlformats.org/officeDocument/2006/relationships/`
+	)
+	files := map[string]string{}
+	var order []string
+	add := func(name, content string) {
+		files[name] = content
+		order = append(order, name)
+	}
+
+	var types, workbook, workbookRels strings.Builder
+	types.WriteString(xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	types.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/>`)
+	types.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	types.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(xmlHeader + `<Relationships xmlns="` + relsNS + `">`)
+
+	charts := 0
+	for i, s := range sheets {
+		n := i + 1
+		sheetPath := fmt.Sprintf("xl/worksheets/sheet%d.xml", n)
+		add(sheetPath, s.sheetXML())
+		fmt.Fprintf(&types, `<Override PartName="/%s" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, sheetPath)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, html.EscapeString(s.Name), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="%sworksheet" Target="worksheets/sheet%d.xml"/>`, n, relType, n)
+
+		if s.Chart == nil {
+			continue
+		}
+		charts++
+		add(fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", n), xmlHeader+`<Relationships xmlns="`+relsNS+`">`+
+			fmt.Sprintf(`<Relationship Id="rId1" Type="%sdrawing" Target="../drawings/drawing%d.xml"/>`, relType, charts)+`</Relationships>`)
+		add(fmt.Sprintf("xl/drawings/drawing%d.xml", charts), drawingXML())
+		add(fmt.Sprintf("xl/drawings/_rels/drawing%d.xml.rels", charts), xmlHeader+`<Relationships xmlns="`+relsNS+`">`+
+			fmt.Sprintf(`<Relationship Id="rId1" Type="%schart" Target="../charts/chart%d.xml"/>`, relType, charts)+`</Relationships>`)
+		add(fmt.Sprintf("xl/charts/chart%d.xml", charts), s.Chart.chartXML())
+		fmt.Fprintf(&types, `<Override PartName="/xl/drawings/drawing%d.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"/>`, charts)
+		fmt.Fprintf(&types, `<Override PartName="/xl/charts/chart%d.xml" ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml"/>`, charts)
+	}
+	types.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="%sstyles" Target="styles.xml"/></Relationships>`, len(sheets)+1, relType)
+
+	// The content types part goes first in the archive
+	files["[Content_Types].xml"] = types.String()
+	order = append([]string{"[Content_Types].xml"}, order...)
+	add("_rels/.rels", xmlHeader+`<Relationships xmlns="`+relsNS+`"><Relationship Id="rId1" Type="`+relType+`officeDocument" Target="xl/workbook.xml"/></Relationships>`)
+	add("xl/workbook.xml", workbook.String())
+	add("xl/_rels/workbook.xml.rels", workbookRels.String())
+	add("xl/styles.xml", xlsxStyles)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range order {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// symbolWorkbook builds the export workbook of symbol: the raw bars, the
+// indicator series and a summary sheet with a price chart
+func symbolWorkbook(symbol, name string, bars []Bar) []xlsxSheet {
+	n := len(bars)
+	str := func(s string) xlsxCell { return xlsxCell{Value: s} }
+	num := func(v float64, style int) xlsxCell { return xlsxCell{Value: v, Style: style} }
+	header := func(titles ...string) []xlsxCell {
+		row := make([]xlsxCell, len(titles))
+		for i, t := range titles {
+			row[i] = xlsxCell{Value: t, Style: xlsxHeader}
+		}
+		return row
+	}
+
+	data := xlsxSheet{
+		Name:   "Data",
+		Widths: []float64{12, 10, 10, 10, 10, 10, 10, 14, 14, 12},
+		Rows: [][]xlsxCell{header(tr("report.date"), tr("xlsx.open"), tr("report.close"), tr("xlsx.change"), tr("xlsx.changePct"),
+			tr("report.low"), tr("report.high"), tr("xlsx.volume"), tr("xlsx.turnover"), tr("xlsx.turnoverRate"))},
+	}
+	for _, b := range bars {
+		data.Rows = append(data.Rows, []xlsxCell{
+			str(b.Date), num(b.Open, xlsxDecimal), num(b.Close, xlsxDecimal), num(b.Change, xlsxDecimal),
+			num(b.ChangePct/100, xlsxPercent), num(b.Low, xlsxDecimal), num(b.High, xlsxDecimal),
+			num(b.Volume, xlsxInteger), num(b.Turnover, xlsxInteger), num(b.TurnoverRate/100, xlsxPercent),
+		})
+	}
+
+	set := symbolIndicators(symbol, bars)
+	indicators := xlsxSheet{
+		Name:   "Indicators",
+		Widths: []float64{12, 10, 10, 10, 10, 10, 10, 10, 12},
+		Rows:   [][]xlsxCell{header(tr("report.date"), "MA5", "MA10", "MA20", "DIF", "DEA", "MACD", "ATR14", tr("report.volume5d"))},
+	}
+	for i, b := range bars {
+		indicators.Rows = append(indicators.Rows, []xlsxCell{
+			str(b.Date), num(set.MA5[i], xlsxDecimal), num(set.MA10[i], xlsxDecimal), num(set.MA20[i], xlsxDecimal),
+			num(set.DIF[i], xlsxDecimal4), num(set.DEA[i], xlsxDecimal4), num(set.Hist[i], xlsxDecimal4),
+			num(set.ATR14[i], xlsxDecimal4), num(set.VolumeRate5D[i]/100, xlsxPercent),
+		})
+	}
+
+	stats := symbolStats(bars)
+	summary := xlsxSheet{
+		Name:   "Summary",
+		Widths: []float64{20, 14},
+		Rows: [][]xlsxCell{
+			header(tr("xlsx.item"), tr("xlsx.value")),
+			{str(tr("xlsx.symbol")), str(symbol)},
+			{str(tr("xlsx.name")), str(name)},
+			{str(tr("xlsx.from")), str(stats.From)},
+			{str(tr("xlsx.to")), str(stats.To)},
+			{str(tr("report.sessions")), num(float64(stats.Sessions), xlsxInteger)},
+			{str(tr("report.firstClose")), num(stats.FirstClose, xlsxDecimal)},
+			{str(tr("report.lastClose")), num(stats.LastClose, xlsxDecimal)},
+			{str(tr("report.return")), num(stats.Return/100, xlsxPercent)},
+			{str(tr("report.high")), num(stats.High, xlsxDecimal)},
+			{str(tr("report.low")), num(stats.Low, xlsxDecimal)},
+			{str(tr("report.avgVolume")), num(stats.AvgVolume, xlsxInteger)},
+			{str(tr("report.volatility")), num(stats.Volatility/100, xlsxPercent)},
+			{str(tr("report.maxDrawdown")), num(stats.MaxDrawdown/100, xlsxPercent)},
+			{str(tr("xlsx.exportedAt")), str(shanghaiNow().Format(time.RFC3339))},
+		},
+		Chart: &xlsxChart{
+			Title:      tr("xlsx.chartTitle", symbol),
+			Categories: xlsxRange("Data", 0, 2, n+1),
+			Series: []xlsxSeries{
+				{Name: "'Data'!$C$1", Values: xlsxRange("Data", 2, 2, n+1)},
+				{Name: "'Indicators'!$D$1", Values: xlsxRange("Indicators", 3, 2, n+1)},
+			},
+		},
+	}
+	return []xlsxSheet{summary, data, indicators}
+}
+
+// ExportSymbolXLSX exports the last days of daily bars of symbol as a
+// base64-encoded .xlsx workbook with data, indicator and summary sheets.
+// lookbackMax (-1) as days exports all available history.
+func (a *App) ExportSymbolXLSX(symbol string, days int) (string, error) {
+	a.recent.touch(symbol)
+	if days == 0 || days < lookbackMax {
+		days = 365
+	}
+	now := shanghaiNow()
+	bars, err := fetchDailyBars(symbol, lookbackStart(symbol, days, now), now)
+	if err != nil {
+		return "", err
+	}
+	name := ""
+	if meta, err := a.metadata.get(symbol, false); err == nil {
+		name = meta.Name
+	}
+	data, err := buildXLSX(symbolWorkbook(symbol, name, bars))
+	if err != nil {
+		err = fmt.Errorf("failed to build workbook: %v", err)
+	}
+	recordAudit(auditExport, "xlsx", symbol, fmt.Sprintf("%d bars", len(bars)), err)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}